@@ -2,10 +2,10 @@ package main
 
 import (
 	"context"
-	"log"
 	"os"
 
 	"github.com/aquasecurity/trivy-db/pkg/github"
+	"github.com/aquasecurity/trivy-db/pkg/log"
 
 	"github.com/aquasecurity/trivy-db/pkg"
 )
@@ -23,6 +23,7 @@ func main() {
 	app := ac.NewApp(version)
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
+		log.Error("Failed", log.Err(err))
+		os.Exit(1)
 	}
 }
@@ -1,10 +1,15 @@
 package pkg
 
 import (
+	"os"
 	"strings"
 	"time"
 
+	"github.com/aquasecurity/trivy-db/pkg/artifact"
+	"github.com/aquasecurity/trivy-db/pkg/db"
 	"github.com/aquasecurity/trivy-db/pkg/github"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
 
 	"github.com/aquasecurity/trivy-db/pkg/utils"
 
@@ -22,6 +27,26 @@ func (ac *AppConfig) NewApp(version string) *cli.App {
 	app.Version = version
 	app.ArgsUsage = "image_name"
 	app.Usage = "Trivy DB builder"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "log-level",
+			Usage: "log the records of this level and above: debug, info, warn or error",
+			Value: "info",
+		},
+		cli.StringFlag{
+			Name:  "log-format",
+			Usage: "log as text or json, e.g. for a log collector",
+			Value: "text",
+		},
+	}
+	app.Before = func(c *cli.Context) error {
+		logger, err := log.New(os.Stderr, c.GlobalString("log-level"), c.GlobalString("log-format"))
+		if err != nil {
+			return err
+		}
+		log.SetLogger(logger)
+		return nil
+	}
 
 	app.Commands = []cli.Command{
 		{
@@ -29,31 +54,710 @@ func (ac *AppConfig) NewApp(version string) *cli.App {
 			Usage:  "build a database file",
 			Action: build,
 			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "config",
+					Usage: "path of the pipeline config, whose values apply to the flags not given",
+				},
 				cli.BoolFlag{
 					Name:  "light",
-					Usage: "insert only CVE-ID and severity",
+					Usage: "insert only advisories and severities, without vulnerability details and indexes",
 				},
 				cli.StringFlag{
-					Name:  "only-update",
-					Usage: "update db only specified distribution (comma separated)",
-					Value: strings.Join(vulnsrc.UpdateList, ","),
+					Name:   "only-update",
+					Usage:  "update db only specified distribution (comma separated)",
+					Value:  strings.Join(vulnsrc.UpdateList, ","),
+					EnvVar: "TRIVY_DB_SOURCES",
+				},
+				cli.StringFlag{
+					Name:   "skip-update",
+					Usage:  "do not update the specified sources (comma separated), e.g. to rebuild all but nvd",
+					EnvVar: "TRIVY_DB_SKIP_SOURCES",
+				},
+				cli.StringSliceFlag{
+					Name:  "plugin",
+					Usage: "executable writing the advisories of a source to its stdout, updated with the default sources, e.g. acme=/opt/feeds/acme-feed",
+				},
+				cli.StringFlag{
+					Name:  "encoding",
+					Usage: "encoding of values in a new database file (json, msgpack)",
+					Value: db.EncodingJSON,
+				},
+				cli.BoolFlag{
+					Name:  "compress",
+					Usage: "compress values with zstd and a dictionary trained on them",
+				},
+				cli.StringFlag{
+					Name:  "split",
+					Usage: "also write the advisories into separate files next to the database (ecosystem: os.db and lang.db, distro: a file per OS family and lang.db)",
+				},
+				cli.BoolFlag{
+					Name:  "dedup",
+					Usage: "store descriptions and references repeated across sources once",
+				},
+				cli.BoolFlag{
+					Name:  "validate",
+					Usage: "fail the update of a source writing a malformed advisory or vulnerability, and the build of a DB breaking an invariant checked by validate",
+				},
+				cli.BoolFlag{
+					Name:  "checkpoint",
+					Usage: "snapshot the database after each source and resume an interrupted build from the last one, updating again the sources whose input changed",
+				},
+				cli.BoolFlag{
+					Name:  "incremental",
+					Usage: "only read the files changed in the git repositories of the sources since the previous build, which needs its revisions in their history, e.g. with --fetch-depth 0",
+				},
+				cli.StringFlag{
+					Name:  "channel",
+					Usage: "release channel the database is built for, recorded in its metadata and published to: dev, nightly or stable",
+					Value: db.ChannelStable,
+				},
+				cli.BoolFlag{
+					Name:  "low-memory",
+					Usage: "commit the inputs of the sources every --batch-size of them as they are read, and collect garbage more often, trading speed for memory, e.g. on 2GB runners",
+				},
+				cli.IntFlag{
+					Name:  "batch-size",
+					Usage: "number of inputs of a source read between two commits with --low-memory",
+					Value: 1000,
+				},
+				cli.IntFlag{
+					Name:  "parallel",
+					Usage: "number of sources built at once into shards of their own, merged into the database at the end",
+					Value: 1,
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "build into a throwaway copy of the database and report what the build would change",
+				},
+				cli.StringFlag{
+					Name:  "dry-run-format",
+					Usage: "format of the dry run report: text or json",
+					Value: "text",
+				},
+				cli.IntFlag{
+					Name:  "workers",
+					Usage: "number of sources parsed at once in this process, committed to the database in turn",
+					Value: 1,
+				},
+				cli.StringFlag{
+					Name:   "shard",
+					Usage:  "build the single source of --only-update into a new database in this directory, for --parallel",
+					Hidden: true,
+				},
+				cli.StringFlag{
+					Name:  "wal",
+					Usage: "append the writes of the build to a write-ahead log, which replay turns into the database of an interrupted build",
+				},
+				cli.BoolTFlag{
+					Name:  "compact",
+					Usage: "rewrite the database file without the space freed during the build (bolt only, --compact=false to disable)",
+				},
+				cli.BoolFlag{
+					Name:  "bdu",
+					Usage: "update db with FSTEC BDU data as well (the feed needs to be downloaded into cache-dir/bdu manually)",
+				},
+				cli.BoolFlag{
+					Name:  "ssvc",
+					Usage: "update db with user-supplied SSVC decision points in cache-dir/ssvc as well",
 				},
 				cli.StringFlag{
 					Name:  "cache-dir",
 					Usage: "cache directory path",
 					Value: utils.CacheDir(),
 				},
+				cli.StringFlag{
+					Name:  "backend",
+					Usage: "storage backend of the database file (bolt, sqlite, badger - experimental, the path is a directory and large sources need --low-memory)",
+					Value: storage.DefaultDriver,
+				},
+				cli.DurationFlag{
+					Name:  "source-timeout",
+					Usage: "maximum time to update a source, e.g. 30m (0 for no limit)",
+				},
+				cli.IntFlag{
+					Name:  "source-retries",
+					Usage: "number of times a source failing with a transient error, e.g. of I/O, is updated again",
+					Value: 2,
+				},
+				cli.DurationFlag{
+					Name:  "retry-backoff",
+					Usage: "time to wait before updating a source again, doubled for each next retry",
+					Value: 10 * time.Second,
+				},
 				cli.DurationFlag{
 					Name:   "update-interval",
 					Usage:  "update interval",
 					Value:  24 * time.Hour,
 					EnvVar: "UPDATE_INTERVAL",
 				},
+				cli.DurationFlag{
+					Name:  "valid-for",
+					Usage: "how long after the build the database may be used, defaults to the update interval",
+				},
+				cli.BoolFlag{
+					Name:  "fetch",
+					Usage: "clone or update the git repositories of the sources into cache-dir first, see the fetch command",
+				},
+				cli.IntFlag{
+					Name:  "fetch-depth",
+					Usage: "number of commits fetched with --fetch (0 for the whole history)",
+					Value: 1,
+				},
+				cli.StringSliceFlag{
+					Name:  "mirror",
+					Usage: "URL tried before the upstream of a repository with --fetch, e.g. vuln-list=https://git.example.com/vuln-list.git",
+				},
+				cli.BoolFlag{
+					Name:  "strict",
+					Usage: "fail the build when a source fails, once the others are updated, with the failures of all of them",
+				},
+				cli.BoolFlag{
+					Name:  "continue-on-error",
+					Usage: "keep the previous advisories of a failed source and only report its failure (default)",
+				},
+				cli.BoolTFlag{
+					Name:  "preflight",
+					Usage: "check the inputs of the sources exist, are numerous enough and parse before updating them, failing the sources which don't by their policy (--preflight=false to disable)",
+				},
+				cli.IntFlag{
+					Name:  "preflight-sample",
+					Usage: "number of input files of each source parsed by the preflight",
+					Value: 5,
+				},
+				cli.DurationFlag{
+					Name:  "stale-after",
+					Usage: "report the sources which added no record for longer than this as stale",
+					Value: 7 * 24 * time.Hour,
+				},
+				cli.StringFlag{
+					Name:  "freshness-report",
+					Usage: "path of the JSON report comparing the newest records of each source with the build date",
+				},
+				cli.StringFlag{
+					Name:  "metrics-listen",
+					Usage: "address serving the Prometheus metrics of the build on /metrics while it runs, e.g. :9100",
+				},
+				cli.StringFlag{
+					Name:  "metrics-push",
+					Usage: "URL of the Pushgateway the metrics are pushed to once the build is over",
+				},
+				cli.StringFlag{
+					Name:  "metrics-job",
+					Usage: "job of the metrics pushed to the Pushgateway",
+					Value: "trivy-db",
+				},
+			},
+		},
+		{
+			Name:   "config",
+			Usage:  "validate a pipeline config and print it with the defaults spelled out",
+			Action: dumpConfig,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "config",
+					Usage: "path of the pipeline config, the defaults are printed without one",
+				},
+			},
+		},
+		{
+			Name:   "fetch",
+			Usage:  "clone or update the git repositories of the sources, e.g. vuln-list, into the cache directory and check them",
+			Action: fetchRepositories,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "only-update",
+					Usage:  "fetch the repositories of the specified sources only (comma separated)",
+					EnvVar: "TRIVY_DB_SOURCES",
+				},
+				cli.StringFlag{
+					Name:   "skip-update",
+					Usage:  "do not fetch the repositories of the specified sources (comma separated)",
+					EnvVar: "TRIVY_DB_SKIP_SOURCES",
+				},
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.IntFlag{
+					Name:  "depth",
+					Usage: "number of commits fetched (0 for the whole history)",
+					Value: 1,
+				},
+				cli.StringSliceFlag{
+					Name:  "mirror",
+					Usage: "URL tried before the upstream of a repository, e.g. vuln-list=https://git.example.com/vuln-list.git",
+				},
+			},
+		},
+		{
+			Name:   "verify",
+			Usage:  "verify a database file against the checksums stored at build time",
+			Action: verify,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+			},
+		},
+		{
+			Name:   "validate",
+			Usage:  "check the invariants of a database file: the advisories have vulnerabilities, the severities and the fixed versions are valid, no bucket is empty",
+			Action: validate,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+			},
+		},
+		{
+			Name:   "stats",
+			Usage:  "show the advisory counts per source, ecosystem and severity, and the size of each bucket, to track the growth of a database file",
+			Action: stats,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "output format (table, json)",
+					Value: "table",
+				},
+			},
+		},
+		{
+			Name:   "freshness",
+			Usage:  "compare the newest records of each source with the build date of a database file, to catch the sources which stopped being fetched",
+			Action: freshness,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "output format (table, json)",
+					Value: "table",
+				},
+				cli.DurationFlag{
+					Name:  "stale-after",
+					Usage: "report the sources which added no record for longer than this as stale",
+					Value: 7 * 24 * time.Hour,
+				},
+				cli.BoolFlag{
+					Name:  "fail-on-stale",
+					Usage: "exit with an error when a source is stale",
+				},
+			},
+		},
+		{
+			Name:      "daemon",
+			Usage:     "rebuild the database on a schedule, and publish the outputs of the pipeline config, serving health and readiness endpoints",
+			ArgsUsage: "[-- build flags]",
+			Action:    runDaemon,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "schedule",
+					Usage: "cron expression of the rebuilds, e.g. \"0 */6 * * *\", or a descriptor, e.g. @every 6h",
+					Value: "0 */6 * * *",
+				},
+				cli.BoolTFlag{
+					Name:  "run-on-start",
+					Usage: "rebuild once on start, before the first scheduled rebuild",
+				},
+				cli.StringFlag{
+					Name:  "config",
+					Usage: "path of the pipeline config of the builds, whose outputs are published to its publishers",
+				},
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "listen",
+					Usage: "address to serve /healthz, /readyz and /status on, disabled if empty",
+					Value: ":8080",
+				},
+			},
+		},
+		{
+			Name:   "query",
+			Usage:  "look up the advisories of a package, e.g. --os \"amazon linux 2\" --pkg curl, or a vulnerability, e.g. --cve CVE-2019-5436",
+			Action: query,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "os",
+					Usage: "OS of the package, e.g. debian 9, or the advisory bucket of a language ecosystem",
+				},
+				cli.StringFlag{
+					Name:  "pkg",
+					Usage: "name of the package",
+				},
+				cli.StringFlag{
+					Name:  "version",
+					Usage: "installed version of the package, to only show the advisories affecting it",
+				},
+				cli.StringFlag{
+					Name:  "arch",
+					Usage: "architecture of the package, e.g. aarch64, to only show the advisories affecting it with its fixed versions",
+				},
+				cli.StringFlag{
+					Name:  "purl",
+					Usage: "package URL of the package, in place of --os and --pkg",
+				},
+				cli.StringFlag{
+					Name:  "cve",
+					Usage: "ID of the vulnerability to show, with the packages it affects",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "output format (table, json)",
+					Value: "table",
+				},
+			},
+		},
+		{
+			Name:      "sbom",
+			Usage:     "match the packages of a CycloneDX or SPDX SBOM in JSON against the database by their package URLs, showing the advisories affecting them",
+			ArgsUsage: "[path of the SBOM, stdin by default or with -]",
+			Action:    matchSBOM,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "output format (table, json)",
+					Value: "table",
+				},
+			},
+		},
+		{
+			Name:      "extract",
+			Usage:     "dump everything the database has on a vulnerability as JSON, e.g. for a bug report: its details by source and the advisories of the packages it affects",
+			ArgsUsage: "vulnerability_id",
+			Action:    extract,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+			},
+		},
+		{
+			Name:   "serve",
+			Usage:  "serve read-only lookups of a database file over HTTP and gRPC: advisories by package, vulnerabilities by ID and the metadata",
+			Action: serve,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "listen",
+					Usage: "address to serve HTTP on, HTTP being disabled if empty",
+					Value: ":8080",
+				},
+				cli.StringFlag{
+					Name:  "grpc-listen",
+					Usage: "address to serve the gRPC lookup service on, e.g. :9090",
+				},
+				cli.DurationFlag{
+					Name:  "read-timeout",
+					Usage: "time to read the headers of a request",
+					Value: 10 * time.Second,
+				},
+				cli.DurationFlag{
+					Name:  "shutdown-timeout",
+					Usage: "time the requests in flight have to finish on shutdown",
+					Value: 10 * time.Second,
+				},
+			},
+		},
+		{
+			Name:   "export",
+			Usage:  "export a database file as newline-delimited JSON, one {\"bucket\", \"key\", \"value\"} record per value",
+			Action: export,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "output",
+					Usage: "path of the export, stdout by default or with -",
+				},
+				cli.BoolFlag{
+					Name:  "gzip",
+					Usage: "gzip the export, the default if the output ends with .gz",
+				},
+			},
+		},
+		{
+			Name:   "import",
+			Usage:  "build a database file from the newline-delimited JSON of export, streamed in chunks",
+			Action: importDB,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path, the database file must not exist",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "input",
+					Usage: "path of the export, gzipped or not, stdin by default or with -",
+				},
+			},
+		},
+		{
+			Name:      "merge",
+			Usage:     "merge database files into a new one, e.g. the public one and one of internal advisories, the later files overriding the earlier ones",
+			ArgsUsage: "[name=]path...",
+			Action:    mergeDB,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path, the database file must not exist",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "backend",
+					Usage: "storage backend of the database files",
+					Value: storage.DefaultDriver,
+				},
+			},
+		},
+		{
+			Name:   "replay",
+			Usage:  "build a database file from the write-ahead log of a build, e.g. after a crash, then resume with build --checkpoint",
+			Action: replay,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path, the database file must not exist",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "wal",
+					Usage: "path of the write-ahead log",
+				},
+				cli.StringFlag{
+					Name:  "backend",
+					Usage: "storage backend of the database file",
+					Value: storage.DefaultDriver,
+				},
+			},
+		},
+		{
+			Name:   "delta",
+			Usage:  "make a delta between two database files for incremental updates",
+			Action: makeDelta,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "path of the previous database file",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "path of the new database file",
+				},
+				cli.StringFlag{
+					Name:  "output",
+					Usage: "path of the delta",
+					Value: "trivy.db.delta.gz",
+				},
+				cli.StringFlag{
+					Name:  "backend",
+					Usage: "storage backend of the database files",
+					Value: storage.DefaultDriver,
+				},
+			},
+		},
+		{
+			Name:   "diff",
+			Usage:  "list the advisories and vulnerabilities added, removed or changed between two database files, e.g. a build and the previous release",
+			Action: diff,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "previous database: a file, gzipped or not, an http(s) URL or an OCI reference like oci://ghcr.io/aquasecurity/trivy-db:2",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "new database, like --from",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "output format (text, json)",
+					Value: "text",
+				},
+				cli.StringFlag{
+					Name:  "backend",
+					Usage: "storage backend of the database files",
+					Value: storage.DefaultDriver,
+				},
+			},
+		},
+		{
+			Name:   "package",
+			Usage:  "archive the database file into the compressed files of a release, the same database always archiving to the same files",
+			Action: packageDB,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "backend",
+					Usage: "storage backend of the database file",
+					Value: storage.DefaultDriver,
+				},
+				cli.StringFlag{
+					Name:  "dir",
+					Usage: "dir the files are written to",
+					Value: "assets",
+				},
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "name of the files, e.g. trivy-light for assets/trivy-light.db.gz",
+					Value: "trivy",
+				},
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "comma-separated formats of the files: gz, zst, tar.gz or tar.zst, the tarballs holding the metadata as well",
+					Value: artifact.FormatGzip,
+				},
+			},
+		},
+		{
+			Name:   "sign",
+			Usage:  "sign the compressed database files with cosign, before upload",
+			Action: signAssets,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "dir",
+					Usage: "dir of the files, where the signatures and metadata.json are written",
+					Value: "assets",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "private key of cosign sign-blob, e.g. of cosign generate-key-pair with the password in COSIGN_PASSWORD",
+				},
+				cli.BoolFlag{
+					Name:  "keyless",
+					Usage: "sign with a certificate for the OIDC identity of the environment, through cosign",
+				},
+				cli.StringFlag{
+					Name:  "cosign",
+					Usage: "path of the cosign executable",
+					Value: "cosign",
+				},
+			},
+		},
+		{
+			Name:   "metadata",
+			Usage:  "describe the compressed database files in metadata.json, which clients check the freshness with",
+			Action: generateMetadata,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "dir",
+					Usage: "dir of the files, where metadata.json is written",
+					Value: "assets",
+				},
+				cli.StringSliceFlag{
+					Name:  "url-template",
+					Usage: "URL the files are downloaded from, {name} standing for the file name, e.g. of each mirror",
+				},
+			},
+		},
+		{
+			Name:   "publish",
+			Usage:  "push a database file to an OCI registry as the artifact trivy pulls",
+			Action: publish,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringFlag{
+					Name:  "repository",
+					Usage: "repository to push to, e.g. ghcr.io/aquasecurity/trivy-db",
+				},
+				cli.StringFlag{
+					Name:  "tags",
+					Usage: "comma-separated tags, latest and the schema version by default, with a -light suffix for a light DB",
+				},
+				cli.StringFlag{
+					Name:   "username",
+					Usage:  "username of the registry, anonymous if empty",
+					EnvVar: "REGISTRY_USERNAME",
+				},
+				cli.StringFlag{
+					Name:   "password",
+					Usage:  "password or token of the registry",
+					EnvVar: "REGISTRY_PASSWORD",
+				},
+				cli.BoolFlag{
+					Name:  "plain-http",
+					Usage: "talk to the registry over HTTP, e.g. a local one",
+				},
+				cli.BoolFlag{
+					Name:  "provenance",
+					Usage: "attach the SLSA provenance of the build to the pushed artifact",
+				},
+				cli.StringFlag{
+					Name:  "builder-id",
+					Usage: "ID of what built the DB in the provenance, the GitHub Actions workflow by default",
+				},
+			},
+		},
+		{
+			Name:   "provenance",
+			Usage:  "generate the in-toto SLSA provenance of the built database, with the revisions of its sources",
+			Action: provenance,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "cache-dir",
+					Usage: "cache directory path",
+					Value: utils.CacheDir(),
+				},
+				cli.StringSliceFlag{
+					Name:  "subject",
+					Usage: "path of a file the build produced, e.g. a compressed DB, the DB itself by default",
+				},
+				cli.StringFlag{
+					Name:  "output",
+					Usage: "path the provenance is written to, stdout by default",
+				},
+				cli.StringFlag{
+					Name:  "builder-id",
+					Usage: "ID of what built the DB, the GitHub Actions workflow by default",
+				},
 			},
 		},
 		{
 			Name:   "upload",
-			Usage:  "upload database files to GitHub Release",
+			Usage:  "upload database files to GitHub Release, or to the publishers of a pipeline config",
 			Action: ac.upload,
 			Flags: []cli.Flag{
 				cli.StringFlag{
@@ -61,6 +765,10 @@ func (ac *AppConfig) NewApp(version string) *cli.App {
 					Usage: "dir",
 					Value: strings.Join(vulnsrc.UpdateList, ","),
 				},
+				cli.StringFlag{
+					Name:  "config",
+					Usage: "path of the pipeline config listing the publishers, e.g. S3 and GCS buckets and GitHub releases",
+				},
 			},
 		},
 	}
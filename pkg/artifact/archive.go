@@ -0,0 +1,110 @@
+package artifact
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+)
+
+// the formats of the archives of a DB file, which are the extensions of their paths
+const (
+	FormatGzip    = "gz"
+	FormatZstd    = "zst"
+	FormatTarGzip = "tar.gz"
+	FormatTarZstd = "tar.zst"
+)
+
+// Formats are the archive formats, the longest extensions first
+var Formats = []string{FormatTarGzip, FormatTarZstd, FormatGzip, FormatZstd}
+
+// FormatOf returns the archive format of path by its extension, empty for a plain copy
+func FormatOf(path string) string {
+	for _, format := range Formats {
+		if strings.HasSuffix(path, "."+format) {
+			return format
+		}
+	}
+	return ""
+}
+
+// IsArchive tells whether name is the one of a DB archive, e.g. trivy.db.gz or trivy-light.db.tar.zst
+func IsArchive(name string) bool {
+	format := FormatOf(name)
+	return format != "" && strings.HasSuffix(strings.TrimSuffix(name, "."+format), "db")
+}
+
+// Archive writes the DB file at dbPath to w in format: compressed as is, or as a tarball holding trivy.db and
+// the metadata in metadata.json, as the OCI artifact does. The archive only depends on the DB: the entries
+// are dated with the metadata, owned by root, and the compressors have no timestamp nor concurrency.
+func Archive(w io.Writer, dbPath, format string, metadata db.Metadata) error {
+	f, err := os.Open(dbPath)
+	if err != nil {
+		return xerrors.Errorf("failed to open the DB: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return xerrors.Errorf("%s isn't a file, only the DBs of bolt and sqlite are archived", dbPath)
+	}
+
+	var cw io.WriteCloser
+	switch format {
+	case FormatGzip, FormatTarGzip:
+		cw = gzip.NewWriter(w)
+	case FormatZstd, FormatTarZstd:
+		if cw, err = zstd.NewWriter(w, zstd.WithEncoderConcurrency(1)); err != nil {
+			return xerrors.Errorf("failed to create a zstd encoder: %w", err)
+		}
+	default:
+		return xerrors.Errorf("unknown archive format %q, one of %s", format, strings.Join(Formats, ", "))
+	}
+
+	if format == FormatGzip || format == FormatZstd {
+		_, err = io.Copy(cw, f)
+	} else {
+		err = writeTar(cw, f, info.Size(), metadata)
+	}
+	if err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+func writeTar(w io.Writer, dbFile io.Reader, size int64, metadata db.Metadata) error {
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return xerrors.Errorf("failed to encode the metadata: %w", err)
+	}
+	tw := tar.NewWriter(w)
+	// USTAR has no sub-second times nor access times, which would change the headers
+	modTime := metadata.UpdatedAt.UTC().Truncate(time.Second)
+	header := func(name string, size int64) *tar.Header {
+		return &tar.Header{Name: name, Mode: 0644, Size: size, ModTime: modTime, Typeflag: tar.TypeReg,
+			Format: tar.FormatUSTAR}
+	}
+	if err = tw.WriteHeader(header("trivy.db", size)); err != nil {
+		return err
+	}
+	if _, err = io.Copy(tw, dbFile); err != nil {
+		return err
+	}
+	if err = tw.WriteHeader(header("metadata.json", int64(len(b)))); err != nil {
+		return err
+	}
+	if _, err = tw.Write(b); err != nil {
+		return err
+	}
+	return tw.Close()
+}
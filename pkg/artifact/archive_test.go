@@ -0,0 +1,107 @@
+package artifact
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+)
+
+func TestArchive(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestArchive_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	dbPath := filepath.Join(d, "trivy.db")
+	assert.NoError(t, ioutil.WriteFile(dbPath, []byte("bolt"), 0600))
+	metadata := db.Metadata{Version: db.SchemaVersion, UpdatedAt: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)}
+
+	tests := []struct {
+		format      string
+		wantEntries []string
+		wantErr     string
+	}{
+		{format: FormatGzip},
+		{format: FormatZstd},
+		{format: FormatTarGzip, wantEntries: []string{"trivy.db", "metadata.json"}},
+		{format: FormatTarZstd, wantEntries: []string{"trivy.db", "metadata.json"}},
+		{format: "bz2", wantErr: `unknown archive format "bz2", one of tar.gz, tar.zst, gz, zst`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var first, second bytes.Buffer
+			err := Archive(&first, dbPath, tt.format, metadata)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			// the archive only depends on the DB, even once the file was touched
+			assert.NoError(t, os.Chtimes(dbPath, time.Now(), time.Now()))
+			assert.NoError(t, Archive(&second, dbPath, tt.format, metadata))
+			assert.Equal(t, first.Bytes(), second.Bytes())
+
+			got := filepath.Join(d, "unpacked-"+tt.format)
+			assert.NoError(t, unpack(bytes.NewReader(first.Bytes()), got))
+			b, err := ioutil.ReadFile(got)
+			assert.NoError(t, err)
+			assert.Equal(t, "bolt", string(b))
+
+			if tt.wantEntries == nil {
+				return
+			}
+			var r io.Reader
+			if tt.format == FormatTarGzip {
+				r, err = gzip.NewReader(&first)
+			} else {
+				r, err = zstd.NewReader(&first)
+			}
+			assert.NoError(t, err)
+			var entries []string
+			tr := tar.NewReader(r)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				assert.NoError(t, err)
+				entries = append(entries, hdr.Name)
+				assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), hdr.ModTime.UTC())
+				assert.Equal(t, 0, hdr.Uid)
+			}
+			assert.Equal(t, tt.wantEntries, entries)
+		})
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantFormat string
+		want       bool
+	}{
+		{name: "trivy.db.gz", wantFormat: FormatGzip, want: true},
+		{name: "trivy-light.db.zst", wantFormat: FormatZstd, want: true},
+		{name: "trivy.db.tar.gz", wantFormat: FormatTarGzip, want: true},
+		{name: "trivy.db.tar.zst", wantFormat: FormatTarZstd, want: true},
+		{name: "trivy.db.gz.sig", wantFormat: "", want: false},
+		{name: "trivy.db.delta.gz", wantFormat: FormatGzip, want: false},
+		{name: "trivy.db", wantFormat: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantFormat, FormatOf(tt.name))
+			assert.Equal(t, tt.want, IsArchive(tt.name))
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package artifact
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/oci"
+)
+
+const ociScheme = "oci://"
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Fetch returns the path of the DB file of ref, which is the path of a file, an http(s) URL like the one of
+// a GitHub release asset, or an OCI reference prefixed with oci://, e.g. oci://ghcr.io/aquasecurity/trivy-db:2.
+// Downloads and gzipped files are unpacked into dir, as well as the .db file of a tarball.
+func Fetch(ctx context.Context, client oci.Client, ref, dir string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, ociScheme):
+		r, err := oci.ParseReference(strings.TrimPrefix(ref, ociScheme))
+		if err != nil {
+			return "", err
+		}
+		return download(dir, func(w io.Writer) error {
+			_, err := client.Pull(ctx, r, w)
+			return err
+		})
+	case strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://"):
+		return download(dir, func(w io.Writer) error {
+			return get(ctx, client.HTTPClient, ref, w)
+		})
+	}
+
+	f, err := os.Open(ref)
+	if err != nil {
+		return "", xerrors.Errorf("failed to open %s: %w", ref, err)
+	}
+	defer f.Close()
+	magic := make([]byte, len(gzipMagic))
+	if _, err = io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, gzipMagic) {
+		// a DB file, or a file the DB will fail to open
+		return ref, nil
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "trivy.db")
+	if err = unpack(f, path); err != nil {
+		return "", xerrors.Errorf("failed to unpack %s: %w", ref, err)
+	}
+	return path, nil
+}
+
+// download unpacks what fetch writes, which is first kept in dir, into a DB file in dir
+func download(dir string, fetch func(io.Writer) error) (string, error) {
+	archive := filepath.Join(dir, "download")
+	f, err := os.Create(archive)
+	if err != nil {
+		return "", xerrors.Errorf("failed to create the download: %w", err)
+	}
+	defer os.Remove(archive)
+	defer f.Close()
+
+	if err = fetch(f); err != nil {
+		return "", xerrors.Errorf("failed to download: %w", err)
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "trivy.db")
+	if err = unpack(f, path); err != nil {
+		return "", xerrors.Errorf("failed to unpack the download: %w", err)
+	}
+	return path, nil
+}
+
+func get(ctx context.Context, client *http.Client, url string, w io.Writer) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return xerrors.Errorf("GET %s: %s", url, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// unpack writes the DB file of r, gzipped, zstd-compressed or not, a tarball or a DB file itself, to path
+func unpack(r io.Reader, path string) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return xerrors.Errorf("failed to open gzip: %w", err)
+		}
+		defer gr.Close()
+		br = bufio.NewReader(gr)
+	} else if magic, _ = br.Peek(len(zstdMagic)); bytes.Equal(magic, zstdMagic) {
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return xerrors.Errorf("failed to open zstd: %w", err)
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+
+	var src io.Reader = br
+	// the header of a tarball has the magic after the name, mode, owner, size, time, checksum and type
+	if magic, _ := br.Peek(263); len(magic) == 263 && string(magic[257:262]) == "ustar" {
+		tr := tar.NewReader(br)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return xerrors.New("no .db file in the tarball")
+			} else if err != nil {
+				return xerrors.Errorf("failed to read the tarball: %w", err)
+			}
+			if hdr.Typeflag == tar.TypeReg && strings.HasSuffix(hdr.Name, ".db") {
+				src = tr
+				break
+			}
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return xerrors.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err = io.Copy(f, src); err != nil {
+		return xerrors.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,111 @@
+package artifact
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/oci"
+)
+
+func gzipped(b []byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write(b)
+	gw.Close()
+	return buf.Bytes()
+}
+
+func tarball(files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, b := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(b)), Typeflag: tar.TypeReg})
+		tw.Write(b)
+	}
+	tw.Close()
+	return buf.Bytes()
+}
+
+func TestFetch(t *testing.T) {
+	db := []byte("bolt")
+	tests := []struct {
+		name       string
+		content    []byte
+		url        bool // served over http instead of read from a file
+		downloaded bool // written into the dir
+		wantErr    string
+	}{
+		{
+			name:    "DB file",
+			content: db,
+		},
+		{
+			name:       "gzipped DB file",
+			content:    gzipped(db),
+			downloaded: true,
+		},
+		{
+			name:       "release asset",
+			content:    gzipped(db),
+			url:        true,
+			downloaded: true,
+		},
+		{
+			name:       "tarball",
+			content:    gzipped(tarball(map[string][]byte{"db/trivy.db": db})),
+			url:        true,
+			downloaded: true,
+		},
+		{
+			name:    "tarball without DB",
+			content: gzipped(tarball(map[string][]byte{"metadata.json": []byte("{}")})),
+			url:     true,
+			wantErr: "no .db file in the tarball",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestFetch_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+
+			ref := filepath.Join(d, "src")
+			assert.NoError(t, ioutil.WriteFile(ref, tt.content, 0600))
+			if tt.url {
+				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write(tt.content)
+				}))
+				defer ts.Close()
+				ref = ts.URL + "/trivy.db.gz"
+			}
+			dir := filepath.Join(d, "fetch")
+			assert.NoError(t, os.Mkdir(dir, 0700))
+
+			got, err := Fetch(context.Background(), oci.NewClient(), ref, dir)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.downloaded {
+				assert.Equal(t, filepath.Join(dir, "trivy.db"), got)
+			} else {
+				assert.Equal(t, ref, got)
+			}
+			b, err := ioutil.ReadFile(got)
+			assert.NoError(t, err)
+			assert.Equal(t, db, b)
+		})
+	}
+}
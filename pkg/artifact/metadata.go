@@ -0,0 +1,110 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/sign"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// MetadataFile is the name of the sidecar describing the artifacts of a release, next to them
+const MetadataFile = "metadata.json"
+
+// Metadata describes the artifacts of a release, which clients read to check the freshness of their DB
+// without downloading one
+type Metadata struct {
+	SchemaVersion int       `json:",omitempty"` // of the DBs, clients only use the DBs of their schema
+	GeneratedAt   time.Time `json:",omitempty"`
+	// Channel is the release channel of the DBs, stable if empty, which clients pin to
+	Channel string `json:",omitempty"`
+	// URLTemplates are where the artifacts are downloaded from, {name} standing for the file name
+	URLTemplates []string `json:",omitempty"`
+	// Artifacts is keyed by the file name, e.g. trivy.db.gz
+	Artifacts map[string]ArtifactMetadata
+}
+
+// ArtifactMetadata lets clients check an artifact before trusting it
+type ArtifactMetadata struct {
+	Digest string // e.g. sha256:...
+	Size   int64  `json:",omitempty"`
+	// DB is the metadata of the DB in the artifact: its type, when it was built and the revisions of its sources
+	DB        *db.Metadata    `json:",omitempty"`
+	Signature *sign.Signature `json:",omitempty"`
+}
+
+// Describe reads the size and the digest of the compressed DB at path, and the metadata of the DB once unpacked
+func Describe(path string) (ArtifactMetadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ArtifactMetadata{}, xerrors.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return ArtifactMetadata{}, xerrors.Errorf("failed to read %s: %w", path, err)
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return ArtifactMetadata{}, err
+	}
+
+	dir, err := ioutil.TempDir("", "trivy-db-describe-")
+	if err != nil {
+		return ArtifactMetadata{}, xerrors.Errorf("failed to create a temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	dbPath := filepath.Join(dir, "trivy.db")
+	if err = unpack(f, dbPath); err != nil {
+		return ArtifactMetadata{}, xerrors.Errorf("failed to unpack %s: %w", path, err)
+	}
+	metadata, err := db.ReadMetadata(storage.DefaultDriver, dbPath)
+	if err != nil {
+		return ArtifactMetadata{}, xerrors.Errorf("failed to read the metadata of %s: %w", path, err)
+	}
+
+	return ArtifactMetadata{
+		Digest: "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		Size:   size,
+		DB:     &metadata,
+	}, nil
+}
+
+// ReadMetadata reads the sidecar in dir, empty if there is none yet
+func ReadMetadata(dir string) (Metadata, error) {
+	metadata := Metadata{Artifacts: map[string]ArtifactMetadata{}}
+	b, err := ioutil.ReadFile(filepath.Join(dir, MetadataFile))
+	if os.IsNotExist(err) {
+		return metadata, nil
+	} else if err != nil {
+		return Metadata{}, xerrors.Errorf("failed to read the metadata: %w", err)
+	}
+	if err = json.Unmarshal(b, &metadata); err != nil {
+		return Metadata{}, xerrors.Errorf("invalid metadata: %w", err)
+	}
+	if metadata.Artifacts == nil {
+		metadata.Artifacts = map[string]ArtifactMetadata{}
+	}
+	return metadata, nil
+}
+
+// Write writes the sidecar in dir
+func (m Metadata) Write(dir string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("failed to encode the metadata: %w", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, MetadataFile), append(b, '\n'), 0644); err != nil {
+		return xerrors.Errorf("failed to write the metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/sign"
+)
+
+func TestDescribe(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestDescribe_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	updatedAt := time.Date(2020, 1, 2, 3, 0, 0, 0, time.UTC)
+	want := db.Metadata{
+		Version:    db.SchemaVersion,
+		Type:       db.TypeFull,
+		NextUpdate: updatedAt.Add(12 * time.Hour),
+		UpdatedAt:  updatedAt,
+		Sources: map[string]db.SourceMetadata{
+			"alpine": {UpdatedAt: updatedAt, Revision: "0123abc"},
+		},
+	}
+	assert.NoError(t, db.Init(d))
+	assert.NoError(t, db.Config{}.SetMetadata(want))
+	assert.NoError(t, db.Close())
+	b, err := ioutil.ReadFile(filepath.Join(d, "db", "trivy.db"))
+	assert.NoError(t, err)
+	path := filepath.Join(d, "trivy.db.gz")
+	assert.NoError(t, ioutil.WriteFile(path, gzipped(b), 0644))
+
+	got, err := Describe(path)
+	assert.NoError(t, err)
+	sum := sha256.Sum256(gzipped(b))
+	assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), got.Digest)
+	assert.Equal(t, int64(len(gzipped(b))), got.Size)
+	assert.Equal(t, &want, got.DB)
+
+	_, err = Describe(filepath.Join(d, "missing.db.gz"))
+	assert.Error(t, err)
+}
+
+func TestMetadata_Write(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestMetadata_Write_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	got, err := ReadMetadata(d)
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{Artifacts: map[string]ArtifactMetadata{}}, got)
+
+	want := Metadata{
+		SchemaVersion: db.SchemaVersion,
+		GeneratedAt:   time.Date(2020, 1, 2, 3, 0, 0, 0, time.UTC),
+		URLTemplates:  []string{"https://github.com/aquasecurity/trivy-db/releases/latest/download/{name}"},
+		Artifacts: map[string]ArtifactMetadata{
+			"trivy.db.gz": {
+				Digest:    "sha256:abc",
+				Size:      42,
+				DB:        &db.Metadata{Version: db.SchemaVersion, Type: db.TypeLight},
+				Signature: &sign.Signature{Digest: "sha256:abc", Signature: "MEUC"},
+			},
+		},
+	}
+	assert.NoError(t, want.Write(d))
+	got, err = ReadMetadata(d)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
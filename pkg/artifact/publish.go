@@ -0,0 +1,108 @@
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/oci"
+	"github.com/aquasecurity/trivy-db/pkg/slsa"
+)
+
+// the media types trivy pulls the DB with, pushed as oras push does
+const (
+	MediaTypeConfig = "application/vnd.aquasec.trivy.config.v1+json"
+	MediaTypeLayer  = "application/vnd.aquasec.trivy.db.layer.v1.tar+gzip"
+
+	annotationTitle   = "org.opencontainers.image.title" // the file name oras pull writes the layer to
+	annotationCreated = "org.opencontainers.image.created"
+	annotationVersion = "org.opencontainers.image.version"
+)
+
+// DefaultTags are latest and the schema version, e.g. 2, which clients of the schema pull, with a -light
+// suffix for a light DB. The tags of a channel other than stable are the channel and the schema version
+// suffixed with it, e.g. dev and 2-dev, which clients pin to.
+func DefaultTags(metadata db.Metadata) []string {
+	tags := []string{"latest", strconv.Itoa(metadata.Version)}
+	if channel := metadata.Channel; !db.IsStable(channel) {
+		tags = []string{channel, strconv.Itoa(metadata.Version) + "-" + channel}
+	}
+	if metadata.Type == db.TypeLight {
+		for i := range tags {
+			tags[i] += "-light"
+		}
+	}
+	return tags
+}
+
+// Publish pushes the DB file as an OCI artifact with a layer db.tar.gz holding trivy.db and the metadata
+// in metadata.json, annotated with the schema version and the build date, and tags it with each tag
+func Publish(ctx context.Context, client oci.Client, repository, dbPath string, metadata db.Metadata,
+	tags []string) (oci.Descriptor, error) {
+	ref, err := oci.ParseReference(repository)
+	if err != nil {
+		return oci.Descriptor{}, err
+	}
+
+	f, err := ioutil.TempFile("", "trivy-db-*.tar.gz")
+	if err != nil {
+		return oci.Descriptor{}, xerrors.Errorf("failed to create the layer: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	h := sha256.New()
+	if err = Archive(io.MultiWriter(f, h), dbPath, FormatTarGzip, metadata); err != nil {
+		return oci.Descriptor{}, xerrors.Errorf("failed to pack the DB: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return oci.Descriptor{}, err
+	}
+
+	layer := oci.Blob{
+		Descriptor: oci.Descriptor{
+			MediaType:   MediaTypeLayer,
+			Digest:      "sha256:" + hex.EncodeToString(h.Sum(nil)),
+			Size:        info.Size(),
+			Annotations: map[string]string{annotationTitle: "db.tar.gz"},
+		},
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(f.Name())
+		},
+	}
+	annotations := map[string]string{
+		annotationCreated: metadata.UpdatedAt.UTC().Format(time.RFC3339),
+		annotationVersion: strconv.Itoa(metadata.Version),
+	}
+	manifest, err := client.Push(ctx, ref, oci.BytesBlob(MediaTypeConfig, []byte("{}")), []oci.Blob{layer},
+		annotations, tags)
+	if err != nil {
+		return oci.Descriptor{}, xerrors.Errorf("failed to push to %s: %w", repository, err)
+	}
+	return manifest, nil
+}
+
+// Attest attaches the in-toto statement to the artifact of manifest in repository, which consumers
+// find with the referrers API of the artifact
+func Attest(ctx context.Context, client oci.Client, repository string, manifest oci.Descriptor,
+	statement []byte) (oci.Descriptor, error) {
+	ref, err := oci.ParseReference(repository)
+	if err != nil {
+		return oci.Descriptor{}, err
+	}
+	blob := oci.BytesBlob(slsa.MediaType, statement)
+	desc, err := client.Attach(ctx, ref, manifest, slsa.MediaType, blob,
+		map[string]string{annotationCreated: time.Now().UTC().Format(time.RFC3339)})
+	if err != nil {
+		return oci.Descriptor{}, xerrors.Errorf("failed to attach the attestation to %s: %w", repository, err)
+	}
+	return desc, nil
+}
@@ -0,0 +1,159 @@
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/oci"
+	"github.com/aquasecurity/trivy-db/pkg/slsa"
+)
+
+// registry keeps the blobs and manifests pushed to it in memory
+type registry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+}
+
+func (reg *registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/v2/aquasecurity/trivy-db/")
+	switch {
+	case r.Method == http.MethodPost && path == "blobs/uploads/":
+		w.Header().Set("Location", "/v2/aquasecurity/trivy-db/blobs/uploads/1?state=x")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && path == "blobs/uploads/1":
+		b, _ := ioutil.ReadAll(r.Body)
+		reg.blobs[r.URL.Query().Get("digest")] = b
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "manifests/"):
+		b, _ := ioutil.ReadAll(r.Body)
+		sum := sha256.Sum256(b)
+		reg.manifests[strings.TrimPrefix(path, "manifests/")] = b
+		reg.manifests["sha256:"+hex.EncodeToString(sum[:])] = b
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "manifests/"):
+		b, ok := reg.manifests[strings.TrimPrefix(path, "manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", oci.MediaTypeManifest)
+		w.Write(b)
+	case strings.HasPrefix(path, "blobs/"):
+		b, ok := reg.blobs[strings.TrimPrefix(path, "blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestPublish_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	dbPath := filepath.Join(d, "trivy.db")
+	assert.NoError(t, ioutil.WriteFile(dbPath, []byte("bolt"), 0600))
+
+	reg := &registry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+	repository := strings.TrimPrefix(ts.URL, "http://") + "/aquasecurity/trivy-db"
+	client := oci.Client{HTTPClient: ts.Client(), PlainHTTP: true}
+
+	metadata := db.Metadata{Version: db.SchemaVersion, Type: db.TypeLight, UpdatedAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tags := DefaultTags(metadata)
+	assert.Equal(t, []string{"latest-light", "2-light"}, tags)
+
+	ctx := context.Background()
+	manifest, err := Publish(ctx, client, repository, dbPath, metadata, tags)
+	assert.NoError(t, err)
+	assert.Equal(t, reg.manifests["latest-light"], reg.manifests["2-light"])
+	assert.Len(t, reg.blobs, 2)
+
+	// the tag and the digest pull the same DB
+	for _, ref := range []string{repository + ":2-light", repository + "@" + manifest.Digest} {
+		dir := filepath.Join(d, strings.Replace(ref[strings.LastIndexAny(ref, ":@")+1:], ":", "-", -1))
+		assert.NoError(t, os.Mkdir(dir, 0700))
+		got, err := Fetch(ctx, client, "oci://"+ref, dir)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadFile(got)
+		assert.NoError(t, err)
+		assert.Equal(t, "bolt", string(b))
+	}
+}
+
+func TestDefaultTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata db.Metadata
+		want     []string
+	}{
+		{name: "full", metadata: db.Metadata{Version: 2, Type: db.TypeFull}, want: []string{"latest", "2"}},
+		{name: "stable", metadata: db.Metadata{Version: 2, Type: db.TypeFull, Channel: db.ChannelStable},
+			want: []string{"latest", "2"}},
+		{name: "dev", metadata: db.Metadata{Version: 2, Type: db.TypeFull, Channel: db.ChannelDev},
+			want: []string{"dev", "2-dev"}},
+		{name: "light nightly", metadata: db.Metadata{Version: 2, Type: db.TypeLight, Channel: db.ChannelNightly},
+			want: []string{"nightly-light", "2-nightly-light"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DefaultTags(tt.metadata))
+		})
+	}
+}
+
+func TestAttest(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestAttest_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	dbPath := filepath.Join(d, "trivy.db")
+	assert.NoError(t, ioutil.WriteFile(dbPath, []byte("bolt"), 0600))
+
+	reg := &registry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+	repository := strings.TrimPrefix(ts.URL, "http://") + "/aquasecurity/trivy-db"
+	client := oci.Client{HTTPClient: ts.Client(), PlainHTTP: true}
+
+	ctx := context.Background()
+	metadata := db.Metadata{Version: db.SchemaVersion, Type: db.TypeFull, UpdatedAt: time.Now()}
+	manifest, err := Publish(ctx, client, repository, dbPath, metadata, []string{"latest"})
+	assert.NoError(t, err)
+
+	statement := []byte(`{"_type":"https://in-toto.io/Statement/v1"}`)
+	attestation, err := Attest(ctx, client, repository, manifest, statement)
+	assert.NoError(t, err)
+
+	// the attestation refers to the DB, which is still the tagged one
+	var got oci.Manifest
+	assert.NoError(t, json.Unmarshal(reg.manifests[attestation.Digest], &got))
+	assert.Equal(t, slsa.MediaType, got.ArtifactType)
+	if assert.NotNil(t, got.Subject) {
+		assert.Equal(t, manifest.Digest, got.Subject.Digest)
+	}
+	if assert.Len(t, got.Layers, 1) {
+		assert.Equal(t, statement, reg.blobs[got.Layers[0].Digest])
+	}
+	assert.Equal(t, reg.manifests["latest"], reg.manifests[manifest.Digest])
+}
@@ -1,28 +1,380 @@
 package pkg
 
 import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"syscall"
+
+	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/pipeline"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	_ "github.com/aquasecurity/trivy-db/pkg/storage/badgerdb"
+	_ "github.com/aquasecurity/trivy-db/pkg/storage/sqlite"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 	"github.com/urfave/cli"
 )
 
 func build(c *cli.Context) error {
+	if shardDir := c.String("shard"); shardDir != "" {
+		return buildShard(c, shardDir)
+	}
+
+	config, err := applyPipeline(c)
+	if err != nil {
+		return err
+	}
+	m, err := startMetrics(c)
+	if err != nil {
+		return err
+	}
+	err = buildDB(c, config, m)
+	m.finish(db.Path(c.String("cache-dir")), err)
+	return err
+}
+
+// buildDB updates the sources of the build into the DB and optimizes it
+func buildDB(c *cli.Context, config pipeline.Config, m *buildMetrics) error {
+	options := config.SourceOptions()
+
+	if c.Bool("strict") && c.Bool("continue-on-error") {
+		return xerrors.New("--strict and --continue-on-error can't be combined")
+	}
+	onError := vulnsrc.ContinueOnError
+	if c.Bool("strict") {
+		onError = vulnsrc.FailOnError
+	}
+
 	cacheDir := c.String("cache-dir")
-	if err := db.Init(cacheDir); err != nil {
+	dryRun := c.Bool("dry-run")
+	checkpoint := c.Bool("checkpoint")
+	if dryRun && (checkpoint || c.String("wal") != "" || c.Int("parallel") > 1) {
+		return xerrors.New("--dry-run can't be combined with --checkpoint, --wal or --parallel, which write next to the DB")
+	}
+	incremental := c.Bool("incremental")
+	if incremental && (c.Bool("light") || c.Int("parallel") > 1) {
+		return xerrors.New("--incremental can't be combined with --light, whose DB doesn't trace the advisories to their files, or --parallel")
+	}
+	if err := db.ValidateChannel(c.String("channel")); err != nil {
 		return err
 	}
+	if c.Bool("low-memory") && (c.Int("workers") > 1 || c.Int("parallel") > 1) {
+		return xerrors.New("--low-memory can't be combined with --workers or --parallel, which hold several sources in memory at once")
+	}
+	if checkpoint {
+		// the DB may have batches of the source the interrupted build was updating
+		if restored, err := db.RestoreSnapshot(cacheDir); err != nil {
+			return err
+		} else if restored {
+			log.Info("Restored the snapshot of the interrupted build")
+		}
+	}
+	var run *db.DryRun
+	if dryRun {
+		var err error
+		if run, err = db.StartDryRun(c.String("backend"), cacheDir); err != nil {
+			return err
+		}
+		defer run.Close()
+	} else if err := db.InitWithDriver(c.String("backend"), cacheDir); err != nil {
+		return err
+	}
+	if err := db.SetEncoding(c.String("encoding")); err != nil {
+		return err
+	}
+	db.SetValidation(c.Bool("validate"))
+	if err := setLowMemory(c); err != nil {
+		return err
+	}
+	if path := c.String("wal"); path != "" {
+		if err := db.OpenWAL(path); err != nil {
+			return err
+		}
+		defer db.CloseWAL()
+	}
+	// a build without checkpoints starts over
+	if !checkpoint {
+		if err := (db.Config{}).ClearCheckpoints(); err != nil {
+			return err
+		}
+	}
 
-	targets := c.String("only-update")
+	if err := addPlugins(c.StringSlice("plugin")); err != nil {
+		return err
+	}
+	only := splitList(c.String("only-update"))
+	// the default of --only-update doesn't have the plugins
+	if len(only) == 0 || !c.IsSet("only-update") {
+		only = append([]string{}, vulnsrc.UpdateList...)
+	}
+	if c.Bool("bdu") {
+		only = append(only, vulnerability.BDU)
+	}
+	if c.Bool("ssvc") {
+		only = append(only, vulnerability.SSVC)
+	}
+	targets, err := vulnsrc.Targets(only, splitList(c.String("skip-update")))
+	if err != nil {
+		return err
+	}
 	light := c.Bool("light")
 	updateInterval := c.Duration("update-interval")
 
-	updater := vulnsrc.NewUpdater(cacheDir, light, updateInterval)
-	if err := updater.Update(strings.Split(targets, ",")); err != nil {
+	ctx, cancel := signalContext()
+	defer cancel()
+	if c.Bool("fetch") {
+		// the sources reading another cache dir read a checkout fetched by someone else
+		var fetched []string
+		for _, target := range targets {
+			if options[target].CacheDir == "" {
+				fetched = append(fetched, target)
+			}
+		}
+		if err = fetchSources(ctx, cacheDir, fetched, c.Int("fetch-depth"), c.StringSlice("mirror")); err != nil {
+			return err
+		}
+	}
+	updater := vulnsrc.NewUpdater(cacheDir, light, updateInterval, c.Duration("valid-for"), c.Duration("source-timeout"),
+		checkpoint).WithSourceOptions(options).WithFailurePolicy(onError).WithObserver(m.observe).WithIncremental(incremental).
+		WithRetries(c.Int("source-retries"), c.Duration("retry-backoff")).WithChannel(c.String("channel")).
+		WithPassObserver(m.observePass)
+	if c.BoolT("preflight") {
+		if targets, err = updater.Preflight(targets, c.Int("preflight-sample")); err != nil {
+			return err
+		}
+	}
+	// the dry run compares the DBs before the optimizations of the artifact
+	if c.Bool("dedup") && run == nil {
+		updater = updater.WithPasses(vulnsrc.NewPass("dedup", db.Config{}.Dedup))
+	}
+	if parallel := c.Int("parallel"); parallel > 1 {
+		shardsDir := filepath.Join(cacheDir, "db", "shards")
+		defer os.RemoveAll(shardsDir)
+		updater = updater.WithShards(shardBuilder(c, shardsDir, options), parallel)
+	} else if workers := c.Int("workers"); workers > 1 {
+		updater = updater.WithWorkers(workers)
+	}
+	if err := updater.Update(ctx, targets); err != nil {
+		return err
+	}
+	// the optimizations of the artifact change nothing the report compares
+	if run != nil {
+		report, err := run.Report()
+		if err != nil {
+			return xerrors.Errorf("failed to compare the DBs: %w", err)
+		}
+		return writeReport(os.Stdout, report, c.String("dry-run-format"))
+	}
+
+	metadata, err := db.Config{}.GetMetadata()
+	if err != nil {
+		return xerrors.Errorf("failed to get the metadata: %w", err)
+	}
+	freshness := metadata.Freshness(staleAfter(c.Duration("stale-after"), config.Sources))
+	m.observeFreshness(freshness)
+	if err = reportFreshness(c.String("freshness-report"), freshness); err != nil {
+		return err
+	}
+
+	if c.Bool("validate") {
+		if err := checkDB(); err != nil {
+			return err
+		}
+	}
+
+	dbc := db.Config{}
+	// the DB may have been compressed by a previous build
+	if c.Bool("compress") {
+		if err := dbc.Compress(); err != nil {
+			return err
+		}
+	} else if err := dbc.Decompress(); err != nil {
+		return err
+	}
+
+	if err := dbc.SetChecksums(); err != nil {
 		return err
 	}
 
+	if mode := c.String("split"); mode != "" {
+		paths, err := dbc.Split(c.String("backend"), mode)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			log.Info("Split DB", "path", path)
+		}
+	}
+
+	if c.BoolT("compact") {
+		if err := db.Close(); err != nil {
+			return err
+		}
+		if err := compact(c.String("backend"), db.Path(cacheDir)); err != nil {
+			return err
+		}
+	}
+	return writeOutputs(db.Path(cacheDir), metadata, config.Outputs)
+}
+
+// addPlugins registers the plugins of --plugin, given as name=path
+func addPlugins(plugins []string) error {
+	for _, p := range plugins {
+		s := strings.SplitN(p, "=", 2)
+		if len(s) != 2 || s[0] == "" || s[1] == "" {
+			return xerrors.Errorf("invalid --plugin %q, expected name=path", p)
+		}
+		if err := vulnsrc.AddPlugin(s[0], s[1]); err != nil {
+			return err
+		}
+	}
 	return nil
+}
+
+// shardBuilder builds each source in a child process running build --shard, which writes a bolt file of its own
+func shardBuilder(c *cli.Context, shardsDir string, options map[string]vulnsrc.SourceOptions) vulnsrc.ShardBuilder {
+	return func(ctx context.Context, source string) (string, error) {
+		exe, err := os.Executable()
+		if err != nil {
+			return "", xerrors.Errorf("failed to find the executable: %w", err)
+		}
+		// a shard left by an interrupted build is rebuilt
+		shardDir := filepath.Join(shardsDir, source)
+		if err = os.RemoveAll(shardDir); err != nil {
+			return "", xerrors.Errorf("failed to remove the previous shard: %w", err)
+		}
+
+		cacheDir, timeout := c.String("cache-dir"), c.Duration("source-timeout")
+		if o := options[source]; o.CacheDir != "" {
+			cacheDir = o.CacheDir
+		}
+		if o := options[source]; o.Timeout != 0 {
+			timeout = o.Timeout
+		}
+		retries := c.Int("source-retries")
+		if o := options[source]; o.Retries != 0 {
+			retries = o.Retries
+		}
+		args := []string{"--log-level", c.GlobalString("log-level"), "--log-format", c.GlobalString("log-format"),
+			"build", "--shard", shardDir, "--only-update", source,
+			"--cache-dir", cacheDir, "--encoding", c.String("encoding"),
+			"--source-timeout", timeout.String(), "--source-retries", strconv.Itoa(retries),
+			"--retry-backoff", c.Duration("retry-backoff").String()}
+		if c.Bool("validate") {
+			args = append(args, "--validate")
+		}
+		for _, p := range c.StringSlice("plugin") {
+			args = append(args, "--plugin", p)
+		}
+		if c.Bool("low-memory") {
+			args = append(args, "--low-memory", "--batch-size", strconv.Itoa(c.Int("batch-size")))
+		}
+		cmd := exec.CommandContext(ctx, exe, args...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err = cmd.Run(); err != nil {
+			return "", xerrors.Errorf("failed to build the shard: %w", err)
+		}
+		return db.Path(shardDir), nil
+	}
+}
 
+// lowMemoryGCPercent lets the heap grow by a quarter between two collections with --low-memory, instead of doubling
+const lowMemoryGCPercent = 25
+
+// setLowMemory bounds what the build holds in memory with --low-memory: the sources commit every --batch-size
+// inputs instead of at the end of their walk, and the garbage collector runs more often
+func setLowMemory(c *cli.Context) error {
+	if !c.Bool("low-memory") {
+		db.SetBatchSize(0)
+		return nil
+	}
+	size := c.Int("batch-size")
+	if size < 1 {
+		return xerrors.Errorf("--batch-size is at least 1: %d", size)
+	}
+	db.SetBatchSize(size)
+	debug.SetGCPercent(lowMemoryGCPercent)
+	return nil
+}
+
+// buildShard updates the source of --only-update into a new DB in shardDir, for the build which started it
+func buildShard(c *cli.Context, shardDir string) error {
+	if err := db.Init(shardDir); err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := db.SetEncoding(c.String("encoding")); err != nil {
+		return err
+	}
+	db.SetValidation(c.Bool("validate"))
+	if err := setLowMemory(c); err != nil {
+		return err
+	}
+	if err := addPlugins(c.StringSlice("plugin")); err != nil {
+		return err
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	updater := vulnsrc.NewUpdater(c.String("cache-dir"), false, 0, 0, c.Duration("source-timeout"), false).
+		WithRetries(c.Int("source-retries"), c.Duration("retry-backoff"))
+	return updater.UpdateShard(ctx, c.String("only-update"))
+}
+
+// splitList splits a comma-separated flag, ignoring spaces and empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// signalContext returns a context canceled on SIGINT or SIGTERM, so that e.g. a build stops after rolling back
+// the transaction in progress
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-sigCh:
+			log.Warn("Canceling on the signal", "signal", sig.String())
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigCh)
+	}()
+	return ctx, cancel
+}
+
+// compact reclaims the pages left free by the buckets rewritten during the build
+func compact(driverName, path string) error {
+	before, err := os.Stat(path)
+	if err != nil {
+		return xerrors.Errorf("failed to stat the DB: %w", err)
+	}
+	if err = storage.Compact(driverName, path); err == storage.ErrCompactNotSupported {
+		log.Info("Skipping compaction, not supported by the backend", "backend", driverName)
+		return nil
+	} else if err != nil {
+		return xerrors.Errorf("failed to compact the DB: %w", err)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		return xerrors.Errorf("failed to stat the DB: %w", err)
+	}
+	log.Info("Compacted the DB", "before", before.Size(), "after", after.Size())
+	return nil
 }
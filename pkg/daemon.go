@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/daemon"
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/pipeline"
+)
+
+func runDaemon(c *cli.Context) error {
+	d, err := daemon.New(c.String("schedule"), rebuild(c))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	var srv *http.Server
+	if addr := c.String("listen"); addr != "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return xerrors.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		srv = &http.Server{Handler: d}
+		go func() {
+			if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+				log.Error("Failed to serve the health endpoints", log.Err(err))
+			}
+		}()
+		log.Info("Serving the health endpoints", "address", l.Addr().String())
+	}
+
+	d.Run(ctx, c.BoolT("run-on-start"))
+	if srv != nil {
+		_ = srv.Close()
+	}
+	log.Info("Stopped the daemon")
+	return nil
+}
+
+// rebuild returns the job of the daemon, which builds the DB in a child process running build with the
+// arguments after --, and publishes the outputs of the pipeline config to its publishers
+func rebuild(c *cli.Context) daemon.Job {
+	return func(ctx context.Context) error {
+		exe, err := os.Executable()
+		if err != nil {
+			return xerrors.Errorf("failed to find the executable: %w", err)
+		}
+		args := []string{"--log-level", c.GlobalString("log-level"), "--log-format", c.GlobalString("log-format"),
+			"build", "--cache-dir", c.String("cache-dir")}
+		configPath := c.String("config")
+		if configPath != "" {
+			args = append(args, "--config", configPath)
+		}
+		args = append(args, c.Args()...)
+		if err = runChild(ctx, exe, args); err != nil {
+			return xerrors.Errorf("failed to build the DB: %w", err)
+		}
+
+		if configPath == "" {
+			return nil
+		}
+		// the config is read again, so that it can change between runs
+		config, err := pipeline.Load(configPath)
+		if err != nil {
+			return err
+		}
+		if len(config.Publishers) == 0 || len(config.Outputs) == 0 {
+			return nil
+		}
+		var paths []string
+		for _, output := range config.Outputs {
+			paths = append(paths, output.Path)
+		}
+		// the outputs are published to the channel of the DB, which the arguments of the build may set
+		metadata, err := db.ReadMetadata(config.Build.Backend, db.Path(c.String("cache-dir")))
+		if err != nil {
+			return xerrors.Errorf("failed to read the metadata of the DB: %w", err)
+		}
+		return publishAssets(ctx, configPath, metadata.ReleaseChannel(), paths)
+	}
+}
+
+// runChild runs the command until it exits, sending it SIGTERM once ctx is canceled so that a build rolls back
+// the transaction in progress
+func runChild(ctx context.Context, name string, args []string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,135 @@
+// Package daemon runs a job, e.g. the rebuild and the publication of the DB, on a cron schedule, and reports
+// its health and readiness over HTTP
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+// Job is a run of the daemon, canceled with ctx on shutdown
+type Job func(ctx context.Context) error
+
+// Status is the state of the runs, served on /status
+type Status struct {
+	Running bool
+	Runs    int
+	// Failures counts the runs failed since the last successful one
+	Failures    int
+	LastRun     *time.Time `json:",omitempty"`
+	LastSuccess *time.Time `json:",omitempty"`
+	LastError   string     `json:",omitempty"`
+	NextRun     *time.Time `json:",omitempty"`
+}
+
+// Daemon runs a job on a schedule, one run at a time, a run lasting past the scheduled time of the next one
+// delaying it to the following one
+type Daemon struct {
+	schedule cron.Schedule
+	job      Job
+	now      func() time.Time
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New parses the schedule, a cron expression, e.g. "0 */6 * * *", or a descriptor, e.g. "@every 6h"
+func New(spec string, job Job) (*Daemon, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return &Daemon{schedule: schedule, job: job, now: time.Now}, nil
+}
+
+// Run runs the job on the schedule, and right away with runNow, until ctx is canceled. A failed run isn't
+// retried before the next scheduled one.
+func (d *Daemon) Run(ctx context.Context, runNow bool) {
+	if runNow {
+		d.run(ctx)
+	}
+	for ctx.Err() == nil {
+		next := d.schedule.Next(d.now())
+		d.update(func(s *Status) {
+			s.NextRun = &next
+		})
+		log.Info("Scheduled the next run", "at", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(next.Sub(d.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		d.run(ctx)
+	}
+}
+
+func (d *Daemon) run(ctx context.Context) {
+	start := d.now()
+	d.update(func(s *Status) {
+		s.Running, s.LastRun, s.NextRun = true, &start, nil
+	})
+	log.Info("Starting a run")
+
+	err := d.job(ctx)
+	d.update(func(s *Status) {
+		s.Running = false
+		s.Runs++
+		if err != nil {
+			s.Failures++
+			s.LastError = err.Error()
+			return
+		}
+		end := d.now()
+		s.Failures, s.LastSuccess, s.LastError = 0, &end, ""
+	})
+	if err != nil {
+		log.Error("The run failed", log.Err(err), "elapsed", d.now().Sub(start).String())
+		return
+	}
+	log.Info("The run succeeded", "elapsed", d.now().Sub(start).String())
+}
+
+func (d *Daemon) update(fn func(*Status)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(&d.status)
+}
+
+// Status returns the state of the runs
+func (d *Daemon) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// ServeHTTP serves /healthz, OK as long as the daemon is up, /readyz, OK once a run succeeded so that there
+// is a DB to serve, and /status, the Status
+func (d *Daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := d.Status()
+	switch r.URL.Path {
+	case "/healthz":
+		w.WriteHeader(http.StatusOK)
+	case "/readyz":
+		if status.LastSuccess == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case "/status":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(status)
+	default:
+		http.NotFound(w, r)
+	}
+}
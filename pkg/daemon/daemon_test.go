@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "cron expression", spec: "0 */6 * * *"},
+		{name: "descriptor", spec: "@every 6h"},
+		{name: "invalid", spec: "every 6 hours", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.spec, func(context.Context) error { return nil })
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDaemon_Run(t *testing.T) {
+	errFailed := xerrors.New("failed")
+	tests := []struct {
+		name      string
+		results   []error
+		wantReady bool
+		want      Status
+	}{
+		{
+			name:      "success",
+			results:   []error{nil},
+			wantReady: true,
+			want:      Status{Runs: 1},
+		},
+		{
+			name:    "failure",
+			results: []error{errFailed},
+			want:    Status{Runs: 1, Failures: 1, LastError: "failed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var runs int
+			d, err := New("@every 1h", func(context.Context) error {
+				err := tt.results[runs]
+				runs++
+				// stops the daemon once the runs are over
+				if runs == len(tt.results) {
+					cancel()
+				}
+				return err
+			})
+			assert.NoError(t, err)
+
+			ts := httptest.NewServer(d)
+			defer ts.Close()
+			assertStatusCode(t, ts.URL+"/readyz", http.StatusServiceUnavailable)
+
+			d.Run(ctx, true)
+			got := d.Status()
+			assert.Equal(t, tt.wantReady, got.LastSuccess != nil)
+			assert.NotNil(t, got.LastRun)
+			got.LastRun, got.LastSuccess, got.NextRun = nil, nil, nil
+			assert.Equal(t, tt.want, got)
+
+			assertStatusCode(t, ts.URL+"/healthz", http.StatusOK)
+			if tt.wantReady {
+				assertStatusCode(t, ts.URL+"/readyz", http.StatusOK)
+			} else {
+				assertStatusCode(t, ts.URL+"/readyz", http.StatusServiceUnavailable)
+			}
+
+			resp, err := http.Get(ts.URL + "/status")
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			var status Status
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+			assert.Equal(t, tt.want.Runs, status.Runs)
+			assert.Equal(t, tt.want.LastError, status.LastError)
+		})
+	}
+}
+
+func assertStatusCode(t *testing.T, url string, want int) {
+	resp, err := http.Get(url)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, want, resp.StatusCode, url)
+}
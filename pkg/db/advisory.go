@@ -1,42 +1,125 @@
 package db
 
 import (
-	"encoding/json"
-
 	"github.com/aquasecurity/trivy-db/pkg/types"
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 )
 
-func (dbc Config) PutAdvisory(tx *bolt.Tx, source, pkgName, cveID string, advisory interface{}) error {
-	root, err := tx.CreateBucketIfNotExists([]byte(source))
+// PutAdvisory stores an advisory in the bucket of the platform, e.g. debian 9, of the data source of dbc
+func (dbc Config) PutAdvisory(tx Tx, source, pkgName, cveID string, advisory interface{}) error {
+	pkgName = normalizeSourcePackage(source, pkgName)
+	advisory, err := beforePutAdvisory(source, pkgName, cveID, advisory)
+	if err == nil {
+		err = dbc.putAdvisory(tx, source, pkgName, cveID, advisory)
+	}
+	afterPutAdvisory(source, pkgName, cveID, err)
+	return err
+}
+
+func (dbc Config) putAdvisory(tx Tx, source, pkgName, cveID string, advisory interface{}) error {
+	if err := validate(source, advisory, pkgName, cveID); err != nil {
+		return err
+	}
+	bucket := dbc.advisoryBucket(source)
+	if tx.Bucket([]byte(bucket)) == nil {
+		id, _ := SplitAdvisoryBucket(bucket)
+		if err := dbc.putDataSource(tx, id); err != nil {
+			return xerrors.Errorf("failed to save the data source: %w", err)
+		}
+	}
+	root, err := tx.CreateBucketIfNotExists([]byte(bucket))
 	if err != nil {
 		return xerrors.Errorf("failed to create a bucket: %w", err)
 	}
-	return dbc.put(root, pkgName, cveID, advisory)
+	v, err := Marshal(advisory)
+	if err != nil {
+		return err
+	}
+	if err = dbc.put(root, pkgName, cveID, v); err != nil {
+		return err
+	}
+	trackWrite(bucket, pkgName, cveID)
+	return dbc.putAffectedPackage(tx, bucket, pkgName, cveID)
 }
 
-func (dbc Config) ForEachAdvisory(source, pkgName string) (value map[string][]byte, err error) {
-	return dbc.forEach(source, pkgName)
+// ForEachAdvisory streams the raw values, which are decoded by Unmarshal, without loading the whole bucket,
+// with the data source of their bucket, nil if it has none. A value is only valid during the callback.
+// The source is a platform, e.g. debian 9, or an advisory bucket.
+func (dbc Config) ForEachAdvisory(source, pkgName string,
+	fn func(ds *types.DataSource, vulnID string, value []byte) error) error {
+	beforeGetAdvisories(source, pkgName)
+	return afterForEachAdvisory(source, pkgName, dbc.forEachAdvisory(source, pkgName, fn))
 }
 
-func (dbc Config) GetAdvisories(source, pkgName string) ([]types.Advisory, error) {
-	advisories, err := dbc.ForEachAdvisory(source, pkgName)
+func (dbc Config) forEachAdvisory(source, pkgName string,
+	fn func(ds *types.DataSource, vulnID string, value []byte) error) error {
+	err := db.View(func(tx Tx) error {
+		for _, bucket := range dbc.advisoryBuckets(tx, source) {
+			ds, err := bucketDataSource(tx, bucket)
+			if err != nil {
+				return err
+			}
+			_, platform := SplitAdvisoryBucket(bucket)
+			err = iterateNested(tx, bucket, normalizeSourcePackage(platform, pkgName), func(k, v []byte) error {
+				return fn(ds, string(k), v)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, xerrors.Errorf("error in advisory foreach: %w", err)
-	}
-	if len(advisories) == 0 {
-		return nil, nil
+		return xerrors.Errorf("failed to get the advisories: %w", err)
 	}
+	return nil
+}
 
+func (dbc Config) GetAdvisories(source, pkgName string) ([]types.Advisory, error) {
+	beforeGetAdvisories(source, pkgName)
+	advisories, err := dbc.getAdvisories(source, pkgName)
+	return afterGetAdvisories(source, pkgName, advisories, err)
+}
+
+func (dbc Config) getAdvisories(source, pkgName string) ([]types.Advisory, error) {
 	var results []types.Advisory
-	for vulnID, v := range advisories {
+	err := dbc.forEachAdvisory(source, pkgName, func(_ *types.DataSource, vulnID string, v []byte) error {
 		var advisory types.Advisory
-		if err = json.Unmarshal(v, &advisory); err != nil {
-			return nil, xerrors.Errorf("failed to unmarshal advisory JSON: %w", err)
+		if err := Unmarshal(v, &advisory); err != nil {
+			return xerrors.Errorf("failed to unmarshal advisory: %w", err)
 		}
 		advisory.VulnerabilityID = vulnID
 		results = append(results, advisory)
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("error in advisory foreach: %w", err)
+	}
+	return results, nil
+}
+
+// DataSourceAdvisory is an advisory with the data source of the bucket it was read from, nil if it has none,
+// e.g. a bucket of a DB built before the data sources
+type DataSourceAdvisory struct {
+	types.Advisory
+	DataSource *types.DataSource `json:",omitempty"`
+}
+
+// GetAdvisoriesWithDataSource returns the advisories of GetAdvisories with their data source, for the lookups
+// of every data source of a platform to tell which feed an advisory comes from
+func (dbc Config) GetAdvisoriesWithDataSource(source, pkgName string) ([]DataSourceAdvisory, error) {
+	var results []DataSourceAdvisory
+	err := dbc.ForEachAdvisory(source, pkgName, func(ds *types.DataSource, vulnID string, v []byte) error {
+		advisory := DataSourceAdvisory{DataSource: ds}
+		if err := Unmarshal(v, &advisory.Advisory); err != nil {
+			return xerrors.Errorf("failed to unmarshal advisory: %w", err)
+		}
+		advisory.VulnerabilityID = vulnID
+		results = append(results, advisory)
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("error in advisory foreach: %w", err)
 	}
 	return results, nil
 }
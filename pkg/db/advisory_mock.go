@@ -0,0 +1,93 @@
+package db
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+var _ AdvisoryStore = &MockAdvisoryStore{}
+
+type MockAdvisoryStore struct {
+	mock.Mock
+}
+
+func (_m *MockAdvisoryStore) PutAdvisory(a Tx, b, c, d string, e interface{}) error {
+	ret := _m.Called(a, b, c, d, e)
+	return ret.Error(0)
+}
+
+func (_m *MockAdvisoryStore) ForEachAdvisory(a, b string, c func(*types.DataSource, string, []byte) error) error {
+	ret := _m.Called(a, b, c)
+	return ret.Error(0)
+}
+
+func (_m *MockAdvisoryStore) GetAdvisories(a, b string) ([]types.Advisory, error) {
+	ret := _m.Called(a, b)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil, ret.Error(1)
+	}
+	advisories, ok := ret0.([]types.Advisory)
+	if !ok {
+		return nil, ret.Error(1)
+	}
+	return advisories, ret.Error(1)
+}
+
+func (_m *MockAdvisoryStore) GetAdvisoriesWithDataSource(a, b string) ([]DataSourceAdvisory, error) {
+	ret := _m.Called(a, b)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil, ret.Error(1)
+	}
+	advisories, ok := ret0.([]DataSourceAdvisory)
+	if !ok {
+		return nil, ret.Error(1)
+	}
+	return advisories, ret.Error(1)
+}
+
+func (_m *MockAdvisoryStore) GetAdvisoriesForVersion(a, b, c string, d Comparer) ([]types.Advisory, error) {
+	ret := _m.Called(a, b, c, d)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil, ret.Error(1)
+	}
+	advisories, ok := ret0.([]types.Advisory)
+	if !ok {
+		return nil, ret.Error(1)
+	}
+	return advisories, ret.Error(1)
+}
+
+func (_m *MockAdvisoryStore) GetAdvisoriesByPURL(a string) ([]types.Advisory, error) {
+	ret := _m.Called(a)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil, ret.Error(1)
+	}
+	advisories, ok := ret0.([]types.Advisory)
+	if !ok {
+		return nil, ret.Error(1)
+	}
+	return advisories, ret.Error(1)
+}
+
+func (_m *MockAdvisoryStore) GetAffectedPackages(a string) ([]types.AffectedPackage, error) {
+	ret := _m.Called(a)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil, ret.Error(1)
+	}
+	pkgs, ok := ret0.([]types.AffectedPackage)
+	if !ok {
+		return nil, ret.Error(1)
+	}
+	return pkgs, ret.Error(1)
+}
+
+func (_m *MockAdvisoryStore) DeleteAffectedPackageBucket() error {
+	ret := _m.Called()
+	return ret.Error(0)
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_ForEachAdvisory(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_ForEachAdvisory_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		for _, cveID := range []string{"CVE-2019-0001", "CVE-2019-0002", "CVE-2019-0003"} {
+			if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", cveID, types.Advisory{FixedVersion: "1.0.0"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	t.Run("all values", func(t *testing.T) {
+		var got []string
+		err := dbc.ForEachAdvisory("alpine 3.10", "openssl", func(_ *types.DataSource, vulnID string, v []byte) error {
+			assert.Equal(t, `{"FixedVersion":"1.0.0"}`, string(v))
+			got = append(got, vulnID)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"CVE-2019-0001", "CVE-2019-0002", "CVE-2019-0003"}, got)
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		errStop := xerrors.New("stop")
+		var got []string
+		err := dbc.ForEachAdvisory("alpine 3.10", "openssl", func(_ *types.DataSource, vulnID string, _ []byte) error {
+			got = append(got, vulnID)
+			return errStop
+		})
+		assert.True(t, xerrors.Is(err, errStop), err)
+		assert.Equal(t, []string{"CVE-2019-0001"}, got)
+	})
+
+	t.Run("unknown package", func(t *testing.T) {
+		err := dbc.ForEachAdvisory("alpine 3.10", "curl", func(*types.DataSource, string, []byte) error {
+			t.Fatal("no advisories expected")
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+}
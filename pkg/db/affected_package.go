@@ -0,0 +1,130 @@
+package db
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+const (
+	// affectedPackageBucket indexes advisories by vulnerability: CVE-ID => source/package => AffectedPackage
+	affectedPackageBucket = "affected-package"
+)
+
+// putAffectedPackage indexes an advisory of an advisory bucket, e.g. debian::debian 9
+func (dbc Config) putAffectedPackage(tx Tx, bucket, pkgName, cveID string) error {
+	root, err := tx.CreateBucketIfNotExists([]byte(affectedPackageBucket))
+	if err != nil {
+		return xerrors.Errorf("failed to create a bucket: %w", err)
+	}
+	id, platform := SplitAdvisoryBucket(bucket)
+	ecosystem, release := splitSource(platform)
+	v, err := Marshal(types.AffectedPackage{
+		DataSource: id,
+		Source:     platform,
+		Ecosystem:  ecosystem,
+		Release:    release,
+		Package:    pkgName,
+	})
+	if err != nil {
+		return err
+	}
+	return dbc.put(root, cveID, bucket+"/"+pkgName, v)
+}
+
+// GetAffectedPackages returns the packages with an advisory for the vulnerability
+func (dbc Config) GetAffectedPackages(cveID string) ([]types.AffectedPackage, error) {
+	values, err := dbc.forEach(affectedPackageBucket, cveID)
+	if err != nil {
+		return nil, xerrors.Errorf("error in affected package foreach: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	var pkgs []types.AffectedPackage
+	for _, v := range values {
+		var pkg types.AffectedPackage
+		if err = Unmarshal(v, &pkg); err != nil {
+			return nil, xerrors.Errorf("failed to unmarshal affected package: %w", err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].Source != pkgs[j].Source {
+			return pkgs[i].Source < pkgs[j].Source
+		}
+		if pkgs[i].DataSource != pkgs[j].DataSource {
+			return pkgs[i].DataSource < pkgs[j].DataSource
+		}
+		return pkgs[i].Package < pkgs[j].Package
+	})
+	return pkgs, nil
+}
+
+// AffectedAdvisory is an advisory with the package and the bucket it is stored for
+type AffectedAdvisory struct {
+	types.AffectedPackage
+	Advisory types.Advisory
+}
+
+// GetAdvisoriesByCVE returns the advisories of every package the vulnerability affects, in the order of
+// GetAffectedPackages, found by the affected package index
+func (dbc Config) GetAdvisoriesByCVE(cveID string) ([]AffectedAdvisory, error) {
+	pkgs, err := dbc.GetAffectedPackages(cveID)
+	if err != nil {
+		return nil, err
+	}
+
+	var advisories []AffectedAdvisory
+	err = db.View(func(tx Tx) error {
+		for _, pkg := range pkgs {
+			b := tx.Bucket([]byte(affectedBucket(pkg)))
+			if b == nil || b.Bucket([]byte(pkg.Package)) == nil {
+				continue
+			}
+			v, err := decode(b.Bucket([]byte(pkg.Package)).Get([]byte(cveID)))
+			if err != nil {
+				return err
+			} else if v == nil {
+				continue
+			}
+			advisory := AffectedAdvisory{AffectedPackage: pkg}
+			if err = Unmarshal(v, &advisory.Advisory); err != nil {
+				return xerrors.Errorf("failed to unmarshal advisory: %w", err)
+			}
+			advisory.Advisory.VulnerabilityID = cveID
+			advisories = append(advisories, advisory)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get the advisories of %s: %w", cveID, err)
+	}
+	return advisories, nil
+}
+
+// affectedBucket returns the advisory bucket of an affected package, e.g. debian::debian 9
+func affectedBucket(pkg types.AffectedPackage) string {
+	if pkg.DataSource == "" {
+		return pkg.Source
+	}
+	return AdvisoryBucket(pkg.DataSource, pkg.Source)
+}
+
+// DeleteAffectedPackageBucket deletes the index, which light DBs don't have
+func (dbc Config) DeleteAffectedPackageBucket() error {
+	return dbc.deleteBucketIfExists(affectedPackageBucket)
+}
+
+// splitSource splits an OS bucket like "debian 9" into the ecosystem and the release
+func splitSource(source string) (ecosystem, release string) {
+	i := strings.LastIndex(source, " ")
+	if i <= 0 || i == len(source)-1 || source[i+1] < '0' || source[i+1] > '9' {
+		return source, ""
+	}
+	return source[:i], source[i+1:]
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_GetAffectedPackages(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_GetAffectedPackages_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		advisories := []struct {
+			source  string
+			pkgName string
+			cveID   string
+		}{
+			{source: "debian 9", pkgName: "openssl", cveID: "CVE-2019-0001"},
+			{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0001"},
+			{source: "npm::Node.js Security Working Group", pkgName: "lodash", cveID: "CVE-2019-0001"},
+			{source: "debian 9", pkgName: "curl", cveID: "CVE-2019-0002"},
+		}
+		for _, a := range advisories {
+			if err := dbc.PutAdvisory(tx, a.source, a.pkgName, a.cveID, types.Advisory{FixedVersion: "1.0.0"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		cveID string
+		want  []types.AffectedPackage
+	}{
+		{
+			name:  "multiple sources",
+			cveID: "CVE-2019-0001",
+			want: []types.AffectedPackage{
+				{DataSource: "alpine", Source: "alpine 3.10", Ecosystem: "alpine", Release: "3.10", Package: "openssl"},
+				{DataSource: "debian", Source: "debian 9", Ecosystem: "debian", Release: "9", Package: "openssl"},
+				{DataSource: "npm", Source: "npm::Node.js Security Working Group", Ecosystem: "npm::Node.js Security Working Group",
+					Package: "lodash"},
+			},
+		},
+		{
+			name:  "single source",
+			cveID: "CVE-2019-0002",
+			want: []types.AffectedPackage{
+				{DataSource: "debian", Source: "debian 9", Ecosystem: "debian", Release: "9", Package: "curl"},
+			},
+		},
+		{
+			name:  "unknown vulnerability",
+			cveID: "CVE-2019-9999",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dbc.GetAffectedPackages(tt.cveID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_GetAdvisoriesByCVE(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_GetAdvisoriesByCVE_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	err = Config{}.BatchUpdate(context.Background(), func(tx Tx) error {
+		if err := (Config{}).PutAdvisory(tx, "debian 9", "openssl", "CVE-2019-0001",
+			types.Advisory{FixedVersion: "1.1.0l-1"}); err != nil {
+			return err
+		}
+		if err := (Config{DataSource: "debian-oval"}).PutAdvisory(tx, "debian 9", "openssl", "CVE-2019-0001",
+			types.Advisory{FixedVersion: "1.1.0k-1"}); err != nil {
+			return err
+		}
+		return (Config{}).PutAdvisory(tx, "npm::Node.js Security Working Group", "lodash", "CVE-2019-0001",
+			types.Advisory{VulnerableVersions: []string{"< 4.17.12"}})
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		cveID string
+		want  []AffectedAdvisory
+	}{
+		{
+			name:  "multiple buckets",
+			cveID: "CVE-2019-0001",
+			want: []AffectedAdvisory{
+				{
+					AffectedPackage: types.AffectedPackage{DataSource: "debian", Source: "debian 9", Ecosystem: "debian",
+						Release: "9", Package: "openssl"},
+					Advisory: types.Advisory{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.0l-1"},
+				},
+				{
+					AffectedPackage: types.AffectedPackage{DataSource: "debian-oval", Source: "debian 9", Ecosystem: "debian",
+						Release: "9", Package: "openssl"},
+					Advisory: types.Advisory{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.0k-1"},
+				},
+				{
+					AffectedPackage: types.AffectedPackage{DataSource: "npm", Source: "npm::Node.js Security Working Group",
+						Ecosystem: "npm::Node.js Security Working Group", Package: "lodash"},
+					Advisory: types.Advisory{VulnerabilityID: "CVE-2019-0001", VulnerableVersions: []string{"< 4.17.12"}},
+				},
+			},
+		},
+		{
+			name:  "unknown vulnerability",
+			cveID: "CVE-2019-9999",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Config{}.GetAdvisoriesByCVE(tt.cveID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
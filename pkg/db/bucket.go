@@ -0,0 +1,121 @@
+package db
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/version"
+)
+
+// Bucket is the name of a root bucket of the DB other than the advisory buckets, for the tools reading the DB
+type Bucket string
+
+const (
+	MetadataBucket            Bucket = metadataBucket
+	VulnerabilityBucket       Bucket = vulnerabilityBucket
+	VulnerabilityDetailBucket Bucket = vulnerabilityDetailBucket
+	SeverityBucket            Bucket = severityBucket
+	SSVCBucket                Bucket = ssvcBucket
+	AffectedPackageBucket     Bucket = affectedPackageBucket
+	CPEBucket                 Bucket = cpeBucket
+	DataSourceBucket          Bucket = dataSourceBucket
+	ProvenanceBucket          Bucket = provenanceBucket
+)
+
+// Ecosystem is what the sources of the advisories of the releases of an OS start with, e.g. amazon linux for
+// amazon linux 2. The sources of the language ecosystems have no release, e.g. vulnerability.RubySec.
+type Ecosystem string
+
+const (
+	Alpine      Ecosystem = "alpine"
+	AmazonLinux Ecosystem = "amazon linux"
+	Debian      Ecosystem = "debian"
+	DebianOVAL  Ecosystem = "debian oval"
+	OracleLinux Ecosystem = "Oracle Linux"
+	RedHat      Ecosystem = "Red Hat Enterprise Linux"
+	Ubuntu      Ecosystem = "ubuntu"
+)
+
+// Source returns the source of the advisories of a release, e.g. amazon linux 2 for 2
+func (e Ecosystem) Source(release string) string {
+	return string(e) + " " + release
+}
+
+// AdvisoryBucket returns the name of the bucket of the advisories of a data source for a source, e.g.
+// alpine::alpine 3.10 for the data source alpine and alpine 3.10
+func AdvisoryBucket(dataSource, source string) string {
+	return dataSource + dataSourceSeparator + source
+}
+
+// SplitAdvisoryBucket splits the name of an advisory bucket into the data source ID and the source, which may
+// have the separator too. The data source is empty for a bucket without one.
+func SplitAdvisoryBucket(name string) (dataSource, source string) {
+	i := strings.Index(name, dataSourceSeparator)
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+len(dataSourceSeparator):]
+}
+
+// ListSources returns the sources the DB has advisories of, e.g. alpine 3.10 or ruby-advisory-db, of the data
+// source of dbc, or of every data source when dbc has none
+func (dbc Config) ListSources() ([]string, error) {
+	seen := map[string]bool{}
+	var sources []string
+	err := db.View(func(tx Tx) error {
+		return tx.ForEach(func(name []byte, _ storage.Bucket) error {
+			if utils.StringInSlice(string(name), nonAdvisoryBuckets) {
+				return nil
+			}
+			id, source := SplitAdvisoryBucket(string(name))
+			if dbc.DataSource != "" && id != dbc.DataSource || seen[source] {
+				return nil
+			}
+			seen[source] = true
+			sources = append(sources, source)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to list the sources: %w", err)
+	}
+	sort.Strings(sources)
+	return sources, nil
+}
+
+// ListReleases returns the releases of an ecosystem the DB has advisories of, e.g. 1 and 2 of amazon linux,
+// oldest first, the named ones like edge of alpine last
+func (dbc Config) ListReleases(ecosystem Ecosystem) ([]string, error) {
+	sources, err := dbc.ListSources()
+	if err != nil {
+		return nil, err
+	}
+	// a release isn't always a number, e.g. alpine edge, but has no space, unlike e.g. debian oval 9 of debian
+	prefix := ecosystem.Source("")
+	var releases []string
+	for _, source := range sources {
+		if len(source) <= len(prefix) || !strings.EqualFold(source[:len(prefix)], prefix) {
+			continue
+		}
+		if release := source[len(prefix):]; !strings.Contains(release, " ") {
+			releases = append(releases, release)
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		if ni, nj := numbered(releases[i]), numbered(releases[j]); ni != nj {
+			return ni
+		}
+		// rpmvercmp compares the numbers of releases like 3.9 and 3.10 as numbers
+		c, _ := version.RPM.Compare(releases[i], releases[j])
+		return c < 0
+	})
+	return releases, nil
+}
+
+func numbered(release string) bool {
+	return release != "" && release[0] >= '0' && release[0] <= '9'
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestAdvisoryBucket(t *testing.T) {
+	tests := []struct {
+		name           string
+		dataSource     string
+		source         string
+		wantBucket     string
+		wantDataSource string
+		wantSource     string
+	}{
+		{
+			name:           "release of an OS",
+			dataSource:     "amazon",
+			source:         AmazonLinux.Source("2"),
+			wantBucket:     "amazon::amazon linux 2",
+			wantDataSource: "amazon",
+			wantSource:     "amazon linux 2",
+		},
+		{
+			name:           "source with the separator",
+			dataSource:     "ghsa",
+			source:         "npm::GitHub Advisory Database",
+			wantBucket:     "ghsa::npm::GitHub Advisory Database",
+			wantDataSource: "ghsa",
+			wantSource:     "npm::GitHub Advisory Database",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := AdvisoryBucket(tt.dataSource, tt.source)
+			assert.Equal(t, tt.wantBucket, bucket)
+			dataSource, source := SplitAdvisoryBucket(bucket)
+			assert.Equal(t, tt.wantDataSource, dataSource)
+			assert.Equal(t, tt.wantSource, source)
+		})
+	}
+
+	dataSource, source := SplitAdvisoryBucket("alpine 3.10")
+	assert.Equal(t, "", dataSource)
+	assert.Equal(t, "alpine 3.10", source)
+}
+
+func TestConfig_ListSources(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_ListSources_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	assert.NoError(t, db.Update(func(tx Tx) error {
+		puts := []struct {
+			dataSource string
+			source     string
+		}{
+			{source: Alpine.Source("3.9")},
+			{source: Alpine.Source("3.10")},
+			{source: Alpine.Source("edge")},
+			{source: Debian.Source("10")},
+			{source: DebianOVAL.Source("9")},
+			{source: RedHat.Source("8")},
+			{dataSource: "redhat-oval", source: RedHat.Source("7")},
+			{source: "ruby-advisory-db"},
+		}
+		for _, p := range puts {
+			dbc := Config{DataSource: p.dataSource}
+			if err := dbc.PutAdvisory(tx, p.source, "openssl", "CVE-2019-0001", types.Advisory{}); err != nil {
+				return err
+			}
+		}
+		return Config{}.PutVulnerabilityDetail(tx, "CVE-2019-0001", "nvd", types.VulnerabilityDetail{})
+	}))
+
+	tests := []struct {
+		name       string
+		dataSource string
+		ecosystem  Ecosystem
+		want       []string
+		wantAll    []string
+	}{
+		{
+			name:      "releases in version order",
+			ecosystem: Alpine,
+			want:      []string{"3.9", "3.10", "edge"},
+			wantAll: []string{"Red Hat Enterprise Linux 7", "Red Hat Enterprise Linux 8", "alpine 3.10",
+				"alpine 3.9", "alpine edge", "debian 10", "debian oval 9", "ruby-advisory-db"},
+		},
+		{
+			name:      "not the releases of another ecosystem with the same prefix",
+			ecosystem: Debian,
+			want:      []string{"10"},
+		},
+		{
+			name:       "data source",
+			dataSource: "redhat-oval",
+			ecosystem:  RedHat,
+			want:       []string{"7"},
+			wantAll:    []string{"Red Hat Enterprise Linux 7"},
+		},
+		{
+			name:      "releases of every data source",
+			ecosystem: RedHat,
+			want:      []string{"7", "8"},
+		},
+		{
+			name:      "ecosystem without advisories",
+			ecosystem: Ubuntu,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbc := Config{DataSource: tt.dataSource}
+			if tt.wantAll != nil {
+				sources, err := dbc.ListSources()
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantAll, sources)
+			}
+			releases, err := dbc.ListReleases(tt.ecosystem)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, releases)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// the release channels a DB is built for, which clients pin to. A DB without a channel is a stable one.
+const (
+	ChannelDev     = "dev"     // e.g. built on every commit
+	ChannelNightly = "nightly" // e.g. built every night
+	ChannelStable  = "stable"  // e.g. built every week, what clients download by default
+)
+
+var Channels = []string{ChannelDev, ChannelNightly, ChannelStable}
+
+// ValidateChannel checks channel is one of Channels
+func ValidateChannel(channel string) error {
+	for _, c := range Channels {
+		if channel == c {
+			return nil
+		}
+	}
+	return xerrors.Errorf("unknown channel %q, one of %s", channel, strings.Join(Channels, ", "))
+}
+
+// IsStable tells whether channel is the stable one, which publishing keeps where the DBs without channels went
+func IsStable(channel string) bool {
+	return channel == "" || channel == ChannelStable
+}
+
+// ReleaseChannel returns the channel the DB was built for, stable for the DBs without one
+func (m Metadata) ReleaseChannel() string {
+	if m.Channel == "" {
+		return ChannelStable
+	}
+	return m.Channel
+}
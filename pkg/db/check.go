@@ -0,0 +1,140 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+// versionSchemes is the key in version.Comparers of the fixed versions of the advisories of an ecosystem
+var versionSchemes = map[string]string{
+	"alpine":                   "apk",
+	"amazon linux":             "rpm",
+	"debian":                   "dpkg",
+	"debian oval":              "dpkg",
+	"oracle linux":             "rpm",
+	"red hat enterprise linux": "rpm",
+	"ubuntu":                   "dpkg",
+}
+
+// Violation is a value or a bucket of a built DB which breaks an invariant checked by Check
+type Violation struct {
+	Bucket []string
+	Key    string // empty for a bucket
+	Reason string
+}
+
+func (v Violation) String() string {
+	if v.Key == "" {
+		return fmt.Sprintf("%s: %s", strings.Join(v.Bucket, " > "), v.Reason)
+	}
+	return fmt.Sprintf("%s > %s: %s", strings.Join(v.Bucket, " > "), v.Key, v.Reason)
+}
+
+// Check reads the whole DB and returns the violations of its invariants: the vulnerability of every advisory
+// is stored, the severities are known, the fixed versions parse under the version scheme of the ecosystem
+// and no bucket is empty. The metadata isn't checked, Verify does.
+func (dbc Config) Check() ([]Violation, error) {
+	metadata, err := dbc.GetMetadata()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get the metadata: %w", err)
+	}
+	// a light DB only has the severities of the vulnerabilities
+	c := &checker{known: vulnerabilityBucket}
+	if metadata.Type == TypeLight {
+		c.known = severityBucket
+	}
+
+	err = db.View(func(tx Tx) error {
+		c.vulns = tx.Bucket([]byte(c.known))
+		return tx.ForEach(func(name []byte, b storage.Bucket) error {
+			if string(name) == metadataBucket {
+				return nil
+			}
+			return c.walk(b, []string{string(name)})
+		})
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to check the DB: %w", err)
+	}
+	return c.violations, nil
+}
+
+type checker struct {
+	known      string         // the bucket an advisory needs the vulnerability in
+	vulns      storage.Bucket // the known bucket, nil if there is none
+	violations []Violation
+}
+
+func (c *checker) add(bucket []string, key, format string, args ...interface{}) {
+	c.violations = append(c.violations, Violation{Bucket: bucket, Key: key, Reason: fmt.Sprintf(format, args...)})
+}
+
+// walk checks each value of b and its nested buckets, and the buckets without any
+func (c *checker) walk(b storage.Bucket, path []string) error {
+	empty := true
+	err := b.ForEach(func(k, v []byte) error {
+		empty = false
+		if v == nil {
+			return c.walk(b.Bucket(k), append(append([]string{}, path...), string(k)))
+		}
+		v, err := decode(v)
+		if err != nil {
+			return xerrors.Errorf("%q %s: %w", path, k, err)
+		}
+		c.check(path, string(k), v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if empty {
+		c.add(path, "", "empty bucket")
+	}
+	return nil
+}
+
+func (c *checker) check(path []string, key string, v []byte) {
+	root := path[0]
+	switch {
+	case root == severityBucket:
+		if _, err := types.NewSeverity(string(v)); err != nil {
+			c.add(path, key, "%s", err)
+		}
+	case root == vulnerabilityBucket:
+		var vuln types.Vulnerability
+		if err := Unmarshal(v, &vuln); err != nil {
+			c.add(path, key, "%s", err)
+		} else if vuln.Severity != "" {
+			if _, err = types.NewSeverity(vuln.Severity); err != nil {
+				c.add(path, key, "%s", err)
+			}
+		}
+	case !utils.StringInSlice(root, nonAdvisoryBuckets) && len(path) == 2:
+		if c.vulns == nil || c.vulns.Get([]byte(key)) == nil {
+			c.add(path, key, "no %s record", c.known)
+		}
+		c.checkFixedVersion(path, key, v)
+	}
+}
+
+// checkFixedVersion parses the fixed version and the affected versions of an advisory of an OS, the language
+// advisories have ranges of their own
+func (c *checker) checkFixedVersion(path []string, key string, v []byte) {
+	_, platform := SplitAdvisoryBucket(path[0])
+	comparer, ok := SourceComparer(platform)
+	if !ok {
+		return
+	}
+	var advisory types.Advisory
+	if err := Unmarshal(v, &advisory); err != nil {
+		c.add(path, key, "%s", err)
+	} else if err = checkVersions(advisory, comparer); err != nil {
+		c.add(path, key, "invalid fixed version: %s", err)
+	}
+}
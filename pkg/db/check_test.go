@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Check(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbType Type
+		put    func(dbc Config, tx Tx) error
+		want   []Violation
+	}{
+		{
+			name: "valid",
+			put: func(dbc Config, tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "debian 10", "openssl", "CVE-2019-0001", types.Advisory{FixedVersion: "1.1.1d-0+deb10u2"}); err != nil {
+					return err
+				}
+				if err := dbc.PutAdvisory(tx, "ruby-advisory-db", "rails", "CVE-2019-0001", types.Advisory{}); err != nil {
+					return err
+				}
+				return dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{Severity: "HIGH"})
+			},
+		},
+		{
+			name:   "light",
+			dbType: TypeLight,
+			put: func(dbc Config, tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001", types.Advisory{FixedVersion: "1.1.1d-r0"}); err != nil {
+					return err
+				}
+				return dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityHigh)
+			},
+		},
+		{
+			name: "missing vulnerability",
+			put: func(dbc Config, tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0002", types.Advisory{}); err != nil {
+					return err
+				}
+				return dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{})
+			},
+			want: []Violation{
+				{Bucket: []string{"alpine::alpine 3.10", "openssl"}, Key: "CVE-2019-0002", Reason: "no vulnerability record"},
+			},
+		},
+		{
+			name: "unknown severity",
+			put: func(dbc Config, tx Tx) error {
+				b, err := tx.CreateBucketIfNotExists([]byte(severityBucket))
+				if err != nil {
+					return err
+				}
+				if err = b.Put([]byte("CVE-2019-0001"), []byte("SEVERE")); err != nil {
+					return err
+				}
+				return dbc.PutVulnerability(tx, "CVE-2019-0002", types.Vulnerability{Severity: "SEVERE"})
+			},
+			want: []Violation{
+				{Bucket: []string{"severity"}, Key: "CVE-2019-0001", Reason: "unknown severity: SEVERE"},
+				{Bucket: []string{"vulnerability"}, Key: "CVE-2019-0002", Reason: "unknown severity: SEVERE"},
+			},
+		},
+		{
+			name: "invalid fixed version",
+			put: func(dbc Config, tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "debian 10", "openssl", "CVE-2019-0001", types.Advisory{FixedVersion: "1.1.1d-0+deb10u2"}); err != nil {
+					return err
+				}
+				if err := dbc.PutAdvisory(tx, "debian 10", "curl", "CVE-2019-0001", types.Advisory{FixedVersion: "a:1.0"}); err != nil {
+					return err
+				}
+				return dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{})
+			},
+			want: []Violation{
+				{Bucket: []string{"debian::debian 10", "curl"}, Key: "CVE-2019-0001", Reason: `invalid fixed version: invalid dpkg version a:1.0: epoch parse error: strconv.Atoi: parsing "a": invalid syntax`},
+			},
+		},
+		{
+			name: "empty bucket",
+			put: func(dbc Config, tx Tx) error {
+				b, err := tx.CreateBucketIfNotExists([]byte("alpine::alpine 3.10"))
+				if err != nil {
+					return err
+				}
+				_, err = b.CreateBucketIfNotExists([]byte("openssl"))
+				return err
+			},
+			want: []Violation{
+				{Bucket: []string{"alpine::alpine 3.10", "openssl"}, Reason: "empty bucket"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestConfig_Check_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+			assert.NoError(t, Init(d))
+			defer Close()
+
+			dbc := Config{}
+			assert.NoError(t, dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+				return tt.put(dbc, tx)
+			}))
+			assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: tt.dbType}))
+
+			got, err := dbc.Check()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
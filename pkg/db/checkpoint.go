@@ -0,0 +1,144 @@
+package db
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+const (
+	// checkpointFile records the sources the build in progress completed with the revisions of their inputs,
+	// in JSON, so that it can be read while the DB is being restored
+	checkpointFile = "trivy.db.checkpoint"
+
+	snapshotFile = "trivy.db.snapshot"
+)
+
+// Checkpointer records the sources a build completed, so that an interrupted build can resume after them
+type Checkpointer interface {
+	Checkpoint(string, SourceMetadata) error
+	Checkpoints() (map[string]SourceMetadata, error)
+	ClearCheckpoints() error
+}
+
+// SnapshotPath returns the path of the snapshot taken by Checkpoint
+func SnapshotPath(cacheDir string) string {
+	return filepath.Join(cacheDir, "db", snapshotFile)
+}
+
+// CheckpointPath returns the path of the file Checkpoint records the completed sources in
+func CheckpointPath(cacheDir string) string {
+	return filepath.Join(cacheDir, "db", checkpointFile)
+}
+
+// Checkpoint snapshots the DB next to it when the storage driver supports it, then records that the source
+// is complete. A crash while the next source is written leaves its batches in the DB, RestoreSnapshot
+// goes back to the state after the checkpoint. A crash between the snapshot and the record only makes
+// the source be updated again.
+func (dbc Config) Checkpoint(source string, metadata SourceMetadata) error {
+	if err := snapshot(filepath.Join(dbDir, snapshotFile)); err != nil && err != storage.ErrSnapshotNotSupported {
+		return xerrors.Errorf("failed to snapshot the DB: %w", err)
+	}
+	checkpoints, err := dbc.Checkpoints()
+	if err != nil {
+		return err
+	}
+	checkpoints[source] = metadata
+	b, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("failed to encode the checkpoints: %w", err)
+	}
+	if err = writeFile(filepath.Join(dbDir, checkpointFile), func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	}); err != nil {
+		return xerrors.Errorf("failed to save the checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Checkpoints returns the sources completed since the last ClearCheckpoints
+func (dbc Config) Checkpoints() (map[string]SourceMetadata, error) {
+	checkpoints := map[string]SourceMetadata{}
+	b, err := ioutil.ReadFile(filepath.Join(dbDir, checkpointFile))
+	if os.IsNotExist(err) {
+		return checkpoints, nil
+	} else if err != nil {
+		return nil, xerrors.Errorf("failed to get the checkpoints: %w", err)
+	}
+	if err = json.Unmarshal(b, &checkpoints); err != nil {
+		return nil, xerrors.Errorf("invalid checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// ClearCheckpoints forgets the completed sources and removes the snapshot, once a build is over
+// or when it starts over
+func (dbc Config) ClearCheckpoints() error {
+	if err := os.Remove(filepath.Join(dbDir, checkpointFile)); err != nil && !os.IsNotExist(err) {
+		return xerrors.Errorf("failed to delete the checkpoints: %w", err)
+	}
+	if err := os.Remove(filepath.Join(dbDir, snapshotFile)); err != nil && !os.IsNotExist(err) {
+		return xerrors.Errorf("failed to remove the snapshot: %w", err)
+	}
+	return nil
+}
+
+// RestoreSnapshot replaces the DB with the snapshot of the last checkpoint, if any, and reports whether it did.
+// The DB must be closed.
+func RestoreSnapshot(cacheDir string) (bool, error) {
+	src, err := os.Open(SnapshotPath(cacheDir))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, xerrors.Errorf("failed to open the snapshot: %w", err)
+	}
+	defer src.Close()
+
+	// the snapshot is copied, as the restored DB is written to before the next checkpoint replaces it
+	if err = writeFile(Path(cacheDir), func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	}); err != nil {
+		return false, xerrors.Errorf("failed to restore the snapshot: %w", err)
+	}
+	return true, nil
+}
+
+// snapshot writes the open DB to path
+func snapshot(path string) error {
+	if _, ok := db.(storage.Snapshotter); !ok {
+		return storage.ErrSnapshotNotSupported
+	}
+	return writeFile(path, func(w io.Writer) error {
+		return storage.Snapshot(db, w)
+	})
+}
+
+// writeFile writes path through a temporary file, so that a crash never leaves it half written
+func writeFile(path string, write func(io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	if err = write(f); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
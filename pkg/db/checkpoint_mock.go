@@ -0,0 +1,34 @@
+package db
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+var _ Checkpointer = &MockCheckpointer{}
+
+type MockCheckpointer struct {
+	mock.Mock
+}
+
+func (_m *MockCheckpointer) Checkpoint(a string, b SourceMetadata) error {
+	ret := _m.Called(a, b)
+	return ret.Error(0)
+}
+
+func (_m *MockCheckpointer) Checkpoints() (map[string]SourceMetadata, error) {
+	ret := _m.Called()
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil, ret.Error(1)
+	}
+	checkpoints, ok := ret0.(map[string]SourceMetadata)
+	if !ok {
+		return nil, ret.Error(1)
+	}
+	return checkpoints, ret.Error(1)
+}
+
+func (_m *MockCheckpointer) ClearCheckpoints() error {
+	ret := _m.Called()
+	return ret.Error(0)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Checkpoint(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Checkpoint_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	assert.NoError(t, Init(d))
+
+	dbc := Config{}
+	put := func(platform, cveID string) {
+		assert.NoError(t, db.Update(func(tx Tx) error {
+			return dbc.PutAdvisory(tx, platform, "openssl", cveID, map[string]string{"FixedVersion": "1.1.1"})
+		}))
+	}
+	alpine := SourceMetadata{UpdatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Revision: "abc",
+		NewestRecordAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	put("alpine 3.10", "CVE-2019-0001")
+	assert.NoError(t, dbc.Checkpoint("alpine", alpine))
+	_, err = os.Stat(SnapshotPath(d))
+	assert.NoError(t, err)
+	b, err := ioutil.ReadFile(CheckpointPath(d))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"alpine": {"UpdatedAt": "2020-01-01T00:00:00Z", "Revision": "abc",
+		"NewestRecordAt": "2020-01-01T00:00:00Z"}}`, string(b))
+
+	// the build crashes in the middle of the next source
+	put("debian 9", "CVE-2019-0002")
+	assert.NoError(t, Close())
+
+	restored, err := RestoreSnapshot(d)
+	assert.NoError(t, err)
+	assert.True(t, restored)
+	assert.NoError(t, Init(d))
+
+	checkpoints, err := dbc.Checkpoints()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]SourceMetadata{"alpine": alpine}, checkpoints)
+	got, err := dbc.GetAdvisories("alpine 3.10", "openssl")
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	got, err = dbc.GetAdvisories("debian 9", "openssl")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	assert.NoError(t, dbc.ClearCheckpoints())
+	checkpoints, err = dbc.Checkpoints()
+	assert.NoError(t, err)
+	assert.Empty(t, checkpoints)
+	_, err = os.Stat(SnapshotPath(d))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(CheckpointPath(d))
+	assert.True(t, os.IsNotExist(err))
+	assert.NoError(t, Close())
+
+	restored, err = RestoreSnapshot(d)
+	assert.NoError(t, err)
+	assert.False(t, restored)
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// ErrNoChecksums is returned by Verify for a DB built without checksums
+var ErrNoChecksums = xerrors.New("the DB has no checksums")
+
+// IntegrityError lists the root buckets that don't match the checksums in the metadata
+type IntegrityError struct {
+	Mismatched []string
+	Missing    []string
+	Unexpected []string
+}
+
+func (e *IntegrityError) Error() string {
+	var s []string
+	if len(e.Mismatched) > 0 {
+		s = append(s, "mismatched buckets: "+strings.Join(e.Mismatched, ", "))
+	}
+	if len(e.Missing) > 0 {
+		s = append(s, "missing buckets: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unexpected) > 0 {
+		s = append(s, "unexpected buckets: "+strings.Join(e.Unexpected, ", "))
+	}
+	return "DB integrity check failed: " + strings.Join(s, "; ")
+}
+
+// SetChecksums stores the SHA-256 of every root bucket but the metadata one in the metadata.
+// It is the last step of a build.
+func (dbc Config) SetChecksums() error {
+	err := writeTx(func(tx Tx) error {
+		checksums, err := checksumBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return dbc.updateMetadata(tx, func(metadata *Metadata) {
+			metadata.Checksums = checksums
+		})
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to set checksums: %w", err)
+	}
+	return nil
+}
+
+// Verify re-hashes the buckets and returns an *IntegrityError if they don't match the metadata
+func (dbc Config) Verify() error {
+	metadata, err := dbc.GetMetadata()
+	if err != nil {
+		return xerrors.Errorf("failed to get metadata: %w", err)
+	} else if len(metadata.Checksums) == 0 {
+		return ErrNoChecksums
+	}
+
+	var checksums map[string]string
+	err = db.View(func(tx Tx) error {
+		checksums, err = checksumBuckets(tx)
+		return err
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to hash buckets: %w", err)
+	}
+
+	integrityErr := &IntegrityError{}
+	for name, expected := range metadata.Checksums {
+		actual, ok := checksums[name]
+		switch {
+		case !ok:
+			integrityErr.Missing = append(integrityErr.Missing, name)
+		case actual != expected:
+			integrityErr.Mismatched = append(integrityErr.Mismatched, name)
+		}
+	}
+	for name := range checksums {
+		if _, ok := metadata.Checksums[name]; !ok {
+			integrityErr.Unexpected = append(integrityErr.Unexpected, name)
+		}
+	}
+	if len(integrityErr.Mismatched)+len(integrityErr.Missing)+len(integrityErr.Unexpected) == 0 {
+		return nil
+	}
+	sort.Strings(integrityErr.Mismatched)
+	sort.Strings(integrityErr.Missing)
+	sort.Strings(integrityErr.Unexpected)
+	return integrityErr
+}
+
+func checksumBuckets(tx Tx) (map[string]string, error) {
+	checksums := map[string]string{}
+	err := tx.ForEach(func(name []byte, b storage.Bucket) error {
+		if string(name) == metadataBucket {
+			return nil
+		}
+		h := sha256.New()
+		if err := hashBucket(h, b); err != nil {
+			return xerrors.Errorf("failed to hash %s: %w", name, err)
+		}
+		checksums[string(name)] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+// hashBucket writes the keys and values in order, each prefixed with a type and its length.
+// A nested bucket is written in place and closed with an end marker.
+func hashBucket(h hash.Hash, b storage.Bucket) error {
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			writeField(h, 'b', k)
+			if err := hashBucket(h, b.Bucket(k)); err != nil {
+				return err
+			}
+			h.Write([]byte{'e'})
+			return nil
+		}
+		writeField(h, 'k', k)
+		writeField(h, 'v', v)
+		return nil
+	})
+}
+
+func writeField(h hash.Hash, typ byte, b []byte) {
+	var buf [binary.MaxVarintLen64 + 1]byte
+	buf[0] = typ
+	n := binary.PutUvarint(buf[1:], uint64(len(b)))
+	h.Write(buf[:n+1])
+	h.Write(b)
+}
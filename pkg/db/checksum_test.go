@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Verify(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Verify_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		advisory := types.Advisory{FixedVersion: "1.1.1d-r0"}
+		if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001", advisory); err != nil {
+			return err
+		}
+		return dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityHigh)
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrNoChecksums, dbc.Verify())
+
+	assert.NoError(t, dbc.SetChecksums())
+	assert.NoError(t, dbc.Verify())
+
+	// tampering
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		advisory := types.Advisory{FixedVersion: "9.9.9"}
+		if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001", advisory); err != nil {
+			return err
+		}
+		if err := dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{}); err != nil {
+			return err
+		}
+		return tx.DeleteBucket([]byte(severityBucket))
+	})
+	assert.NoError(t, err)
+
+	err = dbc.Verify()
+	var integrityErr *IntegrityError
+	assert.True(t, xerrors.As(err, &integrityErr))
+	assert.Equal(t, &IntegrityError{
+		Mismatched: []string{"alpine::alpine 3.10"},
+		Missing:    []string{"severity"},
+		Unexpected: []string{"vulnerability"},
+	}, integrityErr)
+	assert.EqualError(t, err, "DB integrity check failed: mismatched buckets: alpine::alpine 3.10; missing buckets: severity; unexpected buckets: vulnerability")
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// DefaultChunkSize is the number of puts after which ChunkedUpdate commits
+const DefaultChunkSize = 10000
+
+// batchSize is set by SetBatchSize
+var batchSize int
+
+// SetBatchSize makes the sources commit what they read every n inputs instead of all of it at the end of
+// their walk, and ChunkedUpdate commit at most n puts at a time, so that a build holds less in memory at
+// the cost of more transactions. 0 restores the defaults.
+func SetBatchSize(n int) {
+	batchSize = n
+}
+
+// BatchFull tells whether a source which read n inputs since its last commit should commit them now
+func BatchFull(n int) bool {
+	return batchSize > 0 && n >= batchSize
+}
+
+// ChunkedUpdate calls fn for each of n items and commits whenever a transaction reaches chunkSize puts,
+// so that a huge source doesn't build a single transaction in memory and a failure keeps the committed chunks.
+// An item is never split across transactions and chunkSize is capped by SetBatchSize. progress, if not nil, is called after each commit.
+// When ctx is done, the current chunk is rolled back and the previous ones are kept.
+func (dbc Config) ChunkedUpdate(ctx context.Context, n, chunkSize int, fn func(tx Tx, i int) error, progress func(done, total int)) error {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if batchSize > 0 && chunkSize > batchSize {
+		chunkSize = batchSize
+	}
+	if err := enterCommit(ctx); err != nil {
+		return xerrors.Errorf("failed to wait for the turn to commit: %w", err)
+	}
+	for done := 0; done < n; {
+		var counting *countingTx
+		err := writeTx(func(tx Tx) error {
+			counting = &countingTx{tx: journalTx(tx)}
+			for i := done; i < n; i++ {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := fn(counting, i); err != nil {
+					return xerrors.Errorf("item %d: %w", i, err)
+				}
+				if counting.puts >= chunkSize || i == n-1 {
+					done = i + 1
+					return nil
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return xerrors.Errorf("error in chunked update: %w", err)
+		}
+		log.ProgressFrom(ctx).AddRecords(counting.puts)
+		if progress != nil {
+			progress(done, n)
+		}
+	}
+	return nil
+}
+
+// countingTx counts the puts made through its buckets
+type countingTx struct {
+	tx   storage.Tx
+	puts int
+}
+
+func (t *countingTx) Bucket(name []byte) storage.Bucket {
+	return t.wrap(t.tx.Bucket(name))
+}
+
+func (t *countingTx) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	b, err := t.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.wrap(b), nil
+}
+
+func (t *countingTx) DeleteBucket(name []byte) error {
+	return t.tx.DeleteBucket(name)
+}
+
+func (t *countingTx) ForEach(fn func(name []byte, b storage.Bucket) error) error {
+	return t.tx.ForEach(func(name []byte, b storage.Bucket) error {
+		return fn(name, t.wrap(b))
+	})
+}
+
+func (t *countingTx) wrap(b storage.Bucket) storage.Bucket {
+	if b == nil {
+		return nil
+	}
+	return countingBucket{b: b, tx: t}
+}
+
+type countingBucket struct {
+	b  storage.Bucket
+	tx *countingTx
+}
+
+func (b countingBucket) Get(key []byte) []byte {
+	return b.b.Get(key)
+}
+
+func (b countingBucket) Put(key, value []byte) error {
+	b.tx.puts++
+	return b.b.Put(key, value)
+}
+
+func (b countingBucket) Delete(key []byte) error {
+	return b.b.Delete(key)
+}
+
+func (b countingBucket) ForEach(fn func(k, v []byte) error) error {
+	return b.b.ForEach(fn)
+}
+
+func (b countingBucket) Bucket(name []byte) storage.Bucket {
+	return b.tx.wrap(b.b.Bucket(name))
+}
+
+func (b countingBucket) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	nested, err := b.b.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.tx.wrap(nested), nil
+}
+
+func (b countingBucket) DeleteBucket(name []byte) error {
+	return b.b.DeleteBucket(name)
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_ChunkedUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		n            int
+		chunkSize    int
+		batchSize    int
+		failAt       int
+		cancelAt     int
+		wantProgress []int
+		wantSeverity []string
+		wantErr      bool
+	}{
+		{
+			name:         "happy path",
+			n:            5,
+			chunkSize:    4, // two puts per item
+			failAt:       -1,
+			cancelAt:     -1,
+			wantProgress: []int{2, 4, 5},
+			wantSeverity: []string{"CVE-2019-0000", "CVE-2019-0001", "CVE-2019-0002", "CVE-2019-0003", "CVE-2019-0004"},
+		},
+		{
+			name:         "batch size caps the chunks",
+			n:            5,
+			chunkSize:    4,
+			batchSize:    2,
+			failAt:       -1,
+			cancelAt:     -1,
+			wantProgress: []int{1, 2, 3, 4, 5},
+			wantSeverity: []string{"CVE-2019-0000", "CVE-2019-0001", "CVE-2019-0002", "CVE-2019-0003", "CVE-2019-0004"},
+		},
+		{
+			name:         "failure keeps the committed chunks",
+			n:            5,
+			chunkSize:    4,
+			failAt:       3,
+			cancelAt:     -1,
+			wantProgress: []int{2},
+			wantSeverity: []string{"CVE-2019-0000", "CVE-2019-0001"},
+			wantErr:      true,
+		},
+		{
+			name:         "cancellation keeps the committed chunks",
+			n:            5,
+			chunkSize:    4,
+			failAt:       -1,
+			cancelAt:     2,
+			wantProgress: []int{2},
+			wantSeverity: []string{"CVE-2019-0000", "CVE-2019-0001"},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestConfig_ChunkedUpdate_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+
+			assert.NoError(t, Init(d))
+			defer Close()
+			SetBatchSize(tt.batchSize)
+			defer SetBatchSize(0)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ctx, records := log.WithProgress(ctx, "nvd")
+
+			dbc := Config{}
+			var progress []int
+			err = dbc.ChunkedUpdate(ctx, tt.n, tt.chunkSize, func(tx Tx, i int) error {
+				if i == tt.failAt {
+					return xerrors.New("error")
+				}
+				if i == tt.cancelAt {
+					cancel()
+				}
+				cveID := fmt.Sprintf("CVE-2019-%04d", i)
+				if err := dbc.PutSeverity(tx, cveID, types.SeverityLow); err != nil {
+					return err
+				}
+				return dbc.PutVulnerabilityDetail(tx, cveID, "nvd", types.VulnerabilityDetail{})
+			}, func(done, total int) {
+				assert.Equal(t, tt.n, total)
+				progress = append(progress, done)
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantProgress, progress)
+			// two puts per committed item
+			assert.Equal(t, int64(2*len(tt.wantSeverity)), records.Records())
+
+			var got []string
+			err = dbc.ForEachSeverity(func(_ Tx, cveID string, _ types.Severity) error {
+				got = append(got, cveID)
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSeverity, got)
+		})
+	}
+}
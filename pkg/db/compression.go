@@ -0,0 +1,291 @@
+package db
+
+import (
+	"bytes"
+	"hash/crc32"
+	"sort"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+const (
+	// CompressionZstd means values may be zstd frames using the dictionary in the trivy bucket
+	CompressionZstd = "zstd"
+
+	metadataBucket    = "trivy"
+	compressionBucket = "compression"
+	dictionaryKey     = "dictionary"
+
+	maxDictionarySize = 110 << 10
+	maxSamples        = 100000
+)
+
+var (
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+	// buckets whose values are never compressed
+	uncompressedBuckets = []string{metadataBucket, severityBucket}
+
+	// decoder is set by Init when the DB is compressed
+	decoder *zstd.Decoder
+
+	// zstd needs a dictionary of 8 bytes at least
+	errTooFewSamples = xerrors.New("not enough values")
+	// errSkipCompression rolls back the transaction of Compress without failing it
+	errSkipCompression = xerrors.New("compression skipped")
+)
+
+// Compress trains a dictionary on the values in the DB and stores every value that gets
+// smaller as a zstd frame. It can be run again after updates, values are re-compressed with a new dictionary.
+func (dbc Config) Compress() error {
+	err := writeTx(func(tx Tx) error {
+		dict, err := dbc.trainDictionary(tx)
+		if xerrors.Is(err, errTooFewSamples) {
+			log.OrDefault(dbc.Logger).Info("Skipping the compression, the DB has too few values to train a dictionary")
+			return errSkipCompression
+		} else if err != nil {
+			return xerrors.Errorf("failed to train a dictionary: %w", err)
+		}
+
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderDictRaw(dictionaryID(dict), dict),
+			zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		if err != nil {
+			return xerrors.Errorf("failed to create a zstd encoder: %w", err)
+		}
+		defer encoder.Close()
+
+		err = dbc.rewriteValues(tx, func(v []byte) ([]byte, error) {
+			raw, err := decode(v)
+			if err != nil {
+				return nil, err
+			}
+			compressed := encoder.EncodeAll(raw, nil)
+			if len(compressed) >= len(raw) {
+				return raw, nil
+			}
+			return compressed, nil
+		})
+		if err != nil {
+			return xerrors.Errorf("failed to compress values: %w", err)
+		}
+
+		root, err := tx.CreateBucketIfNotExists([]byte(metadataBucket))
+		if err != nil {
+			return xerrors.Errorf("failed to create a bucket: %w", err)
+		}
+		bucket, err := root.CreateBucketIfNotExists([]byte(compressionBucket))
+		if err != nil {
+			return xerrors.Errorf("failed to create a bucket: %w", err)
+		}
+		if err = bucket.Put([]byte(dictionaryKey), dict); err != nil {
+			return xerrors.Errorf("failed to save the dictionary: %w", err)
+		}
+		return dbc.updateMetadata(tx, func(metadata *Metadata) {
+			metadata.Compression = CompressionZstd
+		})
+	})
+	if xerrors.Is(err, errSkipCompression) {
+		return nil
+	} else if err != nil {
+		return xerrors.Errorf("failed to compress DB: %w", err)
+	}
+	return loadFormat()
+}
+
+// Decompress stores every value uncompressed again. It does nothing if the DB is not compressed.
+func (dbc Config) Decompress() error {
+	if decoder == nil {
+		return nil
+	}
+	err := writeTx(func(tx Tx) error {
+		if err := dbc.rewriteValues(tx, decode); err != nil {
+			return xerrors.Errorf("failed to decompress values: %w", err)
+		}
+		if root := tx.Bucket([]byte(metadataBucket)); root != nil {
+			if err := root.DeleteBucket([]byte(compressionBucket)); err != nil && err != storage.ErrBucketNotFound {
+				return xerrors.Errorf("failed to delete the dictionary: %w", err)
+			}
+		}
+		return dbc.updateMetadata(tx, func(metadata *Metadata) {
+			metadata.Compression = ""
+		})
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to decompress DB: %w", err)
+	}
+	return loadFormat()
+}
+
+// trainDictionary builds a raw content dictionary from the most frequent values,
+// most valuable last since zstd prefers short offsets
+func (dbc Config) trainDictionary(tx Tx) ([]byte, error) {
+	counts := map[string]int{}
+	err := dbc.walkValues(tx, func(_ storage.Bucket, _, v []byte) error {
+		raw, err := decode(v)
+		if err != nil {
+			return err
+		}
+		if _, ok := counts[string(raw)]; ok || len(counts) < maxSamples {
+			counts[string(raw)]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]string, 0, len(counts))
+	for s := range counts {
+		samples = append(samples, s)
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		wi, wj := counts[samples[i]]*len(samples[i]), counts[samples[j]]*len(samples[j])
+		if wi != wj {
+			return wi > wj
+		}
+		return samples[i] < samples[j]
+	})
+
+	var picked []string
+	size := 0
+	for _, s := range samples {
+		if size+len(s) > maxDictionarySize {
+			continue
+		}
+		picked = append(picked, s)
+		size += len(s)
+	}
+
+	dict := make([]byte, 0, size)
+	for i := len(picked) - 1; i >= 0; i-- {
+		dict = append(dict, picked[i]...)
+	}
+	if len(dict) < 8 {
+		return nil, errTooFewSamples
+	}
+	return dict, nil
+}
+
+// rewriteValues replaces each value with the result of fn
+func (dbc Config) rewriteValues(tx Tx, fn func([]byte) ([]byte, error)) error {
+	return dbc.walkValues(tx, func(b storage.Bucket, k, v []byte) error {
+		nv, err := fn(v)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(v, nv) {
+			return nil
+		}
+		return b.Put(k, nv)
+	})
+}
+
+// walkValues calls fn for every value outside uncompressedBuckets. A bucket is read
+// before fn is called, so fn may write to it.
+func (dbc Config) walkValues(tx Tx, fn func(b storage.Bucket, k, v []byte) error) error {
+	var roots [][]byte
+	err := tx.ForEach(func(name []byte, _ storage.Bucket) error {
+		if !utils.StringInSlice(string(name), uncompressedBuckets) {
+			roots = append(roots, append([]byte{}, name...))
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to list buckets: %w", err)
+	}
+	for _, name := range roots {
+		if err = walkBucket(tx.Bucket(name), fn); err != nil {
+			return xerrors.Errorf("error in %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func walkBucket(b storage.Bucket, fn func(b storage.Bucket, k, v []byte) error) error {
+	var keys, values, nested [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			nested = append(nested, append([]byte{}, k...))
+			return nil
+		}
+		keys = append(keys, append([]byte{}, k...))
+		values = append(values, append([]byte{}, v...))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range nested {
+		if err = walkBucket(b.Bucket(name), fn); err != nil {
+			return err
+		}
+	}
+	for i := range keys {
+		if err = fn(b, keys[i], values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDecoder creates the decoder of the values of a store, nil if they aren't compressed
+func readDecoder(s storage.Store, metadata Metadata) (*zstd.Decoder, error) {
+	if metadata.Compression == "" {
+		return nil, nil
+	} else if metadata.Compression != CompressionZstd {
+		return nil, xerrors.Errorf("unknown compression: %s", metadata.Compression)
+	}
+
+	var dict []byte
+	err := s.View(func(tx Tx) error {
+		if root := tx.Bucket([]byte(metadataBucket)); root != nil {
+			if nested := root.Bucket([]byte(compressionBucket)); nested != nil {
+				// values returned by the storage are only valid in the transaction
+				dict = append([]byte{}, nested.Get([]byte(dictionaryKey))...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get the dictionary: %w", err)
+	} else if len(dict) == 0 {
+		return nil, xerrors.New("compressed DB without a dictionary")
+	}
+
+	d, err := zstd.NewReader(nil, zstd.WithDecoderDictRaw(dictionaryID(dict), dict))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create a zstd decoder: %w", err)
+	}
+	return d, nil
+}
+
+// decode decodes a value of the DB opened by Init
+func decode(v []byte) ([]byte, error) {
+	return currentFormat().decode(v)
+}
+
+// decode returns the value as is unless it is a zstd frame
+func (f format) decode(v []byte) ([]byte, error) {
+	if !bytes.HasPrefix(v, zstdMagic) {
+		return v, nil
+	}
+	if f.decoder == nil {
+		return nil, xerrors.New("compressed value in an uncompressed DB")
+	}
+	raw, err := f.decoder.DecodeAll(v, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to decompress: %w", err)
+	}
+	return raw, nil
+}
+
+// dictionaryID derives the ID from the content, so frames can't be decoded with another dictionary
+func dictionaryID(dict []byte) uint32 {
+	// IDs below 32768 are reserved
+	return crc32.ChecksumIEEE(dict) | 1<<31
+}
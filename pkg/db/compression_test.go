@@ -0,0 +1,120 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Compress(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Compress_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	dbc := Config{}
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+
+	vuln := types.Vulnerability{
+		Title:       "openssl: a long enough title to be worth compressing",
+		Description: "The openssl package has a vulnerability which is described at length here.",
+		Severity:    "HIGH",
+		References:  []string{"https://example.com/advisory"},
+	}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		for i := 0; i < 50; i++ {
+			cveID := fmt.Sprintf("CVE-2019-%04d", i)
+			advisory := types.Advisory{FixedVersion: fmt.Sprintf("1.1.%d-r0", i)}
+			if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", cveID, advisory); err != nil {
+				return err
+			}
+			if err := dbc.PutVulnerability(tx, cveID, vuln); err != nil {
+				return err
+			}
+		}
+		return dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityHigh)
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, dbc.Compress())
+
+	// reopen to load the dictionary from the DB
+	assert.NoError(t, Close())
+	assert.NoError(t, Init(d))
+
+	metadata, err := dbc.GetMetadata()
+	assert.NoError(t, err)
+	assert.Equal(t, CompressionZstd, metadata.Compression)
+	assert.Equal(t, SchemaVersion, metadata.Version)
+
+	raw, err := dbc.getRaw(vulnerabilityBucket, "CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(raw, zstdMagic))
+
+	got, err := dbc.GetVulnerability("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, vuln, got)
+
+	advisories, err := dbc.GetAdvisories("alpine 3.10", "openssl")
+	assert.NoError(t, err)
+	assert.Len(t, advisories, 50)
+
+	severity, err := dbc.GetSeverity("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, types.SeverityHigh, severity)
+
+	assert.NoError(t, dbc.Decompress())
+
+	metadata, err = dbc.GetMetadata()
+	assert.NoError(t, err)
+	assert.Empty(t, metadata.Compression)
+
+	raw, err = dbc.getRaw(vulnerabilityBucket, "CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, byte('{'), raw[0])
+
+	got, err = dbc.GetVulnerability("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, vuln, got)
+	assert.NoError(t, Close())
+}
+
+func TestConfig_Compress_TooFewValues(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Compress_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+	dbc := Config{Logger: log.Discard()}
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		return dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityHigh)
+	})
+	assert.NoError(t, err)
+
+	// the DB is left uncompressed
+	assert.NoError(t, dbc.Compress())
+	metadata, err := dbc.GetMetadata()
+	assert.NoError(t, err)
+	assert.Empty(t, metadata.Compression)
+	severity, err := dbc.GetSeverity("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, types.SeverityHigh, severity)
+}
+
+func (dbc Config) getRaw(bucket, key string) (value []byte, err error) {
+	err = db.View(func(tx Tx) error {
+		value = append([]byte{}, tx.Bucket([]byte(bucket)).Get([]byte(key))...)
+		return nil
+	})
+	return value, err
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"sort"
+	"strings"
+
+	version "github.com/knqyf263/go-rpm-version"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+const (
+	// cpeBucket indexes NVD configurations: vendor:product => CVE-ID => []CPEMatch
+	cpeBucket = "cpe"
+
+	cpeAny = "*"
+	cpeNA  = "-"
+)
+
+// ErrInvalidCPE is returned for a string that is neither a CPE 2.3 formatted string nor a CPE URI
+var ErrInvalidCPE = xerrors.New("invalid CPE")
+
+type cpe struct {
+	part    string
+	vendor  string
+	product string
+	version string
+}
+
+func (c cpe) key() string {
+	return c.vendor + ":" + c.product
+}
+
+// PutCPEMatches indexes the vulnerable CPEs of a CVE by vendor and product
+func (dbc Config) PutCPEMatches(tx Tx, cveID string, matches []types.CPEMatch) error {
+	grouped := map[string][]types.CPEMatch{}
+	for _, m := range matches {
+		c, err := parseCPE(m.CPE)
+		if err != nil {
+			return xerrors.Errorf("%s: %w", cveID, err)
+		}
+		grouped[c.key()] = append(grouped[c.key()], m)
+	}
+	if len(grouped) == 0 {
+		return nil
+	}
+
+	root, err := tx.CreateBucketIfNotExists([]byte(cpeBucket))
+	if err != nil {
+		return xerrors.Errorf("failed to create a bucket: %w", err)
+	}
+	for key, ms := range grouped {
+		v, err := Marshal(ms)
+		if err != nil {
+			return err
+		}
+		if err = dbc.put(root, key, cveID, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAdvisoriesByCPE returns the CVEs whose NVD configurations match the CPE.
+// The fixed version is the end of the matching version range, if it excludes it.
+func (dbc Config) GetAdvisoriesByCPE(s string) ([]types.Advisory, error) {
+	c, err := parseCPE(s)
+	if err != nil {
+		return nil, err
+	}
+	values, err := dbc.forEach(cpeBucket, c.key())
+	if err != nil {
+		return nil, xerrors.Errorf("error in CPE foreach: %w", err)
+	}
+
+	var advisories []types.Advisory
+	for cveID, v := range values {
+		var matches []types.CPEMatch
+		if err = Unmarshal(v, &matches); err != nil {
+			return nil, xerrors.Errorf("failed to unmarshal CPE matches: %w", err)
+		}
+		for _, m := range matches {
+			if ok, err := matchCPE(c, m); err != nil {
+				return nil, xerrors.Errorf("%s: %w", cveID, err)
+			} else if ok {
+				advisories = append(advisories, types.Advisory{
+					VulnerabilityID: cveID,
+					FixedVersion:    m.VersionEndExcluding,
+				})
+				break
+			}
+		}
+	}
+	sort.Slice(advisories, func(i, j int) bool {
+		return advisories[i].VulnerabilityID < advisories[j].VulnerabilityID
+	})
+	return advisories, nil
+}
+
+// DeleteCPEBucket deletes the CPE index, which light DBs don't have
+func (dbc Config) DeleteCPEBucket() error {
+	return dbc.deleteBucketIfExists(cpeBucket)
+}
+
+func matchCPE(c cpe, m types.CPEMatch) (bool, error) {
+	mc, err := parseCPE(m.CPE)
+	if err != nil {
+		return false, err
+	}
+	if c.part != cpeAny && mc.part != cpeAny && c.part != mc.part {
+		return false, nil
+	}
+	if c.version == cpeAny {
+		return true, nil
+	}
+
+	ranged := m.VersionStartIncluding != "" || m.VersionStartExcluding != "" ||
+		m.VersionEndIncluding != "" || m.VersionEndExcluding != ""
+	switch {
+	case c.version == cpeNA || mc.version == cpeNA:
+		return c.version == mc.version && !ranged, nil
+	case mc.version != cpeAny:
+		return compareVersion(c.version, mc.version) == 0, nil
+	case !ranged:
+		return true, nil
+	}
+
+	if m.VersionStartIncluding != "" && compareVersion(c.version, m.VersionStartIncluding) < 0 {
+		return false, nil
+	}
+	if m.VersionStartExcluding != "" && compareVersion(c.version, m.VersionStartExcluding) <= 0 {
+		return false, nil
+	}
+	if m.VersionEndIncluding != "" && compareVersion(c.version, m.VersionEndIncluding) > 0 {
+		return false, nil
+	}
+	if m.VersionEndExcluding != "" && compareVersion(c.version, m.VersionEndExcluding) >= 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// compareVersion compares versions with rpmvercmp, which handles the dotted versions of NVD
+func compareVersion(v1, v2 string) int {
+	return version.NewVersion(v1).Compare(version.NewVersion(v2))
+}
+
+// parseCPE parses a CPE 2.3 formatted string like cpe:2.3:a:openssl:openssl:1.1.1:*:*:*:*:*:*:*
+// or a CPE 2.2 URI like cpe:/a:openssl:openssl:1.1.1
+func parseCPE(s string) (cpe, error) {
+	var fields []string
+	switch {
+	case strings.HasPrefix(s, "cpe:2.3:"):
+		fields = splitCPE(strings.TrimPrefix(s, "cpe:2.3:"))
+	case strings.HasPrefix(s, "cpe:/"):
+		fields = strings.Split(strings.TrimPrefix(s, "cpe:/"), ":")
+	default:
+		return cpe{}, xerrors.Errorf("%s: %w", s, ErrInvalidCPE)
+	}
+	if len(fields) < 3 || fields[1] == "" || fields[2] == "" {
+		return cpe{}, xerrors.Errorf("%s: %w", s, ErrInvalidCPE)
+	}
+
+	c := cpe{
+		part:    fields[0],
+		vendor:  strings.ToLower(fields[1]),
+		product: strings.ToLower(fields[2]),
+		version: cpeAny,
+	}
+	if len(fields) > 3 && fields[3] != "" {
+		c.version = strings.Replace(fields[3], `\`, "", -1)
+	}
+	return c, nil
+}
+
+// splitCPE splits at colons that aren't escaped with a backslash
+func splitCPE(s string) []string {
+	var fields []string
+	var escaped bool
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == ':':
+			fields = append(fields, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(fields, s[start:])
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+var _ CPEStore = &MockCPEStore{}
+
+type MockCPEStore struct {
+	mock.Mock
+}
+
+func (_m *MockCPEStore) PutCPEMatches(a Tx, b string, c []types.CPEMatch) error {
+	ret := _m.Called(a, b, c)
+	return ret.Error(0)
+}
+
+func (_m *MockCPEStore) GetAdvisoriesByCPE(a string) ([]types.Advisory, error) {
+	ret := _m.Called(a)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil, ret.Error(1)
+	}
+	advisories, ok := ret0.([]types.Advisory)
+	if !ok {
+		return nil, ret.Error(1)
+	}
+	return advisories, ret.Error(1)
+}
+
+func (_m *MockCPEStore) DeleteCPEBucket() error {
+	ret := _m.Called()
+	return ret.Error(0)
+}
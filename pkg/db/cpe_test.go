@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_GetAdvisoriesByCPE(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_GetAdvisoriesByCPE_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		matches := map[string][]types.CPEMatch{
+			"CVE-2019-0001": {
+				{
+					CPE:                   "cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*",
+					VersionStartIncluding: "1.1.0",
+					VersionEndExcluding:   "1.1.0l",
+				},
+				{
+					CPE:                   "cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*",
+					VersionStartIncluding: "1.1.1",
+					VersionEndExcluding:   "1.1.1d",
+				},
+			},
+			"CVE-2019-0002": {
+				{CPE: "cpe:2.3:a:openssl:openssl:1.0.2:*:*:*:*:*:*:*"},
+				{CPE: "cpe:2.3:o:debian:debian_linux:9.0:*:*:*:*:*:*:*"},
+			},
+			"CVE-2019-0003": {
+				{
+					CPE:                 "cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*",
+					VersionEndIncluding: "1.1.1c",
+				},
+			},
+		}
+		for cveID, ms := range matches {
+			if err := dbc.PutCPEMatches(tx, cveID, ms); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		cpe     string
+		want    []types.Advisory
+		wantErr error
+	}{
+		{
+			name: "version in ranges",
+			cpe:  "cpe:2.3:a:openssl:openssl:1.1.1c:*:*:*:*:*:*:*",
+			want: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.1d"},
+				{VulnerabilityID: "CVE-2019-0003"},
+			},
+		},
+		{
+			name: "end of a range",
+			cpe:  "cpe:2.3:a:openssl:openssl:1.1.1d:*:*:*:*:*:*:*",
+		},
+		{
+			name: "exact version",
+			cpe:  "cpe:/a:openssl:openssl:1.0.2",
+			want: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0002"},
+				{VulnerabilityID: "CVE-2019-0003"},
+			},
+		},
+		{
+			name: "any version",
+			cpe:  "cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*",
+			want: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.0l"},
+				{VulnerabilityID: "CVE-2019-0002"},
+				{VulnerabilityID: "CVE-2019-0003"},
+			},
+		},
+		{
+			name: "other part",
+			cpe:  "cpe:2.3:o:openssl:openssl:1.0.2:*:*:*:*:*:*:*",
+		},
+		{
+			name: "unknown product",
+			cpe:  "cpe:2.3:a:gnu:glibc:2.28:*:*:*:*:*:*:*",
+		},
+		{
+			name:    "invalid CPE",
+			cpe:     "openssl:1.1.1",
+			wantErr: ErrInvalidCPE,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dbc.GetAdvisoriesByCPE(tt.cpe)
+			if tt.wantErr != nil {
+				assert.True(t, xerrors.Is(err, tt.wantErr), err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// light DBs drop the index
+	assert.NoError(t, dbc.DeleteCPEBucket())
+	assert.NoError(t, dbc.DeleteCPEBucket(), "no bucket")
+	got, err := dbc.GetAdvisoriesByCPE("cpe:2.3:a:openssl:openssl:1.1.1c:*:*:*:*:*:*:*")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestParseCPE(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want cpe
+	}{
+		{
+			name: "formatted string",
+			s:    "cpe:2.3:a:OpenSSL:OpenSSL:1.1.1c:*:*:*:*:*:*:*",
+			want: cpe{part: "a", vendor: "openssl", product: "openssl", version: "1.1.1c"},
+		},
+		{
+			name: "escaped colon",
+			s:    `cpe:2.3:a:vendor:prod\:uct:1.0\:beta:*:*:*:*:*:*:*`,
+			want: cpe{part: "a", vendor: "vendor", product: `prod\:uct`, version: "1.0:beta"},
+		},
+		{
+			name: "URI without version",
+			s:    "cpe:/o:debian:debian_linux",
+			want: cpe{part: "o", vendor: "debian", product: "debian_linux", version: "*"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCPE(tt.s)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
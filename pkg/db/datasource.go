@@ -0,0 +1,185 @@
+package db
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+const (
+	// dataSourceBucket holds a types.DataSource per data source ID
+	dataSourceBucket = "data-source"
+
+	// dataSourceSeparator separates the data source ID and the platform in the name of an advisory bucket,
+	// e.g. alpine::alpine 3.10
+	dataSourceSeparator = "::"
+)
+
+// the db package can't import vulnsrc, so the IDs are repeated here
+var dataSources = map[string]types.DataSource{
+	"alpine": {Name: "Alpine Secdb", URL: "https://secdb.alpinelinux.org/"},
+	"amazon": {Name: "Amazon Linux Security Center", URL: "https://alas.aws.amazon.com/",
+		SeverityScale: "ALAS severity"},
+	"debian": {Name: "Debian Security Tracker", URL: "https://security-tracker.debian.org/tracker/",
+		SeverityScale: "Debian urgency"},
+	"debian-oval": {Name: "Debian OVAL", URL: "https://www.debian.org/security/oval/"},
+	"ubuntu": {Name: "Ubuntu CVE Tracker", URL: "https://git.launchpad.net/ubuntu-cve-tracker",
+		SeverityScale: "Ubuntu priority"},
+	"redhat": {Name: "Red Hat Security Data API", URL: "https://access.redhat.com/security/data",
+		SeverityScale: "Red Hat threat severity"},
+	"redhat-oval": {Name: "Red Hat OVAL v2", URL: "https://www.redhat.com/security/data/oval/v2/",
+		SeverityScale: "Red Hat threat severity"},
+	"oracle-oval": {Name: "Oracle Linux OVAL", URL: "https://linux.oracle.com/security/oval/",
+		SeverityScale: "Oracle Linux severity"},
+	"nodejs-security-wg": {Name: "Node.js Ecosystem Security Working Group", URL: "https://github.com/nodejs/security-wg"},
+	"python-safety-db":   {Name: "Safety DB", URL: "https://github.com/pyupio/safety-db"},
+	"ruby-advisory-db":   {Name: "Ruby Advisory Database", URL: "https://github.com/rubysec/ruby-advisory-db"},
+	"rust-advisory-db":   {Name: "RustSec Advisory Database", URL: "https://github.com/RustSec/advisory-db"},
+	"php-security-advisories": {Name: "PHP Security Advisories Database",
+		URL: "https://github.com/FriendsOfPHP/security-advisories"},
+}
+
+// advisoryBucket returns the bucket of the advisories of the data source of dbc for a platform, e.g. debian 9
+func (dbc Config) advisoryBucket(platform string) string {
+	id := dbc.DataSource
+	if id == "" {
+		id = defaultDataSource(platform)
+	}
+	return AdvisoryBucket(id, platform)
+}
+
+// defaultDataSource is the data source of the writers not setting one, the ecosystem of the platform,
+// e.g. alpine for alpine 3.10 or npm for npm::GitHub Advisory Database
+func defaultDataSource(platform string) string {
+	if i := strings.Index(platform, dataSourceSeparator); i >= 0 {
+		platform = platform[:i]
+	}
+	ecosystem, _ := splitSource(platform)
+	return strings.Replace(strings.ToLower(ecosystem), " ", "-", -1)
+}
+
+// advisoryBuckets returns the existing buckets a lookup reads: the bucket itself if source is the name of one,
+// else the bucket of the platform of the data source of dbc, or of every data source when dbc has none.
+func (dbc Config) advisoryBuckets(tx Tx, source string) []string {
+	if tx.Bucket([]byte(source)) != nil {
+		return []string{source}
+	}
+	if dbc.DataSource != "" {
+		return []string{dbc.advisoryBucket(source)}
+	}
+
+	ids := []string{defaultDataSource(source)}
+	for id := range dataSources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var names []string
+	for i, id := range ids {
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+		name := AdvisoryBucket(id, source)
+		if tx.Bucket([]byte(name)) != nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// putDataSource records the data source of an advisory bucket, unless it already is
+func (dbc Config) putDataSource(tx Tx, id string) error {
+	root, err := tx.CreateBucketIfNotExists([]byte(dataSourceBucket))
+	if err != nil {
+		return xerrors.Errorf("failed to create a bucket: %w", err)
+	}
+	if root.Get([]byte(id)) != nil {
+		return nil
+	}
+	v, err := Marshal(knownDataSource(id))
+	if err != nil {
+		return err
+	}
+	return root.Put([]byte(id), v)
+}
+
+// knownDataSource returns the data source of an ID, named after it if it isn't a known one, e.g. of a plugin
+func knownDataSource(id string) types.DataSource {
+	ds, ok := dataSources[id]
+	if !ok {
+		ds = types.DataSource{Name: id}
+	}
+	ds.ID = id
+	return ds
+}
+
+// refreshDataSources records the data sources of the advisory buckets missing a record, e.g. after a migration
+func (dbc Config) refreshDataSources(tx Tx) error {
+	var ids []string
+	err := tx.ForEach(func(name []byte, _ storage.Bucket) error {
+		if id, _ := SplitAdvisoryBucket(string(name)); id != "" {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err = dbc.putDataSource(tx, id); err != nil {
+			return xerrors.Errorf("failed to save the data source %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// GetDataSource returns the data source of an advisory bucket, e.g. alpine::alpine 3.10
+func (dbc Config) GetDataSource(bucket string) (types.DataSource, error) {
+	id, _ := SplitAdvisoryBucket(bucket)
+	if id == "" {
+		return types.DataSource{}, xerrors.Errorf("%s isn't the name of an advisory bucket", bucket)
+	}
+	var ds types.DataSource
+	err := db.View(func(tx Tx) error {
+		root := tx.Bucket([]byte(dataSourceBucket))
+		if root == nil {
+			return xerrors.New("no data source bucket")
+		}
+		v, err := decode(root.Get([]byte(id)))
+		if err != nil {
+			return err
+		} else if v == nil {
+			return xerrors.Errorf("no data source %s", id)
+		}
+		return Unmarshal(v, &ds)
+	})
+	if err != nil {
+		return types.DataSource{}, xerrors.Errorf("failed to get the data source: %w", err)
+	}
+	return ds, nil
+}
+
+// bucketDataSource returns the data source of the advisories of a bucket, the known one if the DB has no
+// record of it, and nil for the buckets of no data source, e.g. of a DB built before the data sources
+func bucketDataSource(tx Tx, bucket string) (*types.DataSource, error) {
+	id, _ := SplitAdvisoryBucket(bucket)
+	if id == "" {
+		return nil, nil
+	}
+	ds := knownDataSource(id)
+	if root := tx.Bucket([]byte(dataSourceBucket)); root != nil {
+		v, err := decode(root.Get([]byte(id)))
+		if err != nil {
+			return nil, xerrors.Errorf("failed to decode the data source %s: %w", id, err)
+		}
+		if v != nil {
+			if err = Unmarshal(v, &ds); err != nil {
+				return nil, xerrors.Errorf("failed to unmarshal the data source %s: %w", id, err)
+			}
+		}
+	}
+	return &ds, nil
+}
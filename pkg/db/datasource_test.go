@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_DataSource(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_DataSource_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	redhat, redhatOVAL := Config{DataSource: "redhat"}, Config{DataSource: "redhat-oval"}
+	err = Config{}.BatchUpdate(context.Background(), func(tx Tx) error {
+		if err := redhat.PutAdvisory(tx, "Red Hat Enterprise Linux 8", "openssl", "CVE-2019-0001", types.Advisory{FixedVersion: "1.1.1c-2"}); err != nil {
+			return err
+		}
+		return redhatOVAL.PutAdvisory(tx, "Red Hat Enterprise Linux 8", "openssl", "CVE-2019-0002", types.Advisory{FixedVersion: "1.1.1c-15"})
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		dbc    Config
+		source string
+		want   []types.Advisory
+	}{
+		{
+			name:   "data source",
+			dbc:    redhatOVAL,
+			source: "Red Hat Enterprise Linux 8",
+			want:   []types.Advisory{{VulnerabilityID: "CVE-2019-0002", FixedVersion: "1.1.1c-15"}},
+		},
+		{
+			name:   "every data source",
+			source: "Red Hat Enterprise Linux 8",
+			want: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.1c-2"},
+				{VulnerabilityID: "CVE-2019-0002", FixedVersion: "1.1.1c-15"},
+			},
+		},
+		{
+			name:   "advisory bucket",
+			source: "redhat::Red Hat Enterprise Linux 8",
+			want:   []types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.1c-2"}},
+		},
+		{
+			name:   "unknown platform",
+			source: "Red Hat Enterprise Linux 9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dbc.GetAdvisories(tt.source, "openssl")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	ds, err := Config{}.GetDataSource("redhat-oval::Red Hat Enterprise Linux 8")
+	assert.NoError(t, err)
+	assert.Equal(t, types.DataSource{
+		ID:            "redhat-oval",
+		Name:          "Red Hat OVAL v2",
+		URL:           "https://www.redhat.com/security/data/oval/v2/",
+		SeverityScale: "Red Hat threat severity",
+	}, ds)
+	_, err = Config{}.GetDataSource("Red Hat Enterprise Linux 8")
+	assert.Error(t, err)
+
+	advisories, err := Config{}.GetAdvisoriesWithDataSource("Red Hat Enterprise Linux 8", "openssl")
+	assert.NoError(t, err)
+	if assert.Len(t, advisories, 2) {
+		assert.Equal(t, "CVE-2019-0001", advisories[0].VulnerabilityID)
+		assert.Equal(t, "Red Hat Security Data API", advisories[0].DataSource.Name)
+		assert.Equal(t, "CVE-2019-0002", advisories[1].VulnerabilityID)
+		assert.Equal(t, &ds, advisories[1].DataSource)
+	}
+}
+
+func TestInit_MigrateToDataSources(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestInit_MigrateToDataSources_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	// the layout of schema v1
+	assert.NoError(t, Init(d))
+	dbc := Config{}
+	err = db.Update(func(tx Tx) error {
+		v1 := []struct {
+			root, nested, key, value string
+		}{
+			{root: "amazon linux 2", nested: "curl", key: "CVE-2019-0001", value: `{"FixedVersion":"7.61.1-12"}`},
+			{root: "nodejs-security-wg", nested: "lodash", key: "CVE-2019-0002", value: `{"PatchedVersions":">=4.17.12"}`},
+			{root: affectedPackageBucket, nested: "CVE-2019-0001", key: "amazon linux 2/curl",
+				value: `{"Source":"amazon linux 2","Ecosystem":"amazon linux","Release":"2","Package":"curl"}`},
+		}
+		for _, r := range v1 {
+			root, err := tx.CreateBucketIfNotExists([]byte(r.root))
+			if err != nil {
+				return err
+			}
+			if err = dbc.put(root, r.nested, r.key, []byte(r.value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: 1, Type: TypeFull}))
+	assert.NoError(t, Close())
+
+	assert.NoError(t, Init(d))
+	defer Close()
+	assert.Equal(t, SchemaVersion, dbc.GetVersion())
+
+	advisories, err := dbc.GetAdvisories("amazon linux 2", "curl")
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: "7.61.1-12"}}, advisories)
+	advisories, err = Config{DataSource: "amazon"}.GetAdvisories("amazon linux 2", "curl")
+	assert.NoError(t, err)
+	assert.Len(t, advisories, 1)
+
+	for _, bucket := range []string{"amazon::amazon linux 2", "nodejs-security-wg::nodejs-security-wg"} {
+		_, err = dbc.GetDataSource(bucket)
+		assert.NoError(t, err, bucket)
+	}
+	values, err := dbc.forEach(affectedPackageBucket, "CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Contains(t, values, "amazon::amazon linux 2/curl")
+}
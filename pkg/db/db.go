@@ -1,82 +1,245 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	_ "github.com/aquasecurity/trivy-db/pkg/storage/boltdb"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
+	"github.com/klauspost/compress/zstd"
 	"golang.org/x/xerrors"
 )
 
 type Type int
 
 const (
-	SchemaVersion = 1
+	// SchemaVersion is bumped together with a migration in pkg/migrations
+	SchemaVersion = 2
 
 	TypeFull Type = iota
 	TypeLight
 )
 
 var (
-	db    *bolt.DB
+	db    storage.Store
 	dbDir string
 )
 
+// ErrNoBucket is returned when the DB lacks the bucket of a lookup, e.g. the vulnerability bucket of a light
+// DB. It is storage.ErrBucketNotFound, which deleting a missing bucket returns.
+var ErrNoBucket = storage.ErrBucketNotFound
+
+// Tx is a storage transaction passed to the Put functions
+type Tx = storage.Tx
+
 type Operations interface {
-	BatchUpdate(func(*bolt.Tx) error) error
+	BatchUpdater
+	MetadataStore
+	AdvisoryStore
+	VulnerabilityStore
+	CPEStore
+	ProvenanceStore
+}
 
-	PutVulnerabilityDetail(*bolt.Tx, string, string, types.VulnerabilityDetail) error
-	DeleteVulnerabilityDetailBucket() error
+type BatchUpdater interface {
+	BatchUpdate(context.Context, func(Tx) error) error
+	ChunkedUpdate(context.Context, int, int, func(Tx, int) error, func(int, int)) error
+}
+
+type MetadataStore interface {
+	GetVersion() int
+	GetMetadata() (Metadata, error)
+	SetMetadata(Metadata) error
+}
 
-	PutAdvisory(*bolt.Tx, string, string, string, interface{}) error
-	ForEachAdvisory(string, string) (map[string][]byte, error)
+// Pruner deletes the advisories a source no longer has
+type Pruner interface {
+	TrackWrites()
+	Prune(string) (int, error)
+	PruneChanged(string, map[string]bool) (int, error)
+}
+
+type AdvisoryStore interface {
+	PutAdvisory(Tx, string, string, string, interface{}) error
+	ForEachAdvisory(string, string, func(*types.DataSource, string, []byte) error) error
 	GetAdvisories(string, string) ([]types.Advisory, error)
+	GetAdvisoriesWithDataSource(string, string) ([]DataSourceAdvisory, error)
+	GetAdvisoriesForVersion(string, string, string, Comparer) ([]types.Advisory, error)
+	GetAdvisoriesByPURL(string) ([]types.Advisory, error)
+	GetAffectedPackages(string) ([]types.AffectedPackage, error)
+	DeleteAffectedPackageBucket() error
+}
 
-	PutSeverity(*bolt.Tx, string, types.Severity) error
-	GetSeverity(string) (types.Severity, error)
-	ForEachSeverity(f func(tx *bolt.Tx, cveID string, severity types.Severity) error) error
+type CPEStore interface {
+	PutCPEMatches(Tx, string, []types.CPEMatch) error
+	GetAdvisoriesByCPE(string) ([]types.Advisory, error)
+	DeleteCPEBucket() error
+}
 
+type VulnerabilityStore interface {
+	PutVulnerabilityDetail(Tx, string, string, types.VulnerabilityDetail) error
+	GetVulnerabilityDetail(string, string) (types.VulnerabilityDetail, error)
+	GetVulnerabilityDetails(string) (map[string]types.VulnerabilityDetail, error)
+	DeleteVulnerabilityDetailBucket() error
+
+	PutSeverity(Tx, string, types.Severity) error
+	GetSeverity(string) (types.Severity, error)
+	ForEachSeverity(f func(tx Tx, cveID string, severity types.Severity) error) error
 	DeleteSeverityBucket() error
 
-	PutVulnerability(*bolt.Tx, string, types.Vulnerability) error
+	PutVulnerability(Tx, string, types.Vulnerability) error
 	GetVulnerability(string) (types.Vulnerability, error)
+
+	PutSSVC(Tx, string, types.SSVC) error
+	GetSSVC(string) (types.SSVC, error)
 }
 
 type Metadata struct {
 	Version    int
 	Type       Type
 	NextUpdate time.Time
-	UpdatedAt  time.Time
+	UpdatedAt  time.Time // when the DB was built
+	// ValidFor is how long after UpdatedAt the DB may be used, see ExpiresAt
+	ValidFor time.Duration `json:",omitempty"`
+	// Channel is the release channel the DB was built for, stable if empty, see Channels
+	Channel     string            `json:",omitempty"`
+	Encoding    string            `json:",omitempty"`
+	Compression string            `json:",omitempty"`
+	Checksums   map[string]string `json:",omitempty"` // root bucket name => SHA-256
+	// Sources is keyed by the name of the source, e.g. alpine, and kept by builds updating other sources
+	Sources map[string]SourceMetadata `json:",omitempty"`
+}
+
+// SourceMetadata is the last successful update of a source
+type SourceMetadata struct {
+	UpdatedAt time.Time
+	Revision  string `json:",omitempty"` // the commit of the git repository the source is read from
+	// NewestRecordAt is the last update in which the source put an advisory it didn't have before,
+	// see Freshness
+	NewestRecordAt time.Time
+}
+
+// format is how the values of a DB are stored
+type format struct {
+	encoding string
+	// decoder is nil if the values aren't compressed
+	decoder *zstd.Decoder
+}
+
+// currentFormat is the format of the DB opened by Init
+func currentFormat() format {
+	return format{encoding: valueEncoding, decoder: decoder}
+}
+
+// loadFormat reads how values are stored from the metadata
+func loadFormat() error {
+	valueEncoding, decoder = EncodingJSON, nil
+	f, err := readFormat(db)
+	if err != nil {
+		return err
+	}
+	valueEncoding, decoder = f.encoding, f.decoder
+	return nil
+}
+
+// readFormat reads how the values of a store are stored from its metadata
+func readFormat(s storage.Store) (format, error) {
+	f := format{encoding: EncodingJSON}
+	metadata, err := getMetadata(s)
+	if err != nil {
+		// a new DB has no metadata
+		return f, nil
+	}
+	if f.encoding, err = parseEncoding(metadata); err != nil {
+		return format{}, err
+	}
+	if f.decoder, err = readDecoder(s, metadata); err != nil {
+		return format{}, err
+	}
+	return f, nil
 }
 
 type Config struct {
+	// DataSource is the ID of the data source whose advisory buckets the advisory functions use, e.g. redhat-oval.
+	// Lookups without one read the buckets of every data source with the platform.
+	DataSource string
+	// Logger is the logger of the maintenance of the DB, e.g. pruning, the default logger if nil
+	Logger *slog.Logger
 }
 
-func Init(cacheDir string) (err error) {
+func Init(cacheDir string) error {
+	return InitWithDriver(storage.DefaultDriver, cacheDir)
+}
+
+// InitWithDriver opens the DB with the named storage driver, which must have been registered by importing it
+func InitWithDriver(driverName, cacheDir string) (err error) {
 	dbPath := Path(cacheDir)
 	dbDir = filepath.Dir(dbPath)
 	if err = os.MkdirAll(dbDir, 0700); err != nil {
 		return xerrors.Errorf("failed to mkdir: %w", err)
 	}
 
-	db, err = bolt.Open(dbPath, 0600, nil)
+	db, err = storage.Open(driverName, dbPath, storage.Options{})
 	if err != nil {
 		return xerrors.Errorf("failed to open db: %w", err)
 	}
+	if err = migrate(); err != nil {
+		return err
+	}
+	if err = loadFormat(); err != nil {
+		return xerrors.Errorf("failed to load the DB format: %w", err)
+	}
 	return nil
 }
 
+// OpenReadOnly opens an existing DB for scanning. It takes a shared lock, so any number of
+// readers can use the DB at once, and Put functions fail. The staleness policy, if any, is applied.
+func OpenReadOnly(cacheDir string) error {
+	return OpenReadOnlyWithDriver(storage.DefaultDriver, cacheDir)
+}
+
+// OpenReadOnlyWithDriver opens an existing DB like OpenReadOnly, with the named storage driver
+func OpenReadOnlyWithDriver(driverName, cacheDir string) (err error) {
+	dbPath := Path(cacheDir)
+	s, err := storage.Open(driverName, dbPath, storage.Options{ReadOnly: true})
+	if err != nil {
+		return xerrors.Errorf("failed to open db: %w", err)
+	}
+	db, dbDir = s, filepath.Dir(dbPath)
+	// a DB that can't be used isn't left open, holding its lock
+	defer func() {
+		if err != nil {
+			_ = db.Close()
+			db = nil
+		}
+	}()
+	if err = loadFormat(); err != nil {
+		return xerrors.Errorf("failed to load the DB format: %w", err)
+	}
+	// a read-only DB can't be migrated
+	if version := (Config{}).GetVersion(); version != 0 && version != SchemaVersion {
+		return xerrors.Errorf("the DB schema v%d differs from v%d, open it writable to migrate", version, SchemaVersion)
+	}
+	return checkStaleness(time.Now())
+}
+
+// Path returns the path of the DB in cacheDir, whether or not it is the open one
 func Path(cacheDir string) string {
-	dbDir = filepath.Join(cacheDir, "db")
-	dbPath := filepath.Join(dbDir, "trivy.db")
-	return dbPath
+	return filepath.Join(cacheDir, "db", "trivy.db")
 }
 
+// Close closes the DB, if one is open
 func Close() error {
+	if db == nil {
+		return nil
+	}
 	if err := db.Close(); err != nil {
 		return xerrors.Errorf("failed to close DB: %w", err)
 	}
@@ -91,18 +254,50 @@ func (dbc Config) GetVersion() int {
 	return metadata.Version
 }
 func (dbc Config) GetMetadata() (Metadata, error) {
+	return getMetadata(db)
+}
+
+// getMetadata reads the metadata of a store, which is never compressed
+func getMetadata(s storage.Store) (Metadata, error) {
 	var metadata Metadata
-	value, err := Config{}.get("trivy", "metadata", "data")
+	err := s.View(func(tx Tx) error {
+		var value []byte
+		if root := tx.Bucket([]byte(metadataBucket)); root != nil {
+			if nested := root.Bucket([]byte("metadata")); nested != nil {
+				value = nested.Get([]byte("data"))
+			}
+		}
+		return json.Unmarshal(value, &metadata)
+	})
 	if err != nil {
 		return Metadata{}, err
 	}
-	if err = json.Unmarshal(value, &metadata); err != nil {
+	return metadata, nil
+}
+
+// ReadMetadata reads the metadata of a DB file, the DB opened by Init, if any, being left as is
+func ReadMetadata(driverName, path string) (Metadata, error) {
+	// storage.Open would create a missing file
+	if _, err := os.Stat(path); err != nil {
 		return Metadata{}, err
 	}
-	return metadata, nil
+	s, err := storage.Open(driverName, path, storage.Options{ReadOnly: true})
+	if err != nil {
+		return Metadata{}, xerrors.Errorf("failed to open db: %w", err)
+	}
+	defer s.Close()
+	return getMetadata(s)
 }
 
+// SetMetadata keeps the format fields, which are managed by this package
 func (dbc Config) SetMetadata(metadata Metadata) error {
+	metadata.Encoding, metadata.Compression = "", ""
+	if valueEncoding != EncodingJSON {
+		metadata.Encoding = valueEncoding
+	}
+	if decoder != nil {
+		metadata.Compression = CompressionZstd
+	}
 	err := dbc.update("trivy", "metadata", "data", metadata)
 	if err != nil {
 		return xerrors.Errorf("failed to save metadata: %w", err)
@@ -110,17 +305,54 @@ func (dbc Config) SetMetadata(metadata Metadata) error {
 	return nil
 }
 
-func (dbc Config) BatchUpdate(fn func(tx *bolt.Tx) error) error {
-	err := db.Batch(fn)
+// updateMetadata modifies the stored metadata in the transaction
+func (dbc Config) updateMetadata(tx Tx, fn func(*Metadata)) error {
+	var metadata Metadata
+	if root := tx.Bucket([]byte(metadataBucket)); root != nil {
+		if nested := root.Bucket([]byte("metadata")); nested != nil {
+			if v := nested.Get([]byte("data")); v != nil {
+				if err := json.Unmarshal(v, &metadata); err != nil {
+					return xerrors.Errorf("failed to unmarshal metadata: %w", err)
+				}
+			}
+		}
+	}
+	fn(&metadata)
+	v, err := json.Marshal(metadata)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal JSON: %w", err)
+	}
+	return dbc.putNestedBucket(tx, metadataBucket, "metadata", "data", v)
+}
+
+// BatchUpdate runs fn in a batch, unless ctx is done
+func (dbc Config) BatchUpdate(ctx context.Context, fn func(tx Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return xerrors.Errorf("batch update canceled: %w", err)
+	}
+	if err := enterCommit(ctx); err != nil {
+		return xerrors.Errorf("failed to wait for the turn to commit: %w", err)
+	}
+	var counting *countingTx
+	err := batchTx(func(tx Tx) error {
+		// a batch may run fn again, only the last run is committed
+		counting = &countingTx{tx: journalTx(tx)}
+		return fn(counting)
+	})
 	if err != nil {
 		return xerrors.Errorf("error in batch update: %w", err)
 	}
+	log.ProgressFrom(ctx).AddRecords(counting.puts)
 	return nil
 }
 
 func (dbc Config) update(rootBucket, nestedBucket, key string, value interface{}) error {
-	err := db.Update(func(tx *bolt.Tx) error {
-		return dbc.putNestedBucket(tx, rootBucket, nestedBucket, key, value)
+	v, err := json.Marshal(value)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal JSON: %w", err)
+	}
+	err = writeTx(func(tx Tx) error {
+		return dbc.putNestedBucket(tx, rootBucket, nestedBucket, key, v)
 	})
 	if err != nil {
 		return xerrors.Errorf("error in db update: %w", err)
@@ -128,7 +360,7 @@ func (dbc Config) update(rootBucket, nestedBucket, key string, value interface{}
 	return err
 }
 
-func (dbc Config) putNestedBucket(tx *bolt.Tx, rootBucket, nestedBucket, key string, value interface{}) error {
+func (dbc Config) putNestedBucket(tx Tx, rootBucket, nestedBucket, key string, value []byte) error {
 	root, err := tx.CreateBucketIfNotExists([]byte(rootBucket))
 	if err != nil {
 		return xerrors.Errorf("failed to create a bucket: %w", err)
@@ -136,20 +368,16 @@ func (dbc Config) putNestedBucket(tx *bolt.Tx, rootBucket, nestedBucket, key str
 	return dbc.put(root, nestedBucket, key, value)
 }
 
-func (dbc Config) put(root *bolt.Bucket, nestedBucket, key string, value interface{}) error {
+func (dbc Config) put(root storage.Bucket, nestedBucket, key string, value []byte) error {
 	nested, err := root.CreateBucketIfNotExists([]byte(nestedBucket))
 	if err != nil {
 		return xerrors.Errorf("failed to create a bucket: %w", err)
 	}
-	v, err := json.Marshal(value)
-	if err != nil {
-		return xerrors.Errorf("failed to unmarshal JSON: %w", err)
-	}
-	return nested.Put([]byte(key), v)
+	return nested.Put([]byte(key), value)
 }
 
 func (dbc Config) get(rootBucket, nestedBucket, key string) (value []byte, err error) {
-	err = db.View(func(tx *bolt.Tx) error {
+	err = db.View(func(tx Tx) error {
 		root := tx.Bucket([]byte(rootBucket))
 		if root == nil {
 			return nil
@@ -158,8 +386,8 @@ func (dbc Config) get(rootBucket, nestedBucket, key string) (value []byte, err e
 		if nested == nil {
 			return nil
 		}
-		value = nested.Get([]byte(key))
-		return nil
+		value, err = decode(nested.Get([]byte(key)))
+		return err
 	})
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get data from db: %w", err)
@@ -169,35 +397,62 @@ func (dbc Config) get(rootBucket, nestedBucket, key string) (value []byte, err e
 
 func (dbc Config) forEach(rootBucket, nestedBucket string) (value map[string][]byte, err error) {
 	value = map[string][]byte{}
-	err = db.View(func(tx *bolt.Tx) error {
-		root := tx.Bucket([]byte(rootBucket))
-		if root == nil {
-			return nil
-		}
-		nested := root.Bucket([]byte(nestedBucket))
-		if nested == nil {
-			return nil
-		}
-		err := nested.ForEach(func(k, v []byte) error {
-			value[string(k)] = v
-			return nil
-		})
-		if err != nil {
-			return xerrors.Errorf("error in db foreach: %w", err)
-		}
+	err = dbc.iterate(rootBucket, nestedBucket, func(k, v []byte) error {
+		value[string(k)] = append([]byte{}, v...)
 		return nil
 	})
 	if err != nil {
-		return nil, xerrors.Errorf("failed to get all key/value in the specified bucket: %w", err)
+		return nil, err
 	}
 	return value, nil
 }
 
+// iterate streams the decoded key/value pairs of a nested bucket. They are only valid during the callback.
+func (dbc Config) iterate(rootBucket, nestedBucket string, fn func(k, v []byte) error) error {
+	err := db.View(func(tx Tx) error {
+		return iterateNested(tx, rootBucket, nestedBucket, fn)
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to get all key/value in the specified bucket: %w", err)
+	}
+	return nil
+}
+
+func iterateNested(tx Tx, rootBucket, nestedBucket string, fn func(k, v []byte) error) error {
+	root := tx.Bucket([]byte(rootBucket))
+	if root == nil {
+		return nil
+	}
+	nested := root.Bucket([]byte(nestedBucket))
+	if nested == nil {
+		return nil
+	}
+	err := nested.ForEach(func(k, v []byte) error {
+		v, err := decode(v)
+		if err != nil {
+			return err
+		}
+		return fn(k, v)
+	})
+	if err != nil {
+		return xerrors.Errorf("error in db foreach: %w", err)
+	}
+	return nil
+}
+
 func (dbc Config) deleteBucket(bucketName string) error {
-	return db.Update(func(tx *bolt.Tx) error {
+	return writeTx(func(tx Tx) error {
 		if err := tx.DeleteBucket([]byte(bucketName)); err != nil {
 			return xerrors.Errorf("failed to delete bucket: %w", err)
 		}
 		return nil
 	})
 }
+
+// deleteBucketIfExists is deleteBucket for buckets only some sources create
+func (dbc Config) deleteBucketIfExists(bucketName string) error {
+	if err := dbc.deleteBucket(bucketName); err != nil && !xerrors.Is(err, ErrNoBucket) {
+		return err
+	}
+	return nil
+}
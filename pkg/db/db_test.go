@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestOpenReadOnly(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestOpenReadOnly_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.Error(t, OpenReadOnly(d), "no DB yet")
+
+	assert.NoError(t, Init(d))
+	dbc := Config{}
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		if err := dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{Title: "title"}); err != nil {
+			return err
+		}
+		return dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityLow)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, Close())
+
+	assert.NoError(t, OpenReadOnly(d))
+	defer Close()
+
+	// a DB failing to open leaves the open one alone
+	assert.Error(t, OpenReadOnlyWithDriver("unknown", d))
+	assert.Error(t, OpenReadOnly(filepath.Join(d, "missing")))
+
+	vuln, err := dbc.GetVulnerability("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, "title", vuln.Title)
+
+	severity, err := dbc.GetSeverity("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, types.SeverityLow, severity)
+
+	ssvc, err := dbc.GetSSVC("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, types.SSVC{}, ssvc)
+
+	advisories, err := dbc.GetAdvisories("alpine 3.10", "openssl")
+	assert.NoError(t, err)
+	assert.Empty(t, advisories)
+
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		return dbc.PutSeverity(tx, "CVE-2019-0002", types.SeverityLow)
+	})
+	assert.Error(t, err)
+	assert.NoError(t, Close())
+
+	// a DB of another schema is closed again
+	assert.NoError(t, Init(d))
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion + 1, Type: TypeFull}))
+	assert.NoError(t, Close())
+	assert.Error(t, OpenReadOnly(d))
+	assert.Nil(t, db)
+}
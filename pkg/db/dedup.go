@@ -0,0 +1,306 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+const (
+	// sharedDetailBucket holds the texts repeated across records: description|references|reference => hash => value
+	sharedDetailBucket = "shared-detail"
+	descriptionBucket  = "description"
+	referencesBucket   = "references"
+	referenceBucket    = "reference" // a single URL of the reference sets which differ
+
+	// minimum number of records sharing a text for it to be stored once
+	minShared = 2
+)
+
+// dedupBuckets are the buckets whose records may share texts. The per-source details only
+// exist during a build, the optimizers merge them into the vulnerability bucket.
+var dedupBuckets = []struct {
+	name      string
+	newRecord func() sharedRecord
+}{
+	{name: vulnerabilityDetailBucket, newRecord: func() sharedRecord { return &storedDetail{} }},
+	{name: vulnerabilityBucket, newRecord: func() sharedRecord { return &storedVulnerability{} }},
+}
+
+// sharedRecord is a stored value whose description and references may be replaced by a hash
+type sharedRecord interface {
+	fields() textFields
+}
+
+type textFields struct {
+	description     *string
+	references      *[]string
+	descriptionHash *string
+	referencesHash  *string
+
+	// referenceHashes has a hash for each shared URL and an empty string for each URL left in references,
+	// in the order of the references
+	referenceHashes *[]string
+}
+
+type storedDetail struct {
+	types.VulnerabilityDetail
+	DescriptionHash string   `json:",omitempty"`
+	ReferencesHash  string   `json:",omitempty"`
+	ReferenceHashes []string `json:",omitempty"`
+}
+
+func (s *storedDetail) fields() textFields {
+	return textFields{&s.Description, &s.References, &s.DescriptionHash, &s.ReferencesHash, &s.ReferenceHashes}
+}
+
+type storedVulnerability struct {
+	types.Vulnerability
+	DescriptionHash string   `json:",omitempty"`
+	ReferencesHash  string   `json:",omitempty"`
+	ReferenceHashes []string `json:",omitempty"`
+}
+
+func (s *storedVulnerability) fields() textFields {
+	return textFields{&s.Description, &s.References, &s.DescriptionHash, &s.ReferencesHash, &s.ReferenceHashes}
+}
+
+// Dedup stores the descriptions and references repeated across records once and replaces them
+// with their hash. A reference set which isn't repeated as a whole, e.g. the references of NVD and
+// a distribution for the same CVE, still shares its repeated URLs one by one. It can be run again
+// after updates, the shared texts are recounted.
+func (dbc Config) Dedup() error {
+	err := writeTx(func(tx Tx) error {
+		shared, err := tx.CreateBucketIfNotExists([]byte(sharedDetailBucket))
+		if err != nil {
+			return xerrors.Errorf("failed to create a bucket: %w", err)
+		}
+		r := resolver{shared: shared}
+
+		// count the texts
+		counts := map[string]int{}
+		err = walkDedupBuckets(tx, func(_ storage.Bucket, _ []byte, rec sharedRecord) error {
+			f := rec.fields()
+			if *f.description != "" {
+				counts[descriptionHash(*f.description)]++
+			}
+			if len(*f.references) > 0 {
+				counts[referencesHash(*f.references)]++
+			}
+			// a URL counts once per record
+			seen := map[string]bool{}
+			for _, ref := range *f.references {
+				if h := referenceHash(ref); !seen[h] {
+					seen[h] = true
+					counts[h]++
+				}
+			}
+			return nil
+		}, r)
+		if err != nil {
+			return xerrors.Errorf("failed to count shared texts: %w", err)
+		}
+
+		// replace the texts seen more than once
+		used := map[string]bool{}
+		err = walkDedupBuckets(tx, func(b storage.Bucket, k []byte, rec sharedRecord) error {
+			f := rec.fields()
+			if h := descriptionHash(*f.description); *f.description != "" && counts[h] >= minShared {
+				if err := putShared(shared, descriptionBucket, h, *f.description); err != nil {
+					return err
+				}
+				*f.description, *f.descriptionHash = "", h
+				used[h] = true
+			}
+			if h := referencesHash(*f.references); len(*f.references) > 0 && counts[h] >= minShared {
+				if err := putShared(shared, referencesBucket, h, *f.references); err != nil {
+					return err
+				}
+				*f.references, *f.referencesHash = nil, h
+				used[h] = true
+			} else if err := shareReferences(shared, f, counts, used); err != nil {
+				return err
+			}
+			value, err := Marshal(rec)
+			if err != nil {
+				return err
+			}
+			return b.Put(k, value)
+		}, r)
+		if err != nil {
+			return xerrors.Errorf("failed to replace shared texts: %w", err)
+		}
+
+		// drop the texts of a previous run that are no longer shared
+		for _, name := range []string{descriptionBucket, referencesBucket, referenceBucket} {
+			b := shared.Bucket([]byte(name))
+			if b == nil {
+				continue
+			}
+			err = walkBucket(b, func(b storage.Bucket, k, _ []byte) error {
+				if used[string(k)] {
+					return nil
+				}
+				return b.Delete(k)
+			})
+			if err != nil {
+				return xerrors.Errorf("failed to delete unused shared texts: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to deduplicate vulnerability details: %w", err)
+	}
+	return nil
+}
+
+// walkDedupBuckets calls fn with every record of dedupBuckets, its shared texts restored
+func walkDedupBuckets(tx Tx, fn func(b storage.Bucket, k []byte, rec sharedRecord) error, r resolver) error {
+	for _, d := range dedupBuckets {
+		root := tx.Bucket([]byte(d.name))
+		if root == nil {
+			continue
+		}
+		newRecord := d.newRecord
+		err := walkBucket(root, func(b storage.Bucket, k, v []byte) error {
+			rec := newRecord()
+			if err := r.resolve(v, rec); err != nil {
+				return err
+			}
+			return fn(b, k, rec)
+		})
+		if err != nil {
+			return xerrors.Errorf("error in %s: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+// shareReferences replaces the repeated URLs of a reference set with their hash, when the hash is shorter
+func shareReferences(shared storage.Bucket, f textFields, counts map[string]int, used map[string]bool) error {
+	var inline, hashes []string
+	for _, ref := range *f.references {
+		h := referenceHash(ref)
+		if counts[h] < minShared || len(ref) <= len(h) {
+			inline = append(inline, ref)
+			hashes = append(hashes, "")
+			continue
+		}
+		if err := putShared(shared, referenceBucket, h, ref); err != nil {
+			return err
+		}
+		hashes = append(hashes, h)
+		used[h] = true
+	}
+	if len(inline) == len(*f.references) {
+		return nil
+	}
+	*f.references, *f.referenceHashes = inline, hashes
+	return nil
+}
+
+func putShared(shared storage.Bucket, name, hash string, value interface{}) error {
+	b, err := shared.CreateBucketIfNotExists([]byte(name))
+	if err != nil {
+		return xerrors.Errorf("failed to create a bucket: %w", err)
+	}
+	if b.Get([]byte(hash)) != nil {
+		return nil
+	}
+	v, err := Marshal(value)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(hash), v)
+}
+
+// resolver restores the shared texts of stored records
+type resolver struct {
+	shared storage.Bucket
+}
+
+// resolve decodes a value into the record and replaces the hashes with the texts
+func (r resolver) resolve(value []byte, rec sharedRecord) error {
+	v, err := decode(value)
+	if err != nil {
+		return err
+	}
+	if err = Unmarshal(v, rec); err != nil {
+		return err
+	}
+	f := rec.fields()
+	if *f.descriptionHash != "" {
+		if err = r.get(descriptionBucket, *f.descriptionHash, f.description); err != nil {
+			return err
+		}
+		*f.descriptionHash = ""
+	}
+	if *f.referencesHash != "" {
+		if err = r.get(referencesBucket, *f.referencesHash, f.references); err != nil {
+			return err
+		}
+		*f.referencesHash = ""
+	}
+	if len(*f.referenceHashes) > 0 {
+		inline := *f.references
+		references := make([]string, 0, len(*f.referenceHashes))
+		for _, h := range *f.referenceHashes {
+			if h == "" {
+				if len(inline) == 0 {
+					return xerrors.New("missing inline reference")
+				}
+				references, inline = append(references, inline[0]), inline[1:]
+				continue
+			}
+			var ref string
+			if err = r.get(referenceBucket, h, &ref); err != nil {
+				return err
+			}
+			references = append(references, ref)
+		}
+		*f.references, *f.referenceHashes = references, nil
+	}
+	return nil
+}
+
+func (r resolver) get(name, hash string, v interface{}) error {
+	var b storage.Bucket
+	if r.shared != nil {
+		b = r.shared.Bucket([]byte(name))
+	}
+	if b == nil {
+		return xerrors.Errorf("no shared %s: %s", name, hash)
+	}
+	value, err := decode(b.Get([]byte(hash)))
+	if err != nil {
+		return err
+	} else if value == nil {
+		return xerrors.Errorf("no shared %s: %s", name, hash)
+	}
+	return Unmarshal(value, v)
+}
+
+func descriptionHash(description string) string {
+	return shortHash(description)
+}
+
+// references can't contain a newline
+func referencesHash(references []string) string {
+	return shortHash(strings.Join(references, "\n"))
+}
+
+// referenceHash is distinct from the hash of a set with a single reference
+func referenceHash(reference string) string {
+	return shortHash("\n" + reference)
+}
+
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Dedup(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Dedup_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	references := []string{"https://example.com/CVE-2019-0001"}
+	details := map[string]map[string]types.VulnerabilityDetail{
+		"CVE-2019-0001": {
+			"nvd":    {Description: "shared description", References: references, CvssScore: 7.5},
+			"redhat": {Description: "shared description", References: references, Title: "title"},
+			"debian": {Description: "own description"},
+		},
+		"CVE-2019-0002": {
+			"nvd": {Description: "shared description"},
+		},
+	}
+	put := func() {
+		err := dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+			for cveID, sources := range details {
+				for source, detail := range sources {
+					if err := dbc.PutVulnerabilityDetail(tx, cveID, source, detail); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+	assertDetails := func() {
+		for cveID, want := range details {
+			got, err := dbc.GetVulnerabilityDetails(cveID)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got, cveID)
+		}
+	}
+	put()
+
+	assert.NoError(t, dbc.Dedup())
+	assertDetails()
+
+	raw, err := dbc.get(vulnerabilityDetailBucket, "CVE-2019-0002", "nvd")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"DescriptionHash":"`+descriptionHash("shared description")+`"}`, string(raw))
+	raw, err = dbc.get(vulnerabilityDetailBucket, "CVE-2019-0001", "debian")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Description":"own description"}`, string(raw))
+
+	// the texts are no longer shared after an update
+	details["CVE-2019-0001"]["redhat"] = types.VulnerabilityDetail{Title: "title"}
+	delete(details, "CVE-2019-0002")
+	assert.NoError(t, dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		return tx.Bucket([]byte(vulnerabilityDetailBucket)).DeleteBucket([]byte("CVE-2019-0002"))
+	}))
+	put()
+
+	assert.NoError(t, dbc.Dedup())
+	assertDetails()
+
+	shared, err := dbc.forEach(sharedDetailBucket, descriptionBucket)
+	assert.NoError(t, err)
+	assert.Empty(t, shared)
+	shared, err = dbc.forEach(sharedDetailBucket, referencesBucket)
+	assert.NoError(t, err)
+	assert.Empty(t, shared)
+}
+
+func TestConfig_Dedup_Vulnerability(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Dedup_Vulnerability_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	vulns := map[string]types.Vulnerability{
+		"CVE-2019-0001": {Title: "title 1", Description: "shared description", Severity: "HIGH"},
+		"CVE-2019-0002": {Title: "title 2", Description: "shared description", References: []string{"https://example.com"}},
+	}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		for cveID, vuln := range vulns {
+			if err := dbc.PutVulnerability(tx, cveID, vuln); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, dbc.Dedup())
+
+	for cveID, want := range vulns {
+		got, err := dbc.GetVulnerability(cveID)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, cveID)
+	}
+
+	shared, err := dbc.forEach(sharedDetailBucket, descriptionBucket)
+	assert.NoError(t, err)
+	assert.Len(t, shared, 1)
+	shared, err = dbc.forEach(sharedDetailBucket, referencesBucket)
+	assert.NoError(t, err)
+	assert.Empty(t, shared)
+}
+
+func TestConfig_Dedup_Reference(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Dedup_Reference_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	const (
+		advisory = "https://access.redhat.com/security/cve/CVE-2019-0001"
+		mailing  = "https://www.openwall.com/lists/oss-security/2019/01/01/1"
+	)
+	dbc := Config{}
+	details := map[string]types.VulnerabilityDetail{
+		"nvd":    {References: []string{"https://nvd.example/1", advisory, mailing}},
+		"redhat": {References: []string{mailing, advisory, "https://bugzilla.redhat.com/1"}},
+		"ubuntu": {References: []string{"short", "short"}},
+		"debian": {References: []string{"short"}},
+	}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		for source, detail := range details {
+			if err := dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", source, detail); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, dbc.Dedup())
+
+	got, err := dbc.GetVulnerabilityDetails("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, details, got)
+
+	raw, err := dbc.get(vulnerabilityDetailBucket, "CVE-2019-0001", "nvd")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"References":["https://nvd.example/1"],"ReferenceHashes":["","`+
+		referenceHash(advisory)+`","`+referenceHash(mailing)+`"]}`, string(raw))
+
+	// the URLs shorter than their hash stay in the records
+	shared, err := dbc.forEach(sharedDetailBucket, referenceBucket)
+	assert.NoError(t, err)
+	assert.Len(t, shared, 2)
+}
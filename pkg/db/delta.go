@@ -0,0 +1,18 @@
+package db
+
+import (
+	"io"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/delta"
+)
+
+// ApplyDelta updates the DB with a delta made from it by "trivy-db delta"
+func ApplyDelta(r io.Reader) error {
+	if err := delta.Apply(db, r); err != nil {
+		return xerrors.Errorf("failed to apply the delta: %w", err)
+	}
+	// the delta may change the format as well
+	return loadFormat()
+}
@@ -0,0 +1,351 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+// DiffReport lists what changed between two DBs
+type DiffReport struct {
+	From Metadata
+	To   Metadata
+	// advisory sources with changes, sorted by name
+	Sources         []SourceDiff `json:",omitempty"`
+	Vulnerabilities Changes
+	// AddedSeverities counts the added vulnerabilities by severity, e.g. CRITICAL, UNKNOWN for the ones
+	// without any
+	AddedSeverities map[string]int `json:",omitempty"`
+	// VulnerabilityDetails is keyed by the source ID of the details, e.g. nvd
+	VulnerabilityDetails map[string]Changes `json:",omitempty"`
+}
+
+type SourceDiff struct {
+	Source  string
+	Added   []AdvisoryID `json:",omitempty"`
+	Removed []AdvisoryID `json:",omitempty"`
+	Changed []AdvisoryID `json:",omitempty"`
+	// FixedVersions are the changed advisories with another fixed version
+	FixedVersions []FixedVersionChange `json:",omitempty"`
+}
+
+type AdvisoryID struct {
+	Package         string
+	VulnerabilityID string
+}
+
+// FixedVersionChange is the fixed version of an advisory before and after, empty if it had none
+type FixedVersionChange struct {
+	AdvisoryID
+	From string `json:",omitempty"`
+	To   string `json:",omitempty"`
+}
+
+// Changes lists vulnerability IDs
+type Changes struct {
+	Added   []string `json:",omitempty"`
+	Removed []string `json:",omitempty"`
+	Changed []string `json:",omitempty"`
+}
+
+const (
+	added = iota
+	removed
+	changed
+)
+
+// Diff compares the advisories and vulnerabilities of two DB files, which may differ in encoding and compression.
+// The files are exported in turn, the DB opened by Init, if any, is left as is.
+func Diff(driverName, fromPath, toPath string) (DiffReport, error) {
+	dir, err := ioutil.TempDir("", "trivy-db-diff-")
+	if err != nil {
+		return DiffReport{}, xerrors.Errorf("failed to create a temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var exports []string
+	for i, path := range []string{fromPath, toPath} {
+		export := filepath.Join(dir, []string{"from", "to"}[i]+".ndjson")
+		if err = exportFile(driverName, path, export); err != nil {
+			return DiffReport{}, xerrors.Errorf("failed to export %s: %w", path, err)
+		}
+		exports = append(exports, export)
+	}
+
+	from, err := os.Open(exports[0])
+	if err != nil {
+		return DiffReport{}, xerrors.Errorf("failed to open the export: %w", err)
+	}
+	defer from.Close()
+	to, err := os.Open(exports[1])
+	if err != nil {
+		return DiffReport{}, xerrors.Errorf("failed to open the export: %w", err)
+	}
+	defer to.Close()
+
+	return diffExports(from, to)
+}
+
+// exportFile exports a DB file, the DB opened by Init, if any, being left as is
+func exportFile(driverName, path, output string) error {
+	// storage.Open would create a missing file
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	s, err := storage.Open(driverName, path, storage.Options{ReadOnly: true})
+	if err != nil {
+		return xerrors.Errorf("failed to open db: %w", err)
+	}
+	defer s.Close()
+	format, err := readFormat(s)
+	if err != nil {
+		return xerrors.Errorf("failed to load the DB format: %w", err)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return xerrors.Errorf("failed to create the export: %w", err)
+	}
+	defer f.Close()
+	if err = exportStore(s, format, f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// diffExports merges two exports, whose records are sorted by bucket path and key but for the metadata first
+func diffExports(from, to io.Reader) (DiffReport, error) {
+	fromRecords, toRecords := newRecordReader(from), newRecordReader(to)
+	var report DiffReport
+	var err error
+	if report.From, err = fromRecords.metadata(); err != nil {
+		return DiffReport{}, xerrors.Errorf("invalid metadata: %w", err)
+	}
+	if report.To, err = toRecords.metadata(); err != nil {
+		return DiffReport{}, xerrors.Errorf("invalid metadata: %w", err)
+	}
+
+	sources := map[string]*SourceDiff{}
+	var names []string
+	// a light DB only has the severities of the vulnerabilities
+	severities := vulnerabilityBucket
+	if report.To.Type == TypeLight {
+		severities = severityBucket
+	}
+	add := func(rec *ExportRecord, kind int) error {
+		if kind == added && rec.Bucket[0] == severities && len(rec.Bucket) == 1 {
+			severity, err := addedSeverity(rec)
+			if err != nil {
+				return xerrors.Errorf("%q %s: %w", rec.Bucket, rec.Key, err)
+			}
+			if report.AddedSeverities == nil {
+				report.AddedSeverities = map[string]int{}
+			}
+			report.AddedSeverities[severity]++
+		}
+
+		switch root := rec.Bucket[0]; {
+		case root == vulnerabilityBucket && len(rec.Bucket) == 1:
+			report.Vulnerabilities.add(kind, rec.Key)
+		case root == vulnerabilityDetailBucket && len(rec.Bucket) == 2:
+			if report.VulnerabilityDetails == nil {
+				report.VulnerabilityDetails = map[string]Changes{}
+			}
+			c := report.VulnerabilityDetails[rec.Key]
+			c.add(kind, rec.Bucket[1])
+			report.VulnerabilityDetails[rec.Key] = c
+		case utils.StringInSlice(root, nonAdvisoryBuckets) || len(rec.Bucket) != 2:
+			// indexes derived from the advisories
+		default:
+			s, ok := sources[root]
+			if !ok {
+				s = &SourceDiff{Source: root}
+				sources[root] = s
+				names = append(names, root)
+			}
+			s.add(kind, AdvisoryID{Package: rec.Bucket[1], VulnerabilityID: rec.Key})
+		}
+		return nil
+	}
+
+	for {
+		f, err := fromRecords.peek()
+		if err != nil {
+			return DiffReport{}, err
+		}
+		t, err := toRecords.peek()
+		if err != nil {
+			return DiffReport{}, err
+		}
+
+		switch c := compareRecords(f, t); {
+		case f == nil && t == nil:
+			sort.Strings(names)
+			for _, name := range names {
+				report.Sources = append(report.Sources, *sources[name])
+			}
+			return report, nil
+		case c < 0:
+			if err = add(f, removed); err != nil {
+				return DiffReport{}, err
+			}
+			fromRecords.next = nil
+		case c > 0:
+			if err = add(t, added); err != nil {
+				return DiffReport{}, err
+			}
+			toRecords.next = nil
+		default:
+			if equal, err := equalValues(f.Value, t.Value); err != nil {
+				return DiffReport{}, xerrors.Errorf("%q %s: %w", f.Bucket, f.Key, err)
+			} else if !equal {
+				if err = add(f, changed); err != nil {
+					return DiffReport{}, err
+				}
+				if s, ok := sources[f.Bucket[0]]; ok && len(f.Bucket) == 2 {
+					if err = s.addFixedVersion(f, t); err != nil {
+						return DiffReport{}, xerrors.Errorf("%q %s: %w", f.Bucket, f.Key, err)
+					}
+				}
+			}
+			fromRecords.next, toRecords.next = nil, nil
+		}
+	}
+}
+
+// addedSeverity returns the severity of a record of the vulnerability or the severity bucket
+func addedSeverity(rec *ExportRecord) (string, error) {
+	var severity string
+	if rec.Bucket[0] == severityBucket {
+		if err := json.Unmarshal(rec.Value, &severity); err != nil {
+			return "", err
+		}
+	} else {
+		var vuln types.Vulnerability
+		if err := json.Unmarshal(rec.Value, &vuln); err != nil {
+			return "", err
+		}
+		severity = vuln.Severity
+	}
+	if severity == "" {
+		return types.SeverityUnknown.String(), nil
+	}
+	return severity, nil
+}
+
+func (c *Changes) add(kind int, id string) {
+	switch kind {
+	case added:
+		c.Added = append(c.Added, id)
+	case removed:
+		c.Removed = append(c.Removed, id)
+	default:
+		c.Changed = append(c.Changed, id)
+	}
+}
+
+func (s *SourceDiff) add(kind int, id AdvisoryID) {
+	switch kind {
+	case added:
+		s.Added = append(s.Added, id)
+	case removed:
+		s.Removed = append(s.Removed, id)
+	default:
+		s.Changed = append(s.Changed, id)
+	}
+}
+
+// addFixedVersion adds the advisory if the records have different fixed versions
+func (s *SourceDiff) addFixedVersion(from, to *ExportRecord) error {
+	var f, t types.Advisory
+	if err := json.Unmarshal(from.Value, &f); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(to.Value, &t); err != nil {
+		return err
+	}
+	if f.FixedVersion != t.FixedVersion {
+		id := AdvisoryID{Package: from.Bucket[1], VulnerabilityID: from.Key}
+		s.FixedVersions = append(s.FixedVersions, FixedVersionChange{AdvisoryID: id, From: f.FixedVersion, To: t.FixedVersion})
+	}
+	return nil
+}
+
+type recordReader struct {
+	dec  *json.Decoder
+	next *ExportRecord
+}
+
+func newRecordReader(r io.Reader) *recordReader {
+	return &recordReader{dec: json.NewDecoder(r)}
+}
+
+func (r *recordReader) metadata() (Metadata, error) {
+	return readMetadata(r.dec)
+}
+
+// peek returns the next record without consuming it, nil at the end
+func (r *recordReader) peek() (*ExportRecord, error) {
+	if r.next != nil {
+		return r.next, nil
+	}
+	var rec ExportRecord
+	if err := r.dec.Decode(&rec); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, xerrors.Errorf("failed to read the export: %w", err)
+	}
+	if len(rec.Bucket) == 0 {
+		return nil, xerrors.New("record without bucket")
+	}
+	r.next = &rec
+	return r.next, nil
+}
+
+// compareRecords orders records as Export writes them, nil is after any record
+func compareRecords(a, b *ExportRecord) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	pa, pb := append(append([]string{}, a.Bucket...), a.Key), append(append([]string{}, b.Bucket...), b.Key)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(pa) - len(pb)
+}
+
+// equalValues compares JSON values regardless of the order of the fields, which depends on the encoding
+func equalValues(a, b json.RawMessage) (bool, error) {
+	if bytes.Equal(a, b) {
+		return true, nil
+	}
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(va, vb), nil
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+type testAdvisory struct {
+	source  string
+	pkgName string
+	cveID   string
+	fixed   string
+}
+
+func TestDiff(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestDiff_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	from := buildDiffDB(t, filepath.Join(d, "from"), EncodingJSON, []testAdvisory{
+		{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0001", fixed: "1.1.1d-r0"},
+		{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0002", fixed: "1.1.1d-r1"},
+		{source: "alpine 3.10", pkgName: "curl", cveID: "CVE-2019-0003", fixed: "7.66.0-r0"},
+		{source: "debian 9", pkgName: "curl", cveID: "CVE-2019-0003", fixed: "7.52.1-5"},
+	}, map[string]string{"CVE-2019-0001": "padding oracle", "CVE-2019-0002": "timing", "CVE-2019-0003": "overflow"})
+	// a re-encoded copy only differs in the changes
+	to := buildDiffDB(t, filepath.Join(d, "to"), EncodingMsgpack, []testAdvisory{
+		{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0001", fixed: "1.1.1d-r0"},
+		{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0002", fixed: "1.1.1d-r2"},
+		{source: "alpine 3.11", pkgName: "musl", cveID: "CVE-2019-0004", fixed: "1.1.24-r0"},
+		{source: "debian 9", pkgName: "curl", cveID: "CVE-2019-0003", fixed: "7.52.1-5"},
+	}, map[string]string{"CVE-2019-0001": "padding oracle", "CVE-2019-0002": "timing attack", "CVE-2019-0004": "overflow"})
+
+	got, err := Diff(storage.DefaultDriver, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []SourceDiff{
+		{
+			Source:  "alpine::alpine 3.10",
+			Removed: []AdvisoryID{{Package: "curl", VulnerabilityID: "CVE-2019-0003"}},
+			Changed: []AdvisoryID{{Package: "openssl", VulnerabilityID: "CVE-2019-0002"}},
+			FixedVersions: []FixedVersionChange{
+				{
+					AdvisoryID: AdvisoryID{Package: "openssl", VulnerabilityID: "CVE-2019-0002"},
+					From:       "1.1.1d-r1",
+					To:         "1.1.1d-r2",
+				},
+			},
+		},
+		{
+			Source: "alpine::alpine 3.11",
+			Added:  []AdvisoryID{{Package: "musl", VulnerabilityID: "CVE-2019-0004"}},
+		},
+	}, got.Sources)
+	assert.Equal(t, Changes{
+		Added:   []string{"CVE-2019-0004"},
+		Removed: []string{"CVE-2019-0003"},
+		Changed: []string{"CVE-2019-0002"},
+	}, got.Vulnerabilities)
+	assert.Equal(t, map[string]int{"UNKNOWN": 1}, got.AddedSeverities)
+	assert.Equal(t, EncodingMsgpack, got.To.Encoding)
+
+	_, err = Diff(storage.DefaultDriver, from, filepath.Join(d, "missing.db"))
+	assert.Error(t, err)
+}
+
+func TestDiffExports_AddedSeverities(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want map[string]int
+	}{
+		{
+			name: "full",
+			from: `{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":1}}
+{"bucket":["vulnerability"],"key":"CVE-2019-0001","value":{"Severity":"HIGH"}}
+`,
+			to: `{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":1}}
+{"bucket":["vulnerability"],"key":"CVE-2019-0001","value":{"Severity":"CRITICAL"}}
+{"bucket":["vulnerability"],"key":"CVE-2019-0002","value":{"Severity":"CRITICAL"}}
+{"bucket":["vulnerability"],"key":"CVE-2019-0003","value":{"Severity":"LOW"}}
+{"bucket":["vulnerability"],"key":"CVE-2019-0004","value":{"Title":"no severity"}}
+`,
+			// a changed severity isn't a new vulnerability
+			want: map[string]int{"CRITICAL": 1, "LOW": 1, "UNKNOWN": 1},
+		},
+		{
+			name: "light",
+			from: `{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":2}}
+`,
+			to: `{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":2}}
+{"bucket":["severity"],"key":"CVE-2019-0001","value":"CRITICAL"}
+`,
+			want: map[string]int{"CRITICAL": 1},
+		},
+		{
+			name: "nothing added",
+			from: `{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":1}}
+{"bucket":["vulnerability"],"key":"CVE-2019-0001","value":{"Severity":"HIGH"}}
+`,
+			to: `{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":1}}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffExports(strings.NewReader(tt.from), strings.NewReader(tt.to))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.AddedSeverities)
+		})
+	}
+}
+
+func buildDiffDB(t *testing.T, cacheDir, encoding string, advisories []testAdvisory, titles map[string]string) string {
+	assert.NoError(t, Init(cacheDir))
+	defer Close()
+	assert.NoError(t, SetEncoding(encoding))
+
+	dbc := Config{}
+	err := dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		for _, a := range advisories {
+			if err := dbc.PutAdvisory(tx, a.source, a.pkgName, a.cveID, types.Advisory{FixedVersion: a.fixed}); err != nil {
+				return err
+			}
+		}
+		for cveID, title := range titles {
+			if err := dbc.PutVulnerability(tx, cveID, types.Vulnerability{Title: title}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+	return Path(cacheDir)
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// DryRun is a build into a throwaway copy of a DB, so that what the build would change can be reviewed
+// before it produces an artifact. The DB itself is only read.
+type DryRun struct {
+	// dir holds the copy and the exports compared by Report
+	dir string
+}
+
+// StartDryRun opens, like InitWithDriver, a copy of the DB in cacheDir, or a new DB if there is none yet,
+// and exports it for Report
+func StartDryRun(driverName, cacheDir string) (*DryRun, error) {
+	dir, err := ioutil.TempDir("", "trivy-db-dry-run-")
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create a temp dir: %w", err)
+	}
+	run := &DryRun{dir: dir}
+
+	copyDir := filepath.Join(dir, "copy")
+	if err = copyDB(driverName, Path(cacheDir), Path(copyDir)); err != nil {
+		os.RemoveAll(dir)
+		return nil, xerrors.Errorf("failed to copy the DB: %w", err)
+	}
+	if err = InitWithDriver(driverName, copyDir); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err = run.export("base.ndjson"); err != nil {
+		run.Close()
+		return nil, err
+	}
+	return run, nil
+}
+
+// Report compares the advisories and vulnerabilities of the copy with the ones it started with
+func (r *DryRun) Report() (DiffReport, error) {
+	if err := r.export("build.ndjson"); err != nil {
+		return DiffReport{}, err
+	}
+
+	base, err := os.Open(filepath.Join(r.dir, "base.ndjson"))
+	if err != nil {
+		return DiffReport{}, xerrors.Errorf("failed to open the export: %w", err)
+	}
+	defer base.Close()
+	build, err := os.Open(filepath.Join(r.dir, "build.ndjson"))
+	if err != nil {
+		return DiffReport{}, xerrors.Errorf("failed to open the export: %w", err)
+	}
+	defer build.Close()
+
+	return diffExports(base, build)
+}
+
+// Close closes the copy and removes it
+func (r *DryRun) Close() error {
+	defer os.RemoveAll(r.dir)
+	return Close()
+}
+
+func (r *DryRun) export(name string) error {
+	f, err := os.Create(filepath.Join(r.dir, name))
+	if err != nil {
+		return xerrors.Errorf("failed to create the export: %w", err)
+	}
+	defer f.Close()
+
+	if _, err = (Config{}).GetMetadata(); err != nil {
+		// a new DB, which has nothing else than its schema to compare
+		rec := ExportRecord{Bucket: []string{metadataBucket, "metadata"}, Key: "data", Value: json.RawMessage("{}")}
+		if err = json.NewEncoder(f).Encode(rec); err != nil {
+			return xerrors.Errorf("failed to write the export: %w", err)
+		}
+	} else if err = (Config{}).Export(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// copyDB copies the stored values of the DB file at src, if there is one, to a new one at dst,
+// committing every DefaultChunkSize values
+func copyDB(driverName, src, dst string) error {
+	// storage.Open would create a missing file
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	from, err := storage.Open(driverName, src, storage.Options{ReadOnly: true})
+	if err != nil {
+		return xerrors.Errorf("failed to open %s: %w", src, err)
+	}
+	defer from.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return xerrors.Errorf("failed to mkdir: %w", err)
+	}
+	to, err := storage.Open(driverName, dst, storage.Options{})
+	if err != nil {
+		return xerrors.Errorf("failed to open %s: %w", dst, err)
+	}
+	defer to.Close()
+
+	var pending []shardRecord
+	flush := func() error {
+		err := to.Update(func(tx storage.Tx) error {
+			return copyShardRecords(tx, pending)
+		})
+		pending = pending[:0]
+		return err
+	}
+	err = from.View(func(tx storage.Tx) error {
+		err := tx.ForEach(func(name []byte, b storage.Bucket) error {
+			return walkShardBucket(b, []string{string(name)}, func(bucket []string, k, v []byte) error {
+				// the values are only valid during the transaction
+				pending = append(pending, shardRecord{
+					bucket: bucket,
+					key:    append([]byte{}, k...),
+					value:  append([]byte{}, v...),
+				})
+				if len(pending) < DefaultChunkSize {
+					return nil
+				}
+				return flush()
+			})
+		})
+		if err != nil {
+			return err
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	return to.Close()
+}
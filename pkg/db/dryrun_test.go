@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestStartDryRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		empty bool // no DB before the dry run
+		want  []SourceDiff
+	}{
+		{
+			name: "existing DB",
+			want: []SourceDiff{
+				{
+					Source:  "alpine::alpine 3.10",
+					Added:   []AdvisoryID{{Package: "openssl", VulnerabilityID: "CVE-2019-0003"}},
+					Changed: []AdvisoryID{{Package: "openssl", VulnerabilityID: "CVE-2019-0002"}},
+					FixedVersions: []FixedVersionChange{
+						{
+							AdvisoryID: AdvisoryID{Package: "openssl", VulnerabilityID: "CVE-2019-0002"},
+							From:       "1.0",
+							To:         "2.0",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "new DB",
+			empty: true,
+			want: []SourceDiff{
+				{
+					Source: "alpine::alpine 3.10",
+					Added: []AdvisoryID{
+						{Package: "openssl", VulnerabilityID: "CVE-2019-0002"},
+						{Package: "openssl", VulnerabilityID: "CVE-2019-0003"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestStartDryRun_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+
+			dbc := Config{}
+			ctx := context.Background()
+			if !tt.empty {
+				assert.NoError(t, Init(d))
+				assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+					for _, cveID := range []string{"CVE-2019-0001", "CVE-2019-0002"} {
+						if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", cveID, types.Advisory{FixedVersion: "1.0"}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}))
+				assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion}))
+				assert.NoError(t, Close())
+			}
+			before, _ := ioutil.ReadFile(Path(d))
+
+			run, err := StartDryRun(storage.DefaultDriver, d)
+			assert.NoError(t, err)
+			assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0002", types.Advisory{FixedVersion: "2.0"}); err != nil {
+					return err
+				}
+				return dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0003", types.Advisory{FixedVersion: "1.0"})
+			}))
+			assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion}))
+
+			report, err := run.Report()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, report.Sources)
+			assert.NoError(t, run.Close())
+
+			// the DB is only read
+			after, _ := ioutil.ReadFile(Path(d))
+			assert.Equal(t, before, after)
+			_, err = os.Stat(run.dir)
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v4"
+	"golang.org/x/xerrors"
+)
+
+const (
+	EncodingJSON    = "json"
+	EncodingMsgpack = "msgpack"
+)
+
+// valueEncoding is how advisories, vulnerabilities and SSVC decision points are stored.
+// It is loaded from the metadata by Init.
+var valueEncoding = EncodingJSON
+
+// SetEncoding selects the value encoding of a new DB. The encoding of an existing DB can't be changed.
+func SetEncoding(encoding string) error {
+	if encoding == "" {
+		encoding = EncodingJSON
+	}
+	if encoding != EncodingJSON && encoding != EncodingMsgpack {
+		return xerrors.Errorf("unknown encoding: %s", encoding)
+	}
+	if _, err := (Config{}).GetMetadata(); err == nil && encoding != valueEncoding {
+		return xerrors.Errorf("the DB is encoded with %s, remove it to change the encoding", valueEncoding)
+	}
+	valueEncoding = encoding
+	return nil
+}
+
+// Marshal encodes a value with the encoding of the DB
+func Marshal(v interface{}) ([]byte, error) {
+	return currentFormat().marshal(v)
+}
+
+// Unmarshal decodes a value with the encoding of the DB, e.g. the ones passed by ForEachAdvisory
+func Unmarshal(data []byte, v interface{}) error {
+	return currentFormat().unmarshal(data, v)
+}
+
+func (f format) marshal(v interface{}) ([]byte, error) {
+	if f.encoding == EncodingMsgpack {
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf).UseJSONTag(true).UseCompactEncoding(true).SortMapKeys(true)
+		if err := enc.Encode(v); err != nil {
+			return nil, xerrors.Errorf("failed to marshal MessagePack: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal JSON: %w", err)
+	}
+	return b, nil
+}
+
+func (f format) unmarshal(data []byte, v interface{}) error {
+	if f.encoding == EncodingMsgpack {
+		if err := msgpack.NewDecoder(bytes.NewReader(data)).UseJSONTag(true).Decode(v); err != nil {
+			return xerrors.Errorf("failed to unmarshal MessagePack: %w", err)
+		}
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return xerrors.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return nil
+}
+
+func parseEncoding(metadata Metadata) (string, error) {
+	switch metadata.Encoding {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingMsgpack:
+		return EncodingMsgpack, nil
+	default:
+		return "", xerrors.Errorf("unknown encoding: %s", metadata.Encoding)
+	}
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestSetEncoding(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestSetEncoding_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	assert.NoError(t, SetEncoding(EncodingMsgpack))
+
+	dbc := Config{}
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+
+	detail := types.VulnerabilityDetail{
+		ID:          "CVE-2019-0001",
+		CvssScore:   7.5,
+		Severity:    types.SeverityHigh,
+		References:  []string{"https://example.com"},
+		Description: "description",
+		SSVC:        &types.SSVC{Exploitation: "poc"},
+	}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		return dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", "nvd", detail)
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, Close())
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	metadata, err := dbc.GetMetadata()
+	assert.NoError(t, err)
+	assert.Equal(t, EncodingMsgpack, metadata.Encoding)
+
+	got, err := dbc.GetVulnerabilityDetails("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]types.VulnerabilityDetail{"nvd": detail}, got)
+
+	err = SetEncoding(EncodingJSON)
+	assert.EqualError(t, err, "the DB is encoded with msgpack, remove it to change the encoding")
+	assert.NoError(t, SetEncoding(EncodingMsgpack))
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v4"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// ExportRecord is a line of Export, a value and where it is stored
+type ExportRecord struct {
+	// Bucket is the path of bucket names from the root, e.g. ["alpine 3.12", "openssl"]
+	Bucket []string `json:"bucket"`
+	Key    string   `json:"key"`
+	// Value is JSON whatever the encoding of the DB. Severities, which are stored as text, are JSON strings.
+	Value json.RawMessage `json:"value"`
+}
+
+// Export writes every value of the DB as an ExportRecord per line, starting with the metadata.
+// Values are decompressed, and the compression dictionary and the records of Prune are left out.
+func (dbc Config) Export(w io.Writer) error {
+	return exportStore(db, currentFormat(), w)
+}
+
+// exportStore writes the export of a store whose values are stored in the format
+func exportStore(s storage.Store, f format, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	err := s.View(func(tx Tx) error {
+		root := tx.Bucket([]byte(metadataBucket))
+		if root == nil || root.Bucket([]byte("metadata")) == nil {
+			return xerrors.New("no metadata")
+		}
+		path := []string{metadataBucket, "metadata"}
+		if err := exportBucket(enc, f, path, root.Bucket([]byte("metadata"))); err != nil {
+			return err
+		}
+
+		return tx.ForEach(func(name []byte, b storage.Bucket) error {
+			if string(name) == metadataBucket {
+				return nil
+			}
+			return exportBucket(enc, f, []string{string(name)}, b)
+		})
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to export the DB: %w", err)
+	}
+	if err = bw.Flush(); err != nil {
+		return xerrors.Errorf("failed to write the export: %w", err)
+	}
+	return nil
+}
+
+func exportBucket(enc *json.Encoder, f format, path []string, b storage.Bucket) error {
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			nested := append(append([]string{}, path...), string(k))
+			return exportBucket(enc, f, nested, b.Bucket(k))
+		}
+		value, err := f.exportValue(path[0], v)
+		if err != nil {
+			return xerrors.Errorf("%q %s: %w", path, k, err)
+		}
+		return enc.Encode(ExportRecord{Bucket: path, Key: string(k), Value: value})
+	})
+}
+
+// exportValue converts a stored value of a root bucket to JSON
+func (f format) exportValue(rootBucket string, v []byte) (json.RawMessage, error) {
+	v, err := f.decode(v)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case rootBucket == severityBucket:
+		return json.Marshal(string(v))
+	case rootBucket == metadataBucket || f.encoding == EncodingJSON:
+		if !json.Valid(v) {
+			return nil, xerrors.New("invalid JSON")
+		}
+		return append(json.RawMessage{}, v...), nil
+	}
+
+	var value interface{}
+	if err = msgpack.NewDecoder(bytes.NewReader(v)).UseJSONTag(true).Decode(&value); err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal MessagePack: %w", err)
+	}
+	return json.Marshal(value)
+}
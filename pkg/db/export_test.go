@@ -0,0 +1,96 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Export(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		compress bool
+		want     []string
+	}{
+		{
+			name:     "json",
+			encoding: EncodingJSON,
+			want: []string{
+				`{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":1,"NextUpdate":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z"}}`,
+				`{"bucket":["affected-package","CVE-2019-0001"],"key":"alpine::alpine 3.10/openssl","value":{"DataSource":"alpine","Source":"alpine 3.10","Ecosystem":"alpine","Release":"3.10","Package":"openssl"}}`,
+				`{"bucket":["alpine::alpine 3.10","openssl"],"key":"CVE-2019-0001","value":{"FixedVersion":"1.1.1d-r0"}}`,
+				`{"bucket":["data-source"],"key":"alpine","value":{"ID":"alpine","Name":"Alpine Secdb","URL":"https://secdb.alpinelinux.org/"}}`,
+				`{"bucket":["severity"],"key":"CVE-2019-0001","value":"HIGH"}`,
+				`{"bucket":["vulnerability-detail","CVE-2019-0001"],"key":"nvd","value":{"CvssScoreV3":7.5,"SeverityV3":3,"Title":"openssl: padding oracle"}}`,
+			},
+		},
+		{
+			name:     "msgpack",
+			encoding: EncodingMsgpack,
+			want: []string{
+				`{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":1,"NextUpdate":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","Encoding":"msgpack"}}`,
+				`{"bucket":["affected-package","CVE-2019-0001"],"key":"alpine::alpine 3.10/openssl","value":{"DataSource":"alpine","Ecosystem":"alpine","Package":"openssl","Release":"3.10","Source":"alpine 3.10"}}`,
+				`{"bucket":["alpine::alpine 3.10","openssl"],"key":"CVE-2019-0001","value":{"FixedVersion":"1.1.1d-r0"}}`,
+				`{"bucket":["data-source"],"key":"alpine","value":{"ID":"alpine","Name":"Alpine Secdb","URL":"https://secdb.alpinelinux.org/"}}`,
+				`{"bucket":["severity"],"key":"CVE-2019-0001","value":"HIGH"}`,
+				`{"bucket":["vulnerability-detail","CVE-2019-0001"],"key":"nvd","value":{"CvssScoreV3":7.5,"SeverityV3":3,"Title":"openssl: padding oracle"}}`,
+			},
+		},
+		{
+			name:     "compressed",
+			encoding: EncodingJSON,
+			compress: true,
+			want: []string{
+				`{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":1,"NextUpdate":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","Compression":"zstd"}}`,
+				`{"bucket":["affected-package","CVE-2019-0001"],"key":"alpine::alpine 3.10/openssl","value":{"DataSource":"alpine","Source":"alpine 3.10","Ecosystem":"alpine","Release":"3.10","Package":"openssl"}}`,
+				`{"bucket":["alpine::alpine 3.10","openssl"],"key":"CVE-2019-0001","value":{"FixedVersion":"1.1.1d-r0"}}`,
+				`{"bucket":["data-source"],"key":"alpine","value":{"ID":"alpine","Name":"Alpine Secdb","URL":"https://secdb.alpinelinux.org/"}}`,
+				`{"bucket":["severity"],"key":"CVE-2019-0001","value":"HIGH"}`,
+				`{"bucket":["vulnerability-detail","CVE-2019-0001"],"key":"nvd","value":{"CvssScoreV3":7.5,"SeverityV3":3,"Title":"openssl: padding oracle"}}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestConfig_Export_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+
+			assert.NoError(t, Init(d))
+			defer Close()
+			assert.NoError(t, SetEncoding(tt.encoding))
+
+			dbc := Config{}
+			err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001", types.Advisory{FixedVersion: "1.1.1d-r0"}); err != nil {
+					return err
+				}
+				if err := dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityHigh); err != nil {
+					return err
+				}
+				return dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", "nvd", types.VulnerabilityDetail{
+					CvssScoreV3: 7.5,
+					SeverityV3:  types.SeverityHigh,
+					Title:       "openssl: padding oracle",
+				})
+			})
+			assert.NoError(t, err)
+			assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+			if tt.compress {
+				assert.NoError(t, dbc.Compress())
+			}
+
+			var buf bytes.Buffer
+			assert.NoError(t, dbc.Export(&buf))
+			assert.Equal(t, tt.want, strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"))
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"encoding/json"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// Extraction is everything the DB has on a vulnerability, see Extract
+type Extraction struct {
+	ID string
+	// Vulnerability is the one of a full DB, and Severity the one of a light DB
+	Vulnerability *types.Vulnerability `json:",omitempty"`
+	Severity      string               `json:",omitempty"`
+	// Details are keyed by the source ID of the details, e.g. nvd
+	Details    map[string]types.VulnerabilityDetail `json:",omitempty"`
+	SSVC       *types.SSVC                          `json:",omitempty"`
+	Advisories []ExtractedAdvisory                  `json:",omitempty"`
+}
+
+// ExtractedAdvisory is an advisory of an affected package, as it is stored, with its provenance if it has one
+type ExtractedAdvisory struct {
+	types.AffectedPackage
+	Advisory   json.RawMessage   `json:",omitempty"`
+	Provenance *types.Provenance `json:",omitempty"`
+}
+
+// Extract returns everything the DB has on a vulnerability, e.g. for a bug report: its details by source and
+// the advisories of the packages it affects, found by the affected package index. It returns
+// ErrVulnerabilityNotFound when the DB has nothing on the ID.
+func (dbc Config) Extract(cveID string) (Extraction, error) {
+	extraction := Extraction{ID: cveID}
+	err := db.View(func(tx Tx) error {
+		r := resolver{shared: tx.Bucket([]byte(sharedDetailBucket))}
+		if b := tx.Bucket([]byte(vulnerabilityBucket)); b != nil {
+			if v := b.Get([]byte(cveID)); v != nil {
+				var vuln storedVulnerability
+				if err := r.resolve(v, &vuln); err != nil {
+					return xerrors.Errorf("invalid vulnerability: %w", err)
+				}
+				extraction.Vulnerability = &vuln.Vulnerability
+			}
+		}
+		if b := tx.Bucket([]byte(severityBucket)); b != nil {
+			extraction.Severity = string(b.Get([]byte(cveID)))
+		}
+
+		if root := tx.Bucket([]byte(vulnerabilityDetailBucket)); root != nil && root.Bucket([]byte(cveID)) != nil {
+			extraction.Details = map[string]types.VulnerabilityDetail{}
+			err := root.Bucket([]byte(cveID)).ForEach(func(source, v []byte) error {
+				var detail storedDetail
+				if err := r.resolve(v, &detail); err != nil {
+					return xerrors.Errorf("invalid detail of %s: %w", source, err)
+				}
+				extraction.Details[string(source)] = detail.VulnerabilityDetail
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if b := tx.Bucket([]byte(ssvcBucket)); b != nil {
+			v, err := decode(b.Get([]byte(cveID)))
+			if err != nil {
+				return err
+			} else if v != nil {
+				var ssvc types.SSVC
+				if err = Unmarshal(v, &ssvc); err != nil {
+					return xerrors.Errorf("invalid SSVC: %w", err)
+				}
+				extraction.SSVC = &ssvc
+			}
+		}
+
+		root := tx.Bucket([]byte(affectedPackageBucket))
+		if root == nil || root.Bucket([]byte(cveID)) == nil {
+			return nil
+		}
+		return root.Bucket([]byte(cveID)).ForEach(func(k, v []byte) error {
+			advisory, err := extractAdvisory(tx, cveID, v)
+			if err != nil {
+				return xerrors.Errorf("invalid advisory %s: %w", k, err)
+			}
+			extraction.Advisories = append(extraction.Advisories, advisory)
+			return nil
+		})
+	})
+	if err != nil {
+		return Extraction{}, xerrors.Errorf("failed to extract %s: %w", cveID, err)
+	}
+	if extraction.Vulnerability == nil && extraction.Severity == "" && extraction.Details == nil &&
+		extraction.SSVC == nil && extraction.Advisories == nil {
+		return Extraction{}, xerrors.Errorf("%s: %w", cveID, ErrVulnerabilityNotFound)
+	}
+	return extraction, nil
+}
+
+// extractAdvisory reads the advisory an entry of the affected package index refers to, and its provenance
+func extractAdvisory(tx Tx, cveID string, indexed []byte) (ExtractedAdvisory, error) {
+	v, err := decode(indexed)
+	if err != nil {
+		return ExtractedAdvisory{}, err
+	}
+	var extracted ExtractedAdvisory
+	if err = Unmarshal(v, &extracted.AffectedPackage); err != nil {
+		return ExtractedAdvisory{}, err
+	}
+	pkg := extracted.AffectedPackage
+	bucket := affectedBucket(pkg)
+
+	if b := tx.Bucket([]byte(bucket)); b != nil && b.Bucket([]byte(pkg.Package)) != nil {
+		if v = b.Bucket([]byte(pkg.Package)).Get([]byte(cveID)); v != nil {
+			if extracted.Advisory, err = currentFormat().exportValue(bucket, v); err != nil {
+				return ExtractedAdvisory{}, err
+			}
+		}
+	}
+
+	if b := tx.Bucket([]byte(provenanceBucket)); b != nil && b.Bucket([]byte(bucket)) != nil {
+		v, err = decode(b.Bucket([]byte(bucket)).Get([]byte(pkg.Package + keySeparator + cveID)))
+		if err != nil {
+			return ExtractedAdvisory{}, err
+		} else if v != nil {
+			var provenance types.Provenance
+			if err = Unmarshal(v, &provenance); err != nil {
+				return ExtractedAdvisory{}, err
+			}
+			extracted.Provenance = &provenance
+		}
+	}
+	return extracted, nil
+}
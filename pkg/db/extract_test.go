@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Extract(t *testing.T) {
+	for _, encoding := range []string{EncodingJSON, EncodingMsgpack} {
+		t.Run(encoding, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestConfig_Extract_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+			assert.NoError(t, Init(d))
+			defer Close()
+			assert.NoError(t, SetEncoding(encoding))
+
+			dbc := Config{}
+			err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001",
+					types.Advisory{FixedVersion: "1.1.1d-r0"}); err != nil {
+					return err
+				}
+				if err := dbc.PutProvenance(tx, "alpine 3.10", "openssl", "CVE-2019-0001",
+					types.Provenance{Path: "alpine/3.10/main/openssl.json"}); err != nil {
+					return err
+				}
+				if err := dbc.PutAdvisory(tx, "debian 9", "openssl", "CVE-2019-0001", types.Advisory{}); err != nil {
+					return err
+				}
+				if err := dbc.PutAdvisory(tx, "debian 9", "curl", "CVE-2019-0002", types.Advisory{}); err != nil {
+					return err
+				}
+				if err := dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", "nvd",
+					types.VulnerabilityDetail{Title: "padding oracle", CvssScoreV3: 5.9}); err != nil {
+					return err
+				}
+				if err := dbc.PutSSVC(tx, "CVE-2019-0001", types.SSVC{Exploitation: "none"}); err != nil {
+					return err
+				}
+				return dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{Title: "padding oracle"})
+			})
+			assert.NoError(t, err)
+
+			got, err := dbc.Extract("CVE-2019-0001")
+			assert.NoError(t, err)
+			assert.Equal(t, "padding oracle", got.Vulnerability.Title)
+			assert.Equal(t, map[string]types.VulnerabilityDetail{
+				"nvd": {Title: "padding oracle", CvssScoreV3: 5.9},
+			}, got.Details)
+			assert.Equal(t, &types.SSVC{Exploitation: "none"}, got.SSVC)
+			assert.Equal(t, []ExtractedAdvisory{
+				{
+					AffectedPackage: types.AffectedPackage{DataSource: "alpine", Source: "alpine 3.10", Ecosystem: "alpine",
+						Release: "3.10", Package: "openssl"},
+					Advisory:   []byte(`{"FixedVersion":"1.1.1d-r0"}`),
+					Provenance: &types.Provenance{Path: "alpine/3.10/main/openssl.json"},
+				},
+				{
+					AffectedPackage: types.AffectedPackage{DataSource: "debian", Source: "debian 9", Ecosystem: "debian",
+						Release: "9", Package: "openssl"},
+					Advisory: []byte(`{}`),
+				},
+			}, got.Advisories)
+
+			// an ID with only advisories, e.g. of a source without details
+			got, err = dbc.Extract("CVE-2019-0002")
+			assert.NoError(t, err)
+			assert.Nil(t, got.Vulnerability)
+			assert.Len(t, got.Advisories, 1)
+
+			_, err = dbc.Extract("CVE-2019-9999")
+			assert.True(t, xerrors.Is(err, ErrVulnerabilityNotFound))
+		})
+	}
+}
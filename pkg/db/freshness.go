@@ -0,0 +1,42 @@
+package db
+
+import (
+	"sort"
+	"time"
+)
+
+// SourceFreshness is how recent the records of a source are at the time the DB was built
+type SourceFreshness struct {
+	Source    string
+	UpdatedAt time.Time // the last successful update of the source
+	// NewestRecordAt is zero for the sources last updated before it was recorded
+	NewestRecordAt time.Time
+	// Age is the time between NewestRecordAt and the build, a source adding no record for long
+	// having most likely stopped being fetched
+	Age        time.Duration
+	StaleAfter time.Duration
+	Stale      bool
+}
+
+// Freshness compares the newest records of each source with the build date, the sources adding no
+// record for longer than staleAfter(source) being stale, and returns them by source
+func (m Metadata) Freshness(staleAfter func(source string) time.Duration) []SourceFreshness {
+	var report []SourceFreshness
+	for name, source := range m.Sources {
+		f := SourceFreshness{
+			Source:         name,
+			UpdatedAt:      source.UpdatedAt,
+			NewestRecordAt: source.NewestRecordAt,
+			StaleAfter:     staleAfter(name),
+		}
+		if !f.NewestRecordAt.IsZero() {
+			f.Age = m.UpdatedAt.Sub(f.NewestRecordAt)
+			f.Stale = f.StaleAfter > 0 && f.Age > f.StaleAfter
+		}
+		report = append(report, f)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Source < report[j].Source
+	})
+	return report
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadata_Freshness(t *testing.T) {
+	builtAt := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	metadata := Metadata{
+		UpdatedAt: builtAt,
+		Sources: map[string]SourceMetadata{
+			"nvd":    {UpdatedAt: builtAt, NewestRecordAt: builtAt},
+			"alpine": {UpdatedAt: builtAt, NewestRecordAt: builtAt.Add(-8 * 24 * time.Hour)},
+			// its update failed since
+			"debian": {UpdatedAt: builtAt.Add(-3 * 24 * time.Hour), NewestRecordAt: builtAt.Add(-3 * 24 * time.Hour)},
+			// quiet for long, but with a threshold of its own
+			"cargo": {UpdatedAt: builtAt, NewestRecordAt: builtAt.Add(-20 * 24 * time.Hour)},
+			// built before the newest records were recorded
+			"ubuntu": {UpdatedAt: builtAt},
+		},
+	}
+	staleAfter := func(source string) time.Duration {
+		if source == "cargo" {
+			return 30 * 24 * time.Hour
+		}
+		return 7 * 24 * time.Hour
+	}
+
+	got := metadata.Freshness(staleAfter)
+	want := []SourceFreshness{
+		{Source: "alpine", UpdatedAt: builtAt, NewestRecordAt: builtAt.Add(-8 * 24 * time.Hour),
+			Age: 8 * 24 * time.Hour, StaleAfter: 7 * 24 * time.Hour, Stale: true},
+		{Source: "cargo", UpdatedAt: builtAt, NewestRecordAt: builtAt.Add(-20 * 24 * time.Hour),
+			Age: 20 * 24 * time.Hour, StaleAfter: 30 * 24 * time.Hour},
+		{Source: "debian", UpdatedAt: builtAt.Add(-3 * 24 * time.Hour), NewestRecordAt: builtAt.Add(-3 * 24 * time.Hour),
+			Age: 3 * 24 * time.Hour, StaleAfter: 7 * 24 * time.Hour},
+		{Source: "nvd", UpdatedAt: builtAt, NewestRecordAt: builtAt, StaleAfter: 7 * 24 * time.Hour},
+		{Source: "ubuntu", UpdatedAt: builtAt, StaleAfter: 7 * 24 * time.Hour},
+	}
+	assert.Equal(t, want, got)
+
+	// no threshold, nothing is stale
+	for _, f := range metadata.Freshness(func(string) time.Duration { return 0 }) {
+		assert.False(t, f.Stale, f.Source)
+	}
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+type commitGateKey struct{}
+
+type commitGate struct {
+	once  sync.Once
+	enter func() error
+	err   error
+}
+
+// WithCommitGate returns a copy of ctx whose first BatchUpdate or ChunkedUpdate calls enter before writing
+// and fails with its error, so that a source can be parsed concurrently with others and commit in its turn
+func WithCommitGate(ctx context.Context, enter func() error) context.Context {
+	return context.WithValue(ctx, commitGateKey{}, &commitGate{enter: enter})
+}
+
+func enterCommit(ctx context.Context) error {
+	g, ok := ctx.Value(commitGateKey{}).(*commitGate)
+	if !ok {
+		return nil
+	}
+	g.once.Do(func() {
+		g.err = g.enter()
+	})
+	return g.err
+}
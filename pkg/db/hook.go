@@ -0,0 +1,106 @@
+package db
+
+import (
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// hooks are set by SetHooks
+var hooks []Hooks
+
+// Hooks are called around PutAdvisory, PutVulnerabilityDetail, GetAdvisories and ForEachAdvisory, e.g. by
+// an embedder auditing or counting the records of the sources, or rewriting them, without changing the sources.
+// A nil hook is skipped.
+type Hooks struct {
+	// BeforePutAdvisory returns the advisory to store instead, or fails the Put. The package name is normalized.
+	BeforePutAdvisory func(source, pkgName, cveID string, advisory interface{}) (interface{}, error)
+	// AfterPutAdvisory is told the error of the Put, nil if the advisory was stored
+	AfterPutAdvisory func(source, pkgName, cveID string, err error)
+
+	// BeforePutVulnerabilityDetail returns the detail to store instead, or fails the Put
+	BeforePutVulnerabilityDetail func(cveID, source string, detail types.VulnerabilityDetail) (types.VulnerabilityDetail, error)
+	// AfterPutVulnerabilityDetail is told the error of the Put, nil if the detail was stored
+	AfterPutVulnerabilityDetail func(cveID, source string, err error)
+
+	// BeforeGetAdvisories is told the lookup, by GetAdvisories or ForEachAdvisory, e.g. to time it
+	BeforeGetAdvisories func(source, pkgName string)
+	// AfterGetAdvisories returns the advisories and the error of the lookup, possibly rewritten
+	AfterGetAdvisories func(source, pkgName string, advisories []types.Advisory, err error) ([]types.Advisory, error)
+	// AfterForEachAdvisory returns the error of the lookup of ForEachAdvisory, possibly rewritten. The advisories
+	// of the lookup, e.g. the ones of the language sources, are passed to its callback as they are stored, so they
+	// can't be rewritten.
+	AfterForEachAdvisory func(source, pkgName string, err error) error
+}
+
+// SetHooks replaces the hooks, which run in turn, each Before hook given what the previous one returned.
+// No hooks remove them.
+func SetHooks(h ...Hooks) {
+	hooks = h
+}
+
+func beforePutAdvisory(source, pkgName, cveID string, advisory interface{}) (interface{}, error) {
+	for _, h := range hooks {
+		if h.BeforePutAdvisory == nil {
+			continue
+		}
+		var err error
+		if advisory, err = h.BeforePutAdvisory(source, pkgName, cveID, advisory); err != nil {
+			return nil, err
+		}
+	}
+	return advisory, nil
+}
+
+func afterPutAdvisory(source, pkgName, cveID string, err error) {
+	for _, h := range hooks {
+		if h.AfterPutAdvisory != nil {
+			h.AfterPutAdvisory(source, pkgName, cveID, err)
+		}
+	}
+}
+
+func beforePutVulnerabilityDetail(cveID, source string, detail types.VulnerabilityDetail) (types.VulnerabilityDetail, error) {
+	for _, h := range hooks {
+		if h.BeforePutVulnerabilityDetail == nil {
+			continue
+		}
+		var err error
+		if detail, err = h.BeforePutVulnerabilityDetail(cveID, source, detail); err != nil {
+			return types.VulnerabilityDetail{}, err
+		}
+	}
+	return detail, nil
+}
+
+func afterPutVulnerabilityDetail(cveID, source string, err error) {
+	for _, h := range hooks {
+		if h.AfterPutVulnerabilityDetail != nil {
+			h.AfterPutVulnerabilityDetail(cveID, source, err)
+		}
+	}
+}
+
+func beforeGetAdvisories(source, pkgName string) {
+	for _, h := range hooks {
+		if h.BeforeGetAdvisories != nil {
+			h.BeforeGetAdvisories(source, pkgName)
+		}
+	}
+}
+
+func afterGetAdvisories(source, pkgName string, advisories []types.Advisory, err error) ([]types.Advisory, error) {
+	for _, h := range hooks {
+		if h.AfterGetAdvisories != nil {
+			advisories, err = h.AfterGetAdvisories(source, pkgName, advisories, err)
+		}
+	}
+	return advisories, err
+}
+
+func afterForEachAdvisory(source, pkgName string, err error) error {
+	for _, h := range hooks {
+		if h.AfterForEachAdvisory != nil {
+			err = h.AfterForEachAdvisory(source, pkgName, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestSetHooks(t *testing.T) {
+	var events []string
+	audit := Hooks{
+		AfterPutAdvisory: func(source, pkgName, cveID string, err error) {
+			events = append(events, "put "+source+"/"+pkgName+"/"+cveID)
+		},
+		AfterPutVulnerabilityDetail: func(cveID, source string, err error) {
+			events = append(events, "put "+cveID+"/"+source)
+		},
+		BeforeGetAdvisories: func(source, pkgName string) {
+			events = append(events, "get "+source+"/"+pkgName)
+		},
+	}
+
+	tests := []struct {
+		name           string
+		hooks          []Hooks
+		wantErr        string
+		wantAdvisories []types.Advisory
+		wantDetail     types.VulnerabilityDetail
+		wantEvents     []string
+	}{
+		{
+			name:           "no hooks",
+			wantAdvisories: []types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.1d-r0"}},
+			wantDetail:     types.VulnerabilityDetail{Title: "openssl"},
+		},
+		{
+			name: "records rewritten in turn",
+			hooks: []Hooks{
+				{
+					BeforePutAdvisory: func(_, _, _ string, advisory interface{}) (interface{}, error) {
+						a := advisory.(types.Advisory)
+						a.FixedVersion += "-r1"
+						return a, nil
+					},
+					BeforePutVulnerabilityDetail: func(_, _ string, detail types.VulnerabilityDetail) (types.VulnerabilityDetail, error) {
+						detail.Title = "OpenSSL"
+						return detail, nil
+					},
+				},
+				{
+					BeforePutAdvisory: func(_, _, _ string, advisory interface{}) (interface{}, error) {
+						a := advisory.(types.Advisory)
+						a.Severity = types.SeverityHigh
+						return a, nil
+					},
+				},
+				audit,
+			},
+			wantAdvisories: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.1d-r0-r1", Severity: types.SeverityHigh},
+			},
+			wantDetail: types.VulnerabilityDetail{Title: "OpenSSL"},
+			wantEvents: []string{"put alpine 3.10/openssl/CVE-2019-0001", "put CVE-2019-0001/alpine",
+				"get alpine 3.10/openssl"},
+		},
+		{
+			name: "advisories rewritten after the lookup",
+			hooks: []Hooks{
+				{
+					AfterGetAdvisories: func(_, _ string, advisories []types.Advisory, err error) ([]types.Advisory, error) {
+						return nil, err
+					},
+				},
+			},
+			wantDetail: types.VulnerabilityDetail{Title: "openssl"},
+		},
+		{
+			name: "put failed by a hook",
+			hooks: []Hooks{
+				{
+					BeforePutAdvisory: func(_, _, _ string, _ interface{}) (interface{}, error) {
+						return nil, xerrors.New("denied")
+					},
+					AfterPutAdvisory: func(_, _, _ string, err error) {
+						events = append(events, "failed "+err.Error())
+					},
+				},
+			},
+			wantErr:    "denied",
+			wantEvents: []string{"failed denied"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestSetHooks_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+
+			assert.NoError(t, Init(d))
+			defer Close()
+			events = nil
+			SetHooks(tt.hooks...)
+			defer SetHooks()
+
+			dbc := Config{}
+			err = db.Update(func(tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001",
+					types.Advisory{FixedVersion: "1.1.1d-r0"}); err != nil {
+					return err
+				}
+				return dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", "alpine", types.VulnerabilityDetail{Title: "openssl"})
+			})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Equal(t, tt.wantEvents, events)
+				return
+			}
+			assert.NoError(t, err)
+
+			advisories, err := dbc.GetAdvisories("alpine 3.10", "openssl")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantAdvisories, advisories)
+
+			detail, err := dbc.GetVulnerabilityDetail("CVE-2019-0001", "alpine")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDetail, detail)
+			assert.Equal(t, tt.wantEvents, events)
+		})
+	}
+}
+
+func TestSetHooks_ForEachAdvisory(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestSetHooks_ForEachAdvisory_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+	dbc := Config{}
+	err = db.Update(func(tx Tx) error {
+		return dbc.PutAdvisory(tx, "nodejs-security-wg", "lodash", "CVE-2019-10744",
+			types.Advisory{FixedVersion: "4.17.12"})
+	})
+	assert.NoError(t, err)
+
+	var events []string
+	SetHooks(Hooks{
+		BeforeGetAdvisories: func(source, pkgName string) {
+			events = append(events, "get "+source+"/"+pkgName)
+		},
+		AfterForEachAdvisory: func(source, pkgName string, err error) error {
+			events = append(events, "got "+source+"/"+pkgName)
+			if err != nil {
+				return err
+			}
+			return xerrors.New("denied")
+		},
+	})
+	defer SetHooks()
+
+	var vulnIDs []string
+	err = dbc.ForEachAdvisory("nodejs-security-wg", "lodash", func(_ *types.DataSource, vulnID string, _ []byte) error {
+		vulnIDs = append(vulnIDs, vulnID)
+		return nil
+	})
+	assert.EqualError(t, err, "denied")
+	assert.Equal(t, []string{"CVE-2019-10744"}, vulnIDs)
+	assert.Equal(t, []string{"get nodejs-security-wg/lodash", "got nodejs-security-wg/lodash"}, events)
+
+	// GetAdvisories runs its own hooks alone
+	events = nil
+	advisories, err := dbc.GetAdvisories("nodejs-security-wg", "lodash")
+	assert.NoError(t, err)
+	assert.Len(t, advisories, 1)
+	assert.Equal(t, []string{"get nodejs-security-wg/lodash"}, events)
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// Import writes the records of Export into a new DB, with the encoding in the exported metadata.
+// The export must have the current schema version. Values are imported uncompressed, and the checksums,
+// if the export has them, are recomputed.
+func (dbc Config) Import(r io.Reader) error {
+	if _, err := dbc.GetMetadata(); err == nil {
+		return xerrors.New("the DB isn't empty, import into a new DB")
+	}
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	metadata, err := readMetadata(dec)
+	if err != nil {
+		return xerrors.Errorf("invalid metadata: %w", err)
+	} else if metadata.Version != SchemaVersion {
+		return xerrors.Errorf("the export has schema v%d, expected v%d", metadata.Version, SchemaVersion)
+	}
+	encoding, err := parseEncoding(metadata)
+	if err != nil {
+		return err
+	}
+	f := format{encoding: encoding}
+	metadata.Compression = ""
+
+	for line := 2; ; {
+		records, err := readRecords(dec, DefaultChunkSize)
+		if err != nil {
+			return xerrors.Errorf("line %d: %w", line+len(records), err)
+		}
+		if len(records) == 0 {
+			break
+		}
+		err = writeTx(func(tx Tx) error {
+			for i, rec := range records {
+				if err := importRecord(tx, f, rec); err != nil {
+					return xerrors.Errorf("line %d: %w", line+i, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return xerrors.Errorf("failed to import: %w", err)
+		}
+		line += len(records)
+	}
+
+	if err = dbc.saveImportedMetadata(metadata); err != nil {
+		return err
+	}
+	return loadFormat()
+}
+
+// saveImportedMetadata saves the metadata of a DB whose values were imported, recomputing the checksums if it has them
+func (dbc Config) saveImportedMetadata(metadata Metadata) error {
+	err := writeTx(func(tx Tx) error {
+		v, err := json.Marshal(metadata)
+		if err != nil {
+			return xerrors.Errorf("failed to marshal JSON: %w", err)
+		}
+		if err = dbc.putNestedBucket(tx, metadataBucket, "metadata", "data", v); err != nil {
+			return err
+		}
+		return dbc.refreshChecksums(tx)
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to save metadata: %w", err)
+	}
+	return nil
+}
+
+// readMetadata reads the first record of an export, which is the metadata
+func readMetadata(dec *json.Decoder) (Metadata, error) {
+	var rec ExportRecord
+	if err := dec.Decode(&rec); err == io.EOF {
+		return Metadata{}, xerrors.New("empty export")
+	} else if err != nil {
+		return Metadata{}, err
+	}
+	if len(rec.Bucket) != 2 || rec.Bucket[0] != metadataBucket || rec.Bucket[1] != "metadata" || rec.Key != "data" {
+		return Metadata{}, xerrors.Errorf("the first record is %q %s", rec.Bucket, rec.Key)
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(rec.Value, &metadata); err != nil {
+		return Metadata{}, err
+	}
+	return metadata, nil
+}
+
+// readRecords reads up to n records, fewer at the end of the export
+func readRecords(dec *json.Decoder, n int) ([]ExportRecord, error) {
+	var records []ExportRecord
+	for len(records) < n {
+		var rec ExportRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func importRecord(tx Tx, f format, rec ExportRecord) error {
+	switch {
+	case len(rec.Bucket) == 0 || rec.Key == "":
+		return xerrors.New("no bucket or key")
+	case len(rec.Value) == 0:
+		return xerrors.New("no value")
+	case rec.Bucket[0] == metadataBucket:
+		// the metadata is the first record, the dictionary and the records of Prune aren't exported
+		return xerrors.Errorf("unexpected record in %s", metadataBucket)
+	}
+
+	value, err := f.importValue(rec.Bucket[0], rec.Value)
+	if err != nil {
+		return xerrors.Errorf("%q %s: %w", rec.Bucket, rec.Key, err)
+	}
+
+	var b storage.Bucket
+	for i, name := range rec.Bucket {
+		if i == 0 {
+			b, err = tx.CreateBucketIfNotExists([]byte(name))
+		} else {
+			b, err = b.CreateBucketIfNotExists([]byte(name))
+		}
+		if err != nil {
+			return xerrors.Errorf("failed to create a bucket: %w", err)
+		}
+	}
+	return b.Put([]byte(rec.Key), value)
+}
+
+// importValue converts an exported value of a root bucket to its stored form
+func (f format) importValue(rootBucket string, value json.RawMessage) ([]byte, error) {
+	if rootBucket == severityBucket {
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, xerrors.Errorf("invalid severity: %w", err)
+		}
+		if _, err := types.NewSeverity(s); err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	}
+
+	if f.encoding == EncodingJSON {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, value); err != nil {
+			return nil, xerrors.Errorf("invalid JSON: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, xerrors.Errorf("invalid JSON: %w", err)
+	}
+	v, err := importNumbers(v)
+	if err != nil {
+		return nil, err
+	}
+	return f.marshal(v)
+}
+
+// importNumbers replaces JSON numbers with integers or floats, so that MessagePack keeps integers compact
+func importNumbers(v interface{}) (interface{}, error) {
+	var err error
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		for k, e := range v {
+			if v[k], err = importNumbers(e); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			if v[i], err = importNumbers(e); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
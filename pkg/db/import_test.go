@@ -0,0 +1,138 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Import(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		compress bool
+	}{
+		{name: "json", encoding: EncodingJSON},
+		{name: "msgpack", encoding: EncodingMsgpack},
+		{name: "compressed", encoding: EncodingJSON, compress: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestConfig_Import_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+
+			// export a DB
+			assert.NoError(t, Init(d+"/src"))
+			assert.NoError(t, SetEncoding(tt.encoding))
+			dbc := Config{}
+			detail := types.VulnerabilityDetail{CvssScore: 7, CvssScoreV3: 7.5, SeverityV3: types.SeverityHigh, Title: "padding oracle"}
+			err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001", types.Advisory{FixedVersion: "1.1.1d-r0"}); err != nil {
+					return err
+				}
+				if err := dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityHigh); err != nil {
+					return err
+				}
+				return dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", "nvd", detail)
+			})
+			assert.NoError(t, err)
+			assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+			if tt.compress {
+				assert.NoError(t, dbc.Compress())
+			}
+			assert.NoError(t, dbc.SetChecksums())
+			var exported bytes.Buffer
+			assert.NoError(t, dbc.Export(&exported))
+			assert.NoError(t, Close())
+
+			// import it into a new DB
+			assert.NoError(t, Init(d+"/dst"))
+			defer Close()
+			assert.NoError(t, dbc.Import(bytes.NewReader(exported.Bytes())))
+
+			metadata, err := dbc.GetMetadata()
+			assert.NoError(t, err)
+			assert.Equal(t, "", metadata.Compression)
+			assert.NoError(t, dbc.Verify())
+
+			advisories, err := dbc.GetAdvisories("alpine 3.10", "openssl")
+			assert.NoError(t, err)
+			assert.Equal(t, []types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.1d-r0"}}, advisories)
+			severity, err := dbc.GetSeverity("CVE-2019-0001")
+			assert.NoError(t, err)
+			assert.Equal(t, types.SeverityHigh, severity)
+			details, err := dbc.GetVulnerabilityDetails("CVE-2019-0001")
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]types.VulnerabilityDetail{"nvd": detail}, details)
+
+			// an import exports the same records, but for the format fields of the metadata
+			var reexported bytes.Buffer
+			assert.NoError(t, dbc.Export(&reexported))
+			want := strings.SplitN(exported.String(), "\n", 2)[1]
+			got := strings.SplitN(reexported.String(), "\n", 2)[1]
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestConfig_Import_Invalid(t *testing.T) {
+	metadata := `{"bucket":["trivy","metadata"],"key":"data","value":{"Version":2,"Type":1}}`
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: "empty export",
+		},
+		{
+			name:    "no metadata",
+			input:   `{"bucket":["severity"],"key":"CVE-2019-0001","value":"HIGH"}`,
+			wantErr: `the first record is ["severity"] CVE-2019-0001`,
+		},
+		{
+			name:    "other schema",
+			input:   `{"bucket":["trivy","metadata"],"key":"data","value":{"Version":0,"Type":1}}`,
+			wantErr: "the export has schema v0, expected v2",
+		},
+		{
+			name:    "unknown severity",
+			input:   metadata + "\n" + `{"bucket":["severity"],"key":"CVE-2019-0001","value":"SEVERE"}`,
+			wantErr: "unknown severity: SEVERE",
+		},
+		{
+			name:    "no key",
+			input:   metadata + "\n" + `{"bucket":["severity"],"value":"HIGH"}`,
+			wantErr: "line 2: no bucket or key",
+		},
+		{
+			name:    "malformed line",
+			input:   metadata + "\n" + `{"bucket":["severity"],"key":"CVE-2019-0001","value":"HIGH"}` + "\n{",
+			wantErr: "line 3: unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestConfig_Import_Invalid_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+
+			assert.NoError(t, Init(d))
+			defer Close()
+
+			err = Config{}.Import(strings.NewReader(tt.input))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+const (
+	// journalBucket holds what undoes the writes of the source being updated: trivy => journal => sequence
+	journalBucket = "journal"
+
+	// the keys of the journal which aren't a sequence
+	journalNextKey   = "next"
+	journalSourceKey = "source"
+)
+
+// journaling is set between StartJournal and CommitJournal or RollbackJournal
+var journaling bool
+
+// Journal makes the writes of a source a unit, which is rolled back if the source fails half way
+type Journal interface {
+	StartJournal(string) error
+	CommitJournal() error
+	RollbackJournal() error
+}
+
+// undo restores a key or a bucket, at Path from the root, as it was before a write
+type undo struct {
+	Path    [][]byte
+	Key     []byte
+	Bucket  bool   `json:",omitempty"`
+	Existed bool   `json:",omitempty"`
+	Value   []byte `json:",omitempty"`
+}
+
+// StartJournal records how to undo the writes of BatchUpdate and ChunkedUpdate from now on, in the transactions
+// of the writes, so that the committed batches of a source can be rolled back. A journal left by a crashed
+// build is rolled back first.
+func (dbc Config) StartJournal(source string) error {
+	var left string
+	err := db.View(func(tx Tx) error {
+		if b := journal(tx); b != nil {
+			left = string(b.Get([]byte(journalSourceKey)))
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to read the journal: %w", err)
+	}
+	if left != "" {
+		log.OrDefault(dbc.Logger).Warn("Rolling back the writes left by an interrupted build", "source", left)
+		if err = dbc.RollbackJournal(); err != nil {
+			return err
+		}
+	}
+
+	err = writeTx(func(tx Tx) error {
+		return dbc.putNestedBucket(tx, metadataBucket, journalBucket, journalSourceKey, []byte(source))
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to start the journal: %w", err)
+	}
+	journaling = true
+	return nil
+}
+
+// CommitJournal keeps the writes since StartJournal
+func (dbc Config) CommitJournal() error {
+	journaling = false
+	if err := writeTx(deleteJournal); err != nil {
+		return xerrors.Errorf("failed to delete the journal: %w", err)
+	}
+	return nil
+}
+
+// RollbackJournal undoes the writes since StartJournal, latest first
+func (dbc Config) RollbackJournal() error {
+	journaling = false
+	err := writeTx(func(tx Tx) error {
+		b := journal(tx)
+		if b == nil {
+			return nil
+		}
+		var undos []undo
+		err := b.ForEach(func(k, v []byte) error {
+			if len(k) != 8 {
+				return nil
+			}
+			var u undo
+			if err := json.Unmarshal(v, &u); err != nil {
+				return xerrors.Errorf("failed to unmarshal the journal: %w", err)
+			}
+			undos = append(undos, u)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for i := len(undos) - 1; i >= 0; i-- {
+			if err = undos[i].apply(tx); err != nil {
+				return xerrors.Errorf("failed to undo a write: %w", err)
+			}
+		}
+		return deleteJournal(tx)
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to roll back the journal: %w", err)
+	}
+	return nil
+}
+
+func (u undo) apply(tx Tx) error {
+	var parent storage.Bucket
+	for _, name := range u.Path {
+		var b storage.Bucket
+		if parent == nil {
+			b = tx.Bucket(name)
+		} else {
+			b = parent.Bucket(name)
+		}
+		if b == nil {
+			// a bucket created since is already deleted
+			return nil
+		}
+		parent = b
+	}
+
+	switch {
+	case u.Bucket && u.Existed:
+		if parent == nil {
+			_, err := tx.CreateBucketIfNotExists(u.Key)
+			return err
+		}
+		_, err := parent.CreateBucketIfNotExists(u.Key)
+		return err
+	case u.Bucket:
+		var err error
+		if parent == nil {
+			err = tx.DeleteBucket(u.Key)
+		} else {
+			err = parent.DeleteBucket(u.Key)
+		}
+		if err == storage.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	case u.Existed:
+		return parent.Put(u.Key, u.Value)
+	default:
+		return parent.Delete(u.Key)
+	}
+}
+
+func journal(tx Tx) storage.Bucket {
+	root := tx.Bucket([]byte(metadataBucket))
+	if root == nil {
+		return nil
+	}
+	return root.Bucket([]byte(journalBucket))
+}
+
+func deleteJournal(tx Tx) error {
+	root := tx.Bucket([]byte(metadataBucket))
+	if root == nil {
+		return nil
+	}
+	if err := root.DeleteBucket([]byte(journalBucket)); err != nil && err != storage.ErrBucketNotFound {
+		return err
+	}
+	return nil
+}
+
+// journalTx wraps the transactions of BatchUpdate and ChunkedUpdate while journaling
+func journalTx(tx Tx) Tx {
+	if !journaling {
+		return tx
+	}
+	return &journalingTx{tx: tx}
+}
+
+// journalingTx records an undo before each write made through its buckets
+type journalingTx struct {
+	tx Tx
+}
+
+func (t *journalingTx) Bucket(name []byte) storage.Bucket {
+	return t.wrap(nil, name, t.tx.Bucket(name))
+}
+
+func (t *journalingTx) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	if t.tx.Bucket(name) == nil {
+		if err := t.record(undo{Key: name, Bucket: true}); err != nil {
+			return nil, err
+		}
+	}
+	b, err := t.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.wrap(nil, name, b), nil
+}
+
+func (t *journalingTx) DeleteBucket(name []byte) error {
+	if b := t.tx.Bucket(name); b != nil {
+		if err := t.recordBucket(nil, name, b); err != nil {
+			return err
+		}
+	}
+	return t.tx.DeleteBucket(name)
+}
+
+func (t *journalingTx) ForEach(fn func(name []byte, b storage.Bucket) error) error {
+	return t.tx.ForEach(func(name []byte, b storage.Bucket) error {
+		return fn(name, t.wrap(nil, name, b))
+	})
+}
+
+func (t *journalingTx) wrap(parent [][]byte, name []byte, b storage.Bucket) storage.Bucket {
+	if b == nil {
+		return nil
+	}
+	path := make([][]byte, 0, len(parent)+1)
+	path = append(append(path, parent...), append([]byte{}, name...))
+	return journalingBucket{b: b, path: path, tx: t}
+}
+
+// record appends an undo to the journal, in the transaction of the write
+func (t *journalingTx) record(u undo) error {
+	b := journal(t.tx)
+	if b == nil {
+		return xerrors.New("no journal")
+	}
+	var seq uint64
+	if v := b.Get([]byte(journalNextKey)); len(v) == 8 {
+		seq = binary.BigEndian.Uint64(v)
+	}
+	v, err := json.Marshal(u)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal JSON: %w", err)
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	if err = b.Put(key, v); err != nil {
+		return err
+	}
+	next := make([]byte, 8)
+	binary.BigEndian.PutUint64(next, seq+1)
+	return b.Put([]byte(journalNextKey), next)
+}
+
+// recordBucket records how to recreate a bucket about to be deleted: its content, then the bucket itself,
+// so that the rollback creates the bucket first
+func (t *journalingTx) recordBucket(parent [][]byte, name []byte, b storage.Bucket) error {
+	path := make([][]byte, 0, len(parent)+1)
+	path = append(append(path, parent...), append([]byte{}, name...))
+	err := b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return t.recordBucket(path, k, b.Bucket(k))
+		}
+		return t.record(undo{Path: path, Key: k, Existed: true, Value: v})
+	})
+	if err != nil {
+		return err
+	}
+	return t.record(undo{Path: parent, Key: name, Bucket: true, Existed: true})
+}
+
+type journalingBucket struct {
+	b    storage.Bucket
+	path [][]byte
+	tx   *journalingTx
+}
+
+func (b journalingBucket) Get(key []byte) []byte {
+	return b.b.Get(key)
+}
+
+func (b journalingBucket) Put(key, value []byte) error {
+	old := b.b.Get(key)
+	if err := b.tx.record(undo{Path: b.path, Key: key, Existed: old != nil, Value: old}); err != nil {
+		return err
+	}
+	return b.b.Put(key, value)
+}
+
+func (b journalingBucket) Delete(key []byte) error {
+	if old := b.b.Get(key); old != nil {
+		if err := b.tx.record(undo{Path: b.path, Key: key, Existed: true, Value: old}); err != nil {
+			return err
+		}
+	}
+	return b.b.Delete(key)
+}
+
+func (b journalingBucket) ForEach(fn func(k, v []byte) error) error {
+	return b.b.ForEach(fn)
+}
+
+func (b journalingBucket) Bucket(name []byte) storage.Bucket {
+	return b.tx.wrap(b.path, name, b.b.Bucket(name))
+}
+
+func (b journalingBucket) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	if b.b.Bucket(name) == nil {
+		if err := b.tx.record(undo{Path: b.path, Key: name, Bucket: true}); err != nil {
+			return nil, err
+		}
+	}
+	nested, err := b.b.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.tx.wrap(b.path, name, nested), nil
+}
+
+func (b journalingBucket) DeleteBucket(name []byte) error {
+	if nested := b.b.Bucket(name); nested != nil {
+		if err := b.tx.recordBucket(b.path, name, nested); err != nil {
+			return err
+		}
+	}
+	return b.b.DeleteBucket(name)
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+var _ Journal = &MockJournal{}
+
+type MockJournal struct {
+	mock.Mock
+}
+
+func (_m *MockJournal) StartJournal(a string) error {
+	ret := _m.Called(a)
+	return ret.Error(0)
+}
+
+func (_m *MockJournal) CommitJournal() error {
+	ret := _m.Called()
+	return ret.Error(0)
+}
+
+func (_m *MockJournal) RollbackJournal() error {
+	ret := _m.Called()
+	return ret.Error(0)
+}
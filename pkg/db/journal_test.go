@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_RollbackJournal(t *testing.T) {
+	advisory := func(fixedVersion string) types.Advisory {
+		return types.Advisory{FixedVersion: fixedVersion}
+	}
+	tests := []struct {
+		name     string
+		commit   bool
+		crash    bool
+		wantCurl []types.Advisory
+		wantBash []types.Advisory
+	}{
+		{
+			name: "rolled back",
+			wantCurl: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.0"},
+			},
+		},
+		{
+			name:   "committed",
+			commit: true,
+			wantCurl: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "2.0"},
+				{VulnerabilityID: "CVE-2019-0002", FixedVersion: "2.0"},
+			},
+			wantBash: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0003", FixedVersion: "2.0"},
+			},
+		},
+		{
+			name:  "left by a crashed build",
+			crash: true,
+			wantCurl: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestConfig_RollbackJournal_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+			assert.NoError(t, Init(d))
+			defer Close()
+
+			dbc := Config{}
+			ctx := context.Background()
+			assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+				return dbc.PutAdvisory(tx, "amazon linux 2", "curl", "CVE-2019-0001", advisory("1.0"))
+			}))
+
+			assert.NoError(t, dbc.StartJournal("amazon"))
+			// an overwrite and new keys and buckets in separate transactions
+			assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "amazon linux 2", "curl", "CVE-2019-0001", advisory("2.0")); err != nil {
+					return err
+				}
+				return dbc.PutAdvisory(tx, "amazon linux 2", "curl", "CVE-2019-0002", advisory("2.0"))
+			}))
+			assert.NoError(t, dbc.ChunkedUpdate(ctx, 1, 0, func(tx Tx, _ int) error {
+				return dbc.PutAdvisory(tx, "amazon linux 2", "bash", "CVE-2019-0003", advisory("2.0"))
+			}, nil))
+
+			switch {
+			case tt.commit:
+				assert.NoError(t, dbc.CommitJournal())
+			case tt.crash:
+				journaling = false
+				assert.NoError(t, dbc.StartJournal("amazon"))
+				assert.NoError(t, dbc.CommitJournal())
+			default:
+				assert.NoError(t, dbc.RollbackJournal())
+			}
+
+			got, err := dbc.GetAdvisories("amazon linux 2", "curl")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCurl, got)
+			got, err = dbc.GetAdvisories("amazon linux 2", "bash")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBash, got)
+
+			affected, err := dbc.GetAffectedPackages("CVE-2019-0003")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.commit, len(affected) > 0)
+			assert.NoError(t, db.View(func(tx Tx) error {
+				assert.Nil(t, journal(tx))
+				return nil
+			}))
+		})
+	}
+}
+
+func TestConfig_RollbackJournal_DeleteBucket(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_RollbackJournal_DeleteBucket_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	ctx := context.Background()
+	assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+		return dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001", types.Advisory{FixedVersion: "1.0"})
+	}))
+
+	assert.NoError(t, dbc.StartJournal("alpine"))
+	assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+		return tx.DeleteBucket([]byte("alpine::alpine 3.10"))
+	}))
+	got, err := dbc.GetAdvisories("alpine 3.10", "openssl")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	assert.NoError(t, dbc.RollbackJournal())
+	got, err = dbc.GetAdvisories("alpine 3.10", "openssl")
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.0"}}, got)
+}
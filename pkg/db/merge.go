@@ -0,0 +1,285 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+// MergeInput is a DB file to merge, and the name its advisories are traced to in their provenance, e.g. internal
+type MergeInput struct {
+	Name string
+	Path string
+}
+
+// MergeStats counts the values an input of Merge wrote into the merged DB
+type MergeStats struct {
+	Name    string
+	Records int
+	// Overrides are the records whose value of an earlier input was replaced by another one of this input
+	Overrides int
+}
+
+// Merge writes the DB files of inputs into a new DB, e.g. the public DB and a DB of internal advisories.
+// The inputs are given from the lowest precedence to the highest: a record in several inputs has the value
+// of the last one, e.g. the internal advisory of a package overrides the public one. Every advisory has the
+// provenance it has in the input it comes from, with the name of the input.
+// The inputs may differ in encoding and compression, the merged DB has the encoding of the first one and
+// the metadata of its inputs, see mergeMetadata. The DB opened by Init must be empty.
+func (dbc Config) Merge(driverName string, inputs []MergeInput) ([]MergeStats, error) {
+	if _, err := dbc.GetMetadata(); err == nil {
+		return nil, xerrors.New("the DB isn't empty, merge into a new DB")
+	}
+	if len(inputs) < 2 {
+		return nil, xerrors.New("merge two DBs or more")
+	}
+	var names []string
+	for _, input := range inputs {
+		if utils.StringInSlice(input.Name, names) {
+			return nil, xerrors.Errorf("duplicate input name %q", input.Name)
+		}
+		names = append(names, input.Name)
+	}
+
+	dir, err := ioutil.TempDir("", "trivy-db-merge-")
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create a temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var exports []string
+	for i, input := range inputs {
+		export := filepath.Join(dir, fmt.Sprintf("%d.ndjson", i))
+		if err = exportFile(driverName, input.Path, export); err != nil {
+			return nil, xerrors.Errorf("failed to export %s: %w", input.Path, err)
+		}
+		exports = append(exports, export)
+	}
+
+	readers := make([]*recordReader, len(exports))
+	metadatas := make([]Metadata, len(exports))
+	for i, export := range exports {
+		f, err := os.Open(export)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to open the export: %w", err)
+		}
+		defer f.Close()
+		readers[i] = newRecordReader(f)
+		if metadatas[i], err = readers[i].metadata(); err != nil {
+			return nil, xerrors.Errorf("invalid metadata of %s: %w", inputs[i].Path, err)
+		}
+	}
+	metadata, err := mergeMetadata(inputs, metadatas)
+	if err != nil {
+		return nil, err
+	}
+	encoding, err := parseEncoding(metadata)
+	if err != nil {
+		return nil, err
+	}
+	f := format{encoding: encoding}
+	metadata.Encoding, metadata.Compression = encoding, ""
+
+	stats := make([]MergeStats, len(inputs))
+	for i, input := range inputs {
+		stats[i].Name = input.Name
+	}
+	if err = dbc.mergeRecords(f, inputs, readers, stats); err != nil {
+		return nil, xerrors.Errorf("failed to merge: %w", err)
+	}
+	for i, export := range exports {
+		if err = dbc.mergeProvenance(f, inputs[i].Name, export); err != nil {
+			return nil, xerrors.Errorf("failed to merge the provenance of %s: %w", inputs[i].Path, err)
+		}
+	}
+	if err = dbc.saveImportedMetadata(metadata); err != nil {
+		return nil, err
+	}
+	if err = loadFormat(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// mergeMetadata merges the metadata of the inputs, which must have the current schema and the same type.
+// The merged DB was updated when its latest input was and is due for an update when its earliest input is,
+// and it has the sources of every input.
+func mergeMetadata(inputs []MergeInput, metadatas []Metadata) (Metadata, error) {
+	merged := metadatas[0]
+	merged.Sources = nil
+	for i, m := range metadatas {
+		switch {
+		case m.Version != SchemaVersion:
+			return Metadata{}, xerrors.Errorf("%s has schema v%d, expected v%d", inputs[i].Path, m.Version, SchemaVersion)
+		case m.Type != merged.Type:
+			return Metadata{}, xerrors.Errorf("%s and %s are of different types", inputs[0].Path, inputs[i].Path)
+		}
+		if m.UpdatedAt.After(merged.UpdatedAt) {
+			merged.UpdatedAt = m.UpdatedAt
+		}
+		if !m.NextUpdate.IsZero() && (merged.NextUpdate.IsZero() || m.NextUpdate.Before(merged.NextUpdate)) {
+			merged.NextUpdate = m.NextUpdate
+		}
+		// the checksums are recomputed if any input has them
+		if len(m.Checksums) > 0 {
+			merged.Checksums = m.Checksums
+		}
+		for name, source := range m.Sources {
+			if merged.Sources == nil {
+				merged.Sources = map[string]SourceMetadata{}
+			}
+			merged.Sources[name] = source
+		}
+	}
+	return merged, nil
+}
+
+// mergeRecords writes the records of the exports, which are sorted alike, in one pass. The provenance of the
+// exports is left to mergeProvenance, each advisory is given the one of the input it comes from instead.
+func (dbc Config) mergeRecords(f format, inputs []MergeInput, readers []*recordReader, stats []MergeStats) error {
+	var pending []ExportRecord
+	flush := func() error {
+		err := writeTx(func(tx Tx) error {
+			for _, rec := range pending {
+				if err := importRecord(tx, f, rec); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		pending = pending[:0]
+		return err
+	}
+
+	next := make([]*ExportRecord, len(readers))
+	for {
+		var min *ExportRecord
+		for i, r := range readers {
+			rec, err := r.peek()
+			if err != nil {
+				return xerrors.Errorf("failed to read %s: %w", inputs[i].Path, err)
+			}
+			next[i] = rec
+			if rec != nil && compareRecords(rec, min) < 0 {
+				min = rec
+			}
+		}
+		if min == nil {
+			break
+		}
+
+		// the record of the last input having it wins, and overrides the earlier values which differ
+		winner, overrides := -1, false
+		for i, rec := range next {
+			if compareRecords(rec, min) != 0 {
+				continue
+			}
+			if winner >= 0 && !overrides {
+				equal, err := equalValues(rec.Value, next[winner].Value)
+				if err != nil {
+					return xerrors.Errorf("%q %s: %w", rec.Bucket, rec.Key, err)
+				}
+				overrides = !equal
+			}
+			winner = i
+			readers[i].next = nil
+		}
+		rec := *next[winner]
+		if rec.Bucket[0] == provenanceBucket {
+			continue
+		}
+		stats[winner].Records++
+		if overrides {
+			stats[winner].Overrides++
+		}
+
+		pending = append(pending, rec)
+		if !utils.StringInSlice(rec.Bucket[0], nonAdvisoryBuckets) && len(rec.Bucket) == 2 {
+			v, err := json.Marshal(types.Provenance{DB: inputs[winner].Name})
+			if err != nil {
+				return err
+			}
+			pending = append(pending, ExportRecord{
+				Bucket: []string{provenanceBucket, rec.Bucket[0]},
+				Key:    rec.Bucket[1] + keySeparator + rec.Key,
+				Value:  v,
+			})
+		}
+		if len(pending) >= DefaultChunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// mergeProvenance fills in the provenance the input named name has for the advisories merged from it
+func (dbc Config) mergeProvenance(f format, name, export string) error {
+	r, err := os.Open(export)
+	if err != nil {
+		return xerrors.Errorf("failed to open the export: %w", err)
+	}
+	defer r.Close()
+	dec := json.NewDecoder(r)
+	if _, err = readMetadata(dec); err != nil {
+		return err
+	}
+
+	for {
+		records, err := readRecords(dec, DefaultChunkSize)
+		if err != nil {
+			return err
+		} else if len(records) == 0 {
+			return nil
+		}
+		err = writeTx(func(tx Tx) error {
+			root := tx.Bucket([]byte(provenanceBucket))
+			if root == nil {
+				return nil
+			}
+			for _, rec := range records {
+				if rec.Bucket[0] != provenanceBucket || len(rec.Bucket) != 2 {
+					continue
+				}
+				b := root.Bucket([]byte(rec.Bucket[1]))
+				if b == nil {
+					continue
+				}
+				v := b.Get([]byte(rec.Key))
+				if v == nil {
+					continue
+				}
+				var merged types.Provenance
+				if err := f.unmarshal(v, &merged); err != nil {
+					return xerrors.Errorf("%q %s: %w", rec.Bucket, rec.Key, err)
+				} else if merged.DB != name {
+					// the advisory comes from another input
+					continue
+				}
+				var provenance types.Provenance
+				if err := json.Unmarshal(rec.Value, &provenance); err != nil {
+					return xerrors.Errorf("%q %s: %w", rec.Bucket, rec.Key, err)
+				}
+				provenance.DB = name
+				if v, err = f.marshal(provenance); err != nil {
+					return err
+				}
+				if err := b.Put([]byte(rec.Key), v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Merge(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Merge_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	public := buildDiffDB(t, filepath.Join(d, "public"), EncodingJSON, []testAdvisory{
+		{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0001", fixed: "1.1.1d-r0"},
+		{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0002", fixed: "1.1.1d-r1"},
+		{source: "debian 9", pkgName: "curl", cveID: "CVE-2019-0003", fixed: "7.52.1-5"},
+	}, map[string]string{"CVE-2019-0001": "padding oracle", "CVE-2019-0002": "timing", "CVE-2019-0003": "overflow"})
+	assert.NoError(t, Init(filepath.Join(d, "public")))
+	dbc := Config{}
+	assert.NoError(t, dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		for _, cveID := range []string{"CVE-2019-0001", "CVE-2019-0002"} {
+			err := dbc.PutProvenance(tx, "alpine 3.10", "openssl", cveID, types.Provenance{Path: "alpine/" + cveID + ".json"})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull,
+		UpdatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Sources: map[string]SourceMetadata{"alpine": {}}}))
+	assert.NoError(t, Close())
+
+	// the internal advisories override the public ones, and the DB has another encoding
+	internal := buildDiffDB(t, filepath.Join(d, "internal"), EncodingMsgpack, []testAdvisory{
+		{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0001", fixed: "1.1.1d-r0"},
+		{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0002", fixed: "1.1.1d-r2"},
+		{source: "alpine 3.11", pkgName: "musl", cveID: "CVE-2019-0004", fixed: "1.1.24-r0"},
+	}, map[string]string{"CVE-2019-0004": "internal"})
+
+	tests := []struct {
+		name      string
+		inputs    []MergeInput
+		wantStats []MergeStats
+		wantErr   string
+	}{
+		{
+			name:   "internal overrides public",
+			inputs: []MergeInput{{Name: "public", Path: public}, {Name: "internal", Path: internal}},
+			wantStats: []MergeStats{
+				// the values only the public DB has: the vulnerabilities, the advisory of curl, its index and data source
+				{Name: "public", Records: 6},
+				// the advisories and their indexes, the vulnerability and the data source of alpine
+				{Name: "internal", Records: 8, Overrides: 1},
+			},
+		},
+		{
+			name:    "single input",
+			inputs:  []MergeInput{{Name: "public", Path: public}},
+			wantErr: "merge two DBs or more",
+		},
+		{
+			name:    "duplicate name",
+			inputs:  []MergeInput{{Name: "public", Path: public}, {Name: "public", Path: internal}},
+			wantErr: `duplicate input name "public"`,
+		},
+		{
+			name:    "missing input",
+			inputs:  []MergeInput{{Name: "public", Path: public}, {Name: "internal", Path: filepath.Join(d, "missing.db")}},
+			wantErr: "failed to export",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir, err := ioutil.TempDir(d, "merged_*")
+			assert.NoError(t, err)
+			assert.NoError(t, Init(cacheDir))
+			defer Close()
+
+			got, err := dbc.Merge(storage.DefaultDriver, tt.inputs)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStats, got)
+
+			advisories, err := dbc.GetAdvisories("alpine 3.10", "openssl")
+			assert.NoError(t, err)
+			assert.Equal(t, []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.1d-r0"},
+				{VulnerabilityID: "CVE-2019-0002", FixedVersion: "1.1.1d-r2"},
+			}, advisories)
+			advisories, err = dbc.GetAdvisories("debian 9", "curl")
+			assert.NoError(t, err)
+			assert.Equal(t, []types.Advisory{{VulnerabilityID: "CVE-2019-0003", FixedVersion: "7.52.1-5"}}, advisories)
+
+			// the provenance of an advisory is the one of the input it comes from
+			for cveID, want := range map[string]types.Provenance{
+				"CVE-2019-0001": {DB: "internal"},
+				"CVE-2019-0002": {DB: "internal"},
+			} {
+				provenance, err := dbc.GetProvenance("alpine 3.10", "openssl", cveID)
+				assert.NoError(t, err)
+				assert.Equal(t, want, provenance, cveID)
+			}
+			provenance, err := dbc.GetProvenance("debian 9", "curl", "CVE-2019-0003")
+			assert.NoError(t, err)
+			assert.Equal(t, types.Provenance{DB: "public"}, provenance)
+
+			vulnerability, err := dbc.GetVulnerability("CVE-2019-0004")
+			assert.NoError(t, err)
+			assert.Equal(t, "internal", vulnerability.Title)
+
+			metadata, err := dbc.GetMetadata()
+			assert.NoError(t, err)
+			assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), metadata.UpdatedAt)
+			assert.Equal(t, map[string]SourceMetadata{"alpine": {}}, metadata.Sources)
+		})
+	}
+}
+
+func TestConfig_Merge_Provenance(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Merge_Provenance_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	var paths []string
+	for _, name := range []string{"public", "internal"} {
+		cacheDir := filepath.Join(d, name)
+		path := buildDiffDB(t, cacheDir, EncodingMsgpack, []testAdvisory{
+			{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0001", fixed: name},
+			{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0002", fixed: "1.1.1d-r1"},
+		}, nil)
+		assert.NoError(t, Init(cacheDir))
+		dbc := Config{}
+		assert.NoError(t, dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+			for _, cveID := range []string{"CVE-2019-0001", "CVE-2019-0002"} {
+				err := dbc.PutProvenance(tx, "alpine 3.10", "openssl", cveID, types.Provenance{Path: name + "/" + cveID})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		assert.NoError(t, Close())
+		paths = append(paths, path)
+	}
+
+	assert.NoError(t, Init(filepath.Join(d, "merged")))
+	defer Close()
+	dbc := Config{}
+	_, err = dbc.Merge(storage.DefaultDriver, []MergeInput{{Name: "public", Path: paths[0]}, {Name: "internal", Path: paths[1]}})
+	assert.NoError(t, err)
+
+	// an overridden advisory has the provenance of the internal DB, an identical one too as the last input wins
+	for cveID, want := range map[string]types.Provenance{
+		"CVE-2019-0001": {Path: "internal/CVE-2019-0001", DB: "internal"},
+		"CVE-2019-0002": {Path: "internal/CVE-2019-0002", DB: "internal"},
+	} {
+		got, err := dbc.GetProvenance("alpine 3.10", "openssl", cveID)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, cveID)
+	}
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/migrations"
+)
+
+// migrate upgrades a DB of an older schema in place instead of having it rebuilt
+func migrate() error {
+	metadata, err := Config{}.GetMetadata()
+	if err != nil || metadata.Version == SchemaVersion {
+		// a new DB has no metadata
+		return nil
+	}
+
+	err = writeTx(func(tx Tx) error {
+		if err := migrations.All.Run(tx, metadata.Version, SchemaVersion); err != nil {
+			return err
+		}
+		if err := (Config{}).refreshDataSources(tx); err != nil {
+			return err
+		}
+		return Config{}.updateMetadata(tx, func(metadata *Metadata) {
+			metadata.Version = SchemaVersion
+		})
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to migrate the DB: %w", err)
+	}
+	return nil
+}
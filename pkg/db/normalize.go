@@ -0,0 +1,14 @@
+package db
+
+import "github.com/aquasecurity/trivy-db/pkg/utils"
+
+// normalizeSourcePackage normalizes a package name of an advisory bucket, PutAdvisory and the lookups
+// call it so that the sources and the consumers agree on the keys, see utils.NormalizePackageName
+func normalizeSourcePackage(source, pkgName string) string {
+	for ecosystem, bucket := range languageBuckets {
+		if bucket == source {
+			return utils.NormalizePackageName(ecosystem, pkgName)
+		}
+	}
+	return pkgName
+}
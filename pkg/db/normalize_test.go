@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_PutAdvisory_Normalization(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_PutAdvisory_Normalization_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		if err := dbc.PutAdvisory(tx, "python-safety-db", "Zope.Interface", "CVE-2019-0001", types.Advisory{}); err != nil {
+			return err
+		}
+		return dbc.PutAdvisory(tx, "debian 9", "NetworkManager", "CVE-2019-0002", types.Advisory{})
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		source  string
+		pkgName string
+		want    []types.Advisory
+	}{
+		{name: "stored form", source: "python-safety-db", pkgName: "zope-interface",
+			want: []types.Advisory{{VulnerabilityID: "CVE-2019-0001"}}},
+		{name: "other spelling", source: "python-safety-db", pkgName: "zope_Interface",
+			want: []types.Advisory{{VulnerabilityID: "CVE-2019-0001"}}},
+		{name: "os package as is", source: "debian 9", pkgName: "NetworkManager",
+			want: []types.Advisory{{VulnerabilityID: "CVE-2019-0002"}}},
+		{name: "os package is case-sensitive", source: "debian 9", pkgName: "networkmanager"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dbc.GetAdvisories(tt.source, tt.pkgName)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	pkgs, err := dbc.GetAffectedPackages("CVE-2019-0001")
+	assert.NoError(t, err)
+	if assert.Len(t, pkgs, 1) {
+		assert.Equal(t, "zope-interface", pkgs[0].Package)
+	}
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"sync"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// ErrReadOnlyPool is returned by the Put functions of a DB opened with OpenReadOnlyPool
+var ErrReadOnlyPool = xerrors.New("the DB is opened read-only")
+
+// OpenReadOnlyPool opens an existing DB like OpenReadOnly, keeping size read transactions open for the lookups.
+// Lookups from any number of goroutines borrow one of them instead of beginning their own, which saves
+// the lock taken to begin a transaction when serving many lookups at once. A lookup finding them all
+// in use begins its own, so nested lookups can't deadlock. Close ends the transactions.
+func OpenReadOnlyPool(cacheDir string, size int) error {
+	if size <= 0 {
+		return xerrors.Errorf("invalid pool size: %d", size)
+	}
+	if err := OpenReadOnly(cacheDir); err != nil {
+		return err
+	}
+
+	p := &readerPool{
+		store: db,
+		txs:   make(chan storage.Tx, size),
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		started := make(chan error, 1)
+		p.wg.Add(1)
+		go p.hold(started)
+		if err := <-started; err != nil {
+			_ = p.Close()
+			return xerrors.Errorf("failed to begin a read transaction: %w", err)
+		}
+		p.size++
+	}
+	db = p
+	return nil
+}
+
+// readerPool is a read-only storage.Store lending transactions kept open by goroutines blocked in View
+type readerPool struct {
+	store storage.Store
+	size  int
+	txs   chan storage.Tx
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// hold keeps a transaction open in the pool until Close
+func (p *readerPool) hold(started chan<- error) {
+	defer p.wg.Done()
+	held := false
+	err := p.store.View(func(tx storage.Tx) error {
+		held = true
+		p.txs <- tx
+		started <- nil
+		<-p.done
+		return nil
+	})
+	if !held {
+		started <- err
+	}
+}
+
+func (p *readerPool) View(fn func(storage.Tx) error) error {
+	select {
+	case tx := <-p.txs:
+		// a storage transaction may not be used by two goroutines at once
+		defer func() { p.txs <- tx }()
+		return fn(tx)
+	default:
+		return p.store.View(fn)
+	}
+}
+
+func (p *readerPool) Update(func(storage.Tx) error) error {
+	return ErrReadOnlyPool
+}
+
+func (p *readerPool) Batch(func(storage.Tx) error) error {
+	return ErrReadOnlyPool
+}
+
+// Close waits for the lookups in progress, then ends the transactions and closes the DB
+func (p *readerPool) Close() error {
+	for i := 0; i < p.size; i++ {
+		<-p.txs
+	}
+	close(p.done)
+	p.wg.Wait()
+	return p.store.Close()
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestOpenReadOnlyPool(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestOpenReadOnlyPool_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	buildPoolDB(t, d, 100)
+
+	assert.NoError(t, OpenReadOnlyPool(d, 2))
+	dbc := Config{}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				pkgName := fmt.Sprintf("pkg-%d", (g+i)%100)
+				advisories, err := dbc.GetAdvisories("alpine 3.10", pkgName)
+				assert.NoError(t, err)
+				assert.Equal(t, []types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: pkgName}}, advisories)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		return dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityHigh)
+	})
+	assert.Error(t, err)
+	assert.NoError(t, Close())
+
+	assert.Error(t, OpenReadOnlyPool(d, 0))
+}
+
+func BenchmarkGetAdvisories(b *testing.B) {
+	d, err := ioutil.TempDir("", "BenchmarkGetAdvisories_*")
+	assert.NoError(b, err)
+	defer os.RemoveAll(d)
+	buildPoolDB(b, d, 1000)
+
+	benchmarks := []struct {
+		name string
+		open func() error
+	}{
+		{name: "transaction per lookup", open: func() error { return OpenReadOnly(d) }},
+		{name: "pool", open: func() error { return OpenReadOnlyPool(d, 16) }},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			assert.NoError(b, bm.open())
+			defer Close()
+
+			dbc := Config{}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if _, err := dbc.GetAdvisories("alpine 3.10", fmt.Sprintf("pkg-%d", i%1000)); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func buildPoolDB(t assert.TestingT, cacheDir string, n int) {
+	assert.NoError(t, Init(cacheDir))
+	dbc := Config{}
+	err := dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		for i := 0; i < n; i++ {
+			pkgName := fmt.Sprintf("pkg-%d", i)
+			if err := dbc.PutAdvisory(tx, "alpine 3.10", pkgName, "CVE-2019-0001", types.Advisory{FixedVersion: pkgName}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+	assert.NoError(t, Close())
+}
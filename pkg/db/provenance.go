@@ -0,0 +1,64 @@
+package db
+
+import (
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// provenanceBucket traces the advisories to upstream: provenance => advisory bucket => package\x00CVE-ID
+const provenanceBucket = "provenance"
+
+type ProvenanceStore interface {
+	PutProvenance(Tx, string, string, string, types.Provenance) error
+	GetProvenance(string, string, string) (types.Provenance, error)
+	DeleteProvenanceBucket() error
+}
+
+// PutProvenance records the upstream record of the advisory put by PutAdvisory with the same arguments
+func (dbc Config) PutProvenance(tx Tx, source, pkgName, cveID string, provenance types.Provenance) error {
+	pkgName = normalizeSourcePackage(source, pkgName)
+	v, err := Marshal(provenance)
+	if err != nil {
+		return err
+	}
+	if err = dbc.putNestedBucket(tx, provenanceBucket, dbc.advisoryBucket(source), pkgName+keySeparator+cveID, v); err != nil {
+		return xerrors.Errorf("failed to save the provenance: %w", err)
+	}
+	return nil
+}
+
+// GetProvenance returns the upstream record of an advisory, the source is a platform, e.g. debian 9,
+// or an advisory bucket. The provenance is empty for the advisories put without one.
+func (dbc Config) GetProvenance(source, pkgName, cveID string) (types.Provenance, error) {
+	var provenance types.Provenance
+	err := db.View(func(tx Tx) error {
+		root := tx.Bucket([]byte(provenanceBucket))
+		if root == nil {
+			return nil
+		}
+		for _, bucket := range dbc.advisoryBuckets(tx, source) {
+			nested := root.Bucket([]byte(bucket))
+			if nested == nil {
+				continue
+			}
+			_, platform := SplitAdvisoryBucket(bucket)
+			v, err := decode(nested.Get([]byte(normalizeSourcePackage(platform, pkgName) + keySeparator + cveID)))
+			if err != nil {
+				return err
+			} else if v != nil {
+				return Unmarshal(v, &provenance)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return types.Provenance{}, xerrors.Errorf("failed to get the provenance: %w", err)
+	}
+	return provenance, nil
+}
+
+// DeleteProvenanceBucket deletes the provenance, which only the builders of the DB use
+func (dbc Config) DeleteProvenanceBucket() error {
+	return dbc.deleteBucketIfExists(provenanceBucket)
+}
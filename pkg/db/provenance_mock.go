@@ -0,0 +1,36 @@
+package db
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+var _ ProvenanceStore = &MockProvenanceStore{}
+
+type MockProvenanceStore struct {
+	mock.Mock
+}
+
+func (_m *MockProvenanceStore) PutProvenance(a Tx, b, c, d string, e types.Provenance) error {
+	ret := _m.Called(a, b, c, d, e)
+	return ret.Error(0)
+}
+
+func (_m *MockProvenanceStore) GetProvenance(a, b, c string) (types.Provenance, error) {
+	ret := _m.Called(a, b, c)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return types.Provenance{}, ret.Error(1)
+	}
+	provenance, ok := ret0.(types.Provenance)
+	if !ok {
+		return types.Provenance{}, ret.Error(1)
+	}
+	return provenance, ret.Error(1)
+}
+
+func (_m *MockProvenanceStore) DeleteProvenanceBucket() error {
+	ret := _m.Called()
+	return ret.Error(0)
+}
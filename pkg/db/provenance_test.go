@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_GetProvenance(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_GetProvenance_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	provenance := types.Provenance{
+		Path:   "vuln-list/alpine/3.10/main/CVE-2019-0001.json",
+		SHA256: "5b2ac1b45a8f3a1e7d7c5c4d4e6d1b2b0c9b44a7d1e8b0c6d36f15b1d0e3e0a1",
+	}
+	dbc := Config{}
+	put := func(cveIDs ...string) {
+		dbc.TrackWrites()
+		assert.NoError(t, dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+			for _, cveID := range cveIDs {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", cveID, types.Advisory{FixedVersion: "1.0"}); err != nil {
+					return err
+				}
+				if err := dbc.PutProvenance(tx, "alpine 3.10", "openssl", cveID, provenance); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		_, err := dbc.Prune("alpine")
+		assert.NoError(t, err)
+	}
+	put("CVE-2019-0001", "CVE-2019-0002")
+
+	tests := []struct {
+		name    string
+		source  string
+		pkgName string
+		cveID   string
+		want    types.Provenance
+	}{
+		{
+			name:    "platform",
+			source:  "alpine 3.10",
+			pkgName: "openssl",
+			cveID:   "CVE-2019-0001",
+			want:    provenance,
+		},
+		{
+			name:    "unknown advisory",
+			source:  "alpine 3.10",
+			pkgName: "openssl",
+			cveID:   "CVE-2019-9999",
+		},
+		{
+			name:    "unknown platform",
+			source:  "alpine 3.11",
+			pkgName: "openssl",
+			cveID:   "CVE-2019-0001",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dbc.GetProvenance(tt.source, tt.pkgName, tt.cveID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// the provenance of a pruned advisory goes with it
+	put("CVE-2019-0002")
+	got, err := dbc.GetProvenance("alpine 3.10", "openssl", "CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, types.Provenance{}, got)
+	got, err = dbc.GetProvenance("alpine 3.10", "openssl", "CVE-2019-0002")
+	assert.NoError(t, err)
+	assert.Equal(t, provenance, got)
+
+	assert.NoError(t, dbc.DeleteProvenanceBucket())
+	got, err = dbc.GetProvenance("alpine 3.10", "openssl", "CVE-2019-0002")
+	assert.NoError(t, err)
+	assert.Equal(t, types.Provenance{}, got)
+}
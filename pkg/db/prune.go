@@ -0,0 +1,236 @@
+package db
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+const (
+	// writtenBucket records the advisories each source put in the last build: trivy => written => source => key
+	writtenBucket = "written"
+
+	keySeparator = "\x00"
+)
+
+var (
+	// written holds the advisories put since TrackWrites, nil when writes are not tracked
+	written map[string]struct{}
+
+	// an empty value could be taken for a nested bucket by some drivers
+	writtenValue = []byte{1}
+)
+
+// TrackWrites starts recording the advisories put by a source for Prune
+func (dbc Config) TrackWrites() {
+	written = map[string]struct{}{}
+}
+
+// Prune deletes the advisories the source put in its previous build but not since TrackWrites,
+// unless another source still puts them, and stops tracking. It returns the number of advisories
+// the source put which it didn't put in its previous build.
+// Nothing is deleted when the source put no advisory at all, as it is most likely broken upstream.
+func (dbc Config) Prune(source string) (int, error) {
+	return dbc.prune(source, nil)
+}
+
+// PruneChanged is Prune for an incremental update, which only read the changed files, the paths relative
+// to the cache dir the provenance has: only the advisories the source put from one of them in its previous
+// build are deleted if it didn't put them again. The others, and the ones without a provenance, are kept.
+func (dbc Config) PruneChanged(source string, changed map[string]bool) (int, error) {
+	if changed == nil {
+		changed = map[string]bool{}
+	}
+	return dbc.prune(source, changed)
+}
+
+// prune deletes the stale advisories, all those the source didn't put again when changed is nil
+func (dbc Config) prune(source string, changed map[string]bool) (int, error) {
+	current := written
+	written = nil
+	if current == nil {
+		return 0, xerrors.New("writes are not tracked")
+	}
+
+	var added int
+	err := writeTx(func(tx Tx) error {
+		root, err := tx.CreateBucketIfNotExists([]byte(metadataBucket))
+		if err != nil {
+			return xerrors.Errorf("failed to create a bucket: %w", err)
+		}
+		sources, err := root.CreateBucketIfNotExists([]byte(writtenBucket))
+		if err != nil {
+			return xerrors.Errorf("failed to create a bucket: %w", err)
+		}
+
+		var stale, kept []string
+		added = len(current)
+		if previous := sources.Bucket([]byte(source)); previous != nil {
+			err = previous.ForEach(func(k, _ []byte) error {
+				if _, ok := current[string(k)]; ok {
+					added--
+					return nil
+				}
+				if changed != nil {
+					if ok, err := readFrom(tx, string(k), changed); err != nil {
+						return err
+					} else if !ok {
+						kept = append(kept, string(k))
+						return nil
+					}
+				}
+				stale = append(stale, string(k))
+				return nil
+			})
+			if err != nil {
+				return xerrors.Errorf("failed to list the previous advisories: %w", err)
+			}
+		}
+		// an incremental update may only have deleted files
+		if changed == nil && len(current) == 0 && len(stale) > 0 {
+			log.OrDefault(dbc.Logger).Warn("The source put no advisory, skipping pruning", "source", source, "advisories", len(stale))
+			return nil
+		}
+
+		sort.Strings(stale)
+		pruned := 0
+		for _, key := range stale {
+			if ok, err := writtenByOthers(sources, source, key); err != nil {
+				return err
+			} else if ok {
+				continue
+			}
+			if err = deleteAdvisory(tx, key); err != nil {
+				return xerrors.Errorf("failed to delete %q: %w", strings.Replace(key, keySeparator, " ", -1), err)
+			}
+			pruned++
+		}
+		if pruned > 0 {
+			log.OrDefault(dbc.Logger).Info("Pruned the advisories the source no longer has", "source", source, "advisories", pruned)
+		}
+
+		// replace the record with this build
+		if err = sources.DeleteBucket([]byte(source)); err != nil && err != storage.ErrBucketNotFound {
+			return xerrors.Errorf("failed to delete the previous advisories: %w", err)
+		}
+		b, err := sources.CreateBucketIfNotExists([]byte(source))
+		if err != nil {
+			return xerrors.Errorf("failed to create a bucket: %w", err)
+		}
+		for key := range current {
+			if err = b.Put([]byte(key), writtenValue); err != nil {
+				return xerrors.Errorf("failed to record the advisories: %w", err)
+			}
+		}
+		for _, key := range kept {
+			if err = b.Put([]byte(key), writtenValue); err != nil {
+				return xerrors.Errorf("failed to record the advisories: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, xerrors.Errorf("failed to prune %s: %w", source, err)
+	}
+	return added, nil
+}
+
+func trackWrite(source, pkgName, cveID string) {
+	if written == nil {
+		return
+	}
+	written[strings.Join([]string{source, pkgName, cveID}, keySeparator)] = struct{}{}
+}
+
+// readFrom tells whether the provenance of the advisory of the key is one of the files
+func readFrom(tx Tx, key string, files map[string]bool) (bool, error) {
+	parts := strings.SplitN(key, keySeparator, 2)
+	if len(parts) != 2 {
+		return false, xerrors.New("malformed key")
+	}
+	root := tx.Bucket([]byte(provenanceBucket))
+	if root == nil {
+		return false, nil
+	}
+	nested := root.Bucket([]byte(parts[0]))
+	if nested == nil {
+		return false, nil
+	}
+	v, err := decode(nested.Get([]byte(parts[1])))
+	if err != nil || v == nil {
+		return false, err
+	}
+	var provenance types.Provenance
+	if err = Unmarshal(v, &provenance); err != nil {
+		return false, xerrors.Errorf("failed to decode the provenance: %w", err)
+	}
+	return files[provenance.Path], nil
+}
+
+func writtenByOthers(sources storage.Bucket, source, key string) (bool, error) {
+	var found bool
+	err := sources.ForEach(func(name, _ []byte) error {
+		if found || string(name) == source {
+			return nil
+		}
+		if b := sources.Bucket(name); b != nil && b.Get([]byte(key)) != nil {
+			found = true
+		}
+		return nil
+	})
+	return found, err
+}
+
+// deleteAdvisory deletes an advisory, its provenance, its entry in the affected package index
+// and the buckets left empty
+func deleteAdvisory(tx Tx, key string) error {
+	parts := strings.SplitN(key, keySeparator, 3)
+	if len(parts) != 3 {
+		return xerrors.New("malformed key")
+	}
+	source, pkgName, cveID := parts[0], parts[1], parts[2]
+
+	if err := deleteNested(tx, source, pkgName, cveID); err != nil {
+		return err
+	}
+	if err := deleteNested(tx, provenanceBucket, source, pkgName+keySeparator+cveID); err != nil {
+		return err
+	}
+	return deleteNested(tx, affectedPackageBucket, cveID, source+"/"+pkgName)
+}
+
+func deleteNested(tx Tx, rootBucket, nestedBucket, key string) error {
+	root := tx.Bucket([]byte(rootBucket))
+	if root == nil {
+		return nil
+	}
+	nested := root.Bucket([]byte(nestedBucket))
+	if nested == nil {
+		return nil
+	}
+	if err := nested.Delete([]byte(key)); err != nil {
+		return err
+	}
+	if !isEmpty(nested) {
+		return nil
+	}
+	if err := root.DeleteBucket([]byte(nestedBucket)); err != nil {
+		return err
+	}
+	if !isEmpty(root) {
+		return nil
+	}
+	return tx.DeleteBucket([]byte(rootBucket))
+}
+
+func isEmpty(b storage.Bucket) bool {
+	err := b.ForEach(func(_, _ []byte) error {
+		return xerrors.New("not empty")
+	})
+	return err == nil
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Prune(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Prune_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	type advisory struct {
+		source  string
+		pkgName string
+		cveID   string
+	}
+	build := func(name string, advisories []advisory) int {
+		dbc.TrackWrites()
+		err := dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+			for _, a := range advisories {
+				if err := dbc.PutAdvisory(tx, a.source, a.pkgName, a.cveID, types.Advisory{FixedVersion: "1.0.0"}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		added, err := dbc.Prune(name)
+		assert.NoError(t, err)
+		return added
+	}
+	cveIDs := func(source, pkgName string) []string {
+		advisories, err := dbc.GetAdvisories(source, pkgName)
+		assert.NoError(t, err)
+		var ids []string
+		for _, a := range advisories {
+			ids = append(ids, a.VulnerabilityID)
+		}
+		return ids
+	}
+
+	// two sources share the bucket
+	assert.Equal(t, 3, build("redhat", []advisory{
+		{source: "Red Hat Enterprise Linux 8", pkgName: "openssl", cveID: "CVE-2019-0001"},
+		{source: "Red Hat Enterprise Linux 8", pkgName: "openssl", cveID: "CVE-2019-0002"},
+		{source: "Red Hat Enterprise Linux 8", pkgName: "curl", cveID: "CVE-2019-0003"},
+	}))
+	assert.Equal(t, 2, build("redhat-oval", []advisory{
+		{source: "Red Hat Enterprise Linux 8", pkgName: "openssl", cveID: "CVE-2019-0002"},
+		{source: "Red Hat Enterprise Linux 8", pkgName: "openssl", cveID: "CVE-2019-0004"},
+	}))
+
+	// CVE-2019-0002 is still put by redhat-oval
+	assert.Equal(t, 0, build("redhat", []advisory{
+		{source: "Red Hat Enterprise Linux 8", pkgName: "openssl", cveID: "CVE-2019-0001"},
+	}))
+	assert.Equal(t, []string{"CVE-2019-0001", "CVE-2019-0002", "CVE-2019-0004"}, cveIDs("Red Hat Enterprise Linux 8", "openssl"))
+	assert.Empty(t, cveIDs("Red Hat Enterprise Linux 8", "curl"))
+
+	pkgs, err := dbc.GetAffectedPackages("CVE-2019-0003")
+	assert.NoError(t, err)
+	assert.Empty(t, pkgs)
+
+	// a source without advisories is left alone
+	build("redhat-oval", nil)
+	assert.Equal(t, []string{"CVE-2019-0001", "CVE-2019-0002", "CVE-2019-0004"}, cveIDs("Red Hat Enterprise Linux 8", "openssl"))
+
+	build("redhat-oval", []advisory{
+		{source: "Red Hat Enterprise Linux 8", pkgName: "openssl", cveID: "CVE-2019-0004"},
+	})
+	assert.Equal(t, []string{"CVE-2019-0001", "CVE-2019-0004"}, cveIDs("Red Hat Enterprise Linux 8", "openssl"))
+
+	_, err = dbc.Prune("redhat")
+	assert.Error(t, err, "writes are not tracked")
+}
+
+func TestConfig_PruneChanged(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_PruneChanged_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	type advisory struct {
+		cveID string
+		path  string // of the provenance, none if empty
+	}
+	put := func(advisories []advisory) {
+		dbc.TrackWrites()
+		err := dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+			for _, a := range advisories {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", a.cveID, types.Advisory{FixedVersion: "1.0.0"}); err != nil {
+					return err
+				}
+				if a.path == "" {
+					continue
+				}
+				if err := dbc.PutProvenance(tx, "alpine 3.10", "openssl", a.cveID, types.Provenance{Path: a.path}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+	cveIDs := func() []string {
+		advisories, err := dbc.GetAdvisories("alpine 3.10", "openssl")
+		assert.NoError(t, err)
+		var ids []string
+		for _, a := range advisories {
+			ids = append(ids, a.VulnerabilityID)
+		}
+		return ids
+	}
+
+	put([]advisory{
+		{cveID: "CVE-2019-0001", path: "vuln-list/alpine/a.json"},
+		{cveID: "CVE-2019-0002", path: "vuln-list/alpine/a.json"},
+		{cveID: "CVE-2019-0003", path: "vuln-list/alpine/b.json"},
+		{cveID: "CVE-2019-0004", path: "vuln-list/alpine/c.json"},
+		{cveID: "CVE-2019-0005"},
+	})
+	_, err = dbc.Prune("alpine")
+	assert.NoError(t, err)
+
+	// a.json dropped CVE-2019-0002 and added CVE-2019-0006, c.json was deleted
+	put([]advisory{
+		{cveID: "CVE-2019-0001", path: "vuln-list/alpine/a.json"},
+		{cveID: "CVE-2019-0006", path: "vuln-list/alpine/a.json"},
+	})
+	added, err := dbc.PruneChanged("alpine", map[string]bool{
+		"vuln-list/alpine/a.json": true,
+		"vuln-list/alpine/c.json": true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, added)
+	assert.Equal(t, []string{"CVE-2019-0001", "CVE-2019-0003", "CVE-2019-0005", "CVE-2019-0006"}, cveIDs())
+
+	// the kept advisories are still tracked by the next build
+	put([]advisory{
+		{cveID: "CVE-2019-0001", path: "vuln-list/alpine/a.json"},
+	})
+	_, err = dbc.Prune("alpine")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CVE-2019-0001"}, cveIDs())
+
+	// nothing changed
+	dbc.TrackWrites()
+	added, err = dbc.PruneChanged("alpine", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, added)
+	assert.Equal(t, []string{"CVE-2019-0001"}, cveIDs())
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/package-url/packageurl-go"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+// ErrUnsupportedPURL is returned when a package URL doesn't map to an advisory bucket
+var ErrUnsupportedPURL = xerrors.New("unsupported package URL")
+
+// the db package can't import vulnsrc, so the bucket names are repeated here
+var (
+	// purl namespace => bucket format and how many version components the bucket keeps
+	osBuckets = map[string]map[string]struct {
+		format     string
+		components int
+	}{
+		"deb": {
+			"debian": {format: "debian %s", components: 1},
+			"ubuntu": {format: "ubuntu %s", components: 2},
+		},
+		"rpm": {
+			"redhat": {format: "Red Hat Enterprise Linux %s", components: 1},
+			"oracle": {format: "Oracle Linux %s", components: 1},
+			"amazon": {format: "amazon linux %s", components: 1},
+		},
+		"apk": {
+			"alpine": {format: "alpine %s", components: 2},
+		},
+	}
+
+	languageBuckets = map[string]string{
+		packageurl.TypeNPM:      "nodejs-security-wg",
+		packageurl.TypePyPi:     "python-safety-db",
+		packageurl.TypeGem:      "ruby-advisory-db",
+		"cargo":                 "rust-advisory-db",
+		packageurl.TypeComposer: "php-security-advisories",
+	}
+)
+
+// GetAdvisoriesByPURL returns the advisories of the package a package URL points to,
+// e.g. pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9 or pkg:npm/%40babel/core@7.0.0
+func (dbc Config) GetAdvisoriesByPURL(purl string) ([]types.Advisory, error) {
+	source, pkgName, _, err := ParsePURL(purl)
+	if err != nil {
+		return nil, err
+	}
+	return dbc.GetAdvisories(source, pkgName)
+}
+
+// ParsePURL returns the advisory bucket of the package a package URL points to, the name of the package in it
+// and its version, e.g. debian 9, openssl and 1.1.0l-1 for pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9
+func ParsePURL(purl string) (source, pkgName, version string, err error) {
+	p, err := packageurl.FromString(purl)
+	if err != nil {
+		return "", "", "", xerrors.Errorf("failed to parse the package URL: %w", err)
+	}
+	if source, pkgName, err = purlBucket(p); err != nil {
+		return "", "", "", xerrors.Errorf("%s: %w", purl, err)
+	}
+	return source, pkgName, p.Version, nil
+}
+
+// PURLArch returns the architecture of the package a package URL points to, e.g. aarch64 for
+// pkg:rpm/redhat/openssl@1.1.1c-2.el8?arch=aarch64&distro=redhat-8, empty if it has none
+func PURLArch(purl string) string {
+	p, err := packageurl.FromString(purl)
+	if err != nil {
+		return ""
+	}
+	return p.Qualifiers.Map()["arch"]
+}
+
+// purlBucket returns the advisory bucket and the package name in it
+func purlBucket(p packageurl.PackageURL) (string, string, error) {
+	if source, ok := languageBuckets[p.Type]; ok {
+		return source, purlPackageName(p), nil
+	}
+
+	namespaces, ok := osBuckets[p.Type]
+	if !ok {
+		return "", "", ErrUnsupportedPURL
+	}
+	bucket, ok := namespaces[p.Namespace]
+	if !ok {
+		return "", "", ErrUnsupportedPURL
+	}
+	release := purlRelease(p.Qualifiers.Map()["distro"], bucket.components)
+	if release == "" {
+		return "", "", xerrors.Errorf("no distro qualifier: %w", ErrUnsupportedPURL)
+	}
+	return fmt.Sprintf(bucket.format, release), p.Name, nil
+}
+
+// purlPackageName returns the name the advisories of a language package are stored under
+func purlPackageName(p packageurl.PackageURL) string {
+	name := p.Name
+	switch p.Type {
+	case packageurl.TypeNPM:
+		// scoped packages, e.g. @babel/core
+		if p.Namespace != "" {
+			name = p.Namespace + "/" + p.Name
+		}
+	case packageurl.TypeComposer:
+		// FriendsOfPHP references packages as composer://vendor/name
+		name = "composer://" + p.Namespace + "/" + p.Name
+	}
+	return utils.NormalizePackageName(p.Type, name)
+}
+
+// purlRelease extracts the release from a distro qualifier like debian-9.1, ubuntu-18.04 or 3.10.2
+// and keeps the given number of version components
+func purlRelease(distro string, components int) string {
+	if i := strings.LastIndex(distro, "-"); i >= 0 {
+		distro = distro[i+1:]
+	}
+	if distro == "" {
+		return ""
+	}
+	parts := strings.SplitN(distro, ".", components+1)
+	if len(parts) > components {
+		parts = parts[:components]
+	}
+	return strings.Join(parts, ".")
+}
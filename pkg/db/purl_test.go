@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_GetAdvisoriesByPURL(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_GetAdvisoriesByPURL_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		advisories := []struct {
+			source  string
+			pkgName string
+		}{
+			{source: "debian 9", pkgName: "openssl"},
+			{source: "ubuntu 18.04", pkgName: "openssl"},
+			{source: "Red Hat Enterprise Linux 8", pkgName: "openssl"},
+			{source: "amazon linux 2", pkgName: "openssl"},
+			{source: "alpine 3.10", pkgName: "openssl"},
+			{source: "nodejs-security-wg", pkgName: "@babel/core"},
+			{source: "python-safety-db", pkgName: "zope-interface"},
+			{source: "ruby-advisory-db", pkgName: "rails"},
+			{source: "rust-advisory-db", pkgName: "smallvec"},
+			{source: "php-security-advisories", pkgName: "composer://symfony/http-kernel"},
+		}
+		for _, a := range advisories {
+			if err := dbc.PutAdvisory(tx, a.source, a.pkgName, "CVE-2019-0001", types.Advisory{FixedVersion: "1.0.0"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	want := []types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.0.0"}}
+	tests := []struct {
+		name    string
+		purl    string
+		want    []types.Advisory
+		wantErr error
+	}{
+		{name: "debian", purl: "pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9.1", want: want},
+		{name: "ubuntu", purl: "pkg:deb/ubuntu/openssl@1.1.1-1ubuntu2?distro=ubuntu-18.04", want: want},
+		{name: "red hat", purl: "pkg:rpm/redhat/openssl@1.1.1c-2.el8?arch=x86_64&distro=redhat-8.1", want: want},
+		{name: "amazon", purl: "pkg:rpm/amazon/openssl@1.0.2k-19.amzn2?distro=amazon-2", want: want},
+		{name: "alpine", purl: "pkg:apk/alpine/openssl@1.1.1d-r0?distro=3.10.2", want: want},
+		{name: "scoped npm package", purl: "pkg:npm/%40babel/core@7.0.0", want: want},
+		{name: "pypi name normalization", purl: "pkg:pypi/Zope_Interface@4.0.0", want: want},
+		{name: "gem", purl: "pkg:gem/rails@5.2.0", want: want},
+		{name: "cargo", purl: "pkg:cargo/smallvec@0.6.0", want: want},
+		{name: "composer", purl: "pkg:composer/symfony/http-kernel@4.0.0", want: want},
+		{
+			name: "no advisories",
+			purl: "pkg:deb/debian/curl@7.64.0-4?distro=debian-9",
+		},
+		{
+			name:    "no distro",
+			purl:    "pkg:deb/debian/openssl@1.1.0l-1",
+			wantErr: ErrUnsupportedPURL,
+		},
+		{
+			name:    "unknown distro",
+			purl:    "pkg:rpm/fedora/openssl@1.1.1?distro=fedora-31",
+			wantErr: ErrUnsupportedPURL,
+		},
+		{
+			name:    "unsupported type",
+			purl:    "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.0",
+			wantErr: ErrUnsupportedPURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dbc.GetAdvisoriesByPURL(tt.purl)
+			if tt.wantErr != nil {
+				assert.True(t, xerrors.Is(err, tt.wantErr), err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
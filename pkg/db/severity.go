@@ -2,7 +2,6 @@ package db
 
 import (
 	"github.com/aquasecurity/trivy-db/pkg/types"
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 )
 
@@ -10,7 +9,7 @@ const (
 	severityBucket = "severity"
 )
 
-func (dbc Config) PutSeverity(tx *bolt.Tx, cveID string, severity types.Severity) error {
+func (dbc Config) PutSeverity(tx Tx, cveID string, severity types.Severity) error {
 	bucket, err := tx.CreateBucketIfNotExists([]byte(severityBucket))
 	if err != nil {
 		return xerrors.Errorf("failed to create a bucket: %w", err)
@@ -19,8 +18,11 @@ func (dbc Config) PutSeverity(tx *bolt.Tx, cveID string, severity types.Severity
 }
 
 func (dbc Config) GetSeverity(cveID string) (severity types.Severity, err error) {
-	err = db.View(func(tx *bolt.Tx) error {
+	err = db.View(func(tx Tx) error {
 		bucket := tx.Bucket([]byte(severityBucket))
+		if bucket == nil {
+			return xerrors.Errorf("%s: %w", severityBucket, ErrNoBucket)
+		}
 		value := bucket.Get([]byte(cveID))
 		severity, err = types.NewSeverity(string(value))
 		if err != nil {
@@ -34,8 +36,8 @@ func (dbc Config) GetSeverity(cveID string) (severity types.Severity, err error)
 	return severity, nil
 }
 
-func (dbc Config) ForEachSeverity(f func(tx *bolt.Tx, cveID string, severity types.Severity) error) error {
-	err := db.Batch(func(tx *bolt.Tx) error {
+func (dbc Config) ForEachSeverity(f func(tx Tx, cveID string, severity types.Severity) error) error {
+	err := batchTx(func(tx Tx) error {
 		bucket, err := tx.CreateBucketIfNotExists([]byte(severityBucket))
 		if err != nil {
 			return xerrors.Errorf("failed to create a bucket: %w", err)
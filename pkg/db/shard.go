@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// ShardMerger merges the DBs sources were built into by themselves, so that they can be built in parallel
+type ShardMerger interface {
+	MergeShard(context.Context, string, string) error
+}
+
+// shardRecord is a value of a shard waiting to be copied
+type shardRecord struct {
+	bucket []string
+	key    []byte
+	value  []byte
+}
+
+// MergeShard copies the bolt DB at path, which the source was built into alone, into this DB in one pass,
+// committing every DefaultChunkSize values. The advisories the shard recorded for Prune are tracked as if
+// the source had put them here, and the rest of its metadata is left out. A value already here is
+// overwritten, as the source would have done.
+func (dbc Config) MergeShard(ctx context.Context, path, source string) error {
+	shard, err := storage.Open(storage.DefaultDriver, path, storage.Options{ReadOnly: true})
+	if err != nil {
+		return xerrors.Errorf("failed to open the shard of %s: %w", source, err)
+	}
+	defer shard.Close()
+
+	var pending []shardRecord
+	flush := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := writeTx(func(tx Tx) error {
+			return copyShardRecords(journalTx(tx), pending)
+		})
+		if err != nil {
+			return err
+		}
+		log.ProgressFrom(ctx).AddRecords(len(pending))
+		pending = pending[:0]
+		return nil
+	}
+
+	err = shard.View(func(stx storage.Tx) error {
+		err := stx.ForEach(func(name []byte, b storage.Bucket) error {
+			if string(name) == metadataBucket {
+				return trackShardWrites(b, source)
+			}
+			return walkShardBucket(b, []string{string(name)}, func(bucket []string, k, v []byte) error {
+				// the values are only valid during the transaction of the shard
+				pending = append(pending, shardRecord{
+					bucket: bucket,
+					key:    append([]byte{}, k...),
+					value:  append([]byte{}, v...),
+				})
+				if len(pending) < DefaultChunkSize {
+					return nil
+				}
+				return flush()
+			})
+		})
+		if err != nil {
+			return err
+		}
+		return flush()
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to merge the shard of %s: %w", source, err)
+	}
+	return nil
+}
+
+// walkShardBucket calls fn with each value of b and its nested buckets, and the path of its bucket
+func walkShardBucket(b storage.Bucket, path []string, fn func(bucket []string, k, v []byte) error) error {
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			nested := append(append([]string{}, path...), string(k))
+			return walkShardBucket(b.Bucket(k), nested, fn)
+		}
+		return fn(path, k, v)
+	})
+}
+
+func copyShardRecords(tx Tx, records []shardRecord) error {
+	for _, rec := range records {
+		b, err := tx.CreateBucketIfNotExists([]byte(rec.bucket[0]))
+		for _, name := range rec.bucket[1:] {
+			if err != nil {
+				break
+			}
+			b, err = b.CreateBucketIfNotExists([]byte(name))
+		}
+		if err != nil {
+			return xerrors.Errorf("failed to create a bucket: %w", err)
+		}
+		if err = b.Put(rec.key, rec.value); err != nil {
+			return xerrors.Errorf("failed to put %q %s: %w", rec.bucket, rec.key, err)
+		}
+	}
+	return nil
+}
+
+// trackShardWrites tracks the advisories the shard recorded for the Prune of the source
+func trackShardWrites(root storage.Bucket, source string) error {
+	sources := root.Bucket([]byte(writtenBucket))
+	if sources == nil || written == nil {
+		return nil
+	}
+	b := sources.Bucket([]byte(source))
+	if b == nil {
+		return nil
+	}
+	return b.ForEach(func(k, _ []byte) error {
+		written[string(k)] = struct{}{}
+		return nil
+	})
+}
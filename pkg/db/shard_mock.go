@@ -0,0 +1,18 @@
+package db
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ ShardMerger = &MockShardMerger{}
+
+type MockShardMerger struct {
+	mock.Mock
+}
+
+func (_m *MockShardMerger) MergeShard(a context.Context, b, c string) error {
+	ret := _m.Called(a, b, c)
+	return ret.Error(0)
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_MergeShard(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_MergeShard_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	dbc := Config{}
+	ctx := context.Background()
+	putAdvisories := func(cveIDs ...string) {
+		dbc.TrackWrites()
+		assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+			for _, cveID := range cveIDs {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", cveID, types.Advisory{FixedVersion: "1.0"}); err != nil {
+					return err
+				}
+				if err := dbc.PutSeverity(tx, cveID, types.SeverityHigh); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		_, err := dbc.Prune("alpine")
+		assert.NoError(t, err)
+	}
+
+	// the shard of alpine no longer has CVE-2019-0001
+	shardDir := filepath.Join(d, "shard")
+	assert.NoError(t, Init(shardDir))
+	putAdvisories("CVE-2019-0002", "CVE-2019-0003")
+	assert.NoError(t, Close())
+
+	assert.NoError(t, Init(d))
+	defer Close()
+	putAdvisories("CVE-2019-0001", "CVE-2019-0002")
+	assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+		return dbc.PutSeverity(tx, "CVE-2019-0002", types.SeverityLow)
+	}))
+
+	dbc.TrackWrites()
+	assert.NoError(t, dbc.MergeShard(ctx, Path(shardDir), "alpine"))
+	_, err = dbc.Prune("alpine")
+	assert.NoError(t, err)
+
+	got, err := dbc.GetAdvisories("alpine 3.10", "openssl")
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Advisory{
+		{VulnerabilityID: "CVE-2019-0002", FixedVersion: "1.0"},
+		{VulnerabilityID: "CVE-2019-0003", FixedVersion: "1.0"},
+	}, got)
+
+	// overwritten by the shard
+	severity, err := dbc.GetSeverity("CVE-2019-0002")
+	assert.NoError(t, err)
+	assert.Equal(t, types.SeverityHigh, severity)
+
+	pkgs, err := dbc.GetAffectedPackages("CVE-2019-0003")
+	assert.NoError(t, err)
+	assert.Len(t, pkgs, 1)
+}
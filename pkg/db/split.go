@@ -0,0 +1,259 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+const (
+	// SplitEcosystem splits the DB into os.db and lang.db
+	SplitEcosystem = "ecosystem"
+	// SplitDistro splits the DB into a file per OS family, e.g. alpine.db, and lang.db
+	SplitDistro = "distro"
+
+	splitOS   = "os"
+	splitLang = "lang"
+)
+
+var (
+	// root buckets that aren't advisory sources
+	nonAdvisoryBuckets = []string{metadataBucket, vulnerabilityBucket, vulnerabilityDetailBucket, severityBucket,
+		ssvcBucket, affectedPackageBucket, cpeBucket, sharedDetailBucket, dataSourceBucket, provenanceBucket}
+
+	// buckets keyed by CVE-ID, copied for the vulnerabilities of a split DB
+	vulnerabilityBuckets = []string{vulnerabilityBucket, vulnerabilityDetailBucket, severityBucket, ssvcBucket}
+)
+
+// Split writes the advisories into separate DB files next to the DB, each with the metadata and the
+// vulnerabilities it refers to, so that clients scanning one ecosystem download a fraction of the data.
+// The CPE index and the provenance are only in the full DB. Split returns the paths of the files.
+func (dbc Config) Split(driverName, mode string) ([]string, error) {
+	groups := map[string][]string{}
+	err := db.View(func(tx Tx) error {
+		return tx.ForEach(func(name []byte, _ storage.Bucket) error {
+			source := string(name)
+			if utils.StringInSlice(source, nonAdvisoryBuckets) {
+				return nil
+			}
+			group, err := splitGroup(source, mode)
+			if err != nil {
+				return err
+			}
+			groups[group] = append(groups[group], source)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to group the sources: %w", err)
+	}
+
+	var names []string
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+
+	var paths []string
+	for _, group := range names {
+		path := filepath.Join(dbDir, group+".db")
+		if err = dbc.writeSplit(driverName, path, groups[group]); err != nil {
+			return nil, xerrors.Errorf("failed to write %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// splitGroup returns the file name of an advisory bucket without the extension, e.g. "red-hat-enterprise-linux"
+func splitGroup(bucket, mode string) (string, error) {
+	_, source := SplitAdvisoryBucket(bucket)
+	lang := false
+	for _, s := range languageBuckets {
+		if s == source {
+			lang = true
+		}
+	}
+	switch {
+	case lang:
+		return splitLang, nil
+	case mode == SplitEcosystem:
+		return splitOS, nil
+	case mode == SplitDistro:
+		ecosystem, _ := splitSource(source)
+		return strings.Replace(strings.ToLower(ecosystem), " ", "-", -1), nil
+	}
+	return "", xerrors.Errorf("unknown split mode: %s", mode)
+}
+
+func (dbc Config) writeSplit(driverName, path string, sources []string) error {
+	// a file of a previous split, some drivers use a directory
+	if err := os.RemoveAll(path); err != nil {
+		return xerrors.Errorf("failed to remove the previous file: %w", err)
+	}
+	out, err := storage.Open(driverName, path, storage.Options{})
+	if err != nil {
+		return xerrors.Errorf("failed to open db: %w", err)
+	}
+	defer out.Close()
+
+	err = db.View(func(src Tx) error {
+		return out.Update(func(dst Tx) error {
+			if err := copyMetadata(src, dst); err != nil {
+				return xerrors.Errorf("failed to copy the metadata: %w", err)
+			}
+
+			dataSourceIDs, cveIDs := map[string]bool{}, map[string]bool{}
+			for _, source := range sources {
+				id, _ := SplitAdvisoryBucket(source)
+				dataSourceIDs[id] = true
+				err := copyRootBucket(src, dst, source, func(k []byte) bool { return true })
+				if err != nil {
+					return xerrors.Errorf("failed to copy %s: %w", source, err)
+				}
+				if err = collectCVEIDs(src.Bucket([]byte(source)), cveIDs); err != nil {
+					return xerrors.Errorf("failed to list the vulnerabilities of %s: %w", source, err)
+				}
+			}
+
+			for _, name := range vulnerabilityBuckets {
+				err := copyRootBucket(src, dst, name, func(k []byte) bool { return cveIDs[string(k)] })
+				if err != nil {
+					return xerrors.Errorf("failed to copy %s: %w", name, err)
+				}
+			}
+			err := copyRootBucket(src, dst, dataSourceBucket, func(k []byte) bool { return dataSourceIDs[string(k)] })
+			if err != nil {
+				return xerrors.Errorf("failed to copy %s: %w", dataSourceBucket, err)
+			}
+			// the shared texts are few, the vulnerabilities may refer to any of them
+			if err := copyRootBucket(src, dst, sharedDetailBucket, func([]byte) bool { return true }); err != nil {
+				return xerrors.Errorf("failed to copy %s: %w", sharedDetailBucket, err)
+			}
+			if err := copyAffectedPackages(src, dst, sources); err != nil {
+				return xerrors.Errorf("failed to copy %s: %w", affectedPackageBucket, err)
+			}
+
+			return dbc.refreshChecksums(dst)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// copyMetadata copies the metadata and the compression dictionary, but not the records of Prune
+func copyMetadata(src, dst Tx) error {
+	return copyRootBucket(src, dst, metadataBucket, func(k []byte) bool {
+		return string(k) != writtenBucket
+	})
+}
+
+// copyRootBucket copies the keys and nested buckets of a root bucket for which keep returns true
+func copyRootBucket(src, dst Tx, name string, keep func(k []byte) bool) error {
+	b := src.Bucket([]byte(name))
+	if b == nil {
+		return nil
+	}
+	var copied storage.Bucket
+	return b.ForEach(func(k, v []byte) error {
+		if !keep(k) {
+			return nil
+		}
+		if copied == nil {
+			var err error
+			if copied, err = dst.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		if v != nil {
+			return copied.Put(k, v)
+		}
+		nested, err := copied.CreateBucketIfNotExists(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(nested, b.Bucket(k))
+	})
+}
+
+func copyBucket(dst, src storage.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nested, err := dst.CreateBucketIfNotExists(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(nested, src.Bucket(k))
+	})
+}
+
+// collectCVEIDs adds the keys of the package buckets of a source
+func collectCVEIDs(source storage.Bucket, cveIDs map[string]bool) error {
+	return source.ForEach(func(pkgName, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		return source.Bucket(pkgName).ForEach(func(cveID, _ []byte) error {
+			cveIDs[string(cveID)] = true
+			return nil
+		})
+	})
+}
+
+// copyAffectedPackages copies the index entries of the sources, which are keyed by source/package
+func copyAffectedPackages(src, dst Tx, sources []string) error {
+	b := src.Bucket([]byte(affectedPackageBucket))
+	if b == nil {
+		return nil
+	}
+	var root storage.Bucket
+	return b.ForEach(func(cveID, _ []byte) error {
+		var nested storage.Bucket
+		return b.Bucket(cveID).ForEach(func(k, v []byte) error {
+			keep := false
+			for _, source := range sources {
+				if strings.HasPrefix(string(k), source+"/") {
+					keep = true
+				}
+			}
+			if !keep {
+				return nil
+			}
+			var err error
+			if root == nil {
+				if root, err = dst.CreateBucketIfNotExists([]byte(affectedPackageBucket)); err != nil {
+					return err
+				}
+			}
+			if nested == nil {
+				if nested, err = root.CreateBucketIfNotExists(cveID); err != nil {
+					return err
+				}
+			}
+			return nested.Put(k, v)
+		})
+	})
+}
+
+// refreshChecksums replaces the checksums in the metadata, if any, with the ones of the buckets in tx
+func (dbc Config) refreshChecksums(tx Tx) error {
+	checksums, err := checksumBuckets(tx)
+	if err != nil {
+		return err
+	}
+	return dbc.updateMetadata(tx, func(metadata *Metadata) {
+		if len(metadata.Checksums) > 0 {
+			metadata.Checksums = checksums
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Split(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Split_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	dbc := Config{}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		advisories := []struct {
+			source  string
+			pkgName string
+			cveID   string
+		}{
+			{source: "alpine 3.10", pkgName: "openssl", cveID: "CVE-2019-0001"},
+			{source: "debian 9", pkgName: "openssl", cveID: "CVE-2019-0001"},
+			{source: "debian 9", pkgName: "curl", cveID: "CVE-2019-0002"},
+			{source: "nodejs-security-wg", pkgName: "lodash", cveID: "CVE-2019-0003"},
+		}
+		for _, a := range advisories {
+			if err := dbc.PutAdvisory(tx, a.source, a.pkgName, a.cveID, types.Advisory{FixedVersion: "1.0.0"}); err != nil {
+				return err
+			}
+		}
+		for _, cveID := range []string{"CVE-2019-0001", "CVE-2019-0002", "CVE-2019-0003"} {
+			if err := dbc.PutVulnerability(tx, cveID, types.Vulnerability{Title: cveID}); err != nil {
+				return err
+			}
+		}
+		return dbc.PutCPEMatches(tx, "CVE-2019-0001", []types.CPEMatch{{CPE: "cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*"}})
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull}))
+	assert.NoError(t, dbc.SetChecksums())
+
+	tests := []struct {
+		name  string
+		mode  string
+		files map[string]splitContent
+	}{
+		{
+			name: "ecosystem",
+			mode: SplitEcosystem,
+			files: map[string]splitContent{
+				"lang.db": {
+					sources:         []string{"nodejs-security-wg::nodejs-security-wg"},
+					vulnerabilities: []string{"CVE-2019-0003"},
+				},
+				"os.db": {
+					sources:         []string{"alpine::alpine 3.10", "debian::debian 9"},
+					vulnerabilities: []string{"CVE-2019-0001", "CVE-2019-0002"},
+				},
+			},
+		},
+		{
+			name: "distro",
+			mode: SplitDistro,
+			files: map[string]splitContent{
+				"alpine.db": {
+					sources:         []string{"alpine::alpine 3.10"},
+					vulnerabilities: []string{"CVE-2019-0001"},
+				},
+				"debian.db": {
+					sources:         []string{"debian::debian 9"},
+					vulnerabilities: []string{"CVE-2019-0001", "CVE-2019-0002"},
+				},
+				"lang.db": {
+					sources:         []string{"nodejs-security-wg::nodejs-security-wg"},
+					vulnerabilities: []string{"CVE-2019-0003"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := dbc.Split(storage.DefaultDriver, tt.mode)
+			assert.NoError(t, err)
+			assert.Len(t, paths, len(tt.files))
+
+			for _, path := range paths {
+				want, ok := tt.files[filepath.Base(path)]
+				assert.True(t, ok, path)
+				assert.Equal(t, want, readSplit(t, path))
+			}
+		})
+	}
+
+	_, err = dbc.Split(storage.DefaultDriver, "unknown")
+	assert.Error(t, err)
+	assert.NoError(t, Close())
+
+	// a split DB is a DB of its own
+	dbDir := filepath.Join(d, "db")
+	assert.NoError(t, os.Rename(filepath.Join(dbDir, "lang.db"), filepath.Join(dbDir, "trivy.db")))
+	assert.NoError(t, OpenReadOnly(d))
+	defer Close()
+	assert.NoError(t, dbc.Verify())
+	vuln, err := dbc.GetVulnerability("CVE-2019-0003")
+	assert.NoError(t, err)
+	assert.Equal(t, "CVE-2019-0003", vuln.Title)
+	pkgs, err := dbc.GetAffectedPackages("CVE-2019-0003")
+	assert.NoError(t, err)
+	assert.Len(t, pkgs, 1)
+}
+
+type splitContent struct {
+	sources         []string
+	vulnerabilities []string
+}
+
+func readSplit(t *testing.T, path string) splitContent {
+	s, err := storage.Open(storage.DefaultDriver, path, storage.Options{ReadOnly: true})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	var content splitContent
+	err = s.View(func(tx Tx) error {
+		err := tx.ForEach(func(name []byte, _ storage.Bucket) error {
+			switch string(name) {
+			case metadataBucket, vulnerabilityBucket, affectedPackageBucket, dataSourceBucket:
+			case cpeBucket:
+				t.Error("the CPE index is only in the full DB")
+			default:
+				content.sources = append(content.sources, string(name))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(vulnerabilityBucket)).ForEach(func(k, _ []byte) error {
+			content.vulnerabilities = append(content.vulnerabilities, string(k))
+			return nil
+		})
+	})
+	assert.NoError(t, err)
+	return content
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"github.com/aquasecurity/trivy-db/pkg/types"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	ssvcBucket = "ssvc"
+
+	// UserSSVCSource is the source of user-supplied decision points, which the other sources don't replace
+	UserSSVCSource = "user"
+)
+
+// PutSSVC stores the decision points of a CVE, unless they are of another source than the user-supplied
+// ones stored already, whichever source is updated first
+func (dbc Config) PutSSVC(tx Tx, cveID string, ssvc types.SSVC) error {
+	bucket, err := tx.CreateBucketIfNotExists([]byte(ssvcBucket))
+	if err != nil {
+		return xerrors.Errorf("failed to create a bucket: %w", err)
+	}
+	if ssvc.Source != UserSSVCSource {
+		value, err := decode(bucket.Get([]byte(cveID)))
+		if err != nil {
+			return err
+		}
+		var stored types.SSVC
+		if value != nil {
+			if err = Unmarshal(value, &stored); err != nil {
+				return xerrors.Errorf("failed to unmarshal SSVC: %w", err)
+			}
+		}
+		if stored.Source == UserSSVCSource {
+			return nil
+		}
+	}
+	v, err := Marshal(ssvc)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(cveID), v)
+}
+
+func (dbc Config) GetSSVC(cveID string) (ssvc types.SSVC, err error) {
+	err = db.View(func(tx Tx) error {
+		bucket := tx.Bucket([]byte(ssvcBucket))
+		if bucket == nil {
+			return nil
+		}
+		value, err := decode(bucket.Get([]byte(cveID)))
+		if err != nil {
+			return err
+		} else if value == nil {
+			return nil
+		}
+		if err = Unmarshal(value, &ssvc); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return types.SSVC{}, xerrors.Errorf("failed to get SSVC: %w", err)
+	}
+	return ssvc, nil
+}
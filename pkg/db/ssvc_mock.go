@@ -0,0 +1,23 @@
+package db
+
+import (
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func (_m *MockVulnerabilityStore) PutSSVC(a Tx, b string, c types.SSVC) error {
+	ret := _m.Called(a, b, c)
+	return ret.Error(0)
+}
+
+func (_m *MockVulnerabilityStore) GetSSVC(a string) (types.SSVC, error) {
+	ret := _m.Called(a)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return types.SSVC{}, ret.Error(1)
+	}
+	s, ok := ret0.(types.SSVC)
+	if !ok {
+		return types.SSVC{}, ret.Error(1)
+	}
+	return s, ret.Error(1)
+}
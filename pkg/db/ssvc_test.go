@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_PutSSVC(t *testing.T) {
+	user := types.SSVC{Exploitation: "active", Source: UserSSVCSource}
+	vulnrichment := types.SSVC{Exploitation: "none", Source: "cisa-vulnrichment"}
+	tests := []struct {
+		name string
+		puts []types.SSVC
+		want types.SSVC
+	}{
+		{
+			name: "user after vulnrichment",
+			puts: []types.SSVC{vulnrichment, user},
+			want: user,
+		},
+		{
+			name: "vulnrichment after user",
+			puts: []types.SSVC{user, vulnrichment},
+			want: user,
+		},
+		{
+			name: "vulnrichment updated",
+			puts: []types.SSVC{vulnrichment, {Exploitation: "poc", Source: "cisa-vulnrichment"}},
+			want: types.SSVC{Exploitation: "poc", Source: "cisa-vulnrichment"},
+		},
+		{
+			name: "user updated",
+			puts: []types.SSVC{user, {Automatable: "yes", Source: UserSSVCSource}},
+			want: types.SSVC{Automatable: "yes", Source: UserSSVCSource},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestConfig_PutSSVC_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+			assert.NoError(t, Init(d))
+			defer Close()
+
+			dbc := Config{}
+			for _, ssvc := range tt.puts {
+				err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+					return dbc.PutSSVC(tx, "CVE-2021-44228", ssvc)
+				})
+				assert.NoError(t, err)
+			}
+			got, err := dbc.GetSSVC("CVE-2021-44228")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+// ErrStale is returned by RefuseStale
+var ErrStale = xerrors.New("the DB is stale")
+
+// StalenessPolicy is what OpenReadOnly does with a stale DB
+type StalenessPolicy struct {
+	// MaxAge makes a DB built longer ago stale before it expires, 0 for no limit
+	MaxAge time.Duration
+	// OnStale is called with the metadata of a stale DB, OpenReadOnly fails with the error it returns.
+	// Config.WarnStale and RefuseStale are the usual ones.
+	OnStale func(Metadata) error
+}
+
+// stalenessPolicy is set by SetStalenessPolicy, the DB isn't checked without OnStale
+var stalenessPolicy StalenessPolicy
+
+// SetStalenessPolicy makes OpenReadOnly check the age of the DB, e.g. for a scanner to refuse a DB
+// that stopped being updated
+func SetStalenessPolicy(policy StalenessPolicy) {
+	stalenessPolicy = policy
+}
+
+// WarnStale logs that the DB is stale to the logger of dbc and uses it anyway
+func (dbc Config) WarnStale(metadata Metadata) error {
+	log.OrDefault(dbc.Logger).Warn("The DB expired, it may miss recent vulnerabilities",
+		"updated_at", metadata.UpdatedAt.Format(time.RFC3339), "expires_at", metadata.ExpiresAt().Format(time.RFC3339))
+	return nil
+}
+
+// RefuseStale fails with ErrStale
+func RefuseStale(metadata Metadata) error {
+	return xerrors.Errorf("built at %s: %w", metadata.UpdatedAt.Format(time.RFC3339), ErrStale)
+}
+
+// ExpiresAt returns when the DB becomes stale: UpdatedAt plus ValidFor, or NextUpdate for the DBs built without ValidFor
+func (m Metadata) ExpiresAt() time.Time {
+	if m.ValidFor > 0 {
+		return m.UpdatedAt.Add(m.ValidFor)
+	}
+	return m.NextUpdate
+}
+
+// IsStale reports whether the DB is past ExpiresAt at now, or was built more than maxAge before if maxAge isn't 0
+func (m Metadata) IsStale(now time.Time, maxAge time.Duration) bool {
+	if expiresAt := m.ExpiresAt(); !expiresAt.IsZero() && now.After(expiresAt) {
+		return true
+	}
+	return maxAge > 0 && now.Sub(m.UpdatedAt) > maxAge
+}
+
+// checkStaleness applies the staleness policy to the opened DB
+func checkStaleness(now time.Time) error {
+	if stalenessPolicy.OnStale == nil {
+		return nil
+	}
+	metadata, err := Config{}.GetMetadata()
+	if err != nil {
+		return xerrors.Errorf("failed to get the metadata: %w", err)
+	}
+	if !metadata.IsStale(now, stalenessPolicy.MaxAge) {
+		return nil
+	}
+	return stalenessPolicy.OnStale(metadata)
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+func TestMetadata_IsStale(t *testing.T) {
+	builtAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		metadata      Metadata
+		now           time.Time
+		maxAge        time.Duration
+		wantExpiresAt time.Time
+		want          bool
+	}{
+		{
+			name:          "valid",
+			metadata:      Metadata{UpdatedAt: builtAt, ValidFor: 72 * time.Hour},
+			now:           builtAt.Add(48 * time.Hour),
+			wantExpiresAt: builtAt.Add(72 * time.Hour),
+		},
+		{
+			name:          "expired",
+			metadata:      Metadata{UpdatedAt: builtAt, ValidFor: 72 * time.Hour},
+			now:           builtAt.Add(73 * time.Hour),
+			wantExpiresAt: builtAt.Add(72 * time.Hour),
+			want:          true,
+		},
+		{
+			name:          "past the next update",
+			metadata:      Metadata{UpdatedAt: builtAt, NextUpdate: builtAt.Add(24 * time.Hour)},
+			now:           builtAt.Add(25 * time.Hour),
+			wantExpiresAt: builtAt.Add(24 * time.Hour),
+			want:          true,
+		},
+		{
+			name:          "older than the max age",
+			metadata:      Metadata{UpdatedAt: builtAt, ValidFor: 72 * time.Hour},
+			now:           builtAt.Add(48 * time.Hour),
+			maxAge:        24 * time.Hour,
+			wantExpiresAt: builtAt.Add(72 * time.Hour),
+			want:          true,
+		},
+		{
+			name:     "no expiry",
+			metadata: Metadata{UpdatedAt: builtAt},
+			now:      builtAt.Add(365 * 24 * time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantExpiresAt, tt.metadata.ExpiresAt())
+			assert.Equal(t, tt.want, tt.metadata.IsStale(tt.now, tt.maxAge))
+		})
+	}
+}
+
+func TestSetStalenessPolicy(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestSetStalenessPolicy_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	builtAt := time.Now().Add(-48 * time.Hour)
+	assert.NoError(t, Config{}.SetMetadata(Metadata{Version: SchemaVersion, Type: TypeFull, UpdatedAt: builtAt,
+		ValidFor: 24 * time.Hour}))
+	assert.NoError(t, Close())
+	defer SetStalenessPolicy(StalenessPolicy{})
+
+	tests := []struct {
+		name    string
+		policy  StalenessPolicy
+		wantErr error
+	}{
+		{name: "no policy"},
+		{name: "warn", policy: StalenessPolicy{OnStale: Config{Logger: log.Discard()}.WarnStale}},
+		{name: "refuse", policy: StalenessPolicy{OnStale: RefuseStale}, wantErr: ErrStale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetStalenessPolicy(tt.policy)
+			err := OpenReadOnly(d)
+			defer Close()
+			if tt.wantErr != nil {
+				assert.True(t, xerrors.Is(err, tt.wantErr), err)
+				assert.Nil(t, db, "the refused DB is closed")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+// BucketStats describes a root bucket, nested buckets included
+type BucketStats struct {
+	Name       string
+	Keys       int // values only
+	Buckets    int // nested buckets
+	ValueBytes int
+	Depth      int // levels of nested buckets
+	Size       int // bytes in use in the file, 0 if the storage doesn't know
+}
+
+// Stats returns the root buckets, largest values first
+func (dbc Config) Stats() ([]BucketStats, error) {
+	var stats []BucketStats
+	err := db.View(func(tx Tx) error {
+		return tx.ForEach(func(name []byte, b storage.Bucket) error {
+			s := BucketStats{Name: string(name)}
+			if err := collectStats(b, 1, &s); err != nil {
+				return xerrors.Errorf("failed to walk %s: %w", name, err)
+			}
+			if sizer, ok := b.(storage.Sizer); ok {
+				s.Size = sizer.Size()
+			}
+			stats = append(stats, s)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get stats: %w", err)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].ValueBytes > stats[j].ValueBytes
+	})
+	return stats, nil
+}
+
+func collectStats(b storage.Bucket, depth int, s *BucketStats) error {
+	if depth > s.Depth {
+		s.Depth = depth
+	}
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			s.Buckets++
+			return collectStats(b.Bucket(k), depth+1, s)
+		}
+		s.Keys++
+		s.ValueBytes += len(v)
+		return nil
+	})
+}
+
+// AdvisoryStats counts the advisories, to track the growth of the data release over release
+type AdvisoryStats struct {
+	Advisories int
+	Sources    map[string]int // by advisory bucket, e.g. alpine::alpine 3.10
+	Ecosystems map[string]int // e.g. alpine or ruby-advisory-db
+	Severities map[string]int // by the severity of the vulnerability, UNKNOWN if it has none
+}
+
+// AdvisoryStats counts the advisories per source, ecosystem and severity
+func (dbc Config) AdvisoryStats() (AdvisoryStats, error) {
+	stats := AdvisoryStats{Sources: map[string]int{}, Ecosystems: map[string]int{}, Severities: map[string]int{}}
+	err := db.View(func(tx Tx) error {
+		s := severities{vulns: tx.Bucket([]byte(vulnerabilityBucket)), severities: tx.Bucket([]byte(severityBucket)),
+			cache: map[string]string{}}
+		return tx.ForEach(func(name []byte, root storage.Bucket) error {
+			source := string(name)
+			if utils.StringInSlice(source, nonAdvisoryBuckets) {
+				return nil
+			}
+			_, platform := SplitAdvisoryBucket(source)
+			ecosystem, _ := splitSource(platform)
+			return root.ForEach(func(pkgName, v []byte) error {
+				if v != nil {
+					return nil
+				}
+				return root.Bucket(pkgName).ForEach(func(cveID, _ []byte) error {
+					severity, err := s.get(string(cveID))
+					if err != nil {
+						return xerrors.Errorf("%s: %w", cveID, err)
+					}
+					stats.Advisories++
+					stats.Sources[source]++
+					stats.Ecosystems[ecosystem]++
+					stats.Severities[severity]++
+					return nil
+				})
+			})
+		})
+	})
+	if err != nil {
+		return AdvisoryStats{}, xerrors.Errorf("failed to count the advisories: %w", err)
+	}
+	return stats, nil
+}
+
+// severities looks up the severity of vulnerabilities in the vulnerability bucket, then in the severity bucket,
+// which light DBs keep
+type severities struct {
+	vulns      storage.Bucket
+	severities storage.Bucket
+	cache      map[string]string
+}
+
+func (s severities) get(cveID string) (string, error) {
+	if severity, ok := s.cache[cveID]; ok {
+		return severity, nil
+	}
+	severity := types.SeverityUnknown.String()
+	if s.vulns != nil {
+		if v := s.vulns.Get([]byte(cveID)); v != nil {
+			v, err := decode(v)
+			if err != nil {
+				return "", err
+			}
+			var vuln types.Vulnerability
+			if err = Unmarshal(v, &vuln); err != nil {
+				return "", err
+			}
+			if vuln.Severity != "" {
+				s.cache[cveID] = vuln.Severity
+				return vuln.Severity, nil
+			}
+		}
+	}
+	if s.severities != nil {
+		if v := s.severities.Get([]byte(cveID)); v != nil {
+			severity = string(v)
+		}
+	}
+	s.cache[cveID] = severity
+	return severity, nil
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_Stats(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_Stats_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	detail := types.VulnerabilityDetail{Title: "title"}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		for _, source := range []string{"nvd", "redhat"} {
+			if err := dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", source, detail); err != nil {
+				return err
+			}
+		}
+		return dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityHigh)
+	})
+	assert.NoError(t, err)
+
+	stats, err := dbc.Stats()
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+
+	// the bolt driver knows the size
+	assert.NotZero(t, stats[0].Size)
+	stats[0].Size, stats[1].Size = 0, 0
+	assert.Equal(t, []BucketStats{
+		{Name: "vulnerability-detail", Keys: 2, Buckets: 1, ValueBytes: 2 * len(`{"Title":"title"}`), Depth: 2},
+		{Name: "severity", Keys: 1, ValueBytes: len("HIGH"), Depth: 1},
+	}, stats)
+}
+
+func TestConfig_AdvisoryStats(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_AdvisoryStats_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		for _, a := range []struct{ source, pkgName, cveID string }{
+			{"alpine 3.10", "openssl", "CVE-2019-0001"},
+			{"alpine 3.10", "curl", "CVE-2019-0002"},
+			{"alpine 3.11", "openssl", "CVE-2019-0001"},
+			{"ruby-advisory-db", "rails", "CVE-2019-0003"},
+		} {
+			if err := dbc.PutAdvisory(tx, a.source, a.pkgName, a.cveID, types.Advisory{}); err != nil {
+				return err
+			}
+		}
+		if err := dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{Severity: "HIGH"}); err != nil {
+			return err
+		}
+		// the severity bucket is used for a vulnerability without one
+		if err := dbc.PutVulnerability(tx, "CVE-2019-0002", types.Vulnerability{}); err != nil {
+			return err
+		}
+		return dbc.PutSeverity(tx, "CVE-2019-0002", types.SeverityLow)
+	})
+	assert.NoError(t, err)
+
+	got, err := dbc.AdvisoryStats()
+	assert.NoError(t, err)
+	assert.Equal(t, AdvisoryStats{
+		Advisories: 4,
+		Sources: map[string]int{
+			"alpine::alpine 3.10":                2,
+			"alpine::alpine 3.11":                1,
+			"ruby-advisory-db::ruby-advisory-db": 1,
+		},
+		Ecosystems: map[string]int{"alpine": 3, "ruby-advisory-db": 1},
+		Severities: map[string]int{"HIGH": 2, "LOW": 1, "UNKNOWN": 1},
+	}, got)
+}
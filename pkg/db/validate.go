@@ -0,0 +1,70 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+// validation is enabled by SetValidation
+var validation bool
+
+// SetValidation makes the Put functions reject malformed values with a ValidationError instead of storing them
+func SetValidation(enabled bool) {
+	validation = enabled
+}
+
+// Validator is implemented by values with constraints beyond their Go type, e.g. types.Vulnerability
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError is a value rejected by a Put function
+type ValidationError struct {
+	Source string // the advisory or vulnerability detail source, vulnerability for PutVulnerability
+	Key    string // e.g. openssl/CVE-2019-0001
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid record %s of %s: %s", e.Key, e.Source, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// validate checks the keys and the value of a record if validation is enabled.
+// Values must be JSON objects, so that the consumers can add fields to them.
+func validate(source string, v interface{}, keys ...string) error {
+	if !validation {
+		return nil
+	}
+	err := validateValue(v)
+	if source == "" || utils.StringInSlice("", keys) {
+		err = xerrors.New("empty source or key")
+	}
+	if err != nil {
+		return &ValidationError{Source: source, Key: strings.Join(keys, "/"), Err: err}
+	}
+	return nil
+}
+
+func validateValue(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal JSON: %w", err)
+	}
+	if !bytes.HasPrefix(b, []byte("{")) {
+		return xerrors.Errorf("not a JSON object: %s", b)
+	}
+	if validator, ok := v.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
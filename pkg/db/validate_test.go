@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestSetValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		validation bool
+		put        func(dbc Config, tx Tx) error
+		wantSource string
+		wantKey    string
+	}{
+		{
+			name:       "valid advisory",
+			validation: true,
+			put: func(dbc Config, tx Tx) error {
+				return dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001", types.Advisory{FixedVersion: "1.1.1d-r0"})
+			},
+		},
+		{
+			name:       "advisory without package",
+			validation: true,
+			put: func(dbc Config, tx Tx) error {
+				return dbc.PutAdvisory(tx, "alpine 3.10", "", "", types.Advisory{})
+			},
+			wantSource: "alpine 3.10",
+			wantKey:    "/",
+		},
+		{
+			name:       "advisory not an object",
+			validation: true,
+			put: func(dbc Config, tx Tx) error {
+				return dbc.PutAdvisory(tx, "alpine 3.10", "openssl", "CVE-2019-0001", "1.1.1d-r0")
+			},
+			wantSource: "alpine 3.10",
+			wantKey:    "openssl/CVE-2019-0001",
+		},
+		{
+			name:       "invalid CVSS score",
+			validation: true,
+			put: func(dbc Config, tx Tx) error {
+				return dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", "nvd", types.VulnerabilityDetail{CvssScoreV3: 98})
+			},
+			wantSource: "nvd",
+			wantKey:    "CVE-2019-0001",
+		},
+		{
+			name:       "negligible severity",
+			validation: true,
+			put: func(dbc Config, tx Tx) error {
+				return dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", "ubuntu", types.VulnerabilityDetail{Severity: types.SeverityNegligible})
+			},
+		},
+		{
+			name:       "invalid CVSS v4 vector of a source",
+			validation: true,
+			put: func(dbc Config, tx Tx) error {
+				return dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{
+					CVSS: map[string]types.CVSS{"nvd": {V4Score: 9.3, V4Vector: "AV:N/AC:L"}},
+				})
+			},
+			wantSource: "vulnerability",
+			wantKey:    "CVE-2019-0001",
+		},
+		{
+			name:       "invalid severity",
+			validation: true,
+			put: func(dbc Config, tx Tx) error {
+				return dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{Severity: "SEVERE"})
+			},
+			wantSource: "vulnerability",
+			wantKey:    "CVE-2019-0001",
+		},
+		{
+			name: "disabled",
+			put: func(dbc Config, tx Tx) error {
+				return dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{Severity: "SEVERE"})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestSetValidation_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+
+			assert.NoError(t, Init(d))
+			defer Close()
+			SetValidation(tt.validation)
+			defer SetValidation(false)
+
+			dbc := Config{}
+			err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+				return tt.put(dbc, tx)
+			})
+			if tt.wantSource == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			if assert.True(t, xerrors.As(err, &verr), err) {
+				assert.Equal(t, tt.wantSource, verr.Source)
+				assert.Equal(t, tt.wantKey, verr.Key)
+			}
+		})
+	}
+}
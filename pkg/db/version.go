@@ -0,0 +1,195 @@
+package db
+
+import (
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/version"
+)
+
+// Comparer compares two versions of the packages of an ecosystem, see version.Comparer
+type Comparer = version.Comparer
+
+// SourceComparer returns the comparer of the fixed versions of the advisories of an OS, e.g. debian 9,
+// and false for the sources with ranges of their own
+func SourceComparer(source string) (Comparer, bool) {
+	ecosystem, _ := splitSource(source)
+	comparer, ok := version.Comparers[versionSchemes[strings.ToLower(ecosystem)]]
+	return comparer, ok
+}
+
+// GetAdvisoriesForVersion returns the advisories of a package which affect the installed version, see Affects
+func (dbc Config) GetAdvisoriesForVersion(source, pkgName, installed string, comparer Comparer) ([]types.Advisory, error) {
+	advisories, err := dbc.GetAdvisories(source, pkgName)
+	if err != nil {
+		return nil, err
+	}
+	var results []types.Advisory
+	for _, advisory := range advisories {
+		affected, err := Affects(advisory, installed, comparer)
+		if err != nil {
+			return nil, err
+		}
+		if affected {
+			results = append(results, advisory)
+		}
+	}
+	return results, nil
+}
+
+// Affects tells whether an advisory affects the installed version. An advisory with affected versions or
+// vulnerable versions affects the versions in any of them. Otherwise it affects the versions before its fixed
+// version, and all of them without a fixed version, as they can't be ruled out.
+func Affects(advisory types.Advisory, installed string, comparer Comparer) (bool, error) {
+	if len(advisory.AffectedVersions) > 0 || len(advisory.VulnerableVersions) > 0 {
+		for _, r := range advisory.AffectedVersions {
+			in, err := inRange(installed, r, comparer)
+			if err != nil {
+				return false, xerrors.Errorf("failed to compare %s with the affected versions of %s: %w",
+					installed, advisory.VulnerabilityID, err)
+			} else if in {
+				return true, nil
+			}
+		}
+		for _, constraints := range advisory.VulnerableVersions {
+			match, err := matchConstraints(installed, constraints, comparer)
+			if err != nil {
+				return false, xerrors.Errorf("failed to compare %s with the vulnerable versions of %s: %w",
+					installed, advisory.VulnerabilityID, err)
+			} else if match {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if advisory.FixedVersion == "" {
+		return true, nil
+	}
+	c, err := comparer.Compare(installed, advisory.FixedVersion)
+	if err != nil {
+		return false, xerrors.Errorf("failed to compare %s with the fixed version of %s: %w",
+			installed, advisory.VulnerabilityID, err)
+	}
+	return c < 0, nil
+}
+
+func inRange(installed string, r types.VersionRange, comparer Comparer) (bool, error) {
+	// OSV introduces the ranges from the first version with 0
+	if r.Introduced != "" && r.Introduced != "0" {
+		c, err := comparer.Compare(installed, r.Introduced)
+		if err != nil {
+			return false, err
+		} else if c < 0 {
+			return false, nil
+		}
+	}
+	switch {
+	case r.Fixed != "":
+		c, err := comparer.Compare(installed, r.Fixed)
+		return c < 0, err
+	case r.LastAffected != "":
+		c, err := comparer.Compare(installed, r.LastAffected)
+		return c <= 0, err
+	}
+	return true, nil
+}
+
+// the operators of constraints, the ones prefixing others first
+var constraintOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// constraint is a comparison of a version constraint, e.g. >= 2.0.0
+type constraint struct {
+	operator string
+	version  string
+}
+
+// parseConstraints parses constraints, e.g. ">= 2.0.0, < 2.3.1", a version without operator being an equality
+func parseConstraints(constraints string) ([]constraint, error) {
+	var parsed []constraint
+	for _, s := range strings.Split(constraints, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		c := constraint{operator: "="}
+		for _, op := range constraintOperators {
+			if strings.HasPrefix(s, op) {
+				c.operator = op
+				break
+			}
+		}
+		if c.version = strings.TrimSpace(strings.TrimPrefix(s, c.operator)); c.version == "" {
+			return nil, xerrors.Errorf("no version in constraint %q", s)
+		}
+		parsed = append(parsed, c)
+	}
+	return parsed, nil
+}
+
+// matchConstraints tells whether the installed version matches all the comparisons of constraints
+func matchConstraints(installed, constraints string, comparer Comparer) (bool, error) {
+	parsed, err := parseConstraints(constraints)
+	if err != nil {
+		return false, err
+	}
+	for _, constraint := range parsed {
+		c, err := comparer.Compare(installed, constraint.version)
+		if err != nil {
+			return false, err
+		}
+		var match bool
+		switch constraint.operator {
+		case ">=":
+			match = c >= 0
+		case "<=":
+			match = c <= 0
+		case "!=":
+			match = c != 0
+		case ">":
+			match = c > 0
+		case "<":
+			match = c < 0
+		default:
+			match = c == 0
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// checkVersions parses the versions of an advisory with comparer
+func checkVersions(advisory types.Advisory, comparer Comparer) error {
+	versions := []string{advisory.FixedVersion}
+	for _, v := range advisory.ArchFixedVersions {
+		versions = append(versions, v)
+	}
+	for _, r := range advisory.AffectedVersions {
+		if r.Introduced != "0" {
+			versions = append(versions, r.Introduced)
+		}
+		versions = append(versions, r.Fixed, r.LastAffected)
+	}
+	for _, constraints := range advisory.VulnerableVersions {
+		parsed, err := parseConstraints(constraints)
+		if err != nil {
+			return err
+		}
+		for _, c := range parsed {
+			versions = append(versions, c.version)
+		}
+	}
+	for _, v := range versions {
+		if v == "" {
+			continue
+		}
+		if err := version.Validate(comparer, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/version"
+)
+
+func TestConfig_GetAdvisoriesForVersion(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_GetAdvisoriesForVersion_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	assert.NoError(t, db.Update(func(tx Tx) error {
+		for cveID, fixedVersion := range map[string]string{
+			"CVE-2019-0001": "1.1.1d-r2",
+			"CVE-2019-0002": "1.1.1a-r0",
+			"CVE-2019-0003": "",
+		} {
+			if err := dbc.PutAdvisory(tx, "alpine 3.10", "openssl", cveID, types.Advisory{FixedVersion: fixedVersion}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	tests := []struct {
+		name      string
+		installed string
+		want      []types.Advisory
+		wantErr   string
+	}{
+		{
+			name:      "fixed later and unfixed",
+			installed: "1.1.1c-r0",
+			want: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.1d-r2"},
+				{VulnerabilityID: "CVE-2019-0003"},
+			},
+		},
+		{
+			name:      "all fixed",
+			installed: "1.1.1d-r2",
+			want: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0003"},
+			},
+		},
+		{
+			name:      "invalid installed version",
+			installed: "1.1.1_foo",
+			wantErr:   "failed to compare 1.1.1_foo with the fixed version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dbc.GetAdvisoriesForVersion("alpine 3.10", "openssl", tt.installed, version.APK)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAffects(t *testing.T) {
+	tests := []struct {
+		name      string
+		advisory  types.Advisory
+		installed string
+		want      bool
+		wantErr   string
+	}{
+		{
+			name:      "before the fixed version",
+			advisory:  types.Advisory{FixedVersion: "1.2.3"},
+			installed: "1.2.2",
+			want:      true,
+		},
+		{
+			name:      "fixed",
+			advisory:  types.Advisory{FixedVersion: "1.2.3"},
+			installed: "1.2.3",
+		},
+		{
+			name:      "in the second range",
+			advisory:  types.Advisory{AffectedVersions: []types.VersionRange{{Introduced: "0", Fixed: "1.2.3"}, {Introduced: "2.0.0", Fixed: "2.0.5"}}},
+			installed: "2.0.1",
+			want:      true,
+		},
+		{
+			name:      "between the ranges",
+			advisory:  types.Advisory{AffectedVersions: []types.VersionRange{{Introduced: "0", Fixed: "1.2.3"}, {Introduced: "2.0.0", Fixed: "2.0.5"}}},
+			installed: "1.5.0",
+		},
+		{
+			name:      "last affected",
+			advisory:  types.Advisory{AffectedVersions: []types.VersionRange{{Introduced: "1.0.0", LastAffected: "1.4.0"}}},
+			installed: "1.4.0",
+			want:      true,
+		},
+		{
+			name:      "without end",
+			advisory:  types.Advisory{AffectedVersions: []types.VersionRange{{Introduced: "1.0.0"}}},
+			installed: "9.0.0",
+			want:      true,
+		},
+		{
+			name: "ranges take precedence over the fixed version",
+			advisory: types.Advisory{FixedVersion: "2.0.5",
+				AffectedVersions: []types.VersionRange{{Introduced: "2.0.0", Fixed: "2.0.5"}}},
+			installed: "1.0.0",
+		},
+		{
+			name:      "vulnerable versions",
+			advisory:  types.Advisory{VulnerableVersions: []string{"< 1.2.3", ">= 2.0.0, < 2.0.5"}},
+			installed: "2.0.4",
+			want:      true,
+		},
+		{
+			name:      "no vulnerable version",
+			advisory:  types.Advisory{VulnerableVersions: []string{"< 1.2.3", ">= 2.0.0, < 2.0.5"}},
+			installed: "2.0.5",
+		},
+		{
+			name:      "exact vulnerable version",
+			advisory:  types.Advisory{VulnerableVersions: []string{"1.2.3"}},
+			installed: "1.2.3",
+			want:      true,
+		},
+		{
+			name:      "constraint without version",
+			advisory:  types.Advisory{VulnerabilityID: "CVE-2019-0001", VulnerableVersions: []string{">= 1.0.0, <"}},
+			installed: "1.2.3",
+			wantErr:   "failed to compare 1.2.3 with the vulnerable versions of CVE-2019-0001: no version in constraint \"<\"",
+		},
+		{
+			name:      "invalid range",
+			advisory:  types.Advisory{VulnerabilityID: "CVE-2019-0001", AffectedVersions: []types.VersionRange{{Introduced: "foo"}}},
+			installed: "1.2.3",
+			wantErr:   "failed to compare 1.2.3 with the affected versions of CVE-2019-0001",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Affects(tt.advisory, tt.installed, version.Semver)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
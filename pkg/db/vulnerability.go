@@ -1,11 +1,8 @@
 package db
 
 import (
-	"encoding/json"
-
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 )
 
@@ -13,29 +10,41 @@ const (
 	vulnerabilityBucket = "vulnerability"
 )
 
-func (dbc Config) PutVulnerability(tx *bolt.Tx, cveID string, vuln types.Vulnerability) error {
+// ErrVulnerabilityNotFound is returned by GetVulnerability for the IDs without a vulnerability
+var ErrVulnerabilityNotFound = xerrors.New("vulnerability not found")
+
+func (dbc Config) PutVulnerability(tx Tx, cveID string, vuln types.Vulnerability) error {
+	if err := validate(vulnerabilityBucket, vuln, cveID); err != nil {
+		return err
+	}
 	bucket, err := tx.CreateBucketIfNotExists([]byte(vulnerabilityBucket))
 	if err != nil {
 		return xerrors.Errorf("failed to create a bucket: %w", err)
 	}
-	v, err := json.Marshal(vuln)
+	v, err := Marshal(vuln)
 	if err != nil {
-		return xerrors.Errorf("failed to marshal JSON: %w", err)
+		return err
 	}
 	return bucket.Put([]byte(cveID), v)
 }
 
-func (dbc Config) GetVulnerability(cveID string) (vuln types.Vulnerability, err error) {
-	err = db.View(func(tx *bolt.Tx) error {
+// GetVulnerability returns the vulnerability, with the texts shared by Dedup restored
+func (dbc Config) GetVulnerability(cveID string) (types.Vulnerability, error) {
+	var vuln storedVulnerability
+	err := db.View(func(tx Tx) error {
 		bucket := tx.Bucket([]byte(vulnerabilityBucket))
+		if bucket == nil {
+			return xerrors.Errorf("%s: %w", vulnerabilityBucket, ErrNoBucket)
+		}
 		value := bucket.Get([]byte(cveID))
-		if err = json.Unmarshal(value, &vuln); err != nil {
-			return xerrors.Errorf("failed to marshal JSON: %w", err)
+		if value == nil {
+			return ErrVulnerabilityNotFound
 		}
-		return nil
+		r := resolver{shared: tx.Bucket([]byte(sharedDetailBucket))}
+		return r.resolve(value, &vuln)
 	})
 	if err != nil {
 		return types.Vulnerability{}, xerrors.Errorf("failed to get the vulnerability: %w", err)
 	}
-	return vuln, nil
+	return vuln.Vulnerability, nil
 }
@@ -1,11 +1,8 @@
 package db
 
 import (
-	"encoding/json"
-
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 )
 
@@ -13,30 +10,87 @@ const (
 	vulnerabilityDetailBucket = "vulnerability-detail"
 )
 
-func (dbc Config) PutVulnerabilityDetail(tx *bolt.Tx, cveID, source string, vuln types.VulnerabilityDetail) error {
+func (dbc Config) PutVulnerabilityDetail(tx Tx, cveID, source string, vuln types.VulnerabilityDetail) error {
+	vuln, err := beforePutVulnerabilityDetail(cveID, source, vuln)
+	if err == nil {
+		err = dbc.putVulnerabilityDetail(tx, cveID, source, vuln)
+	}
+	afterPutVulnerabilityDetail(cveID, source, err)
+	return err
+}
+
+func (dbc Config) putVulnerabilityDetail(tx Tx, cveID, source string, vuln types.VulnerabilityDetail) error {
+	if err := validate(source, vuln, cveID); err != nil {
+		return err
+	}
 	root, err := tx.CreateBucketIfNotExists([]byte(vulnerabilityDetailBucket))
 	if err != nil {
 		return err
 	}
-	return dbc.put(root, cveID, source, vuln)
+	v, err := Marshal(vuln)
+	if err != nil {
+		return err
+	}
+	return dbc.put(root, cveID, source, v)
 }
 
-func (dbc Config) GetVulnerabilityDetail(cveID string) (map[string]types.VulnerabilityDetail, error) {
-	values, err := dbc.forEach(vulnerabilityDetailBucket, cveID)
+// GetVulnerabilityDetail returns the detail of a source as the source put it, e.g. of nvd, with the texts
+// shared by Dedup restored. It returns ErrVulnerabilityNotFound when the source has no detail of the ID.
+func (dbc Config) GetVulnerabilityDetail(cveID, source string) (types.VulnerabilityDetail, error) {
+	var detail storedDetail
+	found := false
+	err := db.View(func(tx Tx) error {
+		root := tx.Bucket([]byte(vulnerabilityDetailBucket))
+		if root == nil || root.Bucket([]byte(cveID)) == nil {
+			return nil
+		}
+		v := root.Bucket([]byte(cveID)).Get([]byte(source))
+		if v == nil {
+			return nil
+		}
+		found = true
+		r := resolver{shared: tx.Bucket([]byte(sharedDetailBucket))}
+		if err := r.resolve(v, &detail); err != nil {
+			return xerrors.Errorf("failed to unmarshal Vulnerability: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, xerrors.Errorf("error in NVD get: %w", err)
-	}
-	if len(values) == 0 {
-		return nil, nil
+		return types.VulnerabilityDetail{}, xerrors.Errorf("failed to get the detail of %s: %w", cveID, err)
+	} else if !found {
+		return types.VulnerabilityDetail{}, xerrors.Errorf("%s of %s: %w", cveID, source, ErrVulnerabilityNotFound)
 	}
+	return detail.VulnerabilityDetail, nil
+}
 
+// GetVulnerabilityDetails returns the details by source, before the full DB merges them into the
+// vulnerability and drops them, e.g. to show the ones of nvd and of the vendors side by side
+func (dbc Config) GetVulnerabilityDetails(cveID string) (map[string]types.VulnerabilityDetail, error) {
 	vulns := map[string]types.VulnerabilityDetail{}
-	for source, value := range values {
-		var vuln types.VulnerabilityDetail
-		if err = json.Unmarshal(value, &vuln); err != nil {
-			return nil, xerrors.Errorf("failed to unmarshal Vulnerability JSON: %w", err)
+	err := db.View(func(tx Tx) error {
+		root := tx.Bucket([]byte(vulnerabilityDetailBucket))
+		if root == nil {
+			return nil
 		}
-		vulns[source] = vuln
+		nested := root.Bucket([]byte(cveID))
+		if nested == nil {
+			return nil
+		}
+		r := resolver{shared: tx.Bucket([]byte(sharedDetailBucket))}
+		return nested.ForEach(func(source, value []byte) error {
+			var vuln storedDetail
+			if err := r.resolve(value, &vuln); err != nil {
+				return xerrors.Errorf("failed to unmarshal Vulnerability: %w", err)
+			}
+			vulns[string(source)] = vuln.VulnerabilityDetail
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("error in NVD get: %w", err)
+	}
+	if len(vulns) == 0 {
+		return nil, nil
 	}
 	return vulns, nil
 }
@@ -2,15 +2,27 @@ package db
 
 import (
 	"github.com/aquasecurity/trivy-db/pkg/types"
-	bolt "github.com/etcd-io/bbolt"
 )
 
-func (_m *MockDBConfig) PutVulnerabilityDetail(a *bolt.Tx, b, c string, d types.VulnerabilityDetail) error {
+func (_m *MockVulnerabilityStore) PutVulnerabilityDetail(a Tx, b, c string, d types.VulnerabilityDetail) error {
 	ret := _m.Called(a, b, c, d)
 	return ret.Error(0)
 }
 
-func (_m *MockDBConfig) GetVulnerabilityDetail(a string) (map[string]types.VulnerabilityDetail, error) {
+func (_m *MockVulnerabilityStore) GetVulnerabilityDetail(a, b string) (types.VulnerabilityDetail, error) {
+	ret := _m.Called(a, b)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return types.VulnerabilityDetail{}, ret.Error(1)
+	}
+	r, ok := ret0.(types.VulnerabilityDetail)
+	if !ok {
+		return types.VulnerabilityDetail{}, ret.Error(1)
+	}
+	return r, ret.Error(1)
+}
+
+func (_m *MockVulnerabilityStore) GetVulnerabilityDetails(a string) (map[string]types.VulnerabilityDetail, error) {
 	ret := _m.Called(a)
 	ret0 := ret.Get(0)
 	if ret0 == nil {
@@ -23,7 +35,7 @@ func (_m *MockDBConfig) GetVulnerabilityDetail(a string) (map[string]types.Vulne
 	return r, ret.Error(1)
 }
 
-func (_m *MockDBConfig) DeleteVulnerabilityDetailBucket() error {
+func (_m *MockVulnerabilityStore) DeleteVulnerabilityDetailBucket() error {
 	ret := _m.Called()
 	return ret.Error(0)
 }
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_GetVulnerabilityDetail(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_GetVulnerabilityDetail_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	nvd := types.VulnerabilityDetail{CvssScoreV3: 5.9, Title: "padding oracle"}
+	debian := types.VulnerabilityDetail{Severity: types.SeverityLow, Description: "a padding oracle in openssl"}
+	err = dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		if err := dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", "nvd", nvd); err != nil {
+			return err
+		}
+		return dbc.PutVulnerabilityDetail(tx, "CVE-2019-0001", "debian", debian)
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		cveID    string
+		source   string
+		want     types.VulnerabilityDetail
+		notFound bool
+	}{
+		{
+			name:   "detail of a source",
+			cveID:  "CVE-2019-0001",
+			source: "debian",
+			want:   debian,
+		},
+		{
+			name:     "source without the detail",
+			cveID:    "CVE-2019-0001",
+			source:   "alpine",
+			notFound: true,
+		},
+		{
+			name:     "unknown vulnerability",
+			cveID:    "CVE-2019-9999",
+			source:   "nvd",
+			notFound: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dbc.GetVulnerabilityDetail(tt.cveID, tt.source)
+			if tt.notFound {
+				assert.True(t, xerrors.Is(err, ErrVulnerabilityNotFound), err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	details, err := dbc.GetVulnerabilityDetails("CVE-2019-0001")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]types.VulnerabilityDetail{"nvd": nvd, "debian": debian}, details)
+
+	details, err = dbc.GetVulnerabilityDetails("CVE-2019-9999")
+	assert.NoError(t, err)
+	assert.Nil(t, details)
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+var _ VulnerabilityStore = &MockVulnerabilityStore{}
+
+type MockVulnerabilityStore struct {
+	mock.Mock
+}
+
+func (_m *MockVulnerabilityStore) PutVulnerability(a Tx, b string, c types.Vulnerability) error {
+	ret := _m.Called(a, b, c)
+	return ret.Error(0)
+}
+
+func (_m *MockVulnerabilityStore) GetVulnerability(a string) (types.Vulnerability, error) {
+	ret := _m.Called(a)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return types.Vulnerability{}, ret.Error(1)
+	}
+	v, ok := ret0.(types.Vulnerability)
+	if !ok {
+		return types.Vulnerability{}, ret.Error(1)
+	}
+	return v, ret.Error(1)
+}
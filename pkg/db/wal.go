@@ -0,0 +1,302 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+const (
+	walPut          = "put"
+	walDelete       = "delete"
+	walDeleteBucket = "delete-bucket"
+	walCommit       = "commit" // ends the records of a transaction
+)
+
+// WALRecord is a line of the write-ahead log, a write of a committed transaction. Keys and values are
+// stored as they are in the DB, whatever the encoding and the compression, so they are base64 in JSON.
+type WALRecord struct {
+	Op string `json:"op"`
+	// Bucket is the path of bucket names from the root, empty for the deletion of a root bucket
+	Bucket []string `json:"bucket,omitempty"`
+	Key    []byte   `json:"key,omitempty"` // the name of the bucket for delete-bucket
+	Value  []byte   `json:"value,omitempty"`
+}
+
+// wal, if not nil, is the write-ahead log every transaction of the package is appended to
+var wal *walFile
+
+type walFile struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenWAL logs the writes of the following transactions to path, after the records already there.
+// A build interrupted by a crash can be replayed into a new DB with ReplayWAL.
+func OpenWAL(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return xerrors.Errorf("failed to open the write-ahead log: %w", err)
+	}
+	wal = &walFile{f: f}
+	return nil
+}
+
+// CloseWAL stops logging the writes
+func CloseWAL() error {
+	if wal == nil {
+		return nil
+	}
+	f := wal.f
+	wal = nil
+	if err := f.Close(); err != nil {
+		return xerrors.Errorf("failed to close the write-ahead log: %w", err)
+	}
+	return nil
+}
+
+// append writes the records of a committed transaction and syncs them, so that they survive a crash
+func (w *walFile) append(records []WALRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	bw := bufio.NewWriter(w.f)
+	enc := json.NewEncoder(bw)
+	for _, rec := range append(records, WALRecord{Op: walCommit}) {
+		if err := enc.Encode(rec); err != nil {
+			return xerrors.Errorf("failed to encode a WAL record: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return xerrors.Errorf("failed to write the write-ahead log: %w", err)
+	}
+	return w.f.Sync()
+}
+
+// writeTx runs fn in a writable transaction, logging its writes once it is committed
+func writeTx(fn func(Tx) error) error {
+	return logWrites(db.Update, fn)
+}
+
+// batchTx is writeTx with a transaction the storage may share with concurrent calls
+func batchTx(fn func(Tx) error) error {
+	return logWrites(db.Batch, fn)
+}
+
+func logWrites(write func(func(Tx) error) error, fn func(Tx) error) error {
+	if wal == nil {
+		return write(fn)
+	}
+	w := wal
+	var logging *walTx
+	err := write(func(tx Tx) error {
+		// a batch may run fn again, only the last run is committed
+		logging = &walTx{tx: tx}
+		return fn(logging)
+	})
+	if err != nil {
+		return err
+	}
+	if len(logging.records) == 0 {
+		return nil
+	}
+	if err = w.append(logging.records); err != nil {
+		return xerrors.Errorf("failed to log a transaction: %w", err)
+	}
+	return nil
+}
+
+// walTx records the writes made through its buckets
+type walTx struct {
+	tx      Tx
+	records []WALRecord
+}
+
+func (t *walTx) Bucket(name []byte) storage.Bucket {
+	return t.wrap([]string{string(name)}, t.tx.Bucket(name))
+}
+
+func (t *walTx) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	b, err := t.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.wrap([]string{string(name)}, b), nil
+}
+
+func (t *walTx) DeleteBucket(name []byte) error {
+	if err := t.tx.DeleteBucket(name); err != nil {
+		return err
+	}
+	t.record(WALRecord{Op: walDeleteBucket, Key: name})
+	return nil
+}
+
+func (t *walTx) ForEach(fn func(name []byte, b storage.Bucket) error) error {
+	return t.tx.ForEach(func(name []byte, b storage.Bucket) error {
+		return fn(name, t.wrap([]string{string(name)}, b))
+	})
+}
+
+// record copies the key and the value, which are only valid during the transaction
+func (t *walTx) record(rec WALRecord) {
+	rec.Key = append([]byte{}, rec.Key...)
+	if rec.Value != nil {
+		rec.Value = append([]byte{}, rec.Value...)
+	}
+	t.records = append(t.records, rec)
+}
+
+func (t *walTx) wrap(path []string, b storage.Bucket) storage.Bucket {
+	if b == nil {
+		return nil
+	}
+	return walBucket{b: b, path: path, tx: t}
+}
+
+type walBucket struct {
+	b    storage.Bucket
+	path []string
+	tx   *walTx
+}
+
+func (b walBucket) Get(key []byte) []byte {
+	return b.b.Get(key)
+}
+
+func (b walBucket) Put(key, value []byte) error {
+	if err := b.b.Put(key, value); err != nil {
+		return err
+	}
+	b.tx.record(WALRecord{Op: walPut, Bucket: b.path, Key: key, Value: value})
+	return nil
+}
+
+func (b walBucket) Delete(key []byte) error {
+	if err := b.b.Delete(key); err != nil {
+		return err
+	}
+	b.tx.record(WALRecord{Op: walDelete, Bucket: b.path, Key: key})
+	return nil
+}
+
+func (b walBucket) ForEach(fn func(k, v []byte) error) error {
+	return b.b.ForEach(fn)
+}
+
+func (b walBucket) Bucket(name []byte) storage.Bucket {
+	return b.tx.wrap(b.nested(name), b.b.Bucket(name))
+}
+
+func (b walBucket) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	nested, err := b.b.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.tx.wrap(b.nested(name), nested), nil
+}
+
+func (b walBucket) DeleteBucket(name []byte) error {
+	if err := b.b.DeleteBucket(name); err != nil {
+		return err
+	}
+	b.tx.record(WALRecord{Op: walDeleteBucket, Bucket: b.path, Key: name})
+	return nil
+}
+
+func (b walBucket) nested(name []byte) []string {
+	return append(append([]string{}, b.path...), string(name))
+}
+
+// ReplayWAL writes the committed transactions of a write-ahead log into a new DB and returns their number.
+// The transaction a crash cut short is left out.
+func (dbc Config) ReplayWAL(r io.Reader) (int, error) {
+	empty := true
+	if err := db.View(func(tx Tx) error {
+		return tx.ForEach(func(name []byte, _ storage.Bucket) error {
+			// the migrations of a new DB only write its schema
+			if string(name) != metadataBucket {
+				empty = false
+			}
+			return nil
+		})
+	}); err != nil {
+		return 0, xerrors.Errorf("failed to read the DB: %w", err)
+	} else if !empty {
+		return 0, xerrors.New("the DB isn't empty, replay into a new DB")
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var records []WALRecord
+	n := 0
+	for {
+		var rec WALRecord
+		if err := dec.Decode(&rec); err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return n, xerrors.Errorf("invalid record after %d transactions: %w", n, err)
+		}
+		if rec.Op != walCommit {
+			records = append(records, rec)
+			continue
+		}
+		if err := db.Update(func(tx Tx) error {
+			for _, rec := range records {
+				if err := replayRecord(tx, rec); err != nil {
+					return xerrors.Errorf("%s %q %q: %w", rec.Op, rec.Bucket, rec.Key, err)
+				}
+			}
+			return nil
+		}); err != nil {
+			return n, xerrors.Errorf("failed to replay transaction %d: %w", n+1, err)
+		}
+		records, n = nil, n+1
+	}
+
+	if err := loadFormat(); err != nil {
+		return n, xerrors.Errorf("failed to load the DB format: %w", err)
+	}
+	return n, nil
+}
+
+func replayRecord(tx Tx, rec WALRecord) error {
+	if rec.Op == walDeleteBucket && len(rec.Bucket) == 0 {
+		if err := tx.DeleteBucket(rec.Key); err != nil && err != storage.ErrBucketNotFound {
+			return err
+		}
+		return nil
+	}
+	if len(rec.Bucket) == 0 {
+		return xerrors.New("no bucket")
+	}
+
+	b, err := tx.CreateBucketIfNotExists([]byte(rec.Bucket[0]))
+	for _, name := range rec.Bucket[1:] {
+		if err != nil {
+			break
+		}
+		b, err = b.CreateBucketIfNotExists([]byte(name))
+	}
+	if err != nil {
+		return xerrors.Errorf("failed to create a bucket: %w", err)
+	}
+
+	switch rec.Op {
+	case walPut:
+		return b.Put(rec.Key, rec.Value)
+	case walDelete:
+		return b.Delete(rec.Key)
+	case walDeleteBucket:
+		if err = b.DeleteBucket(rec.Key); err != nil && err != storage.ErrBucketNotFound {
+			return err
+		}
+		return nil
+	}
+	return xerrors.Errorf("unknown operation %s", rec.Op)
+}
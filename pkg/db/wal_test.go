@@ -0,0 +1,136 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestConfig_ReplayWAL(t *testing.T) {
+	tests := []struct {
+		name     string
+		truncate int // bytes cut from the end of the log, the last transaction sets the metadata
+		wantN    int
+		wantCurl []types.Advisory
+	}{
+		{
+			name:  "complete",
+			wantN: 6,
+			wantCurl: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0002", FixedVersion: "2.0"},
+			},
+		},
+		{
+			name:     "cut in the last transaction",
+			truncate: 20,
+			wantN:    5,
+			wantCurl: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0002", FixedVersion: "2.0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestConfig_ReplayWAL_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+
+			// the build
+			assert.NoError(t, Init(filepath.Join(d, "build")))
+			walPath := filepath.Join(d, "trivy.wal")
+			assert.NoError(t, OpenWAL(walPath))
+
+			dbc := Config{}
+			ctx := context.Background()
+			dbc.TrackWrites()
+			assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "curl", "CVE-2019-0001", types.Advisory{FixedVersion: "1.0"}); err != nil {
+					return err
+				}
+				return dbc.PutAdvisory(tx, "alpine 3.10", "curl", "CVE-2019-0002", types.Advisory{FixedVersion: "2.0"})
+			}))
+			_, err = dbc.Prune("alpine")
+			assert.NoError(t, err)
+			// a rolled back transaction isn't logged
+			assert.Error(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+				if err := dbc.PutAdvisory(tx, "alpine 3.10", "bash", "CVE-2019-0003", types.Advisory{}); err != nil {
+					return err
+				}
+				return errors.New("error")
+			}))
+			assert.NoError(t, dbc.ChunkedUpdate(ctx, 1, 0, func(tx Tx, _ int) error {
+				return dbc.PutSeverity(tx, "CVE-2019-0002", types.SeverityHigh)
+			}, nil))
+			// CVE-2019-0001 is deleted
+			dbc.TrackWrites()
+			assert.NoError(t, dbc.BatchUpdate(ctx, func(tx Tx) error {
+				return dbc.PutAdvisory(tx, "alpine 3.10", "curl", "CVE-2019-0002", types.Advisory{FixedVersion: "2.0"})
+			}))
+			_, err = dbc.Prune("alpine")
+			assert.NoError(t, err)
+			assert.NoError(t, dbc.SetMetadata(Metadata{Version: SchemaVersion, UpdatedAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}))
+
+			var want bytes.Buffer
+			assert.NoError(t, dbc.Export(&want))
+			assert.NoError(t, CloseWAL())
+			assert.NoError(t, Close())
+
+			if tt.truncate > 0 {
+				info, err := os.Stat(walPath)
+				assert.NoError(t, err)
+				assert.NoError(t, os.Truncate(walPath, info.Size()-int64(tt.truncate)))
+			}
+
+			// the replay
+			assert.NoError(t, Init(filepath.Join(d, "replay")))
+			defer Close()
+			f, err := os.Open(walPath)
+			assert.NoError(t, err)
+			defer f.Close()
+
+			n, err := dbc.ReplayWAL(f)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantN, n)
+
+			got, err := dbc.GetAdvisories("alpine 3.10", "curl")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCurl, got)
+			got, err = dbc.GetAdvisories("alpine 3.10", "bash")
+			assert.NoError(t, err)
+			assert.Empty(t, got)
+			severity, err := dbc.GetSeverity("CVE-2019-0002")
+			assert.NoError(t, err)
+			assert.Equal(t, types.SeverityHigh, severity)
+
+			if tt.truncate == 0 {
+				var replayed bytes.Buffer
+				assert.NoError(t, dbc.Export(&replayed))
+				assert.Equal(t, want.String(), replayed.String())
+			}
+		})
+	}
+}
+
+func TestConfig_ReplayWAL_NotEmpty(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestConfig_ReplayWAL_NotEmpty_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	assert.NoError(t, Init(d))
+	defer Close()
+
+	dbc := Config{}
+	assert.NoError(t, dbc.BatchUpdate(context.Background(), func(tx Tx) error {
+		return dbc.PutSeverity(tx, "CVE-2019-0001", types.SeverityLow)
+	}))
+	_, err = dbc.ReplayWAL(bytes.NewReader(nil))
+	assert.EqualError(t, err, "the DB isn't empty, replay into a new DB")
+}
@@ -0,0 +1,36 @@
+package pkg
+
+import (
+	"os"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/delta"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+func makeDelta(c *cli.Context) error {
+	from, err := storage.Open(c.String("backend"), c.String("from"), storage.Options{ReadOnly: true})
+	if err != nil {
+		return xerrors.Errorf("failed to open the base DB: %w", err)
+	}
+	defer from.Close()
+
+	to, err := storage.Open(c.String("backend"), c.String("to"), storage.Options{ReadOnly: true})
+	if err != nil {
+		return xerrors.Errorf("failed to open the new DB: %w", err)
+	}
+	defer to.Close()
+
+	f, err := os.Create(c.String("output"))
+	if err != nil {
+		return xerrors.Errorf("failed to create the delta: %w", err)
+	}
+	defer f.Close()
+
+	if err = delta.Diff(from, to, f); err != nil {
+		return err
+	}
+	return f.Close()
+}
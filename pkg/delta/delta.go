@@ -0,0 +1,321 @@
+package delta
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// A delta is a gzipped stream of JSON lines, a header followed by operations.
+// Compressing the DB re-encodes every value, so deltas between compressed builds are as large as the DB.
+
+const (
+	// FormatVersion is bumped on incompatible changes of the delta format
+	FormatVersion = 1
+
+	OpPut          = "put"
+	OpDelete       = "delete"
+	OpCreateBucket = "create-bucket"
+	OpDeleteBucket = "delete-bucket"
+)
+
+var (
+	metadataPath = [][]byte{[]byte("trivy"), []byte("metadata")}
+	metadataKey  = []byte("data")
+
+	// ErrBaseMismatch is returned by Apply when the DB is not the one the delta was made from
+	ErrBaseMismatch = xerrors.New("the DB is not the base of the delta")
+)
+
+type Header struct {
+	Version int
+	// raw metadata of both DBs
+	From []byte
+	To   []byte
+}
+
+type Operation struct {
+	Op    string
+	Path  [][]byte // bucket names from the root
+	Key   []byte   `json:",omitempty"`
+	Value []byte   `json:",omitempty"`
+}
+
+// Diff writes the operations turning "from" into "to"
+func Diff(from, to storage.Store, w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	enc := json.NewEncoder(gw)
+
+	err := from.View(func(fromTx storage.Tx) error {
+		return to.View(func(toTx storage.Tx) error {
+			header := Header{
+				Version: FormatVersion,
+				From:    getMetadata(fromTx),
+				To:      getMetadata(toTx),
+			}
+			if err := enc.Encode(header); err != nil {
+				return xerrors.Errorf("failed to write the header: %w", err)
+			}
+			return diffBuckets(nil, rootBucket{fromTx}, rootBucket{toTx}, enc.Encode)
+		})
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to diff: %w", err)
+	}
+	if err = gw.Close(); err != nil {
+		return xerrors.Errorf("failed to close gzip: %w", err)
+	}
+	return nil
+}
+
+// Apply updates the DB with a delta. Nothing is written unless the DB is the base of the delta.
+func Apply(s storage.Store, r io.Reader) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return xerrors.Errorf("failed to open gzip: %w", err)
+	}
+	defer gr.Close()
+	dec := json.NewDecoder(gr)
+
+	var header Header
+	if err = dec.Decode(&header); err != nil {
+		return xerrors.Errorf("failed to read the header: %w", err)
+	}
+	if header.Version != FormatVersion {
+		return xerrors.Errorf("unsupported delta version: %d", header.Version)
+	}
+
+	err = s.Update(func(tx storage.Tx) error {
+		if !bytes.Equal(getMetadata(tx), header.From) {
+			return ErrBaseMismatch
+		}
+		for {
+			var op Operation
+			if err := dec.Decode(&op); err == io.EOF {
+				break
+			} else if err != nil {
+				return xerrors.Errorf("failed to read an operation: %w", err)
+			}
+			if err := apply(tx, op); err != nil {
+				return xerrors.Errorf("failed to apply %s: %w", op.Op, err)
+			}
+		}
+		if !bytes.Equal(getMetadata(tx), header.To) {
+			return xerrors.New("incomplete delta")
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to apply the delta: %w", err)
+	}
+	return nil
+}
+
+func apply(tx storage.Tx, op Operation) error {
+	if len(op.Path) == 0 {
+		return xerrors.New("empty path")
+	}
+	parent := op.Path[:len(op.Path)-1]
+	name := op.Path[len(op.Path)-1]
+
+	switch op.Op {
+	case OpPut:
+		b, err := createBuckets(tx, op.Path)
+		if err != nil {
+			return err
+		}
+		return b.Put(op.Key, op.Value)
+	case OpDelete:
+		b := lookup(tx, op.Path)
+		if b == nil {
+			return storage.ErrBucketNotFound
+		}
+		return b.Delete(op.Key)
+	case OpCreateBucket:
+		_, err := createBuckets(tx, op.Path)
+		return err
+	case OpDeleteBucket:
+		if len(parent) == 0 {
+			return tx.DeleteBucket(name)
+		}
+		b := lookup(tx, parent)
+		if b == nil {
+			return storage.ErrBucketNotFound
+		}
+		return b.DeleteBucket(name)
+	default:
+		return xerrors.Errorf("unknown operation: %s", op.Op)
+	}
+}
+
+// container abstracts over a transaction and a bucket, both of which hold buckets
+type container interface {
+	child(name []byte) container
+	// forEach passes a nil value for nested buckets
+	forEach(func(k, v []byte) error) error
+}
+
+type rootBucket struct {
+	tx storage.Tx
+}
+
+func (r rootBucket) child(name []byte) container {
+	return bucket{r.tx.Bucket(name)}
+}
+
+func (r rootBucket) forEach(fn func(k, v []byte) error) error {
+	return r.tx.ForEach(func(name []byte, _ storage.Bucket) error {
+		return fn(name, nil)
+	})
+}
+
+type bucket struct {
+	b storage.Bucket
+}
+
+func (b bucket) child(name []byte) container {
+	return bucket{b.b.Bucket(name)}
+}
+
+func (b bucket) forEach(fn func(k, v []byte) error) error {
+	return b.b.ForEach(fn)
+}
+
+func diffBuckets(path [][]byte, from, to container, emit func(interface{}) error) error {
+	// values are only valid in the transaction, which stays open
+	type item struct {
+		value  []byte
+		bucket bool
+	}
+	toItems := map[string]item{}
+	var toKeys [][]byte
+	err := to.forEach(func(k, v []byte) error {
+		toItems[string(k)] = item{value: v, bucket: v == nil}
+		toKeys = append(toKeys, k)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fromItems := map[string]bool{}
+	var nested [][]byte
+	err = from.forEach(func(k, v []byte) error {
+		fromItems[string(k)] = true
+		t, ok := toItems[string(k)]
+		switch {
+		case !ok && v == nil:
+			return emit(Operation{Op: OpDeleteBucket, Path: appendPath(path, k)})
+		case !ok:
+			return emit(Operation{Op: OpDelete, Path: path, Key: k})
+		case v == nil && t.bucket:
+			nested = append(nested, k)
+		case v == nil || t.bucket:
+			// a bucket replaced by a value or vice versa
+			if v == nil {
+				if err := emit(Operation{Op: OpDeleteBucket, Path: appendPath(path, k)}); err != nil {
+					return err
+				}
+				return emit(Operation{Op: OpPut, Path: path, Key: k, Value: t.value})
+			}
+			if err := emit(Operation{Op: OpDelete, Path: path, Key: k}); err != nil {
+				return err
+			}
+			return addBucket(appendPath(path, k), to.child(k), emit)
+		case !bytes.Equal(v, t.value):
+			return emit(Operation{Op: OpPut, Path: path, Key: k, Value: t.value})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range nested {
+		if err = diffBuckets(appendPath(path, k), from.child(k), to.child(k), emit); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range toKeys {
+		if fromItems[string(k)] {
+			continue
+		}
+		t := toItems[string(k)]
+		if t.bucket {
+			err = addBucket(appendPath(path, k), to.child(k), emit)
+		} else {
+			err = emit(Operation{Op: OpPut, Path: path, Key: k, Value: t.value})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addBucket(path [][]byte, b container, emit func(interface{}) error) error {
+	if err := emit(Operation{Op: OpCreateBucket, Path: path}); err != nil {
+		return err
+	}
+	var nested [][]byte
+	err := b.forEach(func(k, v []byte) error {
+		if v == nil {
+			nested = append(nested, k)
+			return nil
+		}
+		return emit(Operation{Op: OpPut, Path: path, Key: k, Value: v})
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range nested {
+		if err = addBucket(appendPath(path, k), b.child(k), emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createBuckets(tx storage.Tx, path [][]byte) (storage.Bucket, error) {
+	b, err := tx.CreateBucketIfNotExists(path[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range path[1:] {
+		if b, err = b.CreateBucketIfNotExists(name); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func lookup(tx storage.Tx, path [][]byte) storage.Bucket {
+	b := tx.Bucket(path[0])
+	for _, name := range path[1:] {
+		if b == nil {
+			return nil
+		}
+		b = b.Bucket(name)
+	}
+	return b
+}
+
+func getMetadata(tx storage.Tx) []byte {
+	b := lookup(tx, metadataPath)
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b.Get(metadataKey)...)
+}
+
+func appendPath(path [][]byte, name []byte) [][]byte {
+	p := make([][]byte, 0, len(path)+1)
+	p = append(p, path...)
+	return append(p, name)
+}
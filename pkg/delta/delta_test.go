@@ -0,0 +1,112 @@
+package delta
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	_ "github.com/aquasecurity/trivy-db/pkg/storage/boltdb"
+)
+
+type entry struct {
+	path  []string
+	key   string
+	value string
+}
+
+func TestDiffAndApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDiffAndApply_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	base := []entry{
+		{path: []string{"trivy", "metadata"}, key: "data", value: `{"Version":1,"UpdatedAt":"2019-10-01T00:00:00Z"}`},
+		{path: []string{"alpine 3.10", "openssl"}, key: "CVE-2019-0001", value: `{"FixedVersion":"1.1.1d-r0"}`},
+		{path: []string{"alpine 3.10", "openssl"}, key: "CVE-2019-0002", value: `{"FixedVersion":"1.1.1d-r1"}`},
+		{path: []string{"alpine 3.10", "curl"}, key: "CVE-2019-0003", value: `{"FixedVersion":"7.66.0-r0"}`},
+		{path: []string{"severity"}, key: "CVE-2019-0001", value: "HIGH"},
+		{path: []string{"severity"}, key: "CVE-2019-0003", value: "LOW"},
+	}
+	next := []entry{
+		{path: []string{"trivy", "metadata"}, key: "data", value: `{"Version":1,"UpdatedAt":"2019-10-02T00:00:00Z"}`},
+		{path: []string{"alpine 3.10", "openssl"}, key: "CVE-2019-0001", value: `{"FixedVersion":"1.1.1d-r0"}`},
+		{path: []string{"alpine 3.10", "openssl"}, key: "CVE-2019-0002", value: `{"FixedVersion":"1.1.1d-r2"}`},
+		{path: []string{"alpine 3.11", "musl"}, key: "CVE-2019-0004", value: `{"FixedVersion":"1.1.24-r0"}`},
+		{path: []string{"severity"}, key: "CVE-2019-0001", value: "HIGH"},
+		{path: []string{"severity"}, key: "CVE-2019-0004", value: "MEDIUM"},
+	}
+
+	from := openStore(t, filepath.Join(dir, "from.db"), base)
+	defer from.Close()
+	to := openStore(t, filepath.Join(dir, "to.db"), next)
+	defer to.Close()
+
+	var buf bytes.Buffer
+	assert.NoError(t, Diff(from, to, &buf))
+	delta := buf.Bytes()
+
+	t.Run("happy path", func(t *testing.T) {
+		local := openStore(t, filepath.Join(dir, "local.db"), base)
+		defer local.Close()
+
+		assert.NoError(t, Apply(local, bytes.NewReader(delta)))
+		assert.Equal(t, dump(t, to), dump(t, local))
+	})
+
+	t.Run("sad path: different base", func(t *testing.T) {
+		local := openStore(t, filepath.Join(dir, "other.db"), next)
+		defer local.Close()
+
+		err := Apply(local, bytes.NewReader(delta))
+		assert.True(t, xerrors.Is(err, ErrBaseMismatch), err)
+		assert.Equal(t, dump(t, to), dump(t, local))
+	})
+}
+
+func openStore(t *testing.T, path string, entries []entry) storage.Store {
+	s, err := storage.Open(storage.DefaultDriver, path, storage.Options{})
+	assert.NoError(t, err)
+	err = s.Update(func(tx storage.Tx) error {
+		for _, e := range entries {
+			var paths [][]byte
+			for _, p := range e.path {
+				paths = append(paths, []byte(p))
+			}
+			b, err := createBuckets(tx, paths)
+			if err != nil {
+				return err
+			}
+			if err = b.Put([]byte(e.key), []byte(e.value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	return s
+}
+
+func dump(t *testing.T, s storage.Store) map[string]string {
+	m := map[string]string{}
+	var walk func(prefix string, c container) error
+	walk = func(prefix string, c container) error {
+		return c.forEach(func(k, v []byte) error {
+			if v == nil {
+				return walk(prefix+"/"+string(k), c.child(k))
+			}
+			m[prefix+"/"+string(k)] = string(v)
+			return nil
+		})
+	}
+	err := s.View(func(tx storage.Tx) error {
+		return walk("", rootBucket{tx})
+	})
+	assert.NoError(t, err)
+	return m
+}
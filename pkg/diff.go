@@ -0,0 +1,135 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/artifact"
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/oci"
+)
+
+func diff(c *cli.Context) error {
+	dir, err := ioutil.TempDir("", "trivy-db-diff-")
+	if err != nil {
+		return xerrors.Errorf("failed to create a temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	// the previous release may be downloaded
+	var paths []string
+	for _, name := range []string{"from", "to"} {
+		fetchDir := filepath.Join(dir, name)
+		if err = os.Mkdir(fetchDir, 0700); err != nil {
+			return xerrors.Errorf("failed to mkdir: %w", err)
+		}
+		path, err := artifact.Fetch(ctx, oci.NewClient(), c.String(name), fetchDir)
+		if err != nil {
+			return xerrors.Errorf("failed to fetch the --%s DB: %w", name, err)
+		}
+		paths = append(paths, path)
+	}
+
+	report, err := db.Diff(c.String("backend"), paths[0], paths[1])
+	if err != nil {
+		return xerrors.Errorf("failed to diff the DBs: %w", err)
+	}
+
+	return writeReport(os.Stdout, report, c.String("format"))
+}
+
+// writeReport writes the report as text or json
+func writeReport(w io.Writer, report db.DiffReport, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "text":
+		return writeDiff(w, report)
+	default:
+		return xerrors.Errorf("unknown format: %s", format)
+	}
+}
+
+func writeDiff(w io.Writer, report db.DiffReport) error {
+	fmt.Fprintf(w, "From: %s\nTo:   %s\n", report.From.UpdatedAt.Format(time.RFC3339), report.To.UpdatedAt.Format(time.RFC3339))
+
+	for _, s := range report.Sources {
+		fmt.Fprintf(w, "\n%s: %d added, %d removed, %d changed advisories\n", s.Source, len(s.Added), len(s.Removed), len(s.Changed))
+		fixedVersions := map[db.AdvisoryID]db.FixedVersionChange{}
+		for _, f := range s.FixedVersions {
+			fixedVersions[f.AdvisoryID] = f
+		}
+		for _, list := range []struct {
+			mark       string
+			advisories []db.AdvisoryID
+		}{{"+", s.Added}, {"-", s.Removed}, {"~", s.Changed}} {
+			for _, a := range list.advisories {
+				fmt.Fprintf(w, "  %s %s %s", list.mark, a.Package, a.VulnerabilityID)
+				if f, ok := fixedVersions[a]; ok {
+					fmt.Fprintf(w, " (fixed version %s -> %s)", versionOrNone(f.From), versionOrNone(f.To))
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+
+	writeChanges(w, "vulnerabilities", report.Vulnerabilities)
+	if len(report.AddedSeverities) > 0 {
+		var severities []string
+		for severity := range report.AddedSeverities {
+			severities = append(severities, severity)
+		}
+		sort.Slice(severities, func(i, j int) bool {
+			return severityOrder(severities[i], severities[j])
+		})
+		fmt.Fprint(w, "added by severity:")
+		for _, severity := range severities {
+			fmt.Fprintf(w, " %s %d", severity, report.AddedSeverities[severity])
+		}
+		fmt.Fprintln(w)
+	}
+	var sources []string
+	for source := range report.VulnerabilityDetails {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		writeChanges(w, source+" vulnerability details", report.VulnerabilityDetails[source])
+	}
+	return nil
+}
+
+func versionOrNone(v string) string {
+	if v == "" {
+		return "none"
+	}
+	return v
+}
+
+func writeChanges(w io.Writer, name string, c db.Changes) {
+	if len(c.Added)+len(c.Removed)+len(c.Changed) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s: %d added, %d removed, %d changed\n", name, len(c.Added), len(c.Removed), len(c.Changed))
+	for _, list := range []struct {
+		mark string
+		ids  []string
+	}{{"+", c.Added}, {"-", c.Removed}, {"~", c.Changed}} {
+		for _, id := range list.ids {
+			fmt.Fprintf(w, "  %s %s\n", list.mark, id)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package pkg
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+)
+
+func export(c *cli.Context) error {
+	if err := db.OpenReadOnly(c.String("cache-dir")); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	output := c.String("output")
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if output != "" && output != "-" {
+		var err error
+		if f, err = os.Create(output); err != nil {
+			return xerrors.Errorf("failed to create %s: %w", output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	var gw *gzip.Writer
+	if c.Bool("gzip") || strings.HasSuffix(output, ".gz") {
+		gw = gzip.NewWriter(w)
+		w = gw
+	}
+
+	if err := (db.Config{}).Export(w); err != nil {
+		return err
+	}
+	if gw != nil {
+		if err := gw.Close(); err != nil {
+			return xerrors.Errorf("failed to close gzip: %w", err)
+		}
+	}
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return xerrors.Errorf("failed to write %s: %w", output, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package pkg
+
+import (
+	"os"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+)
+
+// extract writes everything the DB has on the vulnerability of the argument as a JSON document
+func extract(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return xerrors.New("the ID of a vulnerability is required, e.g. trivy-db extract CVE-2019-5436")
+	}
+	if err := db.OpenReadOnly(c.String("cache-dir")); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	extraction, err := db.Config{}.Extract(c.Args().First())
+	if err != nil {
+		return err
+	}
+	return writeQueryJSON(os.Stdout, extraction)
+}
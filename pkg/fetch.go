@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"context"
+	"strings"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/fetch"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc"
+)
+
+func fetchRepositories(c *cli.Context) error {
+	targets, err := vulnsrc.Targets(splitList(c.String("only-update")), splitList(c.String("skip-update")))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signalContext()
+	defer cancel()
+	return fetchSources(ctx, c.String("cache-dir"), targets, c.Int("depth"), c.StringSlice("mirror"))
+}
+
+// fetchSources clones or updates the repositories the targets are read from into the cache dir
+func fetchSources(ctx context.Context, cacheDir string, targets []string, depth int, mirrors []string) error {
+	fetcher := fetch.Fetcher{Depth: depth, Mirrors: map[string][]string{}}
+	for _, mirror := range mirrors {
+		// e.g. vuln-list=https://git.example.com/vuln-list.git
+		ss := strings.SplitN(mirror, "=", 2)
+		if len(ss) != 2 || ss[0] == "" || ss[1] == "" {
+			return xerrors.Errorf("invalid mirror %q, <repository>=<url> expected", mirror)
+		}
+		if _, ok := fetch.Upstreams[ss[0]]; !ok {
+			return xerrors.Errorf("invalid mirror %q: unknown repository %s", mirror, ss[0])
+		}
+		fetcher.Mirrors[ss[0]] = append(fetcher.Mirrors[ss[0]], ss[1])
+	}
+
+	for _, repo := range vulnsrc.Repositories(targets) {
+		if _, err := fetcher.Fetch(ctx, cacheDir, repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+// Upstreams are the URLs of the git repositories the sources are read from, keyed by their dir in the cache dir
+var Upstreams = map[string]string{
+	"vuln-list":               "https://github.com/aquasecurity/vuln-list.git",
+	"ruby-advisory-db":        "https://github.com/rubysec/ruby-advisory-db.git",
+	"rust-advisory-db":        "https://github.com/RustSec/advisory-db.git",
+	"php-security-advisories": "https://github.com/FriendsOfPHP/security-advisories.git",
+	"nodejs-security-wg":      "https://github.com/nodejs/security-wg.git",
+	"python-safety-db":        "https://github.com/pyupio/safety-db.git",
+	"vulnrichment":            "https://github.com/cisagov/vulnrichment.git",
+}
+
+// Fetcher clones the repositories into the cache dir, or updates the clones of a previous fetch, with git
+type Fetcher struct {
+	Git string // the path of the executable, git in the PATH if empty
+	// Depth is the number of commits fetched, the whole history if 0
+	Depth int
+	// Mirrors are tried in turn before the upstream, keyed by the dir of the repository
+	Mirrors map[string][]string
+}
+
+// Fetch clones or updates the repository of dir in the cache dir to the head of its default branch, then
+// checks the fetched objects and that the working tree is the commit, and returns the commit
+func (f Fetcher) Fetch(ctx context.Context, cacheDir, dir string) (string, error) {
+	upstream, ok := Upstreams[dir]
+	if !ok {
+		return "", xerrors.Errorf("unknown repository: %s", dir)
+	}
+	urls := append(append([]string{}, f.Mirrors[dir]...), upstream)
+	path := filepath.Join(cacheDir, dir)
+
+	var err error
+	for _, url := range urls {
+		log.Info("Fetching", "repository", dir, "url", url)
+		if err = f.fetch(ctx, path, url); err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		log.Warn("Failed to fetch", "repository", dir, "url", url, log.Err(err))
+	}
+	if err != nil {
+		return "", xerrors.Errorf("failed to fetch %s: %w", dir, err)
+	}
+
+	if err = f.check(ctx, path); err != nil {
+		return "", xerrors.Errorf("integrity check of %s failed: %w", dir, err)
+	}
+	revision, err := utils.GitRevision(path)
+	if err != nil {
+		return "", xerrors.Errorf("failed to get the revision of %s: %w", dir, err)
+	}
+	log.Info("Fetched", "repository", dir, "revision", revision)
+	return revision, nil
+}
+
+// fetch clones url into path through a temporary dir, so that a failed clone leaves nothing behind,
+// or resets the clone at path to the head of url
+func (f Fetcher) fetch(ctx context.Context, path, url string) error {
+	if _, err := os.Stat(filepath.Join(path, ".git")); os.IsNotExist(err) {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		tmp := path + ".tmp"
+		if err = os.RemoveAll(tmp); err != nil {
+			return err
+		}
+		args := append([]string{"clone", "--quiet", "--single-branch"}, f.depth()...)
+		if err = f.git(ctx, "", append(args, url, tmp)...); err != nil {
+			os.RemoveAll(tmp)
+			return err
+		}
+		// e.g. the dir of vuln-list unzipped from an archive
+		if err = os.RemoveAll(path); err != nil {
+			return err
+		}
+		return os.Rename(tmp, path)
+	} else if err != nil {
+		return err
+	}
+
+	args := append([]string{"fetch", "--quiet", "--force"}, f.depth()...)
+	if err := f.git(ctx, path, append(args, url, "HEAD")...); err != nil {
+		return err
+	}
+	if err := f.git(ctx, path, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+		return err
+	}
+	return f.git(ctx, path, "clean", "--quiet", "-d", "--force", "-x")
+}
+
+// check verifies the objects reachable from the commit, which a shallow clone has down to its depth,
+// and that no file of the working tree differs from the commit
+func (f Fetcher) check(ctx context.Context, path string) error {
+	if err := f.git(ctx, path, "fsck", "--no-progress", "--no-dangling", "--connectivity-only"); err != nil {
+		return err
+	}
+	status, err := f.output(ctx, path, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return err
+	}
+	if status = strings.TrimSpace(status); status != "" {
+		return xerrors.Errorf("the working tree differs from the commit:\n%s", status)
+	}
+	return nil
+}
+
+func (f Fetcher) depth() []string {
+	if f.Depth <= 0 {
+		return nil
+	}
+	return []string{"--depth", strconv.Itoa(f.Depth)}
+}
+
+func (f Fetcher) git(ctx context.Context, dir string, args ...string) error {
+	_, err := f.output(ctx, dir, args...)
+	return err
+}
+
+func (f Fetcher) output(ctx context.Context, dir string, args ...string) (string, error) {
+	git := f.Git
+	if git == "" {
+		git = "git"
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, git, args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	// fail instead of prompting for credentials
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if err := cmd.Run(); err != nil {
+		return "", xerrors.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
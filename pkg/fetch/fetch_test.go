@@ -0,0 +1,88 @@
+package fetch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// commit writes the file in the repository at dir and commits it, returning the commit
+func commit(t *testing.T, dir, name, content string) string {
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	for _, args := range [][]string{
+		{"add", name},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", name},
+	} {
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	assert.NoError(t, err)
+	return strings.TrimSpace(string(out))
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	d, err := ioutil.TempDir("", "TestFetcher_Fetch_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	upstream := filepath.Join(d, "upstream")
+	assert.NoError(t, os.MkdirAll(upstream, 0755))
+	out, err := exec.Command("git", "init", "--quiet", upstream).CombinedOutput()
+	assert.NoError(t, err, string(out))
+	first := commit(t, upstream, "CVE-2020-0001.json", "{}")
+
+	prev := Upstreams["vuln-list"]
+	defer func() { Upstreams["vuln-list"] = prev }()
+	Upstreams["vuln-list"] = "file://" + upstream
+
+	cacheDir := filepath.Join(d, "cache")
+	ctx := context.Background()
+	f := Fetcher{Depth: 1, Mirrors: map[string][]string{"vuln-list": {"file://" + filepath.Join(d, "missing")}}}
+
+	// the mirror fails, the upstream is cloned
+	revision, err := f.Fetch(ctx, cacheDir, "vuln-list")
+	assert.NoError(t, err)
+	assert.Equal(t, first, revision)
+	_, err = os.Stat(filepath.Join(cacheDir, "vuln-list", "CVE-2020-0001.json"))
+	assert.NoError(t, err)
+
+	// the clone is updated, dropping local changes
+	second := commit(t, upstream, "CVE-2020-0002.json", "{}")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(cacheDir, "vuln-list", "CVE-2020-0001.json"), []byte("x"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(cacheDir, "vuln-list", "stray.json"), []byte("x"), 0644))
+	revision, err = f.Fetch(ctx, cacheDir, "vuln-list")
+	assert.NoError(t, err)
+	assert.Equal(t, second, revision)
+	b, err := ioutil.ReadFile(filepath.Join(cacheDir, "vuln-list", "CVE-2020-0001.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", string(b))
+	_, err = os.Stat(filepath.Join(cacheDir, "vuln-list", "stray.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	// a modified tree fails the check
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(cacheDir, "vuln-list", "CVE-2020-0002.json"), []byte("x"), 0644))
+	err = f.check(ctx, filepath.Join(cacheDir, "vuln-list"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the working tree differs from the commit")
+
+	// every URL fails
+	Upstreams["vuln-list"] = "file://" + filepath.Join(d, "missing")
+	_, err = f.Fetch(ctx, filepath.Join(d, "other"), "vuln-list")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to fetch vuln-list")
+	_, err = os.Stat(filepath.Join(d, "other", "vuln-list.tmp"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = f.Fetch(ctx, cacheDir, "unknown")
+	assert.EqualError(t, err, "unknown repository: unknown")
+}
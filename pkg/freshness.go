@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/pipeline"
+)
+
+func freshness(c *cli.Context) error {
+	if err := db.OpenReadOnly(c.String("cache-dir")); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	metadata, err := db.Config{}.GetMetadata()
+	if err != nil {
+		return xerrors.Errorf("failed to get the metadata: %w", err)
+	}
+	report := metadata.Freshness(staleAfter(c.Duration("stale-after"), nil))
+
+	switch format := c.String("format"); format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(report); err != nil {
+			return err
+		}
+	case "table":
+		if err = writeFreshness(os.Stdout, report); err != nil {
+			return err
+		}
+	default:
+		return xerrors.Errorf("unknown format: %s", format)
+	}
+
+	if c.Bool("fail-on-stale") {
+		if stale := staleSources(report); len(stale) > 0 {
+			return xerrors.Errorf("%d stale source(s): %v", len(stale), stale)
+		}
+	}
+	return nil
+}
+
+// reportFreshness logs the sources of the built DB which look stale, and writes the report to path if any
+func reportFreshness(path string, report []db.SourceFreshness) error {
+	for _, f := range report {
+		if f.Stale {
+			log.Warn("The source added no record for long, its fetcher may be broken", "source", f.Source,
+				"newest_record_at", f.NewestRecordAt.Format(time.RFC3339), "age", f.Age.String(),
+				"stale_after", f.StaleAfter.String())
+		}
+	}
+	log.Info("Checked the freshness of the sources", "sources", len(report), "stale", len(staleSources(report)))
+
+	if path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("failed to encode the freshness report: %w", err)
+	}
+	if err = ioutil.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return xerrors.Errorf("failed to write the freshness report: %w", err)
+	}
+	return nil
+}
+
+// staleAfter returns the threshold of each source, the one of its config or def
+func staleAfter(def time.Duration, sources []pipeline.SourceConfig) func(string) time.Duration {
+	thresholds := map[string]time.Duration{}
+	for _, s := range sources {
+		if s.StaleAfter != 0 {
+			thresholds[s.Name] = s.StaleAfter
+		}
+	}
+	return func(source string) time.Duration {
+		if d, ok := thresholds[source]; ok {
+			return d
+		}
+		return def
+	}
+}
+
+func staleSources(report []db.SourceFreshness) []string {
+	var stale []string
+	for _, f := range report {
+		if f.Stale {
+			stale = append(stale, f.Source)
+		}
+	}
+	return stale
+}
+
+func writeFreshness(w io.Writer, report []db.SourceFreshness) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tUPDATED AT\tNEWEST RECORD AT\tAGE\tSTALE AFTER\tSTALE\t")
+	for _, f := range report {
+		newest, age := "unknown", "-"
+		if !f.NewestRecordAt.IsZero() {
+			newest, age = f.NewestRecordAt.Format(time.RFC3339), f.Age.Round(time.Minute).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%t\t\n", f.Source, f.UpdatedAt.Format(time.RFC3339), newest, age,
+			f.StaleAfter, f.Stale)
+	}
+	return tw.Flush()
+}
@@ -3,10 +3,10 @@ package github
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"time"
 
@@ -16,6 +16,7 @@ import (
 	"k8s.io/utils/clock"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
 )
 
 const (
@@ -28,6 +29,7 @@ type RepositoryInterface interface {
 	GetReleaseByTag(ctx context.Context, tag string) (*github.RepositoryRelease, *github.Response, error)
 	CreateRelease(ctx context.Context, release *github.RepositoryRelease) (*github.RepositoryRelease, *github.Response, error)
 	UploadReleaseAsset(ctx context.Context, id int64, opt *github.UploadOptions, file *os.File) (*github.ReleaseAsset, *github.Response, error)
+	DeleteReleaseAsset(ctx context.Context, id int64) (*github.Response, error)
 	DeleteRelease(ctx context.Context, id int64) (*github.Response, error)
 	DeleteRef(ctx context.Context, ref string) (*github.Response, error)
 }
@@ -55,6 +57,10 @@ func (r Repository) UploadReleaseAsset(ctx context.Context, id int64, opt *githu
 	return r.repository.UploadReleaseAsset(ctx, r.owner, r.repoName, id, opt, file)
 }
 
+func (r Repository) DeleteReleaseAsset(ctx context.Context, id int64) (*github.Response, error) {
+	return r.repository.DeleteReleaseAsset(ctx, r.owner, r.repoName, id)
+}
+
 func (r Repository) DeleteRelease(ctx context.Context, id int64) (*github.Response, error) {
 	return r.repository.DeleteRelease(ctx, r.owner, r.repoName, id)
 }
@@ -64,15 +70,39 @@ func (r Repository) DeleteRef(ctx context.Context, ref string) (*github.Response
 }
 
 type VCSClientInterface interface {
-	UploadReleaseAsset(ctx context.Context, filePaths []string) error
+	UploadReleaseAsset(ctx context.Context, channel string, filePaths []string) error
 }
 
 type Client struct {
 	Clock      clock.Clock
 	Repository RepositoryInterface
+	Retention  Retention
+}
+
+// Retention is how many nightly releases of a channel, tagged v<schema>-<YYYYMMDDHH> for the stable one and
+// v<schema>-<channel>-<YYYYMMDDHH> for the others, are kept once a release of the channel is published.
+// The other releases are never deleted.
+type Retention struct {
+	Keep   int           // the newest releases kept, 3 if 0
+	MaxAge time.Duration // releases published longer ago are deleted even if among the newest, if not 0
+}
+
+// nightlyTag matches the tags of the releases of a channel, the stable one having no channel in its tags.
+// Only the releases of the current schema are matched since the clients of the previous ones still
+// download theirs.
+func nightlyTag(channel string) *regexp.Regexp {
+	if db.IsStable(channel) {
+		return regexp.MustCompile(fmt.Sprintf(`^v%d-\d{10}$`, db.SchemaVersion))
+	}
+	return regexp.MustCompile(fmt.Sprintf(`^v%d-`, db.SchemaVersion) + regexp.QuoteMeta(channel) + `-\d{10}$`)
 }
 
 func NewClient(ctx context.Context) Client {
+	return NewRepositoryClient(ctx, owner, repo)
+}
+
+// NewRepositoryClient publishes to the releases of another repository, e.g. a fork
+func NewRepositoryClient(ctx context.Context, owner, repoName string) Client {
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: os.Getenv("GITHUB_TOKEN")},
 	)
@@ -83,7 +113,7 @@ func NewClient(ctx context.Context) Client {
 		repository: gc.Repositories,
 		git:        gc.Git,
 		owner:      owner,
-		repoName:   repo,
+		repoName:   repoName,
 	}
 
 	return Client{
@@ -92,24 +122,29 @@ func NewClient(ctx context.Context) Client {
 	}
 }
 
-func (c Client) UploadReleaseAsset(ctx context.Context, filePaths []string) error {
+// UploadReleaseAsset uploads the files to the release of the hour of the channel. The releases of the
+// channels other than stable are prereleases, which aren't the latest release clients download.
+func (c Client) UploadReleaseAsset(ctx context.Context, channel string, filePaths []string) error {
 	now := c.Clock.Now().UTC()
 	date := now.Format("2006010215")
 
 	tag := fmt.Sprintf("v%d-%s", db.SchemaVersion, date)
-	if err := c.updateReleaseAsset(ctx, tag, filePaths); err != nil {
+	if !db.IsStable(channel) {
+		tag = fmt.Sprintf("v%d-%s-%s", db.SchemaVersion, channel, date)
+	}
+	if err := c.updateReleaseAsset(ctx, tag, !db.IsStable(channel), filePaths); err != nil {
 		return xerrors.Errorf("failed to update release asset: %w", err)
 	}
 
-	if err := c.deleteOldReleases(ctx, now); err != nil {
+	if err := c.deleteOldReleases(ctx, now, channel, tag); err != nil {
 		return xerrors.Errorf("failed to delete old releases: %w", err)
 	}
 
 	return nil
 }
 
-func (c Client) updateReleaseAsset(ctx context.Context, tag string, filePaths []string) error {
-	log.Printf("Update release assets, release: %s\n", tag)
+func (c Client) updateReleaseAsset(ctx context.Context, tag string, prerelease bool, filePaths []string) error {
+	log.Info("Updating the release assets", "release", tag)
 	release, res, err := c.Repository.GetReleaseByTag(ctx, tag)
 	if err != nil {
 		if res == nil || res.StatusCode != http.StatusNotFound {
@@ -122,7 +157,7 @@ func (c Client) updateReleaseAsset(ctx context.Context, tag string, filePaths []
 			TagName:    github.String(tag),
 			Name:       github.String(tag),
 			Draft:      github.Bool(false),
-			Prerelease: github.Bool(false),
+			Prerelease: github.Bool(prerelease),
 		}
 		release, _, err = c.Repository.CreateRelease(ctx, release)
 		if err != nil {
@@ -130,12 +165,34 @@ func (c Client) updateReleaseAsset(ctx context.Context, tag string, filePaths []
 		}
 	}
 
+	// assets are replaced when the release of the hour is updated
+	assets := map[string]int64{}
+	for _, asset := range release.Assets {
+		assets[asset.GetName()] = asset.GetID()
+	}
+
 	for _, filePath := range filePaths {
-		log.Printf("Update release assets, file: %s\n", filePath)
 		name := filepath.Base(filePath)
+		if id, ok := assets[name]; ok {
+			log.Info("Deleting the previous release asset", "name", name)
+			if _, err = c.Repository.DeleteReleaseAsset(ctx, id); err != nil {
+				return xerrors.Errorf("unable to delete a release asset: %w", err)
+			}
+		}
+
+		log.Info("Uploading a release asset", "file", filePath)
+		mediaType := "application/gzip"
+		switch filepath.Ext(name) {
+		case ".json":
+			mediaType = "application/json"
+		case ".sig", ".pem":
+			mediaType = "text/plain"
+		case ".zst":
+			mediaType = "application/zstd"
+		}
 		uploadOptions := github.UploadOptions{
 			Name:      name,
-			MediaType: "application/gzip",
+			MediaType: mediaType,
 		}
 		f, err := os.Open(filePath)
 		if err != nil {
@@ -150,29 +207,20 @@ func (c Client) updateReleaseAsset(ctx context.Context, tag string, filePaths []
 	return nil
 }
 
-func (c Client) deleteOldReleases(ctx context.Context, now time.Time) error {
-	options := github.ListOptions{}
-	releases, _, err := c.Repository.ListReleases(ctx, &options)
+func (c Client) deleteOldReleases(ctx context.Context, now time.Time, channel, current string) error {
+	releases, err := c.listReleases(ctx)
 	if err != nil {
-		return xerrors.Errorf("failed to list releases: %w", err)
-	}
-
-	if len(releases) <= 3 {
-		return nil
+		return err
 	}
 
-	sort.Slice(releases, func(i, j int) bool {
-		return releases[i].GetPublishedAt().Time.After(releases[j].GetPublishedAt().Time)
-	})
-
-	for _, release := range releases[3:] {
-		log.Printf("Delete the old release, name: %s, published_at: %s",
-			release.GetName(), release.GetPublishedAt())
+	for _, release := range c.Retention.expired(releases, now, nightlyTag(channel), current) {
+		log.Info("Deleting an old release", "name", release.GetName(),
+			"published_at", release.GetPublishedAt().Format(time.RFC3339))
 		_, err = c.Repository.DeleteRelease(ctx, *release.ID)
 		if err != nil {
 			return xerrors.Errorf("failed to delete a release: %w", err)
 		}
-		log.Printf("Delete the tag: %s", release.GetTagName())
+		log.Info("Deleting the tag", "tag", release.GetTagName())
 		_, err = c.Repository.DeleteRef(ctx, fmt.Sprintf("tags/%s", release.GetTagName()))
 		if err != nil {
 			return xerrors.Errorf("failed to delete a tag: %w", err)
@@ -180,3 +228,51 @@ func (c Client) deleteOldReleases(ctx context.Context, now time.Time) error {
 	}
 	return nil
 }
+
+// listReleases lists the releases of every page
+func (c Client) listReleases(ctx context.Context) ([]*github.RepositoryRelease, error) {
+	var releases []*github.RepositoryRelease
+	options := github.ListOptions{PerPage: 100}
+	for {
+		page, res, err := c.Repository.ListReleases(ctx, &options)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to list releases: %w", err)
+		}
+		releases = append(releases, page...)
+		if res == nil || res.NextPage == 0 {
+			return releases, nil
+		}
+		options.Page = res.NextPage
+	}
+}
+
+// expired returns the nightly releases whose tag matches nightly to delete, never the current one
+func (r Retention) expired(releases []*github.RepositoryRelease, now time.Time, nightly *regexp.Regexp,
+	current string) []*github.RepositoryRelease {
+	keep := r.Keep
+	if keep == 0 {
+		keep = 3
+	}
+
+	var matched []*github.RepositoryRelease
+	for _, release := range releases {
+		if nightly.MatchString(release.GetTagName()) {
+			matched = append(matched, release)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].GetPublishedAt().Time.After(matched[j].GetPublishedAt().Time)
+	})
+
+	var expired []*github.RepositoryRelease
+	for i, release := range matched {
+		if release.GetTagName() == current {
+			continue
+		}
+		tooOld := r.MaxAge > 0 && now.Sub(release.GetPublishedAt().Time) > r.MaxAge
+		if i >= keep || tooOld {
+			expired = append(expired, release)
+		}
+	}
+	return expired
+}
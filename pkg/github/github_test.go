@@ -35,7 +35,8 @@ func (_m *MockRepository) ListReleases(ctx context.Context, opt *github.ListOpti
 	if !ok {
 		return nil, nil, ret.Error(2)
 	}
-	return releases, nil, ret.Error(2)
+	response, _ := ret.Get(1).(*github.Response)
+	return releases, response, ret.Error(2)
 }
 
 func (_m *MockRepository) GetReleaseByTag(ctx context.Context, tag string) (
@@ -90,6 +91,19 @@ func (_m *MockRepository) UploadReleaseAsset(ctx context.Context, id int64, opt
 	return asset, nil, ret.Error(2)
 }
 
+func (_m *MockRepository) DeleteReleaseAsset(ctx context.Context, id int64) (*github.Response, error) {
+	ret := _m.Called(ctx, id)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil, ret.Error(1)
+	}
+	response, ok := ret0.(*github.Response)
+	if !ok {
+		return nil, ret.Error(1)
+	}
+	return response, ret.Error(1)
+}
+
 func (_m *MockRepository) DeleteRelease(ctx context.Context, id int64) (*github.Response, error) {
 	ret := _m.Called(ctx, id)
 	ret0 := ret.Get(0)
@@ -123,7 +137,7 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 		err      error
 	}
 	type listReleases struct {
-		input  string
+		input  interface{}
 		output listReleasesOutput
 	}
 
@@ -157,6 +171,15 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 		output uploadReleaseAssetOutput
 	}
 
+	type deleteReleaseAssetOutput struct {
+		response *github.Response
+		err      error
+	}
+	type deleteReleaseAsset struct {
+		input  int64
+		output deleteReleaseAssetOutput
+	}
+
 	type deleteReleaseOutput struct {
 		response *github.Response
 		err      error
@@ -178,12 +201,15 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 	testCases := []struct {
 		name               string
 		clock              clock.Clock
+		retention          gh.Retention
+		channel            string
 		files              map[string][]byte
 		filePaths          []string
 		listReleases       []listReleases
 		getReleaseByTag    []getReleaseByTag
 		createRelease      []createRelease
 		uploadReleaseAsset []uploadReleaseAsset
+		deleteReleaseAsset []deleteReleaseAsset
 		deleteRelease      []deleteRelease
 		deleteRef          []deleteRef
 		expectedError      error
@@ -218,11 +244,11 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			},
 			getReleaseByTag: []getReleaseByTag{
 				{
-					input: "v1-2020123123",
+					input: "v2-2020123123",
 					output: getReleaseByTagOutput{
 						release: &github.RepositoryRelease{
 							ID:      github.Int64(1),
-							TagName: github.String("v1-2020123123"),
+							TagName: github.String("v2-2020123123"),
 						},
 						response: &github.Response{
 							Response: &http.Response{
@@ -270,11 +296,11 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			},
 			getReleaseByTag: []getReleaseByTag{
 				{
-					input: "v1-2020123123",
+					input: "v2-2020123123",
 					output: getReleaseByTagOutput{
 						release: &github.RepositoryRelease{
 							ID:      github.Int64(1),
-							TagName: github.String("v1-2020123123"),
+							TagName: github.String("v2-2020123123"),
 						},
 						response: &github.Response{
 							Response: &http.Response{
@@ -288,15 +314,15 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			createRelease: []createRelease{
 				{
 					input: &github.RepositoryRelease{
-						TagName:    github.String("v1-2020123123"),
-						Name:       github.String("v1-2020123123"),
+						TagName:    github.String("v2-2020123123"),
+						Name:       github.String("v2-2020123123"),
 						Draft:      github.Bool(false),
 						Prerelease: github.Bool(false),
 					},
 					output: createReleaseOutput{
 						release: &github.RepositoryRelease{
 							ID:      github.Int64(1),
-							TagName: github.String("v1-2020123123"),
+							TagName: github.String("v2-2020123123"),
 						},
 					},
 				},
@@ -327,32 +353,41 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 						releases: []*github.RepositoryRelease{
 							{
 								ID:      github.Int64(111),
-								Name:    github.String("v1-2019012023"),
-								TagName: github.String("v1-2019012023"),
+								Name:    github.String("v2-2019012023"),
+								TagName: github.String("v2-2019012023"),
 								PublishedAt: &github.Timestamp{
 									Time: time.Date(2019, 1, 20, 23, 59, 59, 0, time.UTC),
 								},
 							},
 							{
 								ID:      github.Int64(222),
-								Name:    github.String("v1-2019012509"),
-								TagName: github.String("v1-2019012509"),
+								Name:    github.String("v2-2019012509"),
+								TagName: github.String("v2-2019012509"),
 								PublishedAt: &github.Timestamp{
 									Time: time.Date(2019, 1, 25, 9, 0, 59, 0, time.UTC),
 								},
 							},
 							{
 								ID:      github.Int64(333),
-								Name:    github.String("v1-2019013059"),
-								TagName: github.String("v1-2019013059"),
+								Name:    github.String("v2-2019013059"),
+								TagName: github.String("v2-2019013059"),
 								PublishedAt: &github.Timestamp{
 									Time: time.Date(2019, 1, 30, 10, 59, 59, 0, time.UTC),
 								},
 							},
+							// the releases of the previous schema are kept for its clients
+							{
+								ID:      github.Int64(555),
+								Name:    github.String("v1-2019011023"),
+								TagName: github.String("v1-2019011023"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 10, 23, 59, 59, 0, time.UTC),
+								},
+							},
 							{
 								ID:      github.Int64(444),
-								Name:    github.String("v1-2019013059"),
-								TagName: github.String("v1-2019013059"),
+								Name:    github.String("v2-2019013059"),
+								TagName: github.String("v2-2019013059"),
 								PublishedAt: &github.Timestamp{
 									Time: time.Date(2019, 1, 30, 9, 59, 59, 0, time.UTC),
 								},
@@ -363,11 +398,11 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			},
 			getReleaseByTag: []getReleaseByTag{
 				{
-					input: "v1-2019013011",
+					input: "v2-2019013011",
 					output: getReleaseByTagOutput{
 						release: &github.RepositoryRelease{
 							ID:      github.Int64(2),
-							TagName: github.String("v1-2019013011"),
+							TagName: github.String("v2-2019013011"),
 						},
 						response: &github.Response{
 							Response: &http.Response{
@@ -392,7 +427,323 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			},
 			deleteRef: []deleteRef{
 				{
-					input:  "tags/v1-2019012023",
+					input:  "tags/v2-2019012023",
+					output: deleteRefOutput{},
+				},
+			},
+		},
+		{
+			name:      "happy path with releases on several pages",
+			clock:     ct.NewFakeClock(time.Date(2019, 1, 30, 11, 59, 59, 0, time.UTC)),
+			retention: gh.Retention{Keep: 1},
+			files: map[string][]byte{
+				"trivy.db.gz": []byte("full"),
+			},
+			filePaths: []string{
+				"trivy.db.gz",
+			},
+			listReleases: []listReleases{
+				{
+					input: &github.ListOptions{PerPage: 100},
+					output: listReleasesOutput{
+						releases: []*github.RepositoryRelease{
+							{
+								ID:      github.Int64(111),
+								Name:    github.String("v2-2019012910"),
+								TagName: github.String("v2-2019012910"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 29, 10, 0, 59, 0, time.UTC),
+								},
+							},
+						},
+						response: &github.Response{NextPage: 2},
+					},
+				},
+				{
+					input: &github.ListOptions{Page: 2, PerPage: 100},
+					output: listReleasesOutput{
+						releases: []*github.RepositoryRelease{
+							{
+								ID:      github.Int64(222),
+								Name:    github.String("v2-2019012509"),
+								TagName: github.String("v2-2019012509"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 25, 9, 0, 59, 0, time.UTC),
+								},
+							},
+						},
+						response: &github.Response{},
+					},
+				},
+			},
+			getReleaseByTag: []getReleaseByTag{
+				{
+					input: "v2-2019013011",
+					output: getReleaseByTagOutput{
+						release: &github.RepositoryRelease{
+							ID:      github.Int64(2),
+							TagName: github.String("v2-2019013011"),
+						},
+						response: &github.Response{
+							Response: &http.Response{
+								StatusCode: 200,
+							},
+						},
+					},
+				},
+			},
+			uploadReleaseAsset: []uploadReleaseAsset{
+				{
+					input:  2,
+					output: uploadReleaseAssetOutput{},
+				},
+			},
+			deleteRelease: []deleteRelease{
+				{
+					input:  222,
+					output: deleteReleaseOutput{},
+				},
+			},
+			deleteRef: []deleteRef{
+				{
+					input:  "tags/v2-2019012509",
+					output: deleteRefOutput{},
+				},
+			},
+		},
+		{
+			name:  "happy path replacing the assets of the release",
+			clock: ct.NewFakeClock(time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC)),
+			files: map[string][]byte{
+				"trivy.db.gz":   []byte("full"),
+				"metadata.json": []byte("{}"),
+			},
+			filePaths: []string{
+				"trivy.db.gz",
+				"metadata.json",
+			},
+			listReleases: []listReleases{
+				{
+					input:  mock.Anything,
+					output: listReleasesOutput{},
+				},
+			},
+			getReleaseByTag: []getReleaseByTag{
+				{
+					input: "v2-2020123123",
+					output: getReleaseByTagOutput{
+						release: &github.RepositoryRelease{
+							ID:      github.Int64(1),
+							TagName: github.String("v2-2020123123"),
+							Assets: []github.ReleaseAsset{
+								{ID: github.Int64(10), Name: github.String("trivy.db.gz")},
+								{ID: github.Int64(11), Name: github.String("trivy-light.db.gz")},
+							},
+						},
+						response: &github.Response{
+							Response: &http.Response{
+								StatusCode: 200,
+							},
+						},
+					},
+				},
+			},
+			deleteReleaseAsset: []deleteReleaseAsset{
+				{
+					input:  10,
+					output: deleteReleaseAssetOutput{},
+				},
+			},
+			uploadReleaseAsset: []uploadReleaseAsset{
+				{
+					input:  1,
+					output: uploadReleaseAssetOutput{},
+				},
+			},
+		},
+		{
+			name:      "happy path with a channel",
+			clock:     ct.NewFakeClock(time.Date(2019, 1, 30, 11, 59, 59, 0, time.UTC)),
+			retention: gh.Retention{Keep: 1},
+			channel:   "dev",
+			files: map[string][]byte{
+				"trivy.db.gz": []byte("full"),
+			},
+			filePaths: []string{
+				"trivy.db.gz",
+			},
+			listReleases: []listReleases{
+				{
+					input: mock.Anything,
+					output: listReleasesOutput{
+						releases: []*github.RepositoryRelease{
+							// the releases of the other channels are kept
+							{
+								ID:      github.Int64(111),
+								Name:    github.String("v2-2019012509"),
+								TagName: github.String("v2-2019012509"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 25, 9, 0, 59, 0, time.UTC),
+								},
+							},
+							{
+								ID:      github.Int64(222),
+								Name:    github.String("v2-nightly-2019012910"),
+								TagName: github.String("v2-nightly-2019012910"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 29, 10, 0, 59, 0, time.UTC),
+								},
+							},
+							{
+								ID:      github.Int64(333),
+								Name:    github.String("v2-dev-2019012910"),
+								TagName: github.String("v2-dev-2019012910"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 29, 10, 0, 59, 0, time.UTC),
+								},
+							},
+							{
+								ID:      github.Int64(2),
+								Name:    github.String("v2-dev-2019013011"),
+								TagName: github.String("v2-dev-2019013011"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 30, 11, 59, 59, 0, time.UTC),
+								},
+							},
+						},
+					},
+				},
+			},
+			getReleaseByTag: []getReleaseByTag{
+				{
+					input: "v2-dev-2019013011",
+					output: getReleaseByTagOutput{
+						response: &github.Response{
+							Response: &http.Response{
+								StatusCode: http.StatusNotFound,
+							},
+						},
+						err: errors.New("not found"),
+					},
+				},
+			},
+			createRelease: []createRelease{
+				{
+					input: &github.RepositoryRelease{
+						TagName:    github.String("v2-dev-2019013011"),
+						Name:       github.String("v2-dev-2019013011"),
+						Draft:      github.Bool(false),
+						Prerelease: github.Bool(true),
+					},
+					output: createReleaseOutput{
+						release: &github.RepositoryRelease{
+							ID:      github.Int64(2),
+							TagName: github.String("v2-dev-2019013011"),
+						},
+					},
+				},
+			},
+			uploadReleaseAsset: []uploadReleaseAsset{
+				{
+					input:  2,
+					output: uploadReleaseAssetOutput{},
+				},
+			},
+			deleteRelease: []deleteRelease{
+				{
+					input:  333,
+					output: deleteReleaseOutput{},
+				},
+			},
+			deleteRef: []deleteRef{
+				{
+					input:  "tags/v2-dev-2019012910",
+					output: deleteRefOutput{},
+				},
+			},
+		},
+		{
+			name:      "happy path with a retention policy",
+			clock:     ct.NewFakeClock(time.Date(2019, 1, 30, 11, 59, 59, 0, time.UTC)),
+			retention: gh.Retention{Keep: 3, MaxAge: 48 * time.Hour},
+			files: map[string][]byte{
+				"trivy.db.gz": []byte("full"),
+			},
+			filePaths: []string{
+				"trivy.db.gz",
+			},
+			listReleases: []listReleases{
+				{
+					input: mock.Anything,
+					output: listReleasesOutput{
+						releases: []*github.RepositoryRelease{
+							{
+								ID:      github.Int64(1),
+								Name:    github.String("v0.1.0"),
+								TagName: github.String("v0.1.0"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+								},
+							},
+							{
+								ID:      github.Int64(111),
+								Name:    github.String("v2-2019012509"),
+								TagName: github.String("v2-2019012509"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 25, 9, 0, 59, 0, time.UTC),
+								},
+							},
+							{
+								ID:      github.Int64(222),
+								Name:    github.String("v2-2019012910"),
+								TagName: github.String("v2-2019012910"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 29, 10, 0, 59, 0, time.UTC),
+								},
+							},
+							{
+								ID:      github.Int64(2),
+								Name:    github.String("v2-2019013011"),
+								TagName: github.String("v2-2019013011"),
+								PublishedAt: &github.Timestamp{
+									Time: time.Date(2019, 1, 30, 11, 59, 59, 0, time.UTC),
+								},
+							},
+						},
+					},
+				},
+			},
+			getReleaseByTag: []getReleaseByTag{
+				{
+					input: "v2-2019013011",
+					output: getReleaseByTagOutput{
+						release: &github.RepositoryRelease{
+							ID:      github.Int64(2),
+							TagName: github.String("v2-2019013011"),
+						},
+						response: &github.Response{
+							Response: &http.Response{
+								StatusCode: 200,
+							},
+						},
+					},
+				},
+			},
+			uploadReleaseAsset: []uploadReleaseAsset{
+				{
+					input:  2,
+					output: uploadReleaseAssetOutput{},
+				},
+			},
+			deleteRelease: []deleteRelease{
+				{
+					input:  111,
+					output: deleteReleaseOutput{},
+				},
+			},
+			deleteRef: []deleteRef{
+				{
+					input:  "tags/v2-2019012509",
 					output: deleteRefOutput{},
 				},
 			},
@@ -428,11 +779,11 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			},
 			getReleaseByTag: []getReleaseByTag{
 				{
-					input: "v1-2019013011",
+					input: "v2-2019013011",
 					output: getReleaseByTagOutput{
 						release: &github.RepositoryRelease{
 							ID:      github.Int64(2),
-							TagName: github.String("v1-2019013011"),
+							TagName: github.String("v2-2019013011"),
 						},
 						response: &github.Response{
 							Response: &http.Response{
@@ -455,7 +806,7 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			clock: ct.NewFakeClock(time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC)),
 			getReleaseByTag: []getReleaseByTag{
 				{
-					input: "v1-2020123123",
+					input: "v2-2020123123",
 					output: getReleaseByTagOutput{
 						err: errors.New("GetReleaseByTag failed"),
 					},
@@ -468,11 +819,11 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			clock: ct.NewFakeClock(time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC)),
 			getReleaseByTag: []getReleaseByTag{
 				{
-					input: "v1-2020123123",
+					input: "v2-2020123123",
 					output: getReleaseByTagOutput{
 						release: &github.RepositoryRelease{
 							ID:      github.Int64(1),
-							TagName: github.String("v1-2020123123"),
+							TagName: github.String("v2-2020123123"),
 						},
 						response: &github.Response{
 							Response: &http.Response{
@@ -486,8 +837,8 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			createRelease: []createRelease{
 				{
 					input: &github.RepositoryRelease{
-						TagName:    github.String("v1-2020123123"),
-						Name:       github.String("v1-2020123123"),
+						TagName:    github.String("v2-2020123123"),
+						Name:       github.String("v2-2020123123"),
 						Draft:      github.Bool(false),
 						Prerelease: github.Bool(false),
 					},
@@ -511,11 +862,11 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			},
 			getReleaseByTag: []getReleaseByTag{
 				{
-					input: "v1-2020123123",
+					input: "v2-2020123123",
 					output: getReleaseByTagOutput{
 						release: &github.RepositoryRelease{
 							ID:      github.Int64(1),
-							TagName: github.String("v1-2020123123"),
+							TagName: github.String("v2-2020123123"),
 						},
 						response: &github.Response{
 							Response: &http.Response{
@@ -564,6 +915,11 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 				)
 			}
 
+			for _, dra := range tc.deleteReleaseAsset {
+				mockRepo.On("DeleteReleaseAsset", mock.Anything, dra.input).Return(
+					dra.output.response, dra.output.err,
+				)
+			}
 			for _, dr := range tc.deleteRelease {
 				mockRepo.On("DeleteRelease", mock.Anything, dr.input).Return(
 					dr.output.response, dr.output.err,
@@ -594,10 +950,11 @@ func TestClient_UploadReleaseAsset(t *testing.T) {
 			client := gh.Client{
 				Repository: mockRepo,
 				Clock:      tc.clock,
+				Retention:  tc.retention,
 			}
 
 			ctx := context.Background()
-			err = client.UploadReleaseAsset(ctx, filePaths)
+			err = client.UploadReleaseAsset(ctx, tc.channel, filePaths)
 
 			switch {
 			case tc.expectedError != nil:
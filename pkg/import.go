@@ -0,0 +1,52 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func importDB(c *cli.Context) error {
+	var r io.Reader = os.Stdin
+	if input := c.String("input"); input != "" && input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return xerrors.Errorf("failed to open %s: %w", input, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	// a gzipped export is recognized by its magic, stdin included
+	br := bufio.NewReader(r)
+	r = br
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return xerrors.Errorf("failed to open gzip: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	cacheDir := c.String("cache-dir")
+	if err := db.Init(cacheDir); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := (db.Config{}).Import(r); err != nil {
+		return err
+	}
+	log.Info("Imported the DB", "path", db.Path(cacheDir))
+	return nil
+}
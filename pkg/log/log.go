@@ -0,0 +1,82 @@
+// Package log is the leveled, structured logger of trivy-db. An application embedding its packages can
+// route the records with SetLogger, or give a db.Config and a source their own logger.
+package log
+
+import (
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/xerrors"
+)
+
+// logger is swapped atomically, so the packages can log while it is replaced
+var logger atomic.Pointer[slog.Logger]
+
+func init() {
+	logger.Store(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+}
+
+// SetLogger replaces the default logger of the packages, which may be logging meanwhile
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Logger returns the default logger of the packages
+func Logger() *slog.Logger {
+	return logger.Load()
+}
+
+// OrDefault returns l, the default logger if l is nil, e.g. for the logger of an instance
+func OrDefault(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return Logger()
+	}
+	return l
+}
+
+// New returns a logger writing the records of level and above to w, as text or json
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, xerrors.Errorf("invalid log level %s: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, xerrors.Errorf("unknown log format: %s", format)
+}
+
+// Discard returns a logger dropping every record, e.g. to silence tests
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(ioutil.Discard, nil))
+}
+
+// Err is the attribute of an error, logged by its message alone
+func Err(err error) slog.Attr {
+	return slog.String("err", err.Error())
+}
+
+func Debug(msg string, args ...interface{}) {
+	Logger().Debug(msg, args...)
+}
+
+func Info(msg string, args ...interface{}) {
+	Logger().Info(msg, args...)
+}
+
+func Warn(msg string, args ...interface{}) {
+	Logger().Warn(msg, args...)
+}
+
+func Error(msg string, args ...interface{}) {
+	Logger().Error(msg, args...)
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "text",
+			level:  "info",
+			format: "text",
+			want:   `level=WARN msg=warn err="failed: error"`,
+		},
+		{
+			name:   "json",
+			level:  "warn",
+			format: "json",
+			want:   `"level":"WARN","msg":"warn","err":"failed: error"}`,
+		},
+		{
+			name:    "unknown level",
+			level:   "verbose",
+			wantErr: "invalid log level verbose",
+		},
+		{
+			name:    "unknown format",
+			level:   "info",
+			format:  "yaml",
+			wantErr: "unknown log format: yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l, err := New(&buf, tt.level, tt.format)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			l.Debug("debug")
+			l.Warn("warn", Err(xerrors.Errorf("failed: %w", xerrors.New("error"))))
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			assert.Len(t, lines, 1)
+			assert.True(t, strings.HasSuffix(lines[0], tt.want), lines[0])
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "info", "json")
+	assert.NoError(t, err)
+	defer SetLogger(Logger())
+	SetLogger(l)
+
+	// nothing is counted without a progress
+	ProgressFrom(context.Background()).AddFiles(1)
+
+	ctx, progress := WithProgress(context.Background(), "alpine")
+	ProgressFrom(ctx).AddFiles(2)
+	ProgressFrom(ctx).AddRecords(3)
+	ProgressFrom(ctx).AddRecords(4)
+	ProgressFrom(ctx).AddErrors(1)
+	assert.Equal(t, int64(2), progress.Files())
+	assert.Equal(t, int64(7), progress.Records())
+	assert.Equal(t, int64(1), progress.Errors())
+
+	progress.Report("Updated")
+	var record struct {
+		Msg     string
+		Source  string
+		Files   int
+		Records int
+		Errors  int
+		Elapsed string
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "Updated", record.Msg)
+	assert.Equal(t, "alpine", record.Source)
+	assert.Equal(t, 2, record.Files)
+	assert.Equal(t, 7, record.Records)
+	assert.Equal(t, 1, record.Errors)
+	assert.NotEmpty(t, record.Elapsed)
+
+	progress.Reset()
+	assert.Equal(t, int64(0), progress.Files())
+	assert.Equal(t, int64(0), progress.Records())
+	assert.Equal(t, int64(0), progress.Errors())
+}
+
+func TestOrDefault(t *testing.T) {
+	assert.Equal(t, Logger(), OrDefault(nil))
+
+	l := Discard()
+	assert.Equal(t, l, OrDefault(l))
+}
@@ -0,0 +1,134 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type progressKey struct{}
+
+// Progress counts the files a source processed, the records it wrote and the invalid inputs it skipped,
+// for the reports of its update
+type Progress struct {
+	source  string
+	start   time.Time
+	files   int64
+	records int64
+	errors  int64
+}
+
+// WithProgress returns a copy of ctx counting the progress of source from now
+func WithProgress(ctx context.Context, source string) (context.Context, *Progress) {
+	p := &Progress{source: source, start: time.Now()}
+	return context.WithValue(ctx, progressKey{}, p), p
+}
+
+// ProgressFrom returns the progress counted in ctx, nil if there is none, which counts nothing
+func ProgressFrom(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
+
+// AddFiles counts processed files
+func (p *Progress) AddFiles(n int) {
+	if p == nil {
+		return
+	}
+	atomic.AddInt64(&p.files, int64(n))
+}
+
+// AddRecords counts committed records
+func (p *Progress) AddRecords(n int) {
+	if p == nil {
+		return
+	}
+	atomic.AddInt64(&p.records, int64(n))
+}
+
+// AddErrors counts inputs skipped as invalid
+func (p *Progress) AddErrors(n int) {
+	if p == nil {
+		return
+	}
+	atomic.AddInt64(&p.errors, int64(n))
+}
+
+// Files returns the number of processed files
+func (p *Progress) Files() int64 {
+	if p == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&p.files)
+}
+
+// Records returns the number of committed records
+func (p *Progress) Records() int64 {
+	if p == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&p.records)
+}
+
+// Errors returns the number of inputs skipped as invalid
+func (p *Progress) Errors() int64 {
+	if p == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&p.errors)
+}
+
+// Reset counts from zero again, e.g. for another attempt of the update, keeping the start
+func (p *Progress) Reset() {
+	if p == nil {
+		return
+	}
+	atomic.StoreInt64(&p.files, 0)
+	atomic.StoreInt64(&p.records, 0)
+	atomic.StoreInt64(&p.errors, 0)
+}
+
+// Elapsed returns the time elapsed since the start
+func (p *Progress) Elapsed() time.Duration {
+	if p == nil {
+		return 0
+	}
+	return time.Since(p.start)
+}
+
+// Report logs msg with the counts and the time elapsed since the start
+func (p *Progress) Report(msg string) {
+	if p == nil {
+		return
+	}
+	kv := []interface{}{"source", p.source, "files", p.Files(), "records", p.Records()}
+	if errors := p.Errors(); errors > 0 {
+		kv = append(kv, "errors", errors)
+	}
+	Logger().Info(msg, append(kv, "elapsed", p.Elapsed().Round(time.Millisecond).String())...)
+}
+
+// ReportEvery reports the progress every interval until stop is called
+func (p *Progress) ReportEvery(interval time.Duration) (stop func()) {
+	if p == nil || interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.Report("Updating")
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
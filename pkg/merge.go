@@ -0,0 +1,40 @@
+package pkg
+
+import (
+	"strings"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+// mergeDB merges the DB files of the arguments into a new DB in the cache dir, the later ones overriding the
+// earlier ones. An argument is a path or name=path, the name the advisories of the DB are traced to.
+func mergeDB(c *cli.Context) error {
+	var inputs []db.MergeInput
+	for _, arg := range c.Args() {
+		input := db.MergeInput{Name: arg, Path: arg}
+		if i := strings.Index(arg, "="); i > 0 {
+			input = db.MergeInput{Name: arg[:i], Path: arg[i+1:]}
+		}
+		inputs = append(inputs, input)
+	}
+
+	cacheDir := c.String("cache-dir")
+	if err := db.InitWithDriver(c.String("backend"), cacheDir); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stats, err := db.Config{}.Merge(c.String("backend"), inputs)
+	if err != nil {
+		return xerrors.Errorf("failed to merge the DBs: %w", err)
+	}
+	for _, s := range stats {
+		log.Info("Merged the DB", "name", s.Name, "records", s.Records, "overrides", s.Overrides)
+	}
+	log.Info("Merged the DBs", "path", db.Path(cacheDir))
+	return nil
+}
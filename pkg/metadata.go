@@ -0,0 +1,61 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/artifact"
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+func generateMetadata(c *cli.Context) error {
+	return describeDir(c.String("dir"), c.StringSlice("url-template"), time.Now().UTC())
+}
+
+// describeDir records the compressed DBs of dir in the metadata, keeping the signatures of the unchanged ones,
+// with their release channel, which they must share
+func describeDir(dir string, urlTemplates []string, now time.Time) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return xerrors.Errorf("unable to list files: %w", err)
+	}
+	metadata, err := artifact.ReadMetadata(dir)
+	if err != nil {
+		return err
+	}
+
+	var channel string
+	for _, f := range files {
+		if f.IsDir() || !artifact.IsArchive(f.Name()) {
+			continue
+		}
+		a, err := artifact.Describe(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return err
+		}
+		if c := a.DB.ReleaseChannel(); channel != "" && c != channel {
+			return xerrors.Errorf("%s is a DB of the %s channel, unlike the others of the %s channel", f.Name(), c,
+				channel)
+		}
+		channel = a.DB.ReleaseChannel()
+		if prev := metadata.Artifacts[f.Name()]; prev.Digest == a.Digest {
+			a.Signature = prev.Signature
+		}
+		metadata.Artifacts[f.Name()] = a
+		log.Info("Described an artifact", "file", f.Name(), "digest", a.Digest, "updated_at", a.DB.UpdatedAt)
+	}
+	metadata.SchemaVersion = db.SchemaVersion
+	metadata.GeneratedAt = now
+	if channel != "" {
+		metadata.Channel = channel
+	}
+	if len(urlTemplates) > 0 {
+		metadata.URLTemplates = urlTemplates
+	}
+	return metadata.Write(dir)
+}
@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/metrics"
+)
+
+const pushTimeout = 30 * time.Second
+
+// buildMetrics records the metrics of a build, served on --metrics-listen while it runs and pushed
+// to the Pushgateway of --metrics-push once it is over
+type buildMetrics struct {
+	registry *metrics.Registry
+	start    time.Time
+	server   *http.Server
+	gateway  string
+	job      string
+}
+
+func startMetrics(c *cli.Context) (*buildMetrics, error) {
+	m := &buildMetrics{
+		registry: metrics.NewRegistry(),
+		start:    time.Now(),
+		gateway:  c.String("metrics-push"),
+		job:      c.String("metrics-job"),
+	}
+	if addr := c.String("metrics-listen"); addr != "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.registry)
+		m.server = &http.Server{Handler: mux}
+		go func() {
+			if err := m.server.Serve(l); err != nil && err != http.ErrServerClosed {
+				log.Error("Failed to serve the metrics", log.Err(err))
+			}
+		}()
+		log.Info("Serving the metrics", "address", l.Addr().String())
+	}
+	return m, nil
+}
+
+// observe records how the update of a source went
+func (m *buildMetrics) observe(source string, progress *log.Progress, err error) {
+	r := m.registry
+	r.Set("trivy_db_source_records", "Records the source wrote in the last build.",
+		float64(progress.Records()), "source", source)
+	r.Set("trivy_db_source_files", "Files the source processed in the last build.",
+		float64(progress.Files()), "source", source)
+	r.Add("trivy_db_source_parse_errors_total", "Inputs of the source skipped as invalid.",
+		float64(progress.Errors()), "source", source)
+	r.Set("trivy_db_source_duration_seconds", "Duration of the update of the source.",
+		progress.Elapsed().Seconds(), "source", source)
+	r.Set("trivy_db_source_success", "Whether the update of the source succeeded.", boolValue(err == nil),
+		"source", source)
+}
+
+// observePass records how a pass of the optimization went
+func (m *buildMetrics) observePass(pass string, elapsed time.Duration, err error) {
+	r := m.registry
+	r.Set("trivy_db_optimize_pass_duration_seconds", "Duration of the pass of the optimization.",
+		elapsed.Seconds(), "pass", pass)
+	r.Set("trivy_db_optimize_pass_success", "Whether the pass of the optimization succeeded.", boolValue(err == nil),
+		"pass", pass)
+}
+
+// observeFreshness records how recent the records of each source are
+func (m *buildMetrics) observeFreshness(report []db.SourceFreshness) {
+	r := m.registry
+	for _, f := range report {
+		if !f.NewestRecordAt.IsZero() {
+			r.Set("trivy_db_source_newest_record_timestamp_seconds", "Time the source last added a record.",
+				float64(f.NewestRecordAt.Unix()), "source", f.Source)
+		}
+		r.Set("trivy_db_source_stale", "Whether the source added no record for longer than its threshold.",
+			boolValue(f.Stale), "source", f.Source)
+	}
+}
+
+// finish records the outcome of the build and the size of the DB at dbPath, pushes the metrics and stops
+// serving them
+func (m *buildMetrics) finish(dbPath string, err error) {
+	r := m.registry
+	r.Set("trivy_db_build_duration_seconds", "Duration of the last build.", time.Since(m.start).Seconds())
+	r.Set("trivy_db_build_success", "Whether the last build succeeded.", boolValue(err == nil))
+	if err == nil {
+		r.Set("trivy_db_build_last_success_timestamp_seconds", "Time the last successful build finished.",
+			float64(time.Now().Unix()))
+		if size, serr := pathSize(dbPath); serr != nil {
+			log.Warn("Failed to get the size of the DB", log.Err(serr))
+		} else {
+			r.Set("trivy_db_size_bytes", "Size of the built DB.", float64(size))
+		}
+	}
+
+	// the build may have been canceled, the metrics are pushed anyway
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	if m.gateway != "" {
+		if perr := r.Push(ctx, m.gateway, m.job); perr != nil {
+			log.Error("Failed to push the metrics", log.Err(perr))
+		} else {
+			log.Info("Pushed the metrics", "gateway", m.gateway, "job", m.job)
+		}
+	}
+	if m.server != nil {
+		_ = m.server.Shutdown(ctx)
+	}
+}
+
+// pathSize returns the size of the file at path, or of the files under it for a backend storing
+// the DB in a directory
+func pathSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+// Package metrics keeps the metrics of a build in a Prometheus registry, which is scraped from
+// an HTTP endpoint while the build runs or pushed to a Pushgateway once it is over.
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"golang.org/x/xerrors"
+)
+
+// textFormat is the text format of Prometheus, which is also pushed
+var textFormat = expfmt.NewFormat(expfmt.TypeTextPlain)
+
+// Registry holds the samples of the metrics, safe for concurrent use
+type Registry struct {
+	mu       sync.Mutex
+	registry *prometheus.Registry
+	gauges   map[string]*prometheus.GaugeVec
+	counters map[string]*prometheus.CounterVec
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		registry: prometheus.NewRegistry(),
+		gauges:   map[string]*prometheus.GaugeVec{},
+		counters: map[string]*prometheus.CounterVec{},
+	}
+}
+
+// Set sets the gauge name with the labels, given as name and value pairs.
+// The label names of a metric are the ones of its first sample.
+func (r *Registry) Set(name, help string, value float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names, values := splitLabels(labels)
+	g, ok := r.gauges[name]
+	if !ok {
+		g = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, names)
+		r.registry.MustRegister(g)
+		r.gauges[name] = g
+	}
+	g.With(values).Set(value)
+}
+
+// Add adds delta to the counter name with the labels, given as name and value pairs.
+// The label names of a metric are the ones of its first sample.
+func (r *Registry) Add(name, help string, delta float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names, values := splitLabels(labels)
+	c, ok := r.counters[name]
+	if !ok {
+		c = prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, names)
+		r.registry.MustRegister(c)
+		r.counters[name] = c
+	}
+	c.With(values).Add(delta)
+}
+
+// Value returns the sample of name with the labels, and whether there is one
+func (r *Registry) Value(name string, labels ...string) (float64, bool) {
+	families, err := r.registry.Gather()
+	if err != nil {
+		return 0, false
+	}
+	_, want := splitLabels(labels)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			if !labelsEqual(m.GetLabel(), want) {
+				continue
+			}
+			if m.GetCounter() != nil {
+				return m.GetCounter().GetValue(), true
+			}
+			return m.GetGauge().GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+// Write writes the metrics in the text format, sorted by name and labels
+func (r *Registry) Write(w io.Writer) error {
+	families, err := r.registry.Gather()
+	if err != nil {
+		return xerrors.Errorf("failed to gather the metrics: %w", err)
+	}
+	enc := expfmt.NewEncoder(w, textFormat)
+	for _, f := range families {
+		if err = enc.Encode(f); err != nil {
+			return xerrors.Errorf("failed to encode the metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the metrics to a scrape
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{}).ServeHTTP(w, req)
+}
+
+// Push replaces the metrics of job in the Pushgateway at gateway, e.g. http://pushgateway:9091
+func (r *Registry) Push(ctx context.Context, gateway, job string) error {
+	if job == "" {
+		return xerrors.New("no job")
+	}
+	err := push.New(gateway, job).Gatherer(r.registry).Format(textFormat).PushContext(ctx)
+	if err != nil {
+		return xerrors.Errorf("failed to push the metrics: %w", err)
+	}
+	return nil
+}
+
+// splitLabels splits name and value pairs into the names, in order, and the labels
+func splitLabels(labels []string) ([]string, prometheus.Labels) {
+	var names []string
+	values := prometheus.Labels{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		names = append(names, labels[i])
+		values[labels[i]] = labels[i+1]
+	}
+	return names, values
+}
+
+func labelsEqual(pairs []*dto.LabelPair, labels prometheus.Labels) bool {
+	if len(pairs) != len(labels) {
+		return false
+	}
+	for _, p := range pairs {
+		if v, ok := labels[p.GetName()]; !ok || v != p.GetValue() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	r.Set("trivy_db_source_records", "Records the source wrote.", 42, "source", "nvd")
+	r.Set("trivy_db_source_records", "Records the source wrote.", 3, "source", `alp"ine`)
+	r.Add("trivy_db_source_parse_errors_total", "Inputs skipped.", 1, "source", "nvd")
+	r.Add("trivy_db_source_parse_errors_total", "Inputs skipped.", 2, "source", "nvd")
+	r.Set("trivy_db_build_duration_seconds", "Duration of the build.", 1.5)
+
+	var buf bytes.Buffer
+	assert.NoError(t, r.Write(&buf))
+	want := `# HELP trivy_db_build_duration_seconds Duration of the build.
+# TYPE trivy_db_build_duration_seconds gauge
+trivy_db_build_duration_seconds 1.5
+# HELP trivy_db_source_parse_errors_total Inputs skipped.
+# TYPE trivy_db_source_parse_errors_total counter
+trivy_db_source_parse_errors_total{source="nvd"} 3
+# HELP trivy_db_source_records Records the source wrote.
+# TYPE trivy_db_source_records gauge
+trivy_db_source_records{source="alp\"ine"} 3
+trivy_db_source_records{source="nvd"} 42
+`
+	assert.Equal(t, want, buf.String())
+
+	v, ok := r.Value("trivy_db_source_parse_errors_total", "source", "nvd")
+	assert.True(t, ok)
+	assert.Equal(t, 3.0, v)
+	_, ok = r.Value("trivy_db_source_records", "source", "alpine")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Push(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		job     string
+		wantErr string
+	}{
+		{
+			name:   "happy path",
+			status: http.StatusOK,
+			job:    "trivy-db",
+		},
+		{
+			name:    "rejected",
+			status:  http.StatusBadRequest,
+			job:     "trivy-db",
+			wantErr: "unexpected status code 400",
+		},
+		{
+			name:    "no job",
+			wantErr: "no job",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, path, contentType, body string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, path, contentType = r.Method, r.URL.Path, r.Header.Get("Content-Type")
+				b, _ := ioutil.ReadAll(r.Body)
+				body = string(b)
+				w.WriteHeader(tt.status)
+				if tt.status != http.StatusOK {
+					_, _ = w.Write([]byte("invalid metric\n"))
+				}
+			}))
+			defer ts.Close()
+
+			r := NewRegistry()
+			r.Set("trivy_db_build_success", "Whether the build succeeded.", 1)
+			err := r.Push(context.Background(), ts.URL+"/", tt.job)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, http.MethodPut, method)
+			assert.Equal(t, "/metrics/job/trivy-db", path)
+			assert.Equal(t, string(textFormat), contentType)
+			assert.Contains(t, body, "trivy_db_build_success 1\n")
+		})
+	}
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Set("trivy_db_size_bytes", "Size of the DB.", 65536)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	// the scrape negotiates the format, the text one by default
+	assert.Contains(t, resp.Header.Get("Content-Type"), string(textFormat))
+	assert.Contains(t, string(b), "trivy_db_size_bytes 65536\n")
+}
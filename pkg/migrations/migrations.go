@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// Migration upgrades a DB from Version-1 to Version in place
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(tx storage.Tx) error
+}
+
+type Migrations []Migration
+
+// All is the list of migrations in version order. Version 1 is the first schema, so the list starts at 2.
+var All = Migrations{
+	{Version: 2, Description: "namespace the advisory buckets by data source", Migrate: namespaceAdvisoryBuckets},
+}
+
+// Latest returns the schema version the migrations lead to
+func (ms Migrations) Latest() int {
+	if len(ms) == 0 {
+		return 1
+	}
+	return ms[len(ms)-1].Version
+}
+
+// Run migrates the DB from the given schema version to the target one in the transaction
+func (ms Migrations) Run(tx storage.Tx, from, to int) error {
+	if from > to {
+		return xerrors.Errorf("the DB schema v%d is newer than v%d", from, to)
+	}
+	if to > ms.Latest() {
+		return xerrors.Errorf("no migration to schema v%d", to)
+	}
+
+	version := from
+	for _, m := range ms {
+		if m.Version <= from || m.Version > to {
+			continue
+		}
+		if m.Version != version+1 {
+			return xerrors.Errorf("no migration from schema v%d to v%d, the DB needs to be rebuilt", version, version+1)
+		}
+		log.Info("Migrating the DB", "version", m.Version, "description", m.Description)
+		if err := m.Migrate(tx); err != nil {
+			return xerrors.Errorf("failed to migrate to schema v%d: %w", m.Version, err)
+		}
+		version = m.Version
+	}
+	if version != to {
+		return xerrors.Errorf("no migration from schema v%d to v%d, the DB needs to be rebuilt", version, to)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package migrations
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+func TestMigrations_Run(t *testing.T) {
+	testCases := []struct {
+		name             string
+		versions         []int
+		from             int
+		to               int
+		migrateErr       error
+		expectedRun      []int
+		expectedErrorMsg string
+	}{
+		{
+			name:        "happy path",
+			versions:    []int{2, 3, 4},
+			from:        2,
+			to:          4,
+			expectedRun: []int{3, 4},
+		},
+		{
+			name:     "up to date",
+			versions: []int{2, 3},
+			from:     3,
+			to:       3,
+		},
+		{
+			name:        "not to the latest",
+			versions:    []int{2, 3, 4},
+			from:        1,
+			to:          3,
+			expectedRun: []int{2, 3},
+		},
+		{
+			name:             "missing migration",
+			versions:         []int{2, 4},
+			from:             1,
+			to:               4,
+			expectedRun:      []int{2},
+			expectedErrorMsg: "no migration from schema v2 to v3, the DB needs to be rebuilt",
+		},
+		{
+			name:             "newer schema",
+			versions:         []int{2},
+			from:             3,
+			to:               2,
+			expectedErrorMsg: "the DB schema v3 is newer than v2",
+		},
+		{
+			name:             "unknown target",
+			versions:         []int{2},
+			from:             1,
+			to:               3,
+			expectedErrorMsg: "no migration to schema v3",
+		},
+		{
+			name:             "migration fails",
+			versions:         []int{2, 3},
+			from:             1,
+			to:               3,
+			migrateErr:       errors.New("boom"),
+			expectedRun:      []int{2},
+			expectedErrorMsg: "failed to migrate to schema v2: boom",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var run []int
+			var ms Migrations
+			for _, v := range tc.versions {
+				v := v
+				ms = append(ms, Migration{
+					Version: v,
+					Migrate: func(tx storage.Tx) error {
+						run = append(run, v)
+						return tc.migrateErr
+					},
+				})
+			}
+
+			err := ms.Run(&storage.MockTx{}, tc.from, tc.to)
+			switch {
+			case tc.expectedErrorMsg != "":
+				assert.EqualError(t, err, tc.expectedErrorMsg, tc.name)
+			default:
+				assert.NoError(t, err, tc.name)
+			}
+			assert.Equal(t, tc.expectedRun, run, tc.name)
+		})
+	}
+}
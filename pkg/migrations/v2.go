@@ -0,0 +1,158 @@
+package migrations
+
+import (
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// the layout of schema v1, which the migration to v2 reads
+var (
+	v1NonAdvisoryBuckets = []string{"trivy", "vulnerability", "vulnerability-detail", "severity", "ssvc",
+		"affected-package", "cpe", "shared-detail"}
+
+	// platform prefix => data source ID, the first match wins
+	v1DataSources = []struct {
+		prefix string
+		id     string
+	}{
+		{prefix: "alpine ", id: "alpine"},
+		{prefix: "amazon linux ", id: "amazon"},
+		{prefix: "debian oval ", id: "debian-oval"},
+		{prefix: "debian ", id: "debian"},
+		{prefix: "ubuntu ", id: "ubuntu"},
+		{prefix: "Red Hat Enterprise Linux ", id: "redhat"},
+		{prefix: "Oracle Linux ", id: "oracle-oval"},
+	}
+)
+
+// namespaceAdvisoryBuckets renames the advisory buckets, e.g. amazon linux 2, to <data source ID>::<platform>,
+// e.g. amazon::amazon linux 2, along with the keys referring to them
+func namespaceAdvisoryBuckets(tx storage.Tx) error {
+	renamed := map[string]string{}
+	err := tx.ForEach(func(name []byte, _ storage.Bucket) error {
+		if !isV1NonAdvisoryBucket(string(name)) {
+			renamed[string(name)] = v1DataSource(string(name)) + "::" + string(name)
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to list the buckets: %w", err)
+	}
+
+	for old, name := range renamed {
+		if err = renameBucket(tx, old, name); err != nil {
+			return xerrors.Errorf("failed to rename %s: %w", old, err)
+		}
+	}
+
+	// affected-package => CVE-ID => <platform>/<package>
+	if err = renameNestedKeys(tx.Bucket([]byte("affected-package")), func(k string) string {
+		if i := strings.Index(k, "/"); i >= 0 && renamed[k[:i]] != "" {
+			return renamed[k[:i]] + k[i:]
+		}
+		return k
+	}); err != nil {
+		return xerrors.Errorf("failed to rename the affected packages: %w", err)
+	}
+
+	// trivy => written => source => <platform>\x00<package>\x00<CVE-ID>
+	var written storage.Bucket
+	if root := tx.Bucket([]byte("trivy")); root != nil {
+		written = root.Bucket([]byte("written"))
+	}
+	if err = renameNestedKeys(written, func(k string) string {
+		if i := strings.Index(k, "\x00"); i >= 0 && renamed[k[:i]] != "" {
+			return renamed[k[:i]] + k[i:]
+		}
+		return k
+	}); err != nil {
+		return xerrors.Errorf("failed to rename the records of the written advisories: %w", err)
+	}
+	return nil
+}
+
+func isV1NonAdvisoryBucket(name string) bool {
+	for _, b := range v1NonAdvisoryBuckets {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// v1DataSource returns the data source of a platform, the language sources are named after their data source
+func v1DataSource(platform string) string {
+	for _, ds := range v1DataSources {
+		if strings.HasPrefix(platform, ds.prefix) {
+			return ds.id
+		}
+	}
+	return platform
+}
+
+func renameBucket(tx storage.Tx, old, name string) error {
+	dst, err := tx.CreateBucketIfNotExists([]byte(name))
+	if err != nil {
+		return err
+	}
+	if err = copyBucket(dst, tx.Bucket([]byte(old))); err != nil {
+		return err
+	}
+	return tx.DeleteBucket([]byte(old))
+}
+
+func copyBucket(dst, src storage.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		nested, err := dst.CreateBucketIfNotExists(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(nested, src.Bucket(k))
+	})
+}
+
+// renameNestedKeys renames the keys of the nested buckets of a root bucket, which may be nil
+func renameNestedKeys(root storage.Bucket, rename func(string) string) error {
+	if root == nil {
+		return nil
+	}
+	var names [][]byte
+	err := root.ForEach(func(k, v []byte) error {
+		if v == nil {
+			names = append(names, append([]byte{}, k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		b := root.Bucket(name)
+		values := map[string][]byte{}
+		err = b.ForEach(func(k, v []byte) error {
+			if v != nil && rename(string(k)) != string(k) {
+				values[string(k)] = append([]byte{}, v...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for k, v := range values {
+			if err = b.Delete([]byte(k)); err != nil {
+				return err
+			}
+			if err = b.Put([]byte(rename(k)), v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package pkg
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/artifact"
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/notify"
+	"github.com/aquasecurity/trivy-db/pkg/oci"
+	"github.com/aquasecurity/trivy-db/pkg/pipeline"
+)
+
+// fetchBaseline downloads the baseline of the notifications into dir, before the publication replaces it.
+// Without one, or if it can't be fetched, the summary has no highlights.
+func fetchBaseline(ctx context.Context, config notify.Config, dir string) string {
+	if config.Baseline == "" {
+		return ""
+	}
+	path, err := artifact.Fetch(ctx, oci.NewClient(), config.Baseline, dir)
+	if err != nil {
+		log.Warn("Failed to fetch the baseline, the summary has no highlights", "baseline", config.Baseline,
+			log.Err(err))
+		return ""
+	}
+	return path
+}
+
+// notifyPublished reports the publication of the DB built in the cache dir of the config to its hooks. A hook
+// which fails is logged, the DB being published anyway.
+func notifyPublished(ctx context.Context, config pipeline.Config, notifiers []notify.Notifier, baseline string,
+	filePaths, destinations []string) {
+	dbPath := db.Path(config.CacheDir)
+	metadata, err := db.ReadMetadata(config.Build.Backend, dbPath)
+	if err != nil {
+		log.Error("Failed to read the metadata of the published DB, no hook is notified", log.Err(err))
+		return
+	}
+	var diff *db.DiffReport
+	if baseline != "" {
+		report, err := db.Diff(config.Build.Backend, baseline, dbPath)
+		if err != nil {
+			log.Warn("Failed to diff the DB with the baseline, the summary has no highlights", log.Err(err))
+		} else {
+			diff = &report
+		}
+	}
+
+	var files []string
+	for _, path := range filePaths {
+		files = append(files, filepath.Base(path))
+	}
+	summary := notify.NewSummary(metadata, files, destinations, diff)
+	for i, n := range notifiers {
+		hook := config.Notifications.Hooks[i].String()
+		if err = n.Notify(ctx, summary); err != nil {
+			log.Error("Failed to notify the hook", "hook", hook, log.Err(err))
+			continue
+		}
+		log.Info("Notified the hook", "hook", hook)
+	}
+}
+
+// withBaseline runs fn with the baseline of the notifications fetched into a temp dir, if there are hooks
+func withBaseline(ctx context.Context, config pipeline.Config, fn func(baseline string) error) error {
+	if len(config.Notifications.Hooks) == 0 {
+		return fn("")
+	}
+	dir, err := ioutil.TempDir("", "trivy-db-baseline-")
+	if err != nil {
+		return xerrors.Errorf("failed to create a temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	return fn(fetchBaseline(ctx, config.Notifications, dir))
+}
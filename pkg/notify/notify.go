@@ -0,0 +1,227 @@
+// Package notify reports the publication of a DB to hooks, e.g. a Slack channel, so that its consumers learn
+// that a fresh DB is available
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// Config is the notification part of the pipeline config, see pipeline.Config, e.g.
+//
+//	notifications:
+//	  baseline: oci://ghcr.io/aquasecurity/trivy-db:2
+//	  hooks:
+//	    - type: slack
+//	      url-env: SLACK_WEBHOOK_URL
+//	    - type: webhook
+//	      url: https://example.com/hooks/trivy-db
+//	      headers:
+//	        X-Source: trivy-db
+type Config struct {
+	// Baseline is the DB the highlights compare the published one with, e.g. the previous release,
+	// fetched before publishing. There are no highlights if empty.
+	Baseline string       `yaml:"baseline,omitempty"`
+	Hooks    []HookConfig `yaml:"hooks,omitempty"`
+}
+
+// HookConfig is a destination of the summaries
+type HookConfig struct {
+	Type string `yaml:"type"` // webhook or slack
+	URL  string `yaml:"url,omitempty"`
+	// URLEnv is the environment variable holding the URL in place of URL, e.g. for a Slack webhook,
+	// whose URL is a secret
+	URLEnv  string            `yaml:"url-env,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"` // webhook only
+}
+
+// Validate checks the config without reading the environment
+func (c HookConfig) Validate() error {
+	switch c.Type {
+	case "webhook":
+	case "slack":
+		if len(c.Headers) > 0 {
+			return xerrors.New("headers of a slack hook")
+		}
+	default:
+		return xerrors.Errorf("unknown type %q", c.Type)
+	}
+	if (c.URL == "") == (c.URLEnv == "") {
+		return xerrors.New("either url or url-env is required")
+	}
+	return nil
+}
+
+// String is the destination, which leaves a secret URL out
+func (c HookConfig) String() string {
+	if c.URLEnv != "" {
+		return c.Type + " ($" + c.URLEnv + ")"
+	}
+	return c.Type + " (" + c.URL + ")"
+}
+
+// Summary is what the hooks report of a published DB
+type Summary struct {
+	Version    int
+	Type       string // full or light
+	UpdatedAt  time.Time
+	NextUpdate time.Time
+	Sources    int
+	// Files are the names of the published files, e.g. trivy.db.gz
+	Files []string
+	// Destinations are where they were published, e.g. s3://mirror/trivy-db/
+	Destinations []string
+	Highlights   *Highlights `json:",omitempty"` // nil without a baseline
+}
+
+// Highlights sum up the diff of the published DB with the baseline
+type Highlights struct {
+	BaselineUpdatedAt      time.Time
+	AddedVulnerabilities   int
+	RemovedVulnerabilities int
+	AddedAdvisories        int
+	RemovedAdvisories      int
+	NewCritical            int
+	// AddedSeverities counts the added vulnerabilities by severity
+	AddedSeverities map[string]int `json:",omitempty"`
+}
+
+// NewSummary sums up the DB whose metadata is given, and its diff with the baseline if any
+func NewSummary(metadata db.Metadata, files, destinations []string, diff *db.DiffReport) Summary {
+	s := Summary{
+		Version:      metadata.Version,
+		Type:         "full",
+		UpdatedAt:    metadata.UpdatedAt,
+		NextUpdate:   metadata.NextUpdate,
+		Sources:      len(metadata.Sources),
+		Files:        files,
+		Destinations: destinations,
+	}
+	if metadata.Type == db.TypeLight {
+		s.Type = "light"
+	}
+	if diff == nil {
+		return s
+	}
+
+	h := &Highlights{
+		BaselineUpdatedAt:      diff.From.UpdatedAt,
+		AddedVulnerabilities:   len(diff.Vulnerabilities.Added),
+		RemovedVulnerabilities: len(diff.Vulnerabilities.Removed),
+		NewCritical:            diff.AddedSeverities[types.SeverityCritical.String()],
+		AddedSeverities:        diff.AddedSeverities,
+	}
+	for _, source := range diff.Sources {
+		h.AddedAdvisories += len(source.Added)
+		h.RemovedAdvisories += len(source.Removed)
+	}
+	s.Highlights = h
+	return s
+}
+
+// Text is a line per fact of the summary, for the hooks of a chat
+func (s Summary) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Trivy DB v%d (%s) updated at %s is published", s.Version, s.Type,
+		s.UpdatedAt.UTC().Format(time.RFC3339))
+	if len(s.Destinations) > 0 {
+		fmt.Fprintf(&b, " to %s", strings.Join(s.Destinations, ", "))
+	}
+	fmt.Fprintf(&b, "\nFiles: %s", strings.Join(s.Files, ", "))
+	if h := s.Highlights; h != nil {
+		fmt.Fprintf(&b, "\nSince %s: %d new vulnerabilities, %d of them critical, %d removed; "+
+			"%d advisories added, %d removed", h.BaselineUpdatedAt.UTC().Format(time.RFC3339), h.AddedVulnerabilities,
+			h.NewCritical, h.RemovedVulnerabilities, h.AddedAdvisories, h.RemovedAdvisories)
+	}
+	return b.String()
+}
+
+// Notifier sends the summary to a hook
+type Notifier interface {
+	Notify(ctx context.Context, summary Summary) error
+}
+
+// New returns the notifiers of the hooks, in order
+func New(config Config) ([]Notifier, error) {
+	var notifiers []Notifier
+	for i, c := range config.Hooks {
+		if err := c.Validate(); err != nil {
+			return nil, xerrors.Errorf("hook %d: %w", i, err)
+		}
+		endpoint := c.URL
+		if c.URLEnv != "" {
+			if endpoint = os.Getenv(c.URLEnv); endpoint == "" {
+				return nil, xerrors.Errorf("hook %d: %s is not set", i, c.URLEnv)
+			}
+		}
+		switch c.Type {
+		case "webhook":
+			notifiers = append(notifiers, Webhook{URL: endpoint, Headers: c.Headers})
+		case "slack":
+			notifiers = append(notifiers, Slack{URL: endpoint})
+		}
+	}
+	return notifiers, nil
+}
+
+// Webhook posts the summary as JSON
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+}
+
+func (w Webhook) Notify(ctx context.Context, summary Summary) error {
+	return post(ctx, w.URL, w.Headers, summary)
+}
+
+// Slack posts the text of the summary to an incoming webhook of Slack
+type Slack struct {
+	URL string
+}
+
+func (s Slack) Notify(ctx context.Context, summary Summary) error {
+	return post(ctx, s.URL, nil, map[string]string{"text": summary.Text()})
+}
+
+func post(ctx context.Context, endpoint string, headers map[string]string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return xerrors.Errorf("failed to encode the summary: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return xerrors.Errorf("invalid hook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		// leaves out the URL, which may be a secret
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return xerrors.Errorf("failed to post the summary: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return xerrors.Errorf("the hook responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
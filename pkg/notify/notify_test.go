@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+)
+
+func TestNewSummary(t *testing.T) {
+	builtAt := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	metadata := db.Metadata{Version: 2, Type: db.TypeLight, UpdatedAt: builtAt,
+		Sources: map[string]db.SourceMetadata{"alpine": {}, "nvd": {}}}
+	diff := &db.DiffReport{
+		From: db.Metadata{UpdatedAt: builtAt.Add(-24 * time.Hour)},
+		Sources: []db.SourceDiff{
+			{Source: "alpine::alpine 3.10", Added: []db.AdvisoryID{{Package: "openssl", VulnerabilityID: "CVE-2019-0001"}}},
+			{Source: "alpine::alpine 3.11", Added: []db.AdvisoryID{{Package: "openssl", VulnerabilityID: "CVE-2019-0001"}},
+				Removed: []db.AdvisoryID{{Package: "musl", VulnerabilityID: "CVE-2019-0002"}}},
+		},
+		Vulnerabilities: db.Changes{Added: []string{"CVE-2019-0001", "CVE-2019-0003"}},
+		AddedSeverities: map[string]int{"CRITICAL": 1, "LOW": 1},
+	}
+
+	got := NewSummary(metadata, []string{"trivy-light.db.gz"}, []string{"s3://mirror/"}, diff)
+	assert.Equal(t, Summary{
+		Version:      2,
+		Type:         "light",
+		UpdatedAt:    builtAt,
+		Sources:      2,
+		Files:        []string{"trivy-light.db.gz"},
+		Destinations: []string{"s3://mirror/"},
+		Highlights: &Highlights{
+			BaselineUpdatedAt:    builtAt.Add(-24 * time.Hour),
+			AddedVulnerabilities: 2,
+			AddedAdvisories:      2,
+			RemovedAdvisories:    1,
+			NewCritical:          1,
+			AddedSeverities:      map[string]int{"CRITICAL": 1, "LOW": 1},
+		},
+	}, got)
+	assert.Equal(t, "Trivy DB v2 (light) updated at 2020-01-02T00:00:00Z is published to s3://mirror/\n"+
+		"Files: trivy-light.db.gz\n"+
+		"Since 2020-01-01T00:00:00Z: 2 new vulnerabilities, 1 of them critical, 0 removed; 2 advisories added, 1 removed",
+		got.Text())
+
+	// no baseline, no highlights
+	assert.Nil(t, NewSummary(metadata, nil, nil, nil).Highlights)
+}
+
+func TestNew(t *testing.T) {
+	os.Setenv("TEST_NOTIFY_URL", "https://hooks.slack.com/services/x")
+	defer os.Unsetenv("TEST_NOTIFY_URL")
+
+	tests := []struct {
+		name    string
+		hooks   []HookConfig
+		want    []Notifier
+		wantErr string
+	}{
+		{
+			name: "happy path",
+			hooks: []HookConfig{
+				{Type: "webhook", URL: "https://example.com/hook", Headers: map[string]string{"X-Token": "t"}},
+				{Type: "slack", URLEnv: "TEST_NOTIFY_URL"},
+			},
+			want: []Notifier{
+				Webhook{URL: "https://example.com/hook", Headers: map[string]string{"X-Token": "t"}},
+				Slack{URL: "https://hooks.slack.com/services/x"},
+			},
+		},
+		{
+			name:    "unset env",
+			hooks:   []HookConfig{{Type: "slack", URLEnv: "TEST_NOTIFY_UNSET"}},
+			wantErr: "hook 0: TEST_NOTIFY_UNSET is not set",
+		},
+		{
+			name:    "unknown type",
+			hooks:   []HookConfig{{Type: "email", URL: "mailto:security@example.com"}},
+			wantErr: `hook 0: unknown type "email"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(Config{Hooks: tt.hooks})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	summary := Summary{Version: 2, Type: "full", Files: []string{"trivy.db.gz"}}
+	tests := []struct {
+		name     string
+		notifier func(url string) Notifier
+		status   int
+		wantBody string
+		wantErr  bool
+	}{
+		{
+			name: "webhook",
+			notifier: func(url string) Notifier {
+				return Webhook{URL: url, Headers: map[string]string{"X-Token": "secret"}}
+			},
+			status: http.StatusOK,
+			wantBody: `{"Version": 2, "Type": "full", "UpdatedAt": "0001-01-01T00:00:00Z",
+				"NextUpdate": "0001-01-01T00:00:00Z", "Sources": 0, "Files": ["trivy.db.gz"], "Destinations": null}`,
+		},
+		{
+			name: "slack",
+			notifier: func(url string) Notifier {
+				return Slack{URL: url}
+			},
+			status: http.StatusOK,
+			wantBody: `{"text": "Trivy DB v2 (full) updated at 0001-01-01T00:00:00Z is published\n` +
+				`Files: trivy.db.gz"}`,
+		},
+		{
+			name: "failing hook",
+			notifier: func(url string) Notifier {
+				return Slack{URL: url}
+			},
+			status:  http.StatusForbidden,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body json.RawMessage
+			var token string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				token = r.Header.Get("X-Token")
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			err := tt.notifier(ts.URL).Notify(context.Background(), summary)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.wantBody, string(body))
+			if _, ok := tt.notifier(ts.URL).(Webhook); ok {
+				assert.Equal(t, "secret", token)
+			}
+		})
+	}
+}
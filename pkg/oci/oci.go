@@ -0,0 +1,197 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	godigest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/xerrors"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+const (
+	MediaTypeManifest     = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerSchema = "application/vnd.docker.distribution.manifest.v2+json"
+	// MediaTypeEmpty is the config of the artifacts without one, e.g. the ones attached to another
+	MediaTypeEmpty = "application/vnd.oci.empty.v1+json"
+
+	defaultRegistry = "docker.io"
+)
+
+// Reference is a tagged or digested artifact in a repository, e.g. ghcr.io/aquasecurity/trivy-db:2
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string `json:",omitempty"`
+	Digest     string `json:",omitempty"` // e.g. sha256:..., which takes precedence over the tag
+}
+
+// ParseReference parses a reference the way docker does: the registry is docker.io if the first component
+// isn't a host name, and the tag is latest if there is neither a tag nor a digest
+func ParseReference(s string) (Reference, error) {
+	var ref Reference
+	if i := strings.Index(s, "@"); i >= 0 {
+		s, ref.Digest = s[:i], s[i+1:]
+		if !strings.HasPrefix(ref.Digest, "sha256:") {
+			return Reference{}, xerrors.Errorf("unsupported digest: %s", ref.Digest)
+		}
+	}
+	if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") {
+		s, ref.Tag = s[:i], s[i+1:]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	ref.Registry, ref.Repository = defaultRegistry, s
+	if i := strings.Index(s, "/"); i >= 0 {
+		if host := s[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry, ref.Repository = host, s[i+1:]
+		}
+	}
+	if ref.Registry == defaultRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	if ref.Repository == "" {
+		return Reference{}, xerrors.Errorf("no repository: %s", s)
+	}
+	return ref, nil
+}
+
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Descriptor is a content addressed blob of an artifact
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an image or artifact manifest
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	ArtifactType  string       `json:"artifactType,omitempty"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+	// Subject is the manifest an artifact is attached to, which lists it with the referrers API
+	Subject     *Descriptor       `json:"subject,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Client talks to registries implementing the OCI distribution API, anonymously unless a username is set
+type Client struct {
+	HTTPClient *http.Client
+	PlainHTTP  bool // for local registries without TLS
+	Username   string
+	Password   string
+}
+
+// NewClient returns an anonymous client using the default HTTP client
+func NewClient() Client {
+	return Client{HTTPClient: http.DefaultClient}
+}
+
+// Pull writes the first layer of the artifact, which holds the DB in the artifacts of trivy-db, to w,
+// checking its digest, and returns its descriptor
+func (c Client) Pull(ctx context.Context, ref Reference, w io.Writer) (Descriptor, error) {
+	repo, err := c.repository(ref)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	manifest, err := fetchManifest(ctx, repo, ref)
+	if err != nil {
+		return Descriptor{}, xerrors.Errorf("failed to get the manifest of %s: %w", ref, err)
+	}
+	if len(manifest.Layers) == 0 {
+		return Descriptor{}, xerrors.Errorf("%s has no layer", ref)
+	}
+	layer := manifest.Layers[0]
+	if err = fetchBlob(ctx, repo, layer, w); err != nil {
+		return Descriptor{}, xerrors.Errorf("failed to get the layer of %s: %w", ref, err)
+	}
+	return descriptor(layer), nil
+}
+
+// repository returns the repository of ref, whose credentials are the ones of the client
+func (c Client) repository(ref Reference) (*remote.Repository, error) {
+	repo, err := remote.NewRepository(ref.Registry + "/" + ref.Repository)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid repository %s: %w", ref, err)
+	}
+	client := &auth.Client{Client: c.HTTPClient, Cache: auth.NewCache()}
+	if c.Username != "" {
+		client.Credential = auth.StaticCredential(ref.Registry, auth.Credential{Username: c.Username, Password: c.Password})
+	}
+	repo.Client, repo.PlainHTTP = client, c.PlainHTTP
+	// an index of the manifests of several platforms isn't used for artifacts
+	repo.ManifestMediaTypes = []string{MediaTypeManifest, MediaTypeDockerSchema}
+	return repo, nil
+}
+
+func fetchManifest(ctx context.Context, repo *remote.Repository, ref Reference) (ocispec.Manifest, error) {
+	name := ref.Tag
+	if ref.Digest != "" {
+		name = ref.Digest
+	}
+	desc, rc, err := repo.FetchReference(ctx, name)
+	if err != nil {
+		return ocispec.Manifest{}, err
+	}
+	defer rc.Close()
+	if desc.MediaType != MediaTypeManifest && desc.MediaType != MediaTypeDockerSchema {
+		return ocispec.Manifest{}, xerrors.Errorf("unsupported media type: %s", desc.MediaType)
+	}
+	b, err := content.ReadAll(rc, desc)
+	if err != nil {
+		return ocispec.Manifest{}, xerrors.Errorf("failed to read the manifest: %w", err)
+	}
+	var manifest ocispec.Manifest
+	if err = json.Unmarshal(b, &manifest); err != nil {
+		return ocispec.Manifest{}, xerrors.Errorf("invalid manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// fetchBlob writes the blob to w, failing if it doesn't match its size and digest
+func fetchBlob(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor, w io.Writer) error {
+	rc, err := repo.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	vr := content.NewVerifyReader(rc, desc)
+	if _, err = io.Copy(w, vr); err != nil {
+		return xerrors.Errorf("failed to read the blob: %w", err)
+	}
+	return vr.Verify()
+}
+
+func descriptor(desc ocispec.Descriptor) Descriptor {
+	return Descriptor{MediaType: desc.MediaType, Digest: desc.Digest.String(), Size: desc.Size, Annotations: desc.Annotations}
+}
+
+func (d Descriptor) oci() ocispec.Descriptor {
+	return ocispec.Descriptor{MediaType: d.MediaType, Digest: godigest.Digest(d.Digest), Size: d.Size,
+		Annotations: d.Annotations}
+}
+
+func digest(b []byte) string {
+	return godigest.FromBytes(b).String()
+}
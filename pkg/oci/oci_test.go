@@ -0,0 +1,118 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		want    Reference
+		wantErr string
+	}{
+		{
+			name: "tag",
+			ref:  "ghcr.io/aquasecurity/trivy-db:2",
+			want: Reference{Registry: "ghcr.io", Repository: "aquasecurity/trivy-db", Tag: "2"},
+		},
+		{
+			name: "port and no tag",
+			ref:  "localhost:5000/trivy-db",
+			want: Reference{Registry: "localhost:5000", Repository: "trivy-db", Tag: "latest"},
+		},
+		{
+			name: "docker hub",
+			ref:  "alpine@sha256:abc",
+			want: Reference{Registry: "docker.io", Repository: "library/alpine", Digest: "sha256:abc"},
+		},
+		{
+			name:    "unsupported digest",
+			ref:     "ghcr.io/aquasecurity/trivy-db@md5:abc",
+			wantErr: "unsupported digest: md5:abc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReference(tt.ref)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClient_Pull(t *testing.T) {
+	layer := []byte("db")
+	sum := sha256.Sum256(layer)
+	layerDigest := "sha256:" + hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		digest  string // of the layer in the manifest
+		wantErr string
+	}{
+		{
+			name:   "happy path",
+			digest: layerDigest,
+		},
+		{
+			name:    "corrupted layer",
+			digest:  "sha256:0000",
+			wantErr: "mismatched digest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ts *httptest.Server
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/token":
+					assert.Equal(t, "repository:aquasecurity/trivy-db:pull", r.URL.Query().Get("scope"))
+					fmt.Fprint(w, `{"token":"secret"}`)
+				case r.Header.Get("Authorization") != "Bearer secret":
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, ts.URL))
+					w.WriteHeader(http.StatusUnauthorized)
+				case r.URL.Path == "/v2/aquasecurity/trivy-db/manifests/2":
+					w.Header().Set("Content-Type", MediaTypeManifest)
+					json.NewEncoder(w).Encode(Manifest{
+						SchemaVersion: 2,
+						Layers:        []Descriptor{{MediaType: "application/gzip", Digest: tt.digest, Size: int64(len(layer))}},
+					})
+				case strings.HasPrefix(r.URL.Path, "/v2/aquasecurity/trivy-db/blobs/"):
+					w.Write(layer)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer ts.Close()
+
+			ref := Reference{Registry: strings.TrimPrefix(ts.URL, "http://"), Repository: "aquasecurity/trivy-db", Tag: "2"}
+			c := Client{HTTPClient: ts.Client(), PlainHTTP: true}
+			var buf bytes.Buffer
+			got, err := c.Pull(context.Background(), ref, &buf)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, layerDigest, got.Digest)
+			assert.Equal(t, layer, buf.Bytes())
+		})
+	}
+}
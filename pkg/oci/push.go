@@ -0,0 +1,127 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/xerrors"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// Blob is content to push, which is read once its digest is known
+type Blob struct {
+	Descriptor
+	Open func() (io.ReadCloser, error)
+}
+
+// BytesBlob is a blob of b, e.g. a config
+func BytesBlob(mediaType string, b []byte) Blob {
+	return Blob{
+		Descriptor: Descriptor{MediaType: mediaType, Digest: digest(b), Size: int64(len(b))},
+		Open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		},
+	}
+}
+
+// Push uploads the config and the layers the registry doesn't have yet, then tags the manifest, which has
+// the annotations, with each tag. The tag and the digest of ref are ignored.
+func (c Client) Push(ctx context.Context, ref Reference, config Blob, layers []Blob, annotations map[string]string,
+	tags []string) (Descriptor, error) {
+	if len(tags) == 0 {
+		return Descriptor{}, xerrors.New("no tag")
+	}
+	repo, err := c.repository(ref)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	manifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeManifest,
+		Config:        config.Descriptor,
+		Annotations:   annotations,
+	}
+	if err = pushBlob(ctx, repo, config); err != nil {
+		return Descriptor{}, xerrors.Errorf("failed to push the config: %w", err)
+	}
+	for _, layer := range layers {
+		if err = pushBlob(ctx, repo, layer); err != nil {
+			return Descriptor{}, xerrors.Errorf("failed to push the layer %s: %w", layer.Digest, err)
+		}
+		manifest.Layers = append(manifest.Layers, layer.Descriptor)
+	}
+
+	return pushManifest(ctx, repo, manifest, tags)
+}
+
+// Attach pushes blob as an artifact of artifactType attached to the manifest subject in the repository
+// of ref, e.g. an attestation of the DB, which registries list with the referrers API of subject.
+// The attached artifact has no tag.
+func (c Client) Attach(ctx context.Context, ref Reference, subject Descriptor, artifactType string, blob Blob,
+	annotations map[string]string) (Descriptor, error) {
+	repo, err := c.repository(ref)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	config := BytesBlob(MediaTypeEmpty, []byte("{}"))
+	if err = pushBlob(ctx, repo, config); err != nil {
+		return Descriptor{}, xerrors.Errorf("failed to push the config: %w", err)
+	}
+	if err = pushBlob(ctx, repo, blob); err != nil {
+		return Descriptor{}, xerrors.Errorf("failed to push the blob %s: %w", blob.Digest, err)
+	}
+	subject.Annotations = nil
+	manifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeManifest,
+		ArtifactType:  artifactType,
+		Config:        config.Descriptor,
+		Layers:        []Descriptor{blob.Descriptor},
+		Subject:       &subject,
+		Annotations:   annotations,
+	}
+	// an untagged manifest is pushed by its digest, and the repository indexes its subject if the
+	// registry doesn't support the referrers API
+	return pushManifest(ctx, repo, manifest, nil)
+}
+
+// pushManifest puts the manifest with each tag, or with its digest if there is none
+func pushManifest(ctx context.Context, repo *remote.Repository, manifest Manifest, tags []string) (Descriptor, error) {
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return Descriptor{}, xerrors.Errorf("failed to encode the manifest: %w", err)
+	}
+	desc := Descriptor{MediaType: MediaTypeManifest, Digest: digest(b), Size: int64(len(b))}
+	if len(tags) == 0 {
+		if err = repo.Push(ctx, desc.oci(), bytes.NewReader(b)); err != nil {
+			return Descriptor{}, xerrors.Errorf("failed to push the manifest: %w", err)
+		}
+		return desc, nil
+	}
+	for _, tag := range tags {
+		if err = repo.PushReference(ctx, desc.oci(), bytes.NewReader(b), tag); err != nil {
+			return Descriptor{}, xerrors.Errorf("failed to push the manifest of %s: %w", tag, err)
+		}
+	}
+	return desc, nil
+}
+
+// pushBlob uploads the blob unless the repository has it
+func pushBlob(ctx context.Context, repo *remote.Repository, blob Blob) error {
+	desc := blob.oci()
+	if ok, err := repo.Exists(ctx, desc); err != nil {
+		return err
+	} else if ok {
+		return nil
+	}
+
+	r, err := blob.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return repo.Push(ctx, desc, r)
+}
@@ -0,0 +1,39 @@
+package pkg
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/artifact"
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+// packageDB archives the DB of the cache dir into the dir of the assets in each format of --format, e.g.
+// assets/trivy.db.gz, which the same DB always archives to
+func packageDB(c *cli.Context) error {
+	formats := splitList(c.String("format"))
+	for _, format := range formats {
+		if !utils.StringInSlice(format, artifact.Formats) {
+			return xerrors.Errorf("unknown archive format %q, one of %s", format, strings.Join(artifact.Formats, ", "))
+		}
+	}
+	dbPath := db.Path(c.String("cache-dir"))
+	metadata, err := db.ReadMetadata(c.String("backend"), dbPath)
+	if err != nil {
+		return xerrors.Errorf("failed to read the metadata of %s: %w", dbPath, err)
+	}
+
+	for _, format := range formats {
+		path := filepath.Join(c.String("dir"), c.String("name")+".db."+format)
+		if err = writeOutput(dbPath, metadata, path); err != nil {
+			return xerrors.Errorf("failed to write %s: %w", path, err)
+		}
+		log.Info("Packaged the DB", "path", path, "format", format)
+	}
+	return nil
+}
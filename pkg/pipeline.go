@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/artifact"
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/pipeline"
+)
+
+func dumpConfig(c *cli.Context) error {
+	config := pipeline.Default()
+	if path := c.String("config"); path != "" {
+		var err error
+		if config, err = pipeline.Load(path); err != nil {
+			return err
+		}
+	}
+	b, err := config.Effective().Dump()
+	if err != nil {
+		return xerrors.Errorf("failed to encode the config: %w", err)
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+// applyPipeline reads the pipeline config of --config, if any, and sets the flags of the build the command
+// line doesn't. Without a config, the config is empty.
+func applyPipeline(c *cli.Context) (pipeline.Config, error) {
+	path := c.String("config")
+	if path == "" {
+		return pipeline.Config{}, nil
+	}
+	config, err := pipeline.Load(path)
+	if err != nil {
+		return pipeline.Config{}, err
+	}
+	for name, value := range config.BuildFlags() {
+		// --continue-on-error overrides the strict policy of the config
+		if c.IsSet(name) || name == "strict" && c.IsSet("continue-on-error") {
+			continue
+		}
+		if err = c.Set(name, value); err != nil {
+			return pipeline.Config{}, xerrors.Errorf("failed to set --%s: %w", name, err)
+		}
+	}
+
+	if !c.IsSet("mirror") {
+		var repos []string
+		for repo := range config.Fetch.Mirrors {
+			repos = append(repos, repo)
+		}
+		sort.Strings(repos)
+		for _, repo := range repos {
+			for _, url := range config.Fetch.Mirrors[repo] {
+				if err = c.Set("mirror", repo+"="+url); err != nil {
+					return pipeline.Config{}, xerrors.Errorf("failed to set --mirror: %w", err)
+				}
+			}
+		}
+	}
+	if !c.IsSet("plugin") {
+		for _, p := range config.Plugins {
+			if err = c.Set("plugin", p.Name+"="+p.Path); err != nil {
+				return pipeline.Config{}, xerrors.Errorf("failed to set --plugin: %w", err)
+			}
+		}
+	}
+	return config, nil
+}
+
+// writeOutputs copies the DB file to the outputs, archived in the format of their extension if any
+func writeOutputs(dbPath string, metadata db.Metadata, outputs []pipeline.OutputConfig) error {
+	for _, output := range outputs {
+		if err := writeOutput(dbPath, metadata, output.Path); err != nil {
+			return xerrors.Errorf("failed to write %s: %w", output.Path, err)
+		}
+		log.Info("Wrote the DB", "path", output.Path)
+	}
+	return nil
+}
+
+func writeOutput(dbPath string, metadata db.Metadata, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// a partial output is never left in place of a previous one
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	if format := artifact.FormatOf(path); format != "" {
+		err = artifact.Archive(f, dbPath, format, metadata)
+	} else {
+		err = copyFile(f, dbPath)
+	}
+	if err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func copyFile(w io.Writer, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return xerrors.Errorf("%s isn't a file, only the DBs of bolt and sqlite are written to outputs", path)
+	}
+	_, err = io.Copy(w, src)
+	return err
+}
@@ -0,0 +1,389 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/fetch"
+	"github.com/aquasecurity/trivy-db/pkg/notify"
+	"github.com/aquasecurity/trivy-db/pkg/publisher"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
+)
+
+// Config describes a build from the sources to the publishers, e.g.
+//
+//	cache-dir: ./cache
+//	sources:
+//	  - name: alpine
+//	  - name: nvd
+//	    timeout: 30m
+//	    on-error: fail
+//	    min-files: 100000
+//	    retries: 3
+//	skip-sources: [bdu]
+//	plugins:
+//	  - name: acme
+//	    path: /opt/feeds/acme-feed
+//	build:
+//	  update-interval: 12h
+//	  strict: false
+//	  channel: nightly
+//	fetch:
+//	  enabled: true
+//	  mirrors:
+//	    vuln-list: [https://git.example.com/vuln-list.git]
+//	metrics:
+//	  pushgateway: http://pushgateway:9091
+//	outputs:
+//	  - path: assets/trivy.db.gz
+//	publishers:
+//	  - type: github
+//	notifications:
+//	  hooks:
+//	    - type: slack
+//	      url-env: SLACK_WEBHOOK_URL
+//
+// The flags of a command take precedence over the config, and so do the environment variables of the flags,
+// e.g. TRIVY_DB_SOURCES and TRIVY_DB_SKIP_SOURCES for the sources.
+type Config struct {
+	CacheDir string         `yaml:"cache-dir"`
+	Sources  []SourceConfig `yaml:"sources"` // the default sources if empty
+	// SkipSources are left out of Sources, e.g. by a fork which doesn't build some default sources
+	SkipSources []string `yaml:"skip-sources,omitempty"`
+	// Plugins are sources of their own, updated with the default sources
+	Plugins []PluginConfig `yaml:"plugins,omitempty"`
+	Build   BuildConfig    `yaml:"build"`
+	Fetch   FetchConfig    `yaml:"fetch"`
+	Metrics MetricsConfig  `yaml:"metrics"`
+	// Outputs are where the built DB file is written
+	Outputs []OutputConfig `yaml:"outputs,omitempty"`
+
+	publisher.Config `yaml:",inline"`
+	// Notifications report the publication to hooks
+	Notifications notify.Config `yaml:"notifications,omitempty"`
+}
+
+// SourceConfig is a source to update and its options
+type SourceConfig struct {
+	Name string `yaml:"name"`
+	// CacheDir is where the source reads its repository from, e.g. a checkout shared with other builds,
+	// the cache dir of the build if empty
+	CacheDir string        `yaml:"cache-dir,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"` // the source timeout of the build if 0
+	// OnError is fail or continue, the policy of the build if empty
+	OnError vulnsrc.FailurePolicy `yaml:"on-error,omitempty"`
+	// StaleAfter is how long the source may add no record, the threshold of the build if 0
+	StaleAfter time.Duration `yaml:"stale-after,omitempty"`
+	// MinFiles is the number of input files below which the preflight of the build fails the source,
+	// e.g. for a checkout interrupted half way
+	MinFiles int `yaml:"min-files,omitempty"`
+	Retries  int `yaml:"retries,omitempty"` // the source retries of the build if 0
+}
+
+// PluginConfig is an executable feeding the advisories of a source, see the plugin package for its contract
+type PluginConfig struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+}
+
+// BuildConfig are the options of the build command
+type BuildConfig struct {
+	Light      bool   `yaml:"light"`
+	Backend    string `yaml:"backend"`
+	Encoding   string `yaml:"encoding"`
+	Compress   bool   `yaml:"compress"`
+	Compact    bool   `yaml:"compact"`
+	Dedup      bool   `yaml:"dedup"`
+	Validate   bool   `yaml:"validate"`
+	Checkpoint bool   `yaml:"checkpoint"`
+	// Incremental only reads the files changed since the previous build, which needs its revisions in the
+	// history of the repositories, e.g. with a fetch depth of 0
+	Incremental bool `yaml:"incremental"`
+	// LowMemory commits the inputs of the sources every BatchSize of them instead of holding them all, for
+	// builds on small runners
+	LowMemory bool `yaml:"low-memory"`
+	BatchSize int  `yaml:"batch-size"`
+	// Channel is the release channel the DB is built for and published to, e.g. dev on every commit and
+	// stable every week with the same config and --channel
+	Channel        string        `yaml:"channel"`
+	Strict         bool          `yaml:"strict"` // a failed source fails the build, unless it continues on error
+	Workers        int           `yaml:"workers"`
+	Parallel       int           `yaml:"parallel"`
+	UpdateInterval time.Duration `yaml:"update-interval"`
+	ValidFor       time.Duration `yaml:"valid-for"`
+	SourceTimeout  time.Duration `yaml:"source-timeout"`
+	// SourceRetries are the attempts after the first of a source failing with a transient error, after
+	// RetryBackoff doubled each time
+	SourceRetries int           `yaml:"source-retries"`
+	RetryBackoff  time.Duration `yaml:"retry-backoff"`
+	StaleAfter    time.Duration `yaml:"stale-after"`
+	// FreshnessReport is the path of the JSON freshness report of the sources, none if empty
+	FreshnessReport string `yaml:"freshness-report,omitempty"`
+}
+
+// FetchConfig is how the repositories of the sources are fetched before the build
+type FetchConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Depth   int  `yaml:"depth"` // 0 for the whole history
+	// Mirrors are keyed by the repository, e.g. vuln-list
+	Mirrors map[string][]string `yaml:"mirrors,omitempty"`
+}
+
+// MetricsConfig is where the Prometheus metrics of the build go
+type MetricsConfig struct {
+	Listen      string `yaml:"listen,omitempty"`      // the address serving them while the build runs
+	Pushgateway string `yaml:"pushgateway,omitempty"` // the URL they are pushed to once it is over
+	Job         string `yaml:"job"`
+}
+
+// OutputConfig is a copy of the DB file, archived if the path ends with the extension of a format,
+// e.g. .gz or .tar.zst
+type OutputConfig struct {
+	Path string `yaml:"path"`
+}
+
+// Default is the config of a build without one, as the flags default to
+func Default() Config {
+	return Config{
+		CacheDir: utils.CacheDir(),
+		Build: BuildConfig{
+			Backend:        storage.DefaultDriver,
+			Encoding:       db.EncodingJSON,
+			Compact:        true,
+			Workers:        1,
+			Parallel:       1,
+			BatchSize:      1000,
+			Channel:        db.ChannelStable,
+			UpdateInterval: 24 * time.Hour,
+			StaleAfter:     7 * 24 * time.Hour,
+			SourceRetries:  2,
+			RetryBackoff:   10 * time.Second,
+		},
+		Fetch:   FetchConfig{Depth: 1},
+		Metrics: MetricsConfig{Job: "trivy-db"},
+	}
+}
+
+// Load reads the config at path over the defaults, and validates it
+func Load(path string) (Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, xerrors.Errorf("failed to read the config: %w", err)
+	}
+	config := Default()
+	if err = yaml.UnmarshalStrict(b, &config); err != nil {
+		return Config{}, xerrors.Errorf("invalid config %s: %w", path, err)
+	}
+	if err = config.Validate(); err != nil {
+		return Config{}, xerrors.Errorf("invalid config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Validate checks the values the commands would reject, so that a build doesn't fail once it started
+func (c Config) Validate() error {
+	if c.CacheDir == "" {
+		return xerrors.New("no cache-dir")
+	}
+	var plugins []string
+	for i, p := range c.Plugins {
+		switch {
+		case p.Name == "" || p.Path == "":
+			return xerrors.Errorf("plugin %d: no name or path", i)
+		case utils.StringInSlice(p.Name, plugins):
+			return xerrors.Errorf("plugin %d: duplicate name %s", i, p.Name)
+		}
+		if _, ok := registry.Get(p.Name); ok {
+			return xerrors.Errorf("plugin %d: %s is the name of a source", i, p.Name)
+		}
+		plugins = append(plugins, p.Name)
+	}
+	var names []string
+	for i, s := range c.Sources {
+		if s.Name == "" {
+			return xerrors.Errorf("source %d: no name", i)
+		}
+		if s.Timeout < 0 || s.StaleAfter < 0 {
+			return xerrors.Errorf("source %s: negative duration", s.Name)
+		}
+		if s.MinFiles < 0 || s.Retries < 0 {
+			return xerrors.Errorf("source %s: negative min-files or retries", s.Name)
+		}
+		switch s.OnError {
+		case "", vulnsrc.FailOnError, vulnsrc.ContinueOnError:
+		default:
+			return xerrors.Errorf("source %s: unknown on-error policy %q, fail or continue", s.Name, s.OnError)
+		}
+		// the plugins are only known to the build once it registered them
+		if !utils.StringInSlice(s.Name, plugins) {
+			names = append(names, s.Name)
+		}
+	}
+	var skip []string
+	for _, name := range c.SkipSources {
+		if !utils.StringInSlice(name, plugins) {
+			skip = append(skip, name)
+		}
+	}
+	if len(names) > 0 || len(c.Sources) == 0 {
+		if _, err := vulnsrc.Targets(names, skip); err != nil {
+			return err
+		}
+	}
+
+	b := c.Build
+	if !utils.StringInSlice(b.Backend, storage.Drivers()) {
+		return xerrors.Errorf("unknown backend %q, one of %s", b.Backend, strings.Join(storage.Drivers(), ", "))
+	}
+	if b.Encoding != db.EncodingJSON && b.Encoding != db.EncodingMsgpack {
+		return xerrors.Errorf("unknown encoding: %s", b.Encoding)
+	}
+	if b.Workers < 1 || b.Parallel < 1 {
+		return xerrors.New("workers and parallel are at least 1")
+	}
+	if b.Incremental && (b.Light || b.Parallel > 1) {
+		return xerrors.New("incremental builds can't be light or parallel")
+	}
+	if b.LowMemory && (b.Workers > 1 || b.Parallel > 1) {
+		return xerrors.New("low memory builds can't have several workers or be parallel")
+	}
+	if b.LowMemory && b.BatchSize < 1 {
+		return xerrors.New("batch size is at least 1")
+	}
+	if err := db.ValidateChannel(b.Channel); err != nil {
+		return err
+	}
+	if b.UpdateInterval < 0 || b.ValidFor < 0 || b.SourceTimeout < 0 || b.StaleAfter < 0 || b.RetryBackoff < 0 {
+		return xerrors.New("negative duration")
+	}
+	if b.SourceRetries < 0 {
+		return xerrors.New("negative source retries")
+	}
+
+	if c.Fetch.Depth < 0 {
+		return xerrors.New("negative fetch depth")
+	}
+	for repo := range c.Fetch.Mirrors {
+		if _, ok := fetch.Upstreams[repo]; !ok {
+			return xerrors.Errorf("mirror of an unknown repository: %s", repo)
+		}
+	}
+
+	if c.Metrics.Pushgateway != "" && c.Metrics.Job == "" {
+		return xerrors.New("no job of the metrics pushed to the Pushgateway")
+	}
+
+	var paths []string
+	for i, o := range c.Outputs {
+		if o.Path == "" {
+			return xerrors.Errorf("output %d: no path", i)
+		}
+		if utils.StringInSlice(o.Path, paths) {
+			return xerrors.Errorf("output %d: duplicate path %s", i, o.Path)
+		}
+		paths = append(paths, o.Path)
+	}
+
+	for i, p := range c.Publishers {
+		if err := p.Validate(); err != nil {
+			return xerrors.Errorf("publisher %d: %w", i, err)
+		}
+	}
+	for i, h := range c.Notifications.Hooks {
+		if err := h.Validate(); err != nil {
+			return xerrors.Errorf("hook %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Effective returns the config with the defaults spelled out, e.g. the default sources, which the plugins are
+func (c Config) Effective() Config {
+	if len(c.Sources) == 0 {
+		names := append([]string{}, vulnsrc.UpdateList...)
+		for _, p := range c.Plugins {
+			if !utils.StringInSlice(p.Name, names) {
+				names = append(names, p.Name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c.Sources = append(c.Sources, SourceConfig{Name: name})
+		}
+	}
+	return c
+}
+
+// Dump returns the YAML of the config
+func (c Config) Dump() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// SourceNames returns the names of the sources, all the default ones if none is configured
+func (c Config) SourceNames() []string {
+	var names []string
+	for _, s := range c.Effective().Sources {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// SourceOptions returns the options of the sources which have some, keyed by the source
+func (c Config) SourceOptions() map[string]vulnsrc.SourceOptions {
+	options := map[string]vulnsrc.SourceOptions{}
+	for _, s := range c.Sources {
+		if s.CacheDir != "" || s.Timeout != 0 || s.OnError != "" || s.MinFiles != 0 || s.Retries != 0 {
+			options[s.Name] = vulnsrc.SourceOptions{CacheDir: s.CacheDir, Timeout: s.Timeout, OnError: s.OnError,
+				MinFiles: s.MinFiles, Retries: s.Retries}
+		}
+	}
+	return options
+}
+
+// BuildFlags returns the values of the flags of the build command the config sets
+func (c Config) BuildFlags() map[string]string {
+	b := c.Build
+	flags := map[string]string{
+		"cache-dir":        c.CacheDir,
+		"only-update":      strings.Join(c.SourceNames(), ","),
+		"skip-update":      strings.Join(c.SkipSources, ","),
+		"light":            strconv.FormatBool(b.Light),
+		"backend":          b.Backend,
+		"encoding":         b.Encoding,
+		"compress":         strconv.FormatBool(b.Compress),
+		"compact":          strconv.FormatBool(b.Compact),
+		"dedup":            strconv.FormatBool(b.Dedup),
+		"validate":         strconv.FormatBool(b.Validate),
+		"checkpoint":       strconv.FormatBool(b.Checkpoint),
+		"incremental":      strconv.FormatBool(b.Incremental),
+		"low-memory":       strconv.FormatBool(b.LowMemory),
+		"batch-size":       strconv.Itoa(b.BatchSize),
+		"channel":          b.Channel,
+		"strict":           strconv.FormatBool(b.Strict),
+		"workers":          strconv.Itoa(b.Workers),
+		"parallel":         strconv.Itoa(b.Parallel),
+		"update-interval":  b.UpdateInterval.String(),
+		"valid-for":        b.ValidFor.String(),
+		"source-timeout":   b.SourceTimeout.String(),
+		"source-retries":   strconv.Itoa(b.SourceRetries),
+		"retry-backoff":    b.RetryBackoff.String(),
+		"stale-after":      b.StaleAfter.String(),
+		"freshness-report": b.FreshnessReport,
+		"fetch":            strconv.FormatBool(c.Fetch.Enabled),
+		"fetch-depth":      strconv.Itoa(c.Fetch.Depth),
+		"metrics-listen":   c.Metrics.Listen,
+		"metrics-push":     c.Metrics.Pushgateway,
+		"metrics-job":      c.Metrics.Job,
+	}
+	return flags
+}
@@ -0,0 +1,289 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/notify"
+	"github.com/aquasecurity/trivy-db/pkg/publisher"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc"
+)
+
+func TestLoad(t *testing.T) {
+	want := Default()
+	want.CacheDir = "./cache"
+	want.Sources = []SourceConfig{
+		{Name: "alpine", StaleAfter: 30 * 24 * time.Hour},
+		{Name: "nvd", CacheDir: "/mnt/shared", Timeout: 30 * time.Minute, OnError: vulnsrc.FailOnError, MinFiles: 1000},
+	}
+	want.Build.Light = true
+	want.Build.UpdateInterval = 12 * time.Hour
+	want.Fetch = FetchConfig{Enabled: true, Depth: 0,
+		Mirrors: map[string][]string{"vuln-list": {"https://git.example.com/vuln-list.git"}}}
+	want.Outputs = []OutputConfig{{Path: "assets/trivy-light.db.gz"}}
+	want.Publishers = []publisher.PublisherConfig{
+		{Type: "s3", Bucket: "mirror", Prefix: "trivy-db/", Region: "eu-west-1", PublicRead: true},
+		{Type: "github", Retention: publisher.RetentionConfig{Keep: 5, MaxAge: 72 * time.Hour}},
+	}
+	want.Notifications = notify.Config{
+		Baseline: "oci://ghcr.io/aquasecurity/trivy-db:2",
+		Hooks:    []notify.HookConfig{{Type: "slack", URLEnv: "SLACK_WEBHOOK_URL"}},
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		want    Config
+		wantErr string
+	}{
+		{
+			name: "happy path",
+			config: `cache-dir: ./cache
+sources:
+  - name: alpine
+    stale-after: 720h
+  - name: nvd
+    cache-dir: /mnt/shared
+    timeout: 30m
+    on-error: fail
+    min-files: 1000
+build:
+  light: true
+  update-interval: 12h
+fetch:
+  enabled: true
+  depth: 0
+  mirrors:
+    vuln-list: [https://git.example.com/vuln-list.git]
+outputs:
+  - path: assets/trivy-light.db.gz
+publishers:
+  - type: s3
+    bucket: mirror
+    prefix: trivy-db/
+    region: eu-west-1
+    public-read: true
+  - type: github
+    retention:
+      keep: 5
+      max-age: 72h
+notifications:
+  baseline: oci://ghcr.io/aquasecurity/trivy-db:2
+  hooks:
+    - type: slack
+      url-env: SLACK_WEBHOOK_URL
+`,
+			want: want,
+		},
+		{
+			name:   "defaults",
+			config: "{}",
+			want:   Default(),
+		},
+		{
+			name:    "unknown field",
+			config:  "build:\n  lite: true\n",
+			wantErr: "field lite not found",
+		},
+		{
+			name:    "unknown source",
+			config:  "sources:\n  - name: alpin\n",
+			wantErr: "alpin: unknown source",
+		},
+		{
+			name:   "skipped sources",
+			config: "skip-sources: [nvd]\n",
+			want: func() Config {
+				c := Default()
+				c.SkipSources = []string{"nvd"}
+				return c
+			}(),
+		},
+		{
+			name:    "unknown skipped source",
+			config:  "skip-sources: [nvdd]\n",
+			wantErr: "nvdd: unknown source",
+		},
+		{
+			name:    "every source skipped",
+			config:  "sources:\n  - name: nvd\nskip-sources: [nvd]\n",
+			wantErr: "no source to update",
+		},
+		{
+			name:   "plugin",
+			config: "plugins:\n  - name: acme\n    path: /opt/feeds/acme-feed\nsources:\n  - name: acme\n  - name: nvd\n",
+			want: func() Config {
+				c := Default()
+				c.Plugins = []PluginConfig{{Name: "acme", Path: "/opt/feeds/acme-feed"}}
+				c.Sources = []SourceConfig{{Name: "acme"}, {Name: "nvd"}}
+				return c
+			}(),
+		},
+		{
+			name:    "plugin named as a source",
+			config:  "plugins:\n  - name: nvd\n    path: /opt/feeds/nvd\n",
+			wantErr: "plugin 0: nvd is the name of a source",
+		},
+		{
+			name:    "duplicate plugin",
+			config:  "plugins:\n  - name: acme\n    path: /a\n  - name: acme\n    path: /b\n",
+			wantErr: "plugin 1: duplicate name acme",
+		},
+		{
+			name:    "plugin without path",
+			config:  "plugins:\n  - name: acme\n",
+			wantErr: "plugin 0: no name or path",
+		},
+		{
+			name:    "unknown backend",
+			config:  "build:\n  backend: mysql\n",
+			wantErr: `unknown backend "mysql"`,
+		},
+		{
+			name:    "unknown encoding",
+			config:  "build:\n  encoding: xml\n",
+			wantErr: "unknown encoding: xml",
+		},
+		{
+			name:    "mirror of an unknown repository",
+			config:  "fetch:\n  mirrors:\n    vulnlist: [https://git.example.com/vuln-list.git]\n",
+			wantErr: "mirror of an unknown repository: vulnlist",
+		},
+		{
+			name:    "unknown failure policy",
+			config:  "sources:\n  - name: nvd\n    on-error: ignore\n",
+			wantErr: `source nvd: unknown on-error policy "ignore"`,
+		},
+		{
+			name:    "parallel incremental build",
+			config:  "build:\n  incremental: true\n  parallel: 4\n",
+			wantErr: "incremental builds can't be light or parallel",
+		},
+		{
+			name:    "unknown channel",
+			config:  "build:\n  channel: beta\n",
+			wantErr: `unknown channel "beta", one of dev, nightly, stable`,
+		},
+		{
+			name:    "low memory build with workers",
+			config:  "build:\n  low-memory: true\n  workers: 4\n",
+			wantErr: "low memory builds can't have several workers or be parallel",
+		},
+		{
+			name:    "low memory build without batches",
+			config:  "build:\n  low-memory: true\n  batch-size: 0\n",
+			wantErr: "batch size is at least 1",
+		},
+		{
+			name:    "negative min-files",
+			config:  "sources:\n  - name: nvd\n    min-files: -1\n",
+			wantErr: "source nvd: negative min-files or retries",
+		},
+		{
+			name:    "pushgateway without a job",
+			config:  "metrics:\n  pushgateway: http://pushgateway:9091\n  job: \"\"\n",
+			wantErr: "no job of the metrics",
+		},
+		{
+			name:    "duplicate output",
+			config:  "outputs:\n  - path: trivy.db\n  - path: trivy.db\n",
+			wantErr: "output 1: duplicate path trivy.db",
+		},
+		{
+			name:    "invalid publisher",
+			config:  "publishers:\n  - type: gcs\n",
+			wantErr: "publisher 0: no bucket",
+		},
+		{
+			name:    "hook without a URL",
+			config:  "notifications:\n  hooks:\n    - type: webhook\n",
+			wantErr: "hook 0: either url or url-env is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestLoad_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+			path := filepath.Join(d, "pipeline.yaml")
+			assert.NoError(t, ioutil.WriteFile(path, []byte(tt.config), 0600))
+
+			got, err := Load(path)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_BuildFlags(t *testing.T) {
+	config := Default()
+	config.CacheDir = "cache"
+	config.Sources = []SourceConfig{{Name: "alpine", MinFiles: 10},
+		{Name: "nvd", Timeout: time.Hour, OnError: vulnsrc.ContinueOnError, Retries: 5}}
+	config.Build.Light = true
+	config.Build.Strict = true
+	config.Fetch.Enabled = true
+	config.Metrics.Pushgateway = "http://pushgateway:9091"
+
+	flags := config.BuildFlags()
+	assert.Equal(t, "cache", flags["cache-dir"])
+	assert.Equal(t, "alpine,nvd", flags["only-update"])
+	assert.Equal(t, "", flags["skip-update"])
+	assert.Equal(t, "true", flags["light"])
+	assert.Equal(t, "true", flags["compact"])
+	assert.Equal(t, "24h0m0s", flags["update-interval"])
+	assert.Equal(t, "true", flags["fetch"])
+	assert.Equal(t, "http://pushgateway:9091", flags["metrics-push"])
+	assert.Equal(t, "trivy-db", flags["metrics-job"])
+	assert.Equal(t, "true", flags["strict"])
+	assert.Equal(t, "168h0m0s", flags["stale-after"])
+	assert.Equal(t, "2", flags["source-retries"])
+	assert.Equal(t, "10s", flags["retry-backoff"])
+	assert.Equal(t, "false", flags["low-memory"])
+	assert.Equal(t, "1000", flags["batch-size"])
+	assert.Equal(t, "stable", flags["channel"])
+	assert.Equal(t, map[string]vulnsrc.SourceOptions{"alpine": {MinFiles: 10},
+		"nvd": {Timeout: time.Hour, OnError: vulnsrc.ContinueOnError, Retries: 5}}, config.SourceOptions())
+
+	config.SkipSources = []string{"nvd", "redhat"}
+	assert.Equal(t, "nvd,redhat", config.BuildFlags()["skip-update"])
+
+	// the default sources
+	config.Sources = nil
+	assert.ElementsMatch(t, vulnsrc.UpdateList, config.SourceNames())
+	assert.Len(t, config.Effective().Sources, len(vulnsrc.UpdateList))
+
+	// the plugins are default sources too
+	config.Plugins = []PluginConfig{{Name: "acme", Path: "/opt/feeds/acme-feed"}}
+	assert.ElementsMatch(t, append(append([]string{}, vulnsrc.UpdateList...), "acme"), config.SourceNames())
+}
+
+func TestConfig_Dump(t *testing.T) {
+	config := Default()
+	config.CacheDir = "cache"
+	config.Outputs = []OutputConfig{{Path: "assets/trivy.db.gz"}}
+	config = config.Effective()
+	b, err := config.Dump()
+	assert.NoError(t, err)
+
+	d, err := ioutil.TempDir("", "TestConfig_Dump_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, "pipeline.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, b, 0600))
+
+	// the dump is a config itself
+	got, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, config, got)
+}
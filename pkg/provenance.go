@@ -0,0 +1,77 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/slsa"
+)
+
+func provenance(c *cli.Context) error {
+	cacheDir := c.String("cache-dir")
+	metadata, err := readMetadata(cacheDir)
+	if err != nil {
+		return err
+	}
+
+	paths := c.StringSlice("subject")
+	if len(paths) == 0 {
+		paths = []string{db.Path(cacheDir)}
+	}
+	var subjects []slsa.ResourceDescriptor
+	for _, path := range paths {
+		subject, err := slsa.FileSubject(path)
+		if err != nil {
+			return err
+		}
+		subjects = append(subjects, subject)
+	}
+
+	b, err := newProvenance(c, metadata, subjects)
+	if err != nil {
+		return err
+	}
+	output := c.String("output")
+	if output == "" {
+		_, err = os.Stdout.Write(append(b, '\n'))
+		return err
+	}
+	if err = ioutil.WriteFile(output, append(b, '\n'), 0644); err != nil {
+		return xerrors.Errorf("failed to write the provenance: %w", err)
+	}
+	log.Info("Wrote the provenance", "path", output, "subjects", len(subjects))
+	return nil
+}
+
+// newProvenance returns the statement of the build of the DB whose metadata is given, run by
+// the GitHub Actions workflow of the environment or by the builder of --builder-id
+func newProvenance(c *cli.Context, metadata db.Metadata, subjects []slsa.ResourceDescriptor) ([]byte, error) {
+	builder, invocation, ok := slsa.GitHubActions()
+	if id := c.String("builder-id"); id != "" {
+		builder.ID = id
+	} else if !ok {
+		return nil, xerrors.New("--builder-id is required outside GitHub Actions")
+	}
+	builder.Version = map[string]string{"trivy-db": c.App.Version}
+	return slsa.New(metadata, builder, invocation, subjects).Marshal()
+}
+
+func readMetadata(cacheDir string) (db.Metadata, error) {
+	if err := db.OpenReadOnly(cacheDir); err != nil {
+		return db.Metadata{}, err
+	}
+	metadata, err := db.Config{}.GetMetadata()
+	if err != nil {
+		db.Close()
+		return db.Metadata{}, xerrors.Errorf("failed to get the metadata: %w", err)
+	}
+	if err = db.Close(); err != nil {
+		return db.Metadata{}, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,61 @@
+package pkg
+
+import (
+	"strings"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/artifact"
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/oci"
+	"github.com/aquasecurity/trivy-db/pkg/slsa"
+)
+
+func publish(c *cli.Context) error {
+	repository := c.String("repository")
+	if repository == "" {
+		return xerrors.New("--repository is required")
+	}
+
+	cacheDir := c.String("cache-dir")
+	metadata, err := readMetadata(cacheDir)
+	if err != nil {
+		return err
+	}
+
+	tags := splitList(c.String("tags"))
+	if len(tags) == 0 {
+		tags = artifact.DefaultTags(metadata)
+	}
+	client := oci.NewClient()
+	client.PlainHTTP = c.Bool("plain-http")
+	client.Username, client.Password = c.String("username"), c.String("password")
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	manifest, err := artifact.Publish(ctx, client, repository, db.Path(cacheDir), metadata, tags)
+	if err != nil {
+		return err
+	}
+	log.Info("Published the DB", "repository", repository, "tags", strings.Join(tags, ","), "digest", manifest.Digest)
+
+	if !c.Bool("provenance") {
+		return nil
+	}
+	subject, err := slsa.DigestSubject(repository, manifest.Digest)
+	if err != nil {
+		return err
+	}
+	statement, err := newProvenance(c, metadata, []slsa.ResourceDescriptor{subject})
+	if err != nil {
+		return err
+	}
+	attestation, err := artifact.Attest(ctx, client, repository, manifest, statement)
+	if err != nil {
+		return err
+	}
+	log.Info("Attached the provenance", "repository", repository, "digest", attestation.Digest)
+	return nil
+}
@@ -0,0 +1,82 @@
+package publisher
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/option"
+)
+
+const (
+	gcsChunkUnit    = 256 << 10 // the size of the chunks of a resumable upload is a multiple of it
+	gcsDefaultChunk = 32 * gcsChunkUnit
+)
+
+// GCS uploads to a bucket of Google Cloud Storage with the application default credentials, e.g. the
+// key in GOOGLE_APPLICATION_CREDENTIALS, through resumable uploads in chunks of the part size. The MD5
+// of the file is sent with the object, which the storage checks once the upload is complete.
+type GCS struct {
+	config PublisherConfig
+	client *storage.Client
+}
+
+// NewGCS returns the publisher of a gcs config
+func NewGCS(ctx context.Context, config PublisherConfig) (GCS, error) {
+	if err := config.Validate(); err != nil {
+		return GCS{}, err
+	}
+	client, err := storage.NewClient(ctx, option.WithScopes(storage.ScopeReadWrite))
+	if err != nil {
+		return GCS{}, xerrors.Errorf("failed to create the Google Cloud Storage client: %w", err)
+	}
+	return GCS{config: config, client: client}, nil
+}
+
+// Publish uploads each file, with the hex SHA-256 of the file in the sha256 metadata of the object
+func (p GCS) Publish(ctx context.Context, paths []string) error {
+	for _, path := range paths {
+		if err := p.upload(ctx, path); err != nil {
+			return xerrors.Errorf("failed to upload %s to gs://%s: %w", path, p.config.Bucket, err)
+		}
+	}
+	return nil
+}
+
+func (p GCS) upload(ctx context.Context, path string) error {
+	sums, err := fileChecksums(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	chunkSize := p.config.PartSize
+	if chunkSize == 0 {
+		chunkSize = gcsDefaultChunk
+	}
+	// canceling the context aborts the upload of a writer which isn't closed
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := p.client.Bucket(p.config.Bucket).Object(objectName(p.config.Prefix, path)).NewWriter(ctx)
+	w.ContentType = contentType(path)
+	w.MD5 = sums.md5
+	w.Metadata = map[string]string{"sha256": hex.EncodeToString(sums.sha256)}
+	w.ChunkSize = int(chunkSize)
+	if p.config.PublicRead {
+		w.PredefinedACL = "publicRead"
+	}
+	if _, err = io.Copy(w, f); err != nil {
+		return xerrors.Errorf("failed to upload: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return xerrors.Errorf("failed to complete the upload: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+)
+
+// gcsObject is the part of an object resource of the JSON API the upload sets
+type gcsObject struct {
+	Name        string            `json:"name"`
+	ContentType string            `json:"contentType,omitempty"`
+	MD5Hash     string            `json:"md5Hash,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+type gcsUpload struct {
+	object gcsObject
+	acl    string
+	body   []byte
+}
+
+// gcsBucket serves resumable uploads, counting the chunks, and rejects an object which doesn't match its MD5
+type gcsBucket struct {
+	mu      sync.Mutex
+	chunks  int
+	url     string
+	uploads []*gcsUpload
+}
+
+func (s *gcsBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, _ := ioutil.ReadAll(r.Body)
+
+	if r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/mirror/o" {
+		u := &gcsUpload{acl: r.URL.Query().Get("predefinedAcl")}
+		switch r.URL.Query().Get("uploadType") {
+		case "resumable":
+			if json.Unmarshal(b, &u.object) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			s.uploads = append(s.uploads, u)
+			w.Header().Set("Location", fmt.Sprintf("%s/session/%d", s.url, len(s.uploads)-1))
+		case "multipart":
+			// a file smaller than a chunk is uploaded with its object in a single request
+			if err := readMultipart(r.Header.Get("Content-Type"), b, u); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			s.uploads = append(s.uploads, u)
+			complete(w, u)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		return
+	}
+	i, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/session/"))
+	// the chunks are put or posted to the session
+	if (r.Method != http.MethodPut && r.Method != http.MethodPost) || err != nil || i >= len(s.uploads) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	u := s.uploads[i]
+
+	// e.g. bytes 0-99/200, bytes 0-99/* while the size is unknown, or bytes */200
+	var first, last int64
+	size := int64(-1)
+	contentRange := r.Header.Get("Content-Range")
+	if _, err = fmt.Sscanf(contentRange, "bytes */%d", &size); err != nil {
+		i := strings.LastIndex(contentRange, "/")
+		if _, err = fmt.Sscanf(contentRange[:i], "bytes %d-%d", &first, &last); err != nil ||
+			first != int64(len(u.body)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if total := contentRange[i+1:]; total != "*" {
+			if size, err = strconv.ParseInt(total, 10, 64); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		u.body = append(u.body, b...)
+		s.chunks++
+	}
+	if size < 0 || int64(len(u.body)) < size {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(u.body)-1))
+		// what the storage answers to an incomplete upload instead of a 308 when asked to
+		if r.Header.Get("X-GUploader-No-308") == "yes" {
+			w.Header().Set("X-Http-Status-Code-Override", "308")
+			return
+		}
+		w.WriteHeader(http.StatusPermanentRedirect)
+		return
+	}
+	complete(w, u)
+}
+
+// complete answers the object of a complete upload
+func complete(w http.ResponseWriter, u *gcsUpload) {
+	sum := md5.Sum(u.body)
+	uploaded := u.object
+	uploaded.MD5Hash = base64.StdEncoding.EncodeToString(sum[:])
+	if u.object.MD5Hash != uploaded.MD5Hash {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(uploaded)
+}
+
+// readMultipart reads the object and then the content of a multipart upload
+func readMultipart(contentType string, b []byte, u *gcsUpload) error {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+	mr := multipart.NewReader(bytes.NewReader(b), params["boundary"])
+	part, err := mr.NextPart()
+	if err != nil {
+		return err
+	}
+	if err = json.NewDecoder(part).Decode(&u.object); err != nil {
+		return err
+	}
+	if part, err = mr.NextPart(); err != nil {
+		return err
+	}
+	u.body, err = ioutil.ReadAll(part)
+	return err
+}
+
+func TestGCS_Publish(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestGCS_Publish_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	empty := filepath.Join(d, "trivy.db.gz.sig")
+	assert.NoError(t, ioutil.WriteFile(empty, nil, 0600))
+	large := filepath.Join(d, "trivy.db.gz")
+	content := bytes.Repeat([]byte("0123456789abcdef"), 5*gcsChunkUnit/16)
+	assert.NoError(t, ioutil.WriteFile(large, content, 0600))
+
+	testCases := []struct {
+		name       string
+		publicRead bool
+		wantACL    string
+	}{
+		{name: "private"},
+		{name: "public read", publicRead: true, wantACL: "publicRead"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket := &gcsBucket{}
+			ts := httptest.NewServer(bucket)
+			defer ts.Close()
+			bucket.url = ts.URL
+
+			client, err := storage.NewClient(context.Background(), option.WithEndpoint(ts.URL+"/storage/v1/"),
+				option.WithHTTPClient(ts.Client()))
+			assert.NoError(t, err)
+			p := GCS{
+				config: PublisherConfig{Type: "gcs", Bucket: "mirror", Prefix: "trivy-db/",
+					PublicRead: tc.publicRead, PartSize: 2 * gcsChunkUnit},
+				client: client,
+			}
+			assert.NoError(t, p.Publish(context.Background(), []string{large, empty}))
+
+			assert.Len(t, bucket.uploads, 2)
+			got := bucket.uploads[0]
+			assert.Equal(t, "trivy-db/trivy.db.gz", got.object.Name)
+			assert.Equal(t, "application/gzip", got.object.ContentType)
+			assert.Len(t, got.object.Metadata["sha256"], 64)
+			assert.Equal(t, tc.wantACL, got.acl)
+			assert.Equal(t, content, got.body)
+			// the large file is uploaded in chunks of the part size
+			assert.Equal(t, 3, bucket.chunks)
+
+			got = bucket.uploads[1]
+			assert.Equal(t, "trivy-db/trivy.db.gz.sig", got.object.Name)
+			assert.Equal(t, "text/plain", got.object.ContentType)
+			assert.Empty(t, got.body)
+		})
+	}
+}
+
+func TestNewGCS(t *testing.T) {
+	_, err := NewGCS(context.Background(), PublisherConfig{Type: "gcs", Bucket: "mirror", PartSize: 1 << 20 / 3})
+	assert.EqualError(t, err, "the part size of gcs is a multiple of 262144")
+}
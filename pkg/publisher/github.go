@@ -0,0 +1,46 @@
+package publisher
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/github"
+)
+
+// GitHub creates or updates the release of the hour of the channel with the assets, with the token in GITHUB_TOKEN,
+// then deletes the nightly releases the retention policy doesn't keep
+type GitHub struct {
+	client  github.Client
+	channel string
+}
+
+// NewGitHub returns the publisher of a github config
+func NewGitHub(ctx context.Context, config PublisherConfig) (GitHub, error) {
+	if err := config.Validate(); err != nil {
+		return GitHub{}, err
+	}
+	client := github.NewClient(ctx)
+	if owner, name, _ := splitRepository(config.Repository); owner != "" {
+		client = github.NewRepositoryClient(ctx, owner, name)
+	}
+	client.Retention = github.Retention{Keep: config.Retention.Keep, MaxAge: config.Retention.MaxAge}
+	return GitHub{client: client, channel: config.channel}, nil
+}
+
+// splitRepository splits owner/name, empty for the default repository
+func splitRepository(repository string) (string, string, error) {
+	if repository == "" {
+		return "", "", nil
+	}
+	ss := strings.Split(repository, "/")
+	if len(ss) != 2 || ss[0] == "" || ss[1] == "" {
+		return "", "", xerrors.Errorf("invalid repository %q, owner/name expected", repository)
+	}
+	return ss[0], ss[1], nil
+}
+
+func (p GitHub) Publish(ctx context.Context, paths []string) error {
+	return p.client.UploadReleaseAsset(ctx, p.channel, paths)
+}
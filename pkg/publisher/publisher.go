@@ -0,0 +1,197 @@
+package publisher
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+)
+
+// Config is the publishing part of the pipeline config, see pipeline.Config, e.g.
+//
+//	publishers:
+//	  - type: s3
+//	    bucket: mirror
+//	    prefix: trivy-db/
+//	    region: eu-west-1
+//	    public-read: true
+//	  - type: gcs
+//	    bucket: mirror
+//	    channels: [dev, nightly]
+//	  - type: github
+//	    repository: aquasecurity/trivy-db
+//	    retention:
+//	      keep: 3
+//	      max-age: 72h
+type Config struct {
+	Publishers []PublisherConfig `yaml:"publishers,omitempty"`
+}
+
+// PublisherConfig configures a destination of the assets
+type PublisherConfig struct {
+	Type       string `yaml:"type"` // s3, gcs or github
+	Bucket     string `yaml:"bucket"`
+	Prefix     string `yaml:"prefix"`      // prepended to the file names, e.g. trivy-db/
+	PublicRead bool   `yaml:"public-read"` // lets anyone download the objects
+	PartSize   int64  `yaml:"part-size"`   // bytes per request of an upload, the default of the storage if 0
+	// Channels are the release channels published to the destination, all of them if empty. The assets of
+	// the channels other than stable go under the prefix of their channel, e.g. trivy-db/dev/, or in the
+	// prereleases of their channel.
+	Channels []string `yaml:"channels,omitempty"`
+	channel  string   // of the assets, see ForChannel
+
+	// S3 only
+	Region    string `yaml:"region"`
+	Endpoint  string `yaml:"endpoint"`   // of an S3 compatible storage, e.g. MinIO
+	PathStyle bool   `yaml:"path-style"` // addresses the bucket in the path, which such storages often require
+
+	// GitHub only
+	Repository string          `yaml:"repository"` // owner/name, aquasecurity/trivy-db if empty
+	Retention  RetentionConfig `yaml:"retention"`
+}
+
+// RetentionConfig is how many nightly releases are kept, see github.Retention
+type RetentionConfig struct {
+	Keep   int           `yaml:"keep"`
+	MaxAge time.Duration `yaml:"max-age"` // e.g. 72h
+}
+
+// String is the destination, e.g. s3://mirror/trivy-db/
+func (c PublisherConfig) String() string {
+	switch c.Type {
+	case "s3":
+		return "s3://" + c.Bucket + "/" + c.Prefix
+	case "gcs":
+		return "gs://" + c.Bucket + "/" + c.Prefix
+	case "github":
+		if c.Repository == "" {
+			return "github.com/aquasecurity/trivy-db"
+		}
+		return "github.com/" + c.Repository
+	}
+	return c.Type
+}
+
+// Publisher uploads the assets of a release
+type Publisher interface {
+	Publish(ctx context.Context, paths []string) error
+}
+
+// Validate checks the config without creating the publisher, which may need credentials
+func (c PublisherConfig) Validate() error {
+	for _, channel := range c.Channels {
+		if err := db.ValidateChannel(channel); err != nil {
+			return err
+		}
+	}
+	switch c.Type {
+	case "s3", "gcs":
+		if c.Bucket == "" {
+			return xerrors.New("no bucket")
+		}
+		if c.PartSize < 0 {
+			return xerrors.New("negative part size")
+		}
+		if c.Type == "gcs" && c.PartSize%gcsChunkUnit != 0 {
+			return xerrors.Errorf("the part size of gcs is a multiple of %d", gcsChunkUnit)
+		}
+	case "github":
+		if c.Retention.Keep < 0 || c.Retention.MaxAge < 0 {
+			return xerrors.New("negative retention")
+		}
+		if _, _, err := splitRepository(c.Repository); err != nil {
+			return err
+		}
+	default:
+		return xerrors.Errorf("unknown type %q", c.Type)
+	}
+	return nil
+}
+
+// ForChannel returns the config of the publishers of channel, which publish the assets of the channel
+func (c Config) ForChannel(channel string) Config {
+	if channel == "" {
+		channel = db.ChannelStable
+	}
+	var publishers []PublisherConfig
+	for _, p := range c.Publishers {
+		if len(p.Channels) > 0 && !utils.StringInSlice(channel, p.Channels) {
+			continue
+		}
+		p.channel = channel
+		if !db.IsStable(channel) && p.Type != "github" {
+			p.Prefix += channel + "/"
+		}
+		publishers = append(publishers, p)
+	}
+	return Config{Publishers: publishers}
+}
+
+// New returns the publishers of the config, in order
+func New(ctx context.Context, config Config) ([]Publisher, error) {
+	var publishers []Publisher
+	for i, c := range config.Publishers {
+		if err := c.Validate(); err != nil {
+			return nil, xerrors.Errorf("publisher %d: %w", i, err)
+		}
+		var p Publisher
+		var err error
+		switch c.Type {
+		case "s3":
+			p, err = NewS3(c)
+		case "gcs":
+			p, err = NewGCS(ctx, c)
+		case "github":
+			p, err = NewGitHub(ctx, c)
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("publisher %d: %w", i, err)
+		}
+		publishers = append(publishers, p)
+	}
+	return publishers, nil
+}
+
+// objectName is the name of the object of the file at filePath
+func objectName(prefix, filePath string) string {
+	return strings.TrimPrefix(path.Clean(prefix+filepath.Base(filePath)), "/")
+}
+
+type checksums struct {
+	md5, sha256 []byte
+}
+
+func fileChecksums(filePath string) (checksums, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return checksums{}, xerrors.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer f.Close()
+	m, s := md5.New(), sha256.New()
+	if _, err = io.Copy(io.MultiWriter(m, s), f); err != nil {
+		return checksums{}, xerrors.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return checksums{md5: m.Sum(nil), sha256: s.Sum(nil)}, nil
+}
+
+func contentType(filePath string) string {
+	switch filepath.Ext(filePath) {
+	case ".json":
+		return "application/json"
+	case ".sig", ".pem":
+		return "text/plain"
+	case ".zst":
+		return "application/zstd"
+	}
+	return "application/gzip"
+}
@@ -0,0 +1,69 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  PublisherConfig
+		wantErr string
+	}{
+		{name: "no bucket", config: PublisherConfig{Type: "s3"}, wantErr: "publisher 0: no bucket"},
+		{name: "unknown type", config: PublisherConfig{Type: "azure", Bucket: "mirror"},
+			wantErr: `publisher 0: unknown type "azure"`},
+		{name: "invalid repository", config: PublisherConfig{Type: "github", Repository: "trivy-db"},
+			wantErr: `publisher 0: invalid repository "trivy-db", owner/name expected`},
+		{name: "negative retention", config: PublisherConfig{Type: "github", Retention: RetentionConfig{Keep: -1}},
+			wantErr: "publisher 0: negative retention"},
+		{name: "unknown channel", config: PublisherConfig{Type: "github", Channels: []string{"beta"}},
+			wantErr: `publisher 0: unknown channel "beta", one of dev, nightly, stable`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(context.Background(), Config{Publishers: []PublisherConfig{tc.config}})
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestConfig_ForChannel(t *testing.T) {
+	config := Config{Publishers: []PublisherConfig{
+		{Type: "s3", Bucket: "mirror", Prefix: "trivy-db/"},
+		{Type: "gcs", Bucket: "mirror", Channels: []string{"dev", "nightly"}},
+		{Type: "github", Channels: []string{"stable"}},
+	}}
+	tests := []struct {
+		channel string
+		want    []string
+	}{
+		{channel: "", want: []string{"s3://mirror/trivy-db/", "github.com/aquasecurity/trivy-db"}},
+		{channel: "stable", want: []string{"s3://mirror/trivy-db/", "github.com/aquasecurity/trivy-db"}},
+		{channel: "dev", want: []string{"s3://mirror/trivy-db/dev/", "gs://mirror/dev/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			var got []string
+			for _, p := range config.ForChannel(tt.channel).Publishers {
+				got = append(got, p.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	assert.Equal(t, "trivy-db/trivy.db.gz", objectName("trivy-db/", "assets/trivy.db.gz"))
+	assert.Equal(t, "trivy.db.gz", objectName("", "assets/trivy.db.gz"))
+	assert.Equal(t, "mirror/trivy.db.gz", objectName("/mirror/", "trivy.db.gz"))
+}
+
+func TestPublisherConfig_String(t *testing.T) {
+	assert.Equal(t, "s3://mirror/trivy-db/", PublisherConfig{Type: "s3", Bucket: "mirror", Prefix: "trivy-db/"}.String())
+	assert.Equal(t, "gs://mirror/", PublisherConfig{Type: "gcs", Bucket: "mirror"}.String())
+	assert.Equal(t, "github.com/aquasecurity/trivy-db", PublisherConfig{Type: "github"}.String())
+}
@@ -0,0 +1,83 @@
+package publisher
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"golang.org/x/xerrors"
+)
+
+// S3 uploads to a bucket of S3 or of a compatible storage, with the credentials of the environment,
+// e.g. AWS_ACCESS_KEY_ID or the role of the instance. Files larger than the part size are uploaded in
+// parts, in parallel, and each request carries the MD5 of its body, which the storage checks.
+type S3 struct {
+	config   PublisherConfig
+	uploader *s3manager.Uploader
+}
+
+// NewS3 returns the publisher of an s3 config
+func NewS3(config PublisherConfig) (S3, error) {
+	if err := config.Validate(); err != nil {
+		return S3{}, err
+	}
+	awsConfig := aws.NewConfig().WithS3ForcePathStyle(config.PathStyle)
+	if config.Region != "" {
+		awsConfig = awsConfig.WithRegion(config.Region)
+	}
+	if config.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(config.Endpoint)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return S3{}, xerrors.Errorf("failed to create the AWS session: %w", err)
+	}
+	uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		if config.PartSize > 0 {
+			u.PartSize = config.PartSize
+		}
+	})
+	return S3{config: config, uploader: uploader}, nil
+}
+
+// Publish uploads each file, with the hex SHA-256 of the file in the sha256 metadata of the object
+func (p S3) Publish(ctx context.Context, paths []string) error {
+	for _, path := range paths {
+		if err := p.upload(ctx, path); err != nil {
+			return xerrors.Errorf("failed to upload %s to s3://%s: %w", path, p.config.Bucket, err)
+		}
+	}
+	return nil
+}
+
+func (p S3) upload(ctx context.Context, path string) error {
+	sums, err := fileChecksums(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(p.config.Bucket),
+		Key:         aws.String(objectName(p.config.Prefix, path)),
+		Body:        f,
+		ContentType: aws.String(contentType(path)),
+		Metadata:    map[string]*string{"sha256": aws.String(hex.EncodeToString(sums.sha256))},
+	}
+	if p.config.PublicRead {
+		input.ACL = aws.String(s3.ObjectCannedACLPublicRead)
+	}
+	_, err = p.uploader.UploadWithContext(ctx, input)
+	return err
+}
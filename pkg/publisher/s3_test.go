@@ -0,0 +1,137 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type s3Object struct {
+	body   []byte
+	acl    string
+	sha256 string
+}
+
+// s3Bucket keeps the objects uploaded to it in memory and rejects a body which doesn't match its Content-MD5
+type s3Bucket struct {
+	mu       sync.Mutex
+	objects  map[string]s3Object
+	uploads  map[string]s3Object
+	parts    map[string]map[int][]byte
+	requests int
+}
+
+func (s *s3Bucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	key := strings.TrimPrefix(r.URL.Path, "/mirror/")
+	q := r.URL.Query()
+	b, _ := ioutil.ReadAll(r.Body)
+	if r.Method == http.MethodPut {
+		sum := md5.Sum(b)
+		if r.Header.Get("Content-Md5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	object := s3Object{acl: r.Header.Get("X-Amz-Acl"), sha256: r.Header.Get("X-Amz-Meta-Sha256")}
+
+	switch {
+	case r.Method == http.MethodPut && q.Get("uploadId") == "":
+		object.body = b
+		s.objects[key] = object
+	case r.Method == http.MethodPost && q["uploads"] != nil:
+		id := strconv.Itoa(len(s.uploads) + 1)
+		s.uploads[id] = object
+		s.parts[id] = map[int][]byte{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Bucket>mirror</Bucket><Key>%s</Key>"+
+			"<UploadId>%s</UploadId></InitiateMultipartUploadResult>", key, id)
+		return
+	case r.Method == http.MethodPut:
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		s.parts[q.Get("uploadId")][n] = b
+	case r.Method == http.MethodPost:
+		id := q.Get("uploadId")
+		parts := s.parts[id]
+		var numbers []int
+		for n := range parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		object = s.uploads[id]
+		for _, n := range numbers {
+			object.body = append(object.body, parts[n]...)
+		}
+		s.objects[key] = object
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><Bucket>mirror</Bucket><Key>%s</Key>"+
+			"</CompleteMultipartUploadResult>", key)
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("ETag", `"etag"`)
+}
+
+func TestS3_Publish(t *testing.T) {
+	os.Setenv("AWS_ACCESS_KEY_ID", "key")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	d, err := ioutil.TempDir("", "TestS3_Publish_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	small := filepath.Join(d, "metadata.json")
+	assert.NoError(t, ioutil.WriteFile(small, []byte("{}"), 0600))
+	large := filepath.Join(d, "trivy.db.gz")
+	content := bytes.Repeat([]byte("0123456789abcdef"), 6<<20/16) // 6 MiB, 2 parts of 5 MiB at most
+	assert.NoError(t, ioutil.WriteFile(large, content, 0600))
+
+	testCases := []struct {
+		name       string
+		publicRead bool
+		wantACL    string
+	}{
+		{name: "private"},
+		{name: "public read", publicRead: true, wantACL: "public-read"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket := &s3Bucket{objects: map[string]s3Object{}, uploads: map[string]s3Object{},
+				parts: map[string]map[int][]byte{}}
+			ts := httptest.NewServer(bucket)
+			defer ts.Close()
+
+			p, err := NewS3(PublisherConfig{Type: "s3", Bucket: "mirror", Prefix: "trivy-db/", Region: "us-east-1",
+				Endpoint: ts.URL, PathStyle: true, PublicRead: tc.publicRead, PartSize: 5 << 20})
+			assert.NoError(t, err)
+			assert.NoError(t, p.Publish(context.Background(), []string{small, large}))
+
+			assert.Equal(t, s3Object{body: []byte("{}"), acl: tc.wantACL,
+				sha256: "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"},
+				bucket.objects["trivy-db/metadata.json"])
+			object := bucket.objects["trivy-db/trivy.db.gz"]
+			assert.Equal(t, content, object.body)
+			assert.Equal(t, tc.wantACL, object.acl)
+			assert.Len(t, object.sha256, 64)
+			// the small file is put, the large one is initiated, put in 2 parts and completed
+			assert.Equal(t, 5, bucket.requests)
+		})
+	}
+}
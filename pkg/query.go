@@ -0,0 +1,132 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/server"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func query(c *cli.Context) error {
+	format := c.String("format")
+	if format != "table" && format != "json" {
+		return xerrors.Errorf("unknown format: %s", format)
+	}
+	if err := db.OpenReadOnly(c.String("cache-dir")); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbc := db.Config{}
+	if id := c.String("cve"); id != "" {
+		vuln, err := server.GetVulnerability(dbc, id)
+		if err != nil {
+			return err
+		}
+		if format == "json" {
+			return writeQueryJSON(os.Stdout, vuln)
+		}
+		return writeVulnerability(os.Stdout, vuln)
+	}
+
+	if c.String("purl") == "" && (c.String("os") == "" || c.String("pkg") == "") {
+		return xerrors.New("--os and --pkg, --purl or --cve is required")
+	}
+	advisories, err := server.QueryAdvisories(dbc, server.Query{Source: c.String("os"), Package: c.String("pkg"),
+		Version: c.String("version"), Arch: c.String("arch"), PURL: c.String("purl")})
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		if advisories == nil {
+			advisories = []types.Advisory{}
+		}
+		return writeQueryJSON(os.Stdout, advisories)
+	}
+	return writeAdvisories(os.Stdout, advisories)
+}
+
+func writeQueryJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeAdvisories(w io.Writer, advisories []types.Advisory) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "VULNERABILITY ID\tFIXED VERSION\tSEVERITY\tVENDOR ADVISORY\t")
+	for _, a := range advisories {
+		fixed, severity := a.FixedVersion, "-"
+		if fixed == "" {
+			fixed = "-"
+		}
+		// the severity the source assigned to the advisory, if any
+		if a.Severity != types.SeverityUnknown {
+			severity = a.Severity.String()
+		}
+		vendorIDs := strings.Join(a.VendorIDs, ",")
+		if vendorIDs == "" {
+			vendorIDs = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", a.VulnerabilityID, fixed, severity, vendorIDs)
+	}
+	return tw.Flush()
+}
+
+func writeVulnerability(w io.Writer, vuln server.Vulnerability) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%s\t\n", vuln.ID)
+	fmt.Fprintf(tw, "TITLE\t%s\t\n", vuln.Vulnerability.Title)
+	fmt.Fprintf(tw, "SEVERITY\t%s\t\n", vuln.Vulnerability.Severity)
+	if kev := vuln.Vulnerability.KnownExploited; kev != nil {
+		fmt.Fprintf(tw, "KNOWN EXPLOITED\t%s\t\n", kev.DateAdded)
+	}
+	var cvssSources []string
+	for source := range vuln.Vulnerability.CVSS {
+		cvssSources = append(cvssSources, source)
+	}
+	sort.Strings(cvssSources)
+	for _, source := range cvssSources {
+		fmt.Fprintf(tw, "CVSS (%s)\t%s\t\n", source, formatCVSS(vuln.Vulnerability.CVSS[source]))
+	}
+	fmt.Fprintf(tw, "REFERENCES\t%s\t\n", strings.Join(vuln.Vulnerability.References, " "))
+
+	fmt.Fprintln(tw, "\nSOURCE\tPACKAGE\tDATA SOURCE\t")
+	for _, pkg := range vuln.AffectedPackages {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t\n", pkg.Source, pkg.Package, pkg.DataSource)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if vuln.Vulnerability.Description != "" {
+		_, err := fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(vuln.Vulnerability.Description))
+		return err
+	}
+	return nil
+}
+
+// formatCVSS lists the versions of c, the latest first, e.g. v3 9.8 CVSS:3.1/AV:N/...
+func formatCVSS(c types.CVSS) string {
+	var versions []string
+	for _, v := range []struct {
+		version string
+		score   float64
+		vector  string
+	}{{"v4", c.V4Score, c.V4Vector}, {"v3", c.V3Score, c.V3Vector}, {"v2", c.V2Score, c.V2Vector}} {
+		if v.score == 0 && v.vector == "" {
+			continue
+		}
+		versions = append(versions, strings.TrimSpace(fmt.Sprintf("%s %g %s", v.version, v.score, v.vector)))
+	}
+	return strings.Join(versions, ", ")
+}
@@ -0,0 +1,33 @@
+package pkg
+
+import (
+	"os"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+func replay(c *cli.Context) error {
+	path := c.String("wal")
+	f, err := os.Open(path)
+	if err != nil {
+		return xerrors.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	cacheDir := c.String("cache-dir")
+	if err = db.InitWithDriver(c.String("backend"), cacheDir); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	n, err := db.Config{}.ReplayWAL(f)
+	if err != nil {
+		return err
+	}
+	log.Info("Replayed the write-ahead log", "transactions", n, "path", db.Path(cacheDir))
+	return nil
+}
@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/sbom"
+	"github.com/aquasecurity/trivy-db/pkg/server"
+)
+
+// matchSBOM writes the advisories affecting the packages of the SBOM of the argument, stdin by default or with -
+func matchSBOM(c *cli.Context) error {
+	format := c.String("format")
+	if format != "table" && format != "json" {
+		return xerrors.Errorf("unknown format: %s", format)
+	}
+	var r io.Reader = os.Stdin
+	if path := c.Args().First(); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return xerrors.Errorf("failed to open %s: %w", path, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	bom, err := sbom.Decode(r)
+	if err != nil {
+		return err
+	}
+
+	if err = db.OpenReadOnly(c.String("cache-dir")); err != nil {
+		return err
+	}
+	defer db.Close()
+	matches, err := server.MatchSBOM(db.Config{}, bom)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		return writeQueryJSON(os.Stdout, matches)
+	}
+	return writeMatches(os.Stdout, matches)
+}
+
+func writeMatches(w io.Writer, matches server.SBOMMatches) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE URL\tVULNERABILITY ID\tFIXED VERSION\t")
+	for _, m := range matches.Matches {
+		for _, a := range m.Advisories {
+			fixed := a.FixedVersion
+			if fixed == "" {
+				fixed = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\n", m.PURL, a.VulnerabilityID, fixed)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d of %d packages affected, %d not supported by the DB\n", len(matches.Matches),
+		matches.Packages, len(matches.Unsupported))
+	return err
+}
@@ -0,0 +1,94 @@
+// Package sbom reads the package URLs of the packages of an SBOM, in the JSON formats of CycloneDX and SPDX
+package sbom
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// the formats of Decode
+const (
+	FormatCycloneDX = "cyclonedx"
+	FormatSPDX      = "spdx"
+)
+
+// Package is a package of an SBOM with a package URL, e.g. pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9
+type Package struct {
+	Name string
+	PURL string
+}
+
+// BOM is the packages of an SBOM, in the order of the SBOM
+type BOM struct {
+	Format   string
+	Packages []Package
+}
+
+type cycloneDXComponent struct {
+	Name       string               `json:"name"`
+	PURL       string               `json:"purl"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type document struct {
+	// CycloneDX
+	BOMFormat string `json:"bomFormat"`
+	Metadata  *struct {
+		Component *cycloneDXComponent `json:"component"`
+	} `json:"metadata"`
+	Components []cycloneDXComponent `json:"components"`
+
+	// SPDX
+	SPDXVersion string `json:"spdxVersion"`
+	Packages    []struct {
+		Name         string `json:"name"`
+		ExternalRefs []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+// Decode reads a CycloneDX or SPDX SBOM in JSON, telling them apart by their fields. The packages without
+// a package URL are left out, as they can't be matched.
+func Decode(r io.Reader) (BOM, error) {
+	var doc document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return BOM{}, xerrors.Errorf("failed to decode the SBOM: %w", err)
+	}
+
+	switch {
+	case strings.EqualFold(doc.BOMFormat, "CycloneDX"):
+		bom := BOM{Format: FormatCycloneDX}
+		if doc.Metadata != nil && doc.Metadata.Component != nil {
+			bom.addComponents([]cycloneDXComponent{*doc.Metadata.Component})
+		}
+		bom.addComponents(doc.Components)
+		return bom, nil
+	case strings.HasPrefix(doc.SPDXVersion, "SPDX-"):
+		bom := BOM{Format: FormatSPDX}
+		for _, pkg := range doc.Packages {
+			for _, ref := range pkg.ExternalRefs {
+				if ref.ReferenceType == "purl" && ref.ReferenceLocator != "" {
+					bom.Packages = append(bom.Packages, Package{Name: pkg.Name, PURL: ref.ReferenceLocator})
+				}
+			}
+		}
+		return bom, nil
+	default:
+		return BOM{}, xerrors.New("unknown SBOM format, expected CycloneDX or SPDX in JSON")
+	}
+}
+
+// addComponents adds the components with a package URL and their nested components
+func (b *BOM) addComponents(components []cycloneDXComponent) {
+	for _, c := range components {
+		if c.PURL != "" {
+			b.Packages = append(b.Packages, Package{Name: c.Name, PURL: c.PURL})
+		}
+		b.addComponents(c.Components)
+	}
+}
@@ -0,0 +1,67 @@
+package sbom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    BOM
+		wantErr string
+	}{
+		{
+			name: "cyclonedx",
+			input: `{"bomFormat": "CycloneDX", "specVersion": "1.4",
+				"metadata": {"component": {"name": "app", "purl": "pkg:npm/app@1.0.0"}},
+				"components": [
+					{"name": "openssl", "purl": "pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9"},
+					{"name": "busybox"},
+					{"name": "@babel/core", "purl": "pkg:npm/%40babel/core@7.0.0",
+						"components": [{"name": "json5", "purl": "pkg:npm/json5@2.1.0"}]}]}`,
+			want: BOM{Format: FormatCycloneDX, Packages: []Package{
+				{Name: "app", PURL: "pkg:npm/app@1.0.0"},
+				{Name: "openssl", PURL: "pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9"},
+				{Name: "@babel/core", PURL: "pkg:npm/%40babel/core@7.0.0"},
+				{Name: "json5", PURL: "pkg:npm/json5@2.1.0"},
+			}},
+		},
+		{
+			name: "spdx",
+			input: `{"spdxVersion": "SPDX-2.3", "packages": [
+				{"name": "openssl", "externalRefs": [
+					{"referenceCategory": "SECURITY", "referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:openssl:openssl:1.1.0l:*:*:*:*:*:*:*"},
+					{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9"}]},
+				{"name": "busybox"}]}`,
+			want: BOM{Format: FormatSPDX, Packages: []Package{
+				{Name: "openssl", PURL: "pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9"},
+			}},
+		},
+		{
+			name:    "unknown format",
+			input:   `{"packages": []}`,
+			wantErr: "unknown SBOM format, expected CycloneDX or SPDX in JSON",
+		},
+		{
+			name:    "not JSON",
+			input:   `SPDXVersion: SPDX-2.3`,
+			wantErr: "failed to decode the SBOM",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/server"
+	"github.com/aquasecurity/trivy-db/rpc/lookup"
+)
+
+func serve(c *cli.Context) error {
+	addr, grpcAddr := c.String("listen"), c.String("grpc-listen")
+	if addr == "" && grpcAddr == "" {
+		return xerrors.New("--listen or --grpc-listen is required")
+	}
+	if err := db.OpenReadOnly(c.String("cache-dir")); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	errCh := make(chan error, 2)
+
+	var srv *http.Server
+	if addr != "" {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return xerrors.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		srv = &http.Server{Handler: server.New(db.Config{}), ReadHeaderTimeout: c.Duration("read-timeout")}
+		go func() {
+			errCh <- srv.Serve(l)
+		}()
+		log.Info("Serving the DB over HTTP", "address", l.Addr().String())
+	}
+
+	var grpcSrv *grpc.Server
+	if grpcAddr != "" {
+		l, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return xerrors.Errorf("failed to listen on %s: %w", grpcAddr, err)
+		}
+		grpcSrv = grpc.NewServer()
+		lookup.RegisterLookupServer(grpcSrv, server.NewLookupServer(db.Config{}))
+		go func() {
+			errCh <- grpcSrv.Serve(l)
+		}()
+		log.Info("Serving the DB over gRPC", "address", l.Addr().String())
+	}
+
+	var err error
+	select {
+	case err = <-errCh:
+		err = xerrors.Errorf("failed to serve the DB: %w", err)
+	case <-ctx.Done():
+	}
+
+	// let the requests in flight finish
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), c.Duration("shutdown-timeout"))
+	defer shutdownCancel()
+	if grpcSrv != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcSrv.Stop()
+		}
+	}
+	if srv != nil {
+		if serr := srv.Shutdown(shutdownCtx); serr != nil && err == nil {
+			err = xerrors.Errorf("failed to shut down the server: %w", serr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("Stopped serving the DB")
+	return nil
+}
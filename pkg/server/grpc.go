@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/rpc/lookup"
+)
+
+// LookupServer serves the lookups of a Store over gRPC, registered with lookup.RegisterLookupServer
+type LookupServer struct {
+	lookup.UnimplementedLookupServer
+	store Store
+}
+
+func NewLookupServer(store Store) *LookupServer {
+	return &LookupServer{store: store}
+}
+
+func (s *LookupServer) GetMetadata(context.Context, *lookup.GetMetadataRequest) (*lookup.Metadata, error) {
+	metadata, err := s.store.GetMetadata()
+	if err != nil {
+		return nil, statusError(err)
+	}
+	res := &lookup.Metadata{
+		Version:    int32(metadata.Version),
+		Type:       lookup.Metadata_Type(metadata.Type),
+		NextUpdate: timestamppb.New(metadata.NextUpdate),
+		UpdatedAt:  timestamppb.New(metadata.UpdatedAt),
+	}
+	for name, source := range metadata.Sources {
+		res.Sources = append(res.Sources, &lookup.Source{Name: name, UpdatedAt: timestamppb.New(source.UpdatedAt),
+			Revision: source.Revision})
+	}
+	sort.Slice(res.Sources, func(i, j int) bool {
+		return res.Sources[i].Name < res.Sources[j].Name
+	})
+	return res, nil
+}
+
+func (s *LookupServer) GetAdvisories(_ context.Context, q *lookup.AdvisoryQuery) (*lookup.Advisories, error) {
+	res, err := s.advisories(q)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return res, nil
+}
+
+func (s *LookupServer) BatchGetAdvisories(ctx context.Context, req *lookup.BatchGetAdvisoriesRequest) (
+	*lookup.BatchGetAdvisoriesResponse, error) {
+	res := &lookup.BatchGetAdvisoriesResponse{}
+	for i, q := range req.Queries {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		advisories, err := s.advisories(q)
+		if err != nil {
+			return nil, statusError(xerrors.Errorf("query %d: %w", i, err))
+		}
+		res.Results = append(res.Results, advisories)
+	}
+	return res, nil
+}
+
+func (s *LookupServer) GetVulnerability(_ context.Context, req *lookup.GetVulnerabilityRequest) (
+	*lookup.Vulnerability, error) {
+	vuln, err := GetVulnerability(s.store, req.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toVulnerability(vuln), nil
+}
+
+func (s *LookupServer) BatchGetVulnerabilities(ctx context.Context, req *lookup.BatchGetVulnerabilitiesRequest) (
+	*lookup.BatchGetVulnerabilitiesResponse, error) {
+	res := &lookup.BatchGetVulnerabilitiesResponse{}
+	for _, id := range req.Ids {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		vuln, err := GetVulnerability(s.store, id)
+		if xerrors.Is(err, db.ErrVulnerabilityNotFound) {
+			continue
+		} else if err != nil {
+			return nil, statusError(err)
+		}
+		res.Vulnerabilities = append(res.Vulnerabilities, toVulnerability(vuln))
+	}
+	return res, nil
+}
+
+func (s *LookupServer) advisories(q *lookup.AdvisoryQuery) (*lookup.Advisories, error) {
+	advisories, err := QueryAdvisories(s.store, Query{Source: q.Source, Package: q.Package, Version: q.Version,
+		Arch: q.Arch, PURL: q.Purl})
+	if err != nil {
+		return nil, err
+	}
+	res := &lookup.Advisories{}
+	for _, a := range advisories {
+		res.Advisories = append(res.Advisories, &lookup.Advisory{VulnerabilityId: a.VulnerabilityID,
+			FixedVersion: a.FixedVersion})
+	}
+	return res, nil
+}
+
+func toVulnerability(v Vulnerability) *lookup.Vulnerability {
+	res := &lookup.Vulnerability{
+		Id:          v.ID,
+		Title:       v.Vulnerability.Title,
+		Description: v.Vulnerability.Description,
+		Severity:    v.Vulnerability.Severity,
+		References:  v.Vulnerability.References,
+	}
+	if kev := v.Vulnerability.KnownExploited; kev != nil {
+		res.KnownExploited = &lookup.KnownExploited{DateAdded: kev.DateAdded, Reference: kev.Reference}
+	}
+	for _, pkg := range v.AffectedPackages {
+		res.AffectedPackages = append(res.AffectedPackages, toAffectedPackage(pkg))
+	}
+	return res
+}
+
+func toAffectedPackage(pkg types.AffectedPackage) *lookup.AffectedPackage {
+	return &lookup.AffectedPackage{
+		DataSource: pkg.DataSource,
+		Source:     pkg.Source,
+		Ecosystem:  pkg.Ecosystem,
+		Release:    pkg.Release,
+		Package:    pkg.Package,
+	}
+}
+
+// statusError maps the errors of the lookups to the status codes of gRPC
+func statusError(err error) error {
+	switch {
+	case isInvalidQuery(err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case xerrors.Is(err, db.ErrVulnerabilityNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case xerrors.Is(err, ErrNoDetails):
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+	log.Error("Failed to serve the request", log.Err(err))
+	return status.Error(codes.Internal, err.Error())
+}
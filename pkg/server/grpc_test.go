@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/rpc/lookup"
+)
+
+func TestLookupServer(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestLookupServer_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	defer initDB(t, d)()
+
+	l := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	lookup.RegisterLookupServer(srv, NewLookupServer(db.Config{}))
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufconn", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return l.Dial()
+		}))
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := lookup.NewLookupClient(conn)
+
+	openssl := &lookup.Advisories{Advisories: []*lookup.Advisory{
+		{VulnerabilityId: "CVE-2019-0001", FixedVersion: "1.1.0k-1"},
+		{VulnerabilityId: "CVE-2019-0002", FixedVersion: "1.1.0m-1"},
+	}}
+
+	t.Run("metadata", func(t *testing.T) {
+		got, err := client.GetMetadata(ctx, &lookup.GetMetadataRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(db.SchemaVersion), got.Version)
+		assert.Equal(t, lookup.Metadata_FULL, got.Type)
+		assert.Equal(t, "2020-01-01T00:00:00Z", got.UpdatedAt.AsTime().Format("2006-01-02T15:04:05Z07:00"))
+	})
+
+	t.Run("advisories", func(t *testing.T) {
+		got, err := client.GetAdvisories(ctx, &lookup.AdvisoryQuery{Source: "debian 9", Package: "openssl"})
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(openssl, got), got)
+	})
+
+	t.Run("advisories of an architecture", func(t *testing.T) {
+		got, err := client.GetAdvisories(ctx, &lookup.AdvisoryQuery{Source: "debian 9", Package: "kernel", Arch: "amd64"})
+		assert.NoError(t, err)
+		want := &lookup.Advisories{Advisories: []*lookup.Advisory{
+			{VulnerabilityId: "CVE-2019-0003", FixedVersion: "4.9.168-1"},
+		}}
+		assert.True(t, proto.Equal(want, got), got)
+	})
+
+	t.Run("batch advisories", func(t *testing.T) {
+		got, err := client.BatchGetAdvisories(ctx, &lookup.BatchGetAdvisoriesRequest{Queries: []*lookup.AdvisoryQuery{
+			{Source: "debian 9", Package: "openssl", Version: "1.1.0l-1"},
+			{Purl: "pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9"},
+			{Source: "debian 9", Package: "curl"},
+		}})
+		assert.NoError(t, err)
+		want := &lookup.BatchGetAdvisoriesResponse{Results: []*lookup.Advisories{
+			{Advisories: openssl.Advisories[1:]},
+			openssl,
+			{},
+		}}
+		assert.True(t, proto.Equal(want, got), got)
+
+		_, err = client.BatchGetAdvisories(ctx, &lookup.BatchGetAdvisoriesRequest{Queries: []*lookup.AdvisoryQuery{
+			{Source: "debian 9", Package: "openssl"},
+			{Source: "debian 9"},
+		}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "query 1")
+
+		_, err = client.BatchGetAdvisories(ctx, &lookup.BatchGetAdvisoriesRequest{Queries: []*lookup.AdvisoryQuery{
+			{Source: "debian 9", Package: "openssl", Version: "invalid"},
+		}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("vulnerability", func(t *testing.T) {
+		got, err := client.GetVulnerability(ctx, &lookup.GetVulnerabilityRequest{Id: "CVE-2019-0001"})
+		assert.NoError(t, err)
+		want := &lookup.Vulnerability{Id: "CVE-2019-0001", Title: "openssl", Severity: "HIGH",
+			AffectedPackages: []*lookup.AffectedPackage{
+				{DataSource: "debian", Source: "debian 9", Ecosystem: "debian", Release: "9", Package: "openssl"},
+			}}
+		assert.True(t, proto.Equal(want, got), got)
+
+		_, err = client.GetVulnerability(ctx, &lookup.GetVulnerabilityRequest{Id: "CVE-2019-9999"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("batch vulnerabilities", func(t *testing.T) {
+		got, err := client.BatchGetVulnerabilities(ctx, &lookup.BatchGetVulnerabilitiesRequest{
+			Ids: []string{"CVE-2019-9999", "CVE-2019-0001"}})
+		assert.NoError(t, err)
+		if assert.Len(t, got.Vulnerabilities, 1) {
+			assert.Equal(t, "CVE-2019-0001", got.Vulnerabilities[0].Id)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"github.com/package-url/packageurl-go"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/version"
+)
+
+// ErrNoDetails is returned for the vulnerabilities of a light DB, which has no details nor the index
+// of the affected packages
+var ErrNoDetails = xerrors.New("a light DB has no vulnerability details")
+
+// Query looks up the advisories of a package of a source, e.g. openssl of debian 9, or of a package URL.
+// With a version, only the advisories affecting it are returned, and with an architecture, e.g. aarch64, the
+// ones affecting it with its fixed version. The arch qualifier of a package URL is its architecture.
+type Query struct {
+	Source  string
+	Package string
+	Version string
+	Arch    string
+	PURL    string
+}
+
+// invalidQueryError is the error of a query the client got wrong
+type invalidQueryError struct {
+	err error
+}
+
+func (e invalidQueryError) Error() string {
+	return e.err.Error()
+}
+
+func (e invalidQueryError) Unwrap() error {
+	return e.err
+}
+
+func isInvalidQuery(err error) bool {
+	var invalid invalidQueryError
+	return xerrors.As(err, &invalid)
+}
+
+// QueryAdvisories returns the advisories matching q, rejecting an incomplete query
+func QueryAdvisories(store Store, q Query) ([]types.Advisory, error) {
+	switch {
+	case q.PURL != "":
+		if _, err := packageurl.FromString(q.PURL); err != nil {
+			return nil, invalidQueryError{xerrors.Errorf("invalid package URL: %w", err)}
+		}
+		if q.Arch == "" {
+			q.Arch = db.PURLArch(q.PURL)
+		}
+		advisories, err := store.GetAdvisoriesByPURL(q.PURL)
+		if xerrors.Is(err, db.ErrUnsupportedPURL) {
+			return nil, invalidQueryError{err}
+		}
+		return types.AdvisoriesForArch(advisories, q.Arch), err
+	case q.Source == "" || q.Package == "":
+		return nil, invalidQueryError{xerrors.New("source and package, or purl, are required")}
+	case q.Version != "":
+		comparer, ok := db.SourceComparer(q.Source)
+		if !ok {
+			return nil, invalidQueryError{xerrors.Errorf("no version scheme for %s", q.Source)}
+		}
+		return getAdvisoriesForVersion(store, q.Source, q.Package, q.Version, q.Arch, comparer)
+	default:
+		advisories, err := store.GetAdvisories(q.Source, q.Package)
+		return types.AdvisoriesForArch(advisories, q.Arch), err
+	}
+}
+
+// getAdvisoriesForVersion returns the advisories affecting the installed version of a package on an architecture, all of
+// them if arch is empty. A version the comparer can't parse is an invalid query.
+func getAdvisoriesForVersion(store Store, source, pkgName, installed, arch string, comparer db.Comparer) ([]types.Advisory, error) {
+	if err := version.Validate(comparer, installed); err != nil {
+		return nil, invalidQueryError{xerrors.Errorf("invalid version %q: %w", installed, err)}
+	}
+	if arch == "" {
+		return store.GetAdvisoriesForVersion(source, pkgName, installed, comparer)
+	}
+	advisories, err := store.GetAdvisories(source, pkgName)
+	if err != nil {
+		return nil, err
+	}
+	var results []types.Advisory
+	for _, advisory := range types.AdvisoriesForArch(advisories, arch) {
+		affected, err := db.Affects(advisory, installed, comparer)
+		if err != nil {
+			return nil, err
+		}
+		if affected {
+			results = append(results, advisory)
+		}
+	}
+	return results, nil
+}
+
+// GetVulnerability returns the vulnerability with the packages it affects, db.ErrVulnerabilityNotFound
+// for an unknown ID and ErrNoDetails for a light DB
+func GetVulnerability(store Store, id string) (Vulnerability, error) {
+	metadata, err := store.GetMetadata()
+	if err != nil {
+		return Vulnerability{}, err
+	}
+	if metadata.Type == db.TypeLight {
+		return Vulnerability{}, ErrNoDetails
+	}
+	vuln, err := store.GetVulnerability(id)
+	if xerrors.Is(err, db.ErrVulnerabilityNotFound) {
+		return Vulnerability{}, xerrors.Errorf("%s: %w", id, db.ErrVulnerabilityNotFound)
+	} else if err != nil {
+		return Vulnerability{}, err
+	}
+	pkgs, err := store.GetAffectedPackages(id)
+	if err != nil {
+		return Vulnerability{}, err
+	}
+	return Vulnerability{ID: id, Vulnerability: vuln, AffectedPackages: pkgs}, nil
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/sbom"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// maxSBOMSize bounds the body of POST /v1/sbom
+const maxSBOMSize = 64 << 20
+
+// PackageMatch is a package of an SBOM with the advisories affecting it
+type PackageMatch struct {
+	sbom.Package
+	Source     string // the advisory bucket, e.g. debian 9
+	Advisories []types.Advisory
+}
+
+// SBOMMatches is the response of POST /v1/sbom
+type SBOMMatches struct {
+	Format   string
+	Packages int // with a package URL
+	// Matches are the packages with advisories, in the order of the SBOM
+	Matches []PackageMatch `json:",omitempty"`
+	// Unsupported are the package URLs of the packages the DB has no advisory bucket for, e.g. pkg:maven
+	Unsupported []string `json:",omitempty"`
+}
+
+// MatchSBOM returns the advisories affecting the packages of an SBOM, by their package URLs. The versions of
+// a source without a version scheme can't be compared, its packages get all their advisories.
+func MatchSBOM(store Store, bom sbom.BOM) (SBOMMatches, error) {
+	matches := SBOMMatches{Format: bom.Format, Packages: len(bom.Packages)}
+	for _, pkg := range bom.Packages {
+		source, pkgName, version, err := db.ParsePURL(pkg.PURL)
+		if xerrors.Is(err, db.ErrUnsupportedPURL) {
+			matches.Unsupported = append(matches.Unsupported, pkg.PURL)
+			continue
+		} else if err != nil {
+			return SBOMMatches{}, invalidQueryError{err}
+		}
+
+		var advisories []types.Advisory
+		arch := db.PURLArch(pkg.PURL)
+		if comparer, ok := db.SourceComparer(source); ok && version != "" {
+			advisories, err = getAdvisoriesForVersion(store, source, pkgName, version, arch, comparer)
+		} else {
+			advisories, err = store.GetAdvisories(source, pkgName)
+			advisories = types.AdvisoriesForArch(advisories, arch)
+		}
+		if err != nil {
+			return SBOMMatches{}, xerrors.Errorf("failed to match %s: %w", pkg.PURL, err)
+		}
+		if len(advisories) > 0 {
+			matches.Matches = append(matches.Matches, PackageMatch{Package: pkg, Source: source, Advisories: advisories})
+		}
+	}
+	return matches, nil
+}
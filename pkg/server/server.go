@@ -0,0 +1,145 @@
+// Package server serves read-only lookups of an opened DB over HTTP, for a central lookup service
+// in place of the copies of the DB file of every scanner
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/sbom"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// Store is the part of the DB the server reads, db.Config once the DB is opened
+type Store interface {
+	GetMetadata() (db.Metadata, error)
+	GetAdvisories(source, pkgName string) ([]types.Advisory, error)
+	GetAdvisoriesForVersion(source, pkgName, installed string, comparer db.Comparer) ([]types.Advisory, error)
+	GetAdvisoriesByPURL(purl string) ([]types.Advisory, error)
+	GetVulnerability(cveID string) (types.Vulnerability, error)
+	GetAffectedPackages(cveID string) ([]types.AffectedPackage, error)
+}
+
+// Vulnerability is the response of /v1/vulnerabilities/{id}
+type Vulnerability struct {
+	ID               string
+	Vulnerability    types.Vulnerability
+	AffectedPackages []types.AffectedPackage `json:",omitempty"`
+}
+
+// Server answers:
+//
+//	GET /v1/metadata
+//	GET /v1/advisories?source=debian+9&package=openssl[&version=1.1.0k-1][&arch=aarch64], the advisories
+//	    affecting the version and the architecture if any
+//	GET /v1/advisories?purl=pkg:deb/debian/openssl@1.1.0k-1?distro=debian-9
+//	GET /v1/vulnerabilities/{id}
+//	POST /v1/sbom with a CycloneDX or SPDX SBOM in JSON, the advisories affecting its packages
+type Server struct {
+	store Store
+	mux   *http.ServeMux
+}
+
+func New(store Store) *Server {
+	s := &Server{store: store, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/v1/metadata", s.metadata)
+	s.mux.HandleFunc("/v1/advisories", s.advisories)
+	s.mux.HandleFunc("/v1/vulnerabilities/", s.vulnerability)
+	s.mux.HandleFunc("/v1/sbom", s.sbom)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	allowed := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if r.URL.Path == "/v1/sbom" {
+		allowed = r.Method == http.MethodPost
+	}
+	if !allowed {
+		writeError(w, http.StatusMethodNotAllowed, xerrors.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) metadata(w http.ResponseWriter, _ *http.Request) {
+	metadata, err := s.store.GetMetadata()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, metadata)
+}
+
+func (s *Server) advisories(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	advisories, err := QueryAdvisories(s.store, Query{Source: q.Get("source"), Package: q.Get("package"),
+		Version: q.Get("version"), Arch: q.Get("arch"), PURL: q.Get("purl")})
+	if isInvalidQuery(err) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if advisories == nil {
+		advisories = []types.Advisory{}
+	}
+	writeJSON(w, advisories)
+}
+
+func (s *Server) vulnerability(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/vulnerabilities/")
+	if id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, xerrors.New("not found"))
+		return
+	}
+	vuln, err := GetVulnerability(s.store, id)
+	switch {
+	case xerrors.Is(err, db.ErrVulnerabilityNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case xerrors.Is(err, ErrNoDetails):
+		writeError(w, http.StatusNotImplemented, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, vuln)
+	}
+}
+
+func (s *Server) sbom(w http.ResponseWriter, r *http.Request) {
+	bom, err := sbom.Decode(http.MaxBytesReader(w, r.Body, maxSBOMSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	matches, err := MatchSBOM(s.store, bom)
+	if isInvalidQuery(err) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, matches)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn("Failed to write the response", log.Err(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Error("Failed to serve the request", log.Err(err))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// initDB opens a DB with the advisories of openssl and kernel of debian 9 in dir, closed by the returned function
+func initDB(t *testing.T, dir string) func() {
+	assert.NoError(t, db.Init(dir))
+	dbc := db.Config{}
+	err := dbc.BatchUpdate(context.Background(), func(tx db.Tx) error {
+		for cveID, fixed := range map[string]string{"CVE-2019-0001": "1.1.0k-1", "CVE-2019-0002": "1.1.0m-1"} {
+			if err := dbc.PutAdvisory(tx, "debian 9", "openssl", cveID, types.Advisory{FixedVersion: fixed}); err != nil {
+				return err
+			}
+		}
+		// fixed earlier on amd64
+		err := dbc.PutAdvisory(tx, "debian 9", "kernel", "CVE-2019-0003", types.Advisory{FixedVersion: "4.9.168-2",
+			Arches: []string{"amd64", "arm64"}, ArchFixedVersions: map[string]string{"amd64": "4.9.168-1"}})
+		if err != nil {
+			return err
+		}
+		return dbc.PutVulnerability(tx, "CVE-2019-0001", types.Vulnerability{Title: "openssl", Severity: "HIGH"})
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, dbc.SetMetadata(db.Metadata{Version: db.SchemaVersion, Type: db.TypeFull,
+		UpdatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}))
+	return func() {
+		db.Close()
+	}
+}
+
+func TestServer(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestServer_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	defer initDB(t, d)()
+
+	ts := httptest.NewServer(New(db.Config{}))
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "advisories of a package",
+			path:       "/v1/advisories?source=debian+9&package=openssl",
+			wantStatus: http.StatusOK,
+			wantBody: `[{"VulnerabilityID": "CVE-2019-0001", "FixedVersion": "1.1.0k-1"},
+				{"VulnerabilityID": "CVE-2019-0002", "FixedVersion": "1.1.0m-1"}]`,
+		},
+		{
+			name:       "advisories affecting a version",
+			path:       "/v1/advisories?source=debian+9&package=openssl&version=1.1.0l-1",
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"VulnerabilityID": "CVE-2019-0002", "FixedVersion": "1.1.0m-1"}]`,
+		},
+		{
+			name:       "advisories of a package URL",
+			path:       "/v1/advisories?purl=pkg:deb/debian/openssl@1.1.0l-1%3Fdistro=debian-9",
+			wantStatus: http.StatusOK,
+			wantBody: `[{"VulnerabilityID": "CVE-2019-0001", "FixedVersion": "1.1.0k-1"},
+				{"VulnerabilityID": "CVE-2019-0002", "FixedVersion": "1.1.0m-1"}]`,
+		},
+		{
+			name:       "advisories of an architecture",
+			path:       "/v1/advisories?source=debian+9&package=kernel&arch=amd64",
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"VulnerabilityID": "CVE-2019-0003", "FixedVersion": "4.9.168-1", "Arches": ["amd64"]}]`,
+		},
+		{
+			name:       "version fixed on the architecture",
+			path:       "/v1/advisories?source=debian+9&package=kernel&version=4.9.168-1&arch=amd64",
+			wantStatus: http.StatusOK,
+			wantBody:   `[]`,
+		},
+		{
+			name:       "architecture of a package URL",
+			path:       "/v1/advisories?purl=pkg:deb/debian/kernel@4.9.168-1%3Farch=arm64%26distro=debian-9",
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"VulnerabilityID": "CVE-2019-0003", "FixedVersion": "4.9.168-2", "Arches": ["arm64"]}]`,
+		},
+		{
+			name:       "architecture not affected",
+			path:       "/v1/advisories?source=debian+9&package=kernel&arch=i386",
+			wantStatus: http.StatusOK,
+			wantBody:   `[]`,
+		},
+		{
+			name:       "no advisory",
+			path:       "/v1/advisories?source=debian+9&package=curl",
+			wantStatus: http.StatusOK,
+			wantBody:   `[]`,
+		},
+		{
+			name:       "no package",
+			path:       "/v1/advisories?source=debian+9",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error": "source and package, or purl, are required"}`,
+		},
+		{
+			name:       "version without a scheme",
+			path:       "/v1/advisories?source=rubygems&package=rails&version=5.0.0",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error": "no version scheme for rubygems"}`,
+		},
+		{
+			name:       "invalid version",
+			path:       "/v1/advisories?source=debian+9&package=openssl&version=invalid",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error": "invalid version \"invalid\": invalid dpkg version invalid: upstream_version must start with digit"}`,
+		},
+		{
+			name:       "vulnerability",
+			path:       "/v1/vulnerabilities/CVE-2019-0001",
+			wantStatus: http.StatusOK,
+			wantBody: `{"ID": "CVE-2019-0001", "Vulnerability": {"Title": "openssl", "Severity": "HIGH"},
+				"AffectedPackages": [{"DataSource": "debian", "Source": "debian 9", "Ecosystem": "debian", "Release": "9", "Package": "openssl"}]}`,
+		},
+		{
+			name:       "unknown vulnerability",
+			path:       "/v1/vulnerabilities/CVE-2019-9999",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error": "CVE-2019-9999: vulnerability not found"}`,
+		},
+		{
+			name:       "metadata",
+			path:       "/v1/metadata",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"Version": 2, "Type": 1, "NextUpdate": "0001-01-01T00:00:00Z", "UpdatedAt": "2020-01-01T00:00:00Z"}`,
+		},
+		{
+			name:       "read-only",
+			method:     http.MethodPost,
+			path:       "/v1/metadata",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   `{"error": "method POST not allowed"}`,
+		},
+		{
+			name:   "sbom",
+			method: http.MethodPost,
+			path:   "/v1/sbom",
+			body: `{"bomFormat": "CycloneDX", "components": [
+				{"name": "openssl", "purl": "pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9"},
+				{"name": "curl", "purl": "pkg:deb/debian/curl@7.52.1-5?distro=debian-9"},
+				{"name": "log4j-core", "purl": "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"}]}`,
+			wantStatus: http.StatusOK,
+			wantBody: `{"Format": "cyclonedx", "Packages": 3,
+				"Matches": [{"Name": "openssl", "PURL": "pkg:deb/debian/openssl@1.1.0l-1?distro=debian-9", "Source": "debian 9",
+					"Advisories": [{"VulnerabilityID": "CVE-2019-0002", "FixedVersion": "1.1.0m-1"}]}],
+				"Unsupported": ["pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"]}`,
+		},
+		{
+			name:       "invalid sbom",
+			method:     http.MethodPost,
+			path:       "/v1/sbom",
+			body:       `{"bomFormat": "unknown"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error": "unknown SBOM format, expected CycloneDX or SPDX in JSON"}`,
+		},
+		{
+			name:   "invalid version in sbom",
+			method: http.MethodPost,
+			path:   "/v1/sbom",
+			body: `{"bomFormat": "CycloneDX", "components": [
+				{"name": "openssl", "purl": "pkg:deb/debian/openssl@invalid?distro=debian-9"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"error": "failed to match pkg:deb/debian/openssl@invalid?distro=debian-9: ` +
+				`invalid version \"invalid\": invalid dpkg version invalid: upstream_version must start with digit"}`,
+		},
+		{
+			name:       "sbom is posted",
+			path:       "/v1/sbom",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   `{"error": "method GET not allowed"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req, err := http.NewRequest(method, ts.URL+tt.path, strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			resp, err := ts.Client().Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			b, err := ioutil.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.JSONEq(t, tt.wantBody, string(b))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/artifact"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/sign"
+)
+
+func signAssets(c *cli.Context) error {
+	var signer sign.Signer
+	switch key := c.String("key"); {
+	case key != "" && c.Bool("keyless"):
+		return xerrors.New("--key and --keyless are exclusive")
+	case key != "":
+		signer = sign.KeySigner{Cosign: c.String("cosign"), Key: key}
+	case c.Bool("keyless"):
+		signer = sign.KeylessSigner{Cosign: c.String("cosign")}
+	default:
+		return xerrors.New("either --key or --keyless is required")
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	return signDir(ctx, signer, c.String("dir"))
+}
+
+// signDir signs the compressed DBs of dir, writing the signatures next to them for cosign verify-blob,
+// and records them with the digests in the metadata
+func signDir(ctx context.Context, signer sign.Signer, dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return xerrors.Errorf("unable to list files: %w", err)
+	}
+	metadata, err := artifact.ReadMetadata(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !artifact.IsArchive(f.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		sig, err := signer.Sign(ctx, path)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(path+".sig", []byte(sig.Signature), 0644); err != nil {
+			return xerrors.Errorf("failed to write the signature: %w", err)
+		}
+		if sig.Certificate != "" {
+			if err = ioutil.WriteFile(path+".pem", []byte(sig.Certificate), 0644); err != nil {
+				return xerrors.Errorf("failed to write the certificate: %w", err)
+			}
+		}
+		a := metadata.Artifacts[f.Name()]
+		if a.Digest != sig.Digest {
+			// the description is of a previous file
+			a = artifact.ArtifactMetadata{Digest: sig.Digest}
+		}
+		a.Signature = &sig
+		metadata.Artifacts[f.Name()] = a
+		log.Info("Signed an artifact", "file", path, "digest", sig.Digest)
+	}
+	return metadata.Write(dir)
+}
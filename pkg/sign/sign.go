@@ -0,0 +1,223 @@
+package sign
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+)
+
+// Signature is a cosign signature of an artifact, which cosign verify-blob checks
+type Signature struct {
+	Digest    string // of the artifact, e.g. sha256:...
+	Signature string // base64 ASN.1 ECDSA signature of the SHA-256 of the artifact, as written by cosign sign-blob
+	// Certificate is the PEM of the short-lived certificate of a keyless signature
+	Certificate string `json:",omitempty"`
+}
+
+// Signer signs the artifacts of a release
+type Signer interface {
+	Sign(ctx context.Context, path string) (Signature, error)
+}
+
+// KeySigner runs cosign sign-blob with a key, e.g. one of cosign generate-key-pair whose password cosign
+// reads from COSIGN_PASSWORD. The signature isn't logged to Rekor, it's verified with the public key.
+type KeySigner struct {
+	Cosign string // the path of the executable, cosign in the PATH if empty
+	Key    string // the path of the private key, or a KMS URI
+}
+
+func (s KeySigner) Sign(ctx context.Context, path string) (Signature, error) {
+	return signBlob(ctx, cosignPath(s.Cosign), path, false, "--key", s.Key, "--tlog-upload=false")
+}
+
+// KeylessSigner runs cosign sign-blob, which gets a certificate for the OIDC identity of the environment,
+// e.g. the one of a GitHub Actions workflow, from Fulcio and logs the signature to Rekor
+type KeylessSigner struct {
+	Cosign string // the path of the executable, cosign in the PATH if empty
+}
+
+func (s KeylessSigner) Sign(ctx context.Context, path string) (Signature, error) {
+	return signBlob(ctx, cosignPath(s.Cosign), path, true)
+}
+
+// signBlob runs cosign sign-blob with args, reading the certificate of a keyless signature
+func signBlob(ctx context.Context, cosign, path string, keyless bool, args ...string) (Signature, error) {
+	digest, err := fileDigest(path)
+	if err != nil {
+		return Signature{}, err
+	}
+	dir, err := ioutil.TempDir("", "trivy-db-sign-")
+	if err != nil {
+		return Signature{}, xerrors.Errorf("failed to create a temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sigPath, certPath := filepath.Join(dir, "sig"), filepath.Join(dir, "cert")
+	args = append(append([]string{"sign-blob", "--yes"}, args...), "--output-signature", sigPath)
+	if keyless {
+		args = append(args, "--output-certificate", certPath)
+	}
+	cmd := exec.CommandContext(ctx, cosign, append(args, path)...)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return Signature{}, xerrors.Errorf("failed to sign %s with cosign: %w", path, err)
+	}
+
+	sig, err := ioutil.ReadFile(sigPath)
+	if err != nil {
+		return Signature{}, xerrors.Errorf("failed to read the signature: %w", err)
+	}
+	signature := Signature{
+		Digest:    "sha256:" + hex.EncodeToString(digest),
+		Signature: string(sig),
+	}
+	if !keyless {
+		return signature, nil
+	}
+	cert, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return Signature{}, xerrors.Errorf("failed to read the certificate: %w", err)
+	}
+	// cosign writes the certificate base64 encoded
+	if decoded, err := base64.StdEncoding.DecodeString(string(cert)); err == nil {
+		cert = decoded
+	}
+	signature.Certificate = string(cert)
+	return signature, nil
+}
+
+func cosignPath(path string) string {
+	if path == "" {
+		return "cosign"
+	}
+	return path
+}
+
+// Verify checks the artifact at path against the signature with the PEM public key of the signer.
+// A keyless signature is only as good as the identity of its certificate, so it's refused here;
+// KeylessVerifier checks it.
+func Verify(path string, sig Signature, publicKey []byte) error {
+	if publicKey == nil {
+		if sig.Certificate != "" {
+			return xerrors.New("a keyless signature needs the expected identity, see KeylessVerifier")
+		}
+		return xerrors.New("no public key")
+	}
+	digest, err := checkDigest(path, sig)
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return xerrors.New("invalid public key: no PEM")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return xerrors.Errorf("invalid public key: %w", err)
+	}
+	key, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return xerrors.Errorf("unsupported public key: %T", pub)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return xerrors.Errorf("invalid signature: %w", err)
+	}
+	var es ecdsaSignature
+	if rest, err := asn1.Unmarshal(b, &es); err != nil || len(rest) > 0 {
+		return xerrors.New("invalid signature: not an ASN.1 ECDSA signature")
+	}
+	if !ecdsa.Verify(key, digest, es.R, es.S) {
+		return xerrors.Errorf("invalid signature of %s", path)
+	}
+	return nil
+}
+
+// KeylessVerifier runs cosign verify-blob, which checks the certificate of a keyless signature against
+// the Fulcio roots, its identity and the Rekor entry of the signature
+type KeylessVerifier struct {
+	Cosign string // the path of the executable, cosign in the PATH if empty
+
+	// Identity and OIDCIssuer are the ones the certificate must have been issued for, e.g. the workflow
+	// https://github.com/aquasecurity/trivy-db/.github/workflows/cron.yml@refs/heads/main and
+	// https://token.actions.githubusercontent.com
+	Identity   string
+	OIDCIssuer string
+}
+
+func (v KeylessVerifier) Verify(ctx context.Context, path string, sig Signature) error {
+	if v.Identity == "" || v.OIDCIssuer == "" {
+		return xerrors.New("the expected identity and OIDC issuer are required to verify a keyless signature")
+	}
+	if sig.Certificate == "" {
+		return xerrors.New("no certificate: not a keyless signature")
+	}
+	if _, err := checkDigest(path, sig); err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "trivy-db-verify-")
+	if err != nil {
+		return xerrors.Errorf("failed to create a temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sigPath, certPath := filepath.Join(dir, "sig"), filepath.Join(dir, "cert")
+	if err = ioutil.WriteFile(sigPath, []byte(sig.Signature), 0600); err != nil {
+		return xerrors.Errorf("failed to write the signature: %w", err)
+	}
+	if err = ioutil.WriteFile(certPath, []byte(sig.Certificate), 0600); err != nil {
+		return xerrors.Errorf("failed to write the certificate: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, cosignPath(v.Cosign), "verify-blob",
+		"--certificate-identity", v.Identity, "--certificate-oidc-issuer", v.OIDCIssuer,
+		"--signature", sigPath, "--certificate", certPath, path)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return xerrors.Errorf("invalid signature of %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkDigest returns the digest of the artifact at path, failing if it isn't the one signed
+func checkDigest(path string, sig Signature) ([]byte, error) {
+	digest, err := fileDigest(path)
+	if err != nil {
+		return nil, err
+	}
+	if sig.Digest != "" && sig.Digest != "sha256:"+hex.EncodeToString(digest) {
+		return nil, xerrors.Errorf("digest mismatch: %s isn't the signed artifact", path)
+	}
+	return digest, nil
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func fileDigest(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return nil, xerrors.Errorf("failed to read %s: %w", path, err)
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,244 @@
+package sign
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// signFile signs the artifact at path with key as cosign sign-blob does
+func signFile(t *testing.T, key *ecdsa.PrivateKey, path string) Signature {
+	digest, err := fileDigest(path)
+	assert.NoError(t, err)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest)
+	assert.NoError(t, err)
+	return Signature{Digest: "sha256:" + hex.EncodeToString(digest), Signature: base64.StdEncoding.EncodeToString(sig)}
+}
+
+func TestKeySigner_Sign(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestKeySigner_Sign_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, "trivy.db.gz")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("db"), 0600))
+
+	// signs with the key, without logging the signature to Rekor nor writing a certificate
+	cosign := filepath.Join(d, "cosign")
+	assert.NoError(t, ioutil.WriteFile(cosign, []byte(`#!/bin/sh
+test "$1" = sign-blob || exit 1
+shift
+tlog=true
+while [ $# -gt 1 ]; do
+  case "$1" in
+    --key) test "$2" = cosign.key || exit 1; shift ;;
+    --tlog-upload=false) tlog=false ;;
+    --output-signature) printf 'c2ln' > "$2"; shift ;;
+    --output-certificate) exit 1 ;;
+  esac
+  shift
+done
+test "$tlog" = false && test "$1" = "`+path+`"
+`), 0700))
+
+	got, err := KeySigner{Cosign: cosign, Key: "cosign.key"}.Sign(context.Background(), path)
+	assert.NoError(t, err)
+	assert.Equal(t, "c2ln", got.Signature)
+	assert.Empty(t, got.Certificate)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", got.Digest)
+
+	_, err = KeySigner{Cosign: cosign, Key: "other.key"}.Sign(context.Background(), path)
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	pub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		key       *ecdsa.PrivateKey
+		keyless   bool
+		publicKey []byte
+		tamper    bool // the artifact changes after it was signed
+		wantErr   string
+	}{
+		{
+			name:      "happy path",
+			key:       key,
+			publicKey: pub,
+		},
+		{
+			name:      "another key",
+			key:       other,
+			publicKey: pub,
+			wantErr:   "invalid signature",
+		},
+		{
+			name:      "tampered artifact",
+			key:       key,
+			publicKey: pub,
+			tamper:    true,
+			wantErr:   "digest mismatch",
+		},
+		{
+			name:    "keyless signature",
+			key:     key,
+			keyless: true,
+			wantErr: "a keyless signature needs the expected identity",
+		},
+		{
+			name:    "no public key",
+			key:     key,
+			wantErr: "no public key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestVerify_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+			path := filepath.Join(d, "trivy.db.gz")
+			assert.NoError(t, ioutil.WriteFile(path, []byte("db"), 0600))
+
+			sig := signFile(t, tt.key, path)
+			if tt.keyless {
+				sig.Certificate = "CERT"
+			}
+			if tt.tamper {
+				assert.NoError(t, ioutil.WriteFile(path, []byte("tampered"), 0600))
+			}
+			err = Verify(path, sig, tt.publicKey)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestKeylessSigner_Sign(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestKeylessSigner_Sign_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, "trivy.db.gz")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("db"), 0600))
+
+	// writes the signature and the base64 certificate where cosign sign-blob is told to
+	cosign := filepath.Join(d, "cosign")
+	assert.NoError(t, ioutil.WriteFile(cosign, []byte(`#!/bin/sh
+while [ $# -gt 1 ]; do
+  case "$1" in
+    --output-signature) printf 'c2ln' > "$2"; shift ;;
+    --output-certificate) printf 'Q0VSVA==' > "$2"; shift ;;
+  esac
+  shift
+done
+test "$1" = "`+path+`"
+`), 0700))
+
+	got, err := KeylessSigner{Cosign: cosign}.Sign(context.Background(), path)
+	assert.NoError(t, err)
+	assert.Equal(t, "c2ln", got.Signature)
+	assert.Equal(t, "CERT", got.Certificate)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", got.Digest)
+}
+
+func TestKeylessVerifier_Verify(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestKeylessVerifier_Verify_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, "trivy.db.gz")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("db"), 0600))
+	digest, err := fileDigest(path)
+	assert.NoError(t, err)
+
+	// accepts the signature only for the identity and the issuer of the workflow
+	cosign := filepath.Join(d, "cosign")
+	assert.NoError(t, ioutil.WriteFile(cosign, []byte(`#!/bin/sh
+test "$1" = verify-blob || exit 1
+shift
+while [ $# -gt 1 ]; do
+  case "$1" in
+    --certificate-identity) test "$2" = workflow || exit 1; shift ;;
+    --certificate-oidc-issuer) test "$2" = https://token.actions.githubusercontent.com || exit 1; shift ;;
+    --signature) test "$(cat "$2")" = c2ln || exit 1; shift ;;
+    --certificate) test "$(cat "$2")" = CERT || exit 1; shift ;;
+  esac
+  shift
+done
+test "$1" = "`+path+`"
+`), 0700))
+	sig := Signature{Digest: "sha256:" + hex.EncodeToString(digest), Signature: "c2ln", Certificate: "CERT"}
+
+	tests := []struct {
+		name     string
+		verifier KeylessVerifier
+		sig      Signature
+		wantErr  string
+	}{
+		{
+			name:     "happy path",
+			verifier: KeylessVerifier{Identity: "workflow", OIDCIssuer: "https://token.actions.githubusercontent.com"},
+			sig:      sig,
+		},
+		{
+			name:     "another identity",
+			verifier: KeylessVerifier{Identity: "fork", OIDCIssuer: "https://token.actions.githubusercontent.com"},
+			sig:      sig,
+			wantErr:  "invalid signature",
+		},
+		{
+			name:     "no identity",
+			verifier: KeylessVerifier{OIDCIssuer: "https://token.actions.githubusercontent.com"},
+			sig:      sig,
+			wantErr:  "the expected identity and OIDC issuer are required",
+		},
+		{
+			name:     "no certificate",
+			verifier: KeylessVerifier{Identity: "workflow", OIDCIssuer: "https://token.actions.githubusercontent.com"},
+			sig:      Signature{Digest: sig.Digest, Signature: "c2ln"},
+			wantErr:  "not a keyless signature",
+		},
+		{
+			name:     "digest mismatch",
+			verifier: KeylessVerifier{Identity: "workflow", OIDCIssuer: "https://token.actions.githubusercontent.com"},
+			sig:      Signature{Digest: "sha256:abc", Signature: "c2ln", Certificate: "CERT"},
+			wantErr:  "digest mismatch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.verifier.Cosign = cosign
+			err := tt.verifier.Verify(context.Background(), path, tt.sig)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// the certificate alone doesn't make a keyless signature valid
+	err = Verify(path, sig, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "needs the expected identity")
+}
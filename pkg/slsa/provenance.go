@@ -0,0 +1,182 @@
+// Package slsa describes how a DB was built as an in-toto statement with a SLSA provenance predicate,
+// which consumers check the inputs and the outputs of the build with, e.g. with slsa-verifier or cosign
+// verify-attestation once it is signed.
+package slsa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/fetch"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc"
+)
+
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://slsa.dev/provenance/v1"
+	// BuildType is the one of the builds of the build command, whose external parameters are
+	// the type and the sources of the DB
+	BuildType = "https://github.com/aquasecurity/trivy-db/build@v1"
+	// MediaType is the one of a statement attached to an OCI artifact
+	MediaType = "application/vnd.in-toto+json"
+)
+
+// Statement is an in-toto statement about the subjects, the artifacts of the build
+type Statement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     Provenance           `json:"predicate"`
+}
+
+// ResourceDescriptor is an artifact or an input of the build, identified by its digests,
+// e.g. {"sha256": "..."} or {"gitCommit": "..."}
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance is the SLSA provenance predicate
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType          string                 `json:"buildType"`
+	ExternalParameters map[string]interface{} `json:"externalParameters"`
+	// ResolvedDependencies are the commits of the repositories the sources were read from
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+type RunDetails struct {
+	Builder  Builder       `json:"builder"`
+	Metadata BuildMetadata `json:"metadata"`
+}
+
+// Builder is what ran the build, e.g. a GitHub Actions workflow, and the version of trivy-db it ran
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+type BuildMetadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// New describes the build of the DB whose metadata is given, producing the subjects. The sources are
+// the parameters of the build and the revisions they were read from its dependencies.
+func New(metadata db.Metadata, builder Builder, invocationID string, subjects []ResourceDescriptor) Statement {
+	var sources []string
+	var deps []ResourceDescriptor
+	seen := map[string]bool{}
+	for name, source := range metadata.Sources {
+		sources = append(sources, name)
+		repos := vulnsrc.Repositories([]string{name})
+		if source.Revision == "" || len(repos) == 0 || seen[repos[0]] {
+			continue
+		}
+		seen[repos[0]] = true
+		dep := ResourceDescriptor{Name: repos[0], Digest: map[string]string{"gitCommit": source.Revision}}
+		if upstream, ok := fetch.Upstreams[repos[0]]; ok {
+			dep.URI = "git+" + upstream
+		}
+		deps = append(deps, dep)
+	}
+	sort.Strings(sources)
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].Name < deps[j].Name
+	})
+
+	dbType := "full"
+	if metadata.Type == db.TypeLight {
+		dbType = "light"
+	}
+	finishedOn := metadata.UpdatedAt.UTC()
+	return Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateType,
+		Predicate: Provenance{
+			BuildDefinition: BuildDefinition{
+				BuildType: BuildType,
+				ExternalParameters: map[string]interface{}{
+					"schemaVersion": metadata.Version,
+					"type":          dbType,
+					"sources":       sources,
+				},
+				ResolvedDependencies: deps,
+			},
+			RunDetails: RunDetails{
+				Builder:  builder,
+				Metadata: BuildMetadata{InvocationID: invocationID, FinishedOn: &finishedOn},
+			},
+		},
+	}
+}
+
+// FileSubject describes the file at path by its name and its SHA-256
+func FileSubject(path string) (ResourceDescriptor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ResourceDescriptor{}, xerrors.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return ResourceDescriptor{}, xerrors.Errorf("failed to read %s: %w", path, err)
+	}
+	return ResourceDescriptor{
+		Name:   filepath.Base(path),
+		Digest: map[string]string{"sha256": hex.EncodeToString(h.Sum(nil))},
+	}, nil
+}
+
+// DigestSubject describes an artifact by its name and its digest, e.g. sha256:...
+func DigestSubject(name, digest string) (ResourceDescriptor, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return ResourceDescriptor{}, xerrors.Errorf("invalid digest: %s", digest)
+	}
+	return ResourceDescriptor{Name: name, Digest: map[string]string{parts[0]: parts[1]}}, nil
+}
+
+// GitHubActions returns the builder and the invocation of the GitHub Actions run in the environment,
+// and false outside of one
+func GitHubActions() (Builder, string, bool) {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return Builder{}, "", false
+	}
+	server, repo := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY")
+	builder := Builder{ID: server + "/" + repo + "/.github/workflows"}
+	if ref := os.Getenv("GITHUB_WORKFLOW_REF"); ref != "" {
+		// e.g. aquasecurity/trivy-db/.github/workflows/cron.yml@refs/heads/main
+		builder.ID = server + "/" + ref
+	}
+	invocation := server + "/" + repo + "/actions/runs/" + os.Getenv("GITHUB_RUN_ID")
+	if attempt := os.Getenv("GITHUB_RUN_ATTEMPT"); attempt != "" {
+		invocation += "/attempts/" + attempt
+	}
+	return builder, invocation, true
+}
+
+// Marshal returns the JSON of the statement
+func (s Statement) Marshal() ([]byte, error) {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode the provenance: %w", err)
+	}
+	return b, nil
+}
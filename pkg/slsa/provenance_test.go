@@ -0,0 +1,109 @@
+package slsa
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+)
+
+func TestNew(t *testing.T) {
+	builtAt := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+	metadata := db.Metadata{
+		Version:   db.SchemaVersion,
+		Type:      db.TypeLight,
+		UpdatedAt: builtAt,
+		Sources: map[string]db.SourceMetadata{
+			// both read from vuln-list
+			vulnerability.Alpine:  {UpdatedAt: builtAt, Revision: "abc"},
+			vulnerability.Amazon:  {UpdatedAt: builtAt, Revision: "abc"},
+			vulnerability.RubySec: {UpdatedAt: builtAt, Revision: "def"},
+			// built from a dir which isn't a git repository
+			vulnerability.Debian: {UpdatedAt: builtAt},
+		},
+	}
+	builder := Builder{ID: "https://example.com/builder", Version: map[string]string{"trivy-db": "dev"}}
+	subject := ResourceDescriptor{Name: "trivy-light.db.gz", Digest: map[string]string{"sha256": "0123"}}
+
+	got := New(metadata, builder, "run/1", []ResourceDescriptor{subject})
+	assert.Equal(t, StatementType, got.Type)
+	assert.Equal(t, PredicateType, got.PredicateType)
+	assert.Equal(t, []ResourceDescriptor{subject}, got.Subject)
+
+	def := got.Predicate.BuildDefinition
+	assert.Equal(t, BuildType, def.BuildType)
+	assert.Equal(t, map[string]interface{}{
+		"schemaVersion": db.SchemaVersion,
+		"type":          "light",
+		"sources":       []string{vulnerability.Alpine, vulnerability.Amazon, vulnerability.Debian, vulnerability.RubySec},
+	}, def.ExternalParameters)
+	assert.Equal(t, []ResourceDescriptor{
+		{Name: "ruby-advisory-db", URI: "git+https://github.com/rubysec/ruby-advisory-db.git",
+			Digest: map[string]string{"gitCommit": "def"}},
+		{Name: "vuln-list", URI: "git+https://github.com/aquasecurity/vuln-list.git",
+			Digest: map[string]string{"gitCommit": "abc"}},
+	}, def.ResolvedDependencies)
+
+	run := got.Predicate.RunDetails
+	assert.Equal(t, builder, run.Builder)
+	assert.Equal(t, "run/1", run.Metadata.InvocationID)
+	assert.Equal(t, builtAt, *run.Metadata.FinishedOn)
+
+	b, err := got.Marshal()
+	assert.NoError(t, err)
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, StatementType, decoded["_type"])
+}
+
+func TestFileSubject(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestFileSubject_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, "trivy.db")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("bolt"), 0600))
+
+	got, err := FileSubject(path)
+	assert.NoError(t, err)
+	assert.Equal(t, ResourceDescriptor{Name: "trivy.db", Digest: map[string]string{
+		"sha256": "d0b3cba71f725563d316ea3516099328042095d10f4571be25c07f9ce31985a5"}}, got)
+
+	_, err = FileSubject(filepath.Join(d, "missing"))
+	assert.Error(t, err)
+}
+
+func TestDigestSubject(t *testing.T) {
+	tests := []struct {
+		name    string
+		digest  string
+		want    ResourceDescriptor
+		wantErr bool
+	}{
+		{
+			name:   "happy path",
+			digest: "sha256:0123",
+			want: ResourceDescriptor{Name: "ghcr.io/aquasecurity/trivy-db",
+				Digest: map[string]string{"sha256": "0123"}},
+		},
+		{name: "no algorithm", digest: "0123", wantErr: true},
+		{name: "no hex", digest: "sha256:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DigestSubject("ghcr.io/aquasecurity/trivy-db", tt.digest)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// statsReport is the output of stats, which can be compared release over release
+type statsReport struct {
+	Advisories db.AdvisoryStats
+	Buckets    []db.BucketStats
+}
+
+func stats(c *cli.Context) error {
+	if err := db.OpenReadOnly(c.String("cache-dir")); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbc := db.Config{}
+	advisories, err := dbc.AdvisoryStats()
+	if err != nil {
+		return err
+	}
+	buckets, err := dbc.Stats()
+	if err != nil {
+		return err
+	}
+	report := statsReport{Advisories: advisories, Buckets: buckets}
+
+	switch format := c.String("format"); format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "table":
+		return writeStats(os.Stdout, report)
+	default:
+		return xerrors.Errorf("unknown format: %s", format)
+	}
+}
+
+func writeStats(w io.Writer, report statsReport) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "ADVISORIES\t%d\t\n", report.Advisories.Advisories)
+	writeCounts(tw, "SEVERITY", report.Advisories.Severities, severityOrder)
+	writeCounts(tw, "ECOSYSTEM", report.Advisories.Ecosystems, nil)
+	writeCounts(tw, "SOURCE", report.Advisories.Sources, nil)
+
+	fmt.Fprintln(tw, "\nBUCKET\tKEYS\tBUCKETS\tVALUE BYTES\tDEPTH\tSIZE\t")
+	for _, s := range report.Buckets {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t\n", s.Name, s.Keys, s.Buckets, s.ValueBytes, s.Depth, s.Size)
+	}
+	return tw.Flush()
+}
+
+// writeCounts writes the counts in the order of less, or largest first
+func writeCounts(w io.Writer, name string, counts map[string]int, less func(a, b string) bool) {
+	var keys []string
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if less != nil {
+			return less(keys[i], keys[j])
+		}
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Fprintf(w, "\n%s\tADVISORIES\t\n", name)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%d\t\n", k, counts[k])
+	}
+}
+
+// severityOrder sorts the most severe first
+func severityOrder(a, b string) bool {
+	return types.CompareSeverityString(a, b) < 0
+}
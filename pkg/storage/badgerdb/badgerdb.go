@@ -0,0 +1,354 @@
+package badgerdb
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// DriverName is the name this driver is registered under
+const DriverName = "badger"
+
+// bucketMeta is set as user meta on the keys marking nested buckets
+const bucketMeta byte = 1
+
+// Badger has a flat keyspace, so a key is the path of bucket names followed by the key itself.
+// Each component is escaped (0x00 -> 0x00 0xFF) and terminated by 0x00 0x01, which keeps
+// bolt's byte-wise ordering within a bucket. A nested bucket is marked by a key with
+// bucketMeta and its own key is the prefix of everything inside it.
+
+func init() {
+	storage.Register(DriverName, Driver{})
+}
+
+// Driver is an experimental LSM-tree backend for bulk builds. The path is a directory.
+type Driver struct{}
+
+func (Driver) Open(path string, opts storage.Options) (storage.Store, error) {
+	o := badger.DefaultOptions(path).
+		WithLogger(nil).
+		WithSyncWrites(false).
+		WithReadOnly(opts.ReadOnly)
+	db, err := badger.Open(o)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open badger DB: %w", err)
+	}
+	return Store{db: db}, nil
+}
+
+// Store wraps a badger DB
+type Store struct {
+	db *badger.DB
+}
+
+func (s Store) View(fn func(storage.Tx) error) error {
+	return s.run(false, false, fn)
+}
+
+// Update commits the transaction in chunks when it outgrows badger's transaction size,
+// so an Update which fails half way may leave the earlier chunks behind.
+func (s Store) Update(fn func(storage.Tx) error) error {
+	return s.run(true, true, fn)
+}
+
+// Batch is all or nothing: it fails with badger.ErrTxnTooBig when the transaction outgrows badger's
+// transaction size, since the journal and the retries of a source rely on a failed batch writing nothing.
+func (s Store) Batch(fn func(storage.Tx) error) error {
+	return s.run(true, false, fn)
+}
+
+func (s Store) Close() error {
+	return s.db.Close()
+}
+
+func (s Store) run(update, chunked bool, fn func(storage.Tx) error) error {
+	tx := &Tx{db: s.db, txn: s.db.NewTransaction(update), chunked: chunked}
+	defer func() { tx.txn.Discard() }()
+
+	err := fn(tx)
+	if err == nil {
+		err = tx.err
+	}
+	if err != nil || !update {
+		return err
+	}
+	if err = tx.txn.Commit(); err != nil {
+		return xerrors.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Tx is a badger transaction. Since Get and Bucket can't return errors, the first
+// read error is kept and fails the transaction.
+type Tx struct {
+	db  *badger.DB
+	txn *badger.Txn
+	err error
+	// chunked lets the transaction be committed in chunks, see Update
+	chunked bool
+}
+
+func (t *Tx) Bucket(name []byte) storage.Bucket {
+	return Bucket{tx: t}.Bucket(name)
+}
+
+func (t *Tx) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	return Bucket{tx: t}.CreateBucketIfNotExists(name)
+}
+
+func (t *Tx) DeleteBucket(name []byte) error {
+	return Bucket{tx: t}.DeleteBucket(name)
+}
+
+func (t *Tx) ForEach(fn func(name []byte, b storage.Bucket) error) error {
+	return Bucket{tx: t}.forEach(func(k []byte, child *Bucket, _ []byte) error {
+		if child == nil {
+			return nil
+		}
+		return fn(k, *child)
+	})
+}
+
+func (t *Tx) set(e *badger.Entry) error {
+	err := t.txn.SetEntry(e)
+	if err == badger.ErrTxnTooBig && t.chunked {
+		if err = t.flush(); err != nil {
+			return err
+		}
+		err = t.txn.SetEntry(e)
+	}
+	if err != nil {
+		return xerrors.Errorf("failed to set: %w", err)
+	}
+	return nil
+}
+
+func (t *Tx) delete(key []byte) error {
+	err := t.txn.Delete(key)
+	if err == badger.ErrTxnTooBig && t.chunked {
+		if err = t.flush(); err != nil {
+			return err
+		}
+		err = t.txn.Delete(key)
+	}
+	if err != nil {
+		return xerrors.Errorf("failed to delete: %w", err)
+	}
+	return nil
+}
+
+// flush commits what has been written so far and carries on in a new transaction
+func (t *Tx) flush() error {
+	if err := t.txn.Commit(); err != nil {
+		return xerrors.Errorf("failed to commit transaction: %w", err)
+	}
+	t.txn = t.db.NewTransaction(true)
+	return nil
+}
+
+// get returns nil item if the key doesn't exist
+func (t *Tx) get(key []byte) (*badger.Item, error) {
+	item, err := t.txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, xerrors.Errorf("failed to get: %w", err)
+	}
+	return item, nil
+}
+
+func (t *Tx) setErr(err error) {
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+type Bucket struct {
+	tx     *Tx
+	prefix []byte
+}
+
+func (b Bucket) Get(key []byte) []byte {
+	item, err := b.tx.get(childKey(b.prefix, key))
+	if err != nil {
+		b.tx.setErr(err)
+		return nil
+	} else if item == nil || item.UserMeta()&bucketMeta != 0 {
+		return nil
+	}
+	value, err := item.ValueCopy(nil)
+	if err != nil {
+		b.tx.setErr(xerrors.Errorf("failed to read value: %w", err))
+		return nil
+	}
+	return value
+}
+
+// Put doesn't check whether key is a nested bucket, which would cost a read per write
+func (b Bucket) Put(key, value []byte) error {
+	if len(key) == 0 {
+		return xerrors.New("key required")
+	}
+	return b.tx.set(badger.NewEntry(childKey(b.prefix, key), value))
+}
+
+func (b Bucket) Delete(key []byte) error {
+	return b.tx.delete(childKey(b.prefix, key))
+}
+
+// ForEach reads the whole bucket up front, so fn may write to the transaction
+func (b Bucket) ForEach(fn func(k, v []byte) error) error {
+	return b.forEach(func(k []byte, _ *Bucket, v []byte) error {
+		return fn(k, v)
+	})
+}
+
+func (b Bucket) Bucket(name []byte) storage.Bucket {
+	key := childKey(b.prefix, name)
+	item, err := b.tx.get(key)
+	if err != nil {
+		b.tx.setErr(err)
+		return nil
+	} else if item == nil || item.UserMeta()&bucketMeta == 0 {
+		return nil
+	}
+	return Bucket{tx: b.tx, prefix: key}
+}
+
+func (b Bucket) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	if len(name) == 0 {
+		return nil, xerrors.New("bucket name required")
+	}
+	key := childKey(b.prefix, name)
+	item, err := b.tx.get(key)
+	if err != nil {
+		return nil, err
+	} else if item != nil {
+		if item.UserMeta()&bucketMeta == 0 {
+			return nil, xerrors.Errorf("%q is not a bucket", name)
+		}
+		return Bucket{tx: b.tx, prefix: key}, nil
+	}
+
+	if err = b.tx.set(badger.NewEntry(key, nil).WithMeta(bucketMeta)); err != nil {
+		return nil, xerrors.Errorf("failed to create bucket: %w", err)
+	}
+	return Bucket{tx: b.tx, prefix: key}, nil
+}
+
+func (b Bucket) DeleteBucket(name []byte) error {
+	key := childKey(b.prefix, name)
+	item, err := b.tx.get(key)
+	if err != nil {
+		return err
+	} else if item == nil || item.UserMeta()&bucketMeta == 0 {
+		return storage.ErrBucketNotFound
+	}
+
+	// the marker key is the prefix of the whole bucket
+	var keys [][]byte
+	it := b.tx.txn.NewIterator(badger.IteratorOptions{Prefix: key})
+	for it.Seek(key); it.ValidForPrefix(key); it.Next() {
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+	it.Close()
+
+	for _, k := range keys {
+		if err = b.tx.delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEach calls fn with either a nested bucket or a value for each direct child
+func (b Bucket) forEach(fn func(k []byte, child *Bucket, v []byte) error) error {
+	type entry struct {
+		name  []byte
+		child *Bucket
+		value []byte
+	}
+	var entries []entry
+
+	it := b.tx.txn.NewIterator(badger.IteratorOptions{Prefix: b.prefix, PrefetchValues: true, PrefetchSize: 100})
+	for it.Seek(b.prefix); it.ValidForPrefix(b.prefix); {
+		item := it.Item()
+		key := item.KeyCopy(nil)
+		name, rest := splitComponent(key[len(b.prefix):])
+		if name == nil || len(rest) != 0 {
+			// not a direct child
+			it.Next()
+			continue
+		}
+
+		if item.UserMeta()&bucketMeta != 0 {
+			entries = append(entries, entry{name: name, child: &Bucket{tx: b.tx, prefix: key}})
+			// skip everything inside the nested bucket
+			it.Seek(successor(key))
+			continue
+		}
+
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			it.Close()
+			return xerrors.Errorf("failed to read value: %w", err)
+		}
+		entries = append(entries, entry{name: name, value: value})
+		it.Next()
+	}
+	it.Close()
+
+	for _, e := range entries {
+		if err := fn(e.name, e.child, e.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func childKey(prefix, name []byte) []byte {
+	key := make([]byte, 0, len(prefix)+len(name)+2)
+	key = append(key, prefix...)
+	for _, c := range name {
+		if c == 0x00 {
+			key = append(key, 0x00, 0xFF)
+			continue
+		}
+		key = append(key, c)
+	}
+	return append(key, 0x00, 0x01)
+}
+
+// splitComponent unescapes the first component of key and returns the remainder
+func splitComponent(key []byte) (name, rest []byte) {
+	var buf bytes.Buffer
+	for i := 0; i < len(key); i++ {
+		if key[i] != 0x00 {
+			buf.WriteByte(key[i])
+			continue
+		}
+		if i+1 >= len(key) {
+			return nil, nil
+		}
+		switch key[i+1] {
+		case 0xFF:
+			buf.WriteByte(0x00)
+			i++
+		case 0x01:
+			return buf.Bytes(), key[i+2:]
+		default:
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// successor returns the first key after everything prefixed with the given component key
+func successor(key []byte) []byte {
+	s := append([]byte{}, key...)
+	s[len(s)-1]++
+	return s
+}
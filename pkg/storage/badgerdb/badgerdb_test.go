@@ -0,0 +1,138 @@
+package badgerdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+func TestStore(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestStore_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	s, err := storage.Open(DriverName, d, storage.Options{})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	err = s.Update(func(tx storage.Tx) error {
+		for _, name := range []string{"debian 9", "debian", "debian\x00"} {
+			root, err := tx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+			pkg, err := root.CreateBucketIfNotExists([]byte("openssl"))
+			if err != nil {
+				return err
+			}
+			if err = pkg.Put([]byte("CVE-2019-0002"), []byte(name)); err != nil {
+				return err
+			}
+			if err = root.Put([]byte("b-key"), []byte("value")); err != nil {
+				return err
+			}
+			if err = root.Put([]byte("a-key"), []byte("value")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = s.View(func(tx storage.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte("missing")))
+
+		root := tx.Bucket([]byte("debian\x00"))
+		assert.NotNil(t, root)
+		assert.Nil(t, root.Bucket([]byte("a-key")))
+		assert.Nil(t, root.Get([]byte("openssl")))
+		pkg := root.Bucket([]byte("openssl"))
+		assert.Equal(t, []byte("debian\x00"), pkg.Get([]byte("CVE-2019-0002")))
+
+		var keys []string
+		var values [][]byte
+		err := root.ForEach(func(k, v []byte) error {
+			keys = append(keys, string(k))
+			values = append(values, v)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a-key", "b-key", "openssl"}, keys)
+		assert.Equal(t, [][]byte{[]byte("value"), []byte("value"), nil}, values)
+
+		var roots []string
+		err = tx.ForEach(func(name []byte, _ storage.Bucket) error {
+			roots = append(roots, string(name))
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"debian", "debian\x00", "debian 9"}, roots)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = s.Update(func(tx storage.Tx) error {
+		return tx.DeleteBucket([]byte("debian"))
+	})
+	assert.NoError(t, err)
+
+	err = s.Update(func(tx storage.Tx) error {
+		return tx.DeleteBucket([]byte("debian"))
+	})
+	assert.Equal(t, storage.ErrBucketNotFound, err)
+
+	err = s.View(func(tx storage.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte("debian")))
+		pkg := tx.Bucket([]byte("debian\x00")).Bucket([]byte("openssl"))
+		assert.Equal(t, []byte("debian\x00"), pkg.Get([]byte("CVE-2019-0002")))
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestStore_BatchTooBig(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestStore_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+
+	s, err := storage.Open(DriverName, d, storage.Options{})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	put := func(tx storage.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("nvd"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 200000; i++ {
+			if err = b.Put([]byte(fmt.Sprintf("CVE-2019-%06d", i)), []byte("value")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// a batch outgrowing a transaction writes nothing
+	err = s.Batch(put)
+	assert.True(t, xerrors.Is(err, badger.ErrTxnTooBig), err)
+	err = s.View(func(tx storage.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte("nvd")))
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// while an update is committed in chunks
+	assert.NoError(t, s.Update(put))
+	err = s.View(func(tx storage.Tx) error {
+		assert.Equal(t, []byte("value"), tx.Bucket([]byte("nvd")).Get([]byte("CVE-2019-199999")))
+		return nil
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,153 @@
+package boltdb
+
+import (
+	"io"
+
+	bolt "github.com/etcd-io/bbolt"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+func init() {
+	storage.Register(storage.DefaultDriver, Driver{})
+}
+
+type Driver struct{}
+
+func (Driver) Open(path string, opts storage.Options) (storage.Store, error) {
+	boltOpts := &bolt.Options{ReadOnly: opts.ReadOnly}
+	if opts.ReadOnly {
+		// readers page in the whole file up front and never touch the freelist
+		boltOpts.MmapFlags = readOnlyMmapFlags
+		boltOpts.NoFreelistSync = true
+	}
+	db, err := bolt.Open(path, 0600, boltOpts)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open bolt DB: %w", err)
+	}
+	return Store{db: db}, nil
+}
+
+// Store wraps a bolt DB
+type Store struct {
+	db *bolt.DB
+}
+
+// DB returns the underlying bolt DB for bolt-specific operations
+func (s Store) DB() *bolt.DB {
+	return s.db
+}
+
+func (s Store) View(fn func(storage.Tx) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(Tx{tx: tx})
+	})
+}
+
+func (s Store) Update(fn func(storage.Tx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(Tx{tx: tx})
+	})
+}
+
+func (s Store) Batch(fn func(storage.Tx) error) error {
+	return s.db.Batch(func(tx *bolt.Tx) error {
+		return fn(Tx{tx: tx})
+	})
+}
+
+// Snapshot writes the DB as of a read transaction, so writes can go on meanwhile
+func (s Store) Snapshot(w io.Writer) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+func (s Store) Close() error {
+	return s.db.Close()
+}
+
+type Tx struct {
+	tx *bolt.Tx
+}
+
+func (t Tx) Bucket(name []byte) storage.Bucket {
+	b := t.tx.Bucket(name)
+	if b == nil {
+		return nil
+	}
+	return Bucket{bucket: b}
+}
+
+func (t Tx) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	b, err := t.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return Bucket{bucket: b}, nil
+}
+
+func (t Tx) DeleteBucket(name []byte) error {
+	return deleteErr(t.tx.DeleteBucket(name))
+}
+
+func (t Tx) ForEach(fn func(name []byte, b storage.Bucket) error) error {
+	return t.tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		return fn(name, Bucket{bucket: b})
+	})
+}
+
+type Bucket struct {
+	bucket *bolt.Bucket
+}
+
+func (b Bucket) Get(key []byte) []byte {
+	return b.bucket.Get(key)
+}
+
+func (b Bucket) Put(key, value []byte) error {
+	return b.bucket.Put(key, value)
+}
+
+func (b Bucket) Delete(key []byte) error {
+	return b.bucket.Delete(key)
+}
+
+func (b Bucket) ForEach(fn func(k, v []byte) error) error {
+	return b.bucket.ForEach(fn)
+}
+
+func (b Bucket) Bucket(name []byte) storage.Bucket {
+	nested := b.bucket.Bucket(name)
+	if nested == nil {
+		return nil
+	}
+	return Bucket{bucket: nested}
+}
+
+func (b Bucket) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	nested, err := b.bucket.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return Bucket{bucket: nested}, nil
+}
+
+func (b Bucket) DeleteBucket(name []byte) error {
+	return deleteErr(b.bucket.DeleteBucket(name))
+}
+
+// Size returns the bytes of the pages in use
+func (b Bucket) Size() int {
+	s := b.bucket.Stats()
+	return s.BranchInuse + s.LeafInuse
+}
+
+func deleteErr(err error) error {
+	if err == bolt.ErrBucketNotFound {
+		return storage.ErrBucketNotFound
+	}
+	return err
+}
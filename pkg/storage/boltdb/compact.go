@@ -0,0 +1,110 @@
+package boltdb
+
+import (
+	"os"
+
+	bolt "github.com/etcd-io/bbolt"
+	"golang.org/x/xerrors"
+)
+
+// maximum bytes copied in a transaction of Compact
+const compactTxSize = 64 << 20
+
+// Compact copies the DB into a new file, which leaves out the pages freed by deleted and rewritten buckets,
+// and replaces the DB with it. Pages are filled up, as the file is mostly read.
+func (Driver) Compact(path string) error {
+	src, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return xerrors.Errorf("failed to open bolt DB: %w", err)
+	}
+	defer src.Close()
+
+	tmp := path + ".compact"
+	if err = os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return xerrors.Errorf("failed to remove a previous compaction: %w", err)
+	}
+	dst, err := bolt.Open(tmp, 0600, nil)
+	if err != nil {
+		return xerrors.Errorf("failed to open bolt DB: %w", err)
+	}
+	defer os.Remove(tmp)
+	defer dst.Close()
+
+	if err = compact(dst, src); err != nil {
+		return xerrors.Errorf("failed to copy the DB: %w", err)
+	}
+	if err = dst.Close(); err != nil {
+		return xerrors.Errorf("failed to close the compacted DB: %w", err)
+	}
+	if err = src.Close(); err != nil {
+		return xerrors.Errorf("failed to close bolt DB: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return xerrors.Errorf("failed to replace the DB: %w", err)
+	}
+	return nil
+}
+
+// compact copies the buckets of src, committing every compactTxSize bytes so that a large DB isn't held in memory
+func compact(dst, src *bolt.DB) error {
+	tx, err := dst.Begin(true)
+	if err != nil {
+		return err
+	}
+	// the last transaction, a committed one can't be rolled back
+	defer func() { _ = tx.Rollback() }()
+
+	var size int
+	err = src.View(func(srcTx *bolt.Tx) error {
+		return srcTx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return walk(nil, name, b, func(path [][]byte, k, v []byte) error {
+				if size += len(k) + len(v); size > compactTxSize {
+					if err := tx.Commit(); err != nil {
+						return err
+					}
+					// tx stays the committed transaction if Begin fails, so that the deferred rollback has one
+					next, err := dst.Begin(true)
+					if err != nil {
+						return err
+					}
+					tx = next
+					size = len(k) + len(v)
+				}
+
+				if len(path) == 0 {
+					_, err := tx.CreateBucket(k)
+					return err
+				}
+				b := tx.Bucket(path[0])
+				for _, name := range path[1:] {
+					b = b.Bucket(name)
+				}
+				b.FillPercent = 1.0
+				if v == nil {
+					_, err := b.CreateBucket(k)
+					return err
+				}
+				return b.Put(k, v)
+			})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// walk calls fn with a nil value for the bucket k, then with its keys and nested buckets
+func walk(path [][]byte, k []byte, b *bolt.Bucket, fn func(path [][]byte, k, v []byte) error) error {
+	if err := fn(path, k, nil); err != nil {
+		return err
+	}
+	nested := make([][]byte, 0, len(path)+1)
+	nested = append(append(nested, path...), k)
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return walk(nested, k, b.Bucket(k), fn)
+		}
+		return fn(nested, k, v)
+	})
+}
@@ -0,0 +1,78 @@
+package boltdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+func TestDriver_Compact(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestDriver_Compact_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, "trivy.db")
+
+	s, err := storage.Open(storage.DefaultDriver, path, storage.Options{})
+	assert.NoError(t, err)
+	err = s.Update(func(tx storage.Tx) error {
+		for _, name := range []string{"alpine 3.10", "debian 9"} {
+			root, err := tx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+			for i := 0; i < 1000; i++ {
+				pkg, err := root.CreateBucketIfNotExists([]byte(fmt.Sprintf("pkg-%d", i)))
+				if err != nil {
+					return err
+				}
+				value := bytes.Repeat([]byte("x"), 100)
+				if err = pkg.Put([]byte(fmt.Sprintf("CVE-2019-%04d", i)), value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	// the pages of the deleted bucket are free but stay in the file
+	assert.NoError(t, s.Update(func(tx storage.Tx) error {
+		return tx.DeleteBucket([]byte("debian 9"))
+	}))
+	assert.NoError(t, s.Close())
+	before, err := os.Stat(path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, storage.Compact(storage.DefaultDriver, path))
+
+	after, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.True(t, after.Size() < before.Size(), "%d >= %d", after.Size(), before.Size())
+	_, err = os.Stat(path + ".compact")
+	assert.True(t, os.IsNotExist(err))
+
+	s, err = storage.Open(storage.DefaultDriver, path, storage.Options{ReadOnly: true})
+	assert.NoError(t, err)
+	defer s.Close()
+	err = s.View(func(tx storage.Tx) error {
+		var names []string
+		err := tx.ForEach(func(name []byte, _ storage.Bucket) error {
+			names = append(names, string(name))
+			return nil
+		})
+		assert.Equal(t, []string{"alpine 3.10"}, names)
+
+		pkg := tx.Bucket([]byte("alpine 3.10")).Bucket([]byte("pkg-999"))
+		if assert.NotNil(t, pkg) {
+			assert.Equal(t, bytes.Repeat([]byte("x"), 100), pkg.Get([]byte("CVE-2019-0999")))
+		}
+		return err
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,5 @@
+package boltdb
+
+import "syscall"
+
+const readOnlyMmapFlags = syscall.MAP_POPULATE
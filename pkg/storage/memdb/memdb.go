@@ -0,0 +1,230 @@
+package memdb
+
+import (
+	"sort"
+	"sync"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// DriverName is the name this driver is registered under
+const DriverName = "memory"
+
+var (
+	errTxNotWritable    = xerrors.New("tx not writable")
+	errKeyRequired      = xerrors.New("key required")
+	errIncompatibleType = xerrors.New("incompatible value")
+
+	// path => content, so that a store opened again, e.g. after db.Close, has the same content
+	storesMu sync.Mutex
+	stores   = map[string]*content{}
+)
+
+func init() {
+	storage.Register(DriverName, Driver{})
+}
+
+// Driver keeps stores in memory for tests, which can then check what a source stored with the db package.
+// The path only names a store and nothing is written to it.
+type Driver struct{}
+
+func (Driver) Open(path string, opts storage.Options) (storage.Store, error) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	c, ok := stores[path]
+	if !ok {
+		if opts.ReadOnly {
+			return nil, xerrors.Errorf("no memory store at %s", path)
+		}
+		c = &content{root: newBucket()}
+		stores[path] = c
+	}
+	return Store{content: c, readOnly: opts.ReadOnly}, nil
+}
+
+// Drop forgets the store at path
+func Drop(path string) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	delete(stores, path)
+}
+
+// content holds the root buckets of a store
+type content struct {
+	mu   sync.RWMutex
+	root *bucket
+}
+
+// Store is a handle on a content. A write transaction works on a copy of the buckets, which replaces them
+// once it succeeds, so a failed one is rolled back like in bolt.
+type Store struct {
+	*content
+	readOnly bool
+}
+
+func (s Store) View(fn func(storage.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(Tx{root: s.root})
+}
+
+func (s Store) Update(fn func(storage.Tx) error) error {
+	if s.readOnly {
+		return errTxNotWritable
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	root := s.root.clone()
+	if err := fn(Tx{root: root, writable: true}); err != nil {
+		return err
+	}
+	s.root = root
+	return nil
+}
+
+func (s Store) Batch(fn func(storage.Tx) error) error {
+	return s.Update(fn)
+}
+
+func (s Store) Close() error {
+	return nil
+}
+
+type Tx struct {
+	root     *bucket
+	writable bool
+}
+
+func (t Tx) Bucket(name []byte) storage.Bucket {
+	return Bucket{bucket: t.root, writable: t.writable}.Bucket(name)
+}
+
+func (t Tx) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	return Bucket{bucket: t.root, writable: t.writable}.CreateBucketIfNotExists(name)
+}
+
+func (t Tx) DeleteBucket(name []byte) error {
+	return Bucket{bucket: t.root, writable: t.writable}.DeleteBucket(name)
+}
+
+func (t Tx) ForEach(fn func(name []byte, b storage.Bucket) error) error {
+	for _, name := range t.root.keys() {
+		if err := fn([]byte(name), Bucket{bucket: t.root.buckets[name], writable: t.writable}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Bucket struct {
+	bucket   *bucket
+	writable bool
+}
+
+func (b Bucket) Get(key []byte) []byte {
+	return b.bucket.values[string(key)]
+}
+
+// Put copies the value, which the caller may reuse like with bolt
+func (b Bucket) Put(key, value []byte) error {
+	switch {
+	case !b.writable:
+		return errTxNotWritable
+	case len(key) == 0:
+		return errKeyRequired
+	case b.bucket.buckets[string(key)] != nil:
+		return errIncompatibleType
+	}
+	b.bucket.values[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (b Bucket) Delete(key []byte) error {
+	if !b.writable {
+		return errTxNotWritable
+	}
+	delete(b.bucket.values, string(key))
+	return nil
+}
+
+// ForEach goes through the keys in byte-wise order, like bolt
+func (b Bucket) ForEach(fn func(k, v []byte) error) error {
+	for _, k := range b.bucket.keys() {
+		if err := fn([]byte(k), b.bucket.values[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b Bucket) Bucket(name []byte) storage.Bucket {
+	nested, ok := b.bucket.buckets[string(name)]
+	if !ok {
+		return nil
+	}
+	return Bucket{bucket: nested, writable: b.writable}
+}
+
+func (b Bucket) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	switch {
+	case !b.writable:
+		return nil, errTxNotWritable
+	case len(name) == 0:
+		return nil, errKeyRequired
+	case b.bucket.values[string(name)] != nil:
+		return nil, errIncompatibleType
+	}
+	nested, ok := b.bucket.buckets[string(name)]
+	if !ok {
+		nested = newBucket()
+		b.bucket.buckets[string(name)] = nested
+	}
+	return Bucket{bucket: nested, writable: b.writable}, nil
+}
+
+func (b Bucket) DeleteBucket(name []byte) error {
+	if !b.writable {
+		return errTxNotWritable
+	}
+	if _, ok := b.bucket.buckets[string(name)]; !ok {
+		return storage.ErrBucketNotFound
+	}
+	delete(b.bucket.buckets, string(name))
+	return nil
+}
+
+type bucket struct {
+	values  map[string][]byte
+	buckets map[string]*bucket
+}
+
+func newBucket() *bucket {
+	return &bucket{values: map[string][]byte{}, buckets: map[string]*bucket{}}
+}
+
+// clone copies the tree of buckets, the values are never modified in place so they are shared
+func (b *bucket) clone() *bucket {
+	c := newBucket()
+	for k, v := range b.values {
+		c.values[k] = v
+	}
+	for k, nested := range b.buckets {
+		c.buckets[k] = nested.clone()
+	}
+	return c
+}
+
+// keys returns the sorted keys of the values and the nested buckets
+func (b *bucket) keys() []string {
+	keys := make([]string, 0, len(b.values)+len(b.buckets))
+	for k := range b.values {
+		keys = append(keys, k)
+	}
+	for k := range b.buckets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
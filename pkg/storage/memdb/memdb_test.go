@@ -0,0 +1,111 @@
+package memdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+func TestStore(t *testing.T) {
+	path := "TestStore"
+	defer Drop(path)
+
+	s, err := storage.Open(DriverName, path, storage.Options{})
+	assert.NoError(t, err)
+
+	err = s.Update(func(tx storage.Tx) error {
+		for _, name := range []string{"debian 9", "debian", "debian\x00"} {
+			root, err := tx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+			pkg, err := root.CreateBucketIfNotExists([]byte("openssl"))
+			if err != nil {
+				return err
+			}
+			if err = pkg.Put([]byte("CVE-2019-0002"), []byte(name)); err != nil {
+				return err
+			}
+			if err = root.Put([]byte("b-key"), []byte("value")); err != nil {
+				return err
+			}
+			if err = root.Put([]byte("a-key"), []byte("value")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// a failed transaction is rolled back
+	err = s.Update(func(tx storage.Tx) error {
+		if err := tx.DeleteBucket([]byte("debian 9")); err != nil {
+			return err
+		}
+		return errors.New("error")
+	})
+	assert.EqualError(t, err, "error")
+
+	err = s.View(func(tx storage.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte("missing")))
+		assert.NotNil(t, tx.Bucket([]byte("debian 9")))
+
+		root := tx.Bucket([]byte("debian\x00"))
+		assert.NotNil(t, root)
+		assert.Nil(t, root.Bucket([]byte("a-key")))
+		assert.Nil(t, root.Get([]byte("openssl")))
+		pkg := root.Bucket([]byte("openssl"))
+		assert.Equal(t, []byte("debian\x00"), pkg.Get([]byte("CVE-2019-0002")))
+		assert.Error(t, pkg.Put([]byte("CVE-2019-0003"), []byte("value")))
+
+		var keys []string
+		var values [][]byte
+		err := root.ForEach(func(k, v []byte) error {
+			keys = append(keys, string(k))
+			values = append(values, v)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a-key", "b-key", "openssl"}, keys)
+		assert.Equal(t, [][]byte{[]byte("value"), []byte("value"), nil}, values)
+
+		var roots []string
+		err = tx.ForEach(func(name []byte, _ storage.Bucket) error {
+			roots = append(roots, string(name))
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"debian", "debian\x00", "debian 9"}, roots)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	err = s.Update(func(tx storage.Tx) error {
+		return tx.DeleteBucket([]byte("debian"))
+	})
+	assert.NoError(t, err)
+
+	err = s.Update(func(tx storage.Tx) error {
+		return tx.DeleteBucket([]byte("debian"))
+	})
+	assert.Equal(t, storage.ErrBucketNotFound, err)
+	assert.NoError(t, s.Close())
+
+	// the content outlives the handle
+	s, err = storage.Open(DriverName, path, storage.Options{ReadOnly: true})
+	assert.NoError(t, err)
+	err = s.View(func(tx storage.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte("debian")))
+		pkg := tx.Bucket([]byte("debian\x00")).Bucket([]byte("openssl"))
+		assert.Equal(t, []byte("debian\x00"), pkg.Get([]byte("CVE-2019-0002")))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Error(t, s.Update(func(storage.Tx) error { return nil }))
+
+	_, err = storage.Open(DriverName, "missing", storage.Options{ReadOnly: true})
+	assert.Error(t, err)
+}
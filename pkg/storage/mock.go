@@ -0,0 +1,43 @@
+package storage
+
+import "github.com/stretchr/testify/mock"
+
+type MockTx struct {
+	mock.Mock
+}
+
+func (_m *MockTx) Bucket(a []byte) Bucket {
+	ret := _m.Called(a)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil
+	}
+	b, ok := ret0.(Bucket)
+	if !ok {
+		return nil
+	}
+	return b
+}
+
+func (_m *MockTx) CreateBucketIfNotExists(a []byte) (Bucket, error) {
+	ret := _m.Called(a)
+	ret0 := ret.Get(0)
+	if ret0 == nil {
+		return nil, ret.Error(1)
+	}
+	b, ok := ret0.(Bucket)
+	if !ok {
+		return nil, ret.Error(1)
+	}
+	return b, ret.Error(1)
+}
+
+func (_m *MockTx) DeleteBucket(a []byte) error {
+	ret := _m.Called(a)
+	return ret.Error(0)
+}
+
+func (_m *MockTx) ForEach(f func(name []byte, b Bucket) error) error {
+	ret := _m.Called()
+	return ret.Error(0)
+}
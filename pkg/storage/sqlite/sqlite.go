@@ -0,0 +1,376 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+// DriverName is the name this driver is registered under
+const DriverName = "sqlite"
+
+// Buckets are stored as an adjacency list and key/value pairs in a single table.
+// The views give the well-known buckets a relational shape for ad hoc queries, e.g.
+//
+//	SELECT * FROM advisories WHERE vulnerability_id = 'CVE-2019-0001';
+const schema = `
+CREATE TABLE IF NOT EXISTS buckets (
+	id        INTEGER PRIMARY KEY,
+	parent_id INTEGER REFERENCES buckets(id) ON DELETE CASCADE,
+	name      BLOB NOT NULL,
+	UNIQUE (parent_id, name)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS root_buckets ON buckets(name) WHERE parent_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS entries (
+	bucket_id INTEGER NOT NULL REFERENCES buckets(id) ON DELETE CASCADE,
+	key       BLOB NOT NULL,
+	value     BLOB NOT NULL,
+	PRIMARY KEY (bucket_id, key)
+) WITHOUT ROWID;
+
+CREATE VIEW IF NOT EXISTS sources AS
+	SELECT CAST(name AS TEXT) AS name
+	FROM buckets
+	WHERE parent_id IS NULL
+	  AND CAST(name AS TEXT) NOT IN ('trivy', 'vulnerability', 'vulnerability-detail', 'severity', 'ssvc', 'affected-package', 'cpe', 'shared-detail');
+
+CREATE VIEW IF NOT EXISTS advisories AS
+	SELECT CAST(s.name AS TEXT) AS source,
+	       CAST(p.name AS TEXT) AS package,
+	       CAST(e.key AS TEXT) AS vulnerability_id,
+	       CAST(e.value AS TEXT) AS advisory
+	FROM entries e
+	JOIN buckets p ON p.id = e.bucket_id
+	JOIN buckets s ON s.id = p.parent_id
+	WHERE s.parent_id IS NULL
+	  AND CAST(s.name AS TEXT) IN (SELECT name FROM sources);
+
+CREATE VIEW IF NOT EXISTS vulnerabilities AS
+	SELECT CAST(e.key AS TEXT) AS vulnerability_id,
+	       CAST(e.value AS TEXT) AS vulnerability
+	FROM entries e
+	JOIN buckets b ON b.id = e.bucket_id
+	WHERE b.parent_id IS NULL AND CAST(b.name AS TEXT) = 'vulnerability';
+
+CREATE VIEW IF NOT EXISTS vulnerability_details AS
+	SELECT CAST(v.name AS TEXT) AS vulnerability_id,
+	       CAST(e.key AS TEXT) AS source,
+	       CAST(e.value AS TEXT) AS detail
+	FROM entries e
+	JOIN buckets v ON v.id = e.bucket_id
+	JOIN buckets b ON b.id = v.parent_id
+	WHERE b.parent_id IS NULL AND CAST(b.name AS TEXT) = 'vulnerability-detail';
+`
+
+func init() {
+	storage.Register(DriverName, Driver{})
+}
+
+type Driver struct{}
+
+func (Driver) Open(path string, opts storage.Options) (storage.Store, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=1", path)
+	if opts.ReadOnly {
+		dsn += "&mode=ro"
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open SQLite DB: %w", err)
+	}
+	// SQLite allows a single writer anyway, and it keeps pragmas consistent
+	db.SetMaxOpenConns(1)
+
+	if !opts.ReadOnly {
+		if _, err = db.Exec(schema); err != nil {
+			db.Close()
+			return nil, xerrors.Errorf("failed to create schema: %w", err)
+		}
+	}
+	return Store{db: db}, nil
+}
+
+// Store wraps a SQLite database
+type Store struct {
+	db *sql.DB
+}
+
+func (s Store) View(fn func(storage.Tx) error) error {
+	return s.run(true, fn)
+}
+
+func (s Store) Update(fn func(storage.Tx) error) error {
+	return s.run(false, fn)
+}
+
+// Batch is the same as Update since SQLite serializes writers
+func (s Store) Batch(fn func(storage.Tx) error) error {
+	return s.run(false, fn)
+}
+
+func (s Store) Close() error {
+	return s.db.Close()
+}
+
+func (s Store) run(readOnly bool, fn func(storage.Tx) error) error {
+	sqlTx, err := s.db.Begin()
+	if err != nil {
+		return xerrors.Errorf("failed to begin transaction: %w", err)
+	}
+	tx := &Tx{tx: sqlTx, stmts: map[string]*sql.Stmt{}}
+
+	err = fn(tx)
+	if err == nil {
+		err = tx.err
+	}
+	if err != nil || readOnly {
+		sqlTx.Rollback()
+		return err
+	}
+	if err = sqlTx.Commit(); err != nil {
+		return xerrors.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Tx is a SQLite transaction. Since Get and Bucket can't return errors, the first
+// query error is kept and fails the transaction.
+type Tx struct {
+	tx    *sql.Tx
+	stmts map[string]*sql.Stmt
+	err   error
+}
+
+func (t *Tx) Bucket(name []byte) storage.Bucket {
+	return t.bucket(nil, name)
+}
+
+func (t *Tx) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	return t.createBucket(nil, name)
+}
+
+func (t *Tx) DeleteBucket(name []byte) error {
+	return t.deleteBucket(nil, name)
+}
+
+func (t *Tx) ForEach(fn func(name []byte, b storage.Bucket) error) error {
+	rows, err := t.query("SELECT id, name FROM buckets WHERE parent_id IS NULL ORDER BY name")
+	if err != nil {
+		return err
+	}
+	type bucket struct {
+		id   int64
+		name []byte
+	}
+	var buckets []bucket
+	for rows.Next() {
+		var b bucket
+		if err = rows.Scan(&b.id, &b.name); err != nil {
+			rows.Close()
+			return xerrors.Errorf("failed to scan bucket: %w", err)
+		}
+		buckets = append(buckets, b)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return xerrors.Errorf("failed to list buckets: %w", err)
+	}
+
+	for _, b := range buckets {
+		if err = fn(b.name, Bucket{tx: t, id: b.id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Tx) stmt(query string) (*sql.Stmt, error) {
+	if s, ok := t.stmts[query]; ok {
+		return s, nil
+	}
+	s, err := t.tx.Prepare(query)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to prepare statement: %w", err)
+	}
+	t.stmts[query] = s
+	return s, nil
+}
+
+func (t *Tx) query(query string, args ...interface{}) (*sql.Rows, error) {
+	s, err := t.stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.Query(args...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to query: %w", err)
+	}
+	return rows, nil
+}
+
+func (t *Tx) exec(query string, args ...interface{}) (sql.Result, error) {
+	s, err := t.stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Exec(args...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to execute: %w", err)
+	}
+	return res, nil
+}
+
+// parent is nil for root buckets
+func (t *Tx) bucketID(parent interface{}, name []byte) (int64, bool, error) {
+	s, err := t.stmt("SELECT id FROM buckets WHERE parent_id IS ? AND name = ?")
+	if err != nil {
+		return 0, false, err
+	}
+	var id int64
+	switch err = s.QueryRow(parent, name).Scan(&id); err {
+	case nil:
+		return id, true, nil
+	case sql.ErrNoRows:
+		return 0, false, nil
+	default:
+		return 0, false, xerrors.Errorf("failed to get bucket: %w", err)
+	}
+}
+
+func (t *Tx) bucket(parent interface{}, name []byte) storage.Bucket {
+	id, ok, err := t.bucketID(parent, name)
+	if err != nil {
+		t.setErr(err)
+		return nil
+	} else if !ok {
+		return nil
+	}
+	return Bucket{tx: t, id: id}
+}
+
+func (t *Tx) createBucket(parent interface{}, name []byte) (storage.Bucket, error) {
+	if len(name) == 0 {
+		return nil, xerrors.New("bucket name required")
+	}
+	id, ok, err := t.bucketID(parent, name)
+	if err != nil {
+		return nil, err
+	} else if ok {
+		return Bucket{tx: t, id: id}, nil
+	}
+
+	res, err := t.exec("INSERT INTO buckets (parent_id, name) VALUES (?, ?)", parent, name)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create bucket: %w", err)
+	}
+	if id, err = res.LastInsertId(); err != nil {
+		return nil, xerrors.Errorf("failed to get bucket ID: %w", err)
+	}
+	return Bucket{tx: t, id: id}, nil
+}
+
+func (t *Tx) deleteBucket(parent interface{}, name []byte) error {
+	res, err := t.exec("DELETE FROM buckets WHERE parent_id IS ? AND name = ?", parent, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return xerrors.Errorf("failed to delete bucket: %w", err)
+	} else if n == 0 {
+		return storage.ErrBucketNotFound
+	}
+	return nil
+}
+
+func (t *Tx) setErr(err error) {
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+type Bucket struct {
+	tx *Tx
+	id int64
+}
+
+func (b Bucket) Get(key []byte) []byte {
+	s, err := b.tx.stmt("SELECT value FROM entries WHERE bucket_id = ? AND key = ?")
+	if err != nil {
+		b.tx.setErr(err)
+		return nil
+	}
+	var value []byte
+	switch err = s.QueryRow(b.id, key).Scan(&value); err {
+	case nil:
+		return value
+	case sql.ErrNoRows:
+		return nil
+	default:
+		b.tx.setErr(xerrors.Errorf("failed to get value: %w", err))
+		return nil
+	}
+}
+
+func (b Bucket) Put(key, value []byte) error {
+	if len(key) == 0 {
+		return xerrors.New("key required")
+	}
+	// a nil value would be NULL
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := b.tx.exec("INSERT OR REPLACE INTO entries (bucket_id, key, value) VALUES (?, ?, ?)", b.id, key, value)
+	return err
+}
+
+func (b Bucket) Delete(key []byte) error {
+	_, err := b.tx.exec("DELETE FROM entries WHERE bucket_id = ? AND key = ?", b.id, key)
+	return err
+}
+
+// ForEach reads the whole bucket up front, so fn may write to the transaction
+func (b Bucket) ForEach(fn func(k, v []byte) error) error {
+	rows, err := b.tx.query(`SELECT key, value FROM entries WHERE bucket_id = ?1
+		UNION ALL SELECT name, NULL FROM buckets WHERE parent_id = ?1
+		ORDER BY 1`, b.id)
+	if err != nil {
+		return err
+	}
+	var keys, values [][]byte
+	for rows.Next() {
+		var k, v []byte
+		if err = rows.Scan(&k, &v); err != nil {
+			rows.Close()
+			return xerrors.Errorf("failed to scan entry: %w", err)
+		}
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return xerrors.Errorf("failed to list entries: %w", err)
+	}
+
+	for i := range keys {
+		if err = fn(keys[i], values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b Bucket) Bucket(name []byte) storage.Bucket {
+	return b.tx.bucket(b.id, name)
+}
+
+func (b Bucket) CreateBucketIfNotExists(name []byte) (storage.Bucket, error) {
+	return b.tx.createBucket(b.id, name)
+}
+
+func (b Bucket) DeleteBucket(name []byte) error {
+	return b.tx.deleteBucket(b.id, name)
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+)
+
+func TestStore(t *testing.T) {
+	d, err := ioutil.TempDir("", "TestStore_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(d)
+	path := filepath.Join(d, "trivy.db")
+
+	s, err := storage.Open(DriverName, path, storage.Options{})
+	assert.NoError(t, err)
+
+	err = s.Update(func(tx storage.Tx) error {
+		root, err := tx.CreateBucketIfNotExists([]byte("debian 9"))
+		if err != nil {
+			return err
+		}
+		pkg, err := root.CreateBucketIfNotExists([]byte("openssl"))
+		if err != nil {
+			return err
+		}
+		if err = pkg.Put([]byte("CVE-2019-0002"), []byte(`{"FixedVersion":"1.1.0"}`)); err != nil {
+			return err
+		}
+		if err = pkg.Put([]byte("CVE-2019-0001"), []byte(`{"FixedVersion":"1.0.0"}`)); err != nil {
+			return err
+		}
+		if err = root.Put([]byte("a-key"), []byte("value")); err != nil {
+			return err
+		}
+		vuln, err := tx.CreateBucketIfNotExists([]byte("vulnerability"))
+		if err != nil {
+			return err
+		}
+		return vuln.Put([]byte("CVE-2019-0001"), []byte(`{"Title":"title"}`))
+	})
+	assert.NoError(t, err)
+
+	err = s.View(func(tx storage.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte("missing")))
+
+		root := tx.Bucket([]byte("debian 9"))
+		assert.NotNil(t, root)
+		pkg := root.Bucket([]byte("openssl"))
+		assert.Equal(t, []byte(`{"FixedVersion":"1.0.0"}`), pkg.Get([]byte("CVE-2019-0001")))
+		assert.Nil(t, pkg.Get([]byte("CVE-2019-9999")))
+
+		var keys []string
+		var values [][]byte
+		err := root.ForEach(func(k, v []byte) error {
+			keys = append(keys, string(k))
+			values = append(values, v)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a-key", "openssl"}, keys)
+		assert.Equal(t, [][]byte{[]byte("value"), nil}, values)
+
+		var roots []string
+		err = tx.ForEach(func(name []byte, _ storage.Bucket) error {
+			roots = append(roots, string(name))
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"debian 9", "vulnerability"}, roots)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Close())
+
+	// the relational views
+	sqlDB, err := sql.Open("sqlite3", path)
+	assert.NoError(t, err)
+	var source, pkgName, advisory string
+	err = sqlDB.QueryRow("SELECT source, package, advisory FROM advisories WHERE vulnerability_id = ?", "CVE-2019-0002").
+		Scan(&source, &pkgName, &advisory)
+	assert.NoError(t, err)
+	assert.Equal(t, "debian 9", source)
+	assert.Equal(t, "openssl", pkgName)
+	assert.Equal(t, `{"FixedVersion":"1.1.0"}`, advisory)
+
+	var vuln string
+	err = sqlDB.QueryRow("SELECT vulnerability FROM vulnerabilities WHERE vulnerability_id = ?", "CVE-2019-0001").Scan(&vuln)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Title":"title"}`, vuln)
+	assert.NoError(t, sqlDB.Close())
+
+	s, err = storage.Open(DriverName, path, storage.Options{})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	err = s.Update(func(tx storage.Tx) error {
+		return tx.DeleteBucket([]byte("debian 9"))
+	})
+	assert.NoError(t, err)
+
+	err = s.Update(func(tx storage.Tx) error {
+		return tx.DeleteBucket([]byte("debian 9"))
+	})
+	assert.Equal(t, storage.ErrBucketNotFound, err)
+
+	err = s.View(func(tx storage.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte("debian 9")))
+		return nil
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// DefaultDriver is used when no driver is specified
+const DefaultDriver = "bolt"
+
+var (
+	// ErrBucketNotFound is returned when deleting a bucket that does not exist
+	ErrBucketNotFound = xerrors.New("bucket not found")
+
+	// ErrCompactNotSupported is returned by Compact for drivers that aren't a Compactor
+	ErrCompactNotSupported = xerrors.New("compaction not supported")
+
+	// ErrSnapshotNotSupported is returned by Snapshot for stores that aren't a Snapshotter
+	ErrSnapshotNotSupported = xerrors.New("snapshot not supported")
+)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// Driver opens a key/value store with nested buckets at the given path
+type Driver interface {
+	Open(path string, opts Options) (Store, error)
+}
+
+type Options struct {
+	ReadOnly bool
+}
+
+// Store is a database handle. Batch may call the function more than once, so it must be idempotent.
+type Store interface {
+	View(func(Tx) error) error
+	Update(func(Tx) error) error
+	Batch(func(Tx) error) error
+	Close() error
+}
+
+// Tx gives access to root buckets in a transaction
+type Tx interface {
+	Bucket(name []byte) Bucket
+	CreateBucketIfNotExists(name []byte) (Bucket, error)
+	DeleteBucket(name []byte) error
+	ForEach(func(name []byte, b Bucket) error) error
+}
+
+// Bucket is a collection of key/value pairs and nested buckets.
+// ForEach passes a nil value for nested buckets.
+type Bucket interface {
+	Get(key []byte) []byte
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	ForEach(func(k, v []byte) error) error
+
+	Bucket(name []byte) Bucket
+	CreateBucketIfNotExists(name []byte) (Bucket, error)
+	DeleteBucket(name []byte) error
+}
+
+// Sizer is implemented by buckets that know the bytes they take up in the file, nested buckets included
+type Sizer interface {
+	Size() int
+}
+
+// Compactor is implemented by drivers that can rewrite a store without the space freed by deletes
+type Compactor interface {
+	Compact(path string) error
+}
+
+// Snapshotter is implemented by stores that can write a consistent copy of themselves while open,
+// which the driver can open as a store
+type Snapshotter interface {
+	Snapshot(w io.Writer) error
+}
+
+// Snapshot writes a copy of an open store to w
+func Snapshot(s Store, w io.Writer) error {
+	snapshotter, ok := s.(Snapshotter)
+	if !ok {
+		return ErrSnapshotNotSupported
+	}
+	return snapshotter.Snapshot(w)
+}
+
+// Register makes a driver available by the given name
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if driver == nil {
+		panic("storage: Register driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("storage: Register called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers returns the sorted names of the registered drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	var names []string
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens a store with the named driver
+func Open(driverName, path string, opts Options) (Store, error) {
+	driver, err := lookup(driverName)
+	if err != nil {
+		return nil, err
+	}
+	return driver.Open(path, opts)
+}
+
+// Compact compacts the store at path, which must be closed, with the named driver
+func Compact(driverName, path string) error {
+	driver, err := lookup(driverName)
+	if err != nil {
+		return err
+	}
+	c, ok := driver.(Compactor)
+	if !ok {
+		return ErrCompactNotSupported
+	}
+	return c.Compact(path)
+}
+
+func lookup(driverName string) (Driver, error) {
+	driversMu.RLock()
+	driver, ok := drivers[driverName]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, xerrors.Errorf("unknown storage driver %q (forgotten import?)", driverName)
+	}
+	return driver, nil
+}
@@ -0,0 +1,169 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// https://www.first.org/cvss/v2/guide
+var cvss2Weights = map[string]map[string]float64{
+	"AV": {"L": 0.395, "A": 0.646, "N": 1.0},
+	"AC": {"H": 0.35, "M": 0.61, "L": 0.71},
+	"Au": {"M": 0.45, "S": 0.56, "N": 0.704},
+	"C":  {"N": 0, "P": 0.275, "C": 0.660},
+	"I":  {"N": 0, "P": 0.275, "C": 0.660},
+	"A":  {"N": 0, "P": 0.275, "C": 0.660},
+}
+
+// https://www.first.org/cvss/v3.1/specification-document
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+	"UI": {"N": 0.85, "R": 0.62},
+	"S":  {"U": 0, "C": 0},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// Privileges Required has different weights when the scope is changed
+var cvss3ChangedScopePR = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+
+// SeverityFromCVSSScore converts a base score into a qualitative severity rating
+func SeverityFromCVSSScore(score float64) Severity {
+	switch {
+	case score >= 9.0:
+		return SeverityCritical
+	case score >= 7.0:
+		return SeverityHigh
+	case score >= 4.0:
+		return SeverityMedium
+	case score > 0.0:
+		return SeverityLow
+	default:
+		return SeverityUnknown
+	}
+}
+
+// SeverityFromCVSSVector calculates the base score of a CVSS v3.x or v2 vector and converts it into a severity.
+// It returns SeverityUnknown when the vector cannot be parsed.
+func SeverityFromCVSSVector(vector string) Severity {
+	var score float64
+	var err error
+	if strings.HasPrefix(vector, "CVSS:3") || strings.Contains(vector, "PR:") {
+		score, err = CalculateCVSSv3Score(vector)
+	} else {
+		score, err = CalculateCVSSv2Score(vector)
+	}
+	if err != nil {
+		return SeverityUnknown
+	}
+	return SeverityFromCVSSScore(score)
+}
+
+// CalculateCVSSv2Score calculates the base score of a vector like "AV:N/AC:L/Au:N/C:P/I:P/A:P"
+func CalculateCVSSv2Score(vector string) (float64, error) {
+	m, err := parseCVSSVector(vector, cvss2Weights)
+	if err != nil {
+		return 0, err
+	}
+
+	impact := 10.41 * (1 - (1-m["C"])*(1-m["I"])*(1-m["A"]))
+	exploitability := 20 * m["AV"] * m["AC"] * m["Au"]
+	f := 1.176
+	if impact == 0 {
+		f = 0
+	}
+	score := ((0.6 * impact) + (0.4 * exploitability) - 1.5) * f
+	return math.Round(score*10) / 10, nil
+}
+
+// CalculateCVSSv3Score calculates the base score of a vector like "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"
+func CalculateCVSSv3Score(vector string) (float64, error) {
+	version := "3.1"
+	if strings.HasPrefix(vector, "CVSS:") {
+		ss := strings.SplitN(vector, "/", 2)
+		if len(ss) != 2 {
+			return 0, fmt.Errorf("invalid CVSS vector: %s", vector)
+		}
+		version = strings.TrimPrefix(ss[0], "CVSS:")
+		vector = ss[1]
+	}
+
+	m, err := parseCVSSVector(vector, cvss3Weights)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := strings.Contains("/"+vector+"/", "/S:C/")
+	if changed {
+		m["PR"] = cvss3ChangedScopePR[metricValue(vector, "PR")]
+	}
+
+	iss := 1 - (1-m["C"])*(1-m["I"])*(1-m["A"])
+	var impact float64
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	} else {
+		impact = 6.42 * iss
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+
+	exploitability := 8.22 * m["AV"] * m["AC"] * m["PR"] * m["UI"]
+	score := impact + exploitability
+	if changed {
+		score *= 1.08
+	}
+	return roundUp(math.Min(score, 10), version), nil
+}
+
+func parseCVSSVector(vector string, weights map[string]map[string]float64) (map[string]float64, error) {
+	metrics := map[string]float64{}
+	for _, metric := range strings.Split(vector, "/") {
+		ss := strings.SplitN(metric, ":", 2)
+		if len(ss) != 2 {
+			return nil, fmt.Errorf("invalid CVSS metric: %s", metric)
+		}
+		values, ok := weights[ss[0]]
+		if !ok {
+			// temporal and environmental metrics are ignored
+			continue
+		}
+		weight, ok := values[ss[1]]
+		if !ok {
+			return nil, fmt.Errorf("invalid CVSS metric value: %s", metric)
+		}
+		metrics[ss[0]] = weight
+	}
+	for name := range weights {
+		if _, ok := metrics[name]; !ok {
+			return nil, fmt.Errorf("missing CVSS metric: %s", name)
+		}
+	}
+	return metrics, nil
+}
+
+func metricValue(vector, name string) string {
+	for _, metric := range strings.Split(vector, "/") {
+		if strings.HasPrefix(metric, name+":") {
+			return strings.TrimPrefix(metric, name+":")
+		}
+	}
+	return ""
+}
+
+func roundUp(score float64, version string) float64 {
+	if version == "3.0" {
+		return math.Ceil(score*10) / 10
+	}
+	// CVSS v3.1 defines Roundup to avoid floating point errors
+	i := int(math.Round(score * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return (math.Floor(float64(i)/10000) + 1) / 10
+}
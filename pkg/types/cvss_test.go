@@ -0,0 +1,111 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateCVSSv3Score(t *testing.T) {
+	testCases := []struct {
+		name          string
+		vector        string
+		expectedScore float64
+		expectedError string
+	}{
+		{
+			name:          "critical",
+			vector:        "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+			expectedScore: 9.8,
+		},
+		{
+			name:          "without prefix",
+			vector:        "AV:N/AC:L/PR:N/UI:R/S:U/C:H/I:H/A:H",
+			expectedScore: 8.8,
+		},
+		{
+			name:          "scope changed",
+			vector:        "CVSS:3.0/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N",
+			expectedScore: 6.1,
+		},
+		{
+			name:          "no impact",
+			vector:        "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N",
+			expectedScore: 0,
+		},
+		{
+			name:          "missing metric",
+			vector:        "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H",
+			expectedError: "missing CVSS metric: A",
+		},
+		{
+			name:          "invalid value",
+			vector:        "CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+			expectedError: "invalid CVSS metric value: AV:X",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			score, err := CalculateCVSSv3Score(tc.vector)
+			switch {
+			case tc.expectedError != "":
+				assert.EqualError(t, err, tc.expectedError, tc.name)
+			default:
+				assert.NoError(t, err, tc.name)
+				assert.Equal(t, tc.expectedScore, score, tc.name)
+			}
+		})
+	}
+}
+
+func TestCalculateCVSSv2Score(t *testing.T) {
+	testCases := []struct {
+		name          string
+		vector        string
+		expectedScore float64
+		expectedError string
+	}{
+		{
+			name:          "high",
+			vector:        "AV:N/AC:L/Au:N/C:P/I:P/A:P",
+			expectedScore: 7.5,
+		},
+		{
+			name:          "medium",
+			vector:        "AV:N/AC:M/Au:N/C:P/I:P/A:P",
+			expectedScore: 6.8,
+		},
+		{
+			name:          "broken vector",
+			vector:        "AV:N/AC",
+			expectedError: "invalid CVSS metric: AC",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			score, err := CalculateCVSSv2Score(tc.vector)
+			switch {
+			case tc.expectedError != "":
+				assert.EqualError(t, err, tc.expectedError, tc.name)
+			default:
+				assert.NoError(t, err, tc.name)
+				assert.Equal(t, tc.expectedScore, score, tc.name)
+			}
+		})
+	}
+}
+
+func TestSeverityFromCVSSVector(t *testing.T) {
+	testCases := []struct {
+		vector   string
+		expected Severity
+	}{
+		{vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", expected: SeverityCritical},
+		{vector: "AV:N/AC:M/Au:N/C:P/I:P/A:P", expected: SeverityMedium},
+		{vector: "CVSS:3.1/AV:L/AC:H/PR:H/UI:R/S:U/C:L/I:N/A:N", expected: SeverityLow},
+		{vector: "invalid", expected: SeverityUnknown},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, SeverityFromCVSSVector(tc.vector), tc.vector)
+	}
+}
@@ -0,0 +1,26 @@
+package types
+
+import (
+	"fmt"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrUnsupportedRelease is returned by the Get functions of the sources for a release they have no
+// advisories of, e.g. amazon linux 2022, rather than no advisories
+var ErrUnsupportedRelease = xerrors.New("unsupported release")
+
+// DecodeError is a file of a source which couldn't be decoded, e.g. of invalid JSON
+type DecodeError struct {
+	Source string // e.g. amazon
+	Path   string // e.g. vuln-list/amazon/2/ALAS2-2019-1234.json
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s of %s: %s", e.Path, e.Source, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
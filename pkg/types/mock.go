@@ -1,13 +1,17 @@
 package types
 
-import "github.com/stretchr/testify/mock"
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
 
 type MockVulnSrc struct {
 	mock.Mock
 }
 
-func (_m *MockVulnSrc) Update(a string) error {
-	ret := _m.Called(a)
+func (_m *MockVulnSrc) Update(ctx context.Context, a string) error {
+	ret := _m.Called(ctx, a)
 	return ret.Error(0)
 }
 
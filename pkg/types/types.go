@@ -1,7 +1,9 @@
 package types
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -9,12 +11,16 @@ import (
 
 type Severity int
 
+// The values are stored in the DBs, so a new severity comes last whatever its rank, see Rank.
 const (
 	SeverityUnknown Severity = iota
 	SeverityLow
 	SeverityMedium
 	SeverityHigh
 	SeverityCritical
+	// SeverityNegligible is below low, e.g. the negligible priority of Ubuntu and the unimportant urgency of
+	// Debian
+	SeverityNegligible
 )
 
 var (
@@ -24,6 +30,7 @@ var (
 		"MEDIUM",
 		"HIGH",
 		"CRITICAL",
+		"NEGLIGIBLE",
 	}
 	SeverityColor = []func(a ...interface{}) string{
 		color.New(color.FgCyan).SprintFunc(),
@@ -31,6 +38,17 @@ var (
 		color.New(color.FgYellow).SprintFunc(),
 		color.New(color.FgHiRed).SprintFunc(),
 		color.New(color.FgRed).SprintFunc(),
+		color.New(color.FgWhite).SprintFunc(),
+	}
+
+	// severityRanks orders the severities from unknown to critical
+	severityRanks = map[Severity]int{
+		SeverityUnknown:    0,
+		SeverityNegligible: 1,
+		SeverityLow:        2,
+		SeverityMedium:     3,
+		SeverityHigh:       4,
+		SeverityCritical:   5,
 	}
 )
 
@@ -43,10 +61,11 @@ func NewSeverity(severity string) (Severity, error) {
 	return SeverityUnknown, fmt.Errorf("unknown severity: %s", severity)
 }
 
+// CompareSeverityString compares the ranks of two severities, negative if the first is the more severe
 func CompareSeverityString(sev1, sev2 string) int {
 	s1, _ := NewSeverity(sev1)
 	s2, _ := NewSeverity(sev2)
-	return int(s2) - int(s1)
+	return s2.Rank() - s1.Rank()
 }
 
 func ColorizeSeverity(severity string) string {
@@ -62,33 +81,296 @@ func (s Severity) String() string {
 	return SeverityNames[s]
 }
 
+// Rank orders the severities, unknown first and negligible below low, which their values don't
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
 type LastUpdated struct {
 	Date time.Time
 }
 type VulnerabilityDetail struct {
-	ID          string   `json:",omitempty"` // e.g. CVE-2019-8331, OSVDB-104365
-	CvssScore   float64  `json:",omitempty"`
-	CvssScoreV3 float64  `json:",omitempty"`
-	Severity    Severity `json:",omitempty"`
-	SeverityV3  Severity `json:",omitempty"`
-	References  []string `json:",omitempty"`
-	Title       string   `json:",omitempty"`
-	Description string   `json:",omitempty"`
+	ID           string   `json:",omitempty"` // e.g. CVE-2019-8331, OSVDB-104365
+	CvssScore    float64  `json:",omitempty"`
+	CvssVector   string   `json:",omitempty"` // e.g. AV:N/AC:L/Au:N/C:P/I:P/A:P
+	CvssScoreV3  float64  `json:",omitempty"`
+	CvssVectorV3 string   `json:",omitempty"` // e.g. CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H
+	CvssScoreV4  float64  `json:",omitempty"`
+	CvssVectorV4 string   `json:",omitempty"` // e.g. CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N
+	Severity     Severity `json:",omitempty"`
+	SeverityV3   Severity `json:",omitempty"`
+	References   []string `json:",omitempty"`
+	Title        string   `json:",omitempty"`
+	Description  string   `json:",omitempty"`
+
+	// enrichment data such as CISA vulnrichment
+	KnownExploited *KnownExploited `json:",omitempty"`
+	SSVC           *SSVC           `json:",omitempty"`
+}
+
+// KnownExploited links a vulnerability to the CISA Known Exploited Vulnerabilities catalog
+type KnownExploited struct {
+	DateAdded string `json:",omitempty"` // e.g. 2021-11-03
+	Reference string `json:",omitempty"`
+}
+
+// SSVC holds Stakeholder-Specific Vulnerability Categorization decision points
+type SSVC struct {
+	Exploitation    string `json:",omitempty"` // none, poc or active
+	Automatable     string `json:",omitempty"` // yes or no
+	TechnicalImpact string `json:",omitempty"` // partial or total
+	Source          string `json:",omitempty"` // where the decision points came from, e.g. cisa-vulnrichment
 }
 
 type Advisory struct {
 	VulnerabilityID string `json:",omitempty"`
 	FixedVersion    string `json:",omitempty"`
+	// Severity is the one the source assigned to the erratum of the package, e.g. an ALAS rated important,
+	// which may differ from the severity of the vulnerability
+	Severity Severity `json:",omitempty"`
+	// VendorIDs are the IDs of the bulletins of the vendor the advisory comes from, e.g. ALAS-2019-1234 or
+	// RHSA-2019:0966, for the reports to link to them rather than only to the vulnerability
+	VendorIDs []string `json:",omitempty"`
+	// AffectedVersions are the ranges of the affected versions, e.g. of OSV, for the packages with several
+	// vulnerable branches a single fixed version can't describe
+	AffectedVersions []VersionRange `json:",omitempty"`
+	// VulnerableVersions are constraints any of which the affected versions match, each a comma separated list
+	// of comparisons all of which they match, e.g. ">= 2.0.0, < 2.3.1" as in GHSA
+	VulnerableVersions []string `json:",omitempty"`
+	// Arches are the architectures the advisory affects, e.g. x86_64 and aarch64, all of them if empty
+	Arches []string `json:",omitempty"`
+	// ArchFixedVersions are the fixed versions of the architectures whose fix landed in another version than
+	// FixedVersion, keyed by the architecture, e.g. aarch64
+	ArchFixedVersions map[string]string `json:",omitempty"`
+}
+
+// ForArch returns the advisory as it applies to an architecture, with the fixed version of the architecture,
+// and false if it doesn't affect the architecture. An empty arch is any architecture.
+func (a Advisory) ForArch(arch string) (Advisory, bool) {
+	if arch == "" {
+		return a, true
+	}
+	if len(a.Arches) > 0 {
+		affected := false
+		for _, s := range a.Arches {
+			affected = affected || s == arch
+		}
+		if !affected {
+			return Advisory{}, false
+		}
+	}
+	if fixed, ok := a.ArchFixedVersions[arch]; ok {
+		a.FixedVersion = fixed
+	}
+	a.Arches, a.ArchFixedVersions = []string{arch}, nil
+	return a, true
+}
+
+// AdvisoriesForArch returns the advisories affecting an architecture with its fixed version, all of them if
+// arch is empty
+func AdvisoriesForArch(advisories []Advisory, arch string) []Advisory {
+	if arch == "" {
+		return advisories
+	}
+	var results []Advisory
+	for _, advisory := range advisories {
+		if a, ok := advisory.ForArch(arch); ok {
+			results = append(results, a)
+		}
+	}
+	return results
+}
+
+// FixFilter selects the advisories a lookup returns by whether their package is fixed, e.g. as Debian stores
+// the unfixed ones too
+type FixFilter int
+
+const (
+	// AllAdvisories returns the advisories whether they are fixed or not, the default
+	AllAdvisories FixFilter = iota
+	// FixedOnly returns the advisories with a fixed version
+	FixedOnly
+	// UnfixedOnly returns the advisories without a fixed version
+	UnfixedOnly
+)
+
+// GetOptions are the options of the Get of the advisories of a source
+type GetOptions struct {
+	Fix FixFilter
+}
+
+// GetOption is an option of the Get of the advisories of a source
+type GetOption func(*GetOptions)
+
+// WithFixFilter returns the advisories selected by filter, all of them by default
+func WithFixFilter(filter FixFilter) GetOption {
+	return func(o *GetOptions) {
+		o.Fix = filter
+	}
+}
+
+// NewGetOptions returns the options of a Get, opts applied to the defaults
+func NewGetOptions(opts ...GetOption) GetOptions {
+	var o GetOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Fixed tells whether the advisory has a fixed version, for any architecture or range
+func (a Advisory) Fixed() bool {
+	if a.FixedVersion != "" || len(a.ArchFixedVersions) > 0 {
+		return true
+	}
+	for _, r := range a.AffectedVersions {
+		if r.Fixed != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// AdvisoriesByFix returns the advisories selected by filter
+func AdvisoriesByFix(advisories []Advisory, filter FixFilter) []Advisory {
+	if filter == AllAdvisories {
+		return advisories
+	}
+	var results []Advisory
+	for _, advisory := range advisories {
+		if advisory.Fixed() == (filter == FixedOnly) {
+			results = append(results, advisory)
+		}
+	}
+	return results
+}
+
+// VersionRange is the affected versions from Introduced, the first version if empty or 0, up to Fixed
+// excluded or LastAffected included, without end if both are empty
+type VersionRange struct {
+	Introduced   string `json:",omitempty"`
+	Fixed        string `json:",omitempty"`
+	LastAffected string `json:",omitempty"`
+}
+
+// AffectedPackage is a package with an advisory for a vulnerability
+type AffectedPackage struct {
+	DataSource string `json:",omitempty"` // e.g. debian
+	Source     string // the platform of the advisory bucket, e.g. debian 9
+	Ecosystem  string // e.g. debian
+	Release    string `json:",omitempty"` // e.g. 9, empty for language ecosystems
+	Package    string
+}
+
+// DataSource is where the advisories of a data source ID come from
+type DataSource struct {
+	ID            string // e.g. alpine, the prefix of the advisory buckets
+	Name          string // e.g. Alpine Secdb
+	URL           string `json:",omitempty"`
+	SeverityScale string `json:",omitempty"` // how the source rates vulnerabilities, e.g. CVSS v3 or the vendor's own scale
+}
+
+// Provenance is the upstream record an advisory was derived from
+type Provenance struct {
+	Path   string // the file relative to the cache dir, e.g. vuln-list/alpine/3.10/main/openssl.json
+	SHA256 string // of the content of the file
+	// DB is the name of the DB the advisory was merged from, e.g. internal
+	DB string `json:",omitempty"`
+}
+
+// CPEMatch is a vulnerable CPE of an NVD configuration, with an optional version range
+type CPEMatch struct {
+	CPE                   string // CPE 2.3 formatted string
+	VersionStartIncluding string `json:",omitempty"`
+	VersionStartExcluding string `json:",omitempty"`
+	VersionEndIncluding   string `json:",omitempty"`
+	VersionEndExcluding   string `json:",omitempty"`
 }
 
 type Vulnerability struct {
-	Title       string   `json:",omitempty"`
-	Description string   `json:",omitempty"`
-	Severity    string   `json:",omitempty"`
-	References  []string `json:",omitempty"`
+	Title          string          `json:",omitempty"`
+	Description    string          `json:",omitempty"`
+	Severity       string          `json:",omitempty"`
+	References     []string        `json:",omitempty"`
+	KnownExploited *KnownExploited `json:",omitempty"`
+	// CVSS is the CVSS of each source rating the vulnerability, keyed by the source, e.g. nvd or redhat
+	CVSS map[string]CVSS `json:",omitempty"`
+}
+
+// CVSS is the scores and vectors a source rates a vulnerability with, by CVSS version
+type CVSS struct {
+	V2Score  float64 `json:",omitempty"`
+	V2Vector string  `json:",omitempty"` // e.g. AV:N/AC:L/Au:N/C:P/I:P/A:P
+	V3Score  float64 `json:",omitempty"`
+	V3Vector string  `json:",omitempty"` // e.g. CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H
+	V4Score  float64 `json:",omitempty"`
+	V4Vector string  `json:",omitempty"` // e.g. CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N
+}
+
+// IsEmpty returns whether the source gave no score or vector
+func (c CVSS) IsEmpty() bool {
+	return c == CVSS{}
+}
+
+// Validate checks the scores and the vectors of the versions with a prefix
+func (c CVSS) Validate() error {
+	return validateCVSS([]float64{c.V2Score, c.V3Score, c.V4Score}, c.V3Vector, c.V4Vector)
+}
+
+// Validate checks the severity, the CVSS of the sources and the references
+func (v Vulnerability) Validate() error {
+	if v.Severity != "" {
+		if _, err := NewSeverity(v.Severity); err != nil {
+			return err
+		}
+	}
+	for source, c := range v.CVSS {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return validateReferences(v.References)
+}
+
+// Validate checks the severities, the CVSS scores and vectors, and the references
+func (v VulnerabilityDetail) Validate() error {
+	for _, s := range []Severity{v.Severity, v.SeverityV3} {
+		if s < SeverityUnknown || int(s) >= len(SeverityNames) {
+			return fmt.Errorf("invalid severity: %d", s)
+		}
+	}
+	err := validateCVSS([]float64{v.CvssScore, v.CvssScoreV3, v.CvssScoreV4}, v.CvssVectorV3, v.CvssVectorV4)
+	if err != nil {
+		return err
+	}
+	return validateReferences(v.References)
+}
+
+func validateCVSS(scores []float64, vectorV3, vectorV4 string) error {
+	for _, score := range scores {
+		if score < 0 || score > 10 {
+			return fmt.Errorf("invalid CVSS score: %g", score)
+		}
+	}
+	if vectorV3 != "" && !strings.HasPrefix(vectorV3, "CVSS:3.") {
+		return fmt.Errorf("invalid CVSS v3 vector: %s", vectorV3)
+	}
+	if vectorV4 != "" && !strings.HasPrefix(vectorV4, "CVSS:4.") {
+		return fmt.Errorf("invalid CVSS v4 vector: %s", vectorV4)
+	}
+	return nil
+}
+
+func validateReferences(refs []string) error {
+	for _, ref := range refs {
+		if strings.TrimSpace(ref) == "" {
+			return fmt.Errorf("empty reference")
+		}
+	}
+	return nil
 }
 
 type VulnSrc interface {
-	Update(string) error
+	Update(context.Context, string) error
 	Get(string, string) ([]Advisory, error)
 }
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverity(t *testing.T) {
+	for i := range SeverityNames {
+		s := Severity(i)
+		got, err := NewSeverity(s.String())
+		assert.NoError(t, err)
+		assert.Equal(t, s, got)
+
+		b, err := json.Marshal(VulnerabilityDetail{Severity: s})
+		assert.NoError(t, err)
+		var detail VulnerabilityDetail
+		assert.NoError(t, json.Unmarshal(b, &detail))
+		assert.Equal(t, s, detail.Severity)
+	}
+
+	// the stored values of the severities before negligible are kept
+	assert.Equal(t, Severity(4), SeverityCritical)
+	assert.Equal(t, Severity(5), SeverityNegligible)
+
+	severities := []string{"HIGH", "NEGLIGIBLE", "UNKNOWN", "CRITICAL", "LOW", "MEDIUM"}
+	sort.Slice(severities, func(i, j int) bool {
+		return CompareSeverityString(severities[i], severities[j]) < 0
+	})
+	assert.Equal(t, []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "NEGLIGIBLE", "UNKNOWN"}, severities)
+	assert.True(t, SeverityNegligible.Rank() < SeverityLow.Rank())
+	assert.True(t, SeverityUnknown.Rank() < SeverityNegligible.Rank())
+}
+
+func TestAdvisory_ForArch(t *testing.T) {
+	advisory := Advisory{VulnerabilityID: "CVE-2020-0001", FixedVersion: "4.18.0-147.5.1.el8_1",
+		Arches: []string{"aarch64", "x86_64"}, ArchFixedVersions: map[string]string{"x86_64": "4.18.0-147.3.1.el8_1"}}
+
+	tests := []struct {
+		name     string
+		advisory Advisory
+		arch     string
+		want     Advisory
+		wantOK   bool
+	}{
+		{
+			name:     "any architecture",
+			advisory: advisory,
+			want:     advisory,
+			wantOK:   true,
+		},
+		{
+			name:     "fixed version of the architecture",
+			advisory: advisory,
+			arch:     "x86_64",
+			want:     Advisory{VulnerabilityID: "CVE-2020-0001", FixedVersion: "4.18.0-147.3.1.el8_1", Arches: []string{"x86_64"}},
+			wantOK:   true,
+		},
+		{
+			name:     "fixed version of the advisory",
+			advisory: advisory,
+			arch:     "aarch64",
+			want:     Advisory{VulnerabilityID: "CVE-2020-0001", FixedVersion: "4.18.0-147.5.1.el8_1", Arches: []string{"aarch64"}},
+			wantOK:   true,
+		},
+		{
+			name:     "architecture not affected",
+			advisory: advisory,
+			arch:     "ppc64le",
+		},
+		{
+			name:     "advisory of all architectures",
+			advisory: Advisory{VulnerabilityID: "CVE-2020-0001", FixedVersion: "1.0"},
+			arch:     "ppc64le",
+			want:     Advisory{VulnerabilityID: "CVE-2020-0001", FixedVersion: "1.0", Arches: []string{"ppc64le"}},
+			wantOK:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.advisory.ForArch(tt.arch)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAdvisoriesByFix(t *testing.T) {
+	fixed := Advisory{VulnerabilityID: "CVE-2020-0001", FixedVersion: "1.0"}
+	archFixed := Advisory{VulnerabilityID: "CVE-2020-0002", ArchFixedVersions: map[string]string{"x86_64": "1.0"}}
+	rangeFixed := Advisory{VulnerabilityID: "CVE-2020-0003", AffectedVersions: []VersionRange{{Introduced: "0", Fixed: "1.0"}}}
+	unfixed := Advisory{VulnerabilityID: "CVE-2020-0004"}
+	lastAffected := Advisory{VulnerabilityID: "CVE-2020-0005", AffectedVersions: []VersionRange{{LastAffected: "1.0"}}}
+	advisories := []Advisory{fixed, archFixed, rangeFixed, unfixed, lastAffected}
+
+	tests := []struct {
+		name   string
+		filter FixFilter
+		want   []Advisory
+	}{
+		{
+			name:   "all advisories",
+			filter: AllAdvisories,
+			want:   advisories,
+		},
+		{
+			name:   "fixed only",
+			filter: FixedOnly,
+			want:   []Advisory{fixed, archFixed, rangeFixed},
+		},
+		{
+			name:   "unfixed only",
+			filter: UnfixedOnly,
+			want:   []Advisory{unfixed, lastAffected},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AdvisoriesByFix(advisories, NewGetOptions(WithFixFilter(tt.filter)).Fix)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -9,6 +9,13 @@ import (
 	"golang.org/x/xerrors"
 
 	"github.com/urfave/cli"
+
+	"github.com/aquasecurity/trivy-db/pkg/artifact"
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/notify"
+	"github.com/aquasecurity/trivy-db/pkg/pipeline"
+	"github.com/aquasecurity/trivy-db/pkg/publisher"
 )
 
 func (ac AppConfig) upload(c *cli.Context) error {
@@ -18,19 +25,71 @@ func (ac AppConfig) upload(c *cli.Context) error {
 		return xerrors.Errorf("unable to list files: %w", err)
 	}
 
-	// only gz file
+	// only gz file, with the signatures and the metadata of sign
 	var filePaths []string
 	for _, f := range files {
-		if f.IsDir() || !strings.HasSuffix(f.Name(), "db.gz") {
+		if f.IsDir() || !isAsset(f.Name()) {
 			continue
 		}
 		path := filepath.Join(dir, f.Name())
 		filePaths = append(filePaths, path)
 	}
 
+	// the assets are published to the channel of their DBs, described by the metadata command
+	metadata, err := artifact.ReadMetadata(dir)
+	if err != nil {
+		return err
+	}
+	channel := metadata.Channel
+	if channel == "" {
+		channel = db.ChannelStable
+	}
+
 	ctx := context.Background()
-	if err := ac.Client.UploadReleaseAsset(ctx, filePaths); err != nil {
+	if configPath := c.String("config"); configPath != "" {
+		return publishAssets(ctx, configPath, channel, filePaths)
+	}
+	if err := ac.Client.UploadReleaseAsset(ctx, channel, filePaths); err != nil {
 		return xerrors.Errorf("failed to upload a release asset: %w", err)
 	}
 	return nil
 }
+
+// publishAssets uploads the files to the publishers of the channel in the pipeline config, e.g. the buckets
+// of a mirror and GitHub
+func publishAssets(ctx context.Context, configPath, channel string, filePaths []string) error {
+	config, err := pipeline.Load(configPath)
+	if err != nil {
+		return err
+	}
+	config.Config = config.Config.ForChannel(channel)
+	publishers, err := publisher.New(ctx, config.Config)
+	if err != nil {
+		return xerrors.Errorf("invalid publisher: %w", err)
+	}
+	notifiers, err := notify.New(config.Notifications)
+	if err != nil {
+		return xerrors.Errorf("invalid notification: %w", err)
+	}
+
+	return withBaseline(ctx, config, func(baseline string) error {
+		var destinations []string
+		for i, p := range publishers {
+			c := config.Publishers[i]
+			if err := p.Publish(ctx, filePaths); err != nil {
+				return xerrors.Errorf("failed to publish to %s: %w", c, err)
+			}
+			log.Info("Published the assets", "destination", c.String(), "files", len(filePaths))
+			destinations = append(destinations, c.String())
+		}
+		if len(notifiers) > 0 {
+			notifyPublished(ctx, config, notifiers, baseline, filePaths, destinations)
+		}
+		return nil
+	})
+}
+
+func isAsset(name string) bool {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".sig"), ".pem")
+	return artifact.IsArchive(name) || name == artifact.MetadataFile
+}
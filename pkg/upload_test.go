@@ -16,7 +16,7 @@ type mockVCSClient struct {
 	uploadReleaseAsset func(ctx context.Context, filePaths []string) error
 }
 
-func (mc mockVCSClient) UploadReleaseAsset(ctx context.Context, filePaths []string) error {
+func (mc mockVCSClient) UploadReleaseAsset(ctx context.Context, _ string, filePaths []string) error {
 	if mc.uploadReleaseAsset != nil {
 		return mc.uploadReleaseAsset(ctx, filePaths)
 	}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"sort"
+
+	version "github.com/knqyf263/go-rpm-version"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// ArchFix is the rpm version fixing a package on an architecture, e.g. aarch64, all of them if empty
+type ArchFix struct {
+	Arch         string
+	FixedVersion string
+}
+
+// ArchAdvisory merges the fixes of a package on several architectures. Its fixed version is the latest
+// of them, for the scanners ignoring the architecture, and the architectures fixed in another version have
+// theirs. A fix without architecture is for all of them.
+func ArchAdvisory(fixes []ArchFix) types.Advisory {
+	var advisory types.Advisory
+	allArches := false
+	for _, fix := range fixes {
+		if fix.Arch == "" {
+			allArches = true
+		} else if !StringInSlice(fix.Arch, advisory.Arches) {
+			advisory.Arches = append(advisory.Arches, fix.Arch)
+		}
+		if advisory.FixedVersion == "" ||
+			version.NewVersion(advisory.FixedVersion).LessThan(version.NewVersion(fix.FixedVersion)) {
+			advisory.FixedVersion = fix.FixedVersion
+		}
+	}
+	for _, fix := range fixes {
+		if fix.Arch != "" && fix.FixedVersion != advisory.FixedVersion {
+			if advisory.ArchFixedVersions == nil {
+				advisory.ArchFixedVersions = map[string]string{}
+			}
+			advisory.ArchFixedVersions[fix.Arch] = fix.FixedVersion
+		}
+	}
+	if allArches {
+		advisory.Arches = nil
+	}
+	sort.Strings(advisory.Arches)
+	return advisory
+}
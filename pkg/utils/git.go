@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// GitRevision returns the commit checked out in a git repository, or "" if dir isn't one
+func GitRevision(dir string) (string, error) {
+	gitDir := filepath.Join(dir, ".git")
+	head, err := ioutil.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", xerrors.Errorf("failed to read HEAD: %w", err)
+	}
+
+	ref := strings.TrimSpace(string(head))
+	if !strings.HasPrefix(ref, "ref: ") {
+		// detached HEAD
+		return ref, nil
+	}
+	ref = strings.TrimPrefix(ref, "ref: ")
+
+	if b, err := ioutil.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(b)), nil
+	} else if !os.IsNotExist(err) {
+		return "", xerrors.Errorf("failed to read %s: %w", ref, err)
+	}
+
+	// refs are packed by git gc
+	f, err := os.Open(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		return "", xerrors.Errorf("failed to open packed-refs: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == ref {
+			return fields[0], nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", xerrors.Errorf("failed to read packed-refs: %w", err)
+	}
+	return "", xerrors.Errorf("%s not found", ref)
+}
+
+// GitChanges returns the files changed in the git repository at dir between the commit since and HEAD, the
+// deleted ones included, relative to dir. It fails when since isn't in the history, e.g. of a shallow clone.
+func GitChanges(ctx context.Context, dir, since string) ([]string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "diff", "--name-only", "--no-renames", "-z", since, "HEAD")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, xerrors.Errorf("git diff: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var files []string
+	for _, file := range strings.Split(stdout.String(), "\x00") {
+		if file != "" {
+			files = append(files, filepath.FromSlash(file))
+		}
+	}
+	return files, nil
+}
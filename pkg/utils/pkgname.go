@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/package-url/packageurl-go"
+)
+
+var (
+	// PEP 503: runs of -, _ and . are equivalent in PyPI names
+	pypiSeparators = regexp.MustCompile(`[-_.]+`)
+
+	// ecosystem => how its registry compares package names
+	normalizers = map[string]func(string) string{
+		packageurl.TypeNPM:      NormalizeNpmName,
+		packageurl.TypePyPi:     NormalizePyPIName,
+		"cargo":                 strings.ToLower,
+		packageurl.TypeComposer: NormalizeComposerName,
+		packageurl.TypeMaven:    NormalizeMavenName,
+		packageurl.TypeGolang:   EscapeModulePath,
+	}
+)
+
+// NormalizePackageName returns the form of a package name stored in the DB for an ecosystem, a package URL type
+// like pypi or maven, so that names the registry of the ecosystem considers the same get the same advisories.
+// Names of other ecosystems, e.g. OS packages and gems, are case-sensitive and kept as is.
+func NormalizePackageName(ecosystem, pkgName string) string {
+	if normalize, ok := normalizers[ecosystem]; ok {
+		return normalize(pkgName)
+	}
+	return pkgName
+}
+
+// NormalizePyPIName applies PEP 503, e.g. Zope.Interface becomes zope-interface
+func NormalizePyPIName(name string) string {
+	return pypiSeparators.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+}
+
+// NormalizeNpmName lowercases a name, which npm compares case-insensitively, and decodes a scoped name
+// escaped as in a registry URL, e.g. %40Babel%2fCore becomes @babel/core
+func NormalizeNpmName(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "%40") {
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+	}
+	return strings.ToLower(name)
+}
+
+// NormalizeComposerName lowercases a vendor/name, which Packagist compares case-insensitively, with or without
+// the composer:// prefix of FriendsOfPHP
+func NormalizeComposerName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// NormalizeMavenName writes group:artifact, which some sources write group/artifact. Maven names are
+// case-sensitive.
+func NormalizeMavenName(name string) string {
+	name = strings.TrimSpace(name)
+	if !strings.Contains(name, ":") {
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[:i] + ":" + name[i+1:]
+		}
+	}
+	return name
+}
+
+// EscapeModulePath applies the case-encoding of the Go module proxy, e.g. github.com/Azure/go-autorest
+// becomes github.com/!azure/go-autorest, so that paths differing only in case don't collide
+func EscapeModulePath(path string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(path) {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePackageName(t *testing.T) {
+	tests := []struct {
+		name      string
+		ecosystem string
+		pkgName   string
+		want      string
+	}{
+		{name: "pypi", ecosystem: "pypi", pkgName: "Zope.Interface", want: "zope-interface"},
+		{name: "pypi separator runs", ecosystem: "pypi", pkgName: "foo__bar-.baz", want: "foo-bar-baz"},
+		{name: "pypi spaces", ecosystem: "pypi", pkgName: " Django ", want: "django"},
+		{name: "npm", ecosystem: "npm", pkgName: "Lodash", want: "lodash"},
+		{name: "npm scoped", ecosystem: "npm", pkgName: "@Babel/Core", want: "@babel/core"},
+		{name: "npm escaped scope", ecosystem: "npm", pkgName: "%40Babel%2fCore", want: "@babel/core"},
+		{name: "npm invalid escape", ecosystem: "npm", pkgName: "%40babel%zz", want: "%40babel%zz"},
+		{name: "cargo", ecosystem: "cargo", pkgName: "SmallVec", want: "smallvec"},
+		{name: "composer", ecosystem: "composer", pkgName: "Symfony/HTTP-Kernel", want: "symfony/http-kernel"},
+		{name: "composer reference", ecosystem: "composer", pkgName: "composer://Symfony/HTTP-Kernel",
+			want: "composer://symfony/http-kernel"},
+		{name: "maven", ecosystem: "maven", pkgName: "org.apache.logging.log4j:log4j-core",
+			want: "org.apache.logging.log4j:log4j-core"},
+		{name: "maven with a slash", ecosystem: "maven", pkgName: "org.apache.logging.log4j/log4j-core",
+			want: "org.apache.logging.log4j:log4j-core"},
+		{name: "maven is case-sensitive", ecosystem: "maven", pkgName: "com.Example:Lib", want: "com.Example:Lib"},
+		{name: "maven without a group", ecosystem: "maven", pkgName: "log4j-core", want: "log4j-core"},
+		{name: "golang", ecosystem: "golang", pkgName: "github.com/Azure/go-autorest",
+			want: "github.com/!azure/go-autorest"},
+		{name: "golang already encoded", ecosystem: "golang", pkgName: "github.com/!azure/go-autorest",
+			want: "github.com/!azure/go-autorest"},
+		{name: "golang lowercase", ecosystem: "golang", pkgName: "golang.org/x/net", want: "golang.org/x/net"},
+		{name: "gem", ecosystem: "gem", pkgName: "RedCloth", want: "RedCloth"},
+		{name: "os package", ecosystem: "", pkgName: "NetworkManager", want: "NetworkManager"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizePackageName(tt.ecosystem, tt.pkgName))
+		})
+	}
+}
+
+func TestNormalizePackageName_Idempotent(t *testing.T) {
+	for ecosystem, names := range map[string][]string{
+		"pypi":     {"Zope.Interface", "foo__bar-.baz"},
+		"npm":      {"@Babel/Core", "%40Babel%2fCore"},
+		"composer": {"composer://Symfony/HTTP-Kernel"},
+		"maven":    {"org.apache.logging.log4j/log4j-core"},
+		"golang":   {"github.com/Azure/go-autorest"},
+	} {
+		for _, name := range names {
+			once := NormalizePackageName(ecosystem, name)
+			assert.Equal(t, once, NormalizePackageName(ecosystem, once), "%s %s", ecosystem, name)
+		}
+	}
+}
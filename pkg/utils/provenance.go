@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// ProvenanceReader hashes the content of an upstream file read through it
+type ProvenanceReader struct {
+	r    io.Reader
+	h    hash.Hash
+	path string
+}
+
+// NewProvenanceReader reads r, the content of the file at path in cacheDir
+func NewProvenanceReader(r io.Reader, cacheDir, path string) *ProvenanceReader {
+	if rel, err := filepath.Rel(cacheDir, path); err == nil {
+		path = rel
+	}
+	h := sha256.New()
+	return &ProvenanceReader{r: io.TeeReader(r, h), h: h, path: filepath.ToSlash(path)}
+}
+
+func (p *ProvenanceReader) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+// Provenance reads what is left of the file, e.g. after a decoder stopped at the end of a JSON value,
+// and returns its path and hash
+func (p *ProvenanceReader) Provenance() (types.Provenance, error) {
+	if _, err := io.Copy(ioutil.Discard, p.r); err != nil {
+		return types.Provenance{}, err
+	}
+	return types.Provenance{Path: p.path, SHA256: hex.EncodeToString(p.h.Sum(nil))}, nil
+}
+
+// ContentProvenance returns the provenance of a file read at once
+func ContentProvenance(content []byte, cacheDir, path string) types.Provenance {
+	if rel, err := filepath.Rel(cacheDir, path); err == nil {
+		path = rel
+	}
+	sum := sha256.Sum256(content)
+	return types.Provenance{Path: filepath.ToSlash(path), SHA256: hex.EncodeToString(sum[:])}
+}
@@ -2,14 +2,17 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"io"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
 )
 
 func CacheDir() string {
@@ -20,17 +23,35 @@ func CacheDir() string {
 	return filepath.Join(tmpDir, "trivy-db")
 }
 
-func FileWalk(root string, walkFn func(r io.Reader, path string) error) error {
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+type filesKey struct{}
+
+// WithFiles makes FileWalk only walk the files among files, e.g. the ones changed since the last build, which
+// may have been deleted since
+func WithFiles(ctx context.Context, files []string) context.Context {
+	set := map[string]bool{}
+	for _, file := range files {
+		set[filepath.Clean(file)] = true
+	}
+	return context.WithValue(ctx, filesKey{}, set)
+}
+
+// FileWalk calls walkFn with the non-empty files under root, only the ones of WithFiles if ctx has them,
+// and stops when ctx is done
+func FileWalk(ctx context.Context, root string, walkFn func(r io.Reader, path string) error) error {
+	visit := func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		if info.IsDir() {
 			return nil
 		}
 
 		if info.Size() == 0 {
-			log.Printf("invalid size: %s\n", path)
+			log.Warn("Invalid size", "path", path)
+			log.ProgressFrom(ctx).AddErrors(1)
 			return nil
 		}
 
@@ -43,14 +64,47 @@ func FileWalk(root string, walkFn func(r io.Reader, path string) error) error {
 		if err = walkFn(f, path); err != nil {
 			return err
 		}
+		log.ProgressFrom(ctx).AddFiles(1)
 		return nil
-	})
+	}
+
+	var err error
+	if files, ok := ctx.Value(filesKey{}).(map[string]bool); ok {
+		err = walkFiles(root, files, visit)
+	} else {
+		err = filepath.Walk(root, visit)
+	}
 	if err != nil {
 		return xerrors.Errorf("error in file walk: %w", err)
 	}
 	return nil
 }
 
+// walkFiles calls visit with the files under root in lexical order, like filepath.Walk, skipping the missing ones
+func walkFiles(root string, files map[string]bool, visit filepath.WalkFunc) error {
+	if _, err := os.Lstat(root); err != nil {
+		return err
+	}
+	prefix := filepath.Clean(root) + string(filepath.Separator)
+	var paths []string
+	for path := range files {
+		if strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err = visit(path, info, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func IsCommandAvailable(name string) bool {
 	cmd := exec.Command(name, "--help")
 	if err := cmd.Run(); err != nil {
@@ -85,7 +139,7 @@ func Exec(command string, args []string) (string, error) {
 	cmd.Stdout = &stdoutBuf
 	cmd.Stderr = &stderrBuf
 	if err := cmd.Run(); err != nil {
-		log.Println(stderrBuf.String())
+		log.Error("Command failed", "command", command, "stderr", stderrBuf.String())
 		return "", xerrors.Errorf("failed to exec: %w", err)
 	}
 	return stdoutBuf.String(), nil
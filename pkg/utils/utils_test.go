@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"context"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"os"
@@ -10,6 +12,8 @@ import (
 
 	"github.com/aquasecurity/trivy/pkg/log"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
 )
 
 func touch(t *testing.T, name string) {
@@ -69,7 +73,7 @@ func TestFileWalk(t *testing.T) {
 		return nil
 	}
 
-	err = FileWalk(td, walker)
+	err = FileWalk(context.Background(), td, walker)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -84,6 +88,39 @@ func TestFileWalk(t *testing.T) {
 	}
 }
 
+func TestFileWalk_WithFiles(t *testing.T) {
+	td, err := ioutil.TempDir("", "walktest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(td)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(td, "dir", "sub"), 0755))
+	write(t, filepath.Join(td, "dir", "foo1"), "foo1")
+	write(t, filepath.Join(td, "dir", "sub", "foo2"), "foo2")
+	write(t, filepath.Join(td, "dir", "foo3"), "foo3")
+	write(t, filepath.Join(td, "other"), "other")
+
+	ctx := WithFiles(context.Background(), []string{
+		filepath.Join(td, "dir", "sub", "foo2"),
+		filepath.Join(td, "dir", "foo1"),
+		// deleted
+		filepath.Join(td, "dir", "foo4"),
+		// not under the root
+		filepath.Join(td, "other"),
+	})
+	var got []string
+	err = FileWalk(ctx, filepath.Join(td, "dir"), func(r io.Reader, path string) error {
+		rel, err := filepath.Rel(td, path)
+		got = append(got, filepath.ToSlash(rel))
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dir/foo1", "dir/sub/foo2"}, got)
+
+	// as without files
+	err = FileWalk(ctx, filepath.Join(td, "nowhere"), func(io.Reader, string) error { return nil })
+	assert.Error(t, err)
+}
+
 func TestUniq(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -132,3 +169,154 @@ func TestUniq(t *testing.T) {
 	}
 
 }
+
+func TestArchAdvisory(t *testing.T) {
+	testCases := []struct {
+		name  string
+		fixes []ArchFix
+		want  types.Advisory
+	}{
+		{
+			name: "same fixed version",
+			fixes: []ArchFix{
+				{Arch: "x86_64", FixedVersion: "7.61.1-11.amzn2.0.2"},
+				{Arch: "aarch64", FixedVersion: "7.61.1-11.amzn2.0.2"},
+			},
+			want: types.Advisory{FixedVersion: "7.61.1-11.amzn2.0.2", Arches: []string{"aarch64", "x86_64"}},
+		},
+		{
+			name: "fixed in another version",
+			fixes: []ArchFix{
+				{Arch: "x86_64", FixedVersion: "4.18.0-147.3.1.el8_1"},
+				{Arch: "aarch64", FixedVersion: "4.18.0-147.5.1.el8_1"},
+			},
+			want: types.Advisory{FixedVersion: "4.18.0-147.5.1.el8_1", Arches: []string{"aarch64", "x86_64"},
+				ArchFixedVersions: map[string]string{"x86_64": "4.18.0-147.3.1.el8_1"}},
+		},
+		{
+			name: "fix without architecture",
+			fixes: []ArchFix{
+				{Arch: "x86_64", FixedVersion: "1.0-1"},
+				{FixedVersion: "1.0-1"},
+			},
+			want: types.Advisory{FixedVersion: "1.0-1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ArchAdvisory(tc.fixes))
+		})
+	}
+}
+
+func TestGitRevision(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "branch",
+			files: map[string]string{
+				"HEAD":              "ref: refs/heads/main\n",
+				"refs/heads/main":   sha + "\n",
+				"packed-refs":       "# pack-refs with: peeled fully-peeled sorted\n",
+				"refs/heads/stable": "ffffffffffffffffffffffffffffffffffffffff\n",
+			},
+			want: sha,
+		},
+		{
+			name: "packed branch",
+			files: map[string]string{
+				"HEAD":        "ref: refs/heads/main\n",
+				"packed-refs": "# pack-refs with: peeled fully-peeled sorted\n" + sha + " refs/heads/main\n",
+			},
+			want: sha,
+		},
+		{
+			name: "detached",
+			files: map[string]string{
+				"HEAD": sha + "\n",
+			},
+			want: sha,
+		},
+		{
+			name: "not a repository",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "TestGitRevision")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			for name, content := range tt.files {
+				path := filepath.Join(dir, ".git", filepath.FromSlash(name))
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+				write(t, path, content)
+			}
+
+			got, err := GitRevision(dir)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProvenanceReader(t *testing.T) {
+	// the decoder stops before the trailing newline, which is hashed too
+	pr := NewProvenanceReader(strings.NewReader("{\"id\": 1}\n"), "cache", filepath.Join("cache", "vuln-list", "1.json"))
+	var v struct{ ID int }
+	assert.NoError(t, json.NewDecoder(pr).Decode(&v))
+
+	got, err := pr.Provenance()
+	assert.NoError(t, err)
+	want := types.Provenance{
+		Path:   "vuln-list/1.json",
+		SHA256: "82b0cf5da91b6a7e02f031e2da2fa5ed1261dce4c85ae20d63db9d1e84a4c384",
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, want, ContentProvenance([]byte("{\"id\": 1}\n"), "cache", filepath.Join("cache", "vuln-list", "1.json")))
+}
+
+func TestGitChanges(t *testing.T) {
+	if !IsCommandAvailable("git") {
+		t.Skip("git is not available")
+	}
+	dir, err := ioutil.TempDir("", "TestGitChanges")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	git := func(args ...string) string {
+		out, err := Exec("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"},
+			args...))
+		assert.NoError(t, err)
+		return strings.TrimSpace(out)
+	}
+	git("init", "--quiet")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "alpine"), 0755))
+	write(t, filepath.Join(dir, "alpine", "main.json"), "{}")
+	write(t, filepath.Join(dir, "alpine", "community.json"), "{}")
+	write(t, filepath.Join(dir, "nvd.json"), "{}")
+	git("add", "--all")
+	git("commit", "--quiet", "--message", "first")
+	first := git("rev-parse", "HEAD")
+
+	write(t, filepath.Join(dir, "alpine", "main.json"), `{"id": 1}`)
+	assert.NoError(t, os.Rename(filepath.Join(dir, "nvd.json"), filepath.Join(dir, "cve.json")))
+	git("add", "--all")
+	git("commit", "--quiet", "--message", "second")
+
+	got, err := GitChanges(context.Background(), dir, first)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join("alpine", "main.json"), "cve.json", "nvd.json"}, got)
+
+	got, err = GitChanges(context.Background(), dir, git("rev-parse", "HEAD"))
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = GitChanges(context.Background(), dir, "0123456789abcdef0123456789abcdef01234567")
+	assert.Error(t, err)
+}
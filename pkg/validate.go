@@ -0,0 +1,37 @@
+package pkg
+
+import (
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+func validate(c *cli.Context) error {
+	if err := db.OpenReadOnly(c.String("cache-dir")); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := checkDB(); err != nil {
+		return err
+	}
+	log.Info("The DB is valid")
+	return nil
+}
+
+// checkDB logs the violations of the invariants of the DB and fails if there are any
+func checkDB() error {
+	violations, err := db.Config{}.Check()
+	if err != nil {
+		return err
+	}
+	for _, v := range violations {
+		log.Error("Invalid DB", "violation", v.String())
+	}
+	if len(violations) > 0 {
+		return xerrors.Errorf("the DB has %d violations", len(violations))
+	}
+	return nil
+}
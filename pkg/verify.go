@@ -0,0 +1,22 @@
+package pkg
+
+import (
+	"github.com/urfave/cli"
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+func verify(c *cli.Context) error {
+	if err := db.OpenReadOnly(c.String("cache-dir")); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := (db.Config{}).Verify(); err != nil {
+		return xerrors.Errorf("failed to verify the DB: %w", err)
+	}
+	log.Info("The DB matches the checksums")
+	return nil
+}
@@ -0,0 +1,96 @@
+package version
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// apkComparer follows apk-tools: digits{.digits}[letter]{_suffix[digits]}[~hash][-r digits]
+type apkComparer struct{}
+
+// the order of the suffixes of apk-tools, the pre-release ones sort before a version without suffix
+var apkSuffixes = map[string]int{
+	"alpha": -4, "beta": -3, "pre": -2, "rc": -1,
+	"cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5,
+}
+
+func (apkComparer) Compare(a, b string) (int, error) {
+	va, err := parseAPKVersion(a)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid apk version %s: %w", a, err)
+	}
+	vb, err := parseAPKVersion(b)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid apk version %s: %w", b, err)
+	}
+	// the components line up until the first difference, the lengths only differ after it
+	for i := 0; i < len(va) && i < len(vb); i++ {
+		if va[i] != vb[i] {
+			if va[i] < vb[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// parseAPKVersion returns comparable components: the digits, up to 6 of them, the letter, the suffixes
+// and the release
+func parseAPKVersion(v string) ([]int64, error) {
+	release := int64(0)
+	if i := strings.LastIndex(v, "-r"); i >= 0 {
+		r, err := strconv.ParseInt(v[i+2:], 10, 64)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid release: %w", err)
+		}
+		release, v = r, v[:i]
+	}
+	if i := strings.Index(v, "~"); i >= 0 {
+		v = v[:i]
+	}
+	var suffixes []string
+	if i := strings.Index(v, "_"); i >= 0 {
+		suffixes, v = strings.Split(v[i+1:], "_"), v[:i]
+	}
+
+	var letter int64
+	if n := len(v); n > 0 && v[n-1] >= 'a' && v[n-1] <= 'z' {
+		letter, v = int64(v[n-1]), v[:n-1]
+	}
+	digits := strings.Split(v, ".")
+	if len(digits) > 6 {
+		return nil, xerrors.New("too many components")
+	}
+	components := make([]int64, 6, 10+2*len(suffixes))
+	for i, d := range digits {
+		n, err := strconv.ParseInt(d, 10, 64)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid component %q: %w", d, err)
+		}
+		// shifted so that a missing component sorts below 0
+		components[i] = n + 1
+	}
+	components = append(components, letter)
+
+	for _, s := range suffixes {
+		name := strings.TrimRight(s, "0123456789")
+		order, ok := apkSuffixes[name]
+		if !ok {
+			return nil, xerrors.Errorf("invalid suffix %q", s)
+		}
+		var n int64
+		if num := s[len(name):]; num != "" {
+			var err error
+			if n, err = strconv.ParseInt(num, 10, 64); err != nil {
+				return nil, xerrors.Errorf("invalid suffix %q: %w", s, err)
+			}
+		}
+		components = append(components, int64(order), n)
+	}
+	// no suffix sorts between the pre-release and the post-release ones
+	components = append(components, 0, 0)
+	return append(components, release), nil
+}
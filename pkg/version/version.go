@@ -0,0 +1,69 @@
+package version
+
+import (
+	goversion "github.com/hashicorp/go-version"
+	debversion "github.com/knqyf263/go-deb-version"
+	rpmversion "github.com/knqyf263/go-rpm-version"
+	"golang.org/x/xerrors"
+)
+
+// Comparer compares two versions of the packages of an ecosystem, returning -1, 0 or 1
+type Comparer interface {
+	Compare(a, b string) (int, error)
+}
+
+var (
+	RPM    Comparer = rpmComparer{}
+	Dpkg   Comparer = dpkgComparer{}
+	APK    Comparer = apkComparer{}
+	Semver Comparer = semverComparer{}
+
+	// Comparers is keyed by the version scheme, e.g. rpm for Red Hat and Amazon Linux
+	Comparers = map[string]Comparer{
+		"rpm":    RPM,
+		"dpkg":   Dpkg,
+		"apk":    APK,
+		"semver": Semver,
+	}
+)
+
+// Validate returns the error of a version which comparer can't compare, e.g. a fixed version at build time
+func Validate(comparer Comparer, v string) error {
+	_, err := comparer.Compare(v, v)
+	return err
+}
+
+type rpmComparer struct{}
+
+// Compare follows rpmvercmp, which accepts any version
+func (rpmComparer) Compare(a, b string) (int, error) {
+	return rpmversion.NewVersion(a).Compare(rpmversion.NewVersion(b)), nil
+}
+
+type dpkgComparer struct{}
+
+func (dpkgComparer) Compare(a, b string) (int, error) {
+	va, err := debversion.NewVersion(a)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid dpkg version %s: %w", a, err)
+	}
+	vb, err := debversion.NewVersion(b)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid dpkg version %s: %w", b, err)
+	}
+	return va.Compare(vb), nil
+}
+
+type semverComparer struct{}
+
+func (semverComparer) Compare(a, b string) (int, error) {
+	va, err := goversion.NewVersion(a)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid version %s: %w", a, err)
+	}
+	vb, err := goversion.NewVersion(b)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid version %s: %w", b, err)
+	}
+	return va.Compare(vb), nil
+}
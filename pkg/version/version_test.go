@@ -0,0 +1,73 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComparers(t *testing.T) {
+	tests := []struct {
+		scheme  string
+		a       string
+		b       string
+		want    int
+		wantErr bool
+	}{
+		{scheme: "rpm", a: "1:7.61.1-11.amzn2.0.2", b: "1:7.61.1-12.amzn2.0.1", want: -1},
+		{scheme: "rpm", a: "1.0.10", b: "1.0.9", want: 1},
+		{scheme: "dpkg", a: "1.1.0l-1~deb9u1", b: "1.1.0l-1", want: -1},
+		{scheme: "dpkg", a: "2:1.0", b: "1:9.9", want: 1},
+		{scheme: "dpkg", a: "1.0-1", b: "1.0-1", want: 0},
+		{scheme: "dpkg", a: "a:1.0", b: "1.0", wantErr: true},
+		{scheme: "apk", a: "1.1.1d-r0", b: "1.1.1d-r2", want: -1},
+		{scheme: "apk", a: "1.1.1e-r0", b: "1.1.1d-r2", want: 1},
+		{scheme: "apk", a: "2.0_rc1-r0", b: "2.0-r0", want: -1},
+		{scheme: "apk", a: "2.0_p1-r0", b: "2.0-r5", want: 1},
+		{scheme: "apk", a: "1.2", b: "1.2.0", want: -1},
+		{scheme: "apk", a: "1.10.0-r0", b: "1.9.9-r0", want: 1},
+		{scheme: "apk", a: "1.0_foo", b: "1.0", wantErr: true},
+		{scheme: "semver", a: "1.2.3", b: "1.10.0", want: -1},
+		{scheme: "semver", a: "2.0.0-beta.1", b: "2.0.0", want: -1},
+		{scheme: "semver", a: "v1.0.0", b: "1.0.0", want: 0},
+		{scheme: "semver", a: "invalid", b: "1.0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme+" "+tt.a+" "+tt.b, func(t *testing.T) {
+			got, err := Comparers[tt.scheme].Compare(tt.a, tt.b)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		comparer Comparer
+		version  string
+		wantErr  bool
+	}{
+		{name: "rpm accepts any version", comparer: RPM, version: "not a version"},
+		{name: "dpkg", comparer: Dpkg, version: "1:2.3-4"},
+		{name: "dpkg epoch", comparer: Dpkg, version: "a:1.0", wantErr: true},
+		{name: "apk", comparer: APK, version: "1.1.1d-r2"},
+		{name: "apk suffix", comparer: APK, version: "1.0_foo-r0", wantErr: true},
+		{name: "semver", comparer: Semver, version: "1.0.0"},
+		{name: "semver invalid", comparer: Semver, version: "invalid", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.comparer, tt.version)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -1,19 +1,20 @@
 package alpine
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"path/filepath"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
 	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
@@ -21,48 +22,97 @@ const (
 	alpineDir = "alpine"
 )
 
-var (
-	platformFormat = "alpine %s"
-)
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
 
 type VulnSrc struct {
-	dbc db.Operations
+	dbc    operations
+	logger *slog.Logger
+}
+
+var _ registry.AdvisoryGetter = VulnSrc{}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vuln-list", Incremental: true})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.Alpine},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
 	}
 }
 
-func (vs VulnSrc) Update(dir string) error {
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.Alpine
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
 	rootDir := filepath.Join(dir, "vuln-list", alpineDir)
 	var cves []AlpineCVE
-	err := utils.FileWalk(rootDir, func(r io.Reader, path string) error {
+	err := utils.FileWalk(ctx, rootDir, func(r io.Reader, path string) error {
 		var cve AlpineCVE
-		if err := json.NewDecoder(r).Decode(&cve); err != nil {
-			return xerrors.Errorf("failed to decode Alpine JSON: %w", err)
+		pr := utils.NewProvenanceReader(r, dir, path)
+		if err := json.NewDecoder(pr).Decode(&cve); err != nil {
+			return &types.DecodeError{Source: vulnerability.Alpine, Path: path, Err: err}
 		}
+		provenance, err := pr.Provenance()
+		if err != nil {
+			return xerrors.Errorf("failed to read %s: %w", path, err)
+		}
+		cve.Provenance = provenance
 		cves = append(cves, cve)
+		if db.BatchFull(len(cves)) {
+			if err = vs.save(ctx, cves); err != nil {
+				return xerrors.Errorf("error in Alpine save: %w", err)
+			}
+			cves = nil
+		}
 		return nil
 	})
 	if err != nil {
 		return xerrors.Errorf("error in Alpine walk: %w", err)
 	}
 
-	if err = vs.save(cves); err != nil {
+	if err = vs.save(ctx, cves); err != nil {
 		return xerrors.Errorf("error in Alpine save: %w", err)
 	}
 
 	return nil
 }
 
-func (vs VulnSrc) save(cves []AlpineCVE) error {
-	log.Println("Saving Alpine DB")
+func (vs VulnSrc) save(ctx context.Context, cves []AlpineCVE) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.Alpine)
 
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
 		for _, cve := range cves {
-			platformName := fmt.Sprintf(platformFormat, cve.Release)
+			platformName := db.Alpine.Source(cve.Release)
 			pkgName := cve.Package
 			advisory := types.Advisory{
 				FixedVersion: cve.FixedVersion,
@@ -70,6 +120,9 @@ func (vs VulnSrc) save(cves []AlpineCVE) error {
 			if err := vs.dbc.PutAdvisory(tx, platformName, pkgName, cve.VulnerabilityID, advisory); err != nil {
 				return xerrors.Errorf("failed to save alpine advisory: %w", err)
 			}
+			if err := vs.dbc.PutProvenance(tx, platformName, pkgName, cve.VulnerabilityID, cve.Provenance); err != nil {
+				return xerrors.Errorf("failed to save alpine provenance: %w", err)
+			}
 
 			vuln := types.VulnerabilityDetail{
 				Title:       cve.Subject,
@@ -92,11 +145,14 @@ func (vs VulnSrc) save(cves []AlpineCVE) error {
 	return nil
 }
 
-func (vs VulnSrc) Get(release string, pkgName string) ([]types.Advisory, error) {
-	bucket := fmt.Sprintf(platformFormat, release)
+func (vs VulnSrc) Get(ctx context.Context, release string, pkgName string, opts ...types.GetOption) ([]types.Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
+	}
+	bucket := db.Alpine.Source(release)
 	advisories, err := vs.dbc.GetAdvisories(bucket, pkgName)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get Alpine advisories: %w", err)
 	}
-	return advisories, nil
+	return types.AdvisoriesByFix(advisories, types.NewGetOptions(opts...).Fix), nil
 }
@@ -1,5 +1,7 @@
 package alpine
 
+import "github.com/aquasecurity/trivy-db/pkg/types"
+
 type AlpineCVE struct {
 	VulnerabilityID string
 	Release         string
@@ -8,4 +10,6 @@ type AlpineCVE struct {
 	FixedVersion    string
 	Subject         string
 	Description     string
+
+	Provenance types.Provenance `json:"-"`
 }
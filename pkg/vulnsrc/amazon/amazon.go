@@ -1,27 +1,28 @@
 package amazon
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"path/filepath"
 	"strings"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
 	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 	"github.com/aquasecurity/vuln-list-update/amazon"
 )
 
 const (
-	amazonDir      = "amazon"
-	platformFormat = "amazon linux %s"
+	amazonDir = "amazon"
 )
 
 var (
@@ -29,31 +30,104 @@ var (
 	fileWalker     = utils.FileWalk // TODO: Remove once utils.go exposes an interface
 )
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc      db.Operations
+	dbc      operations
+	logger   *slog.Logger
+	releases []string // nil for targetVersions
+	cacheDir string
 	alasList []alas
 }
 
+var _ registry.AdvisoryGetter = VulnSrc{}
+
 type alas struct {
-	Version string
+	Version    string
+	Provenance types.Provenance
 	amazon.ALAS
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vuln-list", Incremental: true})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.Amazon},
+	}
+	for _, opt := range opts {
+		opt(&vs)
 	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
+	}
+}
+
+// WithReleases makes the source keep the advisories of releases, e.g. 2022, rather than of 1 and 2
+func WithReleases(releases ...string) Option {
+	return func(vs *VulnSrc) {
+		vs.releases = releases
+	}
+}
+
+// supported returns whether the source keeps the advisories of release
+func (vs VulnSrc) supported(release string) bool {
+	if vs.releases == nil {
+		return utils.StringInSlice(release, targetVersions)
+	}
+	return utils.StringInSlice(release, vs.releases)
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.Amazon
 }
 
-func (vs VulnSrc) Update(dir string) error {
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
 	rootDir := filepath.Join(dir, "vuln-list", amazonDir)
 
-	err := fileWalker(rootDir, vs.walkFunc)
+	vs.cacheDir = dir
+	err := fileWalker(ctx, rootDir, func(r io.Reader, path string) error {
+		if err := vs.walkFunc(r, path); err != nil {
+			return err
+		}
+		if !db.BatchFull(len(vs.alasList)) {
+			return nil
+		}
+		// in low memory mode, what was read is committed instead of buffered until the end of the walk
+		if err := vs.save(ctx); err != nil {
+			return xerrors.Errorf("error in amazon save: %w", err)
+		}
+		vs.alasList = nil
+		return nil
+	})
 	if err != nil {
 		return xerrors.Errorf("error in amazon walk: %w", err)
 	}
 
-	if err = vs.save(); err != nil {
+	if err = vs.save(ctx); err != nil {
 		return xerrors.Errorf("error in amazon save: %w", err)
 	}
 
@@ -66,26 +140,32 @@ func (vs *VulnSrc) walkFunc(r io.Reader, path string) error {
 		return nil
 	}
 	version := paths[len(paths)-2]
-	if !utils.StringInSlice(version, targetVersions) {
-		log.Printf("unsupported amazon version: %s\n", version)
+	if !vs.supported(version) {
+		log.OrDefault(vs.logger).Warn("Unsupported version", "source", vulnerability.Amazon, "version", version)
 		return nil
 	}
 
 	var vuln amazon.ALAS
-	if err := json.NewDecoder(r).Decode(&vuln); err != nil {
-		return xerrors.Errorf("failed to decode amazon JSON: %w", err)
+	pr := utils.NewProvenanceReader(r, vs.cacheDir, path)
+	if err := json.NewDecoder(pr).Decode(&vuln); err != nil {
+		return &types.DecodeError{Source: vulnerability.Amazon, Path: path, Err: err}
+	}
+	provenance, err := pr.Provenance()
+	if err != nil {
+		return xerrors.Errorf("failed to read %s: %w", path, err)
 	}
 
 	vs.alasList = append(vs.alasList, alas{
-		Version: version,
-		ALAS:    vuln,
+		Version:    version,
+		Provenance: provenance,
+		ALAS:       vuln,
 	})
 	return nil
 }
 
-func (vs VulnSrc) save() error {
-	log.Println("Saving amazon DB")
-	err := vs.dbc.BatchUpdate(vs.commit())
+func (vs VulnSrc) save(ctx context.Context) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.Amazon)
+	err := vs.dbc.BatchUpdate(ctx, vs.commit())
 	if err != nil {
 		return xerrors.Errorf("error in batch update: %w", err)
 	}
@@ -93,21 +173,27 @@ func (vs VulnSrc) save() error {
 }
 
 // TODO: Cleanup the double layer of nested closures
-func (vs VulnSrc) commit() func(tx *bolt.Tx) error {
+func (vs VulnSrc) commit() func(tx db.Tx) error {
 	return vs.commitFunc
 }
 
-func (vs VulnSrc) commitFunc(tx *bolt.Tx) error {
+func (vs VulnSrc) commitFunc(tx db.Tx) error {
 	for _, alas := range vs.alasList {
+		pkgNames, fixes := archFixes(alas.Packages)
 		for _, cveID := range alas.CveIDs {
-			for _, pkg := range alas.Packages {
-				platformName := fmt.Sprintf(platformFormat, alas.Version)
-				advisory := types.Advisory{
-					FixedVersion: constructVersion(pkg.Epoch, pkg.Version, pkg.Release),
+			for _, pkgName := range pkgNames {
+				platformName := db.AmazonLinux.Source(alas.Version)
+				advisory := utils.ArchAdvisory(fixes[pkgName])
+				advisory.Severity = severityFromPriority(alas.Severity)
+				if alas.ID != "" {
+					advisory.VendorIDs = []string{alas.ID}
 				}
-				if err := vs.dbc.PutAdvisory(tx, platformName, pkg.Name, cveID, advisory); err != nil {
+				if err := vs.dbc.PutAdvisory(tx, platformName, pkgName, cveID, advisory); err != nil {
 					return xerrors.Errorf("failed to save amazon advisory: %w", err)
 				}
+				if err := vs.dbc.PutProvenance(tx, platformName, pkgName, cveID, alas.Provenance); err != nil {
+					return xerrors.Errorf("failed to save amazon provenance: %w", err)
+				}
 
 				var references []string
 				for _, ref := range alas.References {
@@ -134,14 +220,57 @@ func (vs VulnSrc) commitFunc(tx *bolt.Tx) error {
 	return nil
 }
 
+// archFixes groups the packages of an ALAS, listed once per architecture, by name. A noarch package is fixed
+// on all the architectures, and a source package only counts for the names without binary packages.
+func archFixes(pkgs []amazon.Package) ([]string, map[string][]utils.ArchFix) {
+	var names []string
+	fixes := map[string][]utils.ArchFix{}
+	srcFixes := map[string][]utils.ArchFix{}
+	for _, pkg := range pkgs {
+		if _, ok := fixes[pkg.Name]; !ok {
+			if _, ok = srcFixes[pkg.Name]; !ok {
+				names = append(names, pkg.Name)
+			}
+		}
+		fix := utils.ArchFix{Arch: pkg.Arch, FixedVersion: constructVersion(pkg.Epoch, pkg.Version, pkg.Release)}
+		switch pkg.Arch {
+		case "src":
+			fix.Arch = ""
+			srcFixes[pkg.Name] = append(srcFixes[pkg.Name], fix)
+			continue
+		case "noarch":
+			fix.Arch = ""
+		}
+		fixes[pkg.Name] = append(fixes[pkg.Name], fix)
+	}
+	for name, fix := range srcFixes {
+		if _, ok := fixes[name]; !ok {
+			fixes[name] = fix
+		}
+	}
+	return names, fixes
+}
+
 // Get returns a security advisory
-func (vs VulnSrc) Get(version string, pkgName string) ([]types.Advisory, error) {
-	bucket := fmt.Sprintf(platformFormat, version)
+func (vs VulnSrc) Get(ctx context.Context, version string, pkgName string, opts ...types.GetOption) ([]types.Advisory, error) {
+	return vs.GetForArch(ctx, version, pkgName, "", opts...)
+}
+
+// GetForArch returns the advisories of a package affecting an architecture, e.g. aarch64, with its fixed
+// version, all of them if arch is empty
+func (vs VulnSrc) GetForArch(ctx context.Context, version, pkgName, arch string, opts ...types.GetOption) ([]types.Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
+	}
+	bucket := db.AmazonLinux.Source(version)
+	if !vs.supported(version) {
+		return nil, xerrors.Errorf("%s: %w", bucket, types.ErrUnsupportedRelease)
+	}
 	advisories, err := vs.dbc.GetAdvisories(bucket, pkgName)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get Amazon advisories: %w", err)
 	}
-	return advisories, nil
+	return types.AdvisoriesByFix(types.AdvisoriesForArch(advisories, arch), types.NewGetOptions(opts...).Fix), nil
 }
 
 func severityFromPriority(priority string) types.Severity {
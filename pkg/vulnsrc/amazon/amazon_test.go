@@ -1,72 +1,124 @@
 package amazon
 
 import (
+	"context"
 	"errors"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
 
 	"golang.org/x/xerrors"
 
-	bolt "github.com/etcd-io/bbolt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/storage/memdb"
 	"github.com/aquasecurity/trivy-db/pkg/types"
-	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnsrctest"
 	"github.com/aquasecurity/vuln-list-update/amazon"
 )
 
-func TestMain(m *testing.M) {
-	utils.Quiet = true
-	os.Exit(m.Run())
-}
-
 func TestVulnSrc_Update(t *testing.T) {
 	testCases := []struct {
-		name           string
-		cacheDir       string
-		batchUpdateErr error
-		expectedError  error
-		expectedVulns  []types.Advisory
+		name               string
+		cacheDir           string
+		expectedError      error
+		expectedAdvisory   map[string][]types.Advisory // platform/package => advisories
+		expectedVulnDetail map[string]types.VulnerabilityDetail
+		expectedProvenance types.Provenance // of curl in amazon linux 2
 	}{
 		{
-			name:          "happy path",
-			cacheDir:      "testdata",
-			expectedError: nil,
+			name:     "happy path",
+			cacheDir: "testdata",
+			expectedAdvisory: map[string][]types.Advisory{
+				"amazon linux 2/curl": {
+					{VulnerabilityID: "CVE-2019-5436", FixedVersion: "7.61.1-11.amzn2.0.2", Severity: types.SeverityMedium,
+						VendorIDs: []string{"ALAS2-2019-1234"}, Arches: []string{"aarch64", "x86_64"}},
+				},
+				"amazon linux 2/libcurl": {
+					{VulnerabilityID: "CVE-2019-5436", FixedVersion: "7.61.1-11.amzn2.0.2", Severity: types.SeverityMedium,
+						VendorIDs: []string{"ALAS2-2019-1234"}, Arches: []string{"x86_64"}},
+				},
+				"amazon linux 1/curl": nil,
+			},
+			expectedVulnDetail: map[string]types.VulnerabilityDetail{
+				"CVE-2019-5436": {
+					Severity:    types.SeverityMedium,
+					References:  []string{"https://cve.mitre.org/cgi-bin/cvename.cgi?name=CVE-2019-5436"},
+					Description: "Package updates are available for Amazon Linux 2 that fix the following vulnerabilities:\nCVE-2019-5436:\n\tA heap buffer overflow in the TFTP receiving code\n",
+				},
+			},
+			expectedProvenance: types.Provenance{
+				Path:   "vuln-list/amazon/2/ALAS2-2019-1234.json",
+				SHA256: "e044f263d1bfabbcfc35ce119e003b86dde3b0fec61aeb7b07d38b244d4b24b9",
+			},
 		},
 		{
 			name:          "cache dir doesnt exist",
 			cacheDir:      "badpathdoesnotexist",
 			expectedError: errors.New("error in amazon walk: error in file walk: lstat badpathdoesnotexist/vuln-list/amazon: no such file or directory"),
 		},
-		{
-			name:           "unable to save amazon defintions",
-			cacheDir:       "testdata",
-			batchUpdateErr: errors.New("unable to batch update"),
-			expectedError:  errors.New("error in amazon save: error in batch update: unable to batch update"),
-		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("BatchUpdate", mock.Anything).Return(tc.batchUpdateErr)
-			ac := VulnSrc{dbc: mockDBConfig}
+			d, err := ioutil.TempDir("", "TestVulnSrc_Update_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+			defer memdb.Drop(db.Path(d))
+			assert.NoError(t, db.InitWithDriver(memdb.DriverName, d))
+			defer db.Close()
 
-			err := ac.Update(tc.cacheDir)
+			ac := NewVulnSrc(WithLogger(log.Discard()))
+			err = ac.Update(context.Background(), tc.cacheDir)
 			switch {
 			case tc.expectedError != nil:
 				assert.EqualError(t, err, tc.expectedError.Error(), tc.name)
+				return
 			default:
 				assert.NoError(t, err, tc.name)
 			}
+
+			dbc := db.Config{}
+			for key, want := range tc.expectedAdvisory {
+				i := strings.Index(key, "/")
+				got, err := dbc.GetAdvisories(key[:i], key[i+1:])
+				assert.NoError(t, err, key)
+				assert.Equal(t, want, got, key)
+			}
+			for cveID, want := range tc.expectedVulnDetail {
+				got, err := dbc.GetVulnerabilityDetails(cveID)
+				assert.NoError(t, err, cveID)
+				assert.Equal(t, map[string]types.VulnerabilityDetail{vulnerability.Amazon: want}, got, cveID)
+
+				severity, err := dbc.GetSeverity(cveID)
+				assert.NoError(t, err, cveID)
+				assert.Equal(t, types.SeverityUnknown, severity, cveID)
+			}
+
+			provenance, err := dbc.GetProvenance("amazon linux 2", "curl", "CVE-2019-5436")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedProvenance, provenance)
 		})
 	}
 }
 
+// the store can't fail a batch, so the error is mocked
+func TestVulnSrc_UpdateBatchError(t *testing.T) {
+	mockBatchUpdater := new(db.MockBatchUpdater)
+	mockBatchUpdater.On("BatchUpdate", mock.Anything, mock.Anything).Return(errors.New("unable to batch update"))
+	ac := VulnSrc{dbc: vulnsrctest.MockOperations{MockBatchUpdater: mockBatchUpdater}, logger: log.Discard()}
+
+	err := ac.Update(context.Background(), "testdata")
+	assert.EqualError(t, err, "error in amazon save: error in batch update: unable to batch update")
+}
+
 func TestVulnSrc_Get(t *testing.T) {
 	type getAdvisoriesInput struct {
 		version string
@@ -85,6 +137,8 @@ func TestVulnSrc_Get(t *testing.T) {
 		name          string
 		version       string
 		pkgName       string
+		arch          string
+		opts          []types.GetOption
 		getAdvisories getAdvisories
 		expectedError error
 		expectedVulns []types.Advisory
@@ -123,7 +177,74 @@ func TestVulnSrc_Get(t *testing.T) {
 			expectedVulns: []types.Advisory{},
 		},
 		{
-			name: "amazon GetAdvisories return an error",
+			name:    "advisories of an architecture",
+			version: "2",
+			pkgName: "curl",
+			arch:    "aarch64",
+			getAdvisories: getAdvisories{
+				input: getAdvisoriesInput{
+					version: "amazon linux 2",
+					pkgName: "curl",
+				},
+				output: getAdvisoriesOutput{
+					advisories: []types.Advisory{
+						{VulnerabilityID: "CVE-2019-0001", FixedVersion: "7.61.1-11.amzn2.0.2", Arches: []string{"aarch64", "x86_64"},
+							ArchFixedVersions: map[string]string{"x86_64": "0.1.1"}},
+						{VulnerabilityID: "CVE-2019-0002", FixedVersion: "0.1.2", Arches: []string{"x86_64"}},
+						{VulnerabilityID: "CVE-2019-0003", FixedVersion: "0.1.3"},
+					},
+				},
+			},
+			expectedVulns: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "7.61.1-11.amzn2.0.2", Arches: []string{"aarch64"}},
+				{VulnerabilityID: "CVE-2019-0003", FixedVersion: "0.1.3", Arches: []string{"aarch64"}},
+			},
+		},
+		{
+			name:    "fixed advisories of an architecture",
+			version: "2",
+			pkgName: "curl",
+			arch:    "x86_64",
+			opts:    []types.GetOption{types.WithFixFilter(types.FixedOnly)},
+			getAdvisories: getAdvisories{
+				input: getAdvisoriesInput{
+					version: "amazon linux 2",
+					pkgName: "curl",
+				},
+				output: getAdvisoriesOutput{
+					advisories: []types.Advisory{
+						{VulnerabilityID: "CVE-2019-0001", Arches: []string{"aarch64", "x86_64"},
+							ArchFixedVersions: map[string]string{"x86_64": "0.1.1"}},
+						{VulnerabilityID: "CVE-2019-0002", Arches: []string{"x86_64"}},
+					},
+				},
+			},
+			expectedVulns: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "0.1.1", Arches: []string{"x86_64"}},
+			},
+		},
+		{
+			name:    "unfixed advisories",
+			version: "2",
+			pkgName: "curl",
+			opts:    []types.GetOption{types.WithFixFilter(types.UnfixedOnly)},
+			getAdvisories: getAdvisories{
+				input: getAdvisoriesInput{
+					version: "amazon linux 2",
+					pkgName: "curl",
+				},
+				output: getAdvisoriesOutput{
+					advisories: []types.Advisory{
+						{VulnerabilityID: "CVE-2019-0001", FixedVersion: "0.1.2"},
+						{VulnerabilityID: "CVE-2019-0002"},
+					},
+				},
+			},
+			expectedVulns: []types.Advisory{{VulnerabilityID: "CVE-2019-0002"}},
+		},
+		{
+			name:    "amazon GetAdvisories return an error",
+			version: "1",
 			getAdvisories: getAdvisories{
 				input: getAdvisoriesInput{
 					version: mock.Anything,
@@ -137,18 +258,28 @@ func TestVulnSrc_Get(t *testing.T) {
 			expectedError: errors.New("failed to get Amazon advisories: unable to get advisories"),
 			expectedVulns: nil,
 		},
+		{
+			name:          "unsupported release",
+			version:       "2022",
+			pkgName:       "curl",
+			expectedError: errors.New("amazon linux 2022: unsupported release"),
+			expectedVulns: nil,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("GetAdvisories",
+			mockAdvisoryStore := new(db.MockAdvisoryStore)
+			mockAdvisoryStore.On("GetAdvisories",
 				tc.getAdvisories.input.version, tc.getAdvisories.input.pkgName).Return(
 				tc.getAdvisories.output.advisories, tc.getAdvisories.output.err,
 			)
 
-			ac := VulnSrc{dbc: mockDBConfig}
-			vuls, err := ac.Get(tc.version, tc.pkgName)
+			ac := VulnSrc{dbc: vulnsrctest.MockOperations{MockAdvisoryStore: mockAdvisoryStore}, logger: log.Discard()}
+			vuls, err := ac.Get(context.Background(), tc.version, tc.pkgName, tc.opts...)
+			if tc.arch != "" {
+				vuls, err = ac.GetForArch(context.Background(), tc.version, tc.pkgName, tc.arch, tc.opts...)
+			}
 
 			switch {
 			case tc.expectedError != nil:
@@ -161,6 +292,22 @@ func TestVulnSrc_Get(t *testing.T) {
 	}
 }
 
+func TestNewVulnSrc(t *testing.T) {
+	mockAdvisoryStore := new(db.MockAdvisoryStore)
+	mockAdvisoryStore.On("GetAdvisories", "amazon linux 2022", "curl").Return(
+		[]types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: "0.1.2"}}, nil)
+
+	vs := NewVulnSrc(WithDB(vulnsrctest.MockOperations{MockAdvisoryStore: mockAdvisoryStore}), WithLogger(log.Discard()), WithReleases("2022"))
+	advisories, err := vs.Get(context.Background(), "2022", "curl")
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: "0.1.2"}}, advisories)
+
+	// the releases replace the default ones
+	_, err = vs.Get(context.Background(), "2", "curl")
+	assert.True(t, xerrors.Is(err, types.ErrUnsupportedRelease), err)
+	mockAdvisoryStore.AssertExpectations(t)
+}
+
 func TestSeverityFromPriority(t *testing.T) {
 	testCases := map[string]types.Severity{
 		"low":       types.SeverityLow,
@@ -249,6 +396,10 @@ func TestVulnSrc_WalkFunc(t *testing.T) {
 			expectedALASList: []alas{
 				{
 					Version: "2",
+					Provenance: types.Provenance{
+						Path:   "1/2/1",
+						SHA256: "bcd480aa50979e549496657373abcba5c8bd9107008298e328d3ae8cc9b2ff6d",
+					},
 					ALAS: amazon.ALAS{
 						ID:       "123",
 						Severity: "high",
@@ -262,7 +413,7 @@ func TestVulnSrc_WalkFunc(t *testing.T) {
 			ioReader:         strings.NewReader(`invalidjson`),
 			inputPath:        "1/2/1",
 			expectedALASList: []alas(nil),
-			expectedError:    errors.New("failed to decode amazon JSON: invalid character 'i' looking for beginning of value"),
+			expectedError:    errors.New("failed to decode 1/2/1 of amazon: invalid character 'i' looking for beginning of value"),
 		},
 		{
 			name:          "unsupported amazon version",
@@ -279,7 +430,7 @@ func TestVulnSrc_WalkFunc(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			ac := VulnSrc{}
+			ac := VulnSrc{logger: log.Discard()}
 
 			err := ac.walkFunc(tc.ioReader, tc.inputPath)
 			switch {
@@ -394,19 +545,23 @@ func TestVulnSrc_CommitFunc(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("PutAdvisory",
+			mockAdvisoryStore := new(db.MockAdvisoryStore)
+			mockVulnerabilityStore := new(db.MockVulnerabilityStore)
+			mockProvenanceStore := new(db.MockProvenanceStore)
+			mockAdvisoryStore.On("PutAdvisory",
 				mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
 				tc.putAdvisoryErr)
-			mockDBConfig.On("PutVulnerabilityDetail",
+			mockProvenanceStore.On("PutProvenance",
+				mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			mockVulnerabilityStore.On("PutVulnerabilityDetail",
 				mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
 				tc.putVulnerabilityDetailErr)
-			mockDBConfig.On("PutSeverity",
+			mockVulnerabilityStore.On("PutSeverity",
 				mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-			vs := VulnSrc{dbc: mockDBConfig, alasList: tc.alasList}
+			vs := VulnSrc{dbc: vulnsrctest.MockOperations{MockAdvisoryStore: mockAdvisoryStore, MockVulnerabilityStore: mockVulnerabilityStore, MockProvenanceStore: mockProvenanceStore}, logger: log.Discard(), alasList: tc.alasList}
 
-			err := vs.commitFunc(&bolt.Tx{WriteFlag: 0})
+			err := vs.commitFunc(&storage.MockTx{})
 			switch {
 			case tc.expectedError != nil:
 				assert.EqualError(t, err, tc.expectedError.Error(), tc.name)
@@ -0,0 +1,208 @@
+package bdu
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+)
+
+// https://bdu.fstec.ru/vul
+// The feed is not mirrored by vuln-list and has to be downloaded manually,
+// e.g. unzip vulxml.zip and place export.xml under <cache-dir>/bdu/
+
+const (
+	bduDir   = "bdu"
+	bduFile  = "export.xml"
+	cveIDKey = "CVE"
+)
+
+var (
+	// FSTEC severity levels, e.g. "Высокий уровень опасности (базовая оценка CVSS 2.0 составляет 7,5)"
+	severityPrefixes = []struct {
+		prefix   string
+		severity types.Severity
+	}{
+		{prefix: "Критический", severity: types.SeverityCritical},
+		{prefix: "Высокий", severity: types.SeverityHigh},
+		{prefix: "Средний", severity: types.SeverityMedium},
+		{prefix: "Низкий", severity: types.SeverityLow},
+	}
+)
+
+type operations interface {
+	db.BatchUpdater
+	db.VulnerabilityStore
+}
+
+type VulnSrc struct {
+	dbc    operations
+	logger *slog.Logger
+}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Optional: true})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
+	}
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.BDU
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
+	f, err := os.Open(filepath.Join(dir, bduDir, bduFile))
+	if err != nil {
+		return xerrors.Errorf("failed to open BDU feed: %w", err)
+	}
+	defer f.Close()
+
+	var vulns []Vulnerability
+	err = parse(f, func(vuln Vulnerability) error {
+		vulns = append(vulns, vuln)
+		if !db.BatchFull(len(vulns)) {
+			return nil
+		}
+		if err := vs.save(ctx, vulns); err != nil {
+			return xerrors.Errorf("error in BDU save: %w", err)
+		}
+		vulns = nil
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("error in BDU parse: %w", err)
+	}
+
+	if err = vs.save(ctx, vulns); err != nil {
+		return xerrors.Errorf("error in BDU save: %w", err)
+	}
+	return nil
+}
+
+// parse decodes <vul> elements one at a time, as the whole export is a few hundred MB, and calls fn with each
+func parse(r io.Reader, fn func(Vulnerability) error) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return &types.DecodeError{Source: vulnerability.BDU, Path: filepath.Join(bduDir, bduFile), Err: err}
+		}
+
+		se, ok := token.(xml.StartElement)
+		if !ok || se.Name.Local != "vul" {
+			continue
+		}
+
+		var vuln Vulnerability
+		if err = decoder.DecodeElement(&vuln, &se); err != nil {
+			return &types.DecodeError{Source: vulnerability.BDU, Path: filepath.Join(bduDir, bduFile), Err: err}
+		}
+		if err = fn(vuln); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (vs VulnSrc) save(ctx context.Context, vulns []Vulnerability) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.BDU)
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
+		return vs.commit(tx, vulns)
+	})
+	if err != nil {
+		return xerrors.Errorf("error in batch update: %w", err)
+	}
+	return nil
+}
+
+func (vs VulnSrc) commit(tx db.Tx, vulns []Vulnerability) error {
+	for _, vuln := range vulns {
+		var references []string
+		for _, ref := range strings.Split(vuln.Sources, "\n") {
+			if ref = strings.TrimSpace(ref); ref != "" {
+				references = append(references, ref)
+			}
+		}
+
+		// BDU records are stored per CVE so that they are merged with the other sources
+		for _, identifier := range vuln.Identifiers {
+			if identifier.Type != cveIDKey {
+				continue
+			}
+			cveID := strings.TrimSpace(identifier.Value)
+			if cveID == "" {
+				continue
+			}
+
+			detail := types.VulnerabilityDetail{
+				ID:           vuln.Identifier,
+				CvssScore:    vuln.Cvss.Vector.Score,
+				CvssVector:   strings.TrimSpace(vuln.Cvss.Vector.Value),
+				CvssScoreV3:  vuln.Cvss3.Vector.Score,
+				CvssVectorV3: strings.TrimSpace(vuln.Cvss3.Vector.Value),
+				Severity:     severityFromLevel(vuln.Severity),
+				References:   references,
+				Title:        strings.TrimSpace(vuln.Name),
+				Description:  strings.TrimSpace(vuln.Description),
+			}
+			if err := vs.dbc.PutVulnerabilityDetail(tx, cveID, vulnerability.BDU, detail); err != nil {
+				return xerrors.Errorf("failed to save BDU vulnerability detail: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// severityFromLevel returns the highest level in the field, as it can list both CVSS 2.0 and CVSS 3.0 levels
+func severityFromLevel(level string) types.Severity {
+	severity := types.SeverityUnknown
+	for _, line := range strings.Split(level, "\n") {
+		line = strings.TrimSpace(line)
+		for _, p := range severityPrefixes {
+			if strings.HasPrefix(line, p.prefix) && p.severity.Rank() > severity.Rank() {
+				severity = p.severity
+			}
+		}
+	}
+	return severity
+}
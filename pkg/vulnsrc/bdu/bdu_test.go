@@ -0,0 +1,132 @@
+package bdu
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnsrctest"
+)
+
+func TestVulnSrc_Update(t *testing.T) {
+	testCases := []struct {
+		name             string
+		cacheDir         string
+		batchSize        int
+		batchUpdateErr   error
+		expectedErrorMsg string
+		expectedBatches  int
+	}{
+		{
+			name:            "happy path",
+			cacheDir:        filepath.Join("testdata", "happy"),
+			expectedBatches: 1,
+		},
+		{
+			name:      "low memory",
+			cacheDir:  filepath.Join("testdata", "happy"),
+			batchSize: 1,
+			// one per vulnerability and the rest, none
+			expectedBatches: 3,
+		},
+		{
+			name:             "feed is not downloaded",
+			cacheDir:         filepath.Join("testdata", "nowhere"),
+			expectedErrorMsg: "failed to open BDU feed",
+		},
+		{
+			name:             "broken XML",
+			cacheDir:         filepath.Join("testdata", "sad"),
+			expectedErrorMsg: "failed to decode bdu/export.xml of bdu",
+		},
+		{
+			name:             "BatchUpdate returns an error",
+			cacheDir:         filepath.Join("testdata", "happy"),
+			batchUpdateErr:   errors.New("batch update failed"),
+			expectedErrorMsg: "batch update failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockBatchUpdater := new(db.MockBatchUpdater)
+			mockBatchUpdater.On("BatchUpdate", mock.Anything, mock.Anything).Return(tc.batchUpdateErr)
+			vs := VulnSrc{dbc: vulnsrctest.MockOperations{MockBatchUpdater: mockBatchUpdater}, logger: log.Discard()}
+			db.SetBatchSize(tc.batchSize)
+			defer db.SetBatchSize(0)
+
+			err := vs.Update(context.Background(), tc.cacheDir)
+			switch {
+			case tc.expectedErrorMsg != "":
+				assert.Contains(t, err.Error(), tc.expectedErrorMsg, tc.name)
+			default:
+				assert.NoError(t, err, tc.name)
+				mockBatchUpdater.AssertNumberOfCalls(t, "BatchUpdate", tc.expectedBatches)
+			}
+		})
+	}
+}
+
+func TestVulnSrc_Commit(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "happy", "bdu", "export.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var vulns []Vulnerability
+	err = parse(f, func(vuln Vulnerability) error {
+		vulns = append(vulns, vuln)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := &storage.MockTx{}
+	mockVulnerabilityStore := new(db.MockVulnerabilityStore)
+	mockVulnerabilityStore.On("PutVulnerabilityDetail", tx, "CVE-2019-5482", vulnerability.BDU,
+		types.VulnerabilityDetail{
+			ID:           "BDU:2019-01234",
+			CvssScore:    6.8,
+			CvssVector:   "AV:N/AC:M/Au:N/C:P/I:P/A:P",
+			CvssScoreV3:  8.8,
+			CvssVectorV3: "AV:N/AC:L/PR:N/UI:R/S:U/C:H/I:H/A:H",
+			Severity:     types.SeverityHigh,
+			References: []string{
+				"https://curl.haxx.se/docs/CVE-2019-5482.html",
+				"https://nvd.nist.gov/vuln/detail/CVE-2019-5482",
+			},
+			Title:       "Уязвимость функции tftp_receive_packet() библиотеки libcurl",
+			Description: "Уязвимость функции tftp_receive_packet() библиотеки libcurl связана с переполнением буфера.",
+		}).Return(nil)
+
+	vs := VulnSrc{dbc: vulnsrctest.MockOperations{MockVulnerabilityStore: mockVulnerabilityStore}, logger: log.Discard()}
+	err = vs.commit(tx, vulns)
+	assert.NoError(t, err)
+	mockVulnerabilityStore.AssertExpectations(t)
+}
+
+func TestSeverityFromLevel(t *testing.T) {
+	testCases := []struct {
+		level    string
+		expected types.Severity
+	}{
+		{level: "Критический уровень опасности (базовая оценка CVSS 3.0 составляет 9,8)", expected: types.SeverityCritical},
+		{level: "Низкий уровень опасности\nСредний уровень опасности", expected: types.SeverityMedium},
+		{level: "", expected: types.SeverityUnknown},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, severityFromLevel(tc.level), tc.level)
+	}
+}
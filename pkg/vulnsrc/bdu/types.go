@@ -0,0 +1,29 @@
+package bdu
+
+type Vulnerability struct {
+	Identifier  string       `xml:"identifier"`
+	Name        string       `xml:"name"`
+	Description string       `xml:"description"`
+	Severity    string       `xml:"severity"`
+	Cvss        Cvss         `xml:"cvss"`
+	Cvss3       Cvss         `xml:"cvss3"`
+	Solution    string       `xml:"solution"`
+	Status      string       `xml:"vul_status"`
+	Sources     string       `xml:"sources"`
+	Identifiers []Identifier `xml:"identifiers>identifier"`
+}
+
+type Cvss struct {
+	Vector Vector `xml:"vector"`
+}
+
+type Vector struct {
+	Score float64 `xml:"score,attr"`
+	Value string  `xml:",chardata"`
+}
+
+type Identifier struct {
+	Type  string `xml:"type,attr"`
+	Link  string `xml:"link,attr"`
+	Value string `xml:",chardata"`
+}
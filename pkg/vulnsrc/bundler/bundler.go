@@ -1,18 +1,20 @@
 package bundler
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 	"gopkg.in/yaml.v2"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
@@ -48,29 +50,62 @@ type Related struct {
 	Url []string
 }
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc      operations
+	cacheDir string
+}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "ruby-advisory-db"})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.RubySec},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
 	}
 }
 
-func (vs VulnSrc) Update(dir string) error {
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.RubySec
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
+	vs.cacheDir = dir
 	repoPath := filepath.Join(dir, bundlerDir)
-	if err := vs.update(repoPath); err != nil {
+	if err := vs.update(ctx, repoPath); err != nil {
 		return xerrors.Errorf("failed to update bundler vulnerabilities: %w", err)
 	}
 	return nil
 }
 
-func (vs VulnSrc) update(repoPath string) error {
+func (vs VulnSrc) update(ctx context.Context, repoPath string) error {
 	root := filepath.Join(repoPath, "gems")
 
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
-		if err := vs.walk(tx, root); err != nil {
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
+		if err := vs.walk(ctx, tx, root); err != nil {
 			return xerrors.Errorf("failed to walk ruby advisories: %w", err)
 		}
 		return nil
@@ -81,7 +116,8 @@ func (vs VulnSrc) update(repoPath string) error {
 	return nil
 }
 
-func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
+func (vs VulnSrc) walk(ctx context.Context, tx db.Tx, root string) error {
+	progress := log.ProgressFrom(ctx)
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -93,11 +129,12 @@ func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
 		if err != nil {
 			return xerrors.Errorf("failed to read a file: %w", err)
 		}
+		progress.AddFiles(1)
 
 		advisory := RawAdvisory{}
 		err = yaml.Unmarshal(buf, &advisory)
 		if err != nil {
-			return xerrors.Errorf("failed to unmarshal YAML: %w", err)
+			return &types.DecodeError{Source: vulnerability.RubySec, Path: path, Err: err}
 		}
 
 		var vulnerabilityID string
@@ -120,6 +157,10 @@ func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
 		if err != nil {
 			return xerrors.Errorf("failed to save ruby advisory: %w", err)
 		}
+		provenance := utils.ContentProvenance(buf, vs.cacheDir, path)
+		if err = vs.dbc.PutProvenance(tx, vulnerability.RubySec, advisory.Gem, vulnerabilityID, provenance); err != nil {
+			return xerrors.Errorf("failed to save ruby provenance: %w", err)
+		}
 
 		// for displaying vulnerability detail
 		vuln := types.VulnerabilityDetail{
@@ -141,20 +182,22 @@ func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
 	})
 }
 
-func (vs VulnSrc) Get(pkgName string) ([]Advisory, error) {
-	advisories, err := vs.dbc.ForEachAdvisory(vulnerability.RubySec, pkgName)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to iterate ruby vulnerabilities: %w", err)
+func (vs VulnSrc) Get(ctx context.Context, pkgName string) ([]Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
 	}
-
 	var results []Advisory
-	for vulnID, a := range advisories {
+	err := vs.dbc.ForEachAdvisory(vulnerability.RubySec, pkgName, func(_ *types.DataSource, vulnID string, v []byte) error {
 		var advisory Advisory
-		if err = json.Unmarshal(a, &advisory); err != nil {
-			return nil, xerrors.Errorf("failed to unmarshal advisory JSON: %w", err)
+		if err := db.Unmarshal(v, &advisory); err != nil {
+			return xerrors.Errorf("failed to unmarshal advisory: %w", err)
 		}
 		advisory.VulnerabilityID = vulnID
 		results = append(results, advisory)
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to iterate ruby vulnerabilities: %w", err)
 	}
 	return results, nil
 }
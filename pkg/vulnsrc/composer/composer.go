@@ -1,18 +1,20 @@
 package composer
 
 import (
-	"encoding/json"
+	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 	"gopkg.in/yaml.v2"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
@@ -39,27 +41,60 @@ type Advisory struct {
 	Branches        map[string]Branch `json:",omitempty"`
 }
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc      operations
+	cacheDir string
+}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "php-security-advisories"})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.PhpSecurityAdvisories},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
 	}
 }
 
-func (vs VulnSrc) Update(dir string) (err error) {
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.PhpSecurityAdvisories
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) (err error) {
+	vs.cacheDir = dir
 	repoPath := filepath.Join(dir, composerDir)
-	if err := vs.update(repoPath); err != nil {
+	if err := vs.update(ctx, repoPath); err != nil {
 		return xerrors.Errorf("failed to update compose vulnerabilities: %w", err)
 	}
 	return nil
 }
 
-func (vs VulnSrc) update(repoPath string) error {
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
-		if err := vs.walk(tx, repoPath); err != nil {
+func (vs VulnSrc) update(ctx context.Context, repoPath string) error {
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
+		if err := vs.walk(ctx, tx, repoPath); err != nil {
 			return xerrors.Errorf("failed to walk compose advisories: %w", err)
 		}
 		return nil
@@ -69,7 +104,8 @@ func (vs VulnSrc) update(repoPath string) error {
 	}
 	return nil
 }
-func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
+func (vs VulnSrc) walk(ctx context.Context, tx db.Tx, root string) error {
+	progress := log.ProgressFrom(ctx)
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -81,11 +117,12 @@ func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
 		if err != nil {
 			return xerrors.Errorf("failed to read a file: %w", err)
 		}
+		progress.AddFiles(1)
 
 		advisory := RawAdvisory{}
 		err = yaml.Unmarshal(buf, &advisory)
 		if err != nil {
-			return xerrors.Errorf("failed to unmarshal YAML: %w", err)
+			return &types.DecodeError{Source: vulnerability.PhpSecurityAdvisories, Path: path, Err: err}
 		}
 
 		// for detecting vulnerabilities
@@ -100,6 +137,10 @@ func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
 		if err != nil {
 			return xerrors.Errorf("failed to save php advisory: %w", err)
 		}
+		provenance := utils.ContentProvenance(buf, vs.cacheDir, path)
+		if err = vs.dbc.PutProvenance(tx, vulnerability.PhpSecurityAdvisories, advisory.Reference, vulnerabilityID, provenance); err != nil {
+			return xerrors.Errorf("failed to save php provenance: %w", err)
+		}
 
 		// for displaying vulnerability detail
 		vuln := types.VulnerabilityDetail{
@@ -118,20 +159,22 @@ func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
 	})
 }
 
-func (vs VulnSrc) Get(pkgName string) ([]Advisory, error) {
-	advisories, err := vs.dbc.ForEachAdvisory(vulnerability.PhpSecurityAdvisories, pkgName)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to iterate php vulnerabilities: %w", err)
+func (vs VulnSrc) Get(ctx context.Context, pkgName string) ([]Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
 	}
-
 	var results []Advisory
-	for vulnID, a := range advisories {
+	err := vs.dbc.ForEachAdvisory(vulnerability.PhpSecurityAdvisories, pkgName, func(_ *types.DataSource, vulnID string, v []byte) error {
 		var advisory Advisory
-		if err = json.Unmarshal(a, &advisory); err != nil {
-			return nil, xerrors.Errorf("failed to unmarshal advisory JSON: %w", err)
+		if err := db.Unmarshal(v, &advisory); err != nil {
+			return xerrors.Errorf("failed to unmarshal advisory: %w", err)
 		}
 		advisory.VulnerabilityID = vulnID
 		results = append(results, advisory)
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to iterate php vulnerabilities: %w", err)
 	}
 	return results, nil
 }
@@ -1,54 +1,101 @@
 package debianoval
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
 	"github.com/aquasecurity/trivy-db/pkg/utils"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/debian"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
 var (
 	debianDir = filepath.Join("oval", "debian")
-	// e.g. debian oval 8
-	platformFormat = "debian oval %s"
 )
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc    operations
+	logger *slog.Logger
+}
+
+var _ registry.AdvisoryGetter = VulnSrc{}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vuln-list"})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.DebianOVAL},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
 	}
 }
 
-func (vs VulnSrc) Update(dir string) error {
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
+	}
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.DebianOVAL
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
 	rootDir := filepath.Join(dir, "vuln-list", debianDir)
 
 	var cves []DebianOVAL
-	err := utils.FileWalk(rootDir, func(r io.Reader, path string) error {
+	err := utils.FileWalk(ctx, rootDir, func(r io.Reader, path string) error {
 		var cve DebianOVAL
-		if err := json.NewDecoder(r).Decode(&cve); err != nil {
-			return xerrors.Errorf("failed to decode Debian OVAL JSON: %w", err)
+		pr := utils.NewProvenanceReader(r, dir, path)
+		if err := json.NewDecoder(pr).Decode(&cve); err != nil {
+			return &types.DecodeError{Source: vulnerability.DebianOVAL, Path: path, Err: err}
+		}
+		provenance, err := pr.Provenance()
+		if err != nil {
+			return xerrors.Errorf("failed to read %s: %w", path, err)
 		}
+		cve.Provenance = provenance
 
 		dirs := strings.Split(path, string(os.PathSeparator))
 		if len(dirs) < 3 {
-			log.Printf("invalid path: %s\n", path)
+			log.OrDefault(vs.logger).Warn("Invalid path", "source", vulnerability.DebianOVAL, "path", path)
+			log.ProgressFrom(ctx).AddErrors(1)
 			return nil
 		}
 		cve.Release = dirs[len(dirs)-3]
@@ -59,7 +106,7 @@ func (vs VulnSrc) Update(dir string) error {
 		return xerrors.Errorf("error in Debian OVAL walk: %w", err)
 	}
 
-	if err = vs.save(cves); err != nil {
+	if err = vs.save(ctx, cves); err != nil {
 		return xerrors.Errorf("error in Debian OVAL save: %w", err)
 	}
 
@@ -94,9 +141,9 @@ func walkDebian(cri Criteria, pkgs []Package) []Package {
 	return pkgs
 }
 
-func (vs VulnSrc) save(cves []DebianOVAL) error {
-	log.Println("Saving Debian OVAL")
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
+func (vs VulnSrc) save(ctx context.Context, cves []DebianOVAL) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.DebianOVAL)
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
 		for _, cve := range cves {
 			affectedPkgs := walkDebian(cve.Criteria, []Package{})
 			for _, affectedPkg := range affectedPkgs {
@@ -105,7 +152,7 @@ func (vs VulnSrc) save(cves []DebianOVAL) error {
 				if !ok {
 					continue
 				}
-				platformName := fmt.Sprintf(platformFormat, majorVersion)
+				platformName := db.DebianOVAL.Source(majorVersion)
 				cveID := cve.Metadata.Title
 				advisory := types.Advisory{
 					FixedVersion: affectedPkg.FixedVersion,
@@ -113,6 +160,9 @@ func (vs VulnSrc) save(cves []DebianOVAL) error {
 				if err := vs.dbc.PutAdvisory(tx, platformName, affectedPkg.Name, cveID, advisory); err != nil {
 					return xerrors.Errorf("failed to save Debian OVAL advisory: %w", err)
 				}
+				if err := vs.dbc.PutProvenance(tx, platformName, affectedPkg.Name, cveID, cve.Provenance); err != nil {
+					return xerrors.Errorf("failed to save Debian OVAL provenance: %w", err)
+				}
 
 				var references []string
 				for _, ref := range cve.Metadata.References {
@@ -143,11 +193,14 @@ func (vs VulnSrc) save(cves []DebianOVAL) error {
 	return nil
 }
 
-func (vs VulnSrc) Get(release string, pkgName string) ([]types.Advisory, error) {
-	bucket := fmt.Sprintf(platformFormat, release)
+func (vs VulnSrc) Get(ctx context.Context, release string, pkgName string, opts ...types.GetOption) ([]types.Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
+	}
+	bucket := db.DebianOVAL.Source(release)
 	advisories, err := vs.dbc.GetAdvisories(bucket, pkgName)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get Alpine advisories: %w", err)
 	}
-	return advisories, nil
+	return types.AdvisoriesByFix(advisories, types.NewGetOptions(opts...).Fix), nil
 }
@@ -1,9 +1,13 @@
 package debianoval
 
+import "github.com/aquasecurity/trivy-db/pkg/types"
+
 type DebianOVAL struct {
 	Metadata Metadata
 	Criteria Criteria
 	Release  string
+
+	Provenance types.Provenance `json:"-"`
 }
 
 type Metadata struct {
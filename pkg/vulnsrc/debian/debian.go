@@ -1,19 +1,20 @@
 package debian
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"path/filepath"
 	"strings"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
 	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 )
 
@@ -22,8 +23,6 @@ const (
 )
 
 var (
-	// e.g. debian 8
-	platformFormat        = "debian %s"
 	DebianReleasesMapping = map[string]string{
 		// Code names
 		"squeeze": "6",
@@ -35,28 +34,81 @@ var (
 	}
 )
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc    operations
+	logger *slog.Logger
+}
+
+var _ registry.AdvisoryGetter = VulnSrc{}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vuln-list", Incremental: true})
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.Debian},
+	}
+	for _, opt := range opts {
+		opt(&vs)
 	}
+	return vs
 }
 
-func (vs VulnSrc) Update(dir string) error {
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
+	}
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.Debian
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
 	rootDir := filepath.Join(dir, "vuln-list", debianDir)
 	var cves []DebianCVE
-	err := utils.FileWalk(rootDir, func(r io.Reader, path string) error {
+	err := utils.FileWalk(ctx, rootDir, func(r io.Reader, path string) error {
 		var cve DebianCVE
-		if err := json.NewDecoder(r).Decode(&cve); err != nil {
-			return xerrors.Errorf("failed to decode Debian JSON: %w", err)
+		pr := utils.NewProvenanceReader(r, dir, path)
+		if err := json.NewDecoder(pr).Decode(&cve); err != nil {
+			return &types.DecodeError{Source: vulnerability.Debian, Path: path, Err: err}
+		}
+		provenance, err := pr.Provenance()
+		if err != nil {
+			return xerrors.Errorf("failed to read %s: %w", path, err)
 		}
+		cve.Provenance = provenance
 
 		cve.VulnerabilityID = strings.TrimSuffix(filepath.Base(path), ".json")
 		cve.Package = filepath.Base(filepath.Dir(path))
 		cves = append(cves, cve)
+		if db.BatchFull(len(cves)) {
+			if err = vs.save(ctx, cves); err != nil {
+				return xerrors.Errorf("error in Debian save: %w", err)
+			}
+			cves = nil
+		}
 
 		return nil
 	})
@@ -64,16 +116,16 @@ func (vs VulnSrc) Update(dir string) error {
 		return xerrors.Errorf("error in Debian walk: %w", err)
 	}
 
-	if err = vs.save(cves); err != nil {
+	if err = vs.save(ctx, cves); err != nil {
 		return xerrors.Errorf("error in Debian save: %w", err)
 	}
 
 	return nil
 }
 
-func (vs VulnSrc) save(cves []DebianCVE) error {
-	log.Println("Saving Debian DB")
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
+func (vs VulnSrc) save(ctx context.Context, cves []DebianCVE) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.Debian)
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
 		for _, cve := range cves {
 			for _, release := range cve.Releases {
 				for releaseStr := range release.Repositories {
@@ -81,7 +133,7 @@ func (vs VulnSrc) save(cves []DebianCVE) error {
 					if !ok {
 						continue
 					}
-					platformName := fmt.Sprintf(platformFormat, majorVersion)
+					platformName := db.Debian.Source(majorVersion)
 					if release.Status != "open" {
 						continue
 					}
@@ -91,6 +143,9 @@ func (vs VulnSrc) save(cves []DebianCVE) error {
 					if err := vs.dbc.PutAdvisory(tx, platformName, cve.Package, cve.VulnerabilityID, advisory); err != nil {
 						return xerrors.Errorf("failed to save Debian advisory: %w", err)
 					}
+					if err := vs.dbc.PutProvenance(tx, platformName, cve.Package, cve.VulnerabilityID, cve.Provenance); err != nil {
+						return xerrors.Errorf("failed to save Debian provenance: %w", err)
+					}
 
 					vuln := types.VulnerabilityDetail{
 						Severity:    severityFromUrgency(release.Urgency),
@@ -116,13 +171,16 @@ func (vs VulnSrc) save(cves []DebianCVE) error {
 	return nil
 }
 
-func (vs VulnSrc) Get(release string, pkgName string) ([]types.Advisory, error) {
-	bucket := fmt.Sprintf(platformFormat, release)
+func (vs VulnSrc) Get(ctx context.Context, release string, pkgName string, opts ...types.GetOption) ([]types.Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
+	}
+	bucket := db.Debian.Source(release)
 	advisories, err := vs.dbc.GetAdvisories(bucket, pkgName)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get Debian advisories: %w", err)
 	}
-	return advisories, nil
+	return types.AdvisoriesByFix(advisories, types.NewGetOptions(opts...).Fix), nil
 }
 
 func severityFromUrgency(urgency string) types.Severity {
@@ -130,7 +188,10 @@ func severityFromUrgency(urgency string) types.Severity {
 	case "not yet assigned":
 		return types.SeverityUnknown
 
-	case "end-of-life", "unimportant", "low", "low*", "low**":
+	case "unimportant":
+		return types.SeverityNegligible
+
+	case "end-of-life", "low", "low*", "low**":
 		return types.SeverityLow
 
 	case "medium", "medium*", "medium**":
@@ -1,11 +1,15 @@
 package debian
 
+import "github.com/aquasecurity/trivy-db/pkg/types"
+
 type DebianCVE struct {
 	Description     string             `json:"description"`
 	Releases        map[string]Release `json:"releases"`
 	Scope           string             `json:"scope"`
 	Package         string
 	VulnerabilityID string
+
+	Provenance types.Provenance `json:"-"`
 }
 
 type Release struct {
@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,11 +9,13 @@ import (
 	"strconv"
 	"strings"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
@@ -46,29 +49,62 @@ type Advisory struct {
 	PatchedVersions    string `json:",omitempty"`
 }
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc      operations
+	cacheDir string
+}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "nodejs-security-wg"})
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.NodejsSecurityWg},
+	}
+	for _, opt := range opts {
+		opt(&vs)
 	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.NodejsSecurityWg
 }
 
-func (vs VulnSrc) Update(dir string) (err error) {
+func (vs VulnSrc) Update(ctx context.Context, dir string) (err error) {
+	vs.cacheDir = dir
 	repoPath = filepath.Join(dir, nodeDir)
-	if err := vs.update(repoPath); err != nil {
+	if err := vs.update(ctx, repoPath); err != nil {
 		return xerrors.Errorf("failed to update node vulnerabilities: %w", err)
 	}
 	return nil
 }
 
-func (vs VulnSrc) update(repoPath string) error {
+func (vs VulnSrc) update(ctx context.Context, repoPath string) error {
 	root := filepath.Join(repoPath, "vuln")
 
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
-		if err := vs.walk(tx, root); err != nil {
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
+		if err := vs.walk(ctx, tx, root); err != nil {
 			return xerrors.Errorf("failed to walk node advisories: %w", err)
 		}
 		return nil
@@ -79,7 +115,8 @@ func (vs VulnSrc) update(repoPath string) error {
 	return nil
 }
 
-func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
+func (vs VulnSrc) walk(ctx context.Context, tx db.Tx, root string) error {
+	progress := log.ProgressFrom(ctx)
 	return filepath.Walk(filepath.Join(repoPath, "vuln"), func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -91,17 +128,23 @@ func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
 		if err != nil {
 			return err
 		}
+		progress.AddFiles(1)
 		defer f.Close()
 
 		advisory := RawAdvisory{}
-		if err = json.NewDecoder(f).Decode(&advisory); err != nil {
-			return err
+		pr := utils.NewProvenanceReader(f, vs.cacheDir, path)
+		if err = json.NewDecoder(pr).Decode(&advisory); err != nil {
+			return &types.DecodeError{Source: vulnerability.NodejsSecurityWg, Path: path, Err: err}
+		}
+		provenance, err := pr.Provenance()
+		if err != nil {
+			return xerrors.Errorf("failed to read %s: %w", path, err)
 		}
 		// Node.js itself
 		if advisory.ModuleName == "" {
 			return nil
 		}
-		advisory.ModuleName = strings.ToLower(advisory.ModuleName)
+		advisory.ModuleName = utils.NormalizeNpmName(advisory.ModuleName)
 
 		// `cvss_score` returns float or string like "4.8 (MEDIUM)"
 		s := strings.Split(advisory.CvssScoreNumber.String(), " ")
@@ -125,6 +168,9 @@ func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
 			if err != nil {
 				return xerrors.Errorf("failed to save node advisory: %w", err)
 			}
+			if err = vs.dbc.PutProvenance(tx, vulnerability.NodejsSecurityWg, advisory.ModuleName, vulnID, provenance); err != nil {
+				return xerrors.Errorf("failed to save node provenance: %w", err)
+			}
 
 			// for displaying vulnerability detail
 			vuln := types.VulnerabilityDetail{
@@ -147,20 +193,22 @@ func (vs VulnSrc) walk(tx *bolt.Tx, root string) error {
 	})
 }
 
-func (vs VulnSrc) Get(pkgName string) ([]Advisory, error) {
-	advisories, err := vs.dbc.ForEachAdvisory(vulnerability.NodejsSecurityWg, pkgName)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to iterate node vulnerabilities: %w", err)
+func (vs VulnSrc) Get(ctx context.Context, pkgName string) ([]Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
 	}
-
 	var results []Advisory
-	for vulnID, a := range advisories {
+	err := vs.dbc.ForEachAdvisory(vulnerability.NodejsSecurityWg, pkgName, func(_ *types.DataSource, vulnID string, v []byte) error {
 		var advisory Advisory
-		if err = json.Unmarshal(a, &advisory); err != nil {
-			return nil, xerrors.Errorf("failed to unmarshal advisory JSON: %w", err)
+		if err := db.Unmarshal(v, &advisory); err != nil {
+			return xerrors.Errorf("failed to unmarshal advisory: %w", err)
 		}
 		advisory.VulnerabilityID = vulnID
 		results = append(results, advisory)
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to iterate node vulnerabilities: %w", err)
 	}
 	return results, nil
 }
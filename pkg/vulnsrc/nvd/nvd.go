@@ -2,18 +2,20 @@ package nvd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"path/filepath"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
 	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
@@ -21,83 +23,157 @@ const (
 	nvdDir = "nvd"
 )
 
+type operations interface {
+	db.BatchUpdater
+	db.VulnerabilityStore
+	db.CPEStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc    operations
+	logger *slog.Logger
+}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vuln-list", Incremental: true})
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
 		dbc: db.Config{},
 	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
 }
 
-func (vs VulnSrc) Update(dir string) error {
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
+	}
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.Nvd
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
 	rootDir := filepath.Join(dir, "vuln-list", nvdDir)
 
 	var items []Item
 	buffer := &bytes.Buffer{}
-	err := utils.FileWalk(rootDir, func(r io.Reader, _ string) error {
+	err := utils.FileWalk(ctx, rootDir, func(r io.Reader, path string) error {
 		item := Item{}
 		if _, err := buffer.ReadFrom(r); err != nil {
 			return xerrors.Errorf("failed to read file: %w", err)
 		}
 		if err := json.Unmarshal(buffer.Bytes(), &item); err != nil {
-			return xerrors.Errorf("failed to decode NVD JSON: %w", err)
+			return &types.DecodeError{Source: vulnerability.Nvd, Path: path, Err: err}
 		}
 		buffer.Reset()
 		items = append(items, item)
+		if db.BatchFull(len(items)) {
+			if err := vs.save(ctx, items); err != nil {
+				return xerrors.Errorf("error in NVD save: %w", err)
+			}
+			items = nil
+		}
 		return nil
 	})
 	if err != nil {
 		return xerrors.Errorf("error in NVD walk: %w", err)
 	}
 
-	if err = vs.save(items); err != nil {
+	if err = vs.save(ctx, items); err != nil {
 		return xerrors.Errorf("error in NVD save: %w", err)
 	}
 
 	return nil
 }
 
-func (vs VulnSrc) save(items []Item) error {
-	log.Println("NVD batch update")
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
-		for _, item := range items {
-			cveID := item.Cve.Meta.ID
-			severity, _ := types.NewSeverity(item.Impact.BaseMetricV2.Severity)
-			severityV3, _ := types.NewSeverity(item.Impact.BaseMetricV3.CvssV3.BaseSeverity)
-
-			var references []string
-			for _, ref := range item.Cve.References.ReferenceDataList {
-				references = append(references, ref.URL)
-			}
+func (vs VulnSrc) save(ctx context.Context, items []Item) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.Nvd)
+	err := vs.dbc.ChunkedUpdate(ctx, len(items), db.DefaultChunkSize, func(tx db.Tx, i int) error {
+		item := items[i]
+		cveID := item.Cve.Meta.ID
+		severity, _ := types.NewSeverity(item.Impact.BaseMetricV2.Severity)
+		severityV3, _ := types.NewSeverity(item.Impact.BaseMetricV3.CvssV3.BaseSeverity)
+
+		var references []string
+		for _, ref := range item.Cve.References.ReferenceDataList {
+			references = append(references, ref.URL)
+		}
 
-			var description string
-			for _, d := range item.Cve.Description.DescriptionDataList {
-				if d.Value != "" {
-					description = d.Value
-					break
-				}
+		var description string
+		for _, d := range item.Cve.Description.DescriptionDataList {
+			if d.Value != "" {
+				description = d.Value
+				break
 			}
+		}
 
-			vuln := types.VulnerabilityDetail{
-				CvssScore:   item.Impact.BaseMetricV2.CvssV2.BaseScore,
-				CvssScoreV3: item.Impact.BaseMetricV3.CvssV3.BaseScore,
-				Severity:    severity,
-				SeverityV3:  severityV3,
-				References:  references,
-				Title:       "",
-				Description: description,
-			}
+		vuln := types.VulnerabilityDetail{
+			CvssScore:    item.Impact.BaseMetricV2.CvssV2.BaseScore,
+			CvssVector:   item.Impact.BaseMetricV2.CvssV2.VectorString,
+			CvssScoreV3:  item.Impact.BaseMetricV3.CvssV3.BaseScore,
+			CvssVectorV3: item.Impact.BaseMetricV3.CvssV3.VectorString,
+			Severity:     severity,
+			SeverityV3:   severityV3,
+			References:   references,
+			Title:        "",
+			Description:  description,
+		}
 
-			if err := vs.dbc.PutVulnerabilityDetail(tx, cveID, vulnerability.Nvd, vuln); err != nil {
-				return err
-			}
+		if err := vs.dbc.PutVulnerabilityDetail(tx, cveID, vulnerability.Nvd, vuln); err != nil {
+			return err
+		}
+
+		// for detecting vulnerabilities by CPE
+		if err := vs.dbc.PutCPEMatches(tx, cveID, cpeMatches(item.Configurations.Nodes)); err != nil {
+			return err
 		}
 		return nil
+	}, func(done, total int) {
+		log.OrDefault(vs.logger).Info("Saved a chunk", "source", vulnerability.Nvd, "done", done, "total", total)
 	})
 	if err != nil {
 		return xerrors.Errorf("error in batch update: %w", err)
 	}
 	return nil
 }
+
+// cpeMatches flattens the configuration nodes into the vulnerable CPEs.
+// The AND operator is ignored, e.g. an application is vulnerable whatever OS it runs on.
+func cpeMatches(nodes []Node) []types.CPEMatch {
+	var matches []types.CPEMatch
+	for _, node := range nodes {
+		for _, m := range node.CpeMatch {
+			if !m.Vulnerable {
+				continue
+			}
+			matches = append(matches, types.CPEMatch{
+				CPE:                   m.Cpe23Uri,
+				VersionStartIncluding: m.VersionStartIncluding,
+				VersionStartExcluding: m.VersionStartExcluding,
+				VersionEndIncluding:   m.VersionEndIncluding,
+				VersionEndExcluding:   m.VersionEndExcluding,
+			})
+		}
+		matches = append(matches, cpeMatches(node.Children)...)
+	}
+	return matches
+}
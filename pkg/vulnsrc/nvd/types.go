@@ -5,8 +5,9 @@ type NVD struct {
 }
 
 type Item struct {
-	Cve    Cve
-	Impact Impact
+	Cve            Cve
+	Impact         Impact
+	Configurations Configurations
 }
 
 type Cve struct {
@@ -30,7 +31,8 @@ type BaseMetricV2 struct {
 }
 
 type CvssV2 struct {
-	BaseScore float64
+	BaseScore    float64
+	VectorString string
 }
 
 type BaseMetricV3 struct {
@@ -40,6 +42,7 @@ type BaseMetricV3 struct {
 type CvssV3 struct {
 	BaseScore    float64
 	BaseSeverity string
+	VectorString string
 }
 
 type References struct {
@@ -59,3 +62,22 @@ type DescriptionData struct {
 	Lang  string
 	Value string
 }
+
+type Configurations struct {
+	Nodes []Node
+}
+
+type Node struct {
+	Operator string
+	Children []Node
+	CpeMatch []CpeMatch `json:"cpe_match"`
+}
+
+type CpeMatch struct {
+	Vulnerable            bool
+	Cpe23Uri              string
+	VersionStartIncluding string
+	VersionStartExcluding string
+	VersionEndIncluding   string
+	VersionEndExcluding   string
+}
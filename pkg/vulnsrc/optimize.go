@@ -0,0 +1,212 @@
+package vulnsrc
+
+import (
+	"time"
+
+	"golang.org/x/xerrors"
+	"k8s.io/utils/clock"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+)
+
+// Optimizer derives what the DB serves from what the sources wrote, once they are all updated
+type Optimizer interface {
+	Optimize() error
+}
+
+// Pass is a step of the optimization, e.g. the resolution of the severities
+type Pass interface {
+	// Name is the name of the pass in the metrics and the errors, e.g. severities
+	Name() string
+	Run() error
+}
+
+// NewPass returns a pass running run, e.g. NewPass("dedup", dbc.Dedup)
+func NewPass(name string, run func() error) Pass {
+	return funcPass{name: name, run: run}
+}
+
+type funcPass struct {
+	name string
+	run  func() error
+}
+
+func (p funcPass) Name() string { return p.name }
+func (p funcPass) Run() error   { return p.run() }
+
+// PassObserver is told how long each pass took once it is over, and its error if it failed
+type PassObserver func(pass string, elapsed time.Duration, err error)
+
+// passer is an optimizer made of passes, which the passes of WithPasses run after
+type passer interface {
+	Passes() []Pass
+}
+
+// passOptimizer runs its passes in order, stopping at the first which fails
+type passOptimizer struct {
+	passes  []Pass
+	observe PassObserver
+	clock   clock.Clock
+}
+
+func (o passOptimizer) Optimize() error {
+	c := o.clock
+	if c == nil {
+		c = clock.RealClock{}
+	}
+	for _, p := range o.passes {
+		start := c.Now()
+		err := p.Run()
+		if o.observe != nil {
+			o.observe(p.Name(), c.Since(start), err)
+		}
+		if err != nil {
+			return xerrors.Errorf("failed to run the %s pass: %w", p.Name(), err)
+		}
+	}
+	return nil
+}
+
+type fullOptimizer struct {
+	dbc db.VulnerabilityStore
+	// merge keeps what the vulnerabilities had from the details not put again, e.g. by the sources of
+	// an incremental update which didn't read the files of a vulnerability
+	merge bool
+}
+
+func (o fullOptimizer) Optimize() error {
+	return passOptimizer{passes: o.Passes()}.Optimize()
+}
+
+// Passes merges the details of the sources into the vulnerabilities, then drops them
+func (o fullOptimizer) Passes() []Pass {
+	return []Pass{
+		NewPass("vulnerabilities", o.putVulnerabilities),
+		NewPass("details", o.deleteDetails),
+	}
+}
+
+func (o fullOptimizer) putVulnerabilities() error {
+	err := o.dbc.ForEachSeverity(func(tx db.Tx, cveID string, _ types.Severity) error {
+		vuln := vulnerability.GetVulnerability(cveID)
+		if o.merge {
+			if previous, err := o.dbc.GetVulnerability(cveID); err == nil {
+				vuln = mergeVulnerability(previous, vuln)
+			} else if !xerrors.Is(err, db.ErrVulnerabilityNotFound) {
+				return xerrors.Errorf("failed to get vulnerability: %w", err)
+			}
+		}
+		if err := o.dbc.PutVulnerability(tx, cveID, vuln); err != nil {
+			return xerrors.Errorf("failed to put vulnerability: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to iterate severity: %w", err)
+	}
+	return nil
+}
+
+func (o fullOptimizer) deleteDetails() error {
+	if err := o.dbc.DeleteSeverityBucket(); err != nil {
+		return xerrors.Errorf("failed to delete severity bucket: %w", err)
+	}
+	if err := o.dbc.DeleteVulnerabilityDetailBucket(); err != nil {
+		return xerrors.Errorf("failed to delete vulnerability detail bucket: %w", err)
+	}
+	return nil
+}
+
+// mergeVulnerability fills what vuln lacks with what previous had
+func mergeVulnerability(previous, vuln types.Vulnerability) types.Vulnerability {
+	if vuln.Title == "" {
+		vuln.Title = previous.Title
+	}
+	if vuln.Description == "" {
+		vuln.Description = previous.Description
+	}
+	if vuln.Severity == "" || vuln.Severity == types.SeverityUnknown.String() && previous.Severity != "" {
+		vuln.Severity = previous.Severity
+	}
+	if len(vuln.References) == 0 {
+		vuln.References = previous.References
+	}
+	if vuln.KnownExploited == nil {
+		vuln.KnownExploited = previous.KnownExploited
+	}
+	for source, c := range previous.CVSS {
+		if _, ok := vuln.CVSS[source]; ok {
+			continue
+		}
+		if vuln.CVSS == nil {
+			vuln.CVSS = map[string]types.CVSS{}
+		}
+		vuln.CVSS[source] = c
+	}
+	return vuln
+}
+
+type lightOperations interface {
+	db.VulnerabilityStore
+	db.AdvisoryStore
+	db.CPEStore
+	db.ProvenanceStore
+}
+
+// lightOptimizer keeps only the advisories and the severities
+type lightOptimizer struct {
+	dbc lightOperations
+}
+
+func (o lightOptimizer) Optimize() error {
+	return passOptimizer{passes: o.Passes()}.Optimize()
+}
+
+// Passes resolves the severities from the details of the sources, then drops the details and the indexes
+func (o lightOptimizer) Passes() []Pass {
+	return []Pass{
+		NewPass("severities", o.putSeverities),
+		NewPass("details", o.deleteDetails),
+		NewPass("indexes", o.deleteIndexes),
+	}
+}
+
+func (o lightOptimizer) putSeverities() error {
+	err := o.dbc.ForEachSeverity(func(tx db.Tx, cveID string, _ types.Severity) error {
+		// get correct severity
+		sev, _, _, _ := vulnerability.GetDetail(cveID)
+
+		// overwrite unknown severity with correct severity
+		if err := o.dbc.PutSeverity(tx, cveID, sev); err != nil {
+			return xerrors.Errorf("failed to put severity: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to iterate severity: %w", err)
+	}
+	return nil
+}
+
+func (o lightOptimizer) deleteDetails() error {
+	if err := o.dbc.DeleteVulnerabilityDetailBucket(); err != nil {
+		return xerrors.Errorf("failed to delete vulnerability detail bucket: %w", err)
+	}
+	return nil
+}
+
+// deleteIndexes drops the indexes, which serve lookups light clients don't do
+func (o lightOptimizer) deleteIndexes() error {
+	if err := o.dbc.DeleteCPEBucket(); err != nil {
+		return xerrors.Errorf("failed to delete CPE bucket: %w", err)
+	}
+	if err := o.dbc.DeleteAffectedPackageBucket(); err != nil {
+		return xerrors.Errorf("failed to delete affected package bucket: %w", err)
+	}
+	if err := o.dbc.DeleteProvenanceBucket(); err != nil {
+		return xerrors.Errorf("failed to delete provenance bucket: %w", err)
+	}
+	return nil
+}
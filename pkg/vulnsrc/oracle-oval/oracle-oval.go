@@ -1,49 +1,110 @@
 package oracleoval
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"path/filepath"
 	"strings"
 
-	bolt "github.com/etcd-io/bbolt"
-
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 	version "github.com/knqyf263/go-rpm-version"
 	"golang.org/x/xerrors"
 )
 
 var (
-	// cat /etc/os-release ORACLE_BUGZILLA_PRODUCT="Oracle Linux 8"
-	platformFormat  = "Oracle Linux %s"
-	targetPlatforms = []string{"Oracle Linux 5", "Oracle Linux 6", "Oracle Linux 7", "Oracle Linux 8"}
-	oracleDir       = filepath.Join("oval", "oracle")
+	targetReleases = []string{"5", "6", "7", "8"}
+	oracleDir      = filepath.Join("oval", "oracle")
 )
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc      operations
+	logger   *slog.Logger
+	releases []string // nil for targetReleases
+}
+
+var _ registry.AdvisoryGetter = VulnSrc{}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vuln-list"})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.OracleOVAL},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
+	}
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// WithReleases makes the source keep the advisories of releases, e.g. 9, rather than of 5 to 8
+func WithReleases(releases ...string) Option {
+	return func(vs *VulnSrc) {
+		vs.releases = releases
 	}
 }
 
-func (vs VulnSrc) Update(dir string) error {
+// supported returns whether the source keeps the advisories of release
+func (vs VulnSrc) supported(release string) bool {
+	if vs.releases == nil {
+		return utils.StringInSlice(release, targetReleases)
+	}
+	return utils.StringInSlice(release, vs.releases)
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.OracleOVAL
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
 	rootDir := filepath.Join(dir, "vuln-list", oracleDir)
 
 	var ovals []OracleOVAL
-	err := utils.FileWalk(rootDir, func(r io.Reader, path string) error {
+	err := utils.FileWalk(ctx, rootDir, func(r io.Reader, path string) error {
 		var oval OracleOVAL
-		if err := json.NewDecoder(r).Decode(&oval); err != nil {
-			return xerrors.Errorf("failed to decode Oracle Linux OVAL JSON: %w", err)
+		pr := utils.NewProvenanceReader(r, dir, path)
+		if err := json.NewDecoder(pr).Decode(&oval); err != nil {
+			return &types.DecodeError{Source: vulnerability.OracleOVAL, Path: path, Err: err}
+		}
+		provenance, err := pr.Provenance()
+		if err != nil {
+			return xerrors.Errorf("failed to read %s: %w", path, err)
 		}
+		oval.Provenance = provenance
 		ovals = append(ovals, oval)
 		return nil
 	})
@@ -51,17 +112,17 @@ func (vs VulnSrc) Update(dir string) error {
 		return xerrors.Errorf("error in Oracle Linux OVAL walk: %w", err)
 	}
 
-	if err = vs.save(ovals); err != nil {
+	if err = vs.save(ctx, ovals); err != nil {
 		return xerrors.Errorf("error in Oracle Linux OVAL save: %w", err)
 	}
 
 	return nil
 }
 
-func (vs VulnSrc) save(ovals []OracleOVAL) error {
-	log.Println("Saving Oracle Linux OVAL")
+func (vs VulnSrc) save(ctx context.Context, ovals []OracleOVAL) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.OracleOVAL)
 
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
 		return vs.commit(tx, ovals)
 	})
 	if err != nil {
@@ -72,7 +133,7 @@ func (vs VulnSrc) save(ovals []OracleOVAL) error {
 
 }
 
-func (vs VulnSrc) commit(tx *bolt.Tx, ovals []OracleOVAL) error {
+func (vs VulnSrc) commit(tx db.Tx, ovals []OracleOVAL) error {
 	for _, oval := range ovals {
 		elsaID := strings.Split(oval.Title, ":")[0]
 
@@ -84,25 +145,43 @@ func (vs VulnSrc) commit(tx *bolt.Tx, ovals []OracleOVAL) error {
 			vulnIDs = append(vulnIDs, elsaID)
 		}
 
-		affectedPkgs := walkOracle(oval.Criteria, "", []AffectedPackage{})
-		for _, affectedPkg := range affectedPkgs {
+		// a package is fixed on each architecture, in versions which may differ
+		type platformPackage struct {
+			platform string
+			name     string
+		}
+		var keys []platformPackage
+		fixes := map[platformPackage][]utils.ArchFix{}
+		for _, affectedPkg := range walkOracle(oval.Criteria, "", "", []AffectedPackage{}) {
 			if affectedPkg.Package.Name == "" {
 				continue
 			}
 
-			platformName := fmt.Sprintf(platformFormat, affectedPkg.OSVer)
-			if !utils.StringInSlice(platformName, targetPlatforms) {
+			if !vs.supported(affectedPkg.OSVer) {
 				continue
 			}
-
-			advisory := types.Advisory{
-				FixedVersion: affectedPkg.Package.FixedVersion,
+			platformName := db.OracleLinux.Source(affectedPkg.OSVer)
+			key := platformPackage{platform: platformName, name: affectedPkg.Package.Name}
+			if _, ok := fixes[key]; !ok {
+				keys = append(keys, key)
 			}
+			fixes[key] = append(fixes[key], utils.ArchFix{Arch: affectedPkg.Arch,
+				FixedVersion: affectedPkg.Package.FixedVersion})
+		}
 
+		for _, key := range keys {
+			advisory := utils.ArchAdvisory(fixes[key])
+			advisory.Severity = severityFromThreat(oval.Severity)
+			if strings.HasPrefix(elsaID, "ELSA-") {
+				advisory.VendorIDs = []string{elsaID}
+			}
 			for _, vulnID := range vulnIDs {
-				if err := vs.dbc.PutAdvisory(tx, platformName, affectedPkg.Package.Name, vulnID, advisory); err != nil {
+				if err := vs.dbc.PutAdvisory(tx, key.platform, key.name, vulnID, advisory); err != nil {
 					return xerrors.Errorf("failed to save Oracle Linux OVAL: %w", err)
 				}
+				if err := vs.dbc.PutProvenance(tx, key.platform, key.name, vulnID, oval.Provenance); err != nil {
+					return xerrors.Errorf("failed to save Oracle Linux OVAL provenance: %w", err)
+				}
 			}
 		}
 
@@ -133,21 +212,37 @@ func (vs VulnSrc) commit(tx *bolt.Tx, ovals []OracleOVAL) error {
 
 }
 
-func (vs VulnSrc) Get(release string, pkgName string) ([]types.Advisory, error) {
-	bucket := fmt.Sprintf(platformFormat, release)
+func (vs VulnSrc) Get(ctx context.Context, release string, pkgName string, opts ...types.GetOption) ([]types.Advisory, error) {
+	return vs.GetForArch(ctx, release, pkgName, "", opts...)
+}
+
+// GetForArch returns the advisories of a package affecting an architecture, e.g. aarch64, with its fixed
+// version, all of them if arch is empty
+func (vs VulnSrc) GetForArch(ctx context.Context, release, pkgName, arch string, opts ...types.GetOption) ([]types.Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
+	}
+	bucket := db.OracleLinux.Source(release)
+	if !vs.supported(release) {
+		return nil, xerrors.Errorf("%s: %w", bucket, types.ErrUnsupportedRelease)
+	}
 	advisories, err := vs.dbc.GetAdvisories(bucket, pkgName)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get Oracle Linux advisories: %w", err)
 	}
-	return advisories, nil
+	return types.AdvisoriesByFix(types.AdvisoriesForArch(advisories, arch), types.NewGetOptions(opts...).Fix), nil
 }
 
-func walkOracle(cri Criteria, osVer string, pkgs []AffectedPackage) []AffectedPackage {
+func walkOracle(cri Criteria, osVer, arch string, pkgs []AffectedPackage) []AffectedPackage {
 	for _, c := range cri.Criterions {
 		if strings.HasPrefix(c.Comment, "Oracle Linux ") &&
 			strings.HasSuffix(c.Comment, " is installed") {
 			osVer = strings.TrimSuffix(strings.TrimPrefix(c.Comment, "Oracle Linux "), " is installed")
 		}
+		// e.g. Oracle Linux arch is aarch64
+		if strings.HasPrefix(c.Comment, "Oracle Linux arch is ") {
+			arch = strings.TrimPrefix(c.Comment, "Oracle Linux arch is ")
+		}
 		ss := strings.Split(c.Comment, " is earlier than ")
 		if len(ss) != 2 {
 			continue
@@ -155,6 +250,7 @@ func walkOracle(cri Criteria, osVer string, pkgs []AffectedPackage) []AffectedPa
 
 		pkgs = append(pkgs, AffectedPackage{
 			OSVer: osVer,
+			Arch:  arch,
 			Package: Package{
 				Name:         ss[0],
 				FixedVersion: version.NewVersion(ss[1]).String(),
@@ -163,7 +259,7 @@ func walkOracle(cri Criteria, osVer string, pkgs []AffectedPackage) []AffectedPa
 	}
 
 	for _, c := range cri.Criterias {
-		pkgs = walkOracle(c, osVer, pkgs)
+		pkgs = walkOracle(c, osVer, arch, pkgs)
 	}
 	return pkgs
 }
@@ -1,26 +1,22 @@
 package oracleoval
 
 import (
+	"context"
 	"errors"
-	"os"
 	"testing"
 
-	bolt "github.com/etcd-io/bbolt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
 	"github.com/aquasecurity/trivy-db/pkg/types"
-	"github.com/aquasecurity/trivy-db/pkg/utils"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnsrctest"
 )
 
-func TestMain(m *testing.M) {
-	utils.Quiet = true
-	os.Exit(m.Run())
-}
-
 func TestVulnSrc_Update(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -48,11 +44,11 @@ func TestVulnSrc_Update(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("BatchUpdate", mock.Anything).Return(tc.batchUpdateErr)
-			ac := VulnSrc{dbc: mockDBConfig}
+			mockBatchUpdater := new(db.MockBatchUpdater)
+			mockBatchUpdater.On("BatchUpdate", mock.Anything, mock.Anything).Return(tc.batchUpdateErr)
+			ac := VulnSrc{dbc: vulnsrctest.MockOperations{MockBatchUpdater: mockBatchUpdater}, logger: log.Discard()}
 
-			err := ac.Update(tc.cacheDir)
+			err := ac.Update(context.Background(), tc.cacheDir)
 			switch {
 			case tc.expectedError != nil:
 				assert.EqualError(t, err, tc.expectedError.Error(), tc.name)
@@ -174,7 +170,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-devel",
 						cveID:    "CVE-2007-0493",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 				{
@@ -182,7 +178,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-devel",
 						cveID:    "CVE-2007-0494",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 			},
@@ -311,7 +307,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-devel",
 						cveID:    "CVE-2007-0493",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 				{
@@ -319,7 +315,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-devel",
 						cveID:    "CVE-2007-0494",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 			},
@@ -500,7 +496,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 6",
 						pkgName:  "kernel-uek-doc",
 						cveID:    "CVE-2018-1094",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el6uek"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el6uek", Severity: types.SeverityHigh, VendorIDs: []string{"ELSA-2019-4510"}},
 					},
 				},
 				{
@@ -508,7 +504,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 6",
 						pkgName:  "kernel-uek-doc",
 						cveID:    "CVE-2018-19824",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el6uek"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el6uek", Severity: types.SeverityHigh, VendorIDs: []string{"ELSA-2019-4510"}},
 					},
 				},
 				{
@@ -516,7 +512,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 6",
 						pkgName:  "kernel-uek-firmware",
 						cveID:    "CVE-2018-1094",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el6uek"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el6uek", Severity: types.SeverityHigh, VendorIDs: []string{"ELSA-2019-4510"}},
 					},
 				},
 				{
@@ -524,7 +520,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 6",
 						pkgName:  "kernel-uek-firmware",
 						cveID:    "CVE-2018-19824",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el6uek"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el6uek", Severity: types.SeverityHigh, VendorIDs: []string{"ELSA-2019-4510"}},
 					},
 				},
 				{
@@ -532,7 +528,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 7",
 						pkgName:  "kernel-uek-doc",
 						cveID:    "CVE-2018-1094",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el7uek"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el7uek", Severity: types.SeverityHigh, VendorIDs: []string{"ELSA-2019-4510"}},
 					},
 				},
 				{
@@ -540,7 +536,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 7",
 						pkgName:  "kernel-uek-doc",
 						cveID:    "CVE-2018-19824",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el7uek"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el7uek", Severity: types.SeverityHigh, VendorIDs: []string{"ELSA-2019-4510"}},
 					},
 				},
 				{
@@ -548,7 +544,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 7",
 						pkgName:  "kernel-uek-firmware",
 						cveID:    "CVE-2018-1094",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el7uek"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el7uek", Severity: types.SeverityHigh, VendorIDs: []string{"ELSA-2019-4510"}},
 					},
 				},
 				{
@@ -556,7 +552,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 7",
 						pkgName:  "kernel-uek-firmware",
 						cveID:    "CVE-2018-19824",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el7uek"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "4.1.12-124.24.3.el7uek", Severity: types.SeverityHigh, VendorIDs: []string{"ELSA-2019-4510"}},
 					},
 				},
 			},
@@ -692,7 +688,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-devel",
 						cveID:    "CVE-2007-0493",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 				{
@@ -700,7 +696,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-devel",
 						cveID:    "CVE-2007-0494",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 				{
@@ -708,7 +704,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-sdb",
 						cveID:    "CVE-2007-0493",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 				{
@@ -716,7 +712,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-sdb",
 						cveID:    "CVE-2007-0494",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "30:9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 			},
@@ -840,7 +836,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-devel",
 						cveID:    "CVE-2007-0493",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 				{
@@ -848,7 +844,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-devel",
 						cveID:    "CVE-2007-0494",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 			},
@@ -955,7 +951,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Oracle Linux 5",
 						pkgName:  "bind-devel",
 						cveID:    "ELSA-2007-0057",
-						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "9.3.3-8.el5"},
+						advisory: types.Advisory{VulnerabilityID: "", FixedVersion: "9.3.3-8.el5", Severity: types.SeverityMedium, VendorIDs: []string{"ELSA-2007-0057"}},
 					},
 				},
 			},
@@ -1150,27 +1146,108 @@ func TestVulnSrc_Commit(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "fixed in other versions on the architectures",
+			cves: []OracleOVAL{
+				{
+					Title:    "ELSA-2020-0001:  Important: kernel security update  (IMPORTANT)",
+					Platform: []string{"Oracle Linux 8"},
+					Criteria: Criteria{
+						Operator: "AND",
+						Criterias: []Criteria{
+							{
+								Operator: "OR",
+								Criterias: []Criteria{
+									{
+										Operator: "AND",
+										Criterions: []Criterion{
+											{Comment: "Oracle Linux arch is aarch64"},
+											{Comment: "kernel is earlier than 0:4.18.0-147.5.1.el8_1"},
+										},
+									},
+									{
+										Operator: "AND",
+										Criterions: []Criterion{
+											{Comment: "Oracle Linux arch is x86_64"},
+											{Comment: "kernel is earlier than 0:4.18.0-147.3.1.el8_1"},
+										},
+									},
+								},
+							},
+						},
+						Criterions: []Criterion{
+							{Comment: "Oracle Linux 8 is installed"},
+						},
+					},
+					Severity: "IMPORTANT",
+					Cves:     []Cve{{ID: "CVE-2020-0001"}},
+				},
+			},
+			putAdvisoryList: []putAdvisory{
+				{
+					input: putAdvisoryInput{
+						source:  "Oracle Linux 8",
+						pkgName: "kernel",
+						cveID:   "CVE-2020-0001",
+						advisory: types.Advisory{
+							FixedVersion:      "4.18.0-147.5.1.el8_1",
+							Severity:          types.SeverityHigh,
+							VendorIDs:         []string{"ELSA-2020-0001"},
+							Arches:            []string{"aarch64", "x86_64"},
+							ArchFixedVersions: map[string]string{"x86_64": "4.18.0-147.3.1.el8_1"},
+						},
+					},
+				},
+			},
+			putVulnerabilityDetailList: []putVulnerabilityDetail{
+				{
+					input: putVulnerabilityDetailInput{
+						cveID:  "CVE-2020-0001",
+						source: vulnerability.OracleOVAL,
+						vuln: types.VulnerabilityDetail{
+							References: []string{},
+							Title:      "ELSA-2020-0001:  Important: kernel security update  (IMPORTANT)",
+							Severity:   types.SeverityHigh,
+						},
+					},
+				},
+			},
+			putSeverityList: []putSeverity{
+				{
+					input: putSeverityInput{
+						cveID:    "CVE-2020-0001",
+						severity: types.SeverityUnknown,
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			tx := &bolt.Tx{}
-			mockDBConfig := new(db.MockDBConfig)
+			tx := &storage.MockTx{}
+			mockAdvisoryStore := new(db.MockAdvisoryStore)
+			mockVulnerabilityStore := new(db.MockVulnerabilityStore)
+			mockProvenanceStore := new(db.MockProvenanceStore)
 
 			for _, pa := range tc.putAdvisoryList {
-				mockDBConfig.On("PutAdvisory", tx, pa.input.source, pa.input.pkgName,
+				mockAdvisoryStore.On("PutAdvisory", tx, pa.input.source, pa.input.pkgName,
 					pa.input.cveID, pa.input.advisory).Return(pa.output)
+				if pa.output == nil {
+					mockProvenanceStore.On("PutProvenance", tx, pa.input.source, pa.input.pkgName,
+						pa.input.cveID, mock.Anything).Return(nil)
+				}
 			}
 			for _, pvd := range tc.putVulnerabilityDetailList {
-				mockDBConfig.On("PutVulnerabilityDetail", tx, pvd.input.cveID,
+				mockVulnerabilityStore.On("PutVulnerabilityDetail", tx, pvd.input.cveID,
 					pvd.input.source, pvd.input.vuln).Return(pvd.output)
 			}
 			for _, ps := range tc.putSeverityList {
-				mockDBConfig.On("PutSeverity", tx, ps.input.cveID,
+				mockVulnerabilityStore.On("PutSeverity", tx, ps.input.cveID,
 					ps.input.severity).Return(ps.output)
 			}
 
-			ac := VulnSrc{dbc: mockDBConfig}
+			ac := VulnSrc{dbc: vulnsrctest.MockOperations{MockAdvisoryStore: mockAdvisoryStore, MockVulnerabilityStore: mockVulnerabilityStore, MockProvenanceStore: mockProvenanceStore}, logger: log.Discard()}
 			err := ac.commit(tx, tc.cves)
 
 			switch {
@@ -1179,7 +1256,9 @@ func TestVulnSrc_Commit(t *testing.T) {
 			default:
 				assert.NoError(t, err, tc.name)
 			}
-			mockDBConfig.AssertExpectations(t)
+			mockAdvisoryStore.AssertExpectations(t)
+			mockVulnerabilityStore.AssertExpectations(t)
+			mockProvenanceStore.AssertExpectations(t)
 		})
 	}
 }
@@ -1215,6 +1294,7 @@ func TestVulnSrc_Get(t *testing.T) {
 		name          string
 		version       string
 		pkgName       string
+		arch          string
 		getAdvisories getAdvisories
 		expectedError error
 		expectedVulns []types.Advisory
@@ -1253,7 +1333,32 @@ func TestVulnSrc_Get(t *testing.T) {
 			expectedVulns: []types.Advisory{},
 		},
 		{
-			name: "oracle GetAdvisories return an error",
+			name:    "advisories of an architecture",
+			version: "8",
+			pkgName: "kernel",
+			arch:    "aarch64",
+			getAdvisories: getAdvisories{
+				input: getAdvisoriesInput{
+					version: "Oracle Linux 8",
+					pkgName: "kernel",
+				},
+				output: getAdvisoriesOutput{
+					advisories: []types.Advisory{
+						{VulnerabilityID: "CVE-2019-0001", FixedVersion: "4.18.0-147.5.1.el8_1", Arches: []string{"aarch64", "x86_64"},
+							ArchFixedVersions: map[string]string{"x86_64": "0.1.1"}},
+						{VulnerabilityID: "CVE-2019-0002", FixedVersion: "0.1.2", Arches: []string{"x86_64"}},
+						{VulnerabilityID: "CVE-2019-0003", FixedVersion: "0.1.3"},
+					},
+				},
+			},
+			expectedVulns: []types.Advisory{
+				{VulnerabilityID: "CVE-2019-0001", FixedVersion: "4.18.0-147.5.1.el8_1", Arches: []string{"aarch64"}},
+				{VulnerabilityID: "CVE-2019-0003", FixedVersion: "0.1.3", Arches: []string{"aarch64"}},
+			},
+		},
+		{
+			name:    "oracle GetAdvisories return an error",
+			version: "8",
 			getAdvisories: getAdvisories{
 				input: getAdvisoriesInput{
 					version: mock.Anything,
@@ -1267,18 +1372,30 @@ func TestVulnSrc_Get(t *testing.T) {
 			expectedError: errors.New("failed to get Oracle Linux advisories: unable to get advisories"),
 			expectedVulns: nil,
 		},
+		{
+			name:          "unsupported release",
+			version:       "9",
+			pkgName:       "bind",
+			expectedError: errors.New("Oracle Linux 9: unsupported release"),
+			expectedVulns: nil,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("GetAdvisories",
-				tc.getAdvisories.input.version, tc.getAdvisories.input.pkgName).Return(
-				tc.getAdvisories.output.advisories, tc.getAdvisories.output.err,
-			)
+			mockAdvisoryStore := new(db.MockAdvisoryStore)
+			if tc.getAdvisories.input.version != "" {
+				mockAdvisoryStore.On("GetAdvisories",
+					tc.getAdvisories.input.version, tc.getAdvisories.input.pkgName).Return(
+					tc.getAdvisories.output.advisories, tc.getAdvisories.output.err,
+				)
+			}
 
-			ac := VulnSrc{dbc: mockDBConfig}
-			vuls, err := ac.Get(tc.version, tc.pkgName)
+			ac := VulnSrc{dbc: vulnsrctest.MockOperations{MockAdvisoryStore: mockAdvisoryStore}, logger: log.Discard()}
+			vuls, err := ac.Get(context.Background(), tc.version, tc.pkgName)
+			if tc.arch != "" {
+				vuls, err = ac.GetForArch(context.Background(), tc.version, tc.pkgName, tc.arch)
+			}
 
 			switch {
 			case tc.expectedError != nil:
@@ -1288,7 +1405,7 @@ func TestVulnSrc_Get(t *testing.T) {
 			}
 			assert.Equal(t, tc.expectedVulns, vuls, tc.name)
 
-			mockDBConfig.AssertExpectations(t)
+			mockAdvisoryStore.AssertExpectations(t)
 		})
 	}
 }
@@ -1,5 +1,7 @@
 package oracleoval
 
+import "github.com/aquasecurity/trivy-db/pkg/types"
+
 type OracleOVAL struct {
 	Title       string
 	Description string
@@ -8,6 +10,8 @@ type OracleOVAL struct {
 	Criteria    Criteria
 	Severity    string
 	Cves        []Cve
+
+	Provenance types.Provenance `json:"-"`
 }
 
 type Reference struct {
@@ -40,4 +44,5 @@ type Package struct {
 type AffectedPackage struct {
 	Package Package
 	OSVer   string
+	Arch    string // e.g. aarch64, empty if the definition has no architecture
 }
@@ -0,0 +1,255 @@
+// Package plugin runs executables feeding advisories into the build, e.g. of a proprietary feed, as sources.
+//
+// A plugin is run with its request on stdin, a JSON object:
+//
+//	{"name": "acme", "cacheDir": "/root/.cache/trivy-db"}
+//
+// and writes its records to stdout, a JSON object per line, each an advisory or the details of a vulnerability:
+//
+//	{"advisory": {"platform": "acme linux 1", "package": "openssl", "vulnerabilityID": "CVE-2019-0001", "fixedVersion": "1.1.1d-1", "severity": "MEDIUM"}}
+//	{"vulnerability": {"id": "CVE-2019-0001", "severity": "HIGH", "cvssScoreV3": 7.5, "title": "padding oracle"}}
+//
+// The advisories are stored under the data source of the plugin, e.g. acme::acme linux 1. What the plugin
+// writes to stderr goes to the stderr of the build. An exit status other than 0 or an invalid record fails the
+// source, whose records are only written once the plugin succeeded.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// Request is what a plugin is given on stdin
+type Request struct {
+	Name     string `json:"name"`
+	CacheDir string `json:"cacheDir"`
+}
+
+// Record is a line of the output of a plugin, with either an advisory or a vulnerability
+type Record struct {
+	Advisory      *Advisory      `json:"advisory,omitempty"`
+	Vulnerability *Vulnerability `json:"vulnerability,omitempty"`
+}
+
+// Advisory is the advisory of a package for a vulnerability
+type Advisory struct {
+	// Platform is the platform of the advisory bucket, e.g. acme linux 1, or an ecosystem, e.g. npm
+	Platform        string `json:"platform"`
+	Package         string `json:"package"`
+	VulnerabilityID string `json:"vulnerabilityID"`
+	FixedVersion    string `json:"fixedVersion,omitempty"`
+	// Severity is the one of the erratum, e.g. HIGH, which may differ from the one of the vulnerability
+	Severity string `json:"severity,omitempty"`
+	// VendorIDs are the IDs of the bulletins the advisory comes from, e.g. ACME-2019-0001
+	VendorIDs []string `json:"vendorIDs,omitempty"`
+	// AffectedVersions and VulnerableVersions are the ones of types.Advisory, e.g. of OSV and GHSA feeds
+	AffectedVersions   []VersionRange `json:"affectedVersions,omitempty"`
+	VulnerableVersions []string       `json:"vulnerableVersions,omitempty"`
+}
+
+// VersionRange is a range of affected versions, see types.VersionRange
+type VersionRange struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"lastAffected,omitempty"`
+}
+
+// Vulnerability is the details of a vulnerability as the plugin rates it
+type Vulnerability struct {
+	ID           string   `json:"id"`
+	Severity     string   `json:"severity,omitempty"` // e.g. HIGH, UNKNOWN if empty
+	CvssScore    float64  `json:"cvssScore,omitempty"`
+	CvssVector   string   `json:"cvssVector,omitempty"`
+	CvssScoreV3  float64  `json:"cvssScoreV3,omitempty"`
+	CvssVectorV3 string   `json:"cvssVectorV3,omitempty"`
+	CvssScoreV4  float64  `json:"cvssScoreV4,omitempty"`
+	CvssVectorV4 string   `json:"cvssVectorV4,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	References   []string `json:"references,omitempty"`
+}
+
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+}
+
+// VulnSrc is a source whose advisories are written by an executable
+type VulnSrc struct {
+	dbc  operations
+	name string
+	path string
+}
+
+// NewVulnSrc returns the source named name running the executable at path, configured by opts
+func NewVulnSrc(name, path string, opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc:  db.Config{DataSource: name},
+		name: name,
+		path: path,
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source write to dbc rather than to the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vs.name
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
+	records, err := vs.run(ctx, dir)
+	if err != nil {
+		return err
+	}
+	err = vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
+		return vs.commit(tx, records)
+	})
+	if err != nil {
+		return xerrors.Errorf("batch update failed: %w", err)
+	}
+	return nil
+}
+
+// run runs the plugin and reads its records, which are only committed once it succeeded
+func (vs VulnSrc) run(ctx context.Context, dir string) ([]Record, error) {
+	request, err := json.Marshal(Request{Name: vs.name, CacheDir: dir})
+	if err != nil {
+		return nil, err
+	}
+	// the plugin is killed when its output is invalid
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, vs.path)
+	cmd.Stdin, cmd.Stderr = bytes.NewReader(request), os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open the output of %s: %w", vs.path, err)
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, xerrors.Errorf("failed to run %s: %w", vs.path, err)
+	}
+
+	records, err := read(ctx, stdout)
+	if err != nil {
+		cancel()
+		_ = cmd.Wait()
+		return nil, xerrors.Errorf("invalid output of %s: %w", vs.path, err)
+	}
+	if err = cmd.Wait(); err != nil {
+		return nil, xerrors.Errorf("%s failed: %w", vs.path, err)
+	}
+	return records, nil
+}
+
+// read decodes the records of the output of a plugin and checks them
+func read(ctx context.Context, r io.Reader) ([]Record, error) {
+	progress := log.ProgressFrom(ctx)
+	var records []Record
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var rec Record
+		if err := dec.Decode(&rec); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, xerrors.Errorf("invalid record %d: %w", line, err)
+		}
+		if err := rec.validate(); err != nil {
+			return nil, xerrors.Errorf("record %d: %w", line, err)
+		}
+		records = append(records, rec)
+		progress.AddFiles(1)
+	}
+}
+
+func (rec Record) validate() error {
+	switch {
+	case (rec.Advisory == nil) == (rec.Vulnerability == nil):
+		return xerrors.New("expected either an advisory or a vulnerability")
+	case rec.Advisory != nil:
+		a := rec.Advisory
+		if a.Platform == "" || a.Package == "" || a.VulnerabilityID == "" {
+			return xerrors.New("an advisory needs a platform, a package and a vulnerability ID")
+		}
+		if a.Severity != "" {
+			if _, err := types.NewSeverity(a.Severity); err != nil {
+				return err
+			}
+		}
+	case rec.Vulnerability.ID == "":
+		return xerrors.New("a vulnerability needs an ID")
+	case rec.Vulnerability.Severity != "":
+		if _, err := types.NewSeverity(rec.Vulnerability.Severity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (vs VulnSrc) commit(tx db.Tx, records []Record) error {
+	for _, rec := range records {
+		if a := rec.Advisory; a != nil {
+			advisory := types.Advisory{FixedVersion: a.FixedVersion, VendorIDs: a.VendorIDs,
+				VulnerableVersions: a.VulnerableVersions}
+			if a.Severity != "" {
+				advisory.Severity, _ = types.NewSeverity(a.Severity)
+			}
+			for _, r := range a.AffectedVersions {
+				advisory.AffectedVersions = append(advisory.AffectedVersions, types.VersionRange(r))
+			}
+			if err := vs.dbc.PutAdvisory(tx, a.Platform, a.Package, a.VulnerabilityID, advisory); err != nil {
+				return xerrors.Errorf("failed to save the advisory: %w", err)
+			}
+			continue
+		}
+
+		v := rec.Vulnerability
+		severity := types.SeverityUnknown
+		if v.Severity != "" {
+			severity, _ = types.NewSeverity(v.Severity)
+		}
+		detail := types.VulnerabilityDetail{
+			ID:           v.ID,
+			CvssScore:    v.CvssScore,
+			CvssVector:   v.CvssVector,
+			CvssScoreV3:  v.CvssScoreV3,
+			CvssVectorV3: v.CvssVectorV3,
+			CvssScoreV4:  v.CvssScoreV4,
+			CvssVectorV4: v.CvssVectorV4,
+			Severity:     severity,
+			References:   v.References,
+			Title:        v.Title,
+			Description:  v.Description,
+		}
+		if err := vs.dbc.PutVulnerabilityDetail(tx, v.ID, vs.name, detail); err != nil {
+			return xerrors.Errorf("failed to save the vulnerability detail: %w", err)
+		}
+		if err := vs.dbc.PutSeverity(tx, v.ID, types.SeverityUnknown); err != nil {
+			return xerrors.Errorf("failed to save the vulnerability severity: %w", err)
+		}
+	}
+	return nil
+}
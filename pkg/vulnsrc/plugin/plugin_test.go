@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func TestVulnSrc_Update(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{
+			name: "happy path",
+			path: "testdata/feed.sh",
+		},
+		{
+			name:    "advisory without vulnerability ID",
+			path:    "testdata/invalid.sh",
+			wantErr: "record 1: an advisory needs a platform, a package and a vulnerability ID",
+		},
+		{
+			name:    "unknown severity",
+			path:    "testdata/severity.sh",
+			wantErr: "unknown severity: SEVERE",
+		},
+		{
+			name:    "exit status",
+			path:    "testdata/fail.sh",
+			wantErr: "testdata/fail.sh failed: exit status 1",
+		},
+		{
+			name:    "missing executable",
+			path:    "testdata/missing.sh",
+			wantErr: "failed to run testdata/missing.sh",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestVulnSrc_Update_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+			assert.NoError(t, db.Init(d))
+			defer db.Close()
+
+			vs := NewVulnSrc("acme", tt.path)
+			err = vs.Update(context.Background(), filepath.Join(d, "cache"))
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			dbc := db.Config{DataSource: "acme"}
+			advisories, err := dbc.GetAdvisories("acme linux 1", "openssl")
+			assert.NoError(t, err)
+			assert.Equal(t, []types.Advisory{{VulnerabilityID: "CVE-2019-0001", FixedVersion: "1.1.1d-1",
+				Severity: types.SeverityMedium, VendorIDs: []string{"ACME-2019-0001"}}}, advisories)
+			advisories, err = dbc.GetAdvisories("acme linux 1", "curl")
+			assert.NoError(t, err)
+			assert.Equal(t, []types.Advisory{{VulnerabilityID: "CVE-2019-0002",
+				AffectedVersions: []types.VersionRange{{Introduced: "7.50.0", Fixed: "7.52.1-5"}}}}, advisories)
+
+			details, err := dbc.GetVulnerabilityDetails("CVE-2019-0001")
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]types.VulnerabilityDetail{
+				"acme": {ID: "CVE-2019-0001", Severity: types.SeverityHigh, CvssScoreV3: 7.5, Title: "padding oracle"},
+			}, details)
+		})
+	}
+}
@@ -0,0 +1,4 @@
+#!/bin/sh
+cat > /dev/null
+echo 'the feed is unavailable' >&2
+exit 1
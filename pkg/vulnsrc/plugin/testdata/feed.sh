@@ -0,0 +1,6 @@
+#!/bin/sh
+# the request is read, as a plugin would
+cat > /dev/null
+echo '{"advisory": {"platform": "acme linux 1", "package": "openssl", "vulnerabilityID": "CVE-2019-0001", "fixedVersion": "1.1.1d-1", "severity": "MEDIUM", "vendorIDs": ["ACME-2019-0001"]}}'
+echo '{"advisory": {"platform": "acme linux 1", "package": "curl", "vulnerabilityID": "CVE-2019-0002", "affectedVersions": [{"introduced": "7.50.0", "fixed": "7.52.1-5"}]}}'
+echo '{"vulnerability": {"id": "CVE-2019-0001", "severity": "HIGH", "cvssScoreV3": 7.5, "title": "padding oracle"}}'
@@ -0,0 +1,3 @@
+#!/bin/sh
+cat > /dev/null
+echo '{"vulnerability": {"id": "CVE-2019-0001", "severity": "SEVERE"}}'
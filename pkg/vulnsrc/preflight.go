@@ -0,0 +1,171 @@
+package vulnsrc
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+)
+
+// layout is where a source reads its inputs in the cache dir
+type layout struct {
+	dir string // relative to the cache dir
+	ext string // of the input files, whose format is the one the extension says
+	// manual is how the inputs get there when they aren't in a repository fetch clones
+	manual string
+}
+
+var layouts = map[string]layout{
+	vulnerability.Nvd:                   {dir: filepath.Join("vuln-list", "nvd"), ext: ".json"},
+	vulnerability.Alpine:                {dir: filepath.Join("vuln-list", "alpine"), ext: ".json"},
+	vulnerability.RedHat:                {dir: filepath.Join("vuln-list", "redhat"), ext: ".json"},
+	vulnerability.RedHatOVAL:            {dir: filepath.Join("vuln-list", "oval", "redhat"), ext: ".json"},
+	vulnerability.Debian:                {dir: filepath.Join("vuln-list", "debian"), ext: ".json"},
+	vulnerability.DebianOVAL:            {dir: filepath.Join("vuln-list", "oval", "debian"), ext: ".json"},
+	vulnerability.Ubuntu:                {dir: filepath.Join("vuln-list", "ubuntu"), ext: ".json"},
+	vulnerability.Amazon:                {dir: filepath.Join("vuln-list", "amazon"), ext: ".json"},
+	vulnerability.OracleOVAL:            {dir: filepath.Join("vuln-list", "oval", "oracle"), ext: ".json"},
+	vulnerability.RubySec:               {dir: filepath.Join("ruby-advisory-db", "gems"), ext: ".yml"},
+	vulnerability.PhpSecurityAdvisories: {dir: "php-security-advisories", ext: ".yaml"},
+	vulnerability.NodejsSecurityWg:      {dir: filepath.Join("nodejs-security-wg", "vuln"), ext: ".json"},
+	vulnerability.PythonSafetyDB:        {dir: filepath.Join("python-safety-db", "data"), ext: ".json"},
+	vulnerability.RustSec:               {dir: filepath.Join("rust-advisory-db", "crates"), ext: ".toml"},
+	vulnerability.Vulnrichment:          {dir: "vulnrichment", ext: ".json"},
+	vulnerability.BDU: {dir: "bdu", ext: ".xml",
+		manual: "download vulxml.zip from bdu.fstec.ru and unzip export.xml into it"},
+	vulnerability.SSVC: {dir: "ssvc", ext: ".json", manual: "write the decision points into it"},
+}
+
+// Preflight checks the inputs of the targets before they are updated: the dir of each source exists, has at
+// least the minimum number of files of its options and the sample files spread over them parse. The
+// sources failing a check are reported by their failure policy, as their update would fail, and the
+// others are returned. Unlike the errors of an update half way through a walk, a failure says how to fix it.
+func (u Updater) Preflight(targets []string, sample int) ([]string, error) {
+	var ok []string
+	failed := SourceErrors{}
+	for _, target := range targets {
+		l, known := layouts[target]
+		if !known {
+			ok = append(ok, target)
+			continue
+		}
+		options := u.sourceOptions(target)
+		if err := l.check(options.CacheDir, options.MinFiles, sample); err != nil {
+			failed[target] = xerrors.Errorf("preflight of %s failed: %w", target, err)
+			continue
+		}
+		ok = append(ok, target)
+	}
+	if err := u.reportFailures(failed); err != nil {
+		return nil, err
+	}
+	log.OrDefault(u.logger).Info("Checked the inputs of the sources", "sources", len(targets), "failed", len(failed))
+	return ok, nil
+}
+
+func (l layout) check(cacheDir string, minFiles, sample int) error {
+	dir := filepath.Join(cacheDir, l.dir)
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		if l.manual != "" {
+			return xerrors.Errorf("%s doesn't exist: %s", dir, l.manual)
+		}
+		return xerrors.Errorf("%s doesn't exist: fetch it with the fetch command or build with --fetch, "+
+			"or set the cache dir of the source to a checkout", dir)
+	} else if err != nil {
+		return xerrors.Errorf("failed to stat %s: %w", dir, err)
+	} else if !info.IsDir() {
+		return xerrors.Errorf("%s isn't a directory", dir)
+	}
+
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() && info.Size() > 0 && strings.HasSuffix(info.Name(), l.ext) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to walk %s: %w", dir, err)
+	}
+	if minFiles < 1 {
+		minFiles = 1
+	}
+	if len(files) < minFiles {
+		return xerrors.Errorf("%s has %d %s file(s), fewer than %d: the checkout may be incomplete, fetch it again",
+			dir, len(files), l.ext, minFiles)
+	}
+
+	for _, path := range sampleFiles(files, sample) {
+		if err = parseFile(path, l.ext); err != nil {
+			return xerrors.Errorf("%s is malformed, the checkout may be corrupted or of another layout: %w",
+				path, err)
+		}
+	}
+	return nil
+}
+
+// sampleFiles returns n files spread evenly over files
+func sampleFiles(files []string, n int) []string {
+	if n >= len(files) {
+		return files
+	}
+	var sample []string
+	for i := 0; i < n; i++ {
+		sample = append(sample, files[i*len(files)/n])
+	}
+	return sample
+}
+
+// parseFile checks the file parses in the format of its extension. Only the root element of an XML file,
+// which may be huge, is parsed.
+func parseFile(path, ext string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return xerrors.Errorf("failed to open: %w", err)
+	}
+	defer f.Close()
+
+	if ext == ".xml" {
+		d := xml.NewDecoder(f)
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return xerrors.Errorf("invalid XML: %w", err)
+			}
+			if _, ok := tok.(xml.StartElement); ok {
+				return nil
+			}
+		}
+	}
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return xerrors.Errorf("failed to read: %w", err)
+	}
+	var v interface{}
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(b, &v)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(b, &v)
+	case ".toml":
+		_, err = toml.Decode(string(b), &map[string]interface{}{})
+	}
+	if err != nil {
+		return xerrors.Errorf("invalid %s: %w", strings.TrimPrefix(ext, "."), err)
+	}
+	return nil
+}
@@ -1,17 +1,18 @@
 package python
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
 
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
-
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
@@ -40,26 +41,60 @@ type Advisory struct {
 	Specs           []string `json:",omitempty"`
 }
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc      operations
+	cacheDir string
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "python-safety-db"})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.PythonSafetyDB},
+	}
+	for _, opt := range opts {
+		opt(&vs)
 	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.PythonSafetyDB
 }
 
-func (vs VulnSrc) Update(dir string) (err error) {
+func (vs VulnSrc) Update(ctx context.Context, dir string) (err error) {
+	vs.cacheDir = dir
 	repoPath = filepath.Join(dir, pythonDir)
-	if err := vs.update(repoPath); err != nil {
+	if err := vs.update(ctx, repoPath); err != nil {
 		return xerrors.Errorf("failed to update python vulnerabilities: %w", err)
 	}
 	return nil
 }
 
-func (vs VulnSrc) update(repoPath string) error {
-	f, err := os.Open(filepath.Join(repoPath, "data", "insecure_full.json"))
+func (vs VulnSrc) update(ctx context.Context, repoPath string) error {
+	path := filepath.Join(repoPath, "data", "insecure_full.json")
+	f, err := os.Open(path)
 	if err != nil {
 		return xerrors.Errorf("failed to open the file: %w", err)
 	}
@@ -67,13 +102,18 @@ func (vs VulnSrc) update(repoPath string) error {
 
 	// for detecting vulnerabilities
 	var advisoryDB AdvisoryDB
-	if err = json.NewDecoder(f).Decode(&advisoryDB); err != nil {
-		return xerrors.Errorf("failed to decode JSON: %w", err)
+	pr := utils.NewProvenanceReader(f, vs.cacheDir, path)
+	if err = json.NewDecoder(pr).Decode(&advisoryDB); err != nil {
+		return &types.DecodeError{Source: vulnerability.PythonSafetyDB, Path: path, Err: err}
+	}
+	provenance, err := pr.Provenance()
+	if err != nil {
+		return xerrors.Errorf("failed to read the file: %w", err)
 	}
 
 	// for displaying vulnerability detail
-	err = vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
-		if err := vs.commit(tx, advisoryDB); err != nil {
+	err = vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
+		if err := vs.commit(tx, advisoryDB, provenance); err != nil {
 			return xerrors.Errorf("failed to save python vulnerabilities: %w", err)
 		}
 		return nil
@@ -84,7 +124,8 @@ func (vs VulnSrc) update(repoPath string) error {
 	return nil
 }
 
-func (vs VulnSrc) commit(tx *bolt.Tx, advisoryDB AdvisoryDB) error {
+// commit saves the advisories of the single file of the Safety DB, so they share its provenance
+func (vs VulnSrc) commit(tx db.Tx, advisoryDB AdvisoryDB, provenance types.Provenance) error {
 	for pkgName, advisories := range advisoryDB {
 		for _, advisory := range advisories {
 			vulnerabilityID := advisory.Cve
@@ -98,6 +139,9 @@ func (vs VulnSrc) commit(tx *bolt.Tx, advisoryDB AdvisoryDB) error {
 			if err != nil {
 				return xerrors.Errorf("failed to save python advisory: %w", err)
 			}
+			if err = vs.dbc.PutProvenance(tx, vulnerability.PythonSafetyDB, pkgName, vulnerabilityID, provenance); err != nil {
+				return xerrors.Errorf("failed to save python provenance: %w", err)
+			}
 
 			// to display vulnerability detail
 			vuln := types.VulnerabilityDetail{
@@ -115,20 +159,22 @@ func (vs VulnSrc) commit(tx *bolt.Tx, advisoryDB AdvisoryDB) error {
 	return nil
 }
 
-func (vs VulnSrc) Get(pkgName string) ([]Advisory, error) {
-	advisories, err := vs.dbc.ForEachAdvisory(vulnerability.PythonSafetyDB, pkgName)
-	if err != nil {
-		return nil, xerrors.Errorf("failed to iterate python vulnerabilities: %w", err)
+func (vs VulnSrc) Get(ctx context.Context, pkgName string) ([]Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
 	}
-
 	var results []Advisory
-	for vulnID, a := range advisories {
+	err := vs.dbc.ForEachAdvisory(vulnerability.PythonSafetyDB, pkgName, func(_ *types.DataSource, vulnID string, v []byte) error {
 		var advisory Advisory
-		if err = json.Unmarshal(a, &advisory); err != nil {
-			return nil, xerrors.Errorf("failed to unmarshal advisory JSON: %w", err)
+		if err := db.Unmarshal(v, &advisory); err != nil {
+			return xerrors.Errorf("failed to unmarshal advisory: %w", err)
 		}
 		advisory.VulnerabilityID = vulnID
 		results = append(results, advisory)
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to iterate python vulnerabilities: %w", err)
 	}
 	return results, nil
 }
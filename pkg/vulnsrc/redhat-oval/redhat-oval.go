@@ -1,52 +1,115 @@
 package redhatoval
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"path/filepath"
 	"regexp"
 	"strings"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
 	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
 var (
 	redhatDir = filepath.Join("oval", "redhat")
 
-	// the same bucket name as Red Hat Security Data API
-	platformFormat = "Red Hat Enterprise Linux %s"
-
 	supportedPlatform = []string{"5", "6", "7", "8"}
 	platformRegexp    = regexp.MustCompile(`Red Hat Enterprise Linux (\d)`)
 )
 
+const archPrefix = "Red Hat Enterprise Linux arch is "
+
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc      operations
+	logger   *slog.Logger
+	releases []string // nil for supportedPlatform
+}
+
+var _ registry.AdvisoryGetter = VulnSrc{}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vuln-list"})
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.RedHatOVAL},
+	}
+	for _, opt := range opts {
+		opt(&vs)
 	}
+	return vs
 }
 
-func (vs VulnSrc) Update(dir string) error {
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
+	}
+}
+
+// WithReleases makes the source keep the advisories of releases, e.g. 9, rather than of 5 to 8
+func WithReleases(releases ...string) Option {
+	return func(vs *VulnSrc) {
+		vs.releases = releases
+	}
+}
+
+// supported returns whether the source keeps the advisories of release
+func (vs VulnSrc) supported(release string) bool {
+	if vs.releases == nil {
+		return utils.StringInSlice(release, supportedPlatform)
+	}
+	return utils.StringInSlice(release, vs.releases)
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.RedHatOVAL
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
 	rootDir := filepath.Join(dir, "vuln-list", redhatDir)
 
 	var advisories []RedhatOVAL
-	err := utils.FileWalk(rootDir, func(r io.Reader, path string) error {
+	err := utils.FileWalk(ctx, rootDir, func(r io.Reader, path string) error {
 		var advisory RedhatOVAL
-		if err := json.NewDecoder(r).Decode(&advisory); err != nil {
-			return xerrors.Errorf("failed to decode Red Hat OVAL JSON: %w", err)
+		pr := utils.NewProvenanceReader(r, dir, path)
+		if err := json.NewDecoder(pr).Decode(&advisory); err != nil {
+			return &types.DecodeError{Source: vulnerability.RedHatOVAL, Path: path, Err: err}
+		}
+		provenance, err := pr.Provenance()
+		if err != nil {
+			return xerrors.Errorf("failed to read %s: %w", path, err)
 		}
+		advisory.Provenance = provenance
 		advisories = append(advisories, advisory)
 		return nil
 	})
@@ -54,7 +117,7 @@ func (vs VulnSrc) Update(dir string) error {
 		return xerrors.Errorf("error in Red Hat OVAL walk: %w", err)
 	}
 
-	if err = vs.save(advisories); err != nil {
+	if err = vs.save(ctx, advisories); err != nil {
 		return xerrors.Errorf("error in Red Hat OVAL save: %w", err)
 	}
 
@@ -62,7 +125,13 @@ func (vs VulnSrc) Update(dir string) error {
 }
 
 // fromhttps://github.com/kotakanbe/goval-dictionary/blob/eff7f862637c3536b5ffef5a255bd1dd2779f582/models/redhat.go
-func (vs VulnSrc) walkRedhat(cri Criteria, pkgs []Package) []Package {
+func (vs VulnSrc) walkRedhat(cri Criteria, arch string, pkgs []Package) []Package {
+	for _, c := range cri.Criterions {
+		// e.g. Red Hat Enterprise Linux arch is aarch64, like in Oracle Linux OVAL
+		if strings.HasPrefix(c.Comment, archPrefix) {
+			arch = strings.TrimPrefix(c.Comment, archPrefix)
+		}
+	}
 	for _, c := range cri.Criterions {
 		// e.g. firefox is earlier than 0:60.6.1-1.el8
 		ss := strings.Split(c.Comment, " is earlier than ")
@@ -73,6 +142,7 @@ func (vs VulnSrc) walkRedhat(cri Criteria, pkgs []Package) []Package {
 		pkgs = append(pkgs, Package{
 			Name:         ss[0],
 			FixedVersion: strings.TrimSpace(ss[1]),
+			Arch:         arch,
 		})
 	}
 
@@ -80,14 +150,14 @@ func (vs VulnSrc) walkRedhat(cri Criteria, pkgs []Package) []Package {
 		return pkgs
 	}
 	for _, c := range cri.Criterias {
-		pkgs = vs.walkRedhat(c, pkgs)
+		pkgs = vs.walkRedhat(c, arch, pkgs)
 	}
 	return pkgs
 }
 
-func (vs VulnSrc) save(advisories []RedhatOVAL) error {
-	log.Println("Saving Red Hat OVAL")
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
+func (vs VulnSrc) save(ctx context.Context, advisories []RedhatOVAL) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.RedHatOVAL)
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
 		return vs.commit(tx, advisories)
 	})
 	if err != nil {
@@ -96,36 +166,67 @@ func (vs VulnSrc) save(advisories []RedhatOVAL) error {
 	return nil
 }
 
-func (vs VulnSrc) commit(tx *bolt.Tx, advisories []RedhatOVAL) error {
+func (vs VulnSrc) commit(tx db.Tx, advisories []RedhatOVAL) error {
 	for _, advisory := range advisories {
+		provenance := advisory.Provenance
 		platforms := vs.getPlatforms(advisory.Affecteds)
 		if len(platforms) != 1 {
-			log.Printf("Invalid advisory: %s\n", advisory.ID)
+			log.OrDefault(vs.logger).Warn("Invalid advisory", "source", vulnerability.RedHatOVAL, "id", advisory.ID)
 			continue
 		}
-		platformName := fmt.Sprintf(platformFormat, platforms[0])
-		affectedPkgs := vs.walkRedhat(advisory.Criteria, []Package{})
-		for _, affectedPkg := range affectedPkgs {
+		// the same source as Red Hat Security Data API, the advisories are kept apart by the data source
+		platformName := db.RedHat.Source(platforms[0])
+		// a package is fixed on each architecture, in versions which may differ
+		var pkgNames []string
+		fixes := map[string][]utils.ArchFix{}
+		for _, pkg := range vs.walkRedhat(advisory.Criteria, "", []Package{}) {
+			if _, ok := fixes[pkg.Name]; !ok {
+				pkgNames = append(pkgNames, pkg.Name)
+			}
+			fixes[pkg.Name] = append(fixes[pkg.Name], utils.ArchFix{Arch: pkg.Arch, FixedVersion: pkg.FixedVersion})
+		}
+		// e.g. RHSA-2019:0966, the other references being the CVEs
+		var vendorIDs []string
+		for _, ref := range advisory.References {
+			if ref.Source == "RHSA" {
+				vendorIDs = append(vendorIDs, ref.RefID)
+			}
+		}
+		for _, pkgName := range pkgNames {
 			for _, cve := range advisory.Advisory.Cves {
-				advisory := types.Advisory{
-					FixedVersion: affectedPkg.FixedVersion,
-				}
-				if err := vs.dbc.PutAdvisory(tx, platformName, affectedPkg.Name, cve.CveID, advisory); err != nil {
+				advisory := utils.ArchAdvisory(fixes[pkgName])
+				advisory.VendorIDs = vendorIDs
+				if err := vs.dbc.PutAdvisory(tx, platformName, pkgName, cve.CveID, advisory); err != nil {
 					return xerrors.Errorf("failed to save Red Hat OVAL advisory: %w", err)
 				}
+				if err := vs.dbc.PutProvenance(tx, platformName, pkgName, cve.CveID, provenance); err != nil {
+					return xerrors.Errorf("failed to save Red Hat OVAL provenance: %w", err)
+				}
 			}
 		}
 	}
 	return nil
 }
 
-func (vs VulnSrc) Get(release string, pkgName string) ([]types.Advisory, error) {
-	bucket := fmt.Sprintf(platformFormat, release)
+func (vs VulnSrc) Get(ctx context.Context, release string, pkgName string, opts ...types.GetOption) ([]types.Advisory, error) {
+	return vs.GetForArch(ctx, release, pkgName, "", opts...)
+}
+
+// GetForArch returns the advisories of a package affecting an architecture, e.g. aarch64, with its fixed
+// version, all of them if arch is empty
+func (vs VulnSrc) GetForArch(ctx context.Context, release, pkgName, arch string, opts ...types.GetOption) ([]types.Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
+	}
+	bucket := db.RedHat.Source(release)
+	if !vs.supported(release) {
+		return nil, xerrors.Errorf("%s: %w", bucket, types.ErrUnsupportedRelease)
+	}
 	advisories, err := vs.dbc.GetAdvisories(bucket, pkgName)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get Alpine advisories: %w", err)
 	}
-	return advisories, nil
+	return types.AdvisoriesByFix(types.AdvisoriesForArch(advisories, arch), types.NewGetOptions(opts...).Fix), nil
 }
 
 func (vs VulnSrc) getPlatforms(affectedList []Affected) []string {
@@ -137,7 +238,7 @@ func (vs VulnSrc) getPlatforms(affectedList []Affected) []string {
 				continue
 			}
 			majorVersion := match[1]
-			if !utils.StringInSlice(majorVersion, supportedPlatform) {
+			if !vs.supported(majorVersion) {
 				continue
 			}
 			platforms = append(platforms, majorVersion)
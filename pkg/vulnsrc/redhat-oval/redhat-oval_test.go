@@ -1,31 +1,29 @@
 package redhatoval
 
 import (
+	"context"
 	"errors"
-	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/aquasecurity/trivy-db/pkg/storage"
 	"github.com/aquasecurity/trivy-db/pkg/types"
-	bolt "github.com/etcd-io/bbolt"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
-	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnsrctest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/xerrors"
 )
 
-func TestMain(m *testing.M) {
-	utils.Quiet = true
-	os.Exit(m.Run())
-}
-
 func TestVulnSrc_Update(t *testing.T) {
 	testCases := []struct {
 		name             string
 		cacheDir         string
 		batchUpdateErr   error
 		expectedErrorMsg string
+		decodeError      bool
 	}{
 		{
 			name:     "happy path",
@@ -34,7 +32,8 @@ func TestVulnSrc_Update(t *testing.T) {
 		{
 			name:             "broken JSON",
 			cacheDir:         filepath.Join("testdata", "sad"),
-			expectedErrorMsg: "failed to decode Red Hat OVAL JSON",
+			expectedErrorMsg: "failed to decode testdata/sad/vuln-list/oval/redhat/RHSA-2019-0966.json of redhat-oval",
+			decodeError:      true,
 		},
 		{
 			name:             "BatchUpdate returns an error",
@@ -46,14 +45,16 @@ func TestVulnSrc_Update(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("BatchUpdate", mock.Anything).Return(tc.batchUpdateErr)
-			ac := VulnSrc{dbc: mockDBConfig}
+			mockBatchUpdater := new(db.MockBatchUpdater)
+			mockBatchUpdater.On("BatchUpdate", mock.Anything, mock.Anything).Return(tc.batchUpdateErr)
+			ac := VulnSrc{dbc: vulnsrctest.MockOperations{MockBatchUpdater: mockBatchUpdater}, logger: log.Discard()}
 
-			err := ac.Update(tc.cacheDir)
+			err := ac.Update(context.Background(), tc.cacheDir)
 			switch {
 			case tc.expectedErrorMsg != "":
 				assert.Contains(t, err.Error(), tc.expectedErrorMsg, tc.name)
+				var decodeErr *types.DecodeError
+				assert.Equal(t, tc.decodeError, xerrors.As(err, &decodeErr), tc.name)
 			default:
 				assert.NoError(t, err, tc.name)
 			}
@@ -87,6 +88,10 @@ func TestVulnSrc_Commit(t *testing.T) {
 					Affecteds: []Affected{
 						{Platforms: []string{"Red Hat Enterprise Linux 8"}},
 					},
+					References: []Reference{
+						{Source: "RHSA", RefID: "RHSA-2015:2237"},
+						{Source: "CVE", RefID: "CVE-2015-2675"},
+					},
 					Advisory: Advisory{
 						Severity: "",
 						Cves: []Cve{
@@ -135,7 +140,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Red Hat Enterprise Linux 8",
 						pkgName:  "rest",
 						cveID:    "CVE-2015-2675",
-						advisory: types.Advisory{FixedVersion: "0:0.7.92-3.el7"},
+						advisory: types.Advisory{FixedVersion: "0:0.7.92-3.el7", VendorIDs: []string{"RHSA-2015:2237"}},
 					},
 				},
 				{
@@ -143,7 +148,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Red Hat Enterprise Linux 8",
 						pkgName:  "rest",
 						cveID:    "CVE-2015-2676",
-						advisory: types.Advisory{FixedVersion: "0:0.7.92-3.el7"},
+						advisory: types.Advisory{FixedVersion: "0:0.7.92-3.el7", VendorIDs: []string{"RHSA-2015:2237"}},
 					},
 				},
 				{
@@ -151,7 +156,7 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Red Hat Enterprise Linux 8",
 						pkgName:  "rest-devel",
 						cveID:    "CVE-2015-2675",
-						advisory: types.Advisory{FixedVersion: "0:0.7.92-3.el7"},
+						advisory: types.Advisory{FixedVersion: "0:0.7.92-3.el7", VendorIDs: []string{"RHSA-2015:2237"}},
 					},
 				},
 				{
@@ -159,7 +164,66 @@ func TestVulnSrc_Commit(t *testing.T) {
 						source:   "Red Hat Enterprise Linux 8",
 						pkgName:  "rest-devel",
 						cveID:    "CVE-2015-2676",
-						advisory: types.Advisory{FixedVersion: "0:0.7.92-3.el7"},
+						advisory: types.Advisory{FixedVersion: "0:0.7.92-3.el7", VendorIDs: []string{"RHSA-2015:2237"}},
+					},
+				},
+			},
+		},
+		{
+			name: "fixed in other versions on the architectures",
+			advisories: []RedhatOVAL{
+				{
+					ID: "oval:com.redhat.rhsa:def:20200001",
+					Affecteds: []Affected{
+						{Platforms: []string{"Red Hat Enterprise Linux 8"}},
+					},
+					References: []Reference{
+						{Source: "RHSA", RefID: "RHSA-2020:0001"},
+					},
+					Advisory: Advisory{
+						Cves: []Cve{{CveID: "CVE-2020-0001"}},
+					},
+					Criteria: Criteria{
+						Operator: "AND",
+						Criterias: []Criteria{
+							{
+								Operator: "OR",
+								Criterias: []Criteria{
+									{
+										Operator: "AND",
+										Criterions: []Criterion{
+											{Comment: "Red Hat Enterprise Linux arch is aarch64"},
+											{Comment: "kernel is earlier than 0:4.18.0-147.5.1.el8_1"},
+										},
+									},
+									{
+										Operator: "AND",
+										Criterions: []Criterion{
+											{Comment: "Red Hat Enterprise Linux arch is x86_64"},
+											{Comment: "kernel is earlier than 0:4.18.0-147.3.1.el8_1"},
+										},
+									},
+								},
+							},
+						},
+						Criterions: []Criterion{
+							{Comment: "Red Hat Enterprise Linux 8 is installed"},
+						},
+					},
+				},
+			},
+			putAdvisoryList: []putAdvisory{
+				{
+					input: putAdvisoryInput{
+						source:  "Red Hat Enterprise Linux 8",
+						pkgName: "kernel",
+						cveID:   "CVE-2020-0001",
+						advisory: types.Advisory{
+							FixedVersion:      "0:4.18.0-147.5.1.el8_1",
+							VendorIDs:         []string{"RHSA-2020:0001"},
+							Arches:            []string{"aarch64", "x86_64"},
+							ArchFixedVersions: map[string]string{"x86_64": "0:4.18.0-147.3.1.el8_1"},
+						},
 					},
 				},
 			},
@@ -232,14 +296,19 @@ func TestVulnSrc_Commit(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			tx := &bolt.Tx{}
-			mockDBConfig := new(db.MockDBConfig)
+			tx := &storage.MockTx{}
+			mockAdvisoryStore := new(db.MockAdvisoryStore)
+			mockProvenanceStore := new(db.MockProvenanceStore)
 			for _, pa := range tc.putAdvisoryList {
-				mockDBConfig.On("PutAdvisory", tx, pa.input.source, pa.input.pkgName,
+				mockAdvisoryStore.On("PutAdvisory", tx, pa.input.source, pa.input.pkgName,
 					pa.input.cveID, pa.input.advisory).Return(pa.output)
+				if pa.output == nil {
+					mockProvenanceStore.On("PutProvenance", tx, pa.input.source, pa.input.pkgName,
+						pa.input.cveID, mock.Anything).Return(nil)
+				}
 			}
 
-			ac := VulnSrc{dbc: mockDBConfig}
+			ac := VulnSrc{dbc: vulnsrctest.MockOperations{MockAdvisoryStore: mockAdvisoryStore, MockProvenanceStore: mockProvenanceStore}, logger: log.Discard()}
 			err := ac.commit(tx, tc.advisories)
 
 			switch {
@@ -248,7 +317,8 @@ func TestVulnSrc_Commit(t *testing.T) {
 			default:
 				assert.NoError(t, err, tc.name)
 			}
-			mockDBConfig.AssertExpectations(t)
+			mockAdvisoryStore.AssertExpectations(t)
+			mockProvenanceStore.AssertExpectations(t)
 		})
 	}
 }
@@ -270,8 +340,11 @@ func TestVulnSrc_Get(t *testing.T) {
 		name               string
 		release            string
 		pkgName            string
+		arch               string
 		getAdvisories      getAdvisories
 		expectedErrorMsg   string
+		unsupported        bool
+		canceled           bool
 		expectedAdvisories []types.Advisory
 	}{
 		{
@@ -299,6 +372,30 @@ func TestVulnSrc_Get(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "advisories of an architecture",
+			release: "8",
+			pkgName: "kernel",
+			arch:    "aarch64",
+			getAdvisories: getAdvisories{
+				input: getAdvisoriesInput{
+					bucket:  "Red Hat Enterprise Linux 8",
+					pkgName: "kernel",
+				},
+				output: getAdvisoriesOutput{
+					advisories: []types.Advisory{
+						{VulnerabilityID: "CVE-2020-0001", FixedVersion: "0:4.18.0-147.5.1.el8_1", Arches: []string{"aarch64", "x86_64"},
+							ArchFixedVersions: map[string]string{"x86_64": "0:4.18.0-147.3.1.el8_1"}},
+						{VulnerabilityID: "CVE-2020-0002", FixedVersion: "0:0.1.2", Arches: []string{"x86_64"}},
+						{VulnerabilityID: "CVE-2020-0003", FixedVersion: "0:0.1.3"},
+					},
+				},
+			},
+			expectedAdvisories: []types.Advisory{
+				{VulnerabilityID: "CVE-2020-0001", FixedVersion: "0:4.18.0-147.5.1.el8_1", Arches: []string{"aarch64"}},
+				{VulnerabilityID: "CVE-2020-0003", FixedVersion: "0:0.1.3", Arches: []string{"aarch64"}},
+			},
+		},
 		{
 			name:    "GetAdvisories returns an error",
 			release: "6",
@@ -315,28 +412,52 @@ func TestVulnSrc_Get(t *testing.T) {
 			expectedErrorMsg:   "failed to get advisories",
 			expectedAdvisories: nil,
 		},
+		{
+			name:               "unsupported release",
+			release:            "9",
+			pkgName:            "package",
+			expectedErrorMsg:   "Red Hat Enterprise Linux 9: unsupported release",
+			unsupported:        true,
+			expectedAdvisories: nil,
+		},
+		{
+			name:               "canceled lookup",
+			release:            "6",
+			pkgName:            "package",
+			expectedErrorMsg:   "lookup canceled",
+			canceled:           true,
+			expectedAdvisories: nil,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("GetAdvisories", tc.getAdvisories.input.bucket,
-				tc.getAdvisories.input.pkgName).Return(tc.getAdvisories.output.advisories,
-				tc.getAdvisories.output.err)
+			mockAdvisoryStore := new(db.MockAdvisoryStore)
+			if tc.getAdvisories.input.bucket != "" {
+				mockAdvisoryStore.On("GetAdvisories", tc.getAdvisories.input.bucket,
+					tc.getAdvisories.input.pkgName).Return(tc.getAdvisories.output.advisories,
+					tc.getAdvisories.output.err)
+			}
 
-			vs := VulnSrc{dbc: mockDBConfig}
-			advisories, err := vs.Get(tc.release, tc.pkgName)
+			vs := VulnSrc{dbc: vulnsrctest.MockOperations{MockAdvisoryStore: mockAdvisoryStore}, logger: log.Discard()}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.canceled {
+				cancel()
+			}
+			advisories, err := vs.GetForArch(ctx, tc.release, tc.pkgName, tc.arch)
 
 			switch {
 			case tc.expectedErrorMsg != "":
 				assert.Contains(t, err.Error(), tc.expectedErrorMsg, tc.name)
+				assert.Equal(t, tc.unsupported, xerrors.Is(err, types.ErrUnsupportedRelease), tc.name)
 			default:
 				assert.NoError(t, err, tc.name)
 			}
 
 			assert.ElementsMatch(t, advisories, tc.expectedAdvisories, tc.name)
 
-			mockDBConfig.AssertExpectations(t)
+			mockAdvisoryStore.AssertExpectations(t)
 		})
 	}
 }
@@ -1,5 +1,7 @@
 package redhatoval
 
+import "github.com/aquasecurity/trivy-db/pkg/types"
+
 type RedhatOVAL struct {
 	ID          string
 	Class       string
@@ -9,6 +11,8 @@ type RedhatOVAL struct {
 	Description string
 	Advisory    Advisory
 	Criteria    Criteria
+
+	Provenance types.Provenance `json:"-"`
 }
 
 type Criteria struct {
@@ -62,4 +66,5 @@ type Bugzilla struct {
 type Package struct {
 	Name         string
 	FixedVersion string
+	Arch         string // e.g. aarch64, empty if the definition has no architecture
 }
@@ -1,58 +1,116 @@
 package redhat
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
 	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
 const (
-	redhatDir      = "redhat"
-	platformFormat = "Red Hat Enterprise Linux %s"
+	redhatDir = "redhat"
 )
 
 var (
-	targetPlatforms = []string{"Red Hat Enterprise Linux 5", "Red Hat Enterprise Linux 6", "Red Hat Enterprise Linux 7", "Red Hat Enterprise Linux 8"}
-	targetStatus    = []string{"Affected", "Fix deferred", "Will not fix"}
+	targetReleases = []string{"5", "6", "7", "8"}
+	targetStatus   = []string{"Affected", "Fix deferred", "Will not fix"}
 )
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc      operations
+	logger   *slog.Logger
+	releases []string // nil for targetReleases
+}
+
+var _ registry.AdvisoryGetter = VulnSrc{}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vuln-list", Incremental: true})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.RedHat},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
 	}
 }
 
-func (vs VulnSrc) Update(dir string) error {
+// WithReleases makes the source keep the advisories of releases, e.g. 9, rather than of 5 to 8
+func WithReleases(releases ...string) Option {
+	return func(vs *VulnSrc) {
+		vs.releases = releases
+	}
+}
+
+// supported returns whether the source keeps the advisories of release
+func (vs VulnSrc) supported(release string) bool {
+	if vs.releases == nil {
+		return utils.StringInSlice(release, targetReleases)
+	}
+	return utils.StringInSlice(release, vs.releases)
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.RedHat
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
 	rootDir := filepath.Join(dir, "vuln-list", redhatDir)
 
 	var cves []RedhatCVE
-	err := utils.FileWalk(rootDir, func(r io.Reader, _ string) error {
+	err := utils.FileWalk(ctx, rootDir, func(r io.Reader, path string) error {
 		content, err := ioutil.ReadAll(r)
 		if err != nil {
 			return err
 		}
 		cve := RedhatCVE{}
 		if err = json.Unmarshal(content, &cve); err != nil {
-			return xerrors.Errorf("failed to decode RedHat JSON: %w", err)
+			return &types.DecodeError{Source: vulnerability.RedHat, Path: path, Err: err}
 		}
+		cve.Provenance = utils.ContentProvenance(content, dir, path)
 		switch cve.TempAffectedRelease.(type) {
 		case []interface{}:
 			var ar RedhatCVEAffectedReleaseArray
@@ -89,22 +147,28 @@ func (vs VulnSrc) Update(dir string) error {
 			return xerrors.New("unknown package_state type")
 		}
 		cves = append(cves, cve)
+		if db.BatchFull(len(cves)) {
+			if err = vs.save(ctx, cves); err != nil {
+				return xerrors.Errorf("error in Red Hat save: %w", err)
+			}
+			cves = nil
+		}
 		return nil
 	})
 	if err != nil {
 		return xerrors.Errorf("error in Red Hat walk: %w", err)
 	}
 
-	if err = vs.save(cves); err != nil {
+	if err = vs.save(ctx, cves); err != nil {
 		return xerrors.Errorf("error in Red Hat save: %w", err)
 	}
 
 	return nil
 }
 
-func (vs VulnSrc) save(cves []RedhatCVE) error {
-	log.Println("Saving RedHat DB")
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
+func (vs VulnSrc) save(ctx context.Context, cves []RedhatCVE) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.RedHat)
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
 		return vs.commit(tx, cves)
 	})
 	if err != nil {
@@ -113,7 +177,7 @@ func (vs VulnSrc) save(cves []RedhatCVE) error {
 	return nil
 }
 
-func (vs VulnSrc) commit(tx *bolt.Tx, cves []RedhatCVE) error {
+func (vs VulnSrc) commit(tx db.Tx, cves []RedhatCVE) error {
 	for _, cve := range cves {
 		for _, pkgState := range cve.PackageState {
 			pkgName := pkgState.PackageName
@@ -122,7 +186,8 @@ func (vs VulnSrc) commit(tx *bolt.Tx, cves []RedhatCVE) error {
 			}
 			// e.g. Red Hat Enterprise Linux 7
 			platformName := pkgState.ProductName
-			if !utils.StringInSlice(platformName, targetPlatforms) {
+			release := strings.TrimPrefix(platformName, string(db.RedHat)+" ")
+			if release == platformName || !vs.supported(release) {
 				continue
 			}
 			if !utils.StringInSlice(pkgState.FixState, targetStatus) {
@@ -136,6 +201,9 @@ func (vs VulnSrc) commit(tx *bolt.Tx, cves []RedhatCVE) error {
 			if err := vs.dbc.PutAdvisory(tx, platformName, pkgName, cve.Name, advisory); err != nil {
 				return xerrors.Errorf("failed to save Red Hat advisory: %w", err)
 			}
+			if err := vs.dbc.PutProvenance(tx, platformName, pkgName, cve.Name, cve.Provenance); err != nil {
+				return xerrors.Errorf("failed to save Red Hat provenance: %w", err)
+			}
 		}
 
 		cvssScore, _ := strconv.ParseFloat(cve.Cvss.CvssBaseScore, 64)
@@ -164,13 +232,19 @@ func (vs VulnSrc) commit(tx *bolt.Tx, cves []RedhatCVE) error {
 	return nil
 }
 
-func (vs VulnSrc) Get(majorVersion string, pkgName string) ([]types.Advisory, error) {
-	bucket := fmt.Sprintf(platformFormat, majorVersion)
+func (vs VulnSrc) Get(ctx context.Context, majorVersion string, pkgName string, opts ...types.GetOption) ([]types.Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
+	}
+	bucket := db.RedHat.Source(majorVersion)
+	if !vs.supported(majorVersion) {
+		return nil, xerrors.Errorf("%s: %w", bucket, types.ErrUnsupportedRelease)
+	}
 	advisories, err := vs.dbc.GetAdvisories(bucket, pkgName)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get Red Hat advisories: %w", err)
 	}
-	return advisories, nil
+	return types.AdvisoriesByFix(advisories, types.NewGetOptions(opts...).Fix), nil
 }
 
 func severityFromThreat(sev string) types.Severity {
@@ -1,26 +1,22 @@
 package redhat
 
 import (
+	"context"
 	"errors"
-	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/aquasecurity/trivy-db/pkg/storage"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
-	bolt "github.com/etcd-io/bbolt"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
-	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnsrctest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-func TestMain(m *testing.M) {
-	utils.Quiet = true
-	os.Exit(m.Run())
-}
-
 func TestVulnSrc_Update(t *testing.T) {
 	testCases := []struct {
 		name             string
@@ -95,11 +91,11 @@ func TestVulnSrc_Update(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("BatchUpdate", mock.Anything).Return(tc.batchUpdateErr)
-			ac := VulnSrc{dbc: mockDBConfig}
+			mockBatchUpdater := new(db.MockBatchUpdater)
+			mockBatchUpdater.On("BatchUpdate", mock.Anything, mock.Anything).Return(tc.batchUpdateErr)
+			ac := VulnSrc{dbc: vulnsrctest.MockOperations{MockBatchUpdater: mockBatchUpdater}, logger: log.Discard()}
 
-			err := ac.Update(tc.cacheDir)
+			err := ac.Update(context.Background(), tc.cacheDir)
 			switch {
 			case tc.expectedErrorMsg != "":
 				assert.Contains(t, err.Error(), tc.expectedErrorMsg, tc.name)
@@ -465,22 +461,28 @@ func TestVulnSrc_Commit(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			tx := &bolt.Tx{}
-			mockDBConfig := new(db.MockDBConfig)
+			tx := &storage.MockTx{}
+			mockAdvisoryStore := new(db.MockAdvisoryStore)
+			mockVulnerabilityStore := new(db.MockVulnerabilityStore)
+			mockProvenanceStore := new(db.MockProvenanceStore)
 			for _, pa := range tc.putAdvisoryList {
-				mockDBConfig.On("PutAdvisory", tx, pa.input.source, pa.input.pkgName,
+				mockAdvisoryStore.On("PutAdvisory", tx, pa.input.source, pa.input.pkgName,
 					pa.input.cveID, pa.input.advisory).Return(pa.output)
+				if pa.output == nil {
+					mockProvenanceStore.On("PutProvenance", tx, pa.input.source, pa.input.pkgName,
+						pa.input.cveID, mock.Anything).Return(nil)
+				}
 			}
 			for _, pvd := range tc.putVulnerabilityDetailList {
-				mockDBConfig.On("PutVulnerabilityDetail", tx, pvd.input.cveID,
+				mockVulnerabilityStore.On("PutVulnerabilityDetail", tx, pvd.input.cveID,
 					pvd.input.source, pvd.input.vuln).Return(pvd.output)
 			}
 			for _, ps := range tc.putSeverityList {
-				mockDBConfig.On("PutSeverity", tx, ps.input.cveID,
+				mockVulnerabilityStore.On("PutSeverity", tx, ps.input.cveID,
 					ps.input.severity).Return(ps.output)
 			}
 
-			ac := VulnSrc{dbc: mockDBConfig}
+			ac := VulnSrc{dbc: vulnsrctest.MockOperations{MockAdvisoryStore: mockAdvisoryStore, MockVulnerabilityStore: mockVulnerabilityStore, MockProvenanceStore: mockProvenanceStore}, logger: log.Discard()}
 			err := ac.commit(tx, tc.cves)
 
 			switch {
@@ -489,7 +491,9 @@ func TestVulnSrc_Commit(t *testing.T) {
 			default:
 				assert.NoError(t, err, tc.name)
 			}
-			mockDBConfig.AssertExpectations(t)
+			mockAdvisoryStore.AssertExpectations(t)
+			mockVulnerabilityStore.AssertExpectations(t)
+			mockProvenanceStore.AssertExpectations(t)
 		})
 	}
 }
@@ -550,13 +554,13 @@ func TestVulnSrc_Get(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("GetAdvisories", tc.getAdvisories.input.bucket,
+			mockAdvisoryStore := new(db.MockAdvisoryStore)
+			mockAdvisoryStore.On("GetAdvisories", tc.getAdvisories.input.bucket,
 				tc.getAdvisories.input.pkgName).Return(tc.getAdvisories.output.advisories,
 				tc.getAdvisories.output.err)
 
-			vs := VulnSrc{dbc: mockDBConfig}
-			advisories, err := vs.Get(tc.majorVersion, tc.pkgName)
+			vs := VulnSrc{dbc: vulnsrctest.MockOperations{MockAdvisoryStore: mockAdvisoryStore}, logger: log.Discard()}
+			advisories, err := vs.Get(context.Background(), tc.majorVersion, tc.pkgName)
 
 			switch {
 			case tc.expectedErrorMsg != "":
@@ -567,7 +571,7 @@ func TestVulnSrc_Get(t *testing.T) {
 
 			assert.ElementsMatch(t, advisories, tc.expectedAdvisories, tc.name)
 
-			mockDBConfig.AssertExpectations(t)
+			mockAdvisoryStore.AssertExpectations(t)
 		})
 	}
 }
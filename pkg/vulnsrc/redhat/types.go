@@ -1,5 +1,7 @@
 package redhat
 
+import "github.com/aquasecurity/trivy-db/pkg/types"
+
 type RedhatCVE struct {
 	ThreatSeverity       string         `json:"threat_severity"`
 	PublicDate           string         `json:"public_date"`
@@ -20,6 +22,8 @@ type RedhatCVE struct {
 
 	Details    []string `json:"details"`
 	References []string `json:"references"`
+
+	Provenance types.Provenance `json:"-"`
 }
 
 type RedhatCVEAffectedReleaseArray struct {
@@ -0,0 +1,78 @@
+// Package registry is where the sources register themselves from the init of their package, so that adding
+// a source is adding its package and importing it in vulnsrc
+package registry
+
+import (
+	"context"
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+// VulnSrc is a source of advisories or vulnerability data. Its lookups, e.g. the Get of the advisories of
+// a package, differ by source and aren't part of it.
+type VulnSrc interface {
+	// Name is the name the source is selected by, e.g. alpine for --only-update
+	Name() string
+	// Update writes what the source reads from the cache dir into the DB
+	Update(ctx context.Context, dir string) error
+}
+
+// AdvisoryGetter is a source whose advisories are looked up by the release of a platform and a package,
+// e.g. the distributions. The lookups of the other sources differ by source, e.g. by package alone.
+type AdvisoryGetter interface {
+	// Get returns the advisories of pkgName in release, e.g. 3.10 of alpine, failing once ctx is done. All of
+	// them are returned, fixed or not, unless filtered by types.WithFixFilter.
+	Get(ctx context.Context, release, pkgName string, opts ...types.GetOption) ([]types.Advisory, error)
+}
+
+// Source is a registered source and how a build reads it
+type Source struct {
+	VulnSrc
+	// Repository is the git repository in the cache dir the source reads, e.g. vuln-list, none if empty
+	Repository string
+	// Optional sources aren't updated unless asked for, e.g. as their input is provided manually
+	Optional bool
+	// Incremental sources read each of their records from a file of its own, which the walk of an incremental
+	// update is limited to when it changed since the previous one
+	Incremental bool
+}
+
+var sources = map[string]Source{}
+
+// Register adds a source, from the init of its package. A name registered twice panics, as the second
+// source would silently replace the first.
+func Register(s Source) {
+	if err := Add(s); err != nil {
+		panic(err.Error())
+	}
+}
+
+// Add adds a source once the sources of the packages are registered, e.g. a plugin, failing for a name
+// already registered
+func Add(s Source) error {
+	name := s.Name()
+	if _, ok := sources[name]; ok {
+		return xerrors.Errorf("source %s is registered twice", name)
+	}
+	sources[name] = s
+	return nil
+}
+
+// Get returns the source registered with name
+func Get(name string) (Source, bool) {
+	s, ok := sources[name]
+	return s, ok
+}
+
+// Names returns the names of the registered sources, sorted
+func Names() []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,44 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeVulnSrc string
+
+func (f fakeVulnSrc) Name() string {
+	return string(f)
+}
+
+func (f fakeVulnSrc) Update(context.Context, string) error {
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	defer func(prev map[string]Source) {
+		sources = prev
+	}(sources)
+	sources = map[string]Source{}
+
+	Register(Source{VulnSrc: fakeVulnSrc("nvd"), Repository: "vuln-list", Incremental: true})
+	Register(Source{VulnSrc: fakeVulnSrc("bdu"), Optional: true})
+	assert.Equal(t, []string{"bdu", "nvd"}, Names())
+
+	got, ok := Get("nvd")
+	assert.True(t, ok)
+	assert.Equal(t, Source{VulnSrc: fakeVulnSrc("nvd"), Repository: "vuln-list", Incremental: true}, got)
+	_, ok = Get("alpine")
+	assert.False(t, ok)
+
+	assert.PanicsWithValue(t, "source nvd is registered twice", func() {
+		Register(Source{VulnSrc: fakeVulnSrc("nvd")})
+	})
+
+	// a source added later, e.g. a plugin, fails instead
+	assert.NoError(t, Add(Source{VulnSrc: fakeVulnSrc("acme")}))
+	assert.EqualError(t, Add(Source{VulnSrc: fakeVulnSrc("acme")}), "source acme is registered twice")
+	assert.Equal(t, []string{"acme", "bdu", "nvd"}, Names())
+}
@@ -0,0 +1,65 @@
+package vulnsrc
+
+import (
+	"context"
+	"net"
+	"syscall"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/log"
+)
+
+// transientErrnos are the errors of system calls which may not happen again, e.g. on a network filesystem
+var transientErrnos = []syscall.Errno{syscall.EAGAIN, syscall.EINTR, syscall.EIO, syscall.EBUSY, syscall.EMFILE,
+	syscall.ENFILE, syscall.ESTALE, syscall.ETIMEDOUT, syscall.ECONNRESET, syscall.ECONNREFUSED}
+
+// IsTransient tells whether an update failing with err may succeed when tried again. The other errors, e.g.
+// a missing dir or a malformed input, are fatal. A source timing out would most likely time out again.
+func IsTransient(err error) bool {
+	if err == nil || xerrors.Is(err, context.DeadlineExceeded) || xerrors.Is(err, context.Canceled) {
+		return false
+	}
+	var errno syscall.Errno
+	if xerrors.As(err, &errno) {
+		for _, e := range transientErrnos {
+			if errno == e {
+				return true
+			}
+		}
+		return false
+	}
+	var netErr net.Error
+	if xerrors.As(err, &netErr) {
+		return netErr.Timeout() || netErr.Temporary()
+	}
+	return false
+}
+
+// retry runs update until it succeeds, fails with an error which isn't transient or the retries of the source
+// are exhausted, waiting the backoff of the update in between, doubled each time. restart undoes the writes
+// of the failed attempt before the next one.
+func (u Updater) retry(ctx context.Context, distribution string, update func(context.Context) error,
+	restart func() error) error {
+	retries, backoff := u.sourceOptions(distribution).Retries, u.backoff
+	for attempt := 1; ; attempt++ {
+		err := update(ctx)
+		if err == nil || attempt > retries || ctx.Err() != nil || !IsTransient(err) {
+			return err
+		}
+		log.OrDefault(u.logger).Warn("Retrying the source after a transient failure", "source", distribution, "attempt", attempt,
+			"retries", retries, "backoff", backoff.String(), log.Err(err))
+		if backoff > 0 {
+			select {
+			case <-u.clock.After(backoff):
+			case <-ctx.Done():
+				return err
+			}
+			backoff *= 2
+		}
+		if rerr := restart(); rerr != nil {
+			return xerrors.Errorf("failed to restart after %v: %w", err, rerr)
+		}
+		log.ProgressFrom(ctx).Reset()
+	}
+}
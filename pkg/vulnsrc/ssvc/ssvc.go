@@ -0,0 +1,153 @@
+package ssvc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+)
+
+// User-supplied SSVC decision points, e.g. <cache-dir>/ssvc/internal.json
+//   {"CVE-2021-44228": {"Exploitation": "active", "Automatable": "yes", "TechnicalImpact": "total"}}
+
+const (
+	ssvcDir = "ssvc"
+
+	// Source is recorded on user-supplied decision points, which take precedence over the ones of vulnrichment
+	Source = db.UserSSVCSource
+)
+
+var (
+	exploitationValues    = []string{"none", "poc", "active"}
+	automatableValues     = []string{"yes", "no"}
+	technicalImpactValues = []string{"partial", "total"}
+)
+
+type operations interface {
+	db.BatchUpdater
+	db.VulnerabilityStore
+}
+
+type VulnSrc struct {
+	dbc    operations
+	logger *slog.Logger
+}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Optional: true})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
+	}
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.SSVC
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
+	rootDir := filepath.Join(dir, ssvcDir)
+
+	decisions := map[string]types.SSVC{}
+	err := utils.FileWalk(ctx, rootDir, func(r io.Reader, path string) error {
+		if !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		var m map[string]types.SSVC
+		if err := json.NewDecoder(r).Decode(&m); err != nil {
+			return &types.DecodeError{Source: vulnerability.SSVC, Path: path, Err: err}
+		}
+		for cveID, ssvc := range m {
+			if err := validate(ssvc); err != nil {
+				return xerrors.Errorf("invalid SSVC of %s in %s: %w", cveID, path, err)
+			}
+			ssvc.Source = Source
+			decisions[cveID] = ssvc
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("error in SSVC walk: %w", err)
+	}
+
+	if err = vs.save(ctx, decisions); err != nil {
+		return xerrors.Errorf("error in SSVC save: %w", err)
+	}
+	return nil
+}
+
+func (vs VulnSrc) save(ctx context.Context, decisions map[string]types.SSVC) error {
+	log.OrDefault(vs.logger).Info("Saving decision points", "source", vulnerability.SSVC)
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
+		for cveID, ssvc := range decisions {
+			if err := vs.dbc.PutSSVC(tx, cveID, ssvc); err != nil {
+				return xerrors.Errorf("failed to save SSVC: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("error in batch update: %w", err)
+	}
+	return nil
+}
+
+func (vs VulnSrc) Get(ctx context.Context, cveID string) (types.SSVC, error) {
+	if err := ctx.Err(); err != nil {
+		return types.SSVC{}, xerrors.Errorf("lookup canceled: %w", err)
+	}
+	ssvc, err := vs.dbc.GetSSVC(cveID)
+	if err != nil {
+		return types.SSVC{}, xerrors.Errorf("failed to get SSVC: %w", err)
+	}
+	return ssvc, nil
+}
+
+func validate(ssvc types.SSVC) error {
+	if ssvc.Exploitation != "" && !utils.StringInSlice(ssvc.Exploitation, exploitationValues) {
+		return xerrors.Errorf("unknown exploitation: %s", ssvc.Exploitation)
+	}
+	if ssvc.Automatable != "" && !utils.StringInSlice(ssvc.Automatable, automatableValues) {
+		return xerrors.Errorf("unknown automatable: %s", ssvc.Automatable)
+	}
+	if ssvc.TechnicalImpact != "" && !utils.StringInSlice(ssvc.TechnicalImpact, technicalImpactValues) {
+		return xerrors.Errorf("unknown technical impact: %s", ssvc.TechnicalImpact)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package ssvc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnsrctest"
+)
+
+func TestVulnSrc_Update(t *testing.T) {
+	testCases := []struct {
+		name             string
+		cacheDir         string
+		putSSVC          *types.SSVC
+		batchUpdateErr   error
+		expectedErrorMsg string
+	}{
+		{
+			name:     "happy path",
+			cacheDir: "testdata/happy",
+			putSSVC: &types.SSVC{
+				Exploitation:    "active",
+				Automatable:     "yes",
+				TechnicalImpact: "total",
+				Source:          db.UserSSVCSource,
+			},
+		},
+		{
+			name:             "unknown value",
+			cacheDir:         "testdata/invalid",
+			expectedErrorMsg: "unknown exploitation: widespread",
+		},
+		{
+			name:             "broken JSON",
+			cacheDir:         "testdata/broken",
+			expectedErrorMsg: "error in SSVC walk",
+		},
+		{
+			name:             "cache dir doesnt exist",
+			cacheDir:         "badpathdoesnotexist",
+			expectedErrorMsg: "error in SSVC walk",
+		},
+		{
+			name:             "BatchUpdate returns an error",
+			cacheDir:         "testdata/happy",
+			batchUpdateErr:   errors.New("batch update failed"),
+			expectedErrorMsg: "batch update failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := &storage.MockTx{}
+			mockBatchUpdater, mockVulnerabilityStore := new(db.MockBatchUpdater), new(db.MockVulnerabilityStore)
+			batchUpdate := mockBatchUpdater.On("BatchUpdate", mock.Anything, mock.Anything).Return(tc.batchUpdateErr)
+			if tc.putSSVC != nil {
+				batchUpdate.Run(func(args mock.Arguments) {
+					fn := args.Get(1).(func(db.Tx) error)
+					assert.NoError(t, fn(tx))
+				})
+				mockVulnerabilityStore.On("PutSSVC", tx, "CVE-2021-44228", *tc.putSSVC).Return(nil)
+			}
+			vs := NewVulnSrc(WithDB(vulnsrctest.MockOperations{MockBatchUpdater: mockBatchUpdater, MockVulnerabilityStore: mockVulnerabilityStore}), WithLogger(log.Discard()))
+
+			err := vs.Update(context.Background(), tc.cacheDir)
+			switch {
+			case tc.expectedErrorMsg != "":
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErrorMsg, tc.name)
+			default:
+				assert.NoError(t, err, tc.name)
+				mockBatchUpdater.AssertExpectations(t)
+				mockVulnerabilityStore.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestVulnSrc_Get(t *testing.T) {
+	want := types.SSVC{Exploitation: "poc", Source: db.UserSSVCSource}
+	mockVulnerabilityStore := new(db.MockVulnerabilityStore)
+	mockVulnerabilityStore.On("GetSSVC", "CVE-2021-44228").Return(want, nil)
+	mockVulnerabilityStore.On("GetSSVC", "CVE-2021-0001").Return(nil, errors.New("get failed"))
+	vs := NewVulnSrc(WithDB(vulnsrctest.MockOperations{MockVulnerabilityStore: mockVulnerabilityStore}))
+
+	got, err := vs.Get(context.Background(), "CVE-2021-44228")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = vs.Get(context.Background(), "CVE-2021-0001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to get SSVC")
+}
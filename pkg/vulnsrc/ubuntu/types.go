@@ -1,11 +1,15 @@
 package ubuntu
 
+import "github.com/aquasecurity/trivy-db/pkg/types"
+
 type UbuntuCVE struct {
 	Description string `json:"description"`
 	Candidate   string
 	Priority    string
 	Patches     map[PackageName]Patch
 	References  []string
+
+	Provenance types.Provenance `json:"-"`
 }
 
 type PackageName string
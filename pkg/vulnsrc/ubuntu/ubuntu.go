@@ -1,25 +1,25 @@
 package ubuntu
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"path/filepath"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
-	bolt "github.com/etcd-io/bbolt"
 	"golang.org/x/xerrors"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
 	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
 const (
-	ubuntuDir      = "ubuntu"
-	platformFormat = "ubuntu %s"
+	ubuntuDir = "ubuntu"
 )
 
 var (
@@ -42,41 +42,94 @@ var (
 	}
 )
 
+type operations interface {
+	db.BatchUpdater
+	db.AdvisoryStore
+	db.VulnerabilityStore
+	db.ProvenanceStore
+}
+
 type VulnSrc struct {
-	dbc db.Operations
+	dbc    operations
+	logger *slog.Logger
+}
+
+var _ registry.AdvisoryGetter = VulnSrc{}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vuln-list", Incremental: true})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{DataSource: vulnerability.Ubuntu},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
 }
 
-func NewVulnSrc() VulnSrc {
-	return VulnSrc{
-		dbc: db.Config{},
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
 	}
 }
 
-func (vs VulnSrc) Update(dir string) error {
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.Ubuntu
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
 	rootDir := filepath.Join(dir, "vuln-list", ubuntuDir)
 	var cves []UbuntuCVE
-	err := utils.FileWalk(rootDir, func(r io.Reader, path string) error {
+	err := utils.FileWalk(ctx, rootDir, func(r io.Reader, path string) error {
 		var cve UbuntuCVE
-		if err := json.NewDecoder(r).Decode(&cve); err != nil {
-			return xerrors.Errorf("failed to decode Ubuntu JSON: %w", err)
+		pr := utils.NewProvenanceReader(r, dir, path)
+		if err := json.NewDecoder(pr).Decode(&cve); err != nil {
+			return &types.DecodeError{Source: vulnerability.Ubuntu, Path: path, Err: err}
 		}
+		provenance, err := pr.Provenance()
+		if err != nil {
+			return xerrors.Errorf("failed to read %s: %w", path, err)
+		}
+		cve.Provenance = provenance
 		cves = append(cves, cve)
+		if db.BatchFull(len(cves)) {
+			if err = vs.save(ctx, cves); err != nil {
+				return xerrors.Errorf("error in Ubuntu save: %w", err)
+			}
+			cves = nil
+		}
 		return nil
 	})
 	if err != nil {
 		return xerrors.Errorf("error in Ubuntu walk: %w", err)
 	}
 
-	if err = vs.save(cves); err != nil {
+	if err = vs.save(ctx, cves); err != nil {
 		return xerrors.Errorf("error in Ubuntu save: %w", err)
 	}
 
 	return nil
 }
 
-func (vs VulnSrc) save(cves []UbuntuCVE) error {
-	log.Println("Saving Ubuntu DB")
-	err := vs.dbc.BatchUpdate(func(tx *bolt.Tx) error {
+func (vs VulnSrc) save(ctx context.Context, cves []UbuntuCVE) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.Ubuntu)
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
 		for _, cve := range cves {
 			for packageName, patch := range cve.Patches {
 				pkgName := string(packageName)
@@ -88,7 +141,7 @@ func (vs VulnSrc) save(cves []UbuntuCVE) error {
 					if !ok {
 						continue
 					}
-					platformName := fmt.Sprintf(platformFormat, osVersion)
+					platformName := db.Ubuntu.Source(osVersion)
 					advisory := types.Advisory{}
 					if status.Status == "released" {
 						advisory.FixedVersion = status.Note
@@ -96,6 +149,9 @@ func (vs VulnSrc) save(cves []UbuntuCVE) error {
 					if err := vs.dbc.PutAdvisory(tx, platformName, pkgName, cve.Candidate, advisory); err != nil {
 						return xerrors.Errorf("failed to save Ubuntu advisory: %w", err)
 					}
+					if err := vs.dbc.PutProvenance(tx, platformName, pkgName, cve.Candidate, cve.Provenance); err != nil {
+						return xerrors.Errorf("failed to save Ubuntu provenance: %w", err)
+					}
 
 					vuln := types.VulnerabilityDetail{
 						Severity:    severityFromPriority(cve.Priority),
@@ -123,20 +179,25 @@ func (vs VulnSrc) save(cves []UbuntuCVE) error {
 	return nil
 }
 
-func (vs VulnSrc) Get(release string, pkgName string) ([]types.Advisory, error) {
-	bucket := fmt.Sprintf(platformFormat, release)
+func (vs VulnSrc) Get(ctx context.Context, release string, pkgName string, opts ...types.GetOption) ([]types.Advisory, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, xerrors.Errorf("lookup canceled: %w", err)
+	}
+	bucket := db.Ubuntu.Source(release)
 	advisories, err := vs.dbc.GetAdvisories(bucket, pkgName)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to get Amazon advisories: %w", err)
 	}
-	return advisories, nil
+	return types.AdvisoriesByFix(advisories, types.NewGetOptions(opts...).Fix), nil
 }
 
 func severityFromPriority(priority string) types.Severity {
 	switch priority {
 	case "untriaged":
 		return types.SeverityUnknown
-	case "negligible", "low":
+	case "negligible":
+		return types.SeverityNegligible
+	case "low":
 		return types.SeverityLow
 	case "medium":
 		return types.SeverityMedium
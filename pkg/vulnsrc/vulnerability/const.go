@@ -18,4 +18,7 @@ const (
 	PhpSecurityAdvisories = "php-security-advisories"
 	NodejsSecurityWg      = "nodejs-security-wg"
 	PythonSafetyDB        = "python-safety-db"
+	BDU                   = "bdu"
+	Vulnrichment          = "cisa-vulnrichment"
+	SSVC                  = "ssvc"
 )
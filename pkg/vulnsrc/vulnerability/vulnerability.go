@@ -1,24 +1,34 @@
 package vulnerability
 
 import (
-	"log"
 	"sort"
 	"strings"
 
+	"github.com/aquasecurity/trivy-db/pkg/log"
 	"github.com/aquasecurity/trivy-db/pkg/types"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
 )
 
 var (
-	sources = []string{Nvd, RedHat, Debian, DebianOVAL, Alpine, Amazon, OracleOVAL,
-		RubySec, RustSec, PhpSecurityAdvisories, NodejsSecurityWg, PythonSafetyDB}
+	sources = []string{Nvd, Vulnrichment, RedHat, Debian, DebianOVAL, Alpine, Amazon, OracleOVAL,
+		RubySec, RustSec, PhpSecurityAdvisories, NodejsSecurityWg, PythonSafetyDB, BDU}
 )
 
+// AddDetailSource merges the details of a source after the ones of the other sources, e.g. of a plugin
+func AddDetailSource(source string) {
+	for _, s := range sources {
+		if s == source {
+			return
+		}
+	}
+	sources = append(sources, source)
+}
+
 func GetDetail(vulnID string) (types.Severity, string, string, []string) {
-	details, err := db.Config{}.GetVulnerabilityDetail(vulnID)
+	details, err := db.Config{}.GetVulnerabilityDetails(vulnID)
 	if err != nil {
-		log.Println(err)
+		log.Warn("Failed to get the vulnerability details", "id", vulnID, log.Err(err))
 		return types.SeverityUnknown, "", "", nil
 	} else if len(details) == 0 {
 		return types.SeverityUnknown, "", "", nil
@@ -26,24 +36,60 @@ func GetDetail(vulnID string) (types.Severity, string, string, []string) {
 	return getSeverity(details), getTitle(details), getDescription(details), getReferences(details)
 }
 
+// GetVulnerability merges the details of all sources into a vulnerability, including enrichment data
+func GetVulnerability(vulnID string) types.Vulnerability {
+	details, err := db.Config{}.GetVulnerabilityDetails(vulnID)
+	if err != nil {
+		log.Warn("Failed to get the vulnerability details", "id", vulnID, log.Err(err))
+		return types.Vulnerability{Severity: types.SeverityUnknown.String()}
+	} else if len(details) == 0 {
+		return types.Vulnerability{Severity: types.SeverityUnknown.String()}
+	}
+	return types.Vulnerability{
+		Title:          getTitle(details),
+		Description:    getDescription(details),
+		Severity:       getSeverity(details).String(),
+		References:     getReferences(details),
+		KnownExploited: getKnownExploited(details),
+		CVSS:           getCVSS(details),
+	}
+}
+
 func getSeverity(details map[string]types.VulnerabilityDetail) types.Severity {
 	for _, source := range sources {
 		switch d, ok := details[source]; {
 		case !ok:
 			continue
 		case d.CvssScore > 0:
-			return scoreToSeverity(d.CvssScore)
+			return types.SeverityFromCVSSScore(d.CvssScore)
 		case d.CvssScoreV3 > 0:
-			return scoreToSeverity(d.CvssScoreV3)
+			return types.SeverityFromCVSSScore(d.CvssScoreV3)
 		case d.Severity != 0:
 			return d.Severity
 		case d.SeverityV3 != 0:
 			return d.SeverityV3
+		case d.CvssVectorV3 != "" || d.CvssVector != "":
+			// e.g. OSV-style records only have CVSS vectors
+			if severity := severityFromVectors(d); severity != types.SeverityUnknown {
+				return severity
+			}
 		}
 	}
 	return types.SeverityUnknown
 }
 
+func severityFromVectors(d types.VulnerabilityDetail) types.Severity {
+	if d.CvssVectorV3 != "" {
+		if severity := types.SeverityFromCVSSVector(d.CvssVectorV3); severity != types.SeverityUnknown {
+			return severity
+		}
+	}
+	if d.CvssVector != "" {
+		return types.SeverityFromCVSSVector(d.CvssVector)
+	}
+	return types.SeverityUnknown
+}
+
 func getTitle(details map[string]types.VulnerabilityDetail) string {
 	for _, source := range sources {
 		d, ok := details[source]
@@ -70,6 +116,42 @@ func getDescription(details map[string]types.VulnerabilityDetail) string {
 	return ""
 }
 
+func getKnownExploited(details map[string]types.VulnerabilityDetail) *types.KnownExploited {
+	for _, source := range sources {
+		d, ok := details[source]
+		if !ok {
+			continue
+		}
+		if d.KnownExploited != nil {
+			return d.KnownExploited
+		}
+	}
+	return nil
+}
+
+// getCVSS keeps the CVSS of every source, unlike the severity, which is the one of the first source rating it
+func getCVSS(details map[string]types.VulnerabilityDetail) map[string]types.CVSS {
+	var cvss map[string]types.CVSS
+	for source, d := range details {
+		c := types.CVSS{
+			V2Score:  d.CvssScore,
+			V2Vector: d.CvssVector,
+			V3Score:  d.CvssScoreV3,
+			V3Vector: d.CvssVectorV3,
+			V4Score:  d.CvssScoreV4,
+			V4Vector: d.CvssVectorV4,
+		}
+		if c.IsEmpty() {
+			continue
+		}
+		if cvss == nil {
+			cvss = map[string]types.CVSS{}
+		}
+		cvss[source] = c
+	}
+	return cvss
+}
+
 func getReferences(details map[string]types.VulnerabilityDetail) []string {
 	references := map[string]struct{}{}
 	for _, source := range sources {
@@ -98,18 +180,3 @@ func getReferences(details map[string]types.VulnerabilityDetail) []string {
 	})
 	return refs
 }
-
-func scoreToSeverity(score float64) types.Severity {
-	switch {
-	case score >= 9.0:
-		return types.SeverityCritical
-	case score >= 7.0:
-		return types.SeverityHigh
-	case score >= 4.0:
-		return types.SeverityMedium
-	case score > 0.0:
-		return types.SeverityLow
-	default:
-		return types.SeverityUnknown
-	}
-}
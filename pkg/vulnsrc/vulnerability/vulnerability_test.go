@@ -0,0 +1,72 @@
+package vulnerability
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aquasecurity/trivy-db/pkg/types"
+)
+
+func Test_getSeverity(t *testing.T) {
+	tests := []struct {
+		name    string
+		details map[string]types.VulnerabilityDetail
+		want    types.Severity
+	}{
+		{
+			name: "CVSS score",
+			details: map[string]types.VulnerabilityDetail{
+				Nvd: {CvssScoreV3: 9.8, Severity: types.SeverityLow},
+			},
+			want: types.SeverityCritical,
+		},
+		{
+			name: "severity of the first source",
+			details: map[string]types.VulnerabilityDetail{
+				Nvd:    {Severity: types.SeverityHigh},
+				RedHat: {Severity: types.SeverityLow},
+			},
+			want: types.SeverityHigh,
+		},
+		{
+			name: "CVSS v3 vector only",
+			details: map[string]types.VulnerabilityDetail{
+				Nvd: {CvssVectorV3: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"},
+			},
+			want: types.SeverityCritical,
+		},
+		{
+			name: "CVSS v2 vector only",
+			details: map[string]types.VulnerabilityDetail{
+				Nvd: {CvssVector: "AV:N/AC:L/Au:N/C:P/I:P/A:P"},
+			},
+			want: types.SeverityHigh,
+		},
+		{
+			name: "invalid v3 vector falls back to the v2 one",
+			details: map[string]types.VulnerabilityDetail{
+				Nvd: {CvssVectorV3: "CVSS:3.1/AV:X", CvssVector: "AV:N/AC:L/Au:N/C:P/I:P/A:P"},
+			},
+			want: types.SeverityHigh,
+		},
+		{
+			name: "invalid vector of a source falls back to the next source",
+			details: map[string]types.VulnerabilityDetail{
+				Nvd:    {CvssVectorV3: "CVSS:3.1/AV:X"},
+				RedHat: {Severity: types.SeverityMedium},
+			},
+			want: types.SeverityMedium,
+		},
+		{
+			name:    "no detail",
+			details: map[string]types.VulnerabilityDetail{},
+			want:    types.SeverityUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getSeverity(tt.details))
+		})
+	}
+}
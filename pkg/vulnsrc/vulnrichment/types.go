@@ -0,0 +1,56 @@
+package vulnrichment
+
+import "encoding/json"
+
+// CVERecord is a subset of the CVE JSON 5 record format
+type CVERecord struct {
+	CveMetadata CveMetadata `json:"cveMetadata"`
+	Containers  Containers  `json:"containers"`
+}
+
+type CveMetadata struct {
+	CveID string `json:"cveId"`
+}
+
+type Containers struct {
+	Adp []Adp `json:"adp"`
+}
+
+type Adp struct {
+	ProviderMetadata ProviderMetadata `json:"providerMetadata"`
+	Metrics          []Metric         `json:"metrics"`
+}
+
+type ProviderMetadata struct {
+	ShortName string `json:"shortName"`
+}
+
+type Metric struct {
+	CvssV31 *Cvss  `json:"cvssV3_1"`
+	CvssV30 *Cvss  `json:"cvssV3_0"`
+	CvssV20 *Cvss  `json:"cvssV2_0"`
+	Other   *Other `json:"other"`
+}
+
+type Cvss struct {
+	BaseScore    float64 `json:"baseScore"`
+	BaseSeverity string  `json:"baseSeverity"`
+	VectorString string  `json:"vectorString"`
+}
+
+type Other struct {
+	Type    string          `json:"type"`
+	Content json.RawMessage `json:"content"`
+}
+
+type SSVCContent struct {
+	ID      string              `json:"id"`
+	Role    string              `json:"role"`
+	Version string              `json:"version"`
+	Options []map[string]string `json:"options"`
+}
+
+type KEVContent struct {
+	DateAdded string `json:"dateAdded"`
+	Reference string `json:"reference"`
+}
@@ -0,0 +1,217 @@
+package vulnrichment
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+)
+
+// https://github.com/cisagov/vulnrichment.git
+
+const (
+	vulnrichmentDir = "vulnrichment"
+	providerName    = "CISA-ADP"
+
+	otherTypeSSVC = "ssvc"
+	otherTypeKEV  = "kev"
+)
+
+type operations interface {
+	db.BatchUpdater
+	db.VulnerabilityStore
+}
+
+type VulnSrc struct {
+	dbc    operations
+	logger *slog.Logger
+}
+
+func init() {
+	registry.Register(registry.Source{VulnSrc: NewVulnSrc(), Repository: "vulnrichment"})
+}
+
+// NewVulnSrc returns the source, configured by opts
+func NewVulnSrc(opts ...Option) VulnSrc {
+	vs := VulnSrc{
+		dbc: db.Config{},
+	}
+	for _, opt := range opts {
+		opt(&vs)
+	}
+	return vs
+}
+
+// Option configures a source, e.g. the DB it writes to
+type Option func(*VulnSrc)
+
+// WithDB makes the source read and write dbc rather than the DB
+func WithDB(dbc operations) Option {
+	return func(vs *VulnSrc) {
+		vs.dbc = dbc
+	}
+}
+
+// WithLogger makes the source log to l rather than to the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(vs *VulnSrc) {
+		vs.logger = l
+	}
+}
+
+// Name returns the name the source is registered with
+func (vs VulnSrc) Name() string {
+	return vulnerability.Vulnrichment
+}
+
+func (vs VulnSrc) Update(ctx context.Context, dir string) error {
+	repoPath := filepath.Join(dir, vulnrichmentDir)
+
+	var records []CVERecord
+	progress := log.ProgressFrom(ctx)
+	err := filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasPrefix(info.Name(), "CVE-") || !strings.HasSuffix(info.Name(), ".json") {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return xerrors.Errorf("failed to open a file: %w", err)
+		}
+		defer f.Close()
+
+		var record CVERecord
+		if err = json.NewDecoder(f).Decode(&record); err != nil {
+			return &types.DecodeError{Source: vulnerability.Vulnrichment, Path: path, Err: err}
+		}
+		progress.AddFiles(1)
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("error in vulnrichment walk: %w", err)
+	}
+
+	if err = vs.save(ctx, records); err != nil {
+		return xerrors.Errorf("error in vulnrichment save: %w", err)
+	}
+	return nil
+}
+
+func (vs VulnSrc) save(ctx context.Context, records []CVERecord) error {
+	log.OrDefault(vs.logger).Info("Saving advisories", "source", vulnerability.Vulnrichment)
+	err := vs.dbc.BatchUpdate(ctx, func(tx db.Tx) error {
+		return vs.commit(tx, records)
+	})
+	if err != nil {
+		return xerrors.Errorf("error in batch update: %w", err)
+	}
+	return nil
+}
+
+// commit stores CISA data as its own per-source detail, so that it is only used
+// when sources with a higher priority such as NVD lack the information.
+func (vs VulnSrc) commit(tx db.Tx, records []CVERecord) error {
+	for _, record := range records {
+		cveID := record.CveMetadata.CveID
+		if cveID == "" {
+			continue
+		}
+
+		detail, found, err := parseAdp(record.Containers.Adp)
+		if err != nil {
+			return xerrors.Errorf("failed to parse ADP container of %s: %w", cveID, err)
+		} else if !found {
+			continue
+		}
+
+		detail.ID = cveID
+		if err = vs.dbc.PutVulnerabilityDetail(tx, cveID, vulnerability.Vulnrichment, detail); err != nil {
+			return xerrors.Errorf("failed to save vulnrichment detail: %w", err)
+		}
+
+		// SSVC is stored separately as vulnerability details are dropped by the optimizer
+		if detail.SSVC != nil {
+			if err = vs.dbc.PutSSVC(tx, cveID, *detail.SSVC); err != nil {
+				return xerrors.Errorf("failed to save vulnrichment SSVC: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func parseAdp(containers []Adp) (types.VulnerabilityDetail, bool, error) {
+	var detail types.VulnerabilityDetail
+	var found bool
+	for _, adp := range containers {
+		if adp.ProviderMetadata.ShortName != providerName {
+			continue
+		}
+		found = true
+
+		for _, metric := range adp.Metrics {
+			switch {
+			case metric.CvssV31 != nil:
+				detail.CvssScoreV3 = metric.CvssV31.BaseScore
+				detail.CvssVectorV3 = metric.CvssV31.VectorString
+				detail.SeverityV3, _ = types.NewSeverity(metric.CvssV31.BaseSeverity)
+			case metric.CvssV30 != nil && detail.CvssVectorV3 == "":
+				detail.CvssScoreV3 = metric.CvssV30.BaseScore
+				detail.CvssVectorV3 = metric.CvssV30.VectorString
+				detail.SeverityV3, _ = types.NewSeverity(metric.CvssV30.BaseSeverity)
+			case metric.CvssV20 != nil:
+				detail.CvssScore = metric.CvssV20.BaseScore
+				detail.CvssVector = metric.CvssV20.VectorString
+			case metric.Other != nil && metric.Other.Type == otherTypeSSVC:
+				var content SSVCContent
+				if err := json.Unmarshal(metric.Other.Content, &content); err != nil {
+					return types.VulnerabilityDetail{}, false, xerrors.Errorf("failed to decode SSVC: %w", err)
+				}
+				detail.SSVC = ssvcFromOptions(content.Options)
+			case metric.Other != nil && metric.Other.Type == otherTypeKEV:
+				var content KEVContent
+				if err := json.Unmarshal(metric.Other.Content, &content); err != nil {
+					return types.VulnerabilityDetail{}, false, xerrors.Errorf("failed to decode KEV: %w", err)
+				}
+				detail.KnownExploited = &types.KnownExploited{
+					DateAdded: content.DateAdded,
+					Reference: content.Reference,
+				}
+				if content.Reference != "" {
+					detail.References = append(detail.References, content.Reference)
+				}
+			}
+		}
+	}
+	return detail, found, nil
+}
+
+// e.g. [{"Exploitation": "none"}, {"Automatable": "no"}, {"Technical Impact": "partial"}]
+func ssvcFromOptions(options []map[string]string) *types.SSVC {
+	ssvc := &types.SSVC{Source: vulnerability.Vulnrichment}
+	for _, option := range options {
+		for k, v := range option {
+			switch strings.ToLower(k) {
+			case "exploitation":
+				ssvc.Exploitation = strings.ToLower(v)
+			case "automatable":
+				ssvc.Automatable = strings.ToLower(v)
+			case "technical impact":
+				ssvc.TechnicalImpact = strings.ToLower(v)
+			}
+		}
+	}
+	return ssvc
+}
@@ -0,0 +1,176 @@
+package vulnrichment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/storage"
+	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnsrctest"
+)
+
+func TestVulnSrc_Update(t *testing.T) {
+	testCases := []struct {
+		name             string
+		cacheDir         string
+		batchUpdateErr   error
+		expectedErrorMsg string
+	}{
+		{
+			name:     "happy path",
+			cacheDir: "testdata",
+		},
+		{
+			name:             "cache dir doesnt exist",
+			cacheDir:         "badpathdoesnotexist",
+			expectedErrorMsg: "error in vulnrichment walk",
+		},
+		{
+			name:             "BatchUpdate returns an error",
+			cacheDir:         "testdata",
+			batchUpdateErr:   errors.New("batch update failed"),
+			expectedErrorMsg: "batch update failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockBatchUpdater := new(db.MockBatchUpdater)
+			mockBatchUpdater.On("BatchUpdate", mock.Anything, mock.Anything).Return(tc.batchUpdateErr)
+			vs := VulnSrc{dbc: vulnsrctest.MockOperations{MockBatchUpdater: mockBatchUpdater}, logger: log.Discard()}
+
+			err := vs.Update(context.Background(), tc.cacheDir)
+			switch {
+			case tc.expectedErrorMsg != "":
+				assert.Contains(t, err.Error(), tc.expectedErrorMsg, tc.name)
+			default:
+				assert.NoError(t, err, tc.name)
+			}
+		})
+	}
+}
+
+func TestVulnSrc_Commit(t *testing.T) {
+	testCases := []struct {
+		name             string
+		records          []CVERecord
+		putDetail        *types.VulnerabilityDetail
+		expectedErrorMsg string
+	}{
+		{
+			name: "happy path",
+			records: []CVERecord{
+				{
+					CveMetadata: CveMetadata{CveID: "CVE-2021-44228"},
+					Containers: Containers{
+						Adp: []Adp{
+							{
+								ProviderMetadata: ProviderMetadata{ShortName: "CISA-ADP"},
+								Metrics: []Metric{
+									{
+										CvssV31: &Cvss{
+											BaseScore:    10,
+											BaseSeverity: "CRITICAL",
+											VectorString: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H",
+										},
+									},
+									{
+										Other: &Other{
+											Type:    "ssvc",
+											Content: json.RawMessage(`{"options": [{"Exploitation": "active"}, {"Automatable": "yes"}, {"Technical Impact": "total"}]}`),
+										},
+									},
+									{
+										Other: &Other{
+											Type:    "kev",
+											Content: json.RawMessage(`{"dateAdded": "2021-12-10", "reference": "https://www.cisa.gov/kev"}`),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			putDetail: &types.VulnerabilityDetail{
+				ID:           "CVE-2021-44228",
+				CvssScoreV3:  10,
+				CvssVectorV3: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H",
+				SeverityV3:   types.SeverityCritical,
+				References:   []string{"https://www.cisa.gov/kev"},
+				KnownExploited: &types.KnownExploited{
+					DateAdded: "2021-12-10",
+					Reference: "https://www.cisa.gov/kev",
+				},
+				SSVC: &types.SSVC{
+					Exploitation:    "active",
+					Automatable:     "yes",
+					TechnicalImpact: "total",
+					Source:          "cisa-vulnrichment",
+				},
+			},
+		},
+		{
+			name: "no CISA container",
+			records: []CVERecord{
+				{
+					CveMetadata: CveMetadata{CveID: "CVE-2021-44228"},
+					Containers: Containers{
+						Adp: []Adp{{ProviderMetadata: ProviderMetadata{ShortName: "CVE"}}},
+					},
+				},
+			},
+		},
+		{
+			name: "broken KEV content",
+			records: []CVERecord{
+				{
+					CveMetadata: CveMetadata{CveID: "CVE-2021-44228"},
+					Containers: Containers{
+						Adp: []Adp{
+							{
+								ProviderMetadata: ProviderMetadata{ShortName: "CISA-ADP"},
+								Metrics: []Metric{
+									{Other: &Other{Type: "kev", Content: json.RawMessage(`1`)}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedErrorMsg: "failed to decode KEV",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := &storage.MockTx{}
+			mockVulnerabilityStore := new(db.MockVulnerabilityStore)
+			if tc.putDetail != nil {
+				mockVulnerabilityStore.On("PutVulnerabilityDetail", tx, "CVE-2021-44228",
+					vulnerability.Vulnrichment, *tc.putDetail).Return(nil)
+				if tc.putDetail.SSVC != nil {
+					mockVulnerabilityStore.On("PutSSVC", tx, "CVE-2021-44228", *tc.putDetail.SSVC).Return(nil)
+				}
+			}
+
+			vs := VulnSrc{dbc: vulnsrctest.MockOperations{MockVulnerabilityStore: mockVulnerabilityStore}, logger: log.Discard()}
+			err := vs.commit(tx, tc.records)
+			switch {
+			case tc.expectedErrorMsg != "":
+				assert.Contains(t, err.Error(), tc.expectedErrorMsg, tc.name)
+			default:
+				assert.NoError(t, err, tc.name)
+			}
+			mockVulnerabilityStore.AssertExpectations(t)
+		})
+	}
+}
@@ -1,83 +1,237 @@
 package vulnsrc
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"k8s.io/utils/clock"
 
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/alpine"
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/amazon"
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/debian"
-	debianoval "github.com/aquasecurity/trivy-db/pkg/vulnsrc/debian-oval"
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/nvd"
-	oracleoval "github.com/aquasecurity/trivy-db/pkg/vulnsrc/oracle-oval"
-	redhatoval "github.com/aquasecurity/trivy-db/pkg/vulnsrc/redhat-oval"
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/ubuntu"
-
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/bundler"
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/cargo"
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/composer"
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/node"
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/python"
-	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/redhat"
+	// the sources, which register themselves
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/alpine"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/amazon"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/bdu"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/bundler"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/cargo"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/composer"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/debian"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/debian-oval"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/node"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/nvd"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/oracle-oval"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/python"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/redhat"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/redhat-oval"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/ssvc"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/ubuntu"
+	_ "github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnrichment"
 
 	"github.com/aquasecurity/trivy-db/pkg/db"
-	"github.com/aquasecurity/trivy-db/pkg/types"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/plugin"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
 	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 
-	bolt "github.com/etcd-io/bbolt"
-
 	"golang.org/x/xerrors"
 )
 
+// progressInterval is how often the progress of a source is reported while it is updated
+const progressInterval = time.Minute
+
+// ErrUnknownSource is returned for a source name no package registered, e.g. a typo in --only-update
+var ErrUnknownSource = xerrors.New("unknown source")
+
+// VulnSrc is the part of a source the Updater runs, see registry.VulnSrc
 type VulnSrc interface {
-	Update(string) error
+	Update(context.Context, string) error
 }
 
 var (
 	// UpdateList has list of update distributions
 	UpdateList []string
-	updateMap  = map[string]VulnSrc{
-		vulnerability.Nvd:                   nvd.NewVulnSrc(),
-		vulnerability.Alpine:                alpine.NewVulnSrc(),
-		vulnerability.RedHat:                redhat.NewVulnSrc(),
-		vulnerability.RedHatOVAL:            redhatoval.NewVulnSrc(),
-		vulnerability.Debian:                debian.NewVulnSrc(),
-		vulnerability.DebianOVAL:            debianoval.NewVulnSrc(),
-		vulnerability.Ubuntu:                ubuntu.NewVulnSrc(),
-		vulnerability.Amazon:                amazon.NewVulnSrc(),
-		vulnerability.OracleOVAL:            oracleoval.NewVulnSrc(),
-		vulnerability.RubySec:               bundler.NewVulnSrc(),
-		vulnerability.PhpSecurityAdvisories: composer.NewVulnSrc(),
-		vulnerability.NodejsSecurityWg:      node.NewVulnSrc(),
-		vulnerability.PythonSafetyDB:        python.NewVulnSrc(),
-		vulnerability.RustSec:               cargo.NewVulnSrc(),
-	}
+	// updateMap has the sources registered by the packages imported above
+	updateMap = map[string]VulnSrc{}
+
+	// OptionalList has sources that are not updated by default since they need to be provided manually
+	OptionalList []string
+
+	// repositories are the git repositories in the cache dir the sources are read from
+	repositories = map[string]string{}
+
+	// incrementalSources read each of their records from a file of its own, which their file walk is
+	// limited to by an incremental update when it changed since the previous one
+	incrementalSources []string
 )
 
 func init() {
-	UpdateList = make([]string, 0, len(updateMap))
-	for distribution := range updateMap {
-		UpdateList = append(UpdateList, distribution)
+	for _, name := range registry.Names() {
+		source, _ := registry.Get(name)
+		add(source)
+	}
+}
+
+func add(source registry.Source) {
+	name := source.Name()
+	updateMap[name] = source
+	if source.Repository != "" {
+		repositories[name] = source.Repository
+	}
+	if source.Incremental {
+		incrementalSources = append(incrementalSources, name)
+	}
+	if source.Optional {
+		OptionalList = append(OptionalList, name)
+	} else {
+		UpdateList = append(UpdateList, name)
 	}
 }
 
-type Operation interface {
-	SetMetadata(db.Metadata) error
+// AddPlugin registers the executable at path as the source named name, updated by default, see plugin
+func AddPlugin(name, path string) error {
+	source := registry.Source{VulnSrc: plugin.NewVulnSrc(name, path)}
+	if err := registry.Add(source); err != nil {
+		return xerrors.Errorf("invalid plugin: %w", err)
+	}
+	add(source)
+	vulnerability.AddDetailSource(name)
+	return nil
 }
 
+// Targets returns the sources to update: only, or UpdateList when only is empty, except the ones in skip.
+// The optional sources are only updated when they are in only. An unknown source is an error, so that
+// a typo doesn't silently rebuild nothing.
+func Targets(only, skip []string) ([]string, error) {
+	if len(only) == 0 {
+		only = UpdateList
+	}
+	for _, name := range append(append([]string{}, only...), skip...) {
+		if _, ok := updateMap[name]; !ok {
+			return nil, xerrors.Errorf("%s: %w", name, ErrUnknownSource)
+		}
+	}
+
+	var targets []string
+	for _, name := range only {
+		if !utils.StringInSlice(name, skip) && !utils.StringInSlice(name, targets) {
+			targets = append(targets, name)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, xerrors.New("no source to update")
+	}
+	return targets, nil
+}
+
+// Repositories returns the git repositories in the cache dir the targets are read from, in the order of targets
+func Repositories(targets []string) []string {
+	var repos []string
+	for _, target := range targets {
+		if repo, ok := repositories[target]; ok && !utils.StringInSlice(repo, repos) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+type operations interface {
+	db.MetadataStore
+	db.Pruner
+	db.Checkpointer
+	db.Journal
+	db.ShardMerger
+}
+
+// ShardBuilder updates a source into a DB of its own, e.g. in a child process, and returns the path of the DB
+type ShardBuilder func(ctx context.Context, source string) (string, error)
+
 type Updater struct {
-	dbc            Operation
+	dbc            operations
 	updateMap      map[string]VulnSrc
 	cacheDir       string
 	dbType         db.Type
 	updateInterval time.Duration
-	clock          clock.Clock
-	optimizer      Optimizer
+	// validFor is the validity window of the DB, 0 to let it expire at the next update
+	validFor time.Duration
+	// channel is the release channel recorded in the metadata
+	channel string
+	// sourceTimeout bounds the update of each source, 0 for no limit
+	sourceTimeout time.Duration
+	// checkpoint makes the update resume after the sources an interrupted one completed
+	checkpoint bool
+	// buildShard, if not nil, builds the sources into shards, up to parallel at once, which are merged in turn
+	buildShard ShardBuilder
+	parallel   int
+	// workers is the number of sources updated at once in this process, see updateConcurrently.
+	// The timeout of a source includes the time it waits for its turn to commit.
+	workers int
+	// options override the cache dir and the timeout of some sources
+	options map[string]SourceOptions
+	// onError is the failure policy of the sources without one, FailOnError if empty
+	onError FailurePolicy
+	// retries are the attempts after the first of the sources failing with a transient error, see IsTransient,
+	// after a backoff doubled each time
+	retries int
+	backoff time.Duration
+	// observe, if not nil, is told the progress of each source once its update is over
+	observe Observer
+	// incremental limits the walk of the incremental sources to the files changed in their git repository
+	// since the revision of their previous update
+	incremental bool
+	// changed are the files the incremental sources walk, relative to their cache dir, keyed by the source
+	changed   map[string][]string
+	clock     clock.Clock
+	optimizer Optimizer
+	// passes run after the ones of the optimizer, e.g. dedup
+	passes      []Pass
+	observePass PassObserver
+	// logger is the logger of the update, the default logger if nil
+	logger *slog.Logger
 }
 
-func NewUpdater(cacheDir string, light bool, interval time.Duration) Updater {
+// SourceOptions are the options of a source which differ from the ones of the update
+type SourceOptions struct {
+	CacheDir string        // the dir the repository of the source is read from
+	Timeout  time.Duration // bounds the update of the source
+	OnError  FailurePolicy // what the failure of the source does to the update
+	MinFiles int           // the input files Preflight expects at least, 1 if 0
+	Retries  int           // the attempts after the first of a source failing with a transient error
+}
+
+// FailurePolicy is what the failure of a source does to the update. Either way, the writes of the source
+// are rolled back and the other sources are updated.
+type FailurePolicy string
+
+const (
+	// FailOnError makes the update fail with the failures of all the sources once they are updated
+	FailOnError FailurePolicy = "fail"
+	// ContinueOnError keeps the previous advisories and metadata of the source and reports its failure
+	ContinueOnError FailurePolicy = "continue"
+)
+
+// SourceErrors are the failures of the sources of an update, keyed by the source
+type SourceErrors map[string]error
+
+func (e SourceErrors) Error() string {
+	var names []string
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var msgs []string
+	for _, name := range names {
+		msgs = append(msgs, e[name].Error())
+	}
+	return fmt.Sprintf("%d source(s) failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func NewUpdater(cacheDir string, light bool, interval, validFor, sourceTimeout time.Duration,
+	checkpoint bool) Updater {
 	var optimizer Optimizer
 	dbConfig := db.Config{}
 	dbType := db.TypeFull
@@ -94,98 +248,630 @@ func NewUpdater(cacheDir string, light bool, interval time.Duration) Updater {
 		cacheDir:       cacheDir,
 		dbType:         dbType,
 		updateInterval: interval,
+		validFor:       validFor,
+		sourceTimeout:  sourceTimeout,
+		checkpoint:     checkpoint,
 		clock:          clock.RealClock{},
 		optimizer:      optimizer,
 	}
 }
 
-func (u Updater) Update(targets []string) error {
-	log.Println("Updating vulnerability database...")
+// WithShards makes Update build the sources into shards in parallel before merging them in turn,
+// as the DB has a single writer
+func (u Updater) WithShards(build ShardBuilder, parallel int) Updater {
+	if parallel < 1 {
+		parallel = 1
+	}
+	u.buildShard, u.parallel = build, parallel
+	return u
+}
 
-	for _, distribution := range targets {
-		vulnSrc, ok := u.updateMap[distribution]
-		if !ok {
-			return xerrors.Errorf("%s does not supported yet", distribution)
+// WithFailurePolicy sets what the failure of a source does to the update, unless its options say otherwise
+func (u Updater) WithFailurePolicy(onError FailurePolicy) Updater {
+	u.onError = onError
+	return u
+}
+
+// WithRetries makes Update try again up to retries times the sources failing with a transient error, unless
+// their options say otherwise, waiting backoff before the first retry and twice as long before each next one
+func (u Updater) WithRetries(retries int, backoff time.Duration) Updater {
+	u.retries, u.backoff = retries, backoff
+	return u
+}
+
+// WithSourceOptions overrides the cache dir, the timeout, the failure policy and the retries of the sources
+// in options
+func (u Updater) WithSourceOptions(options map[string]SourceOptions) Updater {
+	u.options = options
+	return u
+}
+
+// Observer is told the progress of the update of a source once it is over, and its error if it failed
+type Observer func(source string, progress *log.Progress, err error)
+
+// WithObserver makes Update tell observe how each source went, e.g. for the metrics of the build
+func (u Updater) WithObserver(observe Observer) Updater {
+	u.observe = observe
+	return u
+}
+
+// WithIncremental makes Update only read the files changed in the git repositories of the sources which
+// support it since their previous update, and only prune the advisories read from these files. The sources
+// whose previous revision is unknown or no longer in the history, e.g. of a shallow clone, read all the files.
+func (u Updater) WithIncremental(incremental bool) Updater {
+	u.incremental = incremental
+	return u
+}
+
+// WithChannel records the release channel the DB is built for in its metadata, see db.Channels
+func (u Updater) WithChannel(channel string) Updater {
+	u.channel = channel
+	return u
+}
+
+// WithPasses makes Update run passes in turn after the ones of the optimizer, e.g. NewPass("dedup", dbc.Dedup)
+func (u Updater) WithPasses(passes ...Pass) Updater {
+	u.passes = append(append([]Pass{}, u.passes...), passes...)
+	return u
+}
+
+// WithPassObserver makes Update tell observe how each pass of the optimization went, e.g. for the metrics
+// of the build
+func (u Updater) WithPassObserver(observe PassObserver) Updater {
+	u.observePass = observe
+	return u
+}
+
+// WithWorkers makes Update parse up to workers sources at once, committing them in turn.
+// Shards, which are built in parallel by themselves, take precedence.
+func (u Updater) WithWorkers(workers int) Updater {
+	u.workers = workers
+	return u
+}
+
+// WithLogger makes the update log to l rather than to the default logger, and the DB maintenance it does,
+// e.g. pruning, too
+func (u Updater) WithLogger(l *slog.Logger) Updater {
+	u.logger = l
+	if dbc, ok := u.dbc.(db.Config); ok {
+		dbc.Logger = l
+		u.dbc = dbc
+	}
+	return u
+}
+
+// Update updates the targets in turn. A canceled ctx stops the source being updated, whose committed
+// batches are rolled back, and the sources after it. With checkpoints, each completed source is recorded
+// and skipped by the next update until this one is over, unless the revision of its input changed.
+// A failed source is rolled back too and the others are updated, then its failure policy applies: the
+// update returns the SourceErrors of the sources which fail it, and logs the others.
+func (u Updater) Update(ctx context.Context, targets []string) error {
+	log.OrDefault(u.logger).Info("Updating vulnerability database...")
+
+	// the sources not updated this time keep their records
+	sources := map[string]db.SourceMetadata{}
+	metadata, err := u.dbc.GetMetadata()
+	if err == nil {
+		for name, s := range metadata.Sources {
+			sources[name] = s
 		}
-		log.Printf("Updating %s data...\n", distribution)
+	}
+	if u.incremental && err == nil {
+		u.changed = u.changes(ctx, targets, metadata)
+		if o, ok := u.optimizer.(fullOptimizer); ok && len(u.changed) > 0 {
+			o.merge = true
+			u.optimizer = o
+		}
+	}
 
-		if err := vulnSrc.Update(u.cacheDir); err != nil {
-			return xerrors.Errorf("error in %s update: %w", distribution, err)
+	completed := map[string]db.SourceMetadata{}
+	if u.checkpoint {
+		checkpoints, err := u.dbc.Checkpoints()
+		if err != nil {
+			return xerrors.Errorf("failed to get checkpoints: %w", err)
 		}
+		for name, source := range checkpoints {
+			// the input was updated since, e.g. vuln-list was pulled again before the build was restarted
+			if revision := u.revision(name); revision != source.Revision {
+				log.OrDefault(u.logger).Info("Updating again the source whose input changed since its checkpoint", "source", name,
+					"checkpoint_revision", source.Revision, "revision", revision)
+				continue
+			}
+			completed[name] = source
+		}
+	}
+
+	update := u.updateInTurn
+	if u.workers > 1 && u.buildShard == nil {
+		update = u.updateConcurrently
+	}
+	failed := SourceErrors{}
+	if err := update(ctx, targets, completed, sources, failed); err != nil {
+		return err
+	}
+	if err := u.reportFailures(failed); err != nil {
+		return err
 	}
 
-	err := u.dbc.SetMetadata(db.Metadata{
+	err = u.dbc.SetMetadata(db.Metadata{
 		Version:    db.SchemaVersion,
 		Type:       u.dbType,
 		NextUpdate: u.clock.Now().UTC().Add(u.updateInterval),
 		UpdatedAt:  u.clock.Now().UTC(),
+		ValidFor:   u.validFor,
+		Channel:    u.channel,
+		Sources:    sources,
 	})
 	if err != nil {
 		return xerrors.Errorf("failed to save metadata: %w", err)
 	}
 
-	return u.optimizer.Optimize()
+	if err = u.optimize(); err != nil {
+		return err
+	}
+	if u.checkpoint {
+		if err = u.dbc.ClearCheckpoints(); err != nil {
+			return xerrors.Errorf("failed to clear checkpoints: %w", err)
+		}
+	}
+	return nil
 }
 
-type Optimizer interface {
-	Optimize() error
+// optimize runs the passes of the optimizer, then the ones of WithPasses. An optimizer which isn't made of
+// passes, e.g. a mock, runs as a whole.
+func (u Updater) optimize() error {
+	var passes []Pass
+	if p, ok := u.optimizer.(passer); ok {
+		passes = p.Passes()
+	} else if err := u.optimizer.Optimize(); err != nil {
+		return err
+	}
+	return passOptimizer{passes: append(passes, u.passes...), observe: u.observePass, clock: u.clock}.Optimize()
 }
 
-type fullOptimizer struct {
-	dbc db.Operations
+// reportFailures logs the failures of the sources which continue on error, and returns the others
+func (u Updater) reportFailures(failed SourceErrors) error {
+	var names []string
+	for name := range failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fatal := SourceErrors{}
+	for _, name := range names {
+		if u.sourceOptions(name).OnError == ContinueOnError {
+			log.OrDefault(u.logger).Warn("Keeping the previous advisories of the failed source", "source", name, log.Err(failed[name]))
+			continue
+		}
+		fatal[name] = failed[name]
+	}
+	if len(failed) > 0 {
+		log.OrDefault(u.logger).Warn("Some sources failed", "failed", len(failed), "fatal", len(fatal), "sources", strings.Join(names, ","))
+	}
+	if len(fatal) > 0 {
+		return fatal
+	}
+	return nil
 }
 
-func (o fullOptimizer) Optimize() error {
-	err := o.dbc.ForEachSeverity(func(tx *bolt.Tx, cveID string, _ types.Severity) error {
-		severity, title, description, references := vulnerability.GetDetail(cveID)
-		vuln := types.Vulnerability{
-			Title:       title,
-			Description: description,
-			Severity:    severity.String(),
-			References:  references,
+// updateInTurn updates the targets one after the other, from their shards if they are built in parallel,
+// adds their metadata to sources and their failures to failed
+func (u Updater) updateInTurn(ctx context.Context, targets []string, completed,
+	sources map[string]db.SourceMetadata, failed SourceErrors) error {
+	shards := map[string]string{}
+	if u.buildShard != nil {
+		var pending []string
+		for _, distribution := range targets {
+			if _, ok := completed[distribution]; !ok {
+				pending = append(pending, distribution)
+			}
 		}
-		if err := o.dbc.PutVulnerability(tx, cveID, vuln); err != nil {
-			return xerrors.Errorf("failed to put vulnerability: %w", err)
+		var err error
+		if shards, err = u.buildShards(ctx, pending, failed); err != nil {
+			return err
 		}
-		return nil
-	})
-	if err != nil {
-		return xerrors.Errorf("failed to iterate severity: %w", err)
 	}
 
-	if err := o.dbc.DeleteSeverityBucket(); err != nil {
-		return xerrors.Errorf("failed to delete severity bucket: %w", err)
+	for _, distribution := range targets {
+		if err := ctx.Err(); err != nil {
+			return xerrors.Errorf("update canceled before %s: %w", distribution, err)
+		}
+		if source, ok := completed[distribution]; ok {
+			log.OrDefault(u.logger).Info("Skipping the source completed by the interrupted update", "source", distribution)
+			sources[distribution] = source
+			continue
+		}
+		if _, ok := failed[distribution]; ok {
+			// its shard
+			continue
+		}
+		vulnSrc, ok := u.updateMap[distribution]
+		if !ok {
+			return xerrors.Errorf("%s: %w", distribution, ErrUnknownSource)
+		}
+		log.OrDefault(u.logger).Info("Updating", "source", distribution)
+
+		if err := u.begin(distribution); err != nil {
+			return err
+		}
+		update := func(ctx context.Context) error { return u.update(ctx, distribution, vulnSrc) }
+		if path, ok := shards[distribution]; ok {
+			update = func(ctx context.Context) error { return u.dbc.MergeShard(ctx, path, distribution) }
+		}
+		restart := func() error {
+			if err := u.dbc.RollbackJournal(); err != nil {
+				return xerrors.Errorf("error in %s journal: %w", distribution, err)
+			}
+			return u.begin(distribution)
+		}
+		updateErr := u.withProgress(ctx, distribution, func(ctx context.Context) error {
+			return u.retry(ctx, distribution, update, restart)
+		})
+		source, err := u.finish(distribution, true, updateErr, sources[distribution])
+		if updateErr != nil && ctx.Err() == nil {
+			failed[distribution] = err
+			continue
+		} else if err != nil {
+			return err
+		}
+		sources[distribution] = source
 	}
+	return nil
+}
+
+// updateConcurrently runs the updates of the targets on up to u.workers goroutines. The sources are parsed
+// concurrently, but their first write waits until the sources before them in targets are committed, as
+// the journal and the write tracking of the DB are those of a single source. Failures are reported in
+// the same order, so the DB ends up as if the targets were updated in turn.
+func (u Updater) updateConcurrently(ctx context.Context, targets []string, completed,
+	sources map[string]db.SourceMetadata, failed SourceErrors) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	if err := o.dbc.DeleteVulnerabilityDetailBucket(); err != nil {
-		return xerrors.Errorf("failed to delete vulnerability detail bucket: %w", err)
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	// the slots are taken in the order of targets, so the source whose turn it is always has one
+	sem := make(chan struct{}, u.workers)
+	prev := make(chan struct{})
+	close(prev)
+	for _, distribution := range targets {
+		if source, ok := completed[distribution]; ok {
+			log.OrDefault(u.logger).Info("Skipping the source completed by the interrupted update", "source", distribution)
+			mu.Lock()
+			sources[distribution] = source
+			mu.Unlock()
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		turn, done := prev, make(chan struct{})
+		prev = done
+		wg.Add(1)
+		go func(distribution string) {
+			defer wg.Done()
+			// the next source waits for the result of this one
+			defer close(done)
+			defer func() { <-sem }()
+
+			var entered bool
+			enter := func() error {
+				select {
+				case <-turn:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := u.begin(distribution); err != nil {
+					return err
+				}
+				entered = true
+				return nil
+			}
+
+			// the writes of a failed attempt are those of the source whose turn it is
+			restart := func() error {
+				if !entered {
+					return nil
+				}
+				if err := u.dbc.RollbackJournal(); err != nil {
+					return xerrors.Errorf("error in %s journal: %w", distribution, err)
+				}
+				return u.begin(distribution)
+			}
+
+			vulnSrc, ok := u.updateMap[distribution]
+			var updateErr error
+			if ok {
+				log.OrDefault(u.logger).Info("Updating", "source", distribution)
+				updateErr = u.withProgress(db.WithCommitGate(ctx, enter), distribution, func(ctx context.Context) error {
+					return u.retry(ctx, distribution, func(ctx context.Context) error {
+						return u.update(ctx, distribution, vulnSrc)
+					}, restart)
+				})
+			}
+
+			<-turn
+			mu.Lock()
+			stopped, previous := firstErr != nil, sources[distribution]
+			mu.Unlock()
+			if stopped {
+				return
+			}
+			var err error
+			if !ok {
+				err = xerrors.Errorf("%s: %w", distribution, ErrUnknownSource)
+			} else if updateErr == nil && !entered {
+				// a source which wrote nothing still prunes what it no longer has
+				err = enter()
+			}
+			source, ferr := u.finish(distribution, entered, updateErr, previous)
+			if err == nil {
+				err = ferr
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case ok && updateErr != nil && ctx.Err() == nil:
+				failed[distribution] = err
+			case err != nil:
+				firstErr = err
+				cancel()
+			default:
+				sources[distribution] = source
+			}
+		}(distribution)
 	}
+	wg.Wait()
 
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return xerrors.Errorf("update canceled: %w", err)
+	}
 	return nil
-
 }
 
-type lightOptimizer struct {
-	dbc db.Operations
+// begin starts the journal and the write tracking of a source
+func (u Updater) begin(distribution string) error {
+	if err := u.dbc.StartJournal(distribution); err != nil {
+		return xerrors.Errorf("error in %s journal: %w", distribution, err)
+	}
+	u.dbc.TrackWrites()
+	return nil
 }
 
-func (o lightOptimizer) Optimize() error {
-	err := o.dbc.ForEachSeverity(func(tx *bolt.Tx, cveID string, _ types.Severity) error {
-		// get correct severity
-		sev, _, _, _ := vulnerability.GetDetail(cveID)
+// finish commits the writes of a source begun with begin, or rolls them back when its update failed,
+// prunes what it no longer has and returns its metadata, which follows the previous one
+func (u Updater) finish(distribution string, begun bool, err error, previous db.SourceMetadata) (db.SourceMetadata,
+	error) {
+	if err != nil {
+		// the batches committed before the failure would leave the source half written
+		if begun {
+			if rerr := u.dbc.RollbackJournal(); rerr != nil {
+				log.OrDefault(u.logger).Error("Failed to roll back", "source", distribution, log.Err(rerr))
+			}
+		}
+		return db.SourceMetadata{}, xerrors.Errorf("error in %s update: %w", distribution, err)
+	}
+	if err = u.dbc.CommitJournal(); err != nil {
+		return db.SourceMetadata{}, xerrors.Errorf("error in %s journal: %w", distribution, err)
+	}
+	// advisories retracted upstream
+	var added int
+	if files, ok := u.changed[distribution]; ok {
+		set := map[string]bool{}
+		for _, file := range files {
+			set[filepath.ToSlash(file)] = true
+		}
+		added, err = u.dbc.PruneChanged(distribution, set)
+	} else {
+		added, err = u.dbc.Prune(distribution)
+	}
+	if err != nil {
+		return db.SourceMetadata{}, xerrors.Errorf("error in %s prune: %w", distribution, err)
+	}
+
+	source := db.SourceMetadata{UpdatedAt: u.clock.Now().UTC(), Revision: u.revision(distribution),
+		NewestRecordAt: previous.NewestRecordAt}
+	if added > 0 {
+		source.NewestRecordAt = source.UpdatedAt
+	}
 
-		// overwrite unknown severity with correct severity
-		if err := o.dbc.PutSeverity(tx, cveID, sev); err != nil {
-			return xerrors.Errorf("failed to put severity: %w", err)
+	if u.checkpoint {
+		if err = u.dbc.Checkpoint(distribution, source); err != nil {
+			return db.SourceMetadata{}, xerrors.Errorf("error in %s checkpoint: %w", distribution, err)
 		}
+	}
+	return source, nil
+}
+
+// revision returns the commit of the git repository the source is read from, empty if unknown
+func (u Updater) revision(distribution string) string {
+	repo, ok := repositories[distribution]
+	if !ok {
+		return ""
+	}
+	revision, err := utils.GitRevision(filepath.Join(u.sourceOptions(distribution).CacheDir, repo))
+	if err != nil {
+		log.OrDefault(u.logger).Warn("Failed to get the revision", "repository", repo, log.Err(err))
+	}
+	return revision
+}
+
+// changes returns the files the incremental sources among targets walk, relative to their cache dir, which
+// changed in their git repository since the revision of the previous update in metadata. The sources which
+// can't tell are left out and read all the files.
+func (u Updater) changes(ctx context.Context, targets []string, metadata db.Metadata) map[string][]string {
+	// the light DB doesn't keep the provenance of the advisories pruning the changed files needs
+	if u.dbType == db.TypeLight || metadata.Type != u.dbType {
+		log.OrDefault(u.logger).Info("Updating all the files, the incremental update needs a full DB built before")
 		return nil
+	}
+	changed := map[string][]string{}
+	for _, target := range targets {
+		previous := metadata.Sources[target].Revision
+		if !utils.StringInSlice(target, incrementalSources) || previous == "" {
+			continue
+		}
+		repo := repositories[target]
+		dir := filepath.Join(u.sourceOptions(target).CacheDir, repo)
+		files, err := utils.GitChanges(ctx, dir, previous)
+		if err != nil {
+			log.OrDefault(u.logger).Warn("Updating all the files of the source, the changes since its previous update are unknown",
+				"source", target, "revision", previous, log.Err(err))
+			continue
+		}
+		for i, file := range files {
+			files[i] = filepath.Join(repo, file)
+		}
+		log.OrDefault(u.logger).Info("Updating the files changed since the previous update", "source", target, "revision", previous,
+			"files", len(files))
+		changed[target] = files
+	}
+	return changed
+}
+
+// UpdateShard updates a single source into the new DB of a shard for MergeShard. The metadata and
+// the optimizations are left to the update merging the shards.
+func (u Updater) UpdateShard(ctx context.Context, source string) error {
+	vulnSrc, ok := u.updateMap[source]
+	if !ok {
+		return xerrors.Errorf("%s: %w", source, ErrUnknownSource)
+	}
+	// Prune records the advisories MergeShard tracks
+	u.dbc.TrackWrites()
+	err := u.withProgress(ctx, source, func(ctx context.Context) error {
+		// the writes of a failed attempt are put again by the next one
+		return u.retry(ctx, source, func(ctx context.Context) error {
+			return u.update(ctx, source, vulnSrc)
+		}, func() error {
+			u.dbc.TrackWrites()
+			return nil
+		})
 	})
 	if err != nil {
-		return xerrors.Errorf("failed to iterate severity: %w", err)
+		return xerrors.Errorf("error in %s update: %w", source, err)
 	}
+	if _, err := u.dbc.Prune(source); err != nil {
+		return xerrors.Errorf("error in %s prune: %w", source, err)
+	}
+	return nil
+}
 
-	if err = o.dbc.DeleteVulnerabilityDetailBucket(); err != nil {
-		return xerrors.Errorf("failed to delete vulnerability detail bucket: %w", err)
+// buildShards builds the shards of the targets, up to u.parallel at once, and adds the failures of
+// the sources to failed
+func (u Updater) buildShards(ctx context.Context, targets []string, failed SourceErrors) (map[string]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	shards := map[string]string{}
+	sem := make(chan struct{}, u.parallel)
+	for _, distribution := range targets {
+		if _, ok := u.updateMap[distribution]; !ok {
+			// reported by Update
+			continue
+		}
+		wg.Add(1)
+		go func(distribution string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.OrDefault(u.logger).Info("Building the shard", "source", distribution)
+			path, err := u.buildShard(ctx, distribution)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil && ctx.Err() == nil:
+				failed[distribution] = xerrors.Errorf("error in %s shard: %w", distribution, err)
+			case err != nil:
+				if firstErr == nil {
+					firstErr = xerrors.Errorf("error in %s shard: %w", distribution, err)
+				}
+			default:
+				shards[distribution] = path
+			}
+		}(distribution)
 	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return shards, nil
+}
+
+// withProgress runs fn with a ctx counting the progress of the source, which is reported every
+// progressInterval and at the end
+func (u Updater) withProgress(ctx context.Context, source string, fn func(context.Context) error) error {
+	ctx, progress := log.WithProgress(ctx, source)
+	stop := progress.ReportEvery(progressInterval)
+	err := fn(ctx)
+	stop()
+	if u.observe != nil {
+		u.observe(source, progress, err)
+	}
+	if err != nil {
+		return err
+	}
+	progress.Report("Updated")
 	return nil
 }
+
+func (u Updater) update(ctx context.Context, distribution string, vulnSrc VulnSrc) error {
+	options := u.sourceOptions(distribution)
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	if files, ok := u.changed[distribution]; ok {
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, filepath.Join(options.CacheDir, file))
+		}
+		ctx = utils.WithFiles(ctx, paths)
+	}
+	return vulnSrc.Update(ctx, options.CacheDir)
+}
+
+// sourceOptions returns the options of the source, with the ones of the update as defaults
+func (u Updater) sourceOptions(distribution string) SourceOptions {
+	options := u.options[distribution]
+	if options.CacheDir == "" {
+		options.CacheDir = u.cacheDir
+	}
+	if options.Timeout == 0 {
+		options.Timeout = u.sourceTimeout
+	}
+	if options.Retries == 0 {
+		options.Retries = u.retries
+	}
+	if options.OnError == "" {
+		options.OnError = u.onError
+	}
+	if options.OnError == "" {
+		options.OnError = FailOnError
+	}
+	return options
+}
@@ -1,7 +1,16 @@
 package vulnsrc
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -13,7 +22,13 @@ import (
 	"k8s.io/utils/clock"
 	ct "k8s.io/utils/clock/testing"
 
+	"golang.org/x/xerrors"
+
 	"github.com/aquasecurity/trivy-db/pkg/db"
+	"github.com/aquasecurity/trivy-db/pkg/log"
+	"github.com/aquasecurity/trivy-db/pkg/utils"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/registry"
+	"github.com/aquasecurity/trivy-db/pkg/vulnsrc/vulnerability"
 )
 
 type MockOptimizer struct {
@@ -27,16 +42,22 @@ func (_m *MockOptimizer) Optimize() error {
 
 func TestNewUpdater(t *testing.T) {
 	type args struct {
-		cacheDir string
-		light    bool
-		interval time.Duration
+		cacheDir   string
+		light      bool
+		interval   time.Duration
+		validFor   time.Duration
+		timeout    time.Duration
+		checkpoint bool
 	}
 	type want struct {
-		cacheDir  string
-		dbType    db.Type
-		interval  time.Duration
-		clock     clock.Clock
-		optimizer Optimizer
+		cacheDir   string
+		dbType     db.Type
+		interval   time.Duration
+		validFor   time.Duration
+		timeout    time.Duration
+		checkpoint bool
+		clock      clock.Clock
+		optimizer  Optimizer
 	}
 	tests := []struct {
 		name string
@@ -46,16 +67,22 @@ func TestNewUpdater(t *testing.T) {
 		{
 			name: "full",
 			args: args{
-				cacheDir: "/full",
-				light:    false,
-				interval: 60 * time.Hour,
+				cacheDir:   "/full",
+				light:      false,
+				interval:   60 * time.Hour,
+				validFor:   7 * 24 * time.Hour,
+				timeout:    30 * time.Minute,
+				checkpoint: true,
 			},
 			want: want{
-				cacheDir:  "/full",
-				dbType:    db.TypeFull,
-				clock:     clock.RealClock{},
-				interval:  60 * time.Hour,
-				optimizer: fullOptimizer{},
+				cacheDir:   "/full",
+				dbType:     db.TypeFull,
+				clock:      clock.RealClock{},
+				interval:   60 * time.Hour,
+				validFor:   7 * 24 * time.Hour,
+				timeout:    30 * time.Minute,
+				checkpoint: true,
+				optimizer:  fullOptimizer{},
 			},
 		},
 		{
@@ -76,45 +103,185 @@ func TestNewUpdater(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := NewUpdater(tt.args.cacheDir, tt.args.light, tt.args.interval)
+			got := NewUpdater(tt.args.cacheDir, tt.args.light, tt.args.interval, tt.args.validFor, tt.args.timeout,
+				tt.args.checkpoint)
 
 			assert.NotNil(t, got.dbc, tt.name)
 			assert.Equal(t, updateMap, got.updateMap, tt.name)
 			assert.Equal(t, tt.want.cacheDir, got.cacheDir, tt.name)
 			assert.Equal(t, tt.want.dbType, got.dbType, tt.name)
 			assert.Equal(t, tt.want.interval, got.updateInterval, tt.name)
+			assert.Equal(t, tt.want.validFor, got.validFor, tt.name)
+			assert.Equal(t, tt.want.timeout, got.sourceTimeout, tt.name)
+			assert.Equal(t, tt.want.checkpoint, got.checkpoint, tt.name)
 			assert.IsType(t, tt.want.clock, got.clock, tt.name)
 			assert.IsType(t, tt.want.optimizer, got.optimizer, tt.name)
 		})
 	}
 }
 
+func TestTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		only    []string
+		skip    []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "only",
+			only: []string{"amazon", "alpine", "amazon"},
+			want: []string{"amazon", "alpine"},
+		},
+		{
+			name: "skip",
+			only: []string{"amazon", "alpine", "ssvc"},
+			skip: []string{"alpine"},
+			want: []string{"amazon", "ssvc"},
+		},
+		{
+			name: "everything but the optional sources",
+			skip: []string{"nvd"},
+			want: func() []string {
+				var want []string
+				for _, name := range UpdateList {
+					if name != "nvd" {
+						want = append(want, name)
+					}
+				}
+				return want
+			}(),
+		},
+		{
+			name:    "unknown source",
+			only:    []string{"amazon"},
+			skip:    []string{"amazn"},
+			wantErr: "amazn: unknown source",
+		},
+		{
+			name:    "everything skipped",
+			only:    []string{"amazon"},
+			skip:    []string{"amazon"},
+			wantErr: "no source to update",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Targets(tt.only, tt.skip)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// mockOperations composes the mocks of the stores the updater uses
+type mockOperations struct {
+	*db.MockMetadataStore
+	*db.MockPruner
+	*db.MockCheckpointer
+	*db.MockJournal
+	*db.MockShardMerger
+}
+
+func newMockOperations() mockOperations {
+	return mockOperations{
+		MockMetadataStore: new(db.MockMetadataStore),
+		MockPruner:        new(db.MockPruner),
+		MockCheckpointer:  new(db.MockCheckpointer),
+		MockJournal:       new(db.MockJournal),
+		MockShardMerger:   new(db.MockShardMerger),
+	}
+}
+
+func (m mockOperations) AssertExpectations(t *testing.T) {
+	m.MockMetadataStore.AssertExpectations(t)
+	m.MockPruner.AssertExpectations(t)
+	m.MockCheckpointer.AssertExpectations(t)
+	m.MockJournal.AssertExpectations(t)
+	m.MockShardMerger.AssertExpectations(t)
+}
+
+// mockLightOperations composes the mocks of the stores the light optimizer uses
+type mockLightOperations struct {
+	*db.MockVulnerabilityStore
+	*db.MockAdvisoryStore
+	*db.MockCPEStore
+	*db.MockProvenanceStore
+}
+
+func newMockLightOperations() mockLightOperations {
+	return mockLightOperations{
+		MockVulnerabilityStore: new(db.MockVulnerabilityStore),
+		MockAdvisoryStore:      new(db.MockAdvisoryStore),
+		MockCPEStore:           new(db.MockCPEStore),
+		MockProvenanceStore:    new(db.MockProvenanceStore),
+	}
+}
+
+func (m mockLightOperations) AssertExpectations(t *testing.T) {
+	m.MockVulnerabilityStore.AssertExpectations(t)
+	m.MockAdvisoryStore.AssertExpectations(t)
+	m.MockCPEStore.AssertExpectations(t)
+	m.MockProvenanceStore.AssertExpectations(t)
+}
+
 func TestUpdater_Update(t *testing.T) {
 	type fields struct {
 		UpdateMap      map[string]VulnSrc
 		CacheDir       string
 		DBType         db.Type
 		UpdateInterval time.Duration
+		SourceTimeout  time.Duration
+		Checkpoint     bool
+		OnError        FailurePolicy
+		Channel        string
 		Clock          clock.Clock
+		// Revision is the HEAD of the git repository of the test source, which has none if empty
+		Revision string
 	}
 	type args struct {
-		targets []string
+		canceled bool
+		targets  []string
+	}
+	type getMetadata struct {
+		output db.Metadata
+		err    error
 	}
 	type setMetadata struct {
 		input  db.Metadata
 		output error
 	}
 	type update struct {
+		input    string
+		deadline bool
+		output   error
+	}
+	type optimize struct {
+		output error
+	}
+	type prune struct {
 		input  string
+		added  int
 		output error
 	}
-	type optimize struct {
+	type checkpoint struct {
+		input  string
 		output error
 	}
 	type mocks struct {
-		update      []update
-		setMetadata []setMetadata
-		optimize    []optimize
+		getMetadata      getMetadata
+		checkpoints      map[string]db.SourceMetadata
+		update           []update
+		trackWrites      int
+		prune            []prune
+		checkpoint       []checkpoint
+		setMetadata      []setMetadata
+		optimize         []optimize
+		clearCheckpoints bool
 	}
 	tests := []struct {
 		name    string
@@ -135,20 +302,236 @@ func TestUpdater_Update(t *testing.T) {
 				targets: []string{"test"},
 			},
 			mocks: mocks{
-				update: []update{{input: "cache"}},
+				getMetadata: getMetadata{
+					output: db.Metadata{
+						Sources: map[string]db.SourceMetadata{
+							"other": {UpdatedAt: time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC), Revision: "abc"},
+						},
+					},
+				},
+				update:      []update{{input: "cache"}},
+				trackWrites: 1,
+				prune:       []prune{{input: "test", added: 2}},
+				setMetadata: []setMetadata{
+					{
+						input: db.Metadata{
+							Version:    db.SchemaVersion,
+							Type:       db.TypeFull,
+							NextUpdate: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+							UpdatedAt:  time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+							Sources: map[string]db.SourceMetadata{
+								"other": {UpdatedAt: time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC), Revision: "abc"},
+								"test": {UpdatedAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+									NewestRecordAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+							},
+						},
+					},
+				},
+				optimize: []optimize{{output: nil}},
+			},
+		},
+		{
+			name: "channel",
+			fields: fields{
+				CacheDir:       "cache",
+				DBType:         db.TypeFull,
+				UpdateInterval: 12 * time.Hour,
+				Channel:        db.ChannelDev,
+				Clock:          ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			args: args{
+				targets: []string{"test"},
+			},
+			mocks: mocks{
+				update:      []update{{input: "cache"}},
+				trackWrites: 1,
+				prune:       []prune{{input: "test"}},
+				setMetadata: []setMetadata{
+					{
+						input: db.Metadata{
+							Version:    db.SchemaVersion,
+							Type:       db.TypeFull,
+							NextUpdate: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+							UpdatedAt:  time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+							Channel:    db.ChannelDev,
+							Sources: map[string]db.SourceMetadata{
+								"test": {UpdatedAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+							},
+						},
+					},
+				},
+				optimize: []optimize{{output: nil}},
+			},
+		},
+		{
+			name: "no new record",
+			fields: fields{
+				CacheDir:       "cache",
+				DBType:         db.TypeFull,
+				UpdateInterval: 12 * time.Hour,
+				Clock:          ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			args: args{
+				targets: []string{"test"},
+			},
+			mocks: mocks{
+				getMetadata: getMetadata{
+					output: db.Metadata{
+						Sources: map[string]db.SourceMetadata{
+							"test": {UpdatedAt: time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC),
+								NewestRecordAt: time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC)},
+						},
+					},
+				},
+				update:      []update{{input: "cache"}},
+				trackWrites: 1,
+				prune:       []prune{{input: "test"}},
+				// the newest record is the one of a previous update
 				setMetadata: []setMetadata{
 					{
 						input: db.Metadata{
-							Version:    1,
+							Version:    db.SchemaVersion,
 							Type:       db.TypeFull,
 							NextUpdate: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
 							UpdatedAt:  time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+							Sources: map[string]db.SourceMetadata{
+								"test": {UpdatedAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+									NewestRecordAt: time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC)},
+							},
 						},
 					},
 				},
 				optimize: []optimize{{output: nil}},
 			},
 		},
+		{
+			name: "resume after a checkpoint",
+			fields: fields{
+				CacheDir:       "cache",
+				DBType:         db.TypeFull,
+				UpdateInterval: 12 * time.Hour,
+				Checkpoint:     true,
+				Clock:          ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			args: args{
+				targets: []string{"done", "test"},
+			},
+			mocks: mocks{
+				checkpoints: map[string]db.SourceMetadata{
+					"done": {UpdatedAt: time.Date(2018, 12, 31, 23, 0, 0, 0, time.UTC)},
+				},
+				update:      []update{{input: "cache"}},
+				trackWrites: 1,
+				prune:       []prune{{input: "test"}},
+				checkpoint:  []checkpoint{{input: "test"}},
+				setMetadata: []setMetadata{
+					{
+						input: db.Metadata{
+							Version:    db.SchemaVersion,
+							Type:       db.TypeFull,
+							NextUpdate: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+							UpdatedAt:  time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+							Sources: map[string]db.SourceMetadata{
+								"done": {UpdatedAt: time.Date(2018, 12, 31, 23, 0, 0, 0, time.UTC)},
+								"test": {UpdatedAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+							},
+						},
+					},
+				},
+				optimize:         []optimize{{output: nil}},
+				clearCheckpoints: true,
+			},
+		},
+		{
+			name: "update again a checkpointed source whose input changed",
+			fields: fields{
+				DBType:         db.TypeFull,
+				UpdateInterval: 12 * time.Hour,
+				Checkpoint:     true,
+				Clock:          ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+				Revision:       "def",
+			},
+			args: args{
+				targets: []string{"test"},
+			},
+			mocks: mocks{
+				checkpoints: map[string]db.SourceMetadata{
+					"test": {UpdatedAt: time.Date(2018, 12, 31, 23, 0, 0, 0, time.UTC), Revision: "abc"},
+				},
+				update:      []update{{input: mock.Anything}},
+				trackWrites: 1,
+				prune:       []prune{{input: "test"}},
+				checkpoint:  []checkpoint{{input: "test"}},
+				setMetadata: []setMetadata{
+					{
+						input: db.Metadata{
+							Version:    db.SchemaVersion,
+							Type:       db.TypeFull,
+							NextUpdate: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+							UpdatedAt:  time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+							Sources: map[string]db.SourceMetadata{
+								"test": {UpdatedAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), Revision: "def"},
+							},
+						},
+					},
+				},
+				optimize:         []optimize{{output: nil}},
+				clearCheckpoints: true,
+			},
+		},
+		{
+			name: "Checkpoint returns an error",
+			fields: fields{
+				CacheDir:       "cache",
+				DBType:         db.TypeFull,
+				UpdateInterval: 12 * time.Hour,
+				Checkpoint:     true,
+				Clock:          ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			args: args{
+				targets: []string{"test"},
+			},
+			mocks: mocks{
+				checkpoints: map[string]db.SourceMetadata{},
+				update:      []update{{input: "cache"}},
+				trackWrites: 1,
+				prune:       []prune{{input: "test"}},
+				checkpoint:  []checkpoint{{input: "test", output: errors.New("error")}},
+			},
+			wantErr: "error in test checkpoint",
+		},
+		{
+			name: "source timeout",
+			fields: fields{
+				CacheDir:       "cache",
+				DBType:         db.TypeFull,
+				UpdateInterval: 12 * time.Hour,
+				SourceTimeout:  time.Hour,
+				Clock:          ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			args: args{
+				targets: []string{"test"},
+			},
+			mocks: mocks{
+				update:      []update{{input: "cache", deadline: true, output: context.DeadlineExceeded}},
+				trackWrites: 1,
+			},
+			wantErr: "error in test update: context deadline exceeded",
+		},
+		{
+			name: "canceled",
+			fields: fields{
+				CacheDir:       "cache",
+				DBType:         db.TypeFull,
+				UpdateInterval: 12 * time.Hour,
+				Clock:          ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			args: args{
+				canceled: true,
+				targets:  []string{"test"},
+			},
+			wantErr: "update canceled before test: context canceled",
+		},
 		{
 			name: "unknown target",
 			fields: fields{
@@ -160,7 +543,7 @@ func TestUpdater_Update(t *testing.T) {
 			args: args{
 				targets: []string{"unknown"},
 			},
-			wantErr: "unknown does not supported yet",
+			wantErr: "unknown: unknown source",
 		},
 		{
 			name: "Update returns an error",
@@ -174,10 +557,68 @@ func TestUpdater_Update(t *testing.T) {
 				targets: []string{"test"},
 			},
 			mocks: mocks{
-				update: []update{{input: "cache", output: errors.New("error")}},
+				update:      []update{{input: "cache", output: errors.New("error")}},
+				trackWrites: 1,
 			},
 			wantErr: "error in test update",
 		},
+		{
+			name: "Update returns an error, continue on error",
+			fields: fields{
+				CacheDir:       "cache",
+				DBType:         db.TypeFull,
+				UpdateInterval: 12 * time.Hour,
+				OnError:        ContinueOnError,
+				Clock:          ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			args: args{
+				targets: []string{"test"},
+			},
+			mocks: mocks{
+				getMetadata: getMetadata{
+					output: db.Metadata{
+						Sources: map[string]db.SourceMetadata{
+							"test": {UpdatedAt: time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC)},
+						},
+					},
+				},
+				update:      []update{{input: "cache", output: errors.New("error")}},
+				trackWrites: 1,
+				// the failed source keeps its metadata
+				setMetadata: []setMetadata{
+					{
+						input: db.Metadata{
+							Version:    db.SchemaVersion,
+							Type:       db.TypeFull,
+							NextUpdate: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+							UpdatedAt:  time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+							Sources: map[string]db.SourceMetadata{
+								"test": {UpdatedAt: time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC)},
+							},
+						},
+					},
+				},
+				optimize: []optimize{{}},
+			},
+		},
+		{
+			name: "Prune returns an error",
+			fields: fields{
+				CacheDir:       "cache",
+				DBType:         db.TypeFull,
+				UpdateInterval: 12 * time.Hour,
+				Clock:          ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			args: args{
+				targets: []string{"test"},
+			},
+			mocks: mocks{
+				update:      []update{{input: "cache"}},
+				trackWrites: 1,
+				prune:       []prune{{input: "test", output: errors.New("error")}},
+			},
+			wantErr: "error in test prune",
+		},
 		{
 			name: "SetMetadata returns an error",
 			fields: fields{
@@ -190,14 +631,19 @@ func TestUpdater_Update(t *testing.T) {
 				targets: []string{"test"},
 			},
 			mocks: mocks{
-				update: []update{{input: "cache"}},
+				update:      []update{{input: "cache"}},
+				trackWrites: 1,
+				prune:       []prune{{input: "test"}},
 				setMetadata: []setMetadata{
 					{
 						input: db.Metadata{
-							Version:    1,
+							Version:    db.SchemaVersion,
 							Type:       db.TypeFull,
 							NextUpdate: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
 							UpdatedAt:  time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+							Sources: map[string]db.SourceMetadata{
+								"test": {UpdatedAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+							},
 						},
 						output: errors.New("error"),
 					},
@@ -210,12 +656,55 @@ func TestUpdater_Update(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockVulnSrc := new(types.MockVulnSrc)
 			for _, u := range tt.mocks.update {
-				mockVulnSrc.On("Update", u.input).Return(u.output)
+				deadline := u.deadline
+				withDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+					_, ok := ctx.Deadline()
+					return ok == deadline
+				})
+				mockVulnSrc.On("Update", withDeadline, u.input).Return(u.output)
 			}
 
-			mockDBConfig := new(db.MockDBConfig)
+			cacheDir := tt.fields.CacheDir
+			if tt.fields.Revision != "" {
+				d, err := ioutil.TempDir("", "TestUpdater_Update_*")
+				assert.NoError(t, err)
+				defer os.RemoveAll(d)
+				assert.NoError(t, os.MkdirAll(filepath.Join(d, "test-repo", ".git"), 0700))
+				assert.NoError(t, ioutil.WriteFile(filepath.Join(d, "test-repo", ".git", "HEAD"),
+					[]byte(tt.fields.Revision+"\n"), 0600))
+				repositories["test"] = "test-repo"
+				defer delete(repositories, "test")
+				cacheDir = d
+			}
+
+			mockDB := newMockOperations()
+			mockDB.MockMetadataStore.On("GetMetadata").Return(tt.mocks.getMetadata.output, tt.mocks.getMetadata.err)
+			// each update is journaled, and rolled back when it fails
+			for _, u := range tt.mocks.update {
+				mockDB.MockJournal.On("StartJournal", "test").Return(nil)
+				if u.output == nil {
+					mockDB.MockJournal.On("CommitJournal").Return(nil)
+				} else {
+					mockDB.MockJournal.On("RollbackJournal").Return(nil)
+				}
+			}
+			if tt.mocks.trackWrites > 0 {
+				mockDB.MockPruner.On("TrackWrites").Times(tt.mocks.trackWrites)
+			}
+			for _, p := range tt.mocks.prune {
+				mockDB.MockPruner.On("Prune", p.input).Return(p.added, p.output)
+			}
+			if tt.fields.Checkpoint {
+				mockDB.MockCheckpointer.On("Checkpoints").Return(tt.mocks.checkpoints, nil)
+			}
+			for _, c := range tt.mocks.checkpoint {
+				mockDB.MockCheckpointer.On("Checkpoint", c.input, mock.Anything).Return(c.output)
+			}
+			if tt.mocks.clearCheckpoints {
+				mockDB.MockCheckpointer.On("ClearCheckpoints").Return(nil)
+			}
 			for _, sm := range tt.mocks.setMetadata {
-				mockDBConfig.On("SetMetadata", sm.input).Return(sm.output)
+				mockDB.MockMetadataStore.On("SetMetadata", sm.input).Return(sm.output)
 			}
 
 			mockOptimizer := new(MockOptimizer)
@@ -224,17 +713,32 @@ func TestUpdater_Update(t *testing.T) {
 			}
 
 			u := Updater{
-				dbc: mockDBConfig,
+				dbc: mockDB,
 				updateMap: map[string]VulnSrc{
 					"test": mockVulnSrc,
 				},
-				cacheDir:       tt.fields.CacheDir,
+				cacheDir:       cacheDir,
 				dbType:         tt.fields.DBType,
 				updateInterval: tt.fields.UpdateInterval,
+				sourceTimeout:  tt.fields.SourceTimeout,
+				checkpoint:     tt.fields.Checkpoint,
+				onError:        tt.fields.OnError,
+				channel:        tt.fields.Channel,
 				clock:          tt.fields.Clock,
 				optimizer:      mockOptimizer,
 			}
-			err := u.Update(tt.args.targets)
+			var observed []error
+			u = u.WithObserver(func(source string, progress *log.Progress, err error) {
+				assert.Equal(t, "test", source)
+				assert.NotNil(t, progress)
+				observed = append(observed, err)
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.args.canceled {
+				cancel()
+			}
+			err := u.Update(ctx, tt.args.targets)
 			switch {
 			case tt.wantErr != "":
 				assert.Contains(t, err.Error(), tt.wantErr, tt.name)
@@ -242,13 +746,182 @@ func TestUpdater_Update(t *testing.T) {
 				assert.NoError(t, err, tt.name)
 			}
 
+			// each update is observed with its outcome
+			if assert.Len(t, observed, len(tt.mocks.update), tt.name) {
+				for i, u := range tt.mocks.update {
+					assert.Equal(t, u.output != nil, observed[i] != nil, tt.name)
+				}
+			}
+
 			mockVulnSrc.AssertExpectations(t)
-			mockDBConfig.AssertExpectations(t)
+			mockDB.AssertExpectations(t)
+			mockOptimizer.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdater_Update_Shards(t *testing.T) {
+	tests := []struct {
+		name     string
+		shardErr error
+		onError  FailurePolicy
+		wantErr  string
+	}{
+		{
+			name: "merged",
+		},
+		{
+			name:     "the shard fails",
+			shardErr: errors.New("exit status 1"),
+			wantErr:  "1 source(s) failed: error in test shard: exit status 1",
+		},
+		{
+			name:     "the shard fails, continue on error",
+			shardErr: errors.New("exit status 1"),
+			onError:  ContinueOnError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+			mockDB := newMockOperations()
+			mockDB.MockMetadataStore.On("GetMetadata").Return(db.Metadata{}, nil)
+			if tt.shardErr == nil {
+				mockDB.MockJournal.On("StartJournal", "test").Return(nil)
+				mockDB.MockPruner.On("TrackWrites")
+				mockDB.MockShardMerger.On("MergeShard", mock.Anything, "shards/test/db/trivy.db", "test").Return(nil)
+				mockDB.MockJournal.On("CommitJournal").Return(nil)
+				mockDB.MockPruner.On("Prune", "test").Return(0, nil)
+			}
+			if tt.wantErr == "" {
+				mockDB.MockMetadataStore.On("SetMetadata", mock.Anything).Return(nil)
+			}
+			mockOptimizer := new(MockOptimizer)
+			if tt.wantErr == "" {
+				mockOptimizer.On("Optimize").Return(nil)
+			}
+
+			// the source is only updated by the shard
+			u := Updater{
+				dbc:       mockDB,
+				updateMap: map[string]VulnSrc{"test": new(types.MockVulnSrc)},
+				cacheDir:  "cache",
+				clock:     ct.NewFakeClock(now),
+				optimizer: mockOptimizer,
+			}.WithShards(func(_ context.Context, source string) (string, error) {
+				return "shards/" + source + "/db/trivy.db", tt.shardErr
+			}, 2).WithFailurePolicy(tt.onError)
+
+			err := u.Update(context.Background(), []string{"test"})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockDB.AssertExpectations(t)
 			mockOptimizer.AssertExpectations(t)
 		})
 	}
 }
 
+// severitySrc puts a severity after parsing for delay, recording the order of its commit
+type severitySrc struct {
+	name     string
+	delay    time.Duration
+	severity types.Severity
+	err      error
+	mu       *sync.Mutex
+	order    *[]string
+}
+
+func (s severitySrc) Update(ctx context.Context, _ string) error {
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return s.err
+	}
+	dbc := db.Config{}
+	return dbc.BatchUpdate(ctx, func(tx db.Tx) error {
+		s.mu.Lock()
+		*s.order = append(*s.order, s.name)
+		s.mu.Unlock()
+		return dbc.PutSeverity(tx, "CVE-2019-0001", s.severity)
+	})
+}
+
+func TestUpdater_Update_Workers(t *testing.T) {
+	tests := []struct {
+		name         string
+		fastErr      error
+		onError      FailurePolicy
+		wantOrder    []string
+		wantSeverity types.Severity
+		wantErr      string
+	}{
+		{
+			name:         "committed in the order of targets",
+			wantOrder:    []string{"slow", "fast", "idle"},
+			wantSeverity: types.SeverityLow,
+		},
+		{
+			name:         "the second source fails",
+			fastErr:      errors.New("error"),
+			wantOrder:    []string{"slow", "idle"},
+			wantSeverity: types.SeverityLow,
+			wantErr:      "1 source(s) failed: error in fast update: error",
+		},
+		{
+			name:         "the second source fails, continue on error",
+			fastErr:      errors.New("error"),
+			onError:      ContinueOnError,
+			wantOrder:    []string{"slow", "idle"},
+			wantSeverity: types.SeverityLow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ioutil.TempDir("", "TestUpdater_Update_Workers_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(d)
+			assert.NoError(t, db.Init(d))
+			defer db.Close()
+
+			var (
+				mu    sync.Mutex
+				order []string
+			)
+			src := func(name string, delay time.Duration, severity types.Severity, err error) severitySrc {
+				return severitySrc{name: name, delay: delay, severity: severity, err: err, mu: &mu, order: &order}
+			}
+			mockOptimizer := new(MockOptimizer)
+			if tt.wantErr == "" {
+				mockOptimizer.On("Optimize").Return(nil)
+			}
+			u := Updater{
+				dbc: db.Config{},
+				updateMap: map[string]VulnSrc{
+					"slow": src("slow", 50*time.Millisecond, types.SeverityHigh, nil),
+					"fast": src("fast", 0, types.SeverityLow, tt.fastErr),
+					"idle": src("idle", 0, types.SeverityLow, nil),
+				},
+				clock:     ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+				optimizer: mockOptimizer,
+			}.WithWorkers(2).WithFailurePolicy(tt.onError)
+
+			err = u.Update(context.Background(), []string{"slow", "fast", "idle"})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantOrder, order)
+
+			severity, err := db.Config{}.GetSeverity("CVE-2019-0001")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSeverity, severity)
+		})
+	}
+}
+
 func Test_fullOptimizer_Optimize(t *testing.T) {
 	type mocks struct {
 		forEachSeverity                 error
@@ -287,14 +960,14 @@ func Test_fullOptimizer_Optimize(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("ForEachSeverity", mock.Anything).Return(tt.mocks.forEachSeverity)
-			mockDBConfig.On("DeleteSeverityBucket").Return(tt.mocks.deleteSeverityBucket)
-			mockDBConfig.On("DeleteVulnerabilityDetailBucket").Return(
+			mockDB := new(db.MockVulnerabilityStore)
+			mockDB.On("ForEachSeverity", mock.Anything).Return(tt.mocks.forEachSeverity)
+			mockDB.On("DeleteSeverityBucket").Return(tt.mocks.deleteSeverityBucket)
+			mockDB.On("DeleteVulnerabilityDetailBucket").Return(
 				tt.mocks.deleteVulnerabilityDetailBucket)
 
 			o := fullOptimizer{
-				dbc: mockDBConfig,
+				dbc: mockDB,
 			}
 			err := o.Optimize()
 			switch {
@@ -311,6 +984,9 @@ func Test_lightOptimizer_Optimize(t *testing.T) {
 	type mocks struct {
 		forEachSeverity                 error
 		deleteVulnerabilityDetailBucket error
+		deleteCPEBucket                 error
+		deleteAffectedPackageBucket     error
+		deleteProvenanceBucket          error
 	}
 	tests := []struct {
 		name    string
@@ -334,16 +1010,40 @@ func Test_lightOptimizer_Optimize(t *testing.T) {
 			},
 			wantErr: "failed to delete vulnerability detail bucket",
 		},
+		{
+			name: "DeleteCPEBucket returns an error",
+			mocks: mocks{
+				deleteCPEBucket: errors.New("error"),
+			},
+			wantErr: "failed to delete CPE bucket",
+		},
+		{
+			name: "DeleteAffectedPackageBucket returns an error",
+			mocks: mocks{
+				deleteAffectedPackageBucket: errors.New("error"),
+			},
+			wantErr: "failed to delete affected package bucket",
+		},
+		{
+			name: "DeleteProvenanceBucket returns an error",
+			mocks: mocks{
+				deleteProvenanceBucket: errors.New("error"),
+			},
+			wantErr: "failed to delete provenance bucket",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockDBConfig := new(db.MockDBConfig)
-			mockDBConfig.On("ForEachSeverity", mock.Anything).Return(tt.mocks.forEachSeverity)
-			mockDBConfig.On("DeleteVulnerabilityDetailBucket").Return(
+			mockDB := newMockLightOperations()
+			mockDB.MockVulnerabilityStore.On("ForEachSeverity", mock.Anything).Return(tt.mocks.forEachSeverity)
+			mockDB.MockVulnerabilityStore.On("DeleteVulnerabilityDetailBucket").Return(
 				tt.mocks.deleteVulnerabilityDetailBucket)
+			mockDB.MockCPEStore.On("DeleteCPEBucket").Return(tt.mocks.deleteCPEBucket)
+			mockDB.MockAdvisoryStore.On("DeleteAffectedPackageBucket").Return(tt.mocks.deleteAffectedPackageBucket)
+			mockDB.MockProvenanceStore.On("DeleteProvenanceBucket").Return(tt.mocks.deleteProvenanceBucket)
 
 			o := lightOptimizer{
-				dbc: mockDBConfig,
+				dbc: mockDB,
 			}
 			err := o.Optimize()
 			switch {
@@ -355,3 +1055,407 @@ func Test_lightOptimizer_Optimize(t *testing.T) {
 		})
 	}
 }
+
+func Test_passOptimizer_Optimize(t *testing.T) {
+	type observed struct {
+		pass    string
+		elapsed time.Duration
+		err     error
+	}
+	tests := []struct {
+		name         string
+		failing      string
+		wantOrder    []string
+		wantObserved []observed
+		wantErr      string
+	}{
+		{
+			name:      "passes in order",
+			wantOrder: []string{"severities", "dedup"},
+			wantObserved: []observed{
+				{pass: "severities", elapsed: time.Second},
+				{pass: "dedup", elapsed: time.Second},
+			},
+		},
+		{
+			name:      "a failing pass stops the optimization",
+			failing:   "severities",
+			wantOrder: []string{"severities"},
+			wantObserved: []observed{
+				{pass: "severities", elapsed: time.Second, err: errors.New("error")},
+			},
+			wantErr: "failed to run the severities pass: error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := ct.NewFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+			var order []string
+			pass := func(name string) Pass {
+				return NewPass(name, func() error {
+					order = append(order, name)
+					fc.Step(time.Second)
+					if name == tt.failing {
+						return errors.New("error")
+					}
+					return nil
+				})
+			}
+			var got []observed
+			o := passOptimizer{
+				passes: []Pass{pass("severities"), pass("dedup")},
+				observe: func(pass string, elapsed time.Duration, err error) {
+					got = append(got, observed{pass: pass, elapsed: elapsed, err: err})
+				},
+				clock: fc,
+			}
+			err := o.Optimize()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantOrder, order)
+			assert.Equal(t, tt.wantObserved, got)
+		})
+	}
+}
+
+func TestUpdater_WithPasses(t *testing.T) {
+	mockDB := new(db.MockVulnerabilityStore)
+	mockDB.On("ForEachSeverity", mock.Anything).Return(nil)
+	mockDB.On("DeleteSeverityBucket").Return(nil)
+	mockDB.On("DeleteVulnerabilityDetailBucket").Return(nil)
+
+	var order []string
+	u := Updater{optimizer: fullOptimizer{dbc: mockDB}}.
+		WithPasses(NewPass("dedup", func() error { return nil })).
+		WithPassObserver(func(pass string, _ time.Duration, err error) {
+			assert.NoError(t, err)
+			order = append(order, pass)
+		})
+	assert.NoError(t, u.optimize())
+	assert.Equal(t, []string{"vulnerabilities", "details", "dedup"}, order)
+	mockDB.AssertExpectations(t)
+}
+
+func TestUpdater_WithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := log.New(&buf, "info", "json")
+	assert.NoError(t, err)
+
+	u := NewUpdater("cache", false, time.Hour, 0, 0, false).
+		WithFailurePolicy(ContinueOnError).
+		WithLogger(l)
+	assert.Equal(t, l, u.dbc.(db.Config).Logger, "the DB maintenance logs to the logger too")
+	assert.NoError(t, u.reportFailures(SourceErrors{vulnerability.Alpine: xerrors.New("error")}))
+	assert.Contains(t, buf.String(), `"msg":"Keeping the previous advisories of the failed source","source":"alpine"`)
+	assert.Contains(t, buf.String(), `"msg":"Some sources failed"`)
+}
+
+func TestRepositories(t *testing.T) {
+	assert.Equal(t, []string{"vuln-list", "ruby-advisory-db"},
+		Repositories([]string{vulnerability.Alpine, vulnerability.RubySec, vulnerability.Nvd, vulnerability.BDU}))
+	assert.Empty(t, Repositories([]string{vulnerability.BDU}))
+}
+
+func TestRegisteredSources(t *testing.T) {
+	// every source registers itself under its name
+	for name, vs := range updateMap {
+		assert.Equal(t, name, vs.(registry.Source).Name())
+	}
+	assert.Equal(t, []string{vulnerability.BDU, vulnerability.SSVC}, OptionalList)
+	assert.Len(t, UpdateList, len(updateMap)-len(OptionalList))
+	assert.ElementsMatch(t, []string{vulnerability.Alpine, vulnerability.Amazon, vulnerability.Debian, vulnerability.Nvd,
+		vulnerability.RedHat, vulnerability.Ubuntu}, incrementalSources)
+}
+
+func TestUpdater_Preflight(t *testing.T) {
+	files := map[string]string{
+		"vuln-list/alpine/3.10/main/openssl.json":                 `{"name": "openssl"}`,
+		"vuln-list/alpine/3.11/main/openssl.json":                 `{"name": "openssl"}`,
+		"vuln-list/amazon/2/ALAS2-2019-1234.json":                 `{"id": "ALAS2-2019-1234"`,
+		"vuln-list/debian/CVE-2019-0001.json":                     `{}`,
+		"ruby-advisory-db/gems/rack/CVE-2019-1.yml":               "gem: rack\ncve: 2019-1\n",
+		"rust-advisory-db/crates/smallvec/RUSTSEC-2019-0009.toml": "[advisory]\nid = \"RUSTSEC-2019-0009\"\n",
+		"bdu/export.xml": "<?xml version=\"1.0\"?>\n<vulnerabilities><vul>",
+		// a file instead of the dir
+		"vuln-list/ubuntu": "{}",
+	}
+
+	tests := []struct {
+		name        string
+		targets     []string
+		options     map[string]SourceOptions
+		onError     FailurePolicy
+		wantTargets []string
+		wantErr     string
+	}{
+		{
+			name:        "happy path",
+			targets:     []string{vulnerability.Alpine, vulnerability.RubySec, vulnerability.RustSec, vulnerability.BDU},
+			wantTargets: []string{vulnerability.Alpine, vulnerability.RubySec, vulnerability.RustSec, vulnerability.BDU},
+		},
+		{
+			name:    "missing dir",
+			targets: []string{vulnerability.Alpine, vulnerability.Nvd},
+			onError: FailOnError,
+			wantErr: "vuln-list/nvd doesn't exist: fetch it with the fetch command",
+		},
+		{
+			name:    "missing feed",
+			targets: []string{vulnerability.SSVC},
+			onError: FailOnError,
+			wantErr: "ssvc doesn't exist: write the decision points into it",
+		},
+		{
+			name:    "not a dir",
+			targets: []string{vulnerability.Ubuntu},
+			onError: FailOnError,
+			wantErr: "vuln-list/ubuntu isn't a directory",
+		},
+		{
+			name:    "too few files",
+			targets: []string{vulnerability.Alpine},
+			options: map[string]SourceOptions{vulnerability.Alpine: {MinFiles: 3}},
+			onError: FailOnError,
+			wantErr: "vuln-list/alpine has 2 .json file(s), fewer than 3",
+		},
+		{
+			name:    "malformed file",
+			targets: []string{vulnerability.Amazon},
+			onError: FailOnError,
+			wantErr: "ALAS2-2019-1234.json is malformed",
+		},
+		{
+			name:        "failed sources continuing on error are skipped",
+			targets:     []string{vulnerability.Alpine, vulnerability.Amazon, vulnerability.Debian},
+			options:     map[string]SourceOptions{vulnerability.Debian: {MinFiles: 2}},
+			onError:     ContinueOnError,
+			wantTargets: []string{vulnerability.Alpine},
+		},
+		{
+			name:        "sources without a layout",
+			targets:     []string{"unknown"},
+			wantTargets: []string{"unknown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "TestUpdater_Preflight_*")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+			for name, content := range files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+				assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+			}
+
+			u := Updater{cacheDir: dir, options: tt.options, onError: tt.onError}
+			got, err := u.Preflight(tt.targets, 5)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTargets, got)
+		})
+	}
+}
+
+func Test_sampleFiles(t *testing.T) {
+	files := []string{"a", "b", "c", "d", "e", "f"}
+	assert.Equal(t, []string{"a", "c", "e"}, sampleFiles(files, 3))
+	assert.Equal(t, files, sampleFiles(files, 10))
+	assert.Empty(t, sampleFiles(files, 0))
+}
+
+func TestUpdater_changes(t *testing.T) {
+	if !utils.IsCommandAvailable("git") {
+		t.Skip("git is not available")
+	}
+	cacheDir, err := ioutil.TempDir("", "TestUpdater_changes_*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	repo := filepath.Join(cacheDir, "vuln-list")
+	git := func(args ...string) string {
+		out, err := utils.Exec("git", append([]string{"-C", repo, "-c", "user.name=test",
+			"-c", "user.email=test@example.com"}, args...))
+		assert.NoError(t, err)
+		return strings.TrimSpace(out)
+	}
+	writeFile := func(name, content string) {
+		path := filepath.Join(repo, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+	assert.NoError(t, os.MkdirAll(repo, 0700))
+	git("init", "--quiet")
+	writeFile("amazon/2/ALAS2-2019-1234.json", "{}")
+	writeFile("alpine/3.10/main.json", "{}")
+	git("add", "--all")
+	git("commit", "--quiet", "--message", "first")
+	first := git("rev-parse", "HEAD")
+	writeFile("amazon/2/ALAS2-2019-1234.json", `{"id": "ALAS2-2019-1234"}`)
+	git("commit", "--quiet", "--all", "--message", "second")
+
+	metadata := db.Metadata{
+		Type: db.TypeFull,
+		Sources: map[string]db.SourceMetadata{
+			vulnerability.Amazon: {Revision: first},
+			// built before the revisions were recorded
+			vulnerability.Alpine: {},
+			// not incremental
+			vulnerability.OracleOVAL: {Revision: first},
+			vulnerability.Ubuntu:     {Revision: "0123456789abcdef0123456789abcdef01234567"},
+		},
+	}
+	targets := []string{vulnerability.Amazon, vulnerability.Alpine, vulnerability.OracleOVAL, vulnerability.Ubuntu}
+
+	u := Updater{cacheDir: cacheDir, dbType: db.TypeFull}
+	assert.Equal(t, map[string][]string{
+		vulnerability.Amazon: {filepath.Join("vuln-list", "amazon", "2", "ALAS2-2019-1234.json")},
+	}, u.changes(context.Background(), targets, metadata))
+
+	// the light DB has no provenance
+	u.dbType = db.TypeLight
+	metadata.Type = db.TypeLight
+	assert.Nil(t, u.changes(context.Background(), targets, metadata))
+}
+
+func Test_mergeVulnerability(t *testing.T) {
+	previous := types.Vulnerability{
+		Title:       "title",
+		Description: "description",
+		Severity:    "HIGH",
+		References:  []string{"https://example.com"},
+		CVSS: map[string]types.CVSS{
+			"nvd":    {V3Score: 7.5, V3Vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N"},
+			"redhat": {V3Score: 5.9},
+		},
+	}
+	assert.Equal(t, previous, mergeVulnerability(previous, types.Vulnerability{Severity: "UNKNOWN"}))
+	assert.Equal(t, types.Vulnerability{
+		Title:       "title",
+		Description: "new description",
+		Severity:    "CRITICAL",
+		References:  []string{"https://example.com"},
+		CVSS: map[string]types.CVSS{
+			"nvd":    {V3Score: 9.8},
+			"redhat": {V3Score: 5.9},
+		},
+	}, mergeVulnerability(previous, types.Vulnerability{Description: "new description", Severity: "CRITICAL",
+		CVSS: map[string]types.CVSS{"nvd": {V3Score: 9.8}}}))
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "I/O error",
+			err: xerrors.Errorf("error in file walk: %w",
+				&os.PathError{Op: "read", Path: "vuln-list/nvd/2020/CVE-2020-0001.json", Err: syscall.EIO}),
+			want: true,
+		},
+		{
+			name: "stale NFS handle",
+			err:  xerrors.Errorf("failed to open file: %w", &os.PathError{Op: "open", Err: syscall.ESTALE}),
+			want: true,
+		},
+		{
+			name: "network timeout",
+			err:  &net.OpError{Op: "dial", Err: timeoutError{}},
+			want: true,
+		},
+		{
+			name: "missing dir",
+			err:  xerrors.Errorf("error in file walk: %w", &os.PathError{Op: "lstat", Err: syscall.ENOENT}),
+		},
+		{
+			name: "malformed input",
+			err:  xerrors.Errorf("failed to decode: %w", errors.New("invalid character")),
+		},
+		{
+			name: "source timeout",
+			err:  xerrors.Errorf("error in walk: %w", context.DeadlineExceeded),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTransient(tt.err))
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestUpdater_retry(t *testing.T) {
+	transient := &os.PathError{Op: "read", Err: syscall.EIO}
+	tests := []struct {
+		name         string
+		errs         []error // of the attempts, nil once they are over
+		retries      int
+		options      map[string]SourceOptions
+		wantAttempts int
+		wantErr      error
+	}{
+		{
+			name:         "succeeds after transient failures",
+			errs:         []error{transient, transient},
+			retries:      2,
+			wantAttempts: 3,
+		},
+		{
+			name:         "retries exhausted",
+			errs:         []error{transient, transient, transient},
+			retries:      2,
+			wantAttempts: 3,
+			wantErr:      transient,
+		},
+		{
+			name:         "fatal error",
+			errs:         []error{errors.New("malformed")},
+			retries:      2,
+			wantAttempts: 1,
+			wantErr:      errors.New("malformed"),
+		},
+		{
+			name:         "retries of the source",
+			errs:         []error{transient, transient},
+			options:      map[string]SourceOptions{vulnerability.Alpine: {Retries: 3}},
+			wantAttempts: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := Updater{clock: clock.RealClock{}, options: tt.options}.WithRetries(tt.retries, time.Millisecond)
+			var attempts, restarts int
+			ctx, progress := log.WithProgress(context.Background(), vulnerability.Alpine)
+			err := u.retry(ctx, vulnerability.Alpine, func(ctx context.Context) error {
+				attempts++
+				log.ProgressFrom(ctx).AddFiles(1)
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			}, func() error {
+				restarts++
+				return nil
+			})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantAttempts, attempts)
+			assert.Equal(t, tt.wantAttempts-1, restarts)
+			// the files of the last attempt
+			assert.Equal(t, int64(1), progress.Files())
+		})
+	}
+}
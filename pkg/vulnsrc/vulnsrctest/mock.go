@@ -0,0 +1,13 @@
+// Package vulnsrctest has the helpers of the tests of the sources
+package vulnsrctest
+
+import "github.com/aquasecurity/trivy-db/pkg/db"
+
+// MockOperations composes the mocks of the stores the sources use. A test sets the ones its source uses,
+// calling another store panics.
+type MockOperations struct {
+	*db.MockBatchUpdater
+	*db.MockAdvisoryStore
+	*db.MockVulnerabilityStore
+	*db.MockProvenanceStore
+}
@@ -0,0 +1,4 @@
+// Package lookup is the gRPC service serving lookups of a DB and its generated client
+package lookup
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative service.proto
@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package lookup
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// LookupClient is the client API for Lookup service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LookupClient interface {
+	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*Metadata, error)
+	GetAdvisories(ctx context.Context, in *AdvisoryQuery, opts ...grpc.CallOption) (*Advisories, error)
+	// BatchGetAdvisories answers the queries in order, failing as a whole on an invalid one
+	BatchGetAdvisories(ctx context.Context, in *BatchGetAdvisoriesRequest, opts ...grpc.CallOption) (*BatchGetAdvisoriesResponse, error)
+	GetVulnerability(ctx context.Context, in *GetVulnerabilityRequest, opts ...grpc.CallOption) (*Vulnerability, error)
+	// BatchGetVulnerabilities returns the vulnerabilities of the known IDs, the unknown ones being skipped
+	BatchGetVulnerabilities(ctx context.Context, in *BatchGetVulnerabilitiesRequest, opts ...grpc.CallOption) (*BatchGetVulnerabilitiesResponse, error)
+}
+
+type lookupClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLookupClient(cc grpc.ClientConnInterface) LookupClient {
+	return &lookupClient{cc}
+}
+
+func (c *lookupClient) GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*Metadata, error) {
+	out := new(Metadata)
+	err := c.cc.Invoke(ctx, "/trivydb.lookup.v1.Lookup/GetMetadata", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lookupClient) GetAdvisories(ctx context.Context, in *AdvisoryQuery, opts ...grpc.CallOption) (*Advisories, error) {
+	out := new(Advisories)
+	err := c.cc.Invoke(ctx, "/trivydb.lookup.v1.Lookup/GetAdvisories", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lookupClient) BatchGetAdvisories(ctx context.Context, in *BatchGetAdvisoriesRequest, opts ...grpc.CallOption) (*BatchGetAdvisoriesResponse, error) {
+	out := new(BatchGetAdvisoriesResponse)
+	err := c.cc.Invoke(ctx, "/trivydb.lookup.v1.Lookup/BatchGetAdvisories", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lookupClient) GetVulnerability(ctx context.Context, in *GetVulnerabilityRequest, opts ...grpc.CallOption) (*Vulnerability, error) {
+	out := new(Vulnerability)
+	err := c.cc.Invoke(ctx, "/trivydb.lookup.v1.Lookup/GetVulnerability", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lookupClient) BatchGetVulnerabilities(ctx context.Context, in *BatchGetVulnerabilitiesRequest, opts ...grpc.CallOption) (*BatchGetVulnerabilitiesResponse, error) {
+	out := new(BatchGetVulnerabilitiesResponse)
+	err := c.cc.Invoke(ctx, "/trivydb.lookup.v1.Lookup/BatchGetVulnerabilities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LookupServer is the server API for Lookup service.
+// All implementations must embed UnimplementedLookupServer
+// for forward compatibility
+type LookupServer interface {
+	GetMetadata(context.Context, *GetMetadataRequest) (*Metadata, error)
+	GetAdvisories(context.Context, *AdvisoryQuery) (*Advisories, error)
+	// BatchGetAdvisories answers the queries in order, failing as a whole on an invalid one
+	BatchGetAdvisories(context.Context, *BatchGetAdvisoriesRequest) (*BatchGetAdvisoriesResponse, error)
+	GetVulnerability(context.Context, *GetVulnerabilityRequest) (*Vulnerability, error)
+	// BatchGetVulnerabilities returns the vulnerabilities of the known IDs, the unknown ones being skipped
+	BatchGetVulnerabilities(context.Context, *BatchGetVulnerabilitiesRequest) (*BatchGetVulnerabilitiesResponse, error)
+	mustEmbedUnimplementedLookupServer()
+}
+
+// UnimplementedLookupServer must be embedded to have forward compatible implementations.
+type UnimplementedLookupServer struct {
+}
+
+func (UnimplementedLookupServer) GetMetadata(context.Context, *GetMetadataRequest) (*Metadata, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetadata not implemented")
+}
+func (UnimplementedLookupServer) GetAdvisories(context.Context, *AdvisoryQuery) (*Advisories, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAdvisories not implemented")
+}
+func (UnimplementedLookupServer) BatchGetAdvisories(context.Context, *BatchGetAdvisoriesRequest) (*BatchGetAdvisoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetAdvisories not implemented")
+}
+func (UnimplementedLookupServer) GetVulnerability(context.Context, *GetVulnerabilityRequest) (*Vulnerability, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVulnerability not implemented")
+}
+func (UnimplementedLookupServer) BatchGetVulnerabilities(context.Context, *BatchGetVulnerabilitiesRequest) (*BatchGetVulnerabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetVulnerabilities not implemented")
+}
+func (UnimplementedLookupServer) mustEmbedUnimplementedLookupServer() {}
+
+// UnsafeLookupServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LookupServer will
+// result in compilation errors.
+type UnsafeLookupServer interface {
+	mustEmbedUnimplementedLookupServer()
+}
+
+func RegisterLookupServer(s grpc.ServiceRegistrar, srv LookupServer) {
+	s.RegisterService(&Lookup_ServiceDesc, srv)
+}
+
+func _Lookup_GetMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LookupServer).GetMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trivydb.lookup.v1.Lookup/GetMetadata",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LookupServer).GetMetadata(ctx, req.(*GetMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lookup_GetAdvisories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdvisoryQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LookupServer).GetAdvisories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trivydb.lookup.v1.Lookup/GetAdvisories",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LookupServer).GetAdvisories(ctx, req.(*AdvisoryQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lookup_BatchGetAdvisories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetAdvisoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LookupServer).BatchGetAdvisories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trivydb.lookup.v1.Lookup/BatchGetAdvisories",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LookupServer).BatchGetAdvisories(ctx, req.(*BatchGetAdvisoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lookup_GetVulnerability_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVulnerabilityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LookupServer).GetVulnerability(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trivydb.lookup.v1.Lookup/GetVulnerability",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LookupServer).GetVulnerability(ctx, req.(*GetVulnerabilityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lookup_BatchGetVulnerabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetVulnerabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LookupServer).BatchGetVulnerabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/trivydb.lookup.v1.Lookup/BatchGetVulnerabilities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LookupServer).BatchGetVulnerabilities(ctx, req.(*BatchGetVulnerabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Lookup_ServiceDesc is the grpc.ServiceDesc for Lookup service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Lookup_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "trivydb.lookup.v1.Lookup",
+	HandlerType: (*LookupServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMetadata",
+			Handler:    _Lookup_GetMetadata_Handler,
+		},
+		{
+			MethodName: "GetAdvisories",
+			Handler:    _Lookup_GetAdvisories_Handler,
+		},
+		{
+			MethodName: "BatchGetAdvisories",
+			Handler:    _Lookup_BatchGetAdvisories_Handler,
+		},
+		{
+			MethodName: "GetVulnerability",
+			Handler:    _Lookup_GetVulnerability_Handler,
+		},
+		{
+			MethodName: "BatchGetVulnerabilities",
+			Handler:    _Lookup_BatchGetVulnerabilities_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "service.proto",
+}